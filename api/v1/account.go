@@ -64,6 +64,8 @@ type AccountInfo struct {
 	CreatedAt          string `json:"createdAt"`          // 创建时间
 	UpdatedAt          string `json:"updatedAt"`          // 更新时间
 	SubscriptionStatus string `json:"subscriptionStatus"` // 订阅状态
+
+	Cost *AccountCost `json:"cost"` // 虚拟机预计月费用，账户没有虚拟机时合计为 0
 }
 
 // ToAccountInfo 将数据库模型转换为API响应模型
@@ -82,11 +84,13 @@ func ToAccountInfo(account *model.Accounts) *AccountInfo {
 	}
 }
 
-// ToAccountListResp 转换为列表响应
-func ToAccountListResp(result *app.ListResult[*model.Accounts]) *AccountListResp {
+// ToAccountListResp 转换为列表响应，costs 为按账户ID汇总的费用
+func ToAccountListResp(result *app.ListResult[*model.Accounts], costs map[string]*AccountCost) *AccountListResp {
 	items := make([]*AccountInfo, 0, len(result.Items))
 	for _, account := range result.Items {
-		items = append(items, ToAccountInfo(account))
+		info := ToAccountInfo(account)
+		info.Cost = costs[account.AccountID]
+		items = append(items, info)
 	}
 
 	return &AccountListResp{
@@ -115,4 +119,6 @@ type SyncAccountResult struct {
 	Message           string `json:"message"`           // 同步信息
 	SubscriptionCount int    `json:"subscriptionCount"` // 同步的订阅数量
 	VMCount           int    `json:"vmCount"`           // 同步的虚拟机数量
+
+	Subscriptions []SubscriptionSyncResult `json:"subscriptions"` // 每个订阅的虚拟机同步结果
 }
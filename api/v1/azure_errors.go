@@ -0,0 +1,184 @@
+package v1
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+)
+
+// Azure 调用失败时返回的业务错误，Detail 中包含 Azure 返回的错误码和说明
+var (
+	// ErrAzureRequestFailed 无法归类的Azure错误
+	ErrAzureRequestFailed = newError(2000, http.StatusBadGateway, "Azure request failed")
+
+	// ErrAzureAuthenticationFailed 账户凭据无效或已过期
+	ErrAzureAuthenticationFailed = newError(2001, http.StatusBadRequest, "Azure credentials are invalid or expired")
+
+	// ErrAzureAuthorizationFailed 服务主体没有执行该操作的权限
+	ErrAzureAuthorizationFailed = newError(2002, http.StatusForbidden, "The service principal is not authorized to perform this operation")
+
+	// ErrAzureQuotaExceeded 超出订阅配额
+	ErrAzureQuotaExceeded = newError(2003, http.StatusConflict, "Azure quota exceeded")
+
+	// ErrAzureSkuNotAvailable 规格在该区域不可用
+	ErrAzureSkuNotAvailable = newError(2004, http.StatusConflict, "The requested size is not available in this location")
+
+	// ErrAzureAllocationFailed 区域容量不足，无法分配资源
+	ErrAzureAllocationFailed = newError(2005, http.StatusConflict, "Azure could not allocate the requested capacity")
+
+	// ErrAzureResourceNotFound Azure 资源不存在
+	ErrAzureResourceNotFound = newError(2006, http.StatusNotFound, "Azure resource not found")
+
+	// ErrAzureOperationNotAllowed 资源当前状态不允许该操作
+	ErrAzureOperationNotAllowed = newError(2007, http.StatusConflict, "The operation is not allowed in the current resource state")
+
+	// ErrAzureSubscriptionDisabled 订阅已禁用或只读
+	ErrAzureSubscriptionDisabled = newError(2008, http.StatusForbidden, "The Azure subscription is disabled")
+
+	// ErrAzureProviderNotRegistered 订阅未注册所需的资源提供程序
+	ErrAzureProviderNotRegistered = newError(2009, http.StatusBadRequest, "The subscription is not registered for the required resource provider")
+
+	// ErrAzureThrottled 请求被Azure限流
+	ErrAzureThrottled = newError(2010, http.StatusTooManyRequests, "Azure throttled the request, please retry later")
+
+	// ErrAzureInvalidParameter Azure 拒绝了请求参数
+	ErrAzureInvalidParameter = newError(2011, http.StatusBadRequest, "Azure rejected the request parameters")
+)
+
+// azureErrorCodes Azure 错误码(小写)到业务错误的映射
+var azureErrorCodes = map[string]*Error{
+	"authenticationfailed":                 ErrAzureAuthenticationFailed,
+	"invalidauthenticationtoken":           ErrAzureAuthenticationFailed,
+	"invalidauthenticationtokentenant":     ErrAzureAuthenticationFailed,
+	"expiredauthenticationtoken":           ErrAzureAuthenticationFailed,
+	"authorizationfailed":                  ErrAzureAuthorizationFailed,
+	"linkedauthorizationfailed":            ErrAzureAuthorizationFailed,
+	"quotaexceeded":                        ErrAzureQuotaExceeded,
+	"skunotavailable":                      ErrAzureSkuNotAvailable,
+	"invalidsku":                           ErrAzureSkuNotAvailable,
+	"allocationfailed":                     ErrAzureAllocationFailed,
+	"zonalallocationfailed":                ErrAzureAllocationFailed,
+	"overconstrainedallocationrequest":     ErrAzureAllocationFailed,
+	"overconstrainedzonalallocation":       ErrAzureAllocationFailed,
+	"resourcenotfound":                     ErrAzureResourceNotFound,
+	"resourcegroupnotfound":                ErrAzureResourceNotFound,
+	"parentresourcenotfound":               ErrAzureResourceNotFound,
+	"notfound":                             ErrAzureResourceNotFound,
+	"operationnotallowed":                  ErrAzureOperationNotAllowed,
+	"conflict":                             ErrAzureOperationNotAllowed,
+	"conflictingoperationinprogress":       ErrAzureOperationNotAllowed,
+	"operationpreempted":                   ErrAzureOperationNotAllowed,
+	"propertychangenotallowed":             ErrAzureOperationNotAllowed,
+	"readonlydisabledsubscription":         ErrAzureSubscriptionDisabled,
+	"disabledsubscription":                 ErrAzureSubscriptionDisabled,
+	"subscriptionnotfound":                 ErrAzureSubscriptionDisabled,
+	"missingsubscriptionregistration":      ErrAzureProviderNotRegistered,
+	"subscriptionnotregistered":            ErrAzureProviderNotRegistered,
+	"toomanyrequests":                      ErrAzureThrottled,
+	"subscriptionrequeststhrottled":        ErrAzureThrottled,
+	"invalidparameter":                     ErrAzureInvalidParameter,
+	"invalidrequestcontent":                ErrAzureInvalidParameter,
+	"invalidrequestformat":                 ErrAzureInvalidParameter,
+	"badrequest":                           ErrAzureInvalidParameter,
+	"invalidresourcename":                  ErrAzureInvalidParameter,
+	"invalidresourcereference":             ErrAzureInvalidParameter,
+	"publicipcountlimitreached":            ErrAzureQuotaExceeded,
+	"standardskupublicipcountlimitreached": ErrAzureQuotaExceeded,
+	"securityruleconflict":                 ErrSecurityRuleConflict,
+}
+
+// azureStatusErrors 没有可识别错误码时按HTTP状态码归类
+var azureStatusErrors = map[int]*Error{
+	http.StatusBadRequest:      ErrAzureInvalidParameter,
+	http.StatusUnauthorized:    ErrAzureAuthenticationFailed,
+	http.StatusForbidden:       ErrAzureAuthorizationFailed,
+	http.StatusNotFound:        ErrAzureResourceNotFound,
+	http.StatusConflict:        ErrAzureOperationNotAllowed,
+	http.StatusTooManyRequests: ErrAzureThrottled,
+}
+
+// FromAzureError 将Azure调用返回的错误转换为业务错误
+// 已是业务错误时原样返回，其余无法识别的错误归为 ErrAzureRequestFailed，原始错误可通过 errors.As 获取
+// 只有 Azure 响应体中的错误码和说明会作为 Detail 返回给调用方
+func FromAzureError(err error) error {
+	if err == nil {
+		return nil
+	}
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		return err
+	}
+
+	// 认证和传输错误的原文包含租户ID、客户端ID和内部地址，不返回给调用方，原始错误由 HandleError 记录到服务端日志
+	var authErr *azidentity.AuthenticationFailedError
+	if errors.As(err, &authErr) {
+		return ErrAzureAuthenticationFailed.Wrap(err)
+	}
+
+	var respErr *azcore.ResponseError
+	if !errors.As(err, &respErr) {
+		return ErrAzureRequestFailed.Wrap(err)
+	}
+
+	code, message := azureErrorBody(respErr)
+	base, ok := azureErrorCodes[strings.ToLower(code)]
+	if !ok {
+		if base, ok = azureStatusErrors[respErr.StatusCode]; !ok {
+			base = ErrAzureRequestFailed
+		}
+	}
+	// 核心数配额不足时 Azure 返回 OperationNotAllowed
+	if base == ErrAzureOperationNotAllowed && strings.Contains(strings.ToLower(message), "quota") {
+		base = ErrAzureQuotaExceeded
+	}
+
+	detail := code
+	if message != "" {
+		detail = code + ": " + message
+	}
+	return base.Wrap(err).WithDetail(detail)
+}
+
+// isAzureError 判断错误链中是否包含Azure SDK错误
+func isAzureError(err error) bool {
+	var respErr *azcore.ResponseError
+	var authErr *azidentity.AuthenticationFailedError
+	return errors.As(err, &respErr) || errors.As(err, &authErr)
+}
+
+// azureErrorBody 从响应体中解析Azure错误码和说明，响应体不可用时使用 ResponseError 中的错误码
+func azureErrorBody(respErr *azcore.ResponseError) (string, string) {
+	code := respErr.ErrorCode
+	if respErr.RawResponse == nil || respErr.RawResponse.Body == nil {
+		return code, ""
+	}
+	payload, err := runtime.Payload(respErr.RawResponse)
+	if err != nil || len(payload) == 0 {
+		return code, ""
+	}
+
+	type errorBody struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	}
+	var body struct {
+		errorBody
+		Error *errorBody `json:"error"`
+	}
+	if err := json.Unmarshal(payload, &body); err != nil {
+		return code, ""
+	}
+	detail := body.errorBody
+	if body.Error != nil {
+		detail = *body.Error
+	}
+	if code == "" {
+		code = detail.Code
+	}
+	return code, detail.Message
+}
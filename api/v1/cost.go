@@ -0,0 +1,79 @@
+package v1
+
+import "time"
+
+// CostEstimate 虚拟机的预计月费用，按每月 730 小时计算，价格为零售价格(美元)
+type CostEstimate struct {
+	Size           string          `json:"size"`
+	Location       string          `json:"location"`
+	OSType         string          `json:"osType"`
+	Currency       string          `json:"currency"`
+	PriceAvailable bool            `json:"priceAvailable"` // 没有找到规格价格时为 false，此时只包含磁盘费用
+	ComputePerHour float64         `json:"computePerHour"` // 规格按量价格(每小时)
+	ComputeMonthly float64         `json:"computeMonthly"` // 计算费用(每月)，已释放的虚拟机为 0
+	DiskMonthly    float64         `json:"diskMonthly"`    // 磁盘费用(每月)
+	Total          float64         `json:"total"`          // 合计(每月)
+	Disks          []*DiskEstimate `json:"disks"`
+}
+
+// DiskEstimate 单块磁盘的预计月费用，按磁盘档位向上取整计费
+type DiskEstimate struct {
+	Name        string  `json:"name"`
+	DiskType    string  `json:"diskType"`
+	SizeGB      int     `json:"sizeGb"`
+	BilledGB    int     `json:"billedGb"` // 计费档位大小
+	MonthlyCost float64 `json:"monthlyCost"`
+}
+
+// AccountCost 账户下虚拟机的预计月费用合计
+type AccountCost struct {
+	Currency      string              `json:"currency"`
+	MonthlyCost   float64             `json:"monthlyCost"`
+	Subscriptions []*SubscriptionCost `json:"subscriptions"`
+}
+
+// SubscriptionCost 订阅下虚拟机的预计月费用合计
+type SubscriptionCost struct {
+	SubscriptionID string  `json:"subscriptionId"`
+	VMCount        int64   `json:"vmCount"`
+	MonthlyCost    float64 `json:"monthlyCost"`
+}
+
+// SpendSummary 实际费用，来自 Cost Management，通常比实际使用延迟 8 到 24 小时
+// MonthToDate 为本月1日(UTC)至今，Last30Days 为含今天在内的最近30天
+type SpendSummary struct {
+	Currency    string  `json:"currency"`
+	MonthToDate float64 `json:"monthToDate"`
+	Last30Days  float64 `json:"last30Days"`
+}
+
+// AccountSpend 账户的实际费用，按订阅细分
+type AccountSpend struct {
+	AccountID     string               `json:"accountId"`
+	SyncedAt      *time.Time           `json:"syncedAt"` // 最近一次同步费用的时间，从未同步时为 null
+	Spend         SpendSummary         `json:"spend"`
+	Subscriptions []*SubscriptionSpend `json:"subscriptions"`
+}
+
+// SubscriptionSpend 订阅的实际费用，获取单个订阅时按虚拟机细分
+type SubscriptionSpend struct {
+	SubscriptionID string       `json:"subscriptionId"`
+	Spend          SpendSummary `json:"spend"`
+	VMs            []*VMSpend   `json:"vms,omitempty"`
+}
+
+// VMSpend 虚拟机的实际费用，只包含虚拟机资源本身的计量，磁盘和公网IP等资源计入所在订阅
+type VMSpend struct {
+	VMID  string       `json:"vmId"`
+	Name  string       `json:"name"`
+	Spend SpendSummary `json:"spend"`
+}
+
+// CostSyncResult 同步实际费用的结果
+type CostSyncResult struct {
+	From                string   `json:"from"` // 同步的日期范围，格式 2006-01-02
+	To                  string   `json:"to"`
+	Subscriptions       int      `json:"subscriptions"`
+	Records             int      `json:"records"`
+	FailedSubscriptions []string `json:"failedSubscriptions"`
+}
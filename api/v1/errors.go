@@ -1,36 +1,71 @@
 package v1
 
+import "net/http"
+
 var (
 	// ErrSuccess common errors
-	ErrSuccess             = newError(0, "ok")
-	ErrBadRequest          = newError(400, "Bad Request")
-	ErrUnauthorized        = newError(401, "Unauthorized")
-	ErrNotFound            = newError(404, "Not Found")
-	ErrInternalServerError = newError(500, "Internal Server Error")
+	ErrSuccess             = newError(0, http.StatusOK, "ok")
+	ErrBadRequest          = newError(400, http.StatusBadRequest, "Bad Request")
+	ErrUnauthorized        = newError(401, http.StatusUnauthorized, "Unauthorized")
+	ErrNotFound            = newError(404, http.StatusNotFound, "Not Found")
+	ErrInternalServerError = newError(500, http.StatusInternalServerError, "Internal Server Error")
 
 	// ErrEmailAlreadyUse more biz errors
-	ErrEmailAlreadyUse = newError(1001, "The email is already in use.")
+	ErrEmailAlreadyUse = newError(1001, http.StatusConflict, "The email is already in use.")
 
 	// ErrUserAlreadyExist 已存在用户 不允许注册
-	ErrUserAlreadyExist = newError(1002, "User already exists")
+	ErrUserAlreadyExist = newError(1002, http.StatusConflict, "User already exists")
 
 	// ErrAccountError 获取账号出现异常
-	ErrAccountError = newError(1003, "abnormalities in obtaining an account")
+	ErrAccountError = newError(1003, http.StatusBadRequest, "abnormalities in obtaining an account")
 
 	// ErrAccountEmailDuplicate 错误帐户电子邮件重复
-	ErrAccountEmailDuplicate = newError(1004, "error account email duplicates")
+	ErrAccountEmailDuplicate = newError(1004, http.StatusConflict, "error account email duplicates")
 
 	// ErrorAzureNotFound 当前用户不存在这个azure账户
-	ErrorAzureNotFound = newError(1005, "This azure account does not exist for the current user")
+	ErrorAzureNotFound = newError(1005, http.StatusNotFound, "This azure account does not exist for the current user")
 
 	// ErrSubscriptionNotFound 没有找到可用订阅
-	ErrSubscriptionNotFound = newError(1006, "no Available Subscriptions Found")
+	ErrSubscriptionNotFound = newError(1006, http.StatusNotFound, "no Available Subscriptions Found")
 
-	ErrNotImplemented = newError(1007, "Not Implemented")
+	ErrNotImplemented = newError(1007, http.StatusNotImplemented, "Not Implemented")
 
 	// ErrPasswordError 密码错误
-	ErrPasswordError = newError(1008, "Password Error")
+	ErrPasswordError = newError(1008, http.StatusUnauthorized, "Password Error")
 
 	// ErrInvalidParams 无效参数
-	ErrInvalidParams = newError(1009, "Invalid Params")
+	ErrInvalidParams = newError(1009, http.StatusBadRequest, "Invalid Params")
+
+	// ErrVMAlreadyExist 同名虚拟机已存在
+	ErrVMAlreadyExist = newError(1010, http.StatusConflict, "VM already exists")
+
+	// ErrInvalidCronExpr 无效的cron表达式或执行间隔过短
+	ErrInvalidCronExpr = newError(1011, http.StatusBadRequest, "Invalid cron expression")
+
+	// ErrVMSizeUnavailable 目标规格未同步或在虚拟机所在区域不可用
+	ErrVMSizeUnavailable = newError(1012, http.StatusBadRequest, "The requested size is not available for this VM")
+
+	// ErrDataDiskLimitExceeded 数据磁盘数量已达到规格上限
+	ErrDataDiskLimitExceeded = newError(1013, http.StatusConflict, "The VM size does not allow more data disks")
+
+	// ErrVMNotDeallocated 操作要求虚拟机处于已释放状态
+	ErrVMNotDeallocated = newError(1014, http.StatusConflict, "The VM must be deallocated for this operation")
+
+	// ErrSecurityGroupNotFound 虚拟机的网卡和子网都没有关联网络安全组，或指定的网络安全组未作用于该虚拟机
+	ErrSecurityGroupNotFound = newError(1015, http.StatusNotFound, "No network security group is associated with this VM")
+
+	// ErrSecurityRuleConflict 同一方向上已有相同优先级的规则，或规则名与默认规则重名
+	ErrSecurityRuleConflict = newError(1016, http.StatusConflict, "A security rule with the same priority or name already exists")
+
+	// ErrSecurityGroupAlreadyAttached 网卡已关联网络安全组
+	ErrSecurityGroupAlreadyAttached = newError(1017, http.StatusConflict, "The VM network interface already has a network security group")
+
+	// ErrVMNotRunning 操作要求虚拟机处于运行状态
+	ErrVMNotRunning = newError(1018, http.StatusConflict, "The VM must be running for this operation")
+
+	// ErrBootDiagnosticsDisabled 虚拟机未启用启动诊断
+	ErrBootDiagnosticsDisabled = newError(1019, http.StatusConflict, "Boot diagnostics is not enabled for this VM")
+
+	// ErrQuotaExceeded 订阅在该区域的剩余 vCPU 配额不足
+	ErrQuotaExceeded = newError(1020, http.StatusConflict, "Not enough vCPU quota in this region")
 )
@@ -0,0 +1,27 @@
+package v1
+
+import "time"
+
+// ListQuotasRequest 查询订阅配额请求
+type ListQuotasRequest struct {
+	Location string `form:"location" example:"eastus"` // 区域，为空时返回已获取过的所有区域
+	Refresh  bool   `form:"refresh"`                   // 是否先从Azure获取最新配额，需要指定区域
+}
+
+// VCPUQuota vCPU 配额用量
+type VCPUQuota struct {
+	Name          string `json:"name" example:"standardDSv3Family"`
+	LocalizedName string `json:"localizedName" example:"Standard DSv3 Family vCPUs"`
+	Used          int64  `json:"used"`
+	Limit         int64  `json:"limit"`
+	Available     int64  `json:"available"`
+}
+
+// RegionQuota 订阅在一个区域的 vCPU 配额
+type RegionQuota struct {
+	Location string       `json:"location"`
+	Total    *VCPUQuota   `json:"total"`          // 区域总 vCPU
+	Spot     *VCPUQuota   `json:"spot,omitempty"` // Spot/低优先级 vCPU
+	Families []*VCPUQuota `json:"families"`       // 各规格系列，只包含限额或用量不为 0 的系列
+	SyncedAt time.Time    `json:"syncedAt"`
+}
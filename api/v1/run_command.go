@@ -0,0 +1,53 @@
+package v1
+
+import "azure-vm-backend/internal/model"
+
+// CreateScriptSnippetRequest 创建脚本片段请求
+type CreateScriptSnippetRequest struct {
+	Name        string `json:"name" binding:"required,max=128" example:"disk-usage"`           // 名称
+	OSType      string `json:"osType" binding:"omitempty,oneof=Linux Windows" example:"Linux"` // 适用的操作系统，为空时不限制
+	Script      string `json:"script" binding:"required" example:"df -h"`                      // 脚本内容
+	Description string `json:"description" binding:"max=512"`                                  // 描述
+}
+
+// UpdateScriptSnippetRequest 更新脚本片段请求，未传的字段保持不变
+type UpdateScriptSnippetRequest struct {
+	Name        *string `json:"name,omitempty" binding:"omitempty,max=128"`
+	OSType      *string `json:"osType,omitempty" binding:"omitempty,oneof=Linux Windows"`
+	Script      *string `json:"script,omitempty"`
+	Description *string `json:"description,omitempty" binding:"omitempty,max=512"`
+}
+
+// RunCommandRequest 在虚拟机内执行脚本请求，Script 和 SnippetID 二选一
+// Linux 按 shell 脚本执行，Windows 按 PowerShell 脚本执行
+type RunCommandRequest struct {
+	Script    string `json:"script" example:"uptime"` // 脚本内容
+	SnippetID string `json:"snippetId"`               // 已保存的脚本片段ID
+}
+
+// BatchRunCommandRequest 按虚拟机查询条件批量执行脚本请求
+type BatchRunCommandRequest struct {
+	RunCommandRequest
+	Filter VMQueryParams `json:"filter"` // 虚拟机筛选条件，忽略分页参数
+}
+
+// ListCommandExecutionsRequest 查询执行记录请求
+type ListCommandExecutionsRequest struct {
+	Page     int `form:"page" json:"page"`
+	PageSize int `form:"pageSize" json:"pageSize"`
+}
+
+// BatchRunCommandSkipped 批量执行时跳过的虚拟机
+type BatchRunCommandSkipped struct {
+	ID     uint   `json:"id"`
+	VMID   string `json:"vmId"`
+	Name   string `json:"name"`
+	Reason string `json:"reason"`
+}
+
+// BatchRunCommandResponse 批量执行脚本响应
+type BatchRunCommandResponse struct {
+	BatchID    string                      `json:"batchId"`
+	Executions []*model.VmCommandExecution `json:"executions"`
+	Skipped    []*BatchRunCommandSkipped   `json:"skipped"`
+}
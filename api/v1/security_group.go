@@ -0,0 +1,23 @@
+package v1
+
+// AddSecurityRuleRequest 添加入站安全规则请求
+type AddSecurityRuleRequest struct {
+	SecurityGroup string `json:"securityGroup" example:"my-vm-nsg"`                           // 网络安全组名称，为空时优先使用网卡上的网络安全组
+	Name          string `json:"name" binding:"required,max=80" example:"allow-ssh"`          // 规则名称
+	Port          string `json:"port" binding:"required" example:"22"`                        // 目标端口，支持单个端口、范围(8000-8100)、逗号分隔的多个值或 *
+	Protocol      string `json:"protocol" example:"Tcp"`                                      // 协议 Tcp/Udp/Icmp/*，默认 Tcp
+	SourceCIDR    string `json:"sourceCidr" example:"203.0.113.0/24"`                         // 来源地址，支持IP、CIDR、服务标签(如 Internet)或逗号分隔的多个值，默认 *
+	Priority      int32  `json:"priority" binding:"required,min=100,max=4096" example:"1000"` // 优先级，数值越小越优先，同一方向内不能重复
+	Access        string `json:"access" binding:"omitempty,oneof=Allow Deny" example:"Allow"` // 允许或拒绝，默认 Allow
+	Description   string `json:"description" binding:"max=140" example:"SSH from office"`     // 规则描述
+}
+
+// CreateSecurityGroupRequest 创建网络安全组并关联到虚拟机网卡的请求
+type CreateSecurityGroupRequest struct {
+	Name string `json:"name" binding:"max=80" example:"my-vm-nsg"` // 网络安全组名称，为空时使用 <虚拟机名称>-nsg
+}
+
+// ListSecurityGroupsRequest 查询网络安全组请求
+type ListSecurityGroupsRequest struct {
+	Refresh bool `form:"refresh"` // 是否先从Azure同步网络安全组
+}
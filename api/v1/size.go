@@ -2,6 +2,7 @@ package v1
 
 import (
 	"azure-vm-backend/internal/model"
+	"strings"
 	"time"
 )
 
@@ -20,11 +21,25 @@ type VmSizeInfo struct {
 	LastSyncAt   time.Time `json:"lastSyncAt"`   // 最后同步时间
 	CreatedAt    time.Time `json:"createdAt"`    // 创建时间
 	UpdatedAt    time.Time `json:"updatedAt"`    // 更新时间
+
+	QuotaFamily           string   `json:"quotaFamily"`           // 配额系列，例如 standardDSv3Family
+	Zones                 []string `json:"zones"`                 // 可用的可用区
+	AcceleratedNetworking bool     `json:"acceleratedNetworking"` // 是否支持加速网络
+	PremiumIO             bool     `json:"premiumIO"`             // 是否支持高级存储
+	HyperVGenerations     []string `json:"hyperVGenerations"`     // 支持的 Hyper-V 代数，例如 V1、V2
+
+	PricePerHour            float64 `json:"pricePerHour"`            // Linux 按量价格(每小时)，价格未同步时为 0
+	WindowsPricePerHour     float64 `json:"windowsPricePerHour"`     // Windows 按量价格(每小时)
+	SpotPricePerHour        float64 `json:"spotPricePerHour"`        // Linux Spot 价格(每小时)
+	WindowsSpotPricePerHour float64 `json:"windowsSpotPricePerHour"` // Windows Spot 价格(每小时)
+	Currency                string  `json:"currency"`                // 币种
 }
 
 // ListVmSizesRequest 获取规格列表请求
 type ListVmSizesRequest struct {
-	Location string `form:"location" json:"location"` // 区域
+	AccountID      string `form:"accountId" json:"accountId"`           // 账户ID，为空时返回用户所有账户的规格
+	SubscriptionID string `form:"subscriptionId" json:"subscriptionId"` // 订阅ID，指定时排除对该订阅受限的规格
+	Location       string `form:"location" json:"location"`             // 区域
 }
 
 // ListVmSizesResponse 获取规格列表响应
@@ -64,7 +79,27 @@ func ToVmSizeInfo(size *model.VmSize) *VmSizeInfo {
 		LastSyncAt:   size.LastSyncAt,
 		CreatedAt:    size.CreatedAt,
 		UpdatedAt:    size.UpdatedAt,
+
+		QuotaFamily:           size.QuotaFamily,
+		Zones:                 splitSizeList(size.Zones),
+		AcceleratedNetworking: size.AcceleratedNetworking,
+		PremiumIO:             size.PremiumIO,
+		HyperVGenerations:     splitSizeList(size.HyperVGenerations),
+
+		PricePerHour:            size.PricePerHour,
+		WindowsPricePerHour:     size.WindowsPricePerHour,
+		SpotPricePerHour:        size.SpotPricePerHour,
+		WindowsSpotPricePerHour: size.WindowsSpotPricePerHour,
+		Currency:                size.Currency,
+	}
+}
+
+// splitSizeList 拆分数据库中逗号分隔的字段，空字符串返回空列表
+func splitSizeList(value string) []string {
+	if value == "" {
+		return []string{}
 	}
+	return strings.Split(value, ",")
 }
 
 // ToListVmSizesResponse 将数据库结果转换为列表响应
@@ -0,0 +1,13 @@
+package v1
+
+// CreateSnapshotRequest 创建快照请求
+type CreateSnapshotRequest struct {
+	DiskName  string `json:"diskName" example:"my-vm-data-1"`                         // 数据磁盘名称，为空时为系统盘创建快照
+	Name      string `json:"name" example:"my-vm-before-upgrade"`                     // 快照名称，为空时自动生成
+	Retention int    `json:"retention" binding:"omitempty,min=1,max=100" example:"7"` // 虚拟机最多保留的快照数量，超出时删除最旧的快照，默认 7
+}
+
+// ListSnapshotsRequest 查询快照请求
+type ListSnapshotsRequest struct {
+	Refresh bool `form:"refresh"` // 是否先从Azure同步快照列表
+}
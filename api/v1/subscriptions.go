@@ -1,10 +1,17 @@
 package v1
 
+import (
+	"azure-vm-backend/internal/model"
+	"azure-vm-backend/pkg/app"
+)
+
 // ListSubscriptionsRequest 定义请求体结构
 type ListSubscriptionsRequest struct {
 	Page     int    `json:"page"`
 	PageSize int    `json:"pageSize"`
 	Search   string `json:"search"`
+	// OfferType 按优惠类型过滤，FreeTrial、Students、PayAsYouGo、MSDN、Sponsored、EA/CSP 或 Other
+	OfferType string `json:"offerType"`
 }
 
 // SyncRequest represents the request body for bulk subscription sync
@@ -29,3 +36,51 @@ type BulkSyncResponse struct {
 		TotalSynced   int `json:"totalSynced"`
 	} `json:"summary"`
 }
+
+// ListStateChangesReq 查询订阅状态变化记录请求参数
+type ListStateChangesReq struct {
+	Page           int    `form:"page" json:"page"`
+	PageSize       int    `form:"pageSize" json:"pageSize"`
+	SubscriptionID string `form:"subscriptionId" json:"subscriptionId"` // 为空时返回账户下全部记录
+}
+
+// StateChangeInfo 订阅状态变化记录，SubscriptionID 为空表示账户订阅状态的变化
+type StateChangeInfo struct {
+	ID             uint   `json:"id"`
+	AccountID      string `json:"accountId"`
+	SubscriptionID string `json:"subscriptionId,omitempty"`
+	FromState      string `json:"fromState"`
+	ToState        string `json:"toState"`
+	ChangedAt      string `json:"changedAt"`
+}
+
+// StateChangeListResp 订阅状态变化记录列表响应
+type StateChangeListResp struct {
+	Items      []*StateChangeInfo `json:"items"`
+	Page       int                `json:"page"`
+	PageSize   int                `json:"pageSize"`
+	Total      int64              `json:"total"`
+	TotalPages int                `json:"totalPages"`
+}
+
+// ToStateChangeListResp 转换为订阅状态变化记录列表响应
+func ToStateChangeListResp(result *app.ListResult[*model.SubscriptionStateChange]) *StateChangeListResp {
+	items := make([]*StateChangeInfo, 0, len(result.Items))
+	for _, change := range result.Items {
+		items = append(items, &StateChangeInfo{
+			ID:             change.ID,
+			AccountID:      change.AccountID,
+			SubscriptionID: change.SubscriptionID,
+			FromState:      change.FromState,
+			ToState:        change.ToState,
+			ChangedAt:      change.ChangedAt.Format("2006-01-02 15:04:05"),
+		})
+	}
+	return &StateChangeListResp{
+		Items:      items,
+		Page:       result.Page,
+		PageSize:   result.PageSize,
+		Total:      result.Total,
+		TotalPages: result.TotalPages,
+	}
+}
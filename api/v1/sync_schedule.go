@@ -0,0 +1,121 @@
+package v1
+
+import (
+	"azure-vm-backend/internal/model"
+	"azure-vm-backend/pkg/app"
+	"encoding/json"
+)
+
+// CreateSyncScheduleReq 创建定时同步请求参数
+type CreateSyncScheduleReq struct {
+	Name       string   `json:"name"`                                // 名称
+	CronExpr   string   `json:"cronExpr" binding:"required"`         // 标准5段cron表达式，UTC时间
+	AccountIDs []string `json:"accountIds" binding:"required,min=1"` // 要同步的账户ID列表
+	Enabled    *bool    `json:"enabled"`                             // 是否启用，默认启用
+}
+
+// UpdateSyncScheduleReq 更新定时同步请求参数，未传的字段保持不变
+type UpdateSyncScheduleReq struct {
+	Name       *string  `json:"name,omitempty"`
+	CronExpr   *string  `json:"cronExpr,omitempty"`
+	AccountIDs []string `json:"accountIds,omitempty"`
+	Enabled    *bool    `json:"enabled,omitempty"`
+}
+
+// ListSyncScheduleRunsReq 查询执行记录请求参数
+type ListSyncScheduleRunsReq struct {
+	Page     int `form:"page" json:"page"`
+	PageSize int `form:"pageSize" json:"pageSize"`
+}
+
+// SyncScheduleInfo 定时同步信息
+type SyncScheduleInfo struct {
+	ScheduleID string   `json:"scheduleId"`
+	Name       string   `json:"name"`
+	CronExpr   string   `json:"cronExpr"`
+	AccountIDs []string `json:"accountIds"`
+	Enabled    bool     `json:"enabled"`
+	LastRunAt  string   `json:"lastRunAt,omitempty"`  // 最近执行时间
+	LastStatus string   `json:"lastStatus,omitempty"` // 最近执行状态
+	CreatedAt  string   `json:"createdAt"`
+	UpdatedAt  string   `json:"updatedAt"`
+}
+
+// SyncScheduleRunInfo 定时同步执行记录
+type SyncScheduleRunInfo struct {
+	ID           uint             `json:"id"`
+	ScheduleID   string           `json:"scheduleId"`
+	Status       string           `json:"status"` // running/succeeded/partial/failed
+	SuccessCount int              `json:"successCount"`
+	FailedCount  int              `json:"failedCount"`
+	Result       *SyncAccountResp `json:"result,omitempty"`
+	Error        string           `json:"error,omitempty"`
+	StartedAt    string           `json:"startedAt"`
+	FinishedAt   string           `json:"finishedAt,omitempty"`
+}
+
+// SyncScheduleRunListResp 执行记录列表响应
+type SyncScheduleRunListResp struct {
+	Items      []*SyncScheduleRunInfo `json:"items"`
+	Page       int                    `json:"page"`
+	PageSize   int                    `json:"pageSize"`
+	Total      int64                  `json:"total"`
+	TotalPages int                    `json:"totalPages"`
+}
+
+// ToSyncScheduleInfo 将数据库模型转换为API响应模型
+func ToSyncScheduleInfo(schedule *model.SyncSchedule) *SyncScheduleInfo {
+	info := &SyncScheduleInfo{
+		ScheduleID: schedule.ScheduleID,
+		Name:       schedule.Name,
+		CronExpr:   schedule.CronExpr,
+		AccountIDs: []string{},
+		Enabled:    schedule.Enabled,
+		LastStatus: schedule.LastStatus,
+		CreatedAt:  schedule.CreatedAt.Format("2006-01-02 15:04:05"),
+		UpdatedAt:  schedule.UpdatedAt.Format("2006-01-02 15:04:05"),
+	}
+	_ = json.Unmarshal([]byte(schedule.AccountIDs), &info.AccountIDs)
+	if schedule.LastRunAt != nil {
+		info.LastRunAt = schedule.LastRunAt.Format("2006-01-02 15:04:05")
+	}
+	return info
+}
+
+// ToSyncScheduleRunInfo 将执行记录转换为API响应模型
+func ToSyncScheduleRunInfo(run *model.SyncScheduleRun) *SyncScheduleRunInfo {
+	info := &SyncScheduleRunInfo{
+		ID:           run.ID,
+		ScheduleID:   run.ScheduleID,
+		Status:       run.Status,
+		SuccessCount: run.SuccessCount,
+		FailedCount:  run.FailedCount,
+		Error:        run.Error,
+		StartedAt:    run.StartedAt.Format("2006-01-02 15:04:05"),
+	}
+	if run.Result != "" {
+		var result SyncAccountResp
+		if err := json.Unmarshal([]byte(run.Result), &result); err == nil {
+			info.Result = &result
+		}
+	}
+	if run.FinishedAt != nil {
+		info.FinishedAt = run.FinishedAt.Format("2006-01-02 15:04:05")
+	}
+	return info
+}
+
+// ToSyncScheduleRunListResp 转换为执行记录列表响应
+func ToSyncScheduleRunListResp(result *app.ListResult[*model.SyncScheduleRun]) *SyncScheduleRunListResp {
+	items := make([]*SyncScheduleRunInfo, 0, len(result.Items))
+	for _, run := range result.Items {
+		items = append(items, ToSyncScheduleRunInfo(run))
+	}
+	return &SyncScheduleRunListResp{
+		Items:      items,
+		Page:       result.Page,
+		PageSize:   result.PageSize,
+		Total:      result.Total,
+		TotalPages: result.TotalPages,
+	}
+}
@@ -9,6 +9,7 @@ import (
 type Response struct {
 	Code    int         `json:"code"`
 	Message string      `json:"message"`
+	Detail  string      `json:"detail,omitempty"` // 可选的详细信息，例如Azure返回的错误说明
 	Data    interface{} `json:"data"`
 }
 
@@ -16,36 +17,90 @@ func HandleSuccess(ctx *gin.Context, data interface{}) {
 	if data == nil {
 		data = map[string]interface{}{}
 	}
-	resp := Response{Code: errorCodeMap[ErrSuccess], Message: ErrSuccess.Error(), Data: data}
-	if _, ok := errorCodeMap[ErrSuccess]; !ok {
-		resp = Response{Code: 0, Message: "", Data: data}
-	}
+	resp := Response{Code: ErrSuccess.Code, Message: ErrSuccess.Message, Data: data}
 	ctx.JSON(http.StatusOK, resp)
 }
 
+// HandleError 返回错误响应
+// err 链中包含 *Error 时使用其业务码和HTTP状态码，包含 Azure 错误时先转换为对应的业务错误，
+// 其余错误使用 httpCode 并返回 unknown error
 func HandleError(ctx *gin.Context, httpCode int, err error, data interface{}) {
 	if data == nil {
 		data = map[string]string{}
 	}
-	resp := Response{Code: errorCodeMap[err], Message: err.Error(), Data: data}
-	if _, ok := errorCodeMap[err]; !ok {
-		resp = Response{Code: 500, Message: "unknown error", Data: data}
+	apiErr := ResolveError(err)
+	if err != nil {
+		// 原始错误只记录在服务端日志中，见 middleware.ResponseLogMiddleware
+		_ = ctx.Error(err)
+		if apiErr != nil && apiErr.cause != nil {
+			_ = ctx.Error(apiErr.cause)
+		}
+	}
+	if apiErr == nil {
+		ctx.JSON(httpCode, Response{Code: 500, Message: "unknown error", Data: data})
+		return
+	}
+	if apiErr.HTTPStatus != 0 {
+		httpCode = apiErr.HTTPStatus
 	}
-	ctx.JSON(httpCode, resp)
+	ctx.JSON(httpCode, Response{Code: apiErr.Code, Message: apiErr.Message, Detail: apiErr.Detail, Data: data})
 }
 
-type Error struct {
-	Code    int
-	Message string
+// ResolveError 从错误链中解析业务错误，无法识别时返回 nil
+func ResolveError(err error) *Error {
+	if err == nil {
+		return nil
+	}
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		return apiErr
+	}
+	if isAzureError(err) && errors.As(FromAzureError(err), &apiErr) {
+		return apiErr
+	}
+	return nil
 }
 
-var errorCodeMap = map[error]int{}
+// Error 业务错误，携带业务码、HTTP状态码和可选的详细信息
+// 经 fmt.Errorf("%w") 包装后仍可通过 errors.Is/errors.As 识别，errors.Is 按业务码比较
+type Error struct {
+	Code       int
+	HTTPStatus int
+	Message    string
+	Detail     string
+	cause      error
+}
 
-func newError(code int, msg string) error {
-	err := errors.New(msg)
-	errorCodeMap[err] = code
-	return err
+func newError(code, httpStatus int, msg string) *Error {
+	return &Error{Code: code, HTTPStatus: httpStatus, Message: msg}
 }
-func (e Error) Error() string {
+
+func (e *Error) Error() string {
+	if e.Detail != "" {
+		return e.Message + ": " + e.Detail
+	}
 	return e.Message
 }
+
+func (e *Error) Unwrap() error {
+	return e.cause
+}
+
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+// WithDetail 返回附带详细信息的副本，预定义错误本身不变
+func (e *Error) WithDetail(detail string) *Error {
+	c := *e
+	c.Detail = detail
+	return &c
+}
+
+// Wrap 返回保留原始错误的副本，便于日志和 errors.As 获取底层错误
+func (e *Error) Wrap(cause error) *Error {
+	c := *e
+	c.cause = cause
+	return &c
+}
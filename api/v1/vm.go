@@ -4,18 +4,31 @@ import "time"
 
 // VMCreateParams 创建虚拟机的参数
 type VMCreateParams struct {
-	SubscriptionID string            `json:"subscriptionId"`
-	ResourceGroup  string            `json:"resourceGroup"`
-	Name           string            `json:"name"`
-	Location       string            `json:"location"`
-	Size           string            `json:"size"`
+	SubscriptionID string            `json:"subscriptionId" binding:"required"`
+	ResourceGroup  string            `json:"resourceGroup" binding:"required"`
+	Name           string            `json:"name" binding:"required"`
+	Location       string            `json:"location" binding:"required"`
+	Size           string            `json:"size" binding:"required"`
 	OSType         string            `json:"osType"`
 	OSDiskSize     int               `json:"osDiskSize"`
+	OSDiskType     string            `json:"osDiskType"` // 系统盘类型，默认 StandardSSD_LRS
+	Image          VMImageParams     `json:"image"`
+	AdminUsername  string            `json:"adminUsername" binding:"required"`
+	AdminPassword  string            `json:"adminPassword"` // 与 SSHPublicKey 至少提供一个
+	SSHPublicKey   string            `json:"sshPublicKey"`  // 仅 Linux 生效
 	DataDisks      []VMDiskParams    `json:"dataDisks"`
 	NetworkConfig  VMNetworkParams   `json:"networkConfig"`
 	Tags           map[string]string `json:"tags"`
 }
 
+// VMImageParams 虚拟机镜像参数
+type VMImageParams struct {
+	Publisher string `json:"publisher" binding:"required"`
+	Offer     string `json:"offer" binding:"required"`
+	Sku       string `json:"sku" binding:"required"`
+	Version   string `json:"version"` // 为空时使用 latest
+}
+
 // VMDiskParams 虚拟机磁盘参数
 type VMDiskParams struct {
 	Name     string `json:"name"`
@@ -27,6 +40,8 @@ type VMDiskParams struct {
 type VMNetworkParams struct {
 	VNetName       string   `json:"vnetName"`
 	SubnetName     string   `json:"subnetName"`
+	AddressPrefix  string   `json:"addressPrefix"` // 新建VNet时的地址空间，默认 10.0.0.0/16
+	SubnetPrefix   string   `json:"subnetPrefix"`  // 新建子网时的地址段，默认 10.0.0.0/24
 	PublicIP       bool     `json:"publicIp"`
 	SecurityGroups []string `json:"securityGroups"`
 }
@@ -64,6 +79,19 @@ type SyncStats struct {
 	TotalVMs   int `json:"totalVMs"`   // 同步的总虚拟机数量
 	RunningVMs int `json:"runningVMs"` // 运行中的虚拟机数量
 	StoppedVMs int `json:"stoppedVMs"` // 已停止的虚拟机数量
+	MissingVMs int `json:"missingVMs"` // Azure 中已不存在而被移除的虚拟机数量
+
+	FailedSubscriptions int                      `json:"failedSubscriptions"` // 同步失败的订阅数量
+	Subscriptions       []SubscriptionSyncResult `json:"subscriptions"`       // 每个订阅的同步结果
+}
+
+// SubscriptionSyncResult 单个订阅的虚拟机同步结果
+type SubscriptionSyncResult struct {
+	SubscriptionID string `json:"subscriptionId"` // 订阅ID
+	DisplayName    string `json:"displayName"`    // 订阅名称
+	Success        bool   `json:"success"`        // 是否同步成功
+	Message        string `json:"message"`        // 失败原因
+	VMCount        int    `json:"vmCount"`        // 同步的虚拟机数量，为0不代表失败
 }
 
 type UpdateDNSLabelRequest struct {
@@ -77,10 +105,53 @@ const (
 	VMOperationStop    VMOperationType = "stop"
 	VMOperationRestart VMOperationType = "restart"
 	VMOperationDelete  VMOperationType = "delete"
+	// VMOperationChangeIP 更换公网IP，分配新IP后删除原IP并迁移DNS标签
+	VMOperationChangeIP VMOperationType = "change-ip"
+	// VMOperationResize 调整规格，目标规格不在当前硬件集群时会先释放再启动
+	VMOperationResize VMOperationType = "resize"
+	// VMOperationAttachDisk 创建并挂载数据磁盘
+	VMOperationAttachDisk VMOperationType = "attach-disk"
+	// VMOperationDetachDisk 卸载数据磁盘
+	VMOperationDetachDisk VMOperationType = "detach-disk"
+	// VMOperationExpandDisk 扩容系统盘或数据磁盘
+	VMOperationExpandDisk VMOperationType = "expand-disk"
+	// VMOperationSnapshot 为系统盘或数据磁盘创建快照
+	VMOperationSnapshot VMOperationType = "snapshot"
+	// VMOperationRestoreSnapshot 从快照恢复系统盘
+	VMOperationRestoreSnapshot VMOperationType = "restore-snapshot"
+	// VMOperationRunCommand 通过 Run Command 在虚拟机内执行脚本
+	VMOperationRunCommand VMOperationType = "run-command"
+	// VMOperationResetAccess 通过 VMAccess 扩展重置管理员密码、SSH公钥或sshd配置
+	VMOperationResetAccess VMOperationType = "reset-access"
 )
 
 // VMOperationRequest VM操作请求
 type VMOperationRequest struct {
-	Operation VMOperationType `json:"operation" binding:"required" example:"start"` // 操作类型
-	Force     bool            `json:"force" example:"false"`                        // 是否强制执行
+	Operation   VMOperationType `json:"operation" binding:"required" example:"start"` // 操作类型
+	Force       bool            `json:"force" example:"false"`                        // 是否强制执行
+	PublicIPSku string          `json:"publicIpSku" example:"Standard"`               // change-ip: 新公网IP规格(Standard/Basic)，默认沿用原规格
+	Size        string          `json:"size" example:"Standard_B2s"`                  // resize: 目标规格
+}
+
+// AttachDataDiskRequest 创建并挂载数据磁盘请求
+type AttachDataDiskRequest struct {
+	Name               string `json:"name" example:"my-vm-data-1"`                             // 磁盘名称，为空时自动生成
+	SizeGB             int32  `json:"sizeGB" binding:"required,min=1,max=32767" example:"128"` // 磁盘大小(GB)
+	Lun                *int32 `json:"lun" binding:"omitempty,min=0,max=63" example:"0"`        // LUN，为空时使用最小的空闲LUN
+	StorageAccountType string `json:"storageAccountType" example:"Premium_LRS"`                // 磁盘类型，默认 StandardSSD_LRS
+}
+
+// ExpandDiskRequest 磁盘扩容请求
+type ExpandDiskRequest struct {
+	SizeGB int32 `json:"sizeGB" binding:"required,min=1,max=32767" example:"256"` // 扩容后的大小(GB)，只能增加
+}
+
+// VMResizeOption 虚拟机可调整到的规格
+type VMResizeOption struct {
+	Name         string  `json:"name"`
+	Cores        int     `json:"cores"`
+	MemoryGB     float64 `json:"memoryGB"`
+	MaxDataDisks int     `json:"maxDataDisks"`
+	// RequiresDeallocation 目标规格不在当前硬件集群，调整时需要释放虚拟机
+	RequiresDeallocation bool `json:"requiresDeallocation"`
 }
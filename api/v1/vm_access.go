@@ -0,0 +1,10 @@
+package v1
+
+// ResetVMAccessRequest 重置虚拟机访问请求
+// Linux 可以重置密码、替换SSH公钥或修复sshd配置，至少指定一项；Windows 只能重置密码，用户名和密码必填
+type ResetVMAccessRequest struct {
+	Username     string `json:"username" binding:"max=64" example:"azureuser"` // 管理员用户名，用户不存在时会创建
+	Password     string `json:"password" binding:"max=123"`                    // 新密码
+	SSHPublicKey string `json:"sshPublicKey" example:"ssh-rsa AAAA..."`        // 替换的SSH公钥，仅 Linux
+	ResetSSH     bool   `json:"resetSsh"`                                      // 将sshd配置恢复为默认值，仅 Linux
+}
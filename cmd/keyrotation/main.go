@@ -0,0 +1,26 @@
+package main
+
+import (
+	"azure-vm-backend/cmd/keyrotation/wire"
+	"azure-vm-backend/pkg/config"
+	"azure-vm-backend/pkg/log"
+	"context"
+	"flag"
+)
+
+func main() {
+	var envConf = flag.String("conf", "config/local.yml", "config path, eg: -conf ./config/local.yml")
+	flag.Parse()
+	conf := config.NewConfig(*envConf)
+
+	logger := log.NewLog(conf)
+
+	app, cleanup, err := wire.NewWire(conf, logger)
+	defer cleanup()
+	if err != nil {
+		panic(err)
+	}
+	if err = app.Run(context.Background()); err != nil {
+		panic(err)
+	}
+}
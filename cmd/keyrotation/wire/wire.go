@@ -0,0 +1,40 @@
+//go:build wireinject
+// +build wireinject
+
+package wire
+
+import (
+	"azure-vm-backend/internal/repository"
+	"azure-vm-backend/internal/server"
+	"azure-vm-backend/pkg/app"
+	"azure-vm-backend/pkg/log"
+	"azure-vm-backend/pkg/secret"
+	"github.com/google/wire"
+	"github.com/spf13/viper"
+)
+
+var repositorySet = wire.NewSet(
+	repository.NewDB,
+)
+var serverSet = wire.NewSet(
+	server.NewKeyRotation,
+)
+
+// build App
+func newApp(
+	keyRotation *server.KeyRotation,
+) *app.App {
+	return app.NewApp(
+		app.WithServer(keyRotation),
+		app.WithName("azure-key-rotation"),
+	)
+}
+
+func NewWire(*viper.Viper, *log.Logger) (*app.App, func(), error) {
+	panic(wire.Build(
+		repositorySet,
+		serverSet,
+		secret.NewCipher,
+		newApp,
+	))
+}
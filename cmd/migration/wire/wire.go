@@ -8,6 +8,7 @@ import (
 	"azure-vm-backend/internal/server"
 	"azure-vm-backend/pkg/app"
 	"azure-vm-backend/pkg/log"
+	"azure-vm-backend/pkg/secret"
 	"github.com/google/wire"
 	"github.com/spf13/viper"
 )
@@ -36,6 +37,7 @@ func NewWire(*viper.Viper, *log.Logger) (*app.App, func(), error) {
 	panic(wire.Build(
 		repositorySet,
 		serverSet,
+		secret.NewCipher,
 		newApp,
 	))
 }
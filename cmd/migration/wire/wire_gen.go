@@ -11,6 +11,7 @@ import (
 	"azure-vm-backend/internal/server"
 	"azure-vm-backend/pkg/app"
 	"azure-vm-backend/pkg/log"
+	"azure-vm-backend/pkg/secret"
 	"github.com/google/wire"
 	"github.com/spf13/viper"
 )
@@ -19,7 +20,8 @@ import (
 
 func NewWire(viperViper *viper.Viper, logger *log.Logger) (*app.App, func(), error) {
 	db := repository.NewDB(viperViper, logger)
-	migrate := server.NewMigrate(db, logger)
+	cipher := secret.NewCipher(viperViper)
+	migrate := server.NewMigrate(db, logger, cipher)
 	appApp := newApp(migrate)
 	return appApp, func() {
 	}, nil
@@ -9,8 +9,10 @@ import (
 	"azure-vm-backend/internal/server"
 	"azure-vm-backend/internal/service"
 	"azure-vm-backend/pkg/app"
+	"azure-vm-backend/pkg/azure"
 	"azure-vm-backend/pkg/jwt"
 	"azure-vm-backend/pkg/log"
+	"azure-vm-backend/pkg/secret"
 	"azure-vm-backend/pkg/server/http"
 	"azure-vm-backend/pkg/sid"
 
@@ -30,6 +32,14 @@ var repositorySet = wire.NewSet(
 	repository.NewVmRegionRepository,
 	repository.NewVmImageRepository,
 	repository.NewVmSizeRepository,
+	repository.NewOperationRepository,
+	repository.NewSyncScheduleRepository,
+	repository.NewVmSnapshotRepository,
+	repository.NewVmRunCommandRepository,
+	repository.NewSubscriptionQuotaRepository,
+	repository.NewVmSizePriceRepository,
+	repository.NewCostRecordRepository,
+	repository.NewSubscriptionStateChangeRepository,
 )
 
 var serviceSet = wire.NewSet(
@@ -41,6 +51,14 @@ var serviceSet = wire.NewSet(
 	service.NewVmRegionService,
 	service.NewVmImageService,
 	service.NewVmSizeService,
+	service.NewOperationService,
+	service.NewSyncScheduleService,
+	service.NewVmSnapshotService,
+	service.NewVmRunCommandService,
+	service.NewSubscriptionQuotaService,
+	service.NewVmPriceService,
+	service.NewVmCostService,
+	service.NewSpendService,
 )
 
 var handlerSet = wire.NewSet(
@@ -52,6 +70,13 @@ var handlerSet = wire.NewSet(
 	handler.NewVmRegionHandler,
 	handler.NewVmImageHandler,
 	handler.NewVmSizeHandler,
+	handler.NewOperationHandler,
+	handler.NewSyncScheduleHandler,
+	handler.NewVmSnapshotHandler,
+	handler.NewVmRunCommandHandler,
+	handler.NewSubscriptionQuotaHandler,
+	handler.NewVmCostHandler,
+	handler.NewSpendHandler,
 )
 
 var serverSet = wire.NewSet(
@@ -80,6 +105,8 @@ func NewWire(*viper.Viper, *log.Logger) (*app.App, func(), error) {
 		serverSet,
 		sid.NewSid,
 		jwt.NewJwt,
+		secret.NewCipher,
+		azure.NewProvider,
 		newApp,
 	))
 }
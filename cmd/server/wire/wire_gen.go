@@ -12,8 +12,10 @@ import (
 	"azure-vm-backend/internal/server"
 	"azure-vm-backend/internal/service"
 	"azure-vm-backend/pkg/app"
+	"azure-vm-backend/pkg/azure"
 	"azure-vm-backend/pkg/jwt"
 	"azure-vm-backend/pkg/log"
+	"azure-vm-backend/pkg/secret"
 	"azure-vm-backend/pkg/server/http"
 	"azure-vm-backend/pkg/sid"
 	"github.com/google/wire"
@@ -29,27 +31,53 @@ func NewWire(viperViper *viper.Viper, logger *log.Logger) (*app.App, func(), err
 	repositoryRepository := repository.NewRepository(logger, db)
 	transaction := repository.NewTransaction(repositoryRepository)
 	sidSid := sid.NewSid()
-	serviceService := service.NewService(transaction, logger, sidSid, jwtJWT)
+	cipher := secret.NewCipher(viperViper)
+	serviceService := service.NewService(transaction, logger, sidSid, jwtJWT, cipher)
 	userRepository := repository.NewUserRepository(repositoryRepository)
 	userService := service.NewUserService(serviceService, userRepository)
 	userHandler := handler.NewUserHandler(handlerHandler, userService)
 	accountsRepository := repository.NewAccountsRepository(repositoryRepository)
 	subscriptionsRepository := repository.NewSubscriptionsRepository(repositoryRepository)
-	subscriptionsService := service.NewSubscriptionsService(serviceService, subscriptionsRepository, accountsRepository)
+	provider := azure.NewProvider()
+	subscriptionStateChangeRepository := repository.NewSubscriptionStateChangeRepository(repositoryRepository)
+	subscriptionsService := service.NewSubscriptionsService(serviceService, subscriptionsRepository, accountsRepository, subscriptionStateChangeRepository, provider)
 	virtualMachineRepository := repository.NewVirtualMachineRepository(repositoryRepository)
-	virtualMachineService := service.NewVirtualMachineService(serviceService, virtualMachineRepository, accountsRepository, subscriptionsRepository, logger)
-	accountsService := service.NewAccountsService(serviceService, accountsRepository, subscriptionsService, virtualMachineService)
-	accountsHandler := handler.NewAccountsHandler(handlerHandler, accountsService)
+	operationRepository := repository.NewOperationRepository(repositoryRepository)
+	vmSizeRepository := repository.NewVmSizeRepository(repositoryRepository)
+	subscriptionQuotaRepository := repository.NewSubscriptionQuotaRepository(repositoryRepository)
+	subscriptionQuotaService := service.NewSubscriptionQuotaService(serviceService, subscriptionQuotaRepository, accountsRepository, subscriptionsRepository, provider)
+	vmSizePriceRepository := repository.NewVmSizePriceRepository(repositoryRepository)
+	vmPriceService := service.NewVmPriceService(serviceService, vmSizePriceRepository, provider, viperViper)
+	vmCostService := service.NewVmCostService(serviceService, accountsRepository, virtualMachineRepository, vmSizeRepository, vmPriceService)
+	operationService := service.NewOperationService(serviceService, operationRepository, virtualMachineRepository, accountsRepository, vmSizeRepository, subscriptionQuotaService, vmCostService, provider, logger)
+	virtualMachineService := service.NewVirtualMachineService(serviceService, virtualMachineRepository, accountsRepository, subscriptionsRepository, vmSizeRepository, operationService, subscriptionQuotaService, vmCostService, provider, logger)
+	accountsService := service.NewAccountsService(serviceService, accountsRepository, subscriptionsService, virtualMachineService, provider)
+	accountsHandler := handler.NewAccountsHandler(handlerHandler, accountsService, vmCostService)
 	subscriptionsHandler := handler.NewSubscriptionsHandler(handlerHandler, subscriptionsService)
 	virtualMachineHandler := handler.NewVirtualMachineHandler(handlerHandler, virtualMachineService)
 	vmRegionRepository := repository.NewVmRegionRepository(repositoryRepository)
-	vmRegionService := service.NewVmRegionService(serviceService, vmRegionRepository)
+	vmRegionService := service.NewVmRegionService(serviceService, vmRegionRepository, provider)
 	vmRegionHandler := handler.NewVmRegionHandler(handlerHandler, vmRegionService)
 	vmImageRepository := repository.NewVmImageRepository(repositoryRepository)
-	vmImageService := service.NewVmImageService(serviceService, vmImageRepository, accountsRepository, subscriptionsRepository)
+	vmImageService := service.NewVmImageService(serviceService, vmImageRepository, accountsRepository, subscriptionsRepository, provider)
 	vmImageHandler := handler.NewVmImageHandler(handlerHandler, vmImageService)
-	httpServer := server.NewHTTPServer(logger, viperViper, jwtJWT, userHandler, accountsHandler, subscriptionsHandler, virtualMachineHandler, vmRegionHandler, vmImageHandler)
-	job := server.NewJob(logger)
+	operationHandler := handler.NewOperationHandler(handlerHandler, operationService)
+	syncScheduleRepository := repository.NewSyncScheduleRepository(repositoryRepository)
+	syncScheduleService := service.NewSyncScheduleService(serviceService, syncScheduleRepository, accountsRepository, accountsService)
+	syncScheduleHandler := handler.NewSyncScheduleHandler(handlerHandler, syncScheduleService)
+	vmSnapshotRepository := repository.NewVmSnapshotRepository(repositoryRepository)
+	vmSnapshotService := service.NewVmSnapshotService(serviceService, vmSnapshotRepository, virtualMachineRepository, accountsRepository, operationService, provider)
+	vmSnapshotHandler := handler.NewVmSnapshotHandler(handlerHandler, vmSnapshotService)
+	vmRunCommandRepository := repository.NewVmRunCommandRepository(repositoryRepository)
+	vmRunCommandService := service.NewVmRunCommandService(serviceService, vmRunCommandRepository, virtualMachineRepository, accountsRepository, operationService)
+	vmRunCommandHandler := handler.NewVmRunCommandHandler(handlerHandler, vmRunCommandService)
+	subscriptionQuotaHandler := handler.NewSubscriptionQuotaHandler(handlerHandler, subscriptionQuotaService)
+	vmCostHandler := handler.NewVmCostHandler(handlerHandler, vmCostService)
+	costRecordRepository := repository.NewCostRecordRepository(repositoryRepository)
+	spendService := service.NewSpendService(serviceService, costRecordRepository, accountsRepository, subscriptionsRepository, virtualMachineRepository, provider)
+	spendHandler := handler.NewSpendHandler(handlerHandler, spendService)
+	httpServer := server.NewHTTPServer(logger, viperViper, jwtJWT, userHandler, accountsHandler, subscriptionsHandler, virtualMachineHandler, vmRegionHandler, vmImageHandler, operationHandler, syncScheduleHandler, vmSnapshotHandler, vmRunCommandHandler, subscriptionQuotaHandler, vmCostHandler, spendHandler)
+	job := server.NewJob(logger, operationService, vmRunCommandService)
 	appApp := newApp(httpServer, job)
 	return appApp, func() {
 	}, nil
@@ -57,11 +85,11 @@ func NewWire(viperViper *viper.Viper, logger *log.Logger) (*app.App, func(), err
 
 // wire.go:
 
-var repositorySet = wire.NewSet(repository.NewDB, repository.NewRepository, repository.NewTransaction, repository.NewUserRepository, repository.NewAccountsRepository, repository.NewSubscriptionsRepository, repository.NewVirtualMachineRepository, repository.NewVmRegionRepository, repository.NewVmImageRepository, repository.NewVmSizeRepository)
+var repositorySet = wire.NewSet(repository.NewDB, repository.NewRepository, repository.NewTransaction, repository.NewUserRepository, repository.NewAccountsRepository, repository.NewSubscriptionsRepository, repository.NewVirtualMachineRepository, repository.NewVmRegionRepository, repository.NewVmImageRepository, repository.NewVmSizeRepository, repository.NewOperationRepository, repository.NewSyncScheduleRepository, repository.NewVmSnapshotRepository, repository.NewVmRunCommandRepository, repository.NewSubscriptionQuotaRepository, repository.NewVmSizePriceRepository, repository.NewCostRecordRepository, repository.NewSubscriptionStateChangeRepository)
 
-var serviceSet = wire.NewSet(service.NewService, service.NewUserService, service.NewAccountsService, service.NewSubscriptionsService, service.NewVirtualMachineService, service.NewVmRegionService, service.NewVmImageService, service.NewVmSizeService)
+var serviceSet = wire.NewSet(service.NewService, service.NewUserService, service.NewAccountsService, service.NewSubscriptionsService, service.NewVirtualMachineService, service.NewVmRegionService, service.NewVmImageService, service.NewVmSizeService, service.NewOperationService, service.NewSyncScheduleService, service.NewVmSnapshotService, service.NewVmRunCommandService, service.NewSubscriptionQuotaService, service.NewVmPriceService, service.NewVmCostService, service.NewSpendService)
 
-var handlerSet = wire.NewSet(handler.NewHandler, handler.NewUserHandler, handler.NewAccountsHandler, handler.NewSubscriptionsHandler, handler.NewVirtualMachineHandler, handler.NewVmRegionHandler, handler.NewVmImageHandler, handler.NewVmSizeHandler)
+var handlerSet = wire.NewSet(handler.NewHandler, handler.NewUserHandler, handler.NewAccountsHandler, handler.NewSubscriptionsHandler, handler.NewVirtualMachineHandler, handler.NewVmRegionHandler, handler.NewVmImageHandler, handler.NewVmSizeHandler, handler.NewOperationHandler, handler.NewSyncScheduleHandler, handler.NewVmSnapshotHandler, handler.NewVmRunCommandHandler, handler.NewSubscriptionQuotaHandler, handler.NewVmCostHandler, handler.NewSpendHandler)
 
 var serverSet = wire.NewSet(server.NewHTTPServer, server.NewJob, server.NewTask)
 
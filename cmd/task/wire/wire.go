@@ -8,8 +8,10 @@ import (
 	"azure-vm-backend/internal/server"
 	"azure-vm-backend/internal/service"
 	"azure-vm-backend/pkg/app"
+	"azure-vm-backend/pkg/azure"
 	"azure-vm-backend/pkg/jwt"
 	"azure-vm-backend/pkg/log"
+	"azure-vm-backend/pkg/secret"
 	"azure-vm-backend/pkg/sid"
 
 	"github.com/google/wire"
@@ -30,6 +32,14 @@ var repositorySet = wire.NewSet(
 	repository.NewVmRegionRepository,
 	repository.NewVmImageRepository,
 	repository.NewVmSizeRepository,
+	repository.NewOperationRepository,
+	repository.NewSyncScheduleRepository,
+	repository.NewVmSnapshotRepository,
+	repository.NewVmRunCommandRepository,
+	repository.NewSubscriptionQuotaRepository,
+	repository.NewVmSizePriceRepository,
+	repository.NewCostRecordRepository,
+	repository.NewSubscriptionStateChangeRepository,
 )
 
 var serviceSet = wire.NewSet(
@@ -41,6 +51,14 @@ var serviceSet = wire.NewSet(
 	service.NewVmRegionService,
 	service.NewVmImageService,
 	service.NewVmSizeService,
+	service.NewOperationService,
+	service.NewSyncScheduleService,
+	service.NewVmSnapshotService,
+	service.NewVmRunCommandService,
+	service.NewSubscriptionQuotaService,
+	service.NewVmPriceService,
+	service.NewVmCostService,
+	service.NewSpendService,
 )
 
 var serverSet = wire.NewSet(
@@ -65,5 +83,7 @@ func NewWire(*viper.Viper, *log.Logger) (*app.App, func(), error) {
 		newApp,
 		sid.NewSid,
 		jwt.NewJwt,
+		secret.NewCipher,
+		azure.NewProvider,
 	))
 }
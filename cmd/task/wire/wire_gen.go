@@ -11,8 +11,10 @@ import (
 	"azure-vm-backend/internal/server"
 	"azure-vm-backend/internal/service"
 	"azure-vm-backend/pkg/app"
+	"azure-vm-backend/pkg/azure"
 	"azure-vm-backend/pkg/jwt"
 	"azure-vm-backend/pkg/log"
+	"azure-vm-backend/pkg/secret"
 	"azure-vm-backend/pkg/sid"
 	"github.com/google/wire"
 	"github.com/spf13/viper"
@@ -26,14 +28,29 @@ func NewWire(viperViper *viper.Viper, logger *log.Logger) (*app.App, func(), err
 	transaction := repository.NewTransaction(repositoryRepository)
 	sidSid := sid.NewSid()
 	jwtJWT := jwt.NewJwt(viperViper)
-	serviceService := service.NewService(transaction, logger, sidSid, jwtJWT)
+	cipher := secret.NewCipher(viperViper)
+	serviceService := service.NewService(transaction, logger, sidSid, jwtJWT, cipher)
 	accountsRepository := repository.NewAccountsRepository(repositoryRepository)
 	subscriptionsRepository := repository.NewSubscriptionsRepository(repositoryRepository)
-	subscriptionsService := service.NewSubscriptionsService(serviceService, subscriptionsRepository, accountsRepository)
+	provider := azure.NewProvider()
+	subscriptionStateChangeRepository := repository.NewSubscriptionStateChangeRepository(repositoryRepository)
+	subscriptionsService := service.NewSubscriptionsService(serviceService, subscriptionsRepository, accountsRepository, subscriptionStateChangeRepository, provider)
 	virtualMachineRepository := repository.NewVirtualMachineRepository(repositoryRepository)
-	virtualMachineService := service.NewVirtualMachineService(serviceService, virtualMachineRepository, accountsRepository, subscriptionsRepository, logger)
-	accountsService := service.NewAccountsService(serviceService, accountsRepository, subscriptionsService, virtualMachineService)
-	task := server.NewTask(logger, accountsService)
+	operationRepository := repository.NewOperationRepository(repositoryRepository)
+	vmSizeRepository := repository.NewVmSizeRepository(repositoryRepository)
+	subscriptionQuotaRepository := repository.NewSubscriptionQuotaRepository(repositoryRepository)
+	subscriptionQuotaService := service.NewSubscriptionQuotaService(serviceService, subscriptionQuotaRepository, accountsRepository, subscriptionsRepository, provider)
+	vmSizePriceRepository := repository.NewVmSizePriceRepository(repositoryRepository)
+	vmPriceService := service.NewVmPriceService(serviceService, vmSizePriceRepository, provider, viperViper)
+	vmCostService := service.NewVmCostService(serviceService, accountsRepository, virtualMachineRepository, vmSizeRepository, vmPriceService)
+	operationService := service.NewOperationService(serviceService, operationRepository, virtualMachineRepository, accountsRepository, vmSizeRepository, subscriptionQuotaService, vmCostService, provider, logger)
+	virtualMachineService := service.NewVirtualMachineService(serviceService, virtualMachineRepository, accountsRepository, subscriptionsRepository, vmSizeRepository, operationService, subscriptionQuotaService, vmCostService, provider, logger)
+	accountsService := service.NewAccountsService(serviceService, accountsRepository, subscriptionsService, virtualMachineService, provider)
+	syncScheduleRepository := repository.NewSyncScheduleRepository(repositoryRepository)
+	syncScheduleService := service.NewSyncScheduleService(serviceService, syncScheduleRepository, accountsRepository, accountsService)
+	costRecordRepository := repository.NewCostRecordRepository(repositoryRepository)
+	spendService := service.NewSpendService(serviceService, costRecordRepository, accountsRepository, subscriptionsRepository, virtualMachineRepository, provider)
+	task := server.NewTask(logger, viperViper, syncScheduleService, vmPriceService, spendService, subscriptionsService)
 	appApp := newApp(task)
 	return appApp, func() {
 	}, nil
@@ -41,9 +58,9 @@ func NewWire(viperViper *viper.Viper, logger *log.Logger) (*app.App, func(), err
 
 // wire.go:
 
-var repositorySet = wire.NewSet(repository.NewDB, repository.NewRepository, repository.NewTransaction, repository.NewUserRepository, repository.NewAccountsRepository, repository.NewSubscriptionsRepository, repository.NewVirtualMachineRepository, repository.NewVmRegionRepository, repository.NewVmImageRepository, repository.NewVmSizeRepository)
+var repositorySet = wire.NewSet(repository.NewDB, repository.NewRepository, repository.NewTransaction, repository.NewUserRepository, repository.NewAccountsRepository, repository.NewSubscriptionsRepository, repository.NewVirtualMachineRepository, repository.NewVmRegionRepository, repository.NewVmImageRepository, repository.NewVmSizeRepository, repository.NewOperationRepository, repository.NewSyncScheduleRepository, repository.NewVmSnapshotRepository, repository.NewVmRunCommandRepository, repository.NewSubscriptionQuotaRepository, repository.NewVmSizePriceRepository, repository.NewCostRecordRepository, repository.NewSubscriptionStateChangeRepository)
 
-var serviceSet = wire.NewSet(service.NewService, service.NewUserService, service.NewAccountsService, service.NewSubscriptionsService, service.NewVirtualMachineService, service.NewVmRegionService, service.NewVmImageService, service.NewVmSizeService)
+var serviceSet = wire.NewSet(service.NewService, service.NewUserService, service.NewAccountsService, service.NewSubscriptionsService, service.NewVirtualMachineService, service.NewVmRegionService, service.NewVmImageService, service.NewVmSizeService, service.NewOperationService, service.NewSyncScheduleService, service.NewVmSnapshotService, service.NewVmRunCommandService, service.NewSubscriptionQuotaService, service.NewVmPriceService, service.NewVmCostService, service.NewSpendService)
 
 var serverSet = wire.NewSet(server.NewTask)
 
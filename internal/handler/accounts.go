@@ -11,22 +11,25 @@ import (
 type AccountsHandler struct {
 	*Handler
 	accountsService service.AccountsService
+	vmCostService   service.VmCostService
 }
 
 func NewAccountsHandler(
 	handler *Handler,
 	accountsService service.AccountsService,
+	vmCostService service.VmCostService,
 ) *AccountsHandler {
 	return &AccountsHandler{
 		Handler:         handler,
 		accountsService: accountsService,
+		vmCostService:   vmCostService,
 	}
 }
 
 // ListAccounts godoc
 // @Summary 获取账户列表
 // @Schemes
-// @Description 获取当前用户的所有Azure账户
+// @Description 获取当前用户的所有Azure账户，包含按账户和订阅汇总的虚拟机预计月费用
 // @Tags 账户模块
 // @Accept json
 // @Produce json
@@ -68,8 +71,19 @@ func (h *AccountsHandler) ListAccounts(ctx *gin.Context) {
 		return
 	}
 
+	// 汇总当前页账户的虚拟机预计月费用
+	accountIds := make([]string, 0, len(result.Items))
+	for _, account := range result.Items {
+		accountIds = append(accountIds, account.AccountID)
+	}
+	costs, err := h.vmCostService.GetAccountCosts(ctx, accountIds)
+	if err != nil {
+		v1.HandleError(ctx, http.StatusInternalServerError, err, nil)
+		return
+	}
+
 	// 转换并返回响应
-	resp := v1.ToAccountListResp(result)
+	resp := v1.ToAccountListResp(result, costs)
 	v1.HandleSuccess(ctx, resp)
 }
 
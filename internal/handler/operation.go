@@ -0,0 +1,58 @@
+package handler
+
+import (
+	v1 "azure-vm-backend/api/v1"
+	"azure-vm-backend/internal/service"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+type OperationHandler struct {
+	*Handler
+	operationService service.OperationService
+}
+
+func NewOperationHandler(
+	handler *Handler,
+	operationService service.OperationService,
+) *OperationHandler {
+	return &OperationHandler{
+		Handler:          handler,
+		operationService: operationService,
+	}
+}
+
+// GetOperation godoc
+// @Summary 查询异步操作
+// @Schemes
+// @Description 查询异步操作的进度、最终结果和错误信息
+// @Tags 操作模块
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path string true "操作ID"
+// @Success 200 {object} v1.Response
+// @Router /operations/{id} [get]
+func (h *OperationHandler) GetOperation(ctx *gin.Context) {
+	// 获取用户id
+	userId := GetUserIdFromCtx(ctx)
+	if userId == "" {
+		v1.HandleError(ctx, http.StatusUnauthorized, v1.ErrUnauthorized, nil)
+		return
+	}
+
+	// 获取操作ID
+	id := ctx.Param("id")
+	if id == "" {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	op, err := h.operationService.GetOperation(ctx, userId, id)
+	if err != nil {
+		v1.HandleError(ctx, http.StatusInternalServerError, err, nil)
+		return
+	}
+
+	v1.HandleSuccess(ctx, op)
+}
@@ -0,0 +1,155 @@
+package handler
+
+import (
+	v1 "azure-vm-backend/api/v1"
+	"azure-vm-backend/internal/service"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+type SpendHandler struct {
+	*Handler
+	spendService service.SpendService
+}
+
+func NewSpendHandler(
+	handler *Handler,
+	spendService service.SpendService,
+) *SpendHandler {
+	return &SpendHandler{
+		Handler:      handler,
+		spendService: spendService,
+	}
+}
+
+// SyncCosts godoc
+// @Summary 同步账户的实际费用
+// @Schemes
+// @Description 通过 Cost Management 查询账户下各订阅本月和最近30天的每日费用，并替换本地记录；部分订阅失败时在结果中列出
+// @Tags 费用模块
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param accountId path string true "账户ID"
+// @Success 200 {object} v1.Response
+// @Router /costs/{accountId}/sync [post]
+func (h *SpendHandler) SyncCosts(ctx *gin.Context) {
+	userId := GetUserIdFromCtx(ctx)
+	if userId == "" {
+		v1.HandleError(ctx, http.StatusUnauthorized, v1.ErrUnauthorized, nil)
+		return
+	}
+
+	accountId := ctx.Param("accountId")
+	if accountId == "" {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	result, err := h.spendService.SyncAccountCosts(ctx, userId, accountId)
+	if err != nil {
+		v1.HandleError(ctx, http.StatusInternalServerError, err, nil)
+		return
+	}
+	v1.HandleSuccess(ctx, result)
+}
+
+// GetAccountSpend godoc
+// @Summary 获取账户的实际费用
+// @Schemes
+// @Description 返回账户本月至今和最近30天的实际费用，按订阅细分，数据来自最近一次同步
+// @Tags 费用模块
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param accountId path string true "账户ID"
+// @Success 200 {object} v1.Response
+// @Router /costs/{accountId} [get]
+func (h *SpendHandler) GetAccountSpend(ctx *gin.Context) {
+	userId := GetUserIdFromCtx(ctx)
+	if userId == "" {
+		v1.HandleError(ctx, http.StatusUnauthorized, v1.ErrUnauthorized, nil)
+		return
+	}
+
+	accountId := ctx.Param("accountId")
+	if accountId == "" {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	spend, err := h.spendService.GetAccountSpend(ctx, userId, accountId)
+	if err != nil {
+		v1.HandleError(ctx, http.StatusInternalServerError, err, nil)
+		return
+	}
+	v1.HandleSuccess(ctx, spend)
+}
+
+// GetSubscriptionSpend godoc
+// @Summary 获取订阅的实际费用
+// @Schemes
+// @Description 返回订阅本月至今和最近30天的实际费用，按虚拟机细分
+// @Tags 费用模块
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param accountId path string true "账户ID"
+// @Param subscriptionId path string true "订阅ID"
+// @Success 200 {object} v1.Response
+// @Router /costs/{accountId}/subscription/{subscriptionId} [get]
+func (h *SpendHandler) GetSubscriptionSpend(ctx *gin.Context) {
+	userId := GetUserIdFromCtx(ctx)
+	if userId == "" {
+		v1.HandleError(ctx, http.StatusUnauthorized, v1.ErrUnauthorized, nil)
+		return
+	}
+
+	accountId := ctx.Param("accountId")
+	subscriptionId := ctx.Param("subscriptionId")
+	if accountId == "" || subscriptionId == "" {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	spend, err := h.spendService.GetSubscriptionSpend(ctx, userId, accountId, subscriptionId)
+	if err != nil {
+		v1.HandleError(ctx, http.StatusInternalServerError, err, nil)
+		return
+	}
+	v1.HandleSuccess(ctx, spend)
+}
+
+// GetVMSpend godoc
+// @Summary 获取虚拟机的实际费用
+// @Schemes
+// @Description 返回虚拟机本月至今和最近30天的实际费用，只包含虚拟机资源本身的计量
+// @Tags 虚拟机模块
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param accountId path string true "账户ID"
+// @Param id path string true "虚拟机ID"
+// @Success 200 {object} v1.Response
+// @Router /vms/{accountId}/{id}/cost [get]
+func (h *SpendHandler) GetVMSpend(ctx *gin.Context) {
+	userId := GetUserIdFromCtx(ctx)
+	if userId == "" {
+		v1.HandleError(ctx, http.StatusUnauthorized, v1.ErrUnauthorized, nil)
+		return
+	}
+
+	accountId := ctx.Param("accountId")
+	id := ctx.Param("id")
+	if accountId == "" || id == "" {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	spend, err := h.spendService.GetVMSpend(ctx, userId, accountId, id)
+	if err != nil {
+		v1.HandleError(ctx, http.StatusInternalServerError, err, nil)
+		return
+	}
+	v1.HandleSuccess(ctx, spend)
+}
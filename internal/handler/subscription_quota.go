@@ -0,0 +1,65 @@
+package handler
+
+import (
+	v1 "azure-vm-backend/api/v1"
+	"azure-vm-backend/internal/service"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+type SubscriptionQuotaHandler struct {
+	*Handler
+	subscriptionQuotaService service.SubscriptionQuotaService
+}
+
+func NewSubscriptionQuotaHandler(
+	handler *Handler,
+	subscriptionQuotaService service.SubscriptionQuotaService,
+) *SubscriptionQuotaHandler {
+	return &SubscriptionQuotaHandler{
+		Handler:                  handler,
+		subscriptionQuotaService: subscriptionQuotaService,
+	}
+}
+
+// ListQuotas godoc
+// @Summary 获取订阅的vCPU配额
+// @Schemes
+// @Description 按区域返回区域总vCPU、Spot vCPU和各规格系列的已用量与限额；指定区域且没有记录或 refresh=true 时先从Azure获取
+// @Tags 订阅模块
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param accountId path string true "账户ID"
+// @Param subscriptionId path string true "订阅ID"
+// @Param location query string false "区域"
+// @Param refresh query bool false "是否先从Azure获取最新配额"
+// @Success 200 {object} v1.Response
+// @Router /subscriptions/{accountId}/{subscriptionId}/quotas [get]
+func (h *SubscriptionQuotaHandler) ListQuotas(ctx *gin.Context) {
+	userId := GetUserIdFromCtx(ctx)
+	if userId == "" {
+		v1.HandleError(ctx, http.StatusUnauthorized, v1.ErrUnauthorized, nil)
+		return
+	}
+
+	accountId := ctx.Param("accountId")
+	subscriptionId := ctx.Param("subscriptionId")
+	if accountId == "" || subscriptionId == "" {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	var req v1.ListQuotasRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	quotas, err := h.subscriptionQuotaService.ListQuotas(ctx, userId, accountId, subscriptionId, &req)
+	if err != nil {
+		v1.HandleError(ctx, http.StatusInternalServerError, err, nil)
+		return
+	}
+	v1.HandleSuccess(ctx, quotas)
+}
@@ -4,6 +4,7 @@ import (
 	v1 "azure-vm-backend/api/v1"
 	"azure-vm-backend/internal/service"
 	"azure-vm-backend/pkg/app"
+	"azure-vm-backend/pkg/azure"
 	"github.com/gin-gonic/gin"
 	"net/http"
 )
@@ -135,6 +136,53 @@ func (h *SubscriptionsHandler) SyncSubscriptions(ctx *gin.Context) {
 	})
 }
 
+// ListStateChanges godoc
+// @Summary 获取订阅状态变化记录
+// @Schemes
+// @Description 分页获取指定账户下订阅状态的变化记录，按变化时间倒序
+// @Tags 订阅模块
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param accountId path string true "账户ID"
+// @Param subscriptionId query string false "订阅ID"
+// @Param page query int false "页码"
+// @Param pageSize query int false "每页数量"
+// @Success 200 {object} v1.Response
+// @Router /subscriptions/{accountId}/state-changes [get]
+func (h *SubscriptionsHandler) ListStateChanges(ctx *gin.Context) {
+	userId := GetUserIdFromCtx(ctx)
+	if userId == "" {
+		v1.HandleError(ctx, http.StatusUnauthorized, v1.ErrUnauthorized, nil)
+		return
+	}
+
+	accountId := ctx.Param("accountId")
+	if accountId == "" {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	var req v1.ListStateChangesReq
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	option := &app.QueryOption{
+		Pagination: app.Pagination{
+			Page:     req.Page,
+			PageSize: req.PageSize,
+		},
+	}
+	result, err := h.subscriptionsService.ListStateChanges(ctx, userId, accountId, req.SubscriptionID, option)
+	if err != nil {
+		v1.HandleError(ctx, http.StatusInternalServerError, err, nil)
+		return
+	}
+	v1.HandleSuccess(ctx, v1.ToStateChangeListResp(result))
+}
+
 // DeleteSubscriptions godoc
 // @Summary 删除账户订阅信息
 // @Schemes
@@ -176,7 +224,7 @@ func (h *SubscriptionsHandler) DeleteSubscriptions(ctx *gin.Context) {
 // ListSubscriptions godoc
 // @Summary 获取用户所有订阅列表
 // @Schemes
-// @Description 获取当前用户所有Azure账户下的订阅信息，支持分页和按显示名称搜索
+// @Description 获取当前用户所有Azure账户下的订阅信息，支持分页、按显示名称搜索和按优惠类型过滤
 // @Tags 订阅模块
 // @Accept json
 // @Produce json
@@ -212,6 +260,13 @@ func (h *SubscriptionsHandler) ListSubscriptions(ctx *gin.Context) {
 	if req.Search != "" {
 		query.Filters["search"] = req.Search
 	}
+	if req.OfferType != "" {
+		if !azure.IsOfferType(req.OfferType) {
+			v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+			return
+		}
+		query.Filters["offerType"] = req.OfferType
+	}
 
 	// 获取订阅列表
 	result, err := h.subscriptionsService.ListAllSubscriptions(ctx, userId, query)
@@ -0,0 +1,211 @@
+package handler
+
+import (
+	v1 "azure-vm-backend/api/v1"
+	"azure-vm-backend/internal/service"
+	"azure-vm-backend/pkg/app"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+type SyncScheduleHandler struct {
+	*Handler
+	syncScheduleService service.SyncScheduleService
+}
+
+func NewSyncScheduleHandler(
+	handler *Handler,
+	syncScheduleService service.SyncScheduleService,
+) *SyncScheduleHandler {
+	return &SyncScheduleHandler{
+		Handler:             handler,
+		syncScheduleService: syncScheduleService,
+	}
+}
+
+// CreateSchedule godoc
+// @Summary 创建定时同步
+// @Schemes
+// @Description 按cron表达式(UTC)定时同步指定的Azure账户
+// @Tags 定时同步模块
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param request body v1.CreateSyncScheduleReq true "params"
+// @Success 200 {object} v1.Response
+// @Router /sync-schedules [post]
+func (h *SyncScheduleHandler) CreateSchedule(ctx *gin.Context) {
+	userId := GetUserIdFromCtx(ctx)
+	if userId == "" {
+		v1.HandleError(ctx, http.StatusUnauthorized, v1.ErrUnauthorized, nil)
+		return
+	}
+
+	var req v1.CreateSyncScheduleReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	schedule, err := h.syncScheduleService.CreateSchedule(ctx, userId, &req)
+	if err != nil {
+		v1.HandleError(ctx, http.StatusInternalServerError, err, nil)
+		return
+	}
+	v1.HandleSuccess(ctx, v1.ToSyncScheduleInfo(schedule))
+}
+
+// UpdateSchedule godoc
+// @Summary 更新定时同步
+// @Schemes
+// @Description 更新cron表达式、账户列表或启用状态，task 服务会在下次加载时生效
+// @Tags 定时同步模块
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path string true "定时同步ID"
+// @Param request body v1.UpdateSyncScheduleReq true "params"
+// @Success 200 {object} v1.Response
+// @Router /sync-schedules/{id} [put]
+func (h *SyncScheduleHandler) UpdateSchedule(ctx *gin.Context) {
+	userId := GetUserIdFromCtx(ctx)
+	if userId == "" {
+		v1.HandleError(ctx, http.StatusUnauthorized, v1.ErrUnauthorized, nil)
+		return
+	}
+
+	var req v1.UpdateSyncScheduleReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	schedule, err := h.syncScheduleService.UpdateSchedule(ctx, userId, ctx.Param("id"), &req)
+	if err != nil {
+		v1.HandleError(ctx, http.StatusInternalServerError, err, nil)
+		return
+	}
+	v1.HandleSuccess(ctx, v1.ToSyncScheduleInfo(schedule))
+}
+
+// DeleteSchedule godoc
+// @Summary 删除定时同步
+// @Schemes
+// @Tags 定时同步模块
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path string true "定时同步ID"
+// @Success 200 {object} v1.Response
+// @Router /sync-schedules/{id} [delete]
+func (h *SyncScheduleHandler) DeleteSchedule(ctx *gin.Context) {
+	userId := GetUserIdFromCtx(ctx)
+	if userId == "" {
+		v1.HandleError(ctx, http.StatusUnauthorized, v1.ErrUnauthorized, nil)
+		return
+	}
+
+	if err := h.syncScheduleService.DeleteSchedule(ctx, userId, ctx.Param("id")); err != nil {
+		v1.HandleError(ctx, http.StatusInternalServerError, err, nil)
+		return
+	}
+	v1.HandleSuccess(ctx, nil)
+}
+
+// GetSchedule godoc
+// @Summary 获取定时同步
+// @Schemes
+// @Tags 定时同步模块
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path string true "定时同步ID"
+// @Success 200 {object} v1.Response
+// @Router /sync-schedules/{id} [get]
+func (h *SyncScheduleHandler) GetSchedule(ctx *gin.Context) {
+	userId := GetUserIdFromCtx(ctx)
+	if userId == "" {
+		v1.HandleError(ctx, http.StatusUnauthorized, v1.ErrUnauthorized, nil)
+		return
+	}
+
+	schedule, err := h.syncScheduleService.GetSchedule(ctx, userId, ctx.Param("id"))
+	if err != nil {
+		v1.HandleError(ctx, http.StatusInternalServerError, err, nil)
+		return
+	}
+	v1.HandleSuccess(ctx, v1.ToSyncScheduleInfo(schedule))
+}
+
+// ListSchedules godoc
+// @Summary 获取定时同步列表
+// @Schemes
+// @Tags 定时同步模块
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Success 200 {object} v1.Response
+// @Router /sync-schedules [get]
+func (h *SyncScheduleHandler) ListSchedules(ctx *gin.Context) {
+	userId := GetUserIdFromCtx(ctx)
+	if userId == "" {
+		v1.HandleError(ctx, http.StatusUnauthorized, v1.ErrUnauthorized, nil)
+		return
+	}
+
+	schedules, err := h.syncScheduleService.ListSchedules(ctx, userId)
+	if err != nil {
+		v1.HandleError(ctx, http.StatusInternalServerError, err, nil)
+		return
+	}
+	items := make([]*v1.SyncScheduleInfo, 0, len(schedules))
+	for _, schedule := range schedules {
+		items = append(items, v1.ToSyncScheduleInfo(schedule))
+	}
+	v1.HandleSuccess(ctx, items)
+}
+
+// ListRuns godoc
+// @Summary 获取定时同步执行记录
+// @Schemes
+// @Tags 定时同步模块
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path string true "定时同步ID"
+// @Param page query int false "页码"
+// @Param pageSize query int false "每页数量"
+// @Success 200 {object} v1.Response
+// @Router /sync-schedules/{id}/runs [get]
+func (h *SyncScheduleHandler) ListRuns(ctx *gin.Context) {
+	userId := GetUserIdFromCtx(ctx)
+	if userId == "" {
+		v1.HandleError(ctx, http.StatusUnauthorized, v1.ErrUnauthorized, nil)
+		return
+	}
+
+	var req v1.ListSyncScheduleRunsReq
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	scheduleId := ctx.Param("id")
+	if _, err := h.syncScheduleService.GetSchedule(ctx, userId, scheduleId); err != nil {
+		v1.HandleError(ctx, http.StatusInternalServerError, err, nil)
+		return
+	}
+
+	option := app.ValidateAndFillQueryOption(&app.QueryOption{
+		Pagination: app.Pagination{
+			Page:     req.Page,
+			PageSize: req.PageSize,
+		},
+	})
+	result, err := h.syncScheduleService.ListRuns(ctx, userId, scheduleId, option)
+	if err != nil {
+		v1.HandleError(ctx, http.StatusInternalServerError, err, nil)
+		return
+	}
+	v1.HandleSuccess(ctx, v1.ToSyncScheduleRunListResp(result))
+}
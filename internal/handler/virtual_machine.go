@@ -3,7 +3,7 @@ package handler
 import (
 	v1 "azure-vm-backend/api/v1"
 	"azure-vm-backend/internal/service"
-	"errors"
+	"azure-vm-backend/pkg/azure"
 	"github.com/gin-gonic/gin"
 	"net/http"
 )
@@ -91,6 +91,7 @@ func (h *VirtualMachineHandler) ListVMs(ctx *gin.Context) {
 
 	// 构建查询参数
 	params := &v1.VMQueryParams{
+		UserID:         userId,
 		AccountID:      ctx.Query("accountId"),
 		SubscriptionID: ctx.Query("subscriptionId"),
 		Name:           ctx.Query("name"),
@@ -226,7 +227,7 @@ func (h *VirtualMachineHandler) SyncVMsBySubscription(ctx *gin.Context) {
 // CreateVM godoc
 // @Summary 创建虚拟机
 // @Schemes
-// @Description 在指定账户下创建新的虚拟机(暂未实现)
+// @Description 在指定账户下创建新的虚拟机，记录先以 pending 状态返回，后台完成资源创建后更新为 synced
 // @Tags 虚拟机模块
 // @Accept json
 // @Produce json
@@ -310,16 +311,7 @@ func (h *VirtualMachineHandler) UpdateDNSLabel(ctx *gin.Context) {
 	// 调用服务层更新DNS标签
 	err := h.vmService.UpdateDNSLabel(ctx, userId, accountId, ID, req.DNSLabel)
 	if err != nil {
-		switch {
-		case errors.Is(err, v1.ErrUnauthorized):
-			v1.HandleError(ctx, http.StatusUnauthorized, err, nil)
-		case errors.Is(err, v1.ErrAccountError):
-			v1.HandleError(ctx, http.StatusBadRequest, err, nil)
-		case errors.Is(err, v1.ErrorAzureNotFound):
-			v1.HandleError(ctx, http.StatusNotFound, err, nil)
-		default:
-			v1.HandleError(ctx, http.StatusInternalServerError, err, nil)
-		}
+		v1.HandleError(ctx, http.StatusInternalServerError, err, nil)
 		return
 	}
 
@@ -330,7 +322,7 @@ func (h *VirtualMachineHandler) UpdateDNSLabel(ctx *gin.Context) {
 // OperateVM godoc
 // @Summary 执行虚拟机操作
 // @Schemes
-// @Description 对虚拟机执行指定操作（启动/停止/重启/删除）
+// @Description 对虚拟机执行指定操作（启动/停止/重启/删除/更换公网IP/调整规格），立即返回操作ID，进度通过 /operations/{id} 查询
 // @Tags 虚拟机模块
 // @Accept json
 // @Produce json
@@ -364,21 +356,518 @@ func (h *VirtualMachineHandler) OperateVM(ctx *gin.Context) {
 	}
 
 	// 4. 执行操作
-	err := h.vmService.OperateVM(ctx, userId, accountId, id, req.Operation, req.Force)
+	op, err := h.vmService.OperateVM(ctx, userId, accountId, id, &req)
+	if err != nil {
+		v1.HandleError(ctx, http.StatusInternalServerError, err, nil)
+		return
+	}
+
+	// 5. 返回操作记录，进度通过 /operations/{id} 查询
+	v1.HandleSuccess(ctx, op)
+}
+
+// ListResizeOptions godoc
+// @Summary 获取虚拟机可调整的规格
+// @Schemes
+// @Description 列出虚拟机所在区域已同步的规格，requiresDeallocation 表示调整到该规格需要先释放虚拟机
+// @Tags 虚拟机模块
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param accountId path string true "账户ID"
+// @Param id path string true "虚拟机ID"
+// @Success 200 {object} v1.Response
+// @Router /vms/{accountId}/{id}/sizes [get]
+func (h *VirtualMachineHandler) ListResizeOptions(ctx *gin.Context) {
+	userId := GetUserIdFromCtx(ctx)
+	if userId == "" {
+		v1.HandleError(ctx, http.StatusUnauthorized, v1.ErrUnauthorized, nil)
+		return
+	}
+
+	accountId := ctx.Param("accountId")
+	id := ctx.Param("id")
+	if accountId == "" || id == "" {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	options, err := h.vmService.ListResizeOptions(ctx, userId, accountId, id)
 	if err != nil {
-		switch {
-		case errors.Is(err, v1.ErrUnauthorized):
-			v1.HandleError(ctx, http.StatusUnauthorized, err, nil)
-		case errors.Is(err, v1.ErrAccountError), errors.Is(err, v1.ErrBadRequest):
-			v1.HandleError(ctx, http.StatusBadRequest, err, nil)
-		case errors.Is(err, v1.ErrorAzureNotFound):
-			v1.HandleError(ctx, http.StatusNotFound, err, nil)
-		default:
-			v1.HandleError(ctx, http.StatusInternalServerError, err, nil)
+		v1.HandleError(ctx, http.StatusInternalServerError, err, nil)
+		return
+	}
+	v1.HandleSuccess(ctx, options)
+}
+
+// AttachDataDisk godoc
+// @Summary 挂载数据磁盘
+// @Schemes
+// @Description 创建指定大小和类型的托管磁盘并挂载到虚拟机，数量受规格的最大数据磁盘数限制，进度通过 /operations/{id} 查询
+// @Tags 虚拟机模块
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param accountId path string true "账户ID"
+// @Param id path string true "虚拟机ID"
+// @Param request body v1.AttachDataDiskRequest true "磁盘参数"
+// @Success 200 {object} v1.Response
+// @Router /vms/{accountId}/{id}/disks [post]
+func (h *VirtualMachineHandler) AttachDataDisk(ctx *gin.Context) {
+	userId := GetUserIdFromCtx(ctx)
+	if userId == "" {
+		v1.HandleError(ctx, http.StatusUnauthorized, v1.ErrUnauthorized, nil)
+		return
+	}
+
+	accountId := ctx.Param("accountId")
+	id := ctx.Param("id")
+	if accountId == "" || id == "" {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	var req v1.AttachDataDiskRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	op, err := h.vmService.AttachDataDisk(ctx, userId, accountId, id, &req)
+	if err != nil {
+		v1.HandleError(ctx, http.StatusInternalServerError, err, nil)
+		return
+	}
+	v1.HandleSuccess(ctx, op)
+}
+
+// DetachDataDisk godoc
+// @Summary 卸载数据磁盘
+// @Schemes
+// @Description 从虚拟机卸载数据磁盘，deleteDisk=true 时同时删除磁盘资源，进度通过 /operations/{id} 查询
+// @Tags 虚拟机模块
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param accountId path string true "账户ID"
+// @Param id path string true "虚拟机ID"
+// @Param diskName path string true "磁盘名称"
+// @Param deleteDisk query bool false "是否删除磁盘"
+// @Success 200 {object} v1.Response
+// @Router /vms/{accountId}/{id}/disks/{diskName} [delete]
+func (h *VirtualMachineHandler) DetachDataDisk(ctx *gin.Context) {
+	userId := GetUserIdFromCtx(ctx)
+	if userId == "" {
+		v1.HandleError(ctx, http.StatusUnauthorized, v1.ErrUnauthorized, nil)
+		return
+	}
+
+	accountId := ctx.Param("accountId")
+	id := ctx.Param("id")
+	diskName := ctx.Param("diskName")
+	if accountId == "" || id == "" || diskName == "" {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+	deleteDisk := ctx.Query("deleteDisk") == "true"
+
+	op, err := h.vmService.DetachDataDisk(ctx, userId, accountId, id, diskName, deleteDisk)
+	if err != nil {
+		v1.HandleError(ctx, http.StatusInternalServerError, err, nil)
+		return
+	}
+	v1.HandleSuccess(ctx, op)
+}
+
+// ExpandDataDisk godoc
+// @Summary 扩容数据磁盘
+// @Schemes
+// @Description 扩容已挂载的数据磁盘，容量只能增加，进度通过 /operations/{id} 查询
+// @Tags 虚拟机模块
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param accountId path string true "账户ID"
+// @Param id path string true "虚拟机ID"
+// @Param diskName path string true "磁盘名称"
+// @Param request body v1.ExpandDiskRequest true "扩容参数"
+// @Success 200 {object} v1.Response
+// @Router /vms/{accountId}/{id}/disks/{diskName}/expand [post]
+func (h *VirtualMachineHandler) ExpandDataDisk(ctx *gin.Context) {
+	diskName := ctx.Param("diskName")
+	if diskName == "" {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+	h.expandDisk(ctx, diskName)
+}
+
+// ExpandOSDisk godoc
+// @Summary 扩容系统盘
+// @Schemes
+// @Description 扩容虚拟机系统盘，虚拟机需要先释放，容量只能增加，进度通过 /operations/{id} 查询
+// @Tags 虚拟机模块
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param accountId path string true "账户ID"
+// @Param id path string true "虚拟机ID"
+// @Param request body v1.ExpandDiskRequest true "扩容参数"
+// @Success 200 {object} v1.Response
+// @Router /vms/{accountId}/{id}/os-disk/expand [post]
+func (h *VirtualMachineHandler) ExpandOSDisk(ctx *gin.Context) {
+	h.expandDisk(ctx, "")
+}
+
+// expandDisk 扩容磁盘，diskName 为空时扩容系统盘
+func (h *VirtualMachineHandler) expandDisk(ctx *gin.Context, diskName string) {
+	userId := GetUserIdFromCtx(ctx)
+	if userId == "" {
+		v1.HandleError(ctx, http.StatusUnauthorized, v1.ErrUnauthorized, nil)
+		return
+	}
+
+	accountId := ctx.Param("accountId")
+	id := ctx.Param("id")
+	if accountId == "" || id == "" {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	var req v1.ExpandDiskRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	op, err := h.vmService.ExpandDisk(ctx, userId, accountId, id, diskName, req.SizeGB)
+	if err != nil {
+		v1.HandleError(ctx, http.StatusInternalServerError, err, nil)
+		return
+	}
+	v1.HandleSuccess(ctx, op)
+}
+
+// ListSecurityGroups godoc
+// @Summary 获取虚拟机的网络安全组
+// @Schemes
+// @Description 获取作用于虚拟机网卡及其子网的网络安全组和规则(含默认规则)，refresh=true 时先从Azure同步
+// @Tags 虚拟机模块
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param accountId path string true "账户ID"
+// @Param id path string true "虚拟机ID"
+// @Param refresh query bool false "是否从Azure同步"
+// @Success 200 {object} v1.Response
+// @Router /vms/{accountId}/{id}/security-groups [get]
+func (h *VirtualMachineHandler) ListSecurityGroups(ctx *gin.Context) {
+	userId := GetUserIdFromCtx(ctx)
+	if userId == "" {
+		v1.HandleError(ctx, http.StatusUnauthorized, v1.ErrUnauthorized, nil)
+		return
+	}
+
+	accountId := ctx.Param("accountId")
+	id := ctx.Param("id")
+	if accountId == "" || id == "" {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	var req v1.ListSecurityGroupsRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	groups, err := h.vmService.ListSecurityGroups(ctx, userId, accountId, id, req.Refresh)
+	if err != nil {
+		v1.HandleError(ctx, http.StatusInternalServerError, err, nil)
+		return
+	}
+	v1.HandleSuccess(ctx, groups)
+}
+
+// CreateSecurityGroup godoc
+// @Summary 创建网络安全组
+// @Schemes
+// @Description 为虚拟机主网卡创建并关联网络安全组，网卡已关联网络安全组时返回冲突
+// @Tags 虚拟机模块
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param accountId path string true "账户ID"
+// @Param id path string true "虚拟机ID"
+// @Param request body v1.CreateSecurityGroupRequest false "网络安全组参数"
+// @Success 200 {object} v1.Response
+// @Router /vms/{accountId}/{id}/security-groups [post]
+func (h *VirtualMachineHandler) CreateSecurityGroup(ctx *gin.Context) {
+	userId := GetUserIdFromCtx(ctx)
+	if userId == "" {
+		v1.HandleError(ctx, http.StatusUnauthorized, v1.ErrUnauthorized, nil)
+		return
+	}
+
+	accountId := ctx.Param("accountId")
+	id := ctx.Param("id")
+	if accountId == "" || id == "" {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	var req v1.CreateSecurityGroupRequest
+	if ctx.Request.ContentLength > 0 {
+		if err := ctx.ShouldBindJSON(&req); err != nil {
+			v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+			return
 		}
+	}
+
+	groups, err := h.vmService.CreateSecurityGroup(ctx, userId, accountId, id, &req)
+	if err != nil {
+		v1.HandleError(ctx, http.StatusInternalServerError, err, nil)
+		return
+	}
+	v1.HandleSuccess(ctx, groups)
+}
+
+// AddSecurityRule godoc
+// @Summary 添加入站安全规则
+// @Schemes
+// @Description 在虚拟机的网络安全组中添加入站规则，同名规则会被覆盖；未指定网络安全组时优先使用网卡上的网络安全组
+// @Tags 虚拟机模块
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param accountId path string true "账户ID"
+// @Param id path string true "虚拟机ID"
+// @Param request body v1.AddSecurityRuleRequest true "规则参数"
+// @Success 200 {object} v1.Response
+// @Router /vms/{accountId}/{id}/security-rules [post]
+func (h *VirtualMachineHandler) AddSecurityRule(ctx *gin.Context) {
+	userId := GetUserIdFromCtx(ctx)
+	if userId == "" {
+		v1.HandleError(ctx, http.StatusUnauthorized, v1.ErrUnauthorized, nil)
+		return
+	}
+
+	accountId := ctx.Param("accountId")
+	id := ctx.Param("id")
+	if accountId == "" || id == "" {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	var req v1.AddSecurityRuleRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	groups, err := h.vmService.AddSecurityRule(ctx, userId, accountId, id, &req)
+	if err != nil {
+		v1.HandleError(ctx, http.StatusInternalServerError, err, nil)
+		return
+	}
+	v1.HandleSuccess(ctx, groups)
+}
+
+// DeleteSecurityRule godoc
+// @Summary 删除安全规则
+// @Schemes
+// @Description 删除虚拟机网络安全组中的规则，默认规则不能删除
+// @Tags 虚拟机模块
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param accountId path string true "账户ID"
+// @Param id path string true "虚拟机ID"
+// @Param ruleName path string true "规则名称"
+// @Param securityGroup query string false "网络安全组名称，为空时在所有关联的网络安全组中查找"
+// @Success 200 {object} v1.Response
+// @Router /vms/{accountId}/{id}/security-rules/{ruleName} [delete]
+func (h *VirtualMachineHandler) DeleteSecurityRule(ctx *gin.Context) {
+	userId := GetUserIdFromCtx(ctx)
+	if userId == "" {
+		v1.HandleError(ctx, http.StatusUnauthorized, v1.ErrUnauthorized, nil)
+		return
+	}
+
+	accountId := ctx.Param("accountId")
+	id := ctx.Param("id")
+	ruleName := ctx.Param("ruleName")
+	if accountId == "" || id == "" || ruleName == "" {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	groups, err := h.vmService.DeleteSecurityRule(ctx, userId, accountId, id, ctx.Query("securityGroup"), ruleName)
+	if err != nil {
+		v1.HandleError(ctx, http.StatusInternalServerError, err, nil)
+		return
+	}
+	v1.HandleSuccess(ctx, groups)
+}
+
+// ResetVMAccess godoc
+// @Summary 重置虚拟机访问
+// @Schemes
+// @Description 安装或更新 VMAccess 扩展重置管理员密码、替换SSH公钥或修复sshd配置，Windows 只能重置密码；虚拟机需要处于运行状态，扩展执行结果通过操作记录返回
+// @Tags 虚拟机模块
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param accountId path string true "账户ID"
+// @Param id path string true "虚拟机ID"
+// @Param request body v1.ResetVMAccessRequest true "重置参数"
+// @Success 200 {object} v1.Response
+// @Router /vms/{accountId}/{id}/access/reset [post]
+func (h *VirtualMachineHandler) ResetVMAccess(ctx *gin.Context) {
+	userId := GetUserIdFromCtx(ctx)
+	if userId == "" {
+		v1.HandleError(ctx, http.StatusUnauthorized, v1.ErrUnauthorized, nil)
+		return
+	}
+
+	accountId := ctx.Param("accountId")
+	id := ctx.Param("id")
+	if accountId == "" || id == "" {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	var req v1.ResetVMAccessRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
 		return
 	}
 
-	// 5. 返回成功
+	op, err := h.vmService.ResetVMAccess(ctx, userId, accountId, id, &req)
+	if err != nil {
+		v1.HandleError(ctx, http.StatusInternalServerError, err, nil)
+		return
+	}
+	v1.HandleSuccess(ctx, op)
+}
+
+// GetVMAccessStatus godoc
+// @Summary 获取虚拟机访问重置结果
+// @Schemes
+// @Description 返回 VMAccess 扩展最近一次的预配状态和执行消息，未安装扩展时返回 404
+// @Tags 虚拟机模块
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param accountId path string true "账户ID"
+// @Param id path string true "虚拟机ID"
+// @Success 200 {object} v1.Response
+// @Router /vms/{accountId}/{id}/access [get]
+func (h *VirtualMachineHandler) GetVMAccessStatus(ctx *gin.Context) {
+	userId := GetUserIdFromCtx(ctx)
+	if userId == "" {
+		v1.HandleError(ctx, http.StatusUnauthorized, v1.ErrUnauthorized, nil)
+		return
+	}
+
+	accountId := ctx.Param("accountId")
+	id := ctx.Param("id")
+	if accountId == "" || id == "" {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	result, err := h.vmService.GetVMAccessStatus(ctx, userId, accountId, id)
+	if err != nil {
+		v1.HandleError(ctx, http.StatusInternalServerError, err, nil)
+		return
+	}
+	v1.HandleSuccess(ctx, result)
+}
+
+// EnableBootDiagnostics godoc
+// @Summary 启用启动诊断
+// @Schemes
+// @Description 为虚拟机启用使用托管存储账户的启动诊断，虚拟机重新启动后才会生成串行日志和截图
+// @Tags 虚拟机模块
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param accountId path string true "账户ID"
+// @Param vmId path string true "虚拟机ID"
+// @Success 200 {object} v1.Response
+// @Router /vms/{accountId}/instance/{vmId}/boot-diagnostics [post]
+func (h *VirtualMachineHandler) EnableBootDiagnostics(ctx *gin.Context) {
+	userId := GetUserIdFromCtx(ctx)
+	if userId == "" {
+		v1.HandleError(ctx, http.StatusUnauthorized, v1.ErrUnauthorized, nil)
+		return
+	}
+
+	accountId := ctx.Param("accountId")
+	vmId := ctx.Param("vmId")
+	if accountId == "" || vmId == "" {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	if err := h.vmService.EnableBootDiagnostics(ctx, userId, accountId, vmId); err != nil {
+		v1.HandleError(ctx, http.StatusInternalServerError, err, nil)
+		return
+	}
 	v1.HandleSuccess(ctx, nil)
 }
+
+// GetSerialLog godoc
+// @Summary 获取串行控制台日志
+// @Schemes
+// @Description 以纯文本返回启动诊断中的串行控制台日志，未启用启动诊断时返回冲突
+// @Tags 虚拟机模块
+// @Produce plain
+// @Security Bearer
+// @Param accountId path string true "账户ID"
+// @Param vmId path string true "虚拟机ID"
+// @Success 200 {string} string "串行日志"
+// @Router /vms/{accountId}/instance/{vmId}/serial-log [get]
+func (h *VirtualMachineHandler) GetSerialLog(ctx *gin.Context) {
+	h.streamBootDiagnostics(ctx, azure.BootDiagnosticsSerialLog)
+}
+
+// GetScreenshot godoc
+// @Summary 获取控制台截图
+// @Schemes
+// @Description 返回启动诊断中的控制台截图，未启用启动诊断时返回冲突
+// @Tags 虚拟机模块
+// @Produce image/bmp
+// @Security Bearer
+// @Param accountId path string true "账户ID"
+// @Param vmId path string true "虚拟机ID"
+// @Success 200 {file} file "截图"
+// @Router /vms/{accountId}/instance/{vmId}/screenshot [get]
+func (h *VirtualMachineHandler) GetScreenshot(ctx *gin.Context) {
+	h.streamBootDiagnostics(ctx, azure.BootDiagnosticsScreenshot)
+}
+
+// streamBootDiagnostics 将启动诊断数据直接写入响应
+func (h *VirtualMachineHandler) streamBootDiagnostics(ctx *gin.Context, kind azure.BootDiagnosticsKind) {
+	userId := GetUserIdFromCtx(ctx)
+	if userId == "" {
+		v1.HandleError(ctx, http.StatusUnauthorized, v1.ErrUnauthorized, nil)
+		return
+	}
+
+	accountId := ctx.Param("accountId")
+	vmId := ctx.Param("vmId")
+	if accountId == "" || vmId == "" {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	data, err := h.vmService.GetBootDiagnostics(ctx, userId, accountId, vmId, kind)
+	if err != nil {
+		v1.HandleError(ctx, http.StatusInternalServerError, err, nil)
+		return
+	}
+	defer data.Body.Close()
+	ctx.DataFromReader(http.StatusOK, data.ContentLength, data.ContentType, data.Body, nil)
+}
@@ -0,0 +1,62 @@
+package handler
+
+import (
+	v1 "azure-vm-backend/api/v1"
+	"azure-vm-backend/internal/service"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+type VmCostHandler struct {
+	*Handler
+	vmCostService service.VmCostService
+}
+
+func NewVmCostHandler(
+	handler *Handler,
+	vmCostService service.VmCostService,
+) *VmCostHandler {
+	return &VmCostHandler{
+		Handler:       handler,
+		vmCostService: vmCostService,
+	}
+}
+
+// EstimateVM godoc
+// @Summary 估算创建虚拟机的月费用
+// @Schemes
+// @Description 按创建虚拟机的参数估算每月费用，规格价格来自零售价格 API，磁盘按参考价格和档位估算
+// @Tags 虚拟机模块
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param accountId path string true "账户ID"
+// @Param request body v1.VMCreateParams true "创建虚拟机的参数"
+// @Success 200 {object} v1.Response
+// @Router /vms/{accountId}/estimate [post]
+func (h *VmCostHandler) EstimateVM(ctx *gin.Context) {
+	userId := GetUserIdFromCtx(ctx)
+	if userId == "" {
+		v1.HandleError(ctx, http.StatusUnauthorized, v1.ErrUnauthorized, nil)
+		return
+	}
+
+	accountId := ctx.Param("accountId")
+	if accountId == "" {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	var req v1.VMCreateParams
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	estimate, err := h.vmCostService.EstimateCreate(ctx, userId, accountId, &req)
+	if err != nil {
+		v1.HandleError(ctx, http.StatusInternalServerError, err, nil)
+		return
+	}
+	v1.HandleSuccess(ctx, estimate)
+}
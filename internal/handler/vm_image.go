@@ -27,6 +27,12 @@ func NewVmImageHandler(
 
 // GetVmImage 获取指定ID的镜像信息
 func (h *VmImageHandler) GetVmImage(ctx *gin.Context) {
+	userId := GetUserIdFromCtx(ctx)
+	if userId == "" {
+		v1.HandleError(ctx, http.StatusUnauthorized, v1.ErrUnauthorized, nil)
+		return
+	}
+
 	idStr := ctx.Param("id")
 	id, err := strconv.ParseUint(idStr, 10, 32)
 	if err != nil {
@@ -34,7 +40,7 @@ func (h *VmImageHandler) GetVmImage(ctx *gin.Context) {
 		return
 	}
 
-	image, err := h.vmImageService.GetVmImage(ctx.Request.Context(), uint(id))
+	image, err := h.vmImageService.GetVmImage(ctx.Request.Context(), userId, uint(id))
 	if err != nil {
 		v1.HandleError(ctx, http.StatusInternalServerError, err, nil)
 		return
@@ -51,9 +57,15 @@ func (h *VmImageHandler) GetVmImage(ctx *gin.Context) {
 	v1.HandleSuccess(ctx, response)
 }
 
-// ListVmImages 获取所有可用的镜像列表
+// ListVmImages 获取当前用户账户下的镜像列表，可通过 accountId 过滤
 func (h *VmImageHandler) ListVmImages(ctx *gin.Context) {
-	images, err := h.vmImageService.ListVmImages(ctx.Request.Context())
+	userId := GetUserIdFromCtx(ctx)
+	if userId == "" {
+		v1.HandleError(ctx, http.StatusUnauthorized, v1.ErrUnauthorized, nil)
+		return
+	}
+
+	images, err := h.vmImageService.ListVmImages(ctx.Request.Context(), userId, ctx.Query("accountId"))
 	if err != nil {
 		v1.HandleError(ctx, http.StatusInternalServerError, err, nil)
 		return
@@ -0,0 +1,299 @@
+package handler
+
+import (
+	v1 "azure-vm-backend/api/v1"
+	"azure-vm-backend/internal/service"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+type VmRunCommandHandler struct {
+	*Handler
+	vmRunCommandService service.VmRunCommandService
+}
+
+func NewVmRunCommandHandler(
+	handler *Handler,
+	vmRunCommandService service.VmRunCommandService,
+) *VmRunCommandHandler {
+	return &VmRunCommandHandler{
+		Handler:             handler,
+		vmRunCommandService: vmRunCommandService,
+	}
+}
+
+// CreateSnippet godoc
+// @Summary 保存脚本片段
+// @Schemes
+// @Tags 脚本执行模块
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param request body v1.CreateScriptSnippetRequest true "脚本片段"
+// @Success 200 {object} v1.Response
+// @Router /script-snippets [post]
+func (h *VmRunCommandHandler) CreateSnippet(ctx *gin.Context) {
+	userId := GetUserIdFromCtx(ctx)
+	if userId == "" {
+		v1.HandleError(ctx, http.StatusUnauthorized, v1.ErrUnauthorized, nil)
+		return
+	}
+
+	var req v1.CreateScriptSnippetRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	snippet, err := h.vmRunCommandService.CreateSnippet(ctx, userId, &req)
+	if err != nil {
+		v1.HandleError(ctx, http.StatusInternalServerError, err, nil)
+		return
+	}
+	v1.HandleSuccess(ctx, snippet)
+}
+
+// ListSnippets godoc
+// @Summary 获取脚本片段列表
+// @Schemes
+// @Tags 脚本执行模块
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Success 200 {object} v1.Response
+// @Router /script-snippets [get]
+func (h *VmRunCommandHandler) ListSnippets(ctx *gin.Context) {
+	userId := GetUserIdFromCtx(ctx)
+	if userId == "" {
+		v1.HandleError(ctx, http.StatusUnauthorized, v1.ErrUnauthorized, nil)
+		return
+	}
+
+	snippets, err := h.vmRunCommandService.ListSnippets(ctx, userId)
+	if err != nil {
+		v1.HandleError(ctx, http.StatusInternalServerError, err, nil)
+		return
+	}
+	v1.HandleSuccess(ctx, snippets)
+}
+
+// UpdateSnippet godoc
+// @Summary 更新脚本片段
+// @Schemes
+// @Tags 脚本执行模块
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path string true "脚本片段ID"
+// @Param request body v1.UpdateScriptSnippetRequest true "更新内容"
+// @Success 200 {object} v1.Response
+// @Router /script-snippets/{id} [put]
+func (h *VmRunCommandHandler) UpdateSnippet(ctx *gin.Context) {
+	userId := GetUserIdFromCtx(ctx)
+	if userId == "" {
+		v1.HandleError(ctx, http.StatusUnauthorized, v1.ErrUnauthorized, nil)
+		return
+	}
+
+	var req v1.UpdateScriptSnippetRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	snippet, err := h.vmRunCommandService.UpdateSnippet(ctx, userId, ctx.Param("id"), &req)
+	if err != nil {
+		v1.HandleError(ctx, http.StatusInternalServerError, err, nil)
+		return
+	}
+	v1.HandleSuccess(ctx, snippet)
+}
+
+// DeleteSnippet godoc
+// @Summary 删除脚本片段
+// @Schemes
+// @Tags 脚本执行模块
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path string true "脚本片段ID"
+// @Success 200 {object} v1.Response
+// @Router /script-snippets/{id} [delete]
+func (h *VmRunCommandHandler) DeleteSnippet(ctx *gin.Context) {
+	userId := GetUserIdFromCtx(ctx)
+	if userId == "" {
+		v1.HandleError(ctx, http.StatusUnauthorized, v1.ErrUnauthorized, nil)
+		return
+	}
+
+	if err := h.vmRunCommandService.DeleteSnippet(ctx, userId, ctx.Param("id")); err != nil {
+		v1.HandleError(ctx, http.StatusInternalServerError, err, nil)
+		return
+	}
+	v1.HandleSuccess(ctx, nil)
+}
+
+// RunCommand godoc
+// @Summary 在虚拟机内执行脚本
+// @Schemes
+// @Description 通过 Run Command 在运行中的虚拟机内执行脚本，Linux 为 shell，Windows 为 PowerShell，结果通过 /command-executions/{id} 查询
+// @Tags 脚本执行模块
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param accountId path string true "账户ID"
+// @Param id path string true "虚拟机ID"
+// @Param request body v1.RunCommandRequest true "脚本内容或脚本片段ID"
+// @Success 200 {object} v1.Response
+// @Router /vms/{accountId}/{id}/run-command [post]
+func (h *VmRunCommandHandler) RunCommand(ctx *gin.Context) {
+	userId := GetUserIdFromCtx(ctx)
+	if userId == "" {
+		v1.HandleError(ctx, http.StatusUnauthorized, v1.ErrUnauthorized, nil)
+		return
+	}
+
+	accountId := ctx.Param("accountId")
+	id := ctx.Param("id")
+	if accountId == "" || id == "" {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	var req v1.RunCommandRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	execution, err := h.vmRunCommandService.RunCommand(ctx, userId, accountId, id, &req)
+	if err != nil {
+		v1.HandleError(ctx, http.StatusInternalServerError, err, nil)
+		return
+	}
+	v1.HandleSuccess(ctx, execution)
+}
+
+// ListExecutions godoc
+// @Summary 获取虚拟机的脚本执行记录
+// @Schemes
+// @Tags 脚本执行模块
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param accountId path string true "账户ID"
+// @Param id path string true "虚拟机ID"
+// @Param page query int false "页码"
+// @Param pageSize query int false "每页数量"
+// @Success 200 {object} v1.Response
+// @Router /vms/{accountId}/{id}/command-executions [get]
+func (h *VmRunCommandHandler) ListExecutions(ctx *gin.Context) {
+	userId := GetUserIdFromCtx(ctx)
+	if userId == "" {
+		v1.HandleError(ctx, http.StatusUnauthorized, v1.ErrUnauthorized, nil)
+		return
+	}
+
+	accountId := ctx.Param("accountId")
+	id := ctx.Param("id")
+	if accountId == "" || id == "" {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	var req v1.ListCommandExecutionsRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	result, err := h.vmRunCommandService.ListExecutions(ctx, userId, accountId, id, &req)
+	if err != nil {
+		v1.HandleError(ctx, http.StatusInternalServerError, err, nil)
+		return
+	}
+	v1.HandleSuccess(ctx, result)
+}
+
+// GetExecution godoc
+// @Summary 获取脚本执行记录
+// @Schemes
+// @Description 返回执行状态、退出码和输出，Azure 只返回输出的最后 4096 字节
+// @Tags 脚本执行模块
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path string true "执行记录ID"
+// @Success 200 {object} v1.Response
+// @Router /command-executions/{id} [get]
+func (h *VmRunCommandHandler) GetExecution(ctx *gin.Context) {
+	userId := GetUserIdFromCtx(ctx)
+	if userId == "" {
+		v1.HandleError(ctx, http.StatusUnauthorized, v1.ErrUnauthorized, nil)
+		return
+	}
+
+	execution, err := h.vmRunCommandService.GetExecution(ctx, userId, ctx.Param("id"))
+	if err != nil {
+		v1.HandleError(ctx, http.StatusInternalServerError, err, nil)
+		return
+	}
+	v1.HandleSuccess(ctx, execution)
+}
+
+// BatchRunCommand godoc
+// @Summary 批量执行脚本
+// @Schemes
+// @Description 在匹配筛选条件的虚拟机上执行脚本，未运行或系统不匹配的虚拟机会被跳过，单次最多 50 台
+// @Tags 脚本执行模块
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param request body v1.BatchRunCommandRequest true "脚本和虚拟机筛选条件"
+// @Success 200 {object} v1.Response
+// @Router /command-batches [post]
+func (h *VmRunCommandHandler) BatchRunCommand(ctx *gin.Context) {
+	userId := GetUserIdFromCtx(ctx)
+	if userId == "" {
+		v1.HandleError(ctx, http.StatusUnauthorized, v1.ErrUnauthorized, nil)
+		return
+	}
+
+	var req v1.BatchRunCommandRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	resp, err := h.vmRunCommandService.BatchRunCommand(ctx, userId, &req)
+	if err != nil {
+		v1.HandleError(ctx, http.StatusInternalServerError, err, nil)
+		return
+	}
+	v1.HandleSuccess(ctx, resp)
+}
+
+// ListBatchExecutions godoc
+// @Summary 获取批量执行的记录
+// @Schemes
+// @Tags 脚本执行模块
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path string true "批次ID"
+// @Success 200 {object} v1.Response
+// @Router /command-batches/{id} [get]
+func (h *VmRunCommandHandler) ListBatchExecutions(ctx *gin.Context) {
+	userId := GetUserIdFromCtx(ctx)
+	if userId == "" {
+		v1.HandleError(ctx, http.StatusUnauthorized, v1.ErrUnauthorized, nil)
+		return
+	}
+
+	executions, err := h.vmRunCommandService.ListBatchExecutions(ctx, userId, ctx.Param("id"))
+	if err != nil {
+		v1.HandleError(ctx, http.StatusInternalServerError, err, nil)
+		return
+	}
+	v1.HandleSuccess(ctx, executions)
+}
@@ -26,6 +26,12 @@ func NewVmSizeHandler(
 
 // ListVmSizes 获取规格列表
 func (h *VmSizeHandler) ListVmSizes(ctx *gin.Context) {
+	userId := GetUserIdFromCtx(ctx)
+	if userId == "" {
+		v1.HandleError(ctx, http.StatusUnauthorized, v1.ErrUnauthorized, nil)
+		return
+	}
+
 	var req v1.ListVmSizesRequest
 	if err := ctx.ShouldBindQuery(&req); err != nil {
 		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
@@ -37,7 +43,7 @@ func (h *VmSizeHandler) ListVmSizes(ctx *gin.Context) {
 		req.Location = "eastasia"
 	}
 
-	sizes, err := h.vmSizeService.ListVmSizes(ctx.Request.Context(), req.Location)
+	sizes, err := h.vmSizeService.ListVmSizes(ctx.Request.Context(), userId, req.AccountID, req.SubscriptionID, req.Location)
 	if err != nil {
 		v1.HandleError(ctx, http.StatusInternalServerError, err, nil)
 		return
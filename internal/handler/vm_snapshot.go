@@ -0,0 +1,175 @@
+package handler
+
+import (
+	v1 "azure-vm-backend/api/v1"
+	"azure-vm-backend/internal/service"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+type VmSnapshotHandler struct {
+	*Handler
+	vmSnapshotService service.VmSnapshotService
+}
+
+func NewVmSnapshotHandler(
+	handler *Handler,
+	vmSnapshotService service.VmSnapshotService,
+) *VmSnapshotHandler {
+	return &VmSnapshotHandler{
+		Handler:           handler,
+		vmSnapshotService: vmSnapshotService,
+	}
+}
+
+// CreateSnapshot godoc
+// @Summary 创建磁盘快照
+// @Schemes
+// @Description 为系统盘或数据磁盘创建增量快照，超出保留数量时删除最旧的快照，进度通过 /operations/{id} 查询
+// @Tags 快照模块
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param accountId path string true "账户ID"
+// @Param id path string true "虚拟机ID"
+// @Param request body v1.CreateSnapshotRequest true "快照参数"
+// @Success 200 {object} v1.Response
+// @Router /vms/{accountId}/{id}/snapshots [post]
+func (h *VmSnapshotHandler) CreateSnapshot(ctx *gin.Context) {
+	userId := GetUserIdFromCtx(ctx)
+	if userId == "" {
+		v1.HandleError(ctx, http.StatusUnauthorized, v1.ErrUnauthorized, nil)
+		return
+	}
+
+	accountId := ctx.Param("accountId")
+	id := ctx.Param("id")
+	if accountId == "" || id == "" {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	var req v1.CreateSnapshotRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	op, err := h.vmSnapshotService.CreateSnapshot(ctx, userId, accountId, id, &req)
+	if err != nil {
+		v1.HandleError(ctx, http.StatusInternalServerError, err, nil)
+		return
+	}
+	v1.HandleSuccess(ctx, op)
+}
+
+// ListSnapshots godoc
+// @Summary 获取虚拟机快照列表
+// @Schemes
+// @Description 按快照时间倒序返回虚拟机的快照，refresh=true 时先与Azure上的快照对账
+// @Tags 快照模块
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param accountId path string true "账户ID"
+// @Param id path string true "虚拟机ID"
+// @Param refresh query bool false "是否从Azure同步"
+// @Success 200 {object} v1.Response
+// @Router /vms/{accountId}/{id}/snapshots [get]
+func (h *VmSnapshotHandler) ListSnapshots(ctx *gin.Context) {
+	userId := GetUserIdFromCtx(ctx)
+	if userId == "" {
+		v1.HandleError(ctx, http.StatusUnauthorized, v1.ErrUnauthorized, nil)
+		return
+	}
+
+	accountId := ctx.Param("accountId")
+	id := ctx.Param("id")
+	if accountId == "" || id == "" {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	var req v1.ListSnapshotsRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	snapshots, err := h.vmSnapshotService.ListSnapshots(ctx, userId, accountId, id, req.Refresh)
+	if err != nil {
+		v1.HandleError(ctx, http.StatusInternalServerError, err, nil)
+		return
+	}
+	v1.HandleSuccess(ctx, snapshots)
+}
+
+// DeleteSnapshot godoc
+// @Summary 删除快照
+// @Schemes
+// @Tags 快照模块
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param accountId path string true "账户ID"
+// @Param id path string true "虚拟机ID"
+// @Param snapshotId path string true "快照ID"
+// @Success 200 {object} v1.Response
+// @Router /vms/{accountId}/{id}/snapshots/{snapshotId} [delete]
+func (h *VmSnapshotHandler) DeleteSnapshot(ctx *gin.Context) {
+	userId := GetUserIdFromCtx(ctx)
+	if userId == "" {
+		v1.HandleError(ctx, http.StatusUnauthorized, v1.ErrUnauthorized, nil)
+		return
+	}
+
+	accountId := ctx.Param("accountId")
+	id := ctx.Param("id")
+	snapshotId := ctx.Param("snapshotId")
+	if accountId == "" || id == "" || snapshotId == "" {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	if err := h.vmSnapshotService.DeleteSnapshot(ctx, userId, accountId, id, snapshotId); err != nil {
+		v1.HandleError(ctx, http.StatusInternalServerError, err, nil)
+		return
+	}
+	v1.HandleSuccess(ctx, nil)
+}
+
+// RestoreSnapshot godoc
+// @Summary 从快照恢复系统盘
+// @Schemes
+// @Description 从系统盘快照创建新磁盘并替换虚拟机的系统盘，运行中的虚拟机会先释放再启动，原系统盘保留，进度通过 /operations/{id} 查询
+// @Tags 快照模块
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param accountId path string true "账户ID"
+// @Param id path string true "虚拟机ID"
+// @Param snapshotId path string true "快照ID"
+// @Success 200 {object} v1.Response
+// @Router /vms/{accountId}/{id}/snapshots/{snapshotId}/restore [post]
+func (h *VmSnapshotHandler) RestoreSnapshot(ctx *gin.Context) {
+	userId := GetUserIdFromCtx(ctx)
+	if userId == "" {
+		v1.HandleError(ctx, http.StatusUnauthorized, v1.ErrUnauthorized, nil)
+		return
+	}
+
+	accountId := ctx.Param("accountId")
+	id := ctx.Param("id")
+	snapshotId := ctx.Param("snapshotId")
+	if accountId == "" || id == "" || snapshotId == "" {
+		v1.HandleError(ctx, http.StatusBadRequest, v1.ErrBadRequest, nil)
+		return
+	}
+
+	op, err := h.vmSnapshotService.RestoreSnapshot(ctx, userId, accountId, id, snapshotId)
+	if err != nil {
+		v1.HandleError(ctx, http.StatusInternalServerError, err, nil)
+		return
+	}
+	v1.HandleSuccess(ctx, op)
+}
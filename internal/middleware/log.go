@@ -3,11 +3,13 @@ package middleware
 import (
 	"azure-vm-backend/pkg/log"
 	"bytes"
+	"encoding/json"
 	"github.com/duke-git/lancet/v2/cryptor"
 	"github.com/duke-git/lancet/v2/random"
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 	"io"
+	"strings"
 	"time"
 )
 
@@ -26,7 +28,7 @@ func RequestLogMiddleware(logger *log.Logger) gin.HandlerFunc {
 		if ctx.Request.Body != nil {
 			bodyBytes, _ := ctx.GetRawData()
 			ctx.Request.Body = io.NopCloser(bytes.NewBuffer(bodyBytes)) // 关键点
-			logger.WithValue(ctx, zap.String("request_params", string(bodyBytes)))
+			logger.WithValue(ctx, zap.String("request_params", maskSensitiveBody(bodyBytes)))
 		}
 		logger.WithContext(ctx).Info("Request")
 		ctx.Next()
@@ -39,7 +41,11 @@ func ResponseLogMiddleware(logger *log.Logger) gin.HandlerFunc {
 		startTime := time.Now()
 		ctx.Next()
 		duration := time.Since(startTime).String()
-		logger.WithContext(ctx).Info("Response", zap.Any("response_body", blw.body.String()), zap.Any("time", duration))
+		fields := []zap.Field{zap.Any("response_body", blw.body.String()), zap.Any("time", duration)}
+		if len(ctx.Errors) > 0 {
+			fields = append(fields, zap.String("errors", ctx.Errors.String()))
+		}
+		logger.WithContext(ctx).Info("Response", fields...)
 	}
 }
 
@@ -52,3 +58,35 @@ func (w bodyLogWriter) Write(b []byte) (int, error) {
 	w.body.Write(b)
 	return w.ResponseWriter.Write(b)
 }
+
+// maskSensitiveBody 隐藏请求体中的密码、密钥等字段，非JSON请求体原样返回
+func maskSensitiveBody(body []byte) string {
+	var data interface{}
+	if err := json.Unmarshal(body, &data); err != nil {
+		return string(body)
+	}
+	masked, err := json.Marshal(maskSensitive(data))
+	if err != nil {
+		return string(body)
+	}
+	return string(masked)
+}
+
+func maskSensitive(data interface{}) interface{} {
+	switch v := data.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			lower := strings.ToLower(key)
+			if strings.Contains(lower, "password") || strings.Contains(lower, "secret") {
+				v[key] = "******"
+				continue
+			}
+			v[key] = maskSensitive(value)
+		}
+	case []interface{}:
+		for i, value := range v {
+			v[i] = maskSensitive(value)
+		}
+	}
+	return data
+}
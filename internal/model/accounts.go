@@ -2,15 +2,25 @@ package model
 
 import "gorm.io/gorm"
 
+// 账户的订阅状态，由账户下全部订阅中最严重的状态得出，见 service.SubscriptionsService
+const (
+	AccountSubscriptionNormal   = "normal"   // 订阅均为 Enabled
+	AccountSubscriptionWarned   = "warned"   // 存在 Warned 的订阅，通常是额度即将用完或试用即将到期
+	AccountSubscriptionPastDue  = "past_due" // 存在 PastDue 的订阅，账单逾期
+	AccountSubscriptionDisabled = "disabled" // 存在 Disabled 或 Deleted 的订阅
+	AccountSubscriptionNone     = "none"     // 账户下没有可见的订阅
+	AccountSubscriptionError    = "error"    // 获取订阅失败，通常是凭据失效
+)
+
 type Accounts struct {
 	gorm.Model
 	AccountID          string `gorm:"column:account_id;type:varchar(32);uniqueIndex;not null" json:"accountId"`
 	UserID             string `gorm:"column:user_id;type:varchar(32);index;not null" json:"userId"`
 	LoginEmail         string `gorm:"column:login_email;type:varchar(128);index;not null" json:"loginEmail"`
-	LoginPassword      string `gorm:"column:login_password;type:varchar(128);not null" json:"loginPassword"`
+	LoginPassword      string `gorm:"column:login_password;type:varchar(512);not null" json:"-"` // 密文，见 pkg/secret
 	Remark             string `gorm:"column:remark;type:text" json:"remark"`
 	AppID              string `gorm:"column:app_id;type:varchar(128);not null" json:"appId"`
-	PassWord           string `gorm:"column:password;type:varchar(256);not null" json:"password"`
+	PassWord           string `gorm:"column:password;type:varchar(512);not null" json:"-"` // 客户端密钥密文，见 pkg/secret
 	Tenant             string `gorm:"column:tenant;type:varchar(128);not null" json:"tenant"`
 	VmCount            int    `gorm:"column:vm_count;type:int;not null" json:"vmCount"`
 	DisplayName        string `gorm:"column:display_name;type:varchar(128);not null" json:"displayName"`
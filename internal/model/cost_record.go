@@ -0,0 +1,25 @@
+package model
+
+import (
+	"gorm.io/gorm"
+	"time"
+)
+
+// CostRecord 订阅内单个资源的单个计量在一天内的实际费用，来自 Cost Management query API
+// 每次同步按订阅和日期范围整体替换
+type CostRecord struct {
+	gorm.Model
+	AccountID      string    `gorm:"column:account_id;type:varchar(32);not null;index" json:"accountId"`
+	SubscriptionID string    `gorm:"column:subscription_id;type:varchar(128);not null;index:idx_cost_record_date" json:"subscriptionId"`
+	UsageDate      time.Time `gorm:"column:usage_date;not null;index:idx_cost_record_date" json:"usageDate"` // UTC 零点
+	ResourceID     string    `gorm:"column:resource_id;type:varchar(512)" json:"resourceId"`                 // 小写的资源ID，订阅级别的费用为空
+	Meter          string    `gorm:"column:meter;type:varchar(256)" json:"meter"`
+	Cost           float64   `gorm:"column:cost;not null" json:"cost"`
+	Currency       string    `gorm:"column:currency;type:varchar(16)" json:"currency"`
+	SyncedAt       time.Time `gorm:"column:synced_at" json:"syncedAt"`
+}
+
+// TableName 指定表名
+func (r *CostRecord) TableName() string {
+	return "cost_records"
+}
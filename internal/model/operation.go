@@ -0,0 +1,40 @@
+package model
+
+import (
+	"gorm.io/gorm"
+	"time"
+)
+
+// Operation 异步操作记录，保存Azure轮询器的恢复令牌以便服务重启后继续轮询
+type Operation struct {
+	gorm.Model
+	OperationID    string     `gorm:"column:operation_id;type:varchar(64);uniqueIndex;not null" json:"operationId"`
+	UserID         string     `gorm:"column:user_id;type:varchar(32);index;not null" json:"userId"`
+	AccountID      string     `gorm:"column:account_id;type:varchar(32);index;not null" json:"accountId"`
+	VMID           string     `gorm:"column:vm_id;type:varchar(256);index" json:"vmId"`
+	SubscriptionID string     `gorm:"column:subscription_id;type:varchar(128)" json:"subscriptionId"`
+	ResourceGroup  string     `gorm:"column:resource_group;type:varchar(128)" json:"resourceGroup"`
+	ResourceName   string     `gorm:"column:resource_name;type:varchar(128)" json:"resourceName"`
+	Type           string     `gorm:"column:type;type:varchar(32);not null" json:"type"`
+	Force          bool       `gorm:"column:force" json:"force"`
+	Status         string     `gorm:"column:status;type:varchar(32);index;not null;default:pending" json:"status"` // pending/running/succeeded/failed
+	Progress       int        `gorm:"column:progress;type:int;not null;default:0" json:"progress"`                 // 0-100
+	ResumeToken    string     `gorm:"column:resume_token;type:text" json:"-"`
+	Result         string     `gorm:"column:result;type:text" json:"result"`
+	Error          string     `gorm:"column:error;type:text" json:"error"`
+	StartedAt      *time.Time `gorm:"column:started_at" json:"startedAt"`
+	FinishedAt     *time.Time `gorm:"column:finished_at" json:"finishedAt"`
+}
+
+// 操作状态
+const (
+	OperationStatusPending   = "pending"
+	OperationStatusRunning   = "running"
+	OperationStatusSucceeded = "succeeded"
+	OperationStatusFailed    = "failed"
+)
+
+// TableName 指定表名
+func (o *Operation) TableName() string {
+	return "operations"
+}
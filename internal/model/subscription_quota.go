@@ -0,0 +1,25 @@
+package model
+
+import (
+	"gorm.io/gorm"
+	"time"
+)
+
+// SubscriptionQuota 订阅在某个区域的计算资源配额，每次从Azure获取后整体替换该区域的记录
+type SubscriptionQuota struct {
+	gorm.Model
+	AccountID      string    `gorm:"column:account_id;type:varchar(32);not null;uniqueIndex:idx_subscription_quota" json:"accountId"`
+	SubscriptionID string    `gorm:"column:subscription_id;type:varchar(128);not null;uniqueIndex:idx_subscription_quota" json:"subscriptionId"`
+	Location       string    `gorm:"column:location;type:varchar(64);not null;uniqueIndex:idx_subscription_quota" json:"location"`
+	Name           string    `gorm:"column:name;type:varchar(128);not null;uniqueIndex:idx_subscription_quota" json:"name"` // 配额名称，例如 cores、standardDSv3Family
+	LocalizedName  string    `gorm:"column:localized_name;type:varchar(256)" json:"localizedName"`
+	CurrentValue   int64     `gorm:"column:current_value;not null" json:"currentValue"`
+	Limit          int64     `gorm:"column:quota_limit;not null" json:"limit"`
+	Unit           string    `gorm:"column:unit;type:varchar(32)" json:"unit"`
+	SyncedAt       time.Time `gorm:"column:synced_at" json:"syncedAt"`
+}
+
+// TableName 指定表名
+func (q *SubscriptionQuota) TableName() string {
+	return "subscription_quotas"
+}
@@ -0,0 +1,25 @@
+package model
+
+import (
+	"gorm.io/gorm"
+	"time"
+)
+
+// SubscriptionStateMissing 订阅不再出现在账户的订阅列表中，通常是已被删除或服务主体失去了访问权限
+const SubscriptionStateMissing = "Missing"
+
+// SubscriptionStateChange 订阅状态或账户订阅状态的变化记录，用于统计和追溯
+// SubscriptionID 为空时表示账户的 SubscriptionStatus 发生变化
+type SubscriptionStateChange struct {
+	gorm.Model
+	AccountID      string    `gorm:"column:account_id;type:varchar(32);not null;index:idx_subscription_state_change" json:"accountId"`
+	SubscriptionID string    `gorm:"column:subscription_id;type:varchar(128)" json:"subscriptionId"`
+	FromState      string    `gorm:"column:from_state;type:varchar(32)" json:"fromState"` // 新出现的订阅为空
+	ToState        string    `gorm:"column:to_state;type:varchar(32);not null" json:"toState"`
+	ChangedAt      time.Time `gorm:"column:changed_at;not null;index:idx_subscription_state_change" json:"changedAt"`
+}
+
+// TableName 指定表名
+func (c *SubscriptionStateChange) TableName() string {
+	return "subscription_state_changes"
+}
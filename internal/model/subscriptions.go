@@ -20,6 +20,12 @@ type Subscriptions struct {
 	SpendingLimit        string     `gorm:"column:spending_limit;type:varchar(32)"`
 	StartDate            *time.Time `gorm:"column:start_date"`
 	EndDate              *time.Time `gorm:"column:end_date"`
+
+	// 优惠类型和赠送额度，由 quotaId 和消费限制得出，见 azure.ClassifyOffer
+	OfferType       string     `gorm:"column:offer_type;type:varchar(32);index"`
+	CreditAmount    float64    `gorm:"column:credit_amount"`
+	CreditCurrency  string     `gorm:"column:credit_currency;type:varchar(8)"`
+	CreditExpiresAt *time.Time `gorm:"column:credit_expires_at"` // 赠送额度失效时间，取订阅的 EndDate
 }
 
 func (s *Subscriptions) TableName() string {
@@ -67,5 +73,16 @@ func (s *Subscriptions) FromAzureSubscription(accountID string, azureSub *azure.
 		return err
 	}
 
+	// 判断优惠类型
+	quotaID, _ := azureSub.SubscriptionPolicies["quotaId"].(string)
+	offer := azure.ClassifyOffer(quotaID, azureSub.SpendingLimit)
+	s.OfferType = string(offer.Type)
+	s.CreditAmount = offer.CreditAmount
+	s.CreditCurrency = offer.CreditCurrency
+	s.CreditExpiresAt = nil
+	if offer.CreditExpires {
+		s.CreditExpiresAt = azureSub.EndDate
+	}
+
 	return nil
 }
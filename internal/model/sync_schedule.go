@@ -0,0 +1,51 @@
+package model
+
+import (
+	"gorm.io/gorm"
+	"time"
+)
+
+// SyncSchedule 用户的定时同步设置，由 task 服务加载并注册为定时任务
+type SyncSchedule struct {
+	gorm.Model
+	ScheduleID string     `gorm:"column:schedule_id;type:varchar(64);uniqueIndex;not null" json:"scheduleId"`
+	UserID     string     `gorm:"column:user_id;type:varchar(32);index;not null" json:"userId"`
+	Name       string     `gorm:"column:name;type:varchar(128)" json:"name"`
+	CronExpr   string     `gorm:"column:cron_expr;type:varchar(64);not null" json:"cronExpr"` // 标准5段cron表达式，UTC时间
+	AccountIDs string     `gorm:"column:account_ids;type:text;not null" json:"accountIds"`    // JSON string array
+	Enabled    bool       `gorm:"column:enabled;not null;default:true" json:"enabled"`
+	LastRunAt  *time.Time `gorm:"column:last_run_at" json:"lastRunAt"`
+	LastStatus string     `gorm:"column:last_status;type:varchar(32)" json:"lastStatus"`
+}
+
+// TableName 指定表名
+func (s *SyncSchedule) TableName() string {
+	return "sync_schedules"
+}
+
+// SyncScheduleRun 定时同步的单次执行记录
+type SyncScheduleRun struct {
+	gorm.Model
+	ScheduleID   string     `gorm:"column:schedule_id;type:varchar(64);index;not null" json:"scheduleId"`
+	UserID       string     `gorm:"column:user_id;type:varchar(32);index;not null" json:"userId"`
+	Status       string     `gorm:"column:status;type:varchar(32);index;not null" json:"status"` // running/succeeded/partial/failed
+	SuccessCount int        `gorm:"column:success_count;not null;default:0" json:"successCount"`
+	FailedCount  int        `gorm:"column:failed_count;not null;default:0" json:"failedCount"`
+	Result       string     `gorm:"column:result;type:text" json:"result"` // JSON，同步接口的返回结果
+	Error        string     `gorm:"column:error;type:text" json:"error"`
+	StartedAt    time.Time  `gorm:"column:started_at;not null" json:"startedAt"`
+	FinishedAt   *time.Time `gorm:"column:finished_at" json:"finishedAt"`
+}
+
+// 定时同步执行状态
+const (
+	SyncRunStatusRunning   = "running"
+	SyncRunStatusSucceeded = "succeeded"
+	SyncRunStatusPartial   = "partial"
+	SyncRunStatusFailed    = "failed"
+)
+
+// TableName 指定表名
+func (r *SyncScheduleRun) TableName() string {
+	return "sync_schedule_runs"
+}
@@ -27,11 +27,13 @@ type VirtualMachine struct {
 	Memory         int32     `gorm:"column:memory;type:int" json:"memory"`
 	DnsAlias       string    `gorm:"column:dns_alias;type:int" json:"dnsAlias"`
 	OSDiskSize     int       `gorm:"column:os_disk_size;type:integer" json:"osDiskSize"`
-	DataDisks      string    `gorm:"column:data_disks;type:text" json:"dataDisks"` // JSON array of disk objects
-	Tags           string    `gorm:"column:tags;type:text" json:"tags"`            // JSON object
+	DataDisks      string    `gorm:"column:data_disks;type:text" json:"dataDisks"`           // JSON array of disk objects
+	SecurityGroups string    `gorm:"column:security_groups;type:text" json:"securityGroups"` // JSON array of network security groups
+	Tags           string    `gorm:"column:tags;type:text" json:"tags"`                      // JSON object
 	SyncStatus     string    `gorm:"column:sync_status;type:varchar(32);not null;default:pending" json:"syncStatus"`
 	LastSyncAt     time.Time `gorm:"column:last_sync_at" json:"lastSyncAt"`
 	CreatedTime    time.Time `gorm:"column:created_time" json:"createdTime"`
+	MonthlyCost    float64   `gorm:"column:monthly_cost" json:"monthlyCost"` // 预计月费用(美元)，见 service.VmCostService
 }
 
 // TableName 指定表名
@@ -9,6 +9,7 @@ import (
 // VmImage Azure虚拟机镜像信息
 type VmImage struct {
 	gorm.Model
+	AccountID   string    `gorm:"column:account_id;type:varchar(32);index" json:"accountId"` // 同步该镜像的账户
 	Publisher   string    `gorm:"column:publisher;type:varchar(128);not null" json:"publisher"`
 	Offer       string    `gorm:"column:offer;type:varchar(128);not null" json:"offer"`
 	Sku         string    `gorm:"column:sku;type:varchar(128);not null" json:"sku"`
@@ -0,0 +1,57 @@
+package model
+
+import (
+	"gorm.io/gorm"
+	"time"
+)
+
+// VmScriptSnippet 用户保存的脚本片段，执行时可按 SnippetID 引用
+type VmScriptSnippet struct {
+	gorm.Model
+	SnippetID   string `gorm:"column:snippet_id;type:varchar(64);uniqueIndex;not null" json:"snippetId"`
+	UserID      string `gorm:"column:user_id;type:varchar(32);index;not null" json:"userId"`
+	Name        string `gorm:"column:name;type:varchar(128);not null" json:"name"`
+	OSType      string `gorm:"column:os_type;type:varchar(32)" json:"osType"` // Linux/Windows，为空时不限制
+	Script      string `gorm:"column:script;type:text;not null" json:"script"`
+	Description string `gorm:"column:description;type:varchar(512)" json:"description"`
+}
+
+// TableName 指定表名
+func (s *VmScriptSnippet) TableName() string {
+	return "vm_script_snippets"
+}
+
+// VmCommandExecution 虚拟机内脚本的单次执行记录，按 VMID 关联虚拟机
+// 批量执行时同一批次的记录共享 BatchID
+type VmCommandExecution struct {
+	gorm.Model
+	ExecutionID string     `gorm:"column:execution_id;type:varchar(64);uniqueIndex;not null" json:"executionId"`
+	UserID      string     `gorm:"column:user_id;type:varchar(32);index;not null" json:"userId"`
+	AccountID   string     `gorm:"column:account_id;type:varchar(32);index;not null" json:"accountId"`
+	VMID        string     `gorm:"column:vm_id;type:varchar(256);index;not null" json:"vmId"`
+	VMName      string     `gorm:"column:vm_name;type:varchar(128)" json:"vmName"`
+	BatchID     string     `gorm:"column:batch_id;type:varchar(64);index" json:"batchId"`
+	OperationID string     `gorm:"column:operation_id;type:varchar(64)" json:"operationId"`
+	SnippetID   string     `gorm:"column:snippet_id;type:varchar(64)" json:"snippetId"`
+	CommandID   string     `gorm:"column:command_id;type:varchar(32)" json:"commandId"` // RunShellScript/RunPowerShellScript
+	Script      string     `gorm:"column:script;type:text;not null" json:"script"`
+	Status      string     `gorm:"column:status;type:varchar(32);index;not null" json:"status"` // running/succeeded/failed
+	ExitCode    *int       `gorm:"column:exit_code" json:"exitCode"`
+	Stdout      string     `gorm:"column:stdout;type:text" json:"stdout"`
+	Stderr      string     `gorm:"column:stderr;type:text" json:"stderr"`
+	Error       string     `gorm:"column:error;type:text" json:"error"`
+	StartedAt   time.Time  `gorm:"column:started_at;not null" json:"startedAt"`
+	FinishedAt  *time.Time `gorm:"column:finished_at" json:"finishedAt"`
+}
+
+// 脚本执行状态
+const (
+	CommandStatusRunning   = "running"
+	CommandStatusSucceeded = "succeeded"
+	CommandStatusFailed    = "failed"
+)
+
+// TableName 指定表名
+func (e *VmCommandExecution) TableName() string {
+	return "vm_command_executions"
+}
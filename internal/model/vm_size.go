@@ -9,6 +9,7 @@ import (
 // VmSize Azure虚拟机规格信息
 type VmSize struct {
 	gorm.Model
+	AccountID    string    `gorm:"column:account_id;type:varchar(32);index" json:"accountId"` // 同步该规格的账户
 	Name         string    `gorm:"column:name;type:varchar(64);not null" json:"name"`
 	Location     string    `gorm:"column:location;type:varchar(64);not null" json:"location"`
 	Cores        int       `gorm:"column:cores;not null" json:"cores"`
@@ -21,6 +22,22 @@ type VmSize struct {
 	LastSyncAt   time.Time `gorm:"column:last_sync_at" json:"lastSyncAt"`
 	PricePerHour float64   `gorm:"column:price_per_hour" json:"pricePerHour"`
 	Currency     string    `gorm:"column:currency;type:varchar(16)" json:"currency"`
+
+	// 以下字段来自 Resource SKU，规格通过 ListSizes 同步时为空
+	QuotaFamily           string `gorm:"column:quota_family;type:varchar(64)" json:"quotaFamily"`             // 配额系列，例如 standardDSv3Family
+	Zones                 string `gorm:"column:zones;type:varchar(32)" json:"zones"`                          // 可用的可用区，逗号分隔
+	AcceleratedNetworking bool   `gorm:"column:accelerated_networking" json:"acceleratedNetworking"`          // 是否支持加速网络
+	PremiumIO             bool   `gorm:"column:premium_io" json:"premiumIO"`                                  // 是否支持高级存储
+	HyperVGenerations     string `gorm:"column:hyperv_generations;type:varchar(16)" json:"hyperVGenerations"` // 支持的 Hyper-V 代数，逗号分隔
+
+	// 规格对指定订阅的限制，按订阅保存在 vm_size_restrictions，查询时指定订阅才会填充
+	Restricted        bool   `gorm:"-" json:"restricted"`        // 规格在该区域对订阅不可用
+	RestrictionReason string `gorm:"-" json:"restrictionReason"` // NotAvailableForSubscription 或 QuotaId
+
+	// 以下价格来自 vm_size_prices，PricePerHour 为 Linux 按量价格
+	WindowsPricePerHour     float64 `gorm:"column:windows_price_per_hour" json:"windowsPricePerHour"`
+	SpotPricePerHour        float64 `gorm:"column:spot_price_per_hour" json:"spotPricePerHour"`
+	WindowsSpotPricePerHour float64 `gorm:"column:windows_spot_price_per_hour" json:"windowsSpotPricePerHour"`
 }
 
 func (m *VmSize) TableName() string {
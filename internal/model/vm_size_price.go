@@ -0,0 +1,24 @@
+package model
+
+import (
+	"gorm.io/gorm"
+	"time"
+)
+
+// VmSizePrice 规格在区域内的零售价格(美元/小时)，价格为公开数据，不按账户区分
+type VmSizePrice struct {
+	gorm.Model
+	Name               string    `gorm:"column:name;type:varchar(64);not null;uniqueIndex:idx_vm_size_price" json:"name"`
+	Location           string    `gorm:"column:location;type:varchar(64);not null;uniqueIndex:idx_vm_size_price" json:"location"`
+	Currency           string    `gorm:"column:currency;type:varchar(16)" json:"currency"`
+	LinuxPerHour       float64   `gorm:"column:linux_per_hour" json:"linuxPerHour"` // 没有对应报价时为 0
+	WindowsPerHour     float64   `gorm:"column:windows_per_hour" json:"windowsPerHour"`
+	LinuxSpotPerHour   float64   `gorm:"column:linux_spot_per_hour" json:"linuxSpotPerHour"`
+	WindowsSpotPerHour float64   `gorm:"column:windows_spot_per_hour" json:"windowsSpotPerHour"`
+	SyncedAt           time.Time `gorm:"column:synced_at" json:"syncedAt"`
+}
+
+// TableName 指定表名
+func (p *VmSizePrice) TableName() string {
+	return "vm_size_prices"
+}
@@ -0,0 +1,20 @@
+package model
+
+import "time"
+
+// VmSizeRestriction 规格在区域内对某个订阅的限制，来自 Resource SKU 的 Restrictions
+// 限制只对同步时使用的订阅有效，同一账户的其他订阅可能不受限制
+type VmSizeRestriction struct {
+	ID             uint      `gorm:"primarykey" json:"id"`
+	AccountID      string    `gorm:"column:account_id;type:varchar(32);not null;uniqueIndex:idx_vm_size_restriction" json:"accountId"`
+	SubscriptionID string    `gorm:"column:subscription_id;type:varchar(128);not null;uniqueIndex:idx_vm_size_restriction" json:"subscriptionId"`
+	Location       string    `gorm:"column:location;type:varchar(64);not null;uniqueIndex:idx_vm_size_restriction" json:"location"`
+	Name           string    `gorm:"column:name;type:varchar(64);not null;uniqueIndex:idx_vm_size_restriction" json:"name"`
+	Reason         string    `gorm:"column:reason;type:varchar(64)" json:"reason"` // NotAvailableForSubscription 或 QuotaId
+	SyncedAt       time.Time `gorm:"column:synced_at" json:"syncedAt"`
+}
+
+// TableName 指定表名
+func (r *VmSizeRestriction) TableName() string {
+	return "vm_size_restrictions"
+}
@@ -0,0 +1,28 @@
+package model
+
+import (
+	"gorm.io/gorm"
+	"time"
+)
+
+// VmSnapshot 虚拟机磁盘快照，按 VMID 关联虚拟机，超出保留数量的旧快照会被删除
+type VmSnapshot struct {
+	gorm.Model
+	SnapshotID     string    `gorm:"column:snapshot_id;type:varchar(64);uniqueIndex;not null" json:"snapshotId"`
+	AccountID      string    `gorm:"column:account_id;type:varchar(32);index;not null" json:"accountId"`
+	VMID           string    `gorm:"column:vm_id;type:varchar(256);index;not null" json:"vmId"`
+	SubscriptionID string    `gorm:"column:subscription_id;type:varchar(128)" json:"subscriptionId"`
+	ResourceGroup  string    `gorm:"column:resource_group;type:varchar(128)" json:"resourceGroup"`
+	Name           string    `gorm:"column:name;type:varchar(128);not null" json:"name"`
+	ResourceID     string    `gorm:"column:resource_id;type:varchar(512);not null" json:"resourceId"` // Azure 快照资源ID
+	Location       string    `gorm:"column:location;type:varchar(64)" json:"location"`
+	SourceDiskName string    `gorm:"column:source_disk_name;type:varchar(128)" json:"sourceDiskName"`
+	DiskRole       string    `gorm:"column:disk_role;type:varchar(16);not null" json:"diskRole"` // os/data
+	SizeGB         int       `gorm:"column:size_gb" json:"sizeGB"`
+	SnapshotTime   time.Time `gorm:"column:snapshot_time;index" json:"snapshotTime"`
+}
+
+// TableName 指定表名
+func (s *VmSnapshot) TableName() string {
+	return "vm_snapshots"
+}
@@ -23,6 +23,8 @@ type AccountsRepository interface {
 	UpdateVMCount(ctx context.Context, accountID string, vmCount int64) error
 	GetAccountsByIDs(ctx context.Context, userId string, accountIds []string) ([]*model.Accounts, error)
 	GetNotExistAccountIDs(ctx context.Context, userId string, accountIds []string) ([]string, error)
+	// ListAllAccounts 获取全部账户，不按用户隔离，仅供后台任务使用
+	ListAllAccounts(ctx context.Context) ([]*model.Accounts, error)
 }
 
 func NewAccountsRepository(
@@ -271,3 +273,11 @@ func (r *Repository) GetNotExistAccountIDs(ctx context.Context, userId string, a
 
 	return notExistIds, nil
 }
+
+func (r *accountsRepository) ListAllAccounts(ctx context.Context) ([]*model.Accounts, error) {
+	var accounts []*model.Accounts
+	if err := r.DB(ctx).Order("id").Find(&accounts).Error; err != nil {
+		return nil, fmt.Errorf("查询全部账户失败: %w", err)
+	}
+	return accounts, nil
+}
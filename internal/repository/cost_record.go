@@ -0,0 +1,117 @@
+package repository
+
+import (
+	"azure-vm-backend/internal/model"
+	"context"
+	"fmt"
+	"time"
+)
+
+// CostSumOptions 汇总实际费用的条件，日期范围包含 From 和 To 两天
+type CostSumOptions struct {
+	AccountID      string
+	SubscriptionID string // 为空时汇总账户下全部订阅
+	ResourceID     string // 为空时不按资源过滤，比较时不区分大小写
+	From           time.Time
+	To             time.Time
+	// GroupBy 分组列，subscription_id 或 resource_id，为空时只返回合计
+	GroupBy string
+}
+
+// CostTotal 按分组汇总的实际费用，不分组时 Key 为空
+type CostTotal struct {
+	Key      string `gorm:"column:cost_key"`
+	Cost     float64
+	Currency string
+}
+
+// CostRecordRepository 实际费用记录，调用方需先校验账户归属
+type CostRecordRepository interface {
+	// ReplaceCosts 替换账户下订阅在日期范围内的全部费用记录，同一订阅可能在多个账户下
+	ReplaceCosts(ctx context.Context, accountID, subscriptionID string, from, to time.Time, records []*model.CostRecord) error
+	// SumCosts 按条件汇总费用
+	SumCosts(ctx context.Context, opts CostSumOptions) ([]*CostTotal, error)
+	// LastSyncedAt 账户费用最近一次同步的时间，没有记录时返回 nil
+	LastSyncedAt(ctx context.Context, accountID string) (*time.Time, error)
+}
+
+func NewCostRecordRepository(
+	repository *Repository,
+) CostRecordRepository {
+	return &costRecordRepository{
+		Repository: repository,
+	}
+}
+
+type costRecordRepository struct {
+	*Repository
+}
+
+// ReplaceCosts 删除旧记录后写入新记录，费用在账单结算前会被修正，因此整段替换而不是追加
+func (r *costRecordRepository) ReplaceCosts(ctx context.Context, accountID, subscriptionID string, from, to time.Time, records []*model.CostRecord) error {
+	return r.Transaction(ctx, func(ctx context.Context) error {
+		err := r.DB(ctx).Unscoped().
+			Where("account_id = ? AND subscription_id = ? AND usage_date >= ? AND usage_date <= ?", accountID, subscriptionID, from, to).
+			Delete(&model.CostRecord{}).Error
+		if err != nil {
+			return fmt.Errorf("删除费用记录失败: %w", err)
+		}
+		if len(records) == 0 {
+			return nil
+		}
+
+		for _, record := range records {
+			record.AccountID = accountID
+			record.SubscriptionID = subscriptionID
+		}
+		if err := r.DB(ctx).CreateInBatches(&records, 200).Error; err != nil {
+			return fmt.Errorf("保存费用记录失败: %w", err)
+		}
+		return nil
+	})
+}
+
+func (r *costRecordRepository) SumCosts(ctx context.Context, opts CostSumOptions) ([]*CostTotal, error) {
+	if opts.AccountID == "" {
+		return nil, fmt.Errorf("帐户ID不能为空")
+	}
+
+	selectKey := "'' AS cost_key"
+	switch opts.GroupBy {
+	case "":
+	case "subscription_id", "resource_id":
+		selectKey = opts.GroupBy + " AS cost_key"
+	default:
+		return nil, fmt.Errorf("不支持的分组列: %s", opts.GroupBy)
+	}
+
+	db := r.DB(ctx).Model(&model.CostRecord{}).
+		Select(selectKey+", COALESCE(SUM(cost), 0) AS cost, MAX(currency) AS currency").
+		Where("account_id = ? AND usage_date >= ? AND usage_date <= ?", opts.AccountID, opts.From, opts.To)
+	if opts.SubscriptionID != "" {
+		db = db.Where("subscription_id = ?", opts.SubscriptionID)
+	}
+	if opts.ResourceID != "" {
+		db = db.Where("resource_id = LOWER(?)", opts.ResourceID)
+	}
+	if opts.GroupBy != "" {
+		db = db.Group(opts.GroupBy).Order(opts.GroupBy)
+	}
+
+	var totals []*CostTotal
+	if err := db.Scan(&totals).Error; err != nil {
+		return nil, fmt.Errorf("汇总费用失败: %w", err)
+	}
+	return totals, nil
+}
+
+func (r *costRecordRepository) LastSyncedAt(ctx context.Context, accountID string) (*time.Time, error) {
+	var records []*model.CostRecord
+	if err := r.DB(ctx).Where("account_id = ?", accountID).Order("synced_at DESC").Limit(1).Find(&records).Error; err != nil {
+		return nil, fmt.Errorf("查询费用同步时间失败: %w", err)
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+	return &records[0].SyncedAt, nil
+}
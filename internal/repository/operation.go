@@ -0,0 +1,79 @@
+package repository
+
+import (
+	"azure-vm-backend/internal/model"
+	"context"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+)
+
+type OperationRepository interface {
+	Create(ctx context.Context, op *model.Operation) error
+	GetByOperationID(ctx context.Context, userID, operationID string) (*model.Operation, error)
+	Update(ctx context.Context, operationID string, fields map[string]interface{}) error
+	// ListUnfinished 获取所有未结束的操作，用于服务重启后恢复轮询
+	ListUnfinished(ctx context.Context) ([]*model.Operation, error)
+}
+
+func NewOperationRepository(
+	repository *Repository,
+) OperationRepository {
+	return &operationRepository{
+		Repository: repository,
+	}
+}
+
+type operationRepository struct {
+	*Repository
+}
+
+// Create 创建操作记录
+func (r *operationRepository) Create(ctx context.Context, op *model.Operation) error {
+	if err := r.DB(ctx).Create(op).Error; err != nil {
+		return fmt.Errorf("创建操作记录失败: %w", err)
+	}
+	return nil
+}
+
+// GetByOperationID 获取用户的操作记录，不存在时返回 nil
+func (r *operationRepository) GetByOperationID(ctx context.Context, userID, operationID string) (*model.Operation, error) {
+	var op model.Operation
+	err := r.DB(ctx).
+		Where("user_id = ? AND operation_id = ?", userID, operationID).
+		First(&op).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("查询操作记录失败: %w", err)
+	}
+	return &op, nil
+}
+
+// Update 更新操作记录
+func (r *operationRepository) Update(ctx context.Context, operationID string, fields map[string]interface{}) error {
+	result := r.DB(ctx).Model(&model.Operation{}).
+		Where("operation_id = ?", operationID).
+		Updates(fields)
+	if result.Error != nil {
+		return fmt.Errorf("更新操作记录失败: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("未找到操作记录")
+	}
+	return nil
+}
+
+// ListUnfinished 获取所有未结束的操作
+func (r *operationRepository) ListUnfinished(ctx context.Context) ([]*model.Operation, error) {
+	var ops []*model.Operation
+	err := r.DB(ctx).
+		Where("status IN ?", []string{model.OperationStatusPending, model.OperationStatusRunning}).
+		Order("id asc").
+		Find(&ops).Error
+	if err != nil {
+		return nil, fmt.Errorf("查询未完成操作失败: %w", err)
+	}
+	return ops, nil
+}
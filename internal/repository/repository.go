@@ -1,9 +1,11 @@
 package repository
 
 import (
+	"azure-vm-backend/internal/model"
 	"azure-vm-backend/pkg/log"
 	"azure-vm-backend/pkg/zapgorm2"
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
@@ -17,6 +19,9 @@ import (
 
 const ctxTxKey = "TxKey"
 
+// ErrUserScopeRequired 面向用户的查询未指定用户
+var ErrUserScopeRequired = errors.New("查询必须指定用户")
+
 type Repository struct {
 	db *gorm.DB
 	//rdb    *redis.Client
@@ -55,6 +60,11 @@ func (r *Repository) DB(ctx context.Context) *gorm.DB {
 	return r.db.WithContext(ctx)
 }
 
+// userAccountIDs 返回指定用户未删除账户ID的子查询，用于按用户隔离数据
+func (r *Repository) userAccountIDs(ctx context.Context, userID string) *gorm.DB {
+	return r.DB(ctx).Model(&model.Accounts{}).Select("account_id").Where("user_id = ?", userID)
+}
+
 func (r *Repository) Transaction(ctx context.Context, fn func(ctx context.Context) error) error {
 	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
 		ctx = context.WithValue(ctx, ctxTxKey, tx)
@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"azure-vm-backend/internal/model"
+	"context"
+	"fmt"
+)
+
+type SubscriptionQuotaRepository interface {
+	// ReplaceQuotas 用最新获取的配额替换订阅在该区域的全部记录
+	ReplaceQuotas(ctx context.Context, accountID, subscriptionID, location string, quotas []*model.SubscriptionQuota) error
+	// ListQuotas 获取用户订阅的配额，location 为空时返回所有区域
+	ListQuotas(ctx context.Context, userID, accountID, subscriptionID, location string) ([]*model.SubscriptionQuota, error)
+}
+
+func NewSubscriptionQuotaRepository(
+	repository *Repository,
+) SubscriptionQuotaRepository {
+	return &subscriptionQuotaRepository{
+		Repository: repository,
+	}
+}
+
+type subscriptionQuotaRepository struct {
+	*Repository
+}
+
+// ReplaceQuotas 删除旧记录后写入新记录
+func (r *subscriptionQuotaRepository) ReplaceQuotas(ctx context.Context, accountID, subscriptionID, location string, quotas []*model.SubscriptionQuota) error {
+	return r.Transaction(ctx, func(ctx context.Context) error {
+		err := r.DB(ctx).Unscoped().
+			Where("account_id = ? AND subscription_id = ? AND location = ?", accountID, subscriptionID, location).
+			Delete(&model.SubscriptionQuota{}).Error
+		if err != nil {
+			return fmt.Errorf("删除配额记录失败: %w", err)
+		}
+		if len(quotas) == 0 {
+			return nil
+		}
+		for _, quota := range quotas {
+			quota.AccountID = accountID
+			quota.SubscriptionID = subscriptionID
+			quota.Location = location
+		}
+		if err := r.DB(ctx).Create(&quotas).Error; err != nil {
+			return fmt.Errorf("保存配额记录失败: %w", err)
+		}
+		return nil
+	})
+}
+
+// ListQuotas 获取用户订阅的配额，按区域和名称排序
+func (r *subscriptionQuotaRepository) ListQuotas(ctx context.Context, userID, accountID, subscriptionID, location string) ([]*model.SubscriptionQuota, error) {
+	if userID == "" {
+		return nil, ErrUserScopeRequired
+	}
+	query := r.DB(ctx).
+		Where("account_id = ? AND subscription_id = ? AND account_id IN (?)", accountID, subscriptionID, r.userAccountIDs(ctx, userID))
+	if location != "" {
+		query = query.Where("location = ?", location)
+	}
+	var quotas []*model.SubscriptionQuota
+	if err := query.Order("location, name").Find(&quotas).Error; err != nil {
+		return nil, fmt.Errorf("查询配额记录失败: %w", err)
+	}
+	return quotas, nil
+}
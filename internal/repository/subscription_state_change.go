@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"azure-vm-backend/internal/model"
+	"azure-vm-backend/pkg/app"
+	"context"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+type SubscriptionStateChangeRepository interface {
+	// CreateChanges 批量写入状态变化记录
+	CreateChanges(ctx context.Context, changes []*model.SubscriptionStateChange) error
+	// ListChanges 分页查询用户账户下的状态变化记录，按变化时间倒序，subscriptionID 为空时不按订阅过滤
+	ListChanges(ctx context.Context, userID, accountID, subscriptionID string, query *app.QueryOption) (*app.ListResult[*model.SubscriptionStateChange], error)
+}
+
+func NewSubscriptionStateChangeRepository(
+	repository *Repository,
+) SubscriptionStateChangeRepository {
+	return &subscriptionStateChangeRepository{
+		Repository: repository,
+	}
+}
+
+type subscriptionStateChangeRepository struct {
+	*Repository
+}
+
+func (r *subscriptionStateChangeRepository) CreateChanges(ctx context.Context, changes []*model.SubscriptionStateChange) error {
+	if len(changes) == 0 {
+		return nil
+	}
+	if err := r.DB(ctx).Create(&changes).Error; err != nil {
+		return fmt.Errorf("保存订阅状态变化失败: %w", err)
+	}
+	return nil
+}
+
+func (r *subscriptionStateChangeRepository) ListChanges(ctx context.Context, userID, accountID, subscriptionID string, query *app.QueryOption) (*app.ListResult[*model.SubscriptionStateChange], error) {
+	if userID == "" {
+		return nil, ErrUserScopeRequired
+	}
+	return app.WithPagination[*model.SubscriptionStateChange](
+		r.DB(ctx),
+		query,
+		func(db *gorm.DB) *gorm.DB {
+			baseQuery := db.Model(&model.SubscriptionStateChange{}).
+				Where("account_id = ? AND account_id IN (?)", accountID, r.userAccountIDs(ctx, userID))
+			if subscriptionID != "" {
+				baseQuery = baseQuery.Where("subscription_id = ?", subscriptionID)
+			}
+			if query.SortBy == "" {
+				baseQuery = baseQuery.Order("changed_at DESC, id DESC")
+				query.SortOrder = ""
+			}
+			return baseQuery
+		},
+	)
+}
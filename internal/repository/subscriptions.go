@@ -9,12 +9,12 @@ import (
 )
 
 type SubscriptionsRepository interface {
-	// UpsertSubscriptions 批量更新或插入订阅信息
-	UpsertSubscriptions(ctx context.Context, subs []*model.Subscriptions) error
-	// GetSubscriptionsByAccountId 获取账号下的所有订阅
-	GetSubscriptionsByAccountId(ctx context.Context, accountId string) ([]*model.Subscriptions, error)
-	// GetSubscription 获取指定的订阅信息
-	GetSubscription(ctx context.Context, accountId, subscriptionId string) (*model.Subscriptions, error)
+	// UpsertSubscriptions 批量更新或插入账号下的订阅信息，不在列表中的订阅标记为 Missing 并软删除
+	UpsertSubscriptions(ctx context.Context, accountID string, subs []*model.Subscriptions) error
+	// GetSubscriptionsByAccountId 获取用户账号下的所有订阅
+	GetSubscriptionsByAccountId(ctx context.Context, userId, accountId string) ([]*model.Subscriptions, error)
+	// GetSubscription 获取用户账号下指定的订阅信息
+	GetSubscription(ctx context.Context, userId, accountId, subscriptionId string) (*model.Subscriptions, error)
 	// DeleteSubscriptionsByAccountId 删除账号下的所有订阅
 	DeleteSubscriptionsByAccountId(ctx context.Context, accountId string) error
 	//ListAllUserSubscriptions 查询当前用户的所有azure丁页
@@ -34,13 +34,9 @@ type subscriptionsRepository struct {
 }
 
 // UpsertSubscriptions 批量更新或插入订阅信息
-func (r *Repository) UpsertSubscriptions(ctx context.Context, subscriptions []*model.Subscriptions) error {
+// 订阅列表为空时同样需要处理已消失的订阅，因此账号ID单独传入
+func (r *Repository) UpsertSubscriptions(ctx context.Context, accountID string, subscriptions []*model.Subscriptions) error {
 	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
-		if len(subscriptions) == 0 {
-			return nil
-		}
-		accountID := subscriptions[0].AccountID
-
 		// 1. 获取该账号下所有现有订阅（包括已删除的）
 		var existingSubs []model.Subscriptions
 		if err := tx.Unscoped().Where("account_id = ?", accountID).Find(&existingSubs).Error; err != nil {
@@ -66,6 +62,10 @@ func (r *Repository) UpsertSubscriptions(ctx context.Context, subscriptions []*m
 					"spending_limit":        newSub.SpendingLimit,
 					"start_date":            newSub.StartDate,
 					"end_date":              newSub.EndDate,
+					"offer_type":            newSub.OfferType,
+					"credit_amount":         newSub.CreditAmount,
+					"credit_currency":       newSub.CreditCurrency,
+					"credit_expires_at":     newSub.CreditExpiresAt,
 					"deleted_at":            nil, // 恢复已删除的记录
 				}
 
@@ -83,12 +83,20 @@ func (r *Repository) UpsertSubscriptions(ctx context.Context, subscriptions []*m
 			}
 		}
 
-		// 4. 软删除不再存在的订阅
+		// 4. 不再存在的订阅记录为 Missing 后软删除，已删除的记录不再重复处理
 		var subsToDelete []string
-		for subID := range existingSubMap {
+		for subID, existing := range existingSubMap {
+			if existing.DeletedAt.Valid {
+				continue
+			}
 			subsToDelete = append(subsToDelete, subID)
 		}
 		if len(subsToDelete) > 0 {
+			if err := tx.Model(&model.Subscriptions{}).
+				Where("account_id = ? AND subscription_id IN ?", accountID, subsToDelete).
+				Update("state", model.SubscriptionStateMissing).Error; err != nil {
+				return err
+			}
 			if err := tx.Where("account_id = ? AND subscription_id IN ?", accountID, subsToDelete).
 				Delete(&model.Subscriptions{}).Error; err != nil {
 				return err
@@ -99,17 +107,27 @@ func (r *Repository) UpsertSubscriptions(ctx context.Context, subscriptions []*m
 	})
 }
 
-// GetSubscriptionsByAccountId 获取账号下的所有订阅
-func (r *subscriptionsRepository) GetSubscriptionsByAccountId(ctx context.Context, accountId string) ([]*model.Subscriptions, error) {
+// GetSubscriptionsByAccountId 获取用户账号下的所有订阅
+func (r *subscriptionsRepository) GetSubscriptionsByAccountId(ctx context.Context, userId, accountId string) ([]*model.Subscriptions, error) {
+	if userId == "" {
+		return nil, ErrUserScopeRequired
+	}
 	var subs []*model.Subscriptions
-	err := r.DB(ctx).Where("account_id = ?", accountId).Find(&subs).Error
+	err := r.DB(ctx).
+		Where("account_id = ? AND account_id IN (?)", accountId, r.userAccountIDs(ctx, userId)).
+		Find(&subs).Error
 	return subs, err
 }
 
-// GetSubscription 获取指定的订阅信息
-func (r *subscriptionsRepository) GetSubscription(ctx context.Context, accountId, subscriptionId string) (*model.Subscriptions, error) {
+// GetSubscription 获取用户账号下指定的订阅信息，不属于该用户时返回 nil
+func (r *subscriptionsRepository) GetSubscription(ctx context.Context, userId, accountId, subscriptionId string) (*model.Subscriptions, error) {
+	if userId == "" {
+		return nil, ErrUserScopeRequired
+	}
 	var sub model.Subscriptions
-	err := r.DB(ctx).Where("account_id = ? AND subscription_id = ?", accountId, subscriptionId).First(&sub).Error
+	err := r.DB(ctx).
+		Where("account_id = ? AND subscription_id = ? AND account_id IN (?)", accountId, subscriptionId, r.userAccountIDs(ctx, userId)).
+		First(&sub).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, nil
@@ -138,6 +156,9 @@ func (r *subscriptionsRepository) ListAllUserSubscriptions(ctx context.Context,
 			if search, exists := query.Filters["search"]; exists && search != "" {
 				baseQuery = baseQuery.Where("subscriptions.display_name LIKE ?", "%"+search+"%")
 			}
+			if offerType, exists := query.Filters["offerType"]; exists && offerType != "" {
+				baseQuery = baseQuery.Where("subscriptions.offer_type = ?", offerType)
+			}
 
 			// 设置默认排序
 			if query.SortBy == "" {
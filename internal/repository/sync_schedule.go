@@ -0,0 +1,192 @@
+package repository
+
+import (
+	"azure-vm-backend/internal/model"
+	"azure-vm-backend/pkg/app"
+	"context"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"time"
+)
+
+type SyncScheduleRepository interface {
+	Create(ctx context.Context, schedule *model.SyncSchedule) error
+	// GetByScheduleID 获取用户的定时同步设置，不存在时返回 nil
+	GetByScheduleID(ctx context.Context, userID, scheduleID string) (*model.SyncSchedule, error)
+	Update(ctx context.Context, userID, scheduleID string, fields map[string]interface{}) error
+	Delete(ctx context.Context, userID, scheduleID string) error
+	// ListByUserID 获取用户的所有定时同步设置
+	ListByUserID(ctx context.Context, userID string) ([]*model.SyncSchedule, error)
+	// ListEnabled 获取所有启用的定时同步设置，供 task 服务注册定时任务
+	ListEnabled(ctx context.Context) ([]*model.SyncSchedule, error)
+
+	// CreateRun 创建执行记录
+	CreateRun(ctx context.Context, run *model.SyncScheduleRun) error
+	// FinishRun 写入执行结果并更新定时设置的最近执行状态
+	FinishRun(ctx context.Context, run *model.SyncScheduleRun) error
+	// ListRuns 分页查询定时同步的执行记录，按开始时间倒序
+	ListRuns(ctx context.Context, userID, scheduleID string, query *app.QueryOption) (*app.ListResult[*model.SyncScheduleRun], error)
+	// FailInterruptedRuns 将服务重启前未结束的执行记录标记为失败，返回处理数量
+	FailInterruptedRuns(ctx context.Context, reason string) (int64, error)
+}
+
+func NewSyncScheduleRepository(
+	repository *Repository,
+) SyncScheduleRepository {
+	return &syncScheduleRepository{
+		Repository: repository,
+	}
+}
+
+type syncScheduleRepository struct {
+	*Repository
+}
+
+// Create 创建定时同步设置
+func (r *syncScheduleRepository) Create(ctx context.Context, schedule *model.SyncSchedule) error {
+	if err := r.DB(ctx).Create(schedule).Error; err != nil {
+		return fmt.Errorf("创建定时同步设置失败: %w", err)
+	}
+	return nil
+}
+
+// GetByScheduleID 获取用户的定时同步设置
+func (r *syncScheduleRepository) GetByScheduleID(ctx context.Context, userID, scheduleID string) (*model.SyncSchedule, error) {
+	var schedule model.SyncSchedule
+	err := r.DB(ctx).
+		Where("user_id = ? AND schedule_id = ?", userID, scheduleID).
+		First(&schedule).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("查询定时同步设置失败: %w", err)
+	}
+	return &schedule, nil
+}
+
+// Update 更新定时同步设置
+func (r *syncScheduleRepository) Update(ctx context.Context, userID, scheduleID string, fields map[string]interface{}) error {
+	result := r.DB(ctx).Model(&model.SyncSchedule{}).
+		Where("user_id = ? AND schedule_id = ?", userID, scheduleID).
+		Updates(fields)
+	if result.Error != nil {
+		return fmt.Errorf("更新定时同步设置失败: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("未找到定时同步设置")
+	}
+	return nil
+}
+
+// Delete 删除定时同步设置，执行记录保留
+func (r *syncScheduleRepository) Delete(ctx context.Context, userID, scheduleID string) error {
+	result := r.DB(ctx).
+		Where("user_id = ? AND schedule_id = ?", userID, scheduleID).
+		Delete(&model.SyncSchedule{})
+	if result.Error != nil {
+		return fmt.Errorf("删除定时同步设置失败: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("未找到定时同步设置")
+	}
+	return nil
+}
+
+// ListByUserID 获取用户的所有定时同步设置
+func (r *syncScheduleRepository) ListByUserID(ctx context.Context, userID string) ([]*model.SyncSchedule, error) {
+	var schedules []*model.SyncSchedule
+	err := r.DB(ctx).
+		Where("user_id = ?", userID).
+		Order("id asc").
+		Find(&schedules).Error
+	if err != nil {
+		return nil, fmt.Errorf("查询定时同步设置失败: %w", err)
+	}
+	return schedules, nil
+}
+
+// ListEnabled 获取所有启用的定时同步设置
+func (r *syncScheduleRepository) ListEnabled(ctx context.Context) ([]*model.SyncSchedule, error) {
+	var schedules []*model.SyncSchedule
+	err := r.DB(ctx).
+		Where("enabled = ?", true).
+		Order("id asc").
+		Find(&schedules).Error
+	if err != nil {
+		return nil, fmt.Errorf("查询启用的定时同步设置失败: %w", err)
+	}
+	return schedules, nil
+}
+
+// CreateRun 创建执行记录
+func (r *syncScheduleRepository) CreateRun(ctx context.Context, run *model.SyncScheduleRun) error {
+	if err := r.DB(ctx).Create(run).Error; err != nil {
+		return fmt.Errorf("创建执行记录失败: %w", err)
+	}
+	return nil
+}
+
+// FinishRun 写入执行结果并更新定时设置的最近执行状态
+func (r *syncScheduleRepository) FinishRun(ctx context.Context, run *model.SyncScheduleRun) error {
+	return r.Transaction(ctx, func(ctx context.Context) error {
+		err := r.DB(ctx).Model(&model.SyncScheduleRun{}).
+			Where("id = ?", run.ID).
+			Updates(map[string]interface{}{
+				"status":        run.Status,
+				"success_count": run.SuccessCount,
+				"failed_count":  run.FailedCount,
+				"result":        run.Result,
+				"error":         run.Error,
+				"finished_at":   run.FinishedAt,
+			}).Error
+		if err != nil {
+			return fmt.Errorf("更新执行记录失败: %w", err)
+		}
+
+		// 定时设置可能已被删除，不视为错误
+		err = r.DB(ctx).Model(&model.SyncSchedule{}).
+			Where("schedule_id = ?", run.ScheduleID).
+			Updates(map[string]interface{}{
+				"last_run_at": run.StartedAt,
+				"last_status": run.Status,
+			}).Error
+		if err != nil {
+			return fmt.Errorf("更新定时同步设置失败: %w", err)
+		}
+		return nil
+	})
+}
+
+// ListRuns 分页查询定时同步的执行记录
+func (r *syncScheduleRepository) ListRuns(ctx context.Context, userID, scheduleID string, query *app.QueryOption) (*app.ListResult[*model.SyncScheduleRun], error) {
+	return app.WithPagination[*model.SyncScheduleRun](
+		r.DB(ctx),
+		query,
+		func(db *gorm.DB) *gorm.DB {
+			baseQuery := db.Model(&model.SyncScheduleRun{}).
+				Where("user_id = ? AND schedule_id = ?", userID, scheduleID)
+			if query.SortBy == "" {
+				baseQuery = baseQuery.Order("started_at DESC, id DESC")
+				query.SortOrder = ""
+			}
+			return baseQuery
+		},
+	)
+}
+
+// FailInterruptedRuns 将未结束的执行记录标记为失败
+func (r *syncScheduleRepository) FailInterruptedRuns(ctx context.Context, reason string) (int64, error) {
+	result := r.DB(ctx).Model(&model.SyncScheduleRun{}).
+		Where("status = ?", model.SyncRunStatusRunning).
+		Updates(map[string]interface{}{
+			"status":      model.SyncRunStatusFailed,
+			"error":       reason,
+			"finished_at": time.Now(),
+		})
+	if result.Error != nil {
+		return 0, fmt.Errorf("更新中断的执行记录失败: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
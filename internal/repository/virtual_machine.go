@@ -7,11 +7,13 @@ import (
 	"encoding/json"
 	"fmt"
 	"gorm.io/gorm"
+	"strings"
 	"time"
 )
 
 // QueryVMsOptions VM查询选项
 type QueryVMsOptions struct {
+	UserID         string            // 用户ID，必填，只返回该用户账户下的虚拟机
 	AccountID      string            // 账号ID
 	SubscriptionID string            // 订阅ID
 	Query          *app.QueryOption  // 通用查询选项(分页、排序等)
@@ -25,19 +27,41 @@ type VirtualMachineRepository interface {
 	Update(ctx context.Context, vm *model.VirtualMachine) error
 	Delete(ctx context.Context, vmID string) error
 
-	// ListVMs 查询操作
+	// ListVMs 查询操作，按用户隔离
 	ListVMs(ctx context.Context, opts QueryVMsOptions) (*app.ListResult[*model.VirtualMachine], error)
-	// ListBySubscriptionID 根据订阅ID查询
-	ListBySubscriptionID(ctx context.Context, subscriptionID string, query *app.QueryOption) (*app.ListResult[*model.VirtualMachine], error)
 	// ListByAccountID 根据账号ID查询
-	ListByAccountID(ctx context.Context, accountID string, query *app.QueryOption) (*app.ListResult[*model.VirtualMachine], error)
+	ListByAccountID(ctx context.Context, userID, accountID string, query *app.QueryOption) (*app.ListResult[*model.VirtualMachine], error)
 	// ListByAccountAndSubscription 根据账号ID和订阅ID查询
-	ListByAccountAndSubscription(ctx context.Context, accountID string, subscriptionID string, query *app.QueryOption) (*app.ListResult[*model.VirtualMachine], error)
+	ListByAccountAndSubscription(ctx context.Context, userID, accountID, subscriptionID string, query *app.QueryOption) (*app.ListResult[*model.VirtualMachine], error)
+	// CountByAccountID 统计账号下的虚拟机数量，供同步等内部流程使用
+	CountByAccountID(ctx context.Context, accountID string) (int64, error)
 	// BatchUpsert 批量更新或插入
 	BatchUpsert(ctx context.Context, vms []*model.VirtualMachine) error
+	// ReconcileMissing 将指定订阅下 Azure 已不存在的虚拟机标记为 missing 并软删除，返回处理数量
+	ReconcileMissing(ctx context.Context, accountID string, subscriptionIDs []string, presentVMIDs []string) (int64, error)
 	// UpdateStatus 状态相关操作
 	UpdateStatus(ctx context.Context, vmID string, status string) error
 	UpdateDNSLabel(ctx context.Context, vmID string, dnsLabel string) error
+	// UpdatePublicIP 更换公网IP后更新公网IP地址、名称和DNS
+	UpdatePublicIP(ctx context.Context, vmID string, publicIPs []string, publicIPName, dnsAlias string) error
+	// UpdateSize 调整规格后更新规格、核数、内存和电源状态
+	UpdateSize(ctx context.Context, vmID, size string, core, memory int32, powerState string) error
+	// UpdateDisks 挂载、卸载或扩容磁盘后更新系统盘大小和磁盘信息
+	UpdateDisks(ctx context.Context, vmID string, osDiskSize int, dataDisks string) error
+	// UpdateSecurityGroups 修改网络安全组后更新网络安全组信息
+	UpdateSecurityGroups(ctx context.Context, vmID string, securityGroups string) error
+	// UpdateMonthlyCost 调整规格或磁盘后更新预计月费用
+	UpdateMonthlyCost(ctx context.Context, vmID string, monthlyCost float64) error
+	// SumMonthlyCosts 按账户和订阅汇总虚拟机的预计月费用
+	SumMonthlyCosts(ctx context.Context, accountIDs []string) ([]*MonthlyCostTotal, error)
+}
+
+// MonthlyCostTotal 账户下单个订阅的虚拟机数量和预计月费用
+type MonthlyCostTotal struct {
+	AccountID      string
+	SubscriptionID string
+	VMCount        int64
+	MonthlyCost    float64
 }
 
 func NewVirtualMachineRepository(
@@ -54,19 +78,47 @@ type virtualMachineRepository struct {
 
 // Create 创建虚拟机记录
 func (r *virtualMachineRepository) Create(ctx context.Context, vm *model.VirtualMachine) error {
-	// TODO: 实现虚拟机创建逻辑
-	// 1. 验证必要字段
-	// 2. 检查重复记录
-	// 3. 创建记录
+	if vm.VMID == "" || vm.AccountID == "" {
+		return fmt.Errorf("虚拟机ID和账户ID不能为空")
+	}
+
+	// vm_id 有唯一索引，需要包括已软删除的记录
+	var existing []*model.VirtualMachine
+	if err := r.DB(ctx).Unscoped().Select("id", "created_at", "deleted_at").
+		Where("vm_id = ?", vm.VMID).Limit(1).Find(&existing).Error; err != nil {
+		return fmt.Errorf("检查虚拟机记录失败: %w", err)
+	}
+	if len(existing) > 0 {
+		if !existing[0].DeletedAt.Valid {
+			return fmt.Errorf("虚拟机记录已存在: %s", vm.VMID)
+		}
+		// 在Azure中删除后以相同名称重新创建，覆盖并恢复已软删除的记录
+		vm.ID = existing[0].ID
+		vm.CreatedAt = existing[0].CreatedAt
+		vm.DeletedAt = gorm.DeletedAt{}
+		if err := r.DB(ctx).Unscoped().Select("*").Save(vm).Error; err != nil {
+			return fmt.Errorf("恢复虚拟机记录失败: %w", err)
+		}
+		return nil
+	}
+
+	if err := r.DB(ctx).Create(vm).Error; err != nil {
+		return fmt.Errorf("创建虚拟机记录失败: %w", err)
+	}
 	return nil
 }
 
 // ListVMs 统一的虚拟机查询方法
+// 未指定用户时返回 ErrUserScopeRequired，避免返回所有用户的虚拟机
 func (r *virtualMachineRepository) ListVMs(ctx context.Context, opts QueryVMsOptions) (*app.ListResult[*model.VirtualMachine], error) {
+	if opts.UserID == "" {
+		return nil, ErrUserScopeRequired
+	}
 	opts.Query = app.ValidateAndFillQueryOption(opts.Query)
 
 	baseQuery := func(db *gorm.DB) *gorm.DB {
-		q := db.Model(&model.VirtualMachine{})
+		q := db.Model(&model.VirtualMachine{}).
+			Where("account_id IN (?)", r.userAccountIDs(ctx, opts.UserID))
 
 		// 添加基本查询条件
 		if opts.AccountID != "" {
@@ -101,13 +153,28 @@ func (r *virtualMachineRepository) ListVMs(ctx context.Context, opts QueryVMsOpt
 			for field, value := range opts.ExtraFilters {
 				if value != "" {
 					switch field {
-					case "os_type":
-						q = q.Where("os_type = ?", value)
+					case "name", "resource_group", "location", "status", "size", "sync_status", "os_type":
+						q = q.Where(field+" = ?", value)
 					case "name_like":
 						q = q.Where("name LIKE ?", "%"+value+"%")
 					case "tag":
 						q = q.Where("tags LIKE ?", "%"+value+"%")
-						// 可以根据需要添加更多过滤条件
+					case "start_time", "end_time":
+						t, err := time.Parse(time.RFC3339, value)
+						if err != nil {
+							continue
+						}
+						if field == "start_time" {
+							q = q.Where("created_time >= ?", t)
+						} else {
+							q = q.Where("created_time <= ?", t)
+						}
+					default:
+						// 标签过滤，tags 为 JSON 对象，按键值对匹配
+						if strings.HasPrefix(field, "tag_") {
+							pair, _ := json.Marshal(map[string]string{strings.TrimPrefix(field, "tag_"): value})
+							q = q.Where("tags LIKE ?", "%"+strings.Trim(string(pair), "{}")+"%")
+						}
 					}
 				}
 			}
@@ -143,22 +210,111 @@ func (r *virtualMachineRepository) GetVM(ctx context.Context, ID string) (*model
 
 // Update 更新虚拟机信息
 func (r *virtualMachineRepository) Update(ctx context.Context, vm *model.VirtualMachine) error {
-	// TODO: 实现虚拟机更新逻辑
-	// 1. 验证更新字段
-	// 2. 检查记录是否存在
-	// 3. 执行更新操作
+	if vm.ID == 0 {
+		return fmt.Errorf("虚拟机记录ID不能为空")
+	}
+
+	result := r.DB(ctx).Model(&model.VirtualMachine{}).
+		Where("id = ?", vm.ID).
+		Updates(map[string]interface{}{
+			"vm_id":           vm.VMID,
+			"name":            vm.Name,
+			"resource_group":  vm.ResourceGroup,
+			"location":        vm.Location,
+			"size":            vm.Size,
+			"status":          vm.Status,
+			"state":           vm.State,
+			"power_state":     vm.PowerState,
+			"private_ips":     vm.PrivateIPs,
+			"public_ips":      vm.PublicIPs,
+			"public_ip_name":  vm.PublicIPName,
+			"os_type":         vm.OSType,
+			"os_image":        vm.OSImage,
+			"core":            vm.Core,
+			"memory":          vm.Memory,
+			"dns_alias":       vm.DnsAlias,
+			"os_disk_size":    vm.OSDiskSize,
+			"data_disks":      vm.DataDisks,
+			"security_groups": vm.SecurityGroups,
+			"tags":            vm.Tags,
+			"sync_status":     vm.SyncStatus,
+			"last_sync_at":    vm.LastSyncAt,
+			"created_time":    vm.CreatedTime,
+		})
+	if result.Error != nil {
+		return fmt.Errorf("更新虚拟机记录失败: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("未找到虚拟机记录")
+	}
 	return nil
 }
 
-// Delete 删除虚拟机记录
+// Delete 软删除虚拟机记录
 func (r *virtualMachineRepository) Delete(ctx context.Context, vmID string) error {
-	// TODO: 实现虚拟机删除逻辑
-	// 1. 验证ID
-	// 2. 检查记录是否存在
-	// 3. 执行软删除操作
+	if vmID == "" {
+		return fmt.Errorf("虚拟机ID不能为空")
+	}
+
+	result := r.DB(ctx).Where("vm_id = ?", vmID).Delete(&model.VirtualMachine{})
+	if result.Error != nil {
+		return fmt.Errorf("删除虚拟机记录失败: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("未找到虚拟机记录")
+	}
 	return nil
 }
 
+// ReconcileMissing 对账已删除的虚拟机
+// 仅处理已同步过的记录，sync_status=pending 的记录仍在创建中，不参与对账
+// Azure 返回的资源ID大小写不固定，比较时忽略大小写
+func (r *virtualMachineRepository) ReconcileMissing(ctx context.Context, accountID string, subscriptionIDs []string, presentVMIDs []string) (int64, error) {
+	if len(subscriptionIDs) == 0 {
+		return 0, nil
+	}
+
+	present := make(map[string]struct{}, len(presentVMIDs))
+	for _, id := range presentVMIDs {
+		present[strings.ToLower(id)] = struct{}{}
+	}
+
+	var missing int64
+	err := r.DB(ctx).Transaction(func(tx *gorm.DB) error {
+		var existingVMs []*model.VirtualMachine
+		if err := tx.Select("id", "vm_id").
+			Where("account_id = ? AND subscription_id IN ? AND sync_status <> ?", accountID, subscriptionIDs, "pending").
+			Find(&existingVMs).Error; err != nil {
+			return fmt.Errorf("查询现有虚拟机失败: %w", err)
+		}
+
+		var ids []uint
+		for _, vm := range existingVMs {
+			if _, ok := present[strings.ToLower(vm.VMID)]; !ok {
+				ids = append(ids, vm.ID)
+			}
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+
+		if err := tx.Model(&model.VirtualMachine{}).Where("id IN ?", ids).
+			Updates(map[string]interface{}{
+				"sync_status":  "missing",
+				"last_sync_at": time.Now(),
+			}).Error; err != nil {
+			return fmt.Errorf("标记缺失虚拟机失败: %w", err)
+		}
+		result := tx.Where("id IN ?", ids).Delete(&model.VirtualMachine{})
+		if result.Error != nil {
+			return fmt.Errorf("删除缺失虚拟机失败: %w", result.Error)
+		}
+		missing = result.RowsAffected
+		return nil
+	})
+	return missing, err
+}
+
 func (r *virtualMachineRepository) BatchUpsert(ctx context.Context, vms []*model.VirtualMachine) error {
 	if len(vms) == 0 {
 		return nil
@@ -166,22 +322,22 @@ func (r *virtualMachineRepository) BatchUpsert(ctx context.Context, vms []*model
 
 	// 开始事务
 	return r.DB(ctx).Transaction(func(tx *gorm.DB) error {
-		// 获取所有VM的ID列表
+		// 获取所有VM的ID列表，Azure 在不同接口返回的资源组大小写可能不同，按小写匹配
 		var vmIDs []string
 		for _, vm := range vms {
-			vmIDs = append(vmIDs, vm.VMID)
+			vmIDs = append(vmIDs, strings.ToLower(vm.VMID))
 		}
 
-		// 获取数据库中已存在的记录
+		// 获取数据库中已存在的记录，包括已软删除的记录，重新出现的虚拟机直接恢复
 		var existingVMs []*model.VirtualMachine
-		if err := tx.Where("vm_id IN ?", vmIDs).Find(&existingVMs).Error; err != nil {
+		if err := tx.Unscoped().Where("LOWER(vm_id) IN ?", vmIDs).Find(&existingVMs).Error; err != nil {
 			return fmt.Errorf("查询现有虚拟机失败: %w", err)
 		}
 
 		// 创建现有VM ID的映射，用于快速查找
 		existingVMMap := make(map[string]*model.VirtualMachine)
 		for _, vm := range existingVMs {
-			existingVMMap[vm.VMID] = vm
+			existingVMMap[strings.ToLower(vm.VMID)] = vm
 		}
 
 		// 分别处理更新和插入
@@ -190,15 +346,6 @@ func (r *virtualMachineRepository) BatchUpsert(ctx context.Context, vms []*model
 		now := time.Now()
 
 		for _, vm := range vms {
-			// 转换磁盘信息为JSON字符串
-			if len(vm.DataDisks) > 0 {
-				dataDisksJSON, err := json.Marshal(vm.DataDisks)
-				if err != nil {
-					return fmt.Errorf("为虚拟机调用数据磁盘失败 %s: %w", vm.VMID, err)
-				}
-				vm.DataDisks = string(dataDisksJSON)
-			}
-
 			// 转换标签为JSON字符串
 			if len(vm.Tags) > 0 {
 				tagsJSON, err := json.Marshal(vm.Tags)
@@ -207,9 +354,10 @@ func (r *virtualMachineRepository) BatchUpsert(ctx context.Context, vms []*model
 				}
 				vm.Tags = string(tagsJSON)
 			}
-			if existing, exists := existingVMMap[vm.VMID]; exists {
-				// 更新现有记录
+			if existing, exists := existingVMMap[strings.ToLower(vm.VMID)]; exists {
+				// 更新现有记录，保留已记录的虚拟机ID
 				vm.ID = existing.ID
+				vm.VMID = existing.VMID
 				vm.CreatedAt = existing.CreatedAt
 				vm.UpdatedAt = now
 				toUpdate = append(toUpdate, vm)
@@ -245,30 +393,37 @@ func (r *virtualMachineRepository) BatchUpsert(ctx context.Context, vms []*model
 				}
 				for _, vm := range toUpdate[i:end] {
 					updateFields := map[string]interface{}{
-						"name":           vm.Name,
-						"resource_group": vm.ResourceGroup,
-						"location":       vm.Location,
-						"size":           vm.Size,
-						"status":         vm.Status,
-						"state":          vm.State,
-						"private_ips":    vm.PrivateIPs,
-						"public_ips":     vm.PublicIPs,
-						"public_ip_name": vm.PublicIPName,
-						"os_type":        vm.OSType,
-						"os_disk_size":   vm.OSDiskSize,
-						"data_disks":     vm.DataDisks,
-						"power_state":    vm.PowerState,
-						"tags":           vm.Tags,
-						"sync_status":    vm.SyncStatus,
-						"last_sync_at":   vm.LastSyncAt,
-						"updated_at":     now,
-						"core":           vm.Core,
-						"memory":         vm.Memory,
-						"os_image":       vm.OSImage,
-						"dns_alias":      vm.DnsAlias,
+						"name":            vm.Name,
+						"resource_group":  vm.ResourceGroup,
+						"location":        vm.Location,
+						"size":            vm.Size,
+						"status":          vm.Status,
+						"state":           vm.State,
+						"private_ips":     vm.PrivateIPs,
+						"public_ips":      vm.PublicIPs,
+						"public_ip_name":  vm.PublicIPName,
+						"os_type":         vm.OSType,
+						"os_disk_size":    vm.OSDiskSize,
+						"data_disks":      vm.DataDisks,
+						"security_groups": vm.SecurityGroups,
+						"power_state":     vm.PowerState,
+						"tags":            vm.Tags,
+						"sync_status":     vm.SyncStatus,
+						"last_sync_at":    vm.LastSyncAt,
+						"updated_at":      now,
+						"core":            vm.Core,
+						"memory":          vm.Memory,
+						"os_image":        vm.OSImage,
+						"dns_alias":       vm.DnsAlias,
+						"monthly_cost":    vm.MonthlyCost,
+						"deleted_at":      nil,
+					}
+					// 同步时未能获取网络安全组，保留已保存的数据
+					if vm.SecurityGroups == "" {
+						delete(updateFields, "security_groups")
 					}
 
-					if err := tx.Model(vm).Updates(updateFields).Error; err != nil {
+					if err := tx.Unscoped().Model(vm).Updates(updateFields).Error; err != nil {
 						return fmt.Errorf("更新虚拟机失败 %s: %w", vm.VMID, err)
 					}
 				}
@@ -300,30 +455,34 @@ func (r *virtualMachineRepository) UpdateStatus(ctx context.Context, vmID string
 	return nil
 }
 
-// ListByAccountID 获取指定账号的所有虚拟机
-func (r *virtualMachineRepository) ListByAccountID(ctx context.Context, accountID string, query *app.QueryOption) (*app.ListResult[*model.VirtualMachine], error) {
+// ListByAccountID 获取指定用户账号的所有虚拟机
+func (r *virtualMachineRepository) ListByAccountID(ctx context.Context, userID, accountID string, query *app.QueryOption) (*app.ListResult[*model.VirtualMachine], error) {
 	return r.ListVMs(ctx, QueryVMsOptions{
+		UserID:    userID,
 		AccountID: accountID,
 		Query:     query,
 	})
 }
 
-// ListBySubscriptionID 获取指定订阅的所有虚拟机
-func (r *virtualMachineRepository) ListBySubscriptionID(ctx context.Context, subscriptionID string, query *app.QueryOption) (*app.ListResult[*model.VirtualMachine], error) {
+// ListByAccountAndSubscription 获取指定用户账号和订阅的所有虚拟机
+func (r *virtualMachineRepository) ListByAccountAndSubscription(ctx context.Context, userID, accountID, subscriptionID string, query *app.QueryOption) (*app.ListResult[*model.VirtualMachine], error) {
 	return r.ListVMs(ctx, QueryVMsOptions{
+		UserID:         userID,
+		AccountID:      accountID,
 		SubscriptionID: subscriptionID,
 		Query:          query,
 	})
 }
 
-// ListByAccountAndSubscription 获取指定账号和订阅的所有虚拟机
-func (r *virtualMachineRepository) ListByAccountAndSubscription(ctx context.Context, accountID string, subscriptionID string, query *app.QueryOption) (*app.ListResult[*model.VirtualMachine], error) {
-	return r.ListVMs(ctx, QueryVMsOptions{
-		AccountID:      accountID,
-		SubscriptionID: subscriptionID,
-		Query:          query,
-	})
+// CountByAccountID 统计账号下的虚拟机数量
+func (r *virtualMachineRepository) CountByAccountID(ctx context.Context, accountID string) (int64, error) {
+	var count int64
+	if err := r.DB(ctx).Model(&model.VirtualMachine{}).Where("account_id = ?", accountID).Count(&count).Error; err != nil {
+		return 0, fmt.Errorf("统计虚拟机数量失败: %w", err)
+	}
+	return count, nil
 }
+
 func (r *virtualMachineRepository) UpdateDNSLabel(ctx context.Context, vmID string, dnsLabel string) error {
 	result := r.DB(ctx).Model(&model.VirtualMachine{}).
 		Where("vm_id = ?", vmID).
@@ -341,3 +500,117 @@ func (r *virtualMachineRepository) UpdateDNSLabel(ctx context.Context, vmID stri
 
 	return nil
 }
+
+// UpdatePublicIP 更新虚拟机的公网IP信息
+func (r *virtualMachineRepository) UpdatePublicIP(ctx context.Context, vmID string, publicIPs []string, publicIPName, dnsAlias string) error {
+	result := r.DB(ctx).Model(&model.VirtualMachine{}).
+		Where("vm_id = ?", vmID).
+		Updates(map[string]interface{}{
+			"public_ips":     strings.Join(publicIPs, ","),
+			"public_ip_name": publicIPName,
+			"dns_alias":      dnsAlias,
+		})
+
+	if result.Error != nil {
+		return fmt.Errorf("更新公网IP失败: %w", result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("未找到虚拟机记录")
+	}
+
+	return nil
+}
+
+// UpdateSize 更新虚拟机规格信息
+func (r *virtualMachineRepository) UpdateSize(ctx context.Context, vmID, size string, core, memory int32, powerState string) error {
+	fields := map[string]interface{}{
+		"size":   size,
+		"core":   core,
+		"memory": memory,
+	}
+	if powerState != "" {
+		fields["power_state"] = powerState
+	}
+	result := r.DB(ctx).Model(&model.VirtualMachine{}).
+		Where("vm_id = ?", vmID).
+		Updates(fields)
+
+	if result.Error != nil {
+		return fmt.Errorf("更新虚拟机规格失败: %w", result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("未找到虚拟机记录")
+	}
+
+	return nil
+}
+
+func (r *virtualMachineRepository) UpdateDisks(ctx context.Context, vmID string, osDiskSize int, dataDisks string) error {
+	result := r.DB(ctx).Model(&model.VirtualMachine{}).
+		Where("vm_id = ?", vmID).
+		Updates(map[string]interface{}{
+			"os_disk_size": osDiskSize,
+			"data_disks":   dataDisks,
+		})
+
+	if result.Error != nil {
+		return fmt.Errorf("更新虚拟机磁盘信息失败: %w", result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("未找到虚拟机记录")
+	}
+
+	return nil
+}
+
+func (r *virtualMachineRepository) UpdateSecurityGroups(ctx context.Context, vmID string, securityGroups string) error {
+	result := r.DB(ctx).Model(&model.VirtualMachine{}).
+		Where("vm_id = ?", vmID).
+		Update("security_groups", securityGroups)
+
+	if result.Error != nil {
+		return fmt.Errorf("更新虚拟机网络安全组失败: %w", result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("未找到虚拟机记录")
+	}
+
+	return nil
+}
+
+func (r *virtualMachineRepository) UpdateMonthlyCost(ctx context.Context, vmID string, monthlyCost float64) error {
+	result := r.DB(ctx).Model(&model.VirtualMachine{}).
+		Where("vm_id = ?", vmID).
+		Update("monthly_cost", monthlyCost)
+
+	if result.Error != nil {
+		return fmt.Errorf("更新虚拟机预计月费用失败: %w", result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("未找到虚拟机记录")
+	}
+
+	return nil
+}
+
+func (r *virtualMachineRepository) SumMonthlyCosts(ctx context.Context, accountIDs []string) ([]*MonthlyCostTotal, error) {
+	var totals []*MonthlyCostTotal
+	if len(accountIDs) == 0 {
+		return totals, nil
+	}
+	err := r.DB(ctx).Model(&model.VirtualMachine{}).
+		Select("account_id, subscription_id, COUNT(*) AS vm_count, COALESCE(SUM(monthly_cost), 0) AS monthly_cost").
+		Where("account_id IN ?", accountIDs).
+		Group("account_id, subscription_id").
+		Order("account_id, subscription_id").
+		Scan(&totals).Error
+	if err != nil {
+		return nil, fmt.Errorf("汇总虚拟机费用失败: %w", err)
+	}
+	return totals, nil
+}
@@ -10,11 +10,14 @@ import (
 	"gorm.io/gorm"
 )
 
+// VmImageRepository 镜像按同步它的账户归属，查询均按用户隔离
 type VmImageRepository interface {
-	GetVmImage(ctx context.Context, id uint) (*model.VmImage, error)
-	ListVmImages(ctx context.Context) ([]*model.VmImage, error)
-	BatchUpsertVmImages(ctx context.Context, images []*model.VmImage) error
-	GetVmImageBySpec(ctx context.Context, publisher, offer, sku string) (*model.VmImage, error)
+	GetVmImage(ctx context.Context, userId string, id uint) (*model.VmImage, error)
+	// ListVmImages accountId 为空时返回该用户所有账户下的镜像
+	ListVmImages(ctx context.Context, userId, accountId string) ([]*model.VmImage, error)
+	// BatchUpsertVmImages 批量写入同一账户的镜像
+	BatchUpsertVmImages(ctx context.Context, accountId string, images []*model.VmImage) error
+	GetVmImageBySpec(ctx context.Context, userId, accountId, publisher, offer, sku string) (*model.VmImage, error)
 }
 
 type vmImageRepository struct {
@@ -27,9 +30,12 @@ func NewVmImageRepository(repository *Repository) VmImageRepository {
 	}
 }
 
-func (r *vmImageRepository) GetVmImage(ctx context.Context, id uint) (*model.VmImage, error) {
+func (r *vmImageRepository) GetVmImage(ctx context.Context, userId string, id uint) (*model.VmImage, error) {
+	if userId == "" {
+		return nil, ErrUserScopeRequired
+	}
 	var vmImage model.VmImage
-	if err := r.DB(ctx).First(&vmImage, id).Error; err != nil {
+	if err := r.DB(ctx).Where("account_id IN (?)", r.userAccountIDs(ctx, userId)).First(&vmImage, id).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return nil, nil
 		}
@@ -38,18 +44,28 @@ func (r *vmImageRepository) GetVmImage(ctx context.Context, id uint) (*model.VmI
 	return &vmImage, nil
 }
 
-func (r *vmImageRepository) ListVmImages(ctx context.Context) ([]*model.VmImage, error) {
+func (r *vmImageRepository) ListVmImages(ctx context.Context, userId, accountId string) ([]*model.VmImage, error) {
+	if userId == "" {
+		return nil, ErrUserScopeRequired
+	}
+	q := r.DB(ctx).Where("enabled = ? AND account_id IN (?)", true, r.userAccountIDs(ctx, userId))
+	if accountId != "" {
+		q = q.Where("account_id = ?", accountId)
+	}
 	var images []*model.VmImage
-	if err := r.DB(ctx).Where("enabled = ?", true).Find(&images).Error; err != nil {
+	if err := q.Find(&images).Error; err != nil {
 		return nil, fmt.Errorf("查询镜像列表失败: %w", err)
 	}
 	return images, nil
 }
 
-func (r *vmImageRepository) GetVmImageBySpec(ctx context.Context, publisher, offer, sku string) (*model.VmImage, error) {
+func (r *vmImageRepository) GetVmImageBySpec(ctx context.Context, userId, accountId, publisher, offer, sku string) (*model.VmImage, error) {
+	if userId == "" {
+		return nil, ErrUserScopeRequired
+	}
 	var image model.VmImage
-	if err := r.DB(ctx).Where("publisher = ? AND offer = ? AND sku = ? AND enabled = ?",
-		publisher, offer, sku, true).First(&image).Error; err != nil {
+	if err := r.DB(ctx).Where("account_id = ? AND account_id IN (?) AND publisher = ? AND offer = ? AND sku = ? AND enabled = ?",
+		accountId, r.userAccountIDs(ctx, userId), publisher, offer, sku, true).First(&image).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return nil, nil
 		}
@@ -58,7 +74,7 @@ func (r *vmImageRepository) GetVmImageBySpec(ctx context.Context, publisher, off
 	return &image, nil
 }
 
-func (r *vmImageRepository) BatchUpsertVmImages(ctx context.Context, images []*model.VmImage) error {
+func (r *vmImageRepository) BatchUpsertVmImages(ctx context.Context, accountId string, images []*model.VmImage) error {
 	return r.Transaction(ctx, func(ctx context.Context) error {
 		if len(images) == 0 {
 			return nil
@@ -67,18 +83,16 @@ func (r *vmImageRepository) BatchUpsertVmImages(ctx context.Context, images []*m
 		now := time.Now()
 
 		// 构建唯一键映射，用于快速查找
-		var keys []string
 		keyToImage := make(map[string]*model.VmImage)
 		for _, img := range images {
+			img.AccountID = accountId
 			key := fmt.Sprintf("%s:%s:%s", img.Publisher, img.Offer, img.Sku)
-			keys = append(keys, key)
 			keyToImage[key] = img
 		}
 
-		// 查询现有记录
+		// 查询该账户的现有记录
 		var existingImages []*model.VmImage
-		// 使用 SQLite 的字符串连接语法
-		if err := r.DB(ctx).Where("publisher || ':' || offer || ':' || sku IN ?", keys).Find(&existingImages).Error; err != nil {
+		if err := r.DB(ctx).Where("account_id = ?", accountId).Find(&existingImages).Error; err != nil {
 			return fmt.Errorf("查询现有镜像失败: %w", err)
 		}
 
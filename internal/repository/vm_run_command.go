@@ -0,0 +1,209 @@
+package repository
+
+import (
+	"azure-vm-backend/internal/model"
+	"azure-vm-backend/pkg/app"
+	"context"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"time"
+)
+
+type VmRunCommandRepository interface {
+	CreateSnippet(ctx context.Context, snippet *model.VmScriptSnippet) error
+	// GetSnippet 获取用户的脚本片段，不存在时返回 nil
+	GetSnippet(ctx context.Context, userID, snippetID string) (*model.VmScriptSnippet, error)
+	UpdateSnippet(ctx context.Context, userID, snippetID string, fields map[string]interface{}) error
+	DeleteSnippet(ctx context.Context, userID, snippetID string) error
+	// ListSnippets 获取用户的所有脚本片段
+	ListSnippets(ctx context.Context, userID string) ([]*model.VmScriptSnippet, error)
+
+	// CreateExecution 创建执行记录
+	CreateExecution(ctx context.Context, execution *model.VmCommandExecution) error
+	// SetExecutionOperation 关联执行记录与后台操作
+	SetExecutionOperation(ctx context.Context, executionID, operationID string) error
+	// FinishExecution 写入执行结果
+	FinishExecution(ctx context.Context, execution *model.VmCommandExecution) error
+	// GetExecution 获取用户的执行记录，不存在时返回 nil
+	GetExecution(ctx context.Context, userID, executionID string) (*model.VmCommandExecution, error)
+	// ListExecutions 分页查询虚拟机的执行记录，按开始时间倒序
+	ListExecutions(ctx context.Context, userID, vmID string, query *app.QueryOption) (*app.ListResult[*model.VmCommandExecution], error)
+	// ListBatchExecutions 获取同一批次的执行记录
+	ListBatchExecutions(ctx context.Context, userID, batchID string) ([]*model.VmCommandExecution, error)
+	// FailInterruptedExecutions 将服务重启前未结束的执行记录标记为失败，返回处理数量
+	FailInterruptedExecutions(ctx context.Context, reason string) (int64, error)
+}
+
+func NewVmRunCommandRepository(
+	repository *Repository,
+) VmRunCommandRepository {
+	return &vmRunCommandRepository{
+		Repository: repository,
+	}
+}
+
+type vmRunCommandRepository struct {
+	*Repository
+}
+
+// CreateSnippet 创建脚本片段
+func (r *vmRunCommandRepository) CreateSnippet(ctx context.Context, snippet *model.VmScriptSnippet) error {
+	if err := r.DB(ctx).Create(snippet).Error; err != nil {
+		return fmt.Errorf("创建脚本片段失败: %w", err)
+	}
+	return nil
+}
+
+// GetSnippet 获取用户的脚本片段
+func (r *vmRunCommandRepository) GetSnippet(ctx context.Context, userID, snippetID string) (*model.VmScriptSnippet, error) {
+	var snippet model.VmScriptSnippet
+	err := r.DB(ctx).
+		Where("user_id = ? AND snippet_id = ?", userID, snippetID).
+		First(&snippet).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("查询脚本片段失败: %w", err)
+	}
+	return &snippet, nil
+}
+
+// UpdateSnippet 更新脚本片段
+func (r *vmRunCommandRepository) UpdateSnippet(ctx context.Context, userID, snippetID string, fields map[string]interface{}) error {
+	result := r.DB(ctx).Model(&model.VmScriptSnippet{}).
+		Where("user_id = ? AND snippet_id = ?", userID, snippetID).
+		Updates(fields)
+	if result.Error != nil {
+		return fmt.Errorf("更新脚本片段失败: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("未找到脚本片段")
+	}
+	return nil
+}
+
+// DeleteSnippet 删除脚本片段，引用该片段的执行记录保留
+func (r *vmRunCommandRepository) DeleteSnippet(ctx context.Context, userID, snippetID string) error {
+	result := r.DB(ctx).
+		Where("user_id = ? AND snippet_id = ?", userID, snippetID).
+		Delete(&model.VmScriptSnippet{})
+	if result.Error != nil {
+		return fmt.Errorf("删除脚本片段失败: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("未找到脚本片段")
+	}
+	return nil
+}
+
+// ListSnippets 获取用户的所有脚本片段
+func (r *vmRunCommandRepository) ListSnippets(ctx context.Context, userID string) ([]*model.VmScriptSnippet, error) {
+	var snippets []*model.VmScriptSnippet
+	err := r.DB(ctx).
+		Where("user_id = ?", userID).
+		Order("id asc").
+		Find(&snippets).Error
+	if err != nil {
+		return nil, fmt.Errorf("查询脚本片段失败: %w", err)
+	}
+	return snippets, nil
+}
+
+// CreateExecution 创建执行记录
+func (r *vmRunCommandRepository) CreateExecution(ctx context.Context, execution *model.VmCommandExecution) error {
+	if err := r.DB(ctx).Create(execution).Error; err != nil {
+		return fmt.Errorf("创建执行记录失败: %w", err)
+	}
+	return nil
+}
+
+// SetExecutionOperation 关联执行记录与后台操作
+func (r *vmRunCommandRepository) SetExecutionOperation(ctx context.Context, executionID, operationID string) error {
+	err := r.DB(ctx).Model(&model.VmCommandExecution{}).
+		Where("execution_id = ?", executionID).
+		Update("operation_id", operationID).Error
+	if err != nil {
+		return fmt.Errorf("更新执行记录失败: %w", err)
+	}
+	return nil
+}
+
+// FinishExecution 写入执行结果
+func (r *vmRunCommandRepository) FinishExecution(ctx context.Context, execution *model.VmCommandExecution) error {
+	err := r.DB(ctx).Model(&model.VmCommandExecution{}).
+		Where("execution_id = ?", execution.ExecutionID).
+		Updates(map[string]interface{}{
+			"status":      execution.Status,
+			"exit_code":   execution.ExitCode,
+			"stdout":      execution.Stdout,
+			"stderr":      execution.Stderr,
+			"error":       execution.Error,
+			"finished_at": execution.FinishedAt,
+		}).Error
+	if err != nil {
+		return fmt.Errorf("更新执行记录失败: %w", err)
+	}
+	return nil
+}
+
+// GetExecution 获取用户的执行记录
+func (r *vmRunCommandRepository) GetExecution(ctx context.Context, userID, executionID string) (*model.VmCommandExecution, error) {
+	var execution model.VmCommandExecution
+	err := r.DB(ctx).
+		Where("user_id = ? AND execution_id = ?", userID, executionID).
+		First(&execution).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("查询执行记录失败: %w", err)
+	}
+	return &execution, nil
+}
+
+// ListExecutions 分页查询虚拟机的执行记录
+func (r *vmRunCommandRepository) ListExecutions(ctx context.Context, userID, vmID string, query *app.QueryOption) (*app.ListResult[*model.VmCommandExecution], error) {
+	return app.WithPagination[*model.VmCommandExecution](
+		r.DB(ctx),
+		query,
+		func(db *gorm.DB) *gorm.DB {
+			baseQuery := db.Model(&model.VmCommandExecution{}).
+				Where("user_id = ? AND vm_id = ?", userID, vmID)
+			if query.SortBy == "" {
+				baseQuery = baseQuery.Order("started_at DESC, id DESC")
+				query.SortOrder = ""
+			}
+			return baseQuery
+		},
+	)
+}
+
+// ListBatchExecutions 获取同一批次的执行记录
+func (r *vmRunCommandRepository) ListBatchExecutions(ctx context.Context, userID, batchID string) ([]*model.VmCommandExecution, error) {
+	var executions []*model.VmCommandExecution
+	err := r.DB(ctx).
+		Where("user_id = ? AND batch_id = ?", userID, batchID).
+		Order("id asc").
+		Find(&executions).Error
+	if err != nil {
+		return nil, fmt.Errorf("查询批量执行记录失败: %w", err)
+	}
+	return executions, nil
+}
+
+// FailInterruptedExecutions 将未结束的执行记录标记为失败
+func (r *vmRunCommandRepository) FailInterruptedExecutions(ctx context.Context, reason string) (int64, error) {
+	result := r.DB(ctx).Model(&model.VmCommandExecution{}).
+		Where("status = ?", model.CommandStatusRunning).
+		Updates(map[string]interface{}{
+			"status":      model.CommandStatusFailed,
+			"error":       reason,
+			"finished_at": time.Now(),
+		})
+	if result.Error != nil {
+		return 0, fmt.Errorf("更新中断的执行记录失败: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
@@ -3,13 +3,23 @@ package repository
 import (
 	"azure-vm-backend/internal/model"
 	"context"
+	"errors"
 	"fmt"
 	"time"
+
+	"gorm.io/gorm"
 )
 
+// VmSizeRepository 规格按同步它的账户归属，查询均按用户隔离
 type VmSizeRepository interface {
-	ListVmSizes(ctx context.Context, location string) ([]*model.VmSize, error)
-	BatchUpsertVmSizes(ctx context.Context, sizes []*model.VmSize) error
+	// ListVmSizes accountId 为空时返回该用户所有账户下的规格，不包含对订阅受限的规格
+	// subscriptionId 为空时只排除对账户所有订阅都受限的规格
+	ListVmSizes(ctx context.Context, userId, accountId, subscriptionId, location string) ([]*model.VmSize, error)
+	// GetVmSize 获取指定账户在区域内同步的规格，不存在时返回 nil
+	// 受限的规格同样返回，subscriptionId 不为空时填充该订阅的 Restricted，由调用方判断是否可用
+	GetVmSize(ctx context.Context, userId, accountId, subscriptionId, location, name string) (*model.VmSize, error)
+	// BatchUpsertVmSizes 批量写入同一账户的规格，并替换 subscriptionId 在这些区域的规格限制
+	BatchUpsertVmSizes(ctx context.Context, accountId, subscriptionId string, sizes []*model.VmSize) error
 }
 
 type vmSizeRepository struct {
@@ -22,21 +32,77 @@ func NewVmSizeRepository(repository *Repository) VmSizeRepository {
 	}
 }
 
-func (r *vmSizeRepository) ListVmSizes(ctx context.Context, location string) ([]*model.VmSize, error) {
+func (r *vmSizeRepository) ListVmSizes(ctx context.Context, userId, accountId, subscriptionId, location string) ([]*model.VmSize, error) {
+	if userId == "" {
+		return nil, ErrUserScopeRequired
+	}
+	q := r.DB(ctx).Where("location = ? AND enabled = ? AND account_id IN (?)", location, true, r.userAccountIDs(ctx, userId))
+	if accountId != "" {
+		q = q.Where("account_id = ?", accountId)
+	}
+	if subscriptionId != "" {
+		q = q.Where("NOT EXISTS (?)", r.restrictionQuery(ctx, "1").Where("vm_size_restrictions.subscription_id = ?", subscriptionId))
+	} else {
+		// 账户的订阅中只要有一个不受限就保留，创建和调整规格时再按虚拟机所在订阅校验
+		subscriptions := r.DB(ctx).Model(&model.Subscriptions{}).Select("COUNT(*)").
+			Where("subscriptions.account_id = vm_sizes.account_id")
+		q = q.Where("(?) = 0 OR (?) < (?)", r.restrictionQuery(ctx, "COUNT(*)"), r.restrictionQuery(ctx, "COUNT(*)"), subscriptions)
+	}
 	var sizes []*model.VmSize
-	if err := r.DB(ctx).Where("location = ? AND enabled = ?", location, true).Find(&sizes).Error; err != nil {
+	if err := q.Find(&sizes).Error; err != nil {
 		return nil, fmt.Errorf("查询规格列表失败: %w", err)
 	}
 	return sizes, nil
 }
 
-func (r *vmSizeRepository) BatchUpsertVmSizes(ctx context.Context, sizes []*model.VmSize) error {
+func (r *vmSizeRepository) GetVmSize(ctx context.Context, userId, accountId, subscriptionId, location, name string) (*model.VmSize, error) {
+	if userId == "" {
+		return nil, ErrUserScopeRequired
+	}
+	var size model.VmSize
+	err := r.DB(ctx).
+		Where("account_id = ? AND location = ? AND name = ? AND enabled = ?", accountId, location, name, true).
+		Where("account_id IN (?)", r.userAccountIDs(ctx, userId)).
+		First(&size).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("查询规格失败: %w", err)
+	}
+
+	if subscriptionId != "" {
+		var restrictions []*model.VmSizeRestriction
+		if err := r.DB(ctx).Where("account_id = ? AND subscription_id = ? AND location = ? AND name = ?", accountId, subscriptionId, location, name).
+			Limit(1).Find(&restrictions).Error; err != nil {
+			return nil, fmt.Errorf("查询规格限制失败: %w", err)
+		}
+		if len(restrictions) > 0 {
+			size.Restricted = true
+			size.RestrictionReason = restrictions[0].Reason
+		}
+	}
+	return &size, nil
+}
+
+// restrictionQuery 与外层 vm_sizes 记录匹配的规格限制子查询
+func (r *vmSizeRepository) restrictionQuery(ctx context.Context, column string) *gorm.DB {
+	return r.DB(ctx).Model(&model.VmSizeRestriction{}).Select(column).
+		Where("vm_size_restrictions.account_id = vm_sizes.account_id AND vm_size_restrictions.location = vm_sizes.location AND vm_size_restrictions.name = vm_sizes.name")
+}
+
+func (r *vmSizeRepository) BatchUpsertVmSizes(ctx context.Context, accountId, subscriptionId string, sizes []*model.VmSize) error {
 	return r.Transaction(ctx, func(ctx context.Context) error {
 		now := time.Now()
 
+		if err := r.replaceRestrictions(ctx, accountId, subscriptionId, sizes, now); err != nil {
+			return err
+		}
+
 		for _, size := range sizes {
+			size.AccountID = accountId
 			var existing model.VmSize
-			err := r.DB(ctx).Where("name = ? AND location = ?", size.Name, size.Location).First(&existing).Error
+			err := r.DB(ctx).Where("account_id = ? AND name = ? AND location = ?", accountId, size.Name, size.Location).First(&existing).Error
 
 			if err == nil {
 				// 更新现有记录
@@ -46,6 +112,14 @@ func (r *vmSizeRepository) BatchUpsertVmSizes(ctx context.Context, sizes []*mode
 				existing.OSDiskSizeGB = size.OSDiskSizeGB
 				existing.Category = size.Category
 				existing.Family = size.Family
+				existing.QuotaFamily = size.QuotaFamily
+				existing.Zones = size.Zones
+				existing.AcceleratedNetworking = size.AcceleratedNetworking
+				existing.PremiumIO = size.PremiumIO
+				existing.HyperVGenerations = size.HyperVGenerations
+				if err := r.fillPrice(ctx, &existing); err != nil {
+					return err
+				}
 				existing.UpdatedAt = now
 				existing.LastSyncAt = now
 
@@ -58,6 +132,9 @@ func (r *vmSizeRepository) BatchUpsertVmSizes(ctx context.Context, sizes []*mode
 				size.UpdatedAt = now
 				size.LastSyncAt = now
 				size.Enabled = true
+				if err := r.fillPrice(ctx, size); err != nil {
+					return err
+				}
 
 				if err := r.DB(ctx).Create(size).Error; err != nil {
 					return fmt.Errorf("创建规格失败: %w", err)
@@ -67,3 +144,57 @@ func (r *vmSizeRepository) BatchUpsertVmSizes(ctx context.Context, sizes []*mode
 		return nil
 	})
 }
+
+// replaceRestrictions 用本次同步的结果替换订阅在这些区域的规格限制，同一账户其他订阅的限制保持不变
+func (r *vmSizeRepository) replaceRestrictions(ctx context.Context, accountId, subscriptionId string, sizes []*model.VmSize, now time.Time) error {
+	var locations []string
+	seen := make(map[string]bool)
+	var restrictions []*model.VmSizeRestriction
+	for _, size := range sizes {
+		if !seen[size.Location] {
+			seen[size.Location] = true
+			locations = append(locations, size.Location)
+		}
+		if size.Restricted {
+			restrictions = append(restrictions, &model.VmSizeRestriction{
+				AccountID:      accountId,
+				SubscriptionID: subscriptionId,
+				Location:       size.Location,
+				Name:           size.Name,
+				Reason:         size.RestrictionReason,
+				SyncedAt:       now,
+			})
+		}
+	}
+	if len(locations) == 0 {
+		return nil
+	}
+
+	if err := r.DB(ctx).Where("account_id = ? AND subscription_id = ? AND location IN ?", accountId, subscriptionId, locations).
+		Delete(&model.VmSizeRestriction{}).Error; err != nil {
+		return fmt.Errorf("删除规格限制失败: %w", err)
+	}
+	if len(restrictions) > 0 {
+		if err := r.DB(ctx).Create(restrictions).Error; err != nil {
+			return fmt.Errorf("写入规格限制失败: %w", err)
+		}
+	}
+	return nil
+}
+
+// fillPrice 使用已同步的零售价格填充规格记录，价格未同步时保持不变
+func (r *vmSizeRepository) fillPrice(ctx context.Context, size *model.VmSize) error {
+	var prices []*model.VmSizePrice
+	if err := r.DB(ctx).Where("location = ? AND name = ?", size.Location, size.Name).Limit(1).Find(&prices).Error; err != nil {
+		return fmt.Errorf("查询规格价格失败: %w", err)
+	}
+	if len(prices) == 0 {
+		return nil
+	}
+	size.PricePerHour = prices[0].LinuxPerHour
+	size.WindowsPricePerHour = prices[0].WindowsPerHour
+	size.SpotPricePerHour = prices[0].LinuxSpotPerHour
+	size.WindowsSpotPricePerHour = prices[0].WindowsSpotPerHour
+	size.Currency = prices[0].Currency
+	return nil
+}
@@ -0,0 +1,121 @@
+package repository
+
+import (
+	"azure-vm-backend/internal/model"
+	"context"
+	"fmt"
+)
+
+// VmSizePriceRepository 规格价格为公开数据，不按用户隔离
+type VmSizePriceRepository interface {
+	// ReplacePrices 替换区域内的全部规格价格，并同步到各账户的规格记录
+	ReplacePrices(ctx context.Context, location string, prices []*model.VmSizePrice) error
+	// SavePrice 写入或更新单个规格的价格，并同步到各账户的规格记录
+	SavePrice(ctx context.Context, price *model.VmSizePrice) error
+	// ListSizeLocations 已同步规格的全部区域
+	ListSizeLocations(ctx context.Context) ([]string, error)
+	// GetPrice 获取规格在区域内的价格，不存在时返回 nil
+	GetPrice(ctx context.Context, location, name string) (*model.VmSizePrice, error)
+}
+
+func NewVmSizePriceRepository(
+	repository *Repository,
+) VmSizePriceRepository {
+	return &vmSizePriceRepository{
+		Repository: repository,
+	}
+}
+
+type vmSizePriceRepository struct {
+	*Repository
+}
+
+// ReplacePrices 删除旧价格后写入新价格，规格记录中不再有报价的价格清零
+func (r *vmSizePriceRepository) ReplacePrices(ctx context.Context, location string, prices []*model.VmSizePrice) error {
+	return r.Transaction(ctx, func(ctx context.Context) error {
+		if err := r.DB(ctx).Unscoped().Where("location = ?", location).Delete(&model.VmSizePrice{}).Error; err != nil {
+			return fmt.Errorf("删除规格价格失败: %w", err)
+		}
+		err := r.DB(ctx).Model(&model.VmSize{}).Where("location = ?", location).Updates(map[string]interface{}{
+			"price_per_hour":              0,
+			"windows_price_per_hour":      0,
+			"spot_price_per_hour":         0,
+			"windows_spot_price_per_hour": 0,
+		}).Error
+		if err != nil {
+			return fmt.Errorf("清空规格价格失败: %w", err)
+		}
+		if len(prices) == 0 {
+			return nil
+		}
+
+		for _, price := range prices {
+			price.Location = location
+		}
+		if err := r.DB(ctx).CreateInBatches(&prices, 100).Error; err != nil {
+			return fmt.Errorf("保存规格价格失败: %w", err)
+		}
+		for _, price := range prices {
+			err := r.DB(ctx).Model(&model.VmSize{}).
+				Where("location = ? AND name = ?", location, price.Name).
+				Updates(priceColumns(price)).Error
+			if err != nil {
+				return fmt.Errorf("更新规格价格失败: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+func (r *vmSizePriceRepository) SavePrice(ctx context.Context, price *model.VmSizePrice) error {
+	return r.Transaction(ctx, func(ctx context.Context) error {
+		existing, err := r.GetPrice(ctx, price.Location, price.Name)
+		if err != nil {
+			return err
+		}
+		if existing != nil {
+			price.ID = existing.ID
+			price.CreatedAt = existing.CreatedAt
+		}
+		if err := r.DB(ctx).Save(price).Error; err != nil {
+			return fmt.Errorf("保存规格价格失败: %w", err)
+		}
+		err = r.DB(ctx).Model(&model.VmSize{}).
+			Where("location = ? AND name = ?", price.Location, price.Name).
+			Updates(priceColumns(price)).Error
+		if err != nil {
+			return fmt.Errorf("更新规格价格失败: %w", err)
+		}
+		return nil
+	})
+}
+
+func (r *vmSizePriceRepository) ListSizeLocations(ctx context.Context) ([]string, error) {
+	var locations []string
+	if err := r.DB(ctx).Model(&model.VmSize{}).Distinct("location").Order("location").Pluck("location", &locations).Error; err != nil {
+		return nil, fmt.Errorf("查询规格区域失败: %w", err)
+	}
+	return locations, nil
+}
+
+func (r *vmSizePriceRepository) GetPrice(ctx context.Context, location, name string) (*model.VmSizePrice, error) {
+	var prices []*model.VmSizePrice
+	if err := r.DB(ctx).Where("location = ? AND name = ?", location, name).Limit(1).Find(&prices).Error; err != nil {
+		return nil, fmt.Errorf("查询规格价格失败: %w", err)
+	}
+	if len(prices) == 0 {
+		return nil, nil
+	}
+	return prices[0], nil
+}
+
+// priceColumns 规格记录中的价格列
+func priceColumns(price *model.VmSizePrice) map[string]interface{} {
+	return map[string]interface{}{
+		"price_per_hour":              price.LinuxPerHour,
+		"windows_price_per_hour":      price.WindowsPerHour,
+		"spot_price_per_hour":         price.LinuxSpotPerHour,
+		"windows_spot_price_per_hour": price.WindowsSpotPerHour,
+		"currency":                    price.Currency,
+	}
+}
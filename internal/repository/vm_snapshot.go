@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"azure-vm-backend/internal/model"
+	"context"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+)
+
+type VmSnapshotRepository interface {
+	Create(ctx context.Context, snapshot *model.VmSnapshot) error
+	// GetBySnapshotID 获取用户的快照，不存在时返回 nil
+	GetBySnapshotID(ctx context.Context, userID, snapshotID string) (*model.VmSnapshot, error)
+	// ListByVMID 获取用户虚拟机的快照，按快照时间倒序
+	ListByVMID(ctx context.Context, userID, vmID string) ([]*model.VmSnapshot, error)
+	Delete(ctx context.Context, snapshotID string) error
+}
+
+func NewVmSnapshotRepository(
+	repository *Repository,
+) VmSnapshotRepository {
+	return &vmSnapshotRepository{
+		Repository: repository,
+	}
+}
+
+type vmSnapshotRepository struct {
+	*Repository
+}
+
+// Create 创建快照记录
+func (r *vmSnapshotRepository) Create(ctx context.Context, snapshot *model.VmSnapshot) error {
+	if err := r.DB(ctx).Create(snapshot).Error; err != nil {
+		return fmt.Errorf("创建快照记录失败: %w", err)
+	}
+	return nil
+}
+
+// GetBySnapshotID 获取用户的快照
+func (r *vmSnapshotRepository) GetBySnapshotID(ctx context.Context, userID, snapshotID string) (*model.VmSnapshot, error) {
+	if userID == "" {
+		return nil, ErrUserScopeRequired
+	}
+	var snapshot model.VmSnapshot
+	err := r.DB(ctx).
+		Where("snapshot_id = ? AND account_id IN (?)", snapshotID, r.userAccountIDs(ctx, userID)).
+		First(&snapshot).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("查询快照记录失败: %w", err)
+	}
+	return &snapshot, nil
+}
+
+// ListByVMID 获取用户虚拟机的快照
+func (r *vmSnapshotRepository) ListByVMID(ctx context.Context, userID, vmID string) ([]*model.VmSnapshot, error) {
+	if userID == "" {
+		return nil, ErrUserScopeRequired
+	}
+	var snapshots []*model.VmSnapshot
+	err := r.DB(ctx).
+		Where("vm_id = ? AND account_id IN (?)", vmID, r.userAccountIDs(ctx, userID)).
+		Order("snapshot_time desc, id desc").
+		Find(&snapshots).Error
+	if err != nil {
+		return nil, fmt.Errorf("查询快照列表失败: %w", err)
+	}
+	return snapshots, nil
+}
+
+// Delete 删除快照记录
+func (r *vmSnapshotRepository) Delete(ctx context.Context, snapshotID string) error {
+	result := r.DB(ctx).
+		Where("snapshot_id = ?", snapshotID).
+		Delete(&model.VmSnapshot{})
+	if result.Error != nil {
+		return fmt.Errorf("删除快照记录失败: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("未找到快照记录")
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package server
+
+import (
+	"azure-vm-backend/internal/model"
+	"azure-vm-backend/pkg/log"
+	"azure-vm-backend/pkg/secret"
+	"context"
+	"fmt"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"os"
+)
+
+// KeyRotation 使用当前主密钥重新加密所有账户凭据的数据密钥
+// 轮换步骤: 将新主密钥配置为 master_key，旧主密钥加入 previous_keys，执行本任务后移除旧主密钥
+type KeyRotation struct {
+	db     *gorm.DB
+	log    *log.Logger
+	cipher *secret.Cipher
+}
+
+func NewKeyRotation(db *gorm.DB, log *log.Logger, cipher *secret.Cipher) *KeyRotation {
+	return &KeyRotation{
+		db:     db,
+		log:    log,
+		cipher: cipher,
+	}
+}
+
+func (k *KeyRotation) Start(ctx context.Context) error {
+	count, err := reencryptAccounts(ctx, k.db, func(value string) (string, error) {
+		if !secret.IsEncrypted(value) {
+			if value != "" {
+				return "", fmt.Errorf("存在未加密的凭据，请先执行 cmd/migration")
+			}
+			return value, nil
+		}
+		return k.cipher.Rotate(value)
+	})
+	if err != nil {
+		k.log.Error("rotate master key error", zap.Error(err))
+		return err
+	}
+	k.log.Info("rotate master key success", zap.Int("accounts", count))
+	os.Exit(0)
+	return nil
+}
+
+func (k *KeyRotation) Stop(ctx context.Context) error {
+	k.log.Info("KeyRotation stop")
+	return nil
+}
+
+// reencryptAccounts 分批转换账户的客户端密钥和登录密码，返回更新的账户数量
+// 已软删除的账户同样处理，避免恢复后残留明文或旧密钥
+func reencryptAccounts(ctx context.Context, db *gorm.DB, transform func(string) (string, error)) (int, error) {
+	var accounts []*model.Accounts
+	updated := 0
+	result := db.WithContext(ctx).Unscoped().
+		Select("id", "account_id", "password", "login_password").
+		FindInBatches(&accounts, 100, func(tx *gorm.DB, batch int) error {
+			for _, account := range accounts {
+				password, err := transform(account.PassWord)
+				if err != nil {
+					return fmt.Errorf("账户 %s 客户端密钥: %w", account.AccountID, err)
+				}
+				loginPassword, err := transform(account.LoginPassword)
+				if err != nil {
+					return fmt.Errorf("账户 %s 登录密码: %w", account.AccountID, err)
+				}
+				if password == account.PassWord && loginPassword == account.LoginPassword {
+					continue
+				}
+				if err := db.WithContext(ctx).Unscoped().Model(&model.Accounts{}).Where("id = ?", account.ID).
+					UpdateColumns(map[string]interface{}{
+						"password":       password,
+						"login_password": loginPassword,
+					}).Error; err != nil {
+					return fmt.Errorf("更新账户 %s 失败: %w", account.AccountID, err)
+				}
+				updated++
+			}
+			return nil
+		})
+	return updated, result.Error
+}
@@ -25,6 +25,13 @@ func NewHTTPServer(
 	vmHandler *handler.VirtualMachineHandler,
 	vmRegionHandler *handler.VmRegionHandler,
 	vmImageHandler *handler.VmImageHandler,
+	operationHandler *handler.OperationHandler,
+	syncScheduleHandler *handler.SyncScheduleHandler,
+	vmSnapshotHandler *handler.VmSnapshotHandler,
+	vmRunCommandHandler *handler.VmRunCommandHandler,
+	subscriptionQuotaHandler *handler.SubscriptionQuotaHandler,
+	vmCostHandler *handler.VmCostHandler,
+	spendHandler *handler.SpendHandler,
 ) *http.Server {
 	gin.SetMode(gin.DebugMode)
 	s := http.NewServer(
@@ -88,8 +95,12 @@ func NewHTTPServer(
 			// 获取指定账号的所有订阅
 			strictAuthRouter.POST("/subscriptions/get/:accountId", subHandler.GetSubscriptions)
 			strictAuthRouter.POST("/subscriptions/list", subHandler.ListSubscriptions)
+			// 获取指定账号的订阅状态变化记录
+			strictAuthRouter.GET("/subscriptions/:accountId/state-changes", subHandler.ListStateChanges)
 			// 获取指定订阅的详细信息
 			strictAuthRouter.GET("/subscriptions/:accountId/:subscriptionId", subHandler.GetSubscription)
+			// 获取指定订阅各区域的vCPU配额
+			strictAuthRouter.GET("/subscriptions/:accountId/:subscriptionId/quotas", subscriptionQuotaHandler.ListQuotas)
 			// 同步指定账号的订阅信息
 			strictAuthRouter.POST("/subscriptions/:accountId/sync", subHandler.SyncSubscriptions)
 			// 删除指定账号的所有订阅信息
@@ -102,6 +113,11 @@ func NewHTTPServer(
 			// 获取单个虚拟机详细信息
 			strictAuthRouter.GET("/vms/:accountId/instance/:vmId", vmHandler.GetVM)
 
+			// 启动诊断：启用后获取串行日志和控制台截图
+			strictAuthRouter.POST("/vms/:accountId/instance/:vmId/boot-diagnostics", vmHandler.EnableBootDiagnostics)
+			strictAuthRouter.GET("/vms/:accountId/instance/:vmId/serial-log", vmHandler.GetSerialLog)
+			strictAuthRouter.GET("/vms/:accountId/instance/:vmId/screenshot", vmHandler.GetScreenshot)
+
 			// 获取指定账号和订阅下的虚拟机列表
 			strictAuthRouter.GET("/vms/:accountId/subscription/:subscriptionId", vmHandler.ListVMsBySubscription)
 
@@ -111,11 +127,46 @@ func NewHTTPServer(
 			// 同步指定订阅下的虚拟机
 			strictAuthRouter.POST("/vms/:accountId/subscription/:subscriptionId/sync", vmHandler.SyncVMsBySubscription)
 
-			// 创建虚拟机（预留）
+			// 估算创建虚拟机的月费用
+			strictAuthRouter.POST("/vms/:accountId/estimate", vmCostHandler.EstimateVM)
+
+			// 创建虚拟机
 			strictAuthRouter.POST("/vms/:accountId", vmHandler.CreateVM)
 
 			strictAuthRouter.POST("/vms/:accountId/:id/operate", vmHandler.OperateVM)
 
+			// 获取虚拟机可调整的规格
+			strictAuthRouter.GET("/vms/:accountId/:id/sizes", vmHandler.ListResizeOptions)
+
+			// 挂载、卸载和扩容磁盘
+			strictAuthRouter.POST("/vms/:accountId/:id/disks", vmHandler.AttachDataDisk)
+			strictAuthRouter.DELETE("/vms/:accountId/:id/disks/:diskName", vmHandler.DetachDataDisk)
+			strictAuthRouter.POST("/vms/:accountId/:id/disks/:diskName/expand", vmHandler.ExpandDataDisk)
+			strictAuthRouter.POST("/vms/:accountId/:id/os-disk/expand", vmHandler.ExpandOSDisk)
+
+			// 网络安全组及入站规则
+			strictAuthRouter.GET("/vms/:accountId/:id/security-groups", vmHandler.ListSecurityGroups)
+			strictAuthRouter.POST("/vms/:accountId/:id/security-groups", vmHandler.CreateSecurityGroup)
+			strictAuthRouter.POST("/vms/:accountId/:id/security-rules", vmHandler.AddSecurityRule)
+			strictAuthRouter.DELETE("/vms/:accountId/:id/security-rules/:ruleName", vmHandler.DeleteSecurityRule)
+
+			// 通过 VMAccess 扩展重置密码、SSH公钥或sshd配置
+			strictAuthRouter.POST("/vms/:accountId/:id/access/reset", vmHandler.ResetVMAccess)
+			strictAuthRouter.GET("/vms/:accountId/:id/access", vmHandler.GetVMAccessStatus)
+
+			// 磁盘快照及从快照恢复系统盘
+			strictAuthRouter.POST("/vms/:accountId/:id/snapshots", vmSnapshotHandler.CreateSnapshot)
+			strictAuthRouter.GET("/vms/:accountId/:id/snapshots", vmSnapshotHandler.ListSnapshots)
+			strictAuthRouter.DELETE("/vms/:accountId/:id/snapshots/:snapshotId", vmSnapshotHandler.DeleteSnapshot)
+			strictAuthRouter.POST("/vms/:accountId/:id/snapshots/:snapshotId/restore", vmSnapshotHandler.RestoreSnapshot)
+
+			// 通过 Run Command 在虚拟机内执行脚本及执行记录
+			strictAuthRouter.POST("/vms/:accountId/:id/run-command", vmRunCommandHandler.RunCommand)
+			strictAuthRouter.GET("/vms/:accountId/:id/command-executions", vmRunCommandHandler.ListExecutions)
+
+			// 虚拟机的实际费用
+			strictAuthRouter.GET("/vms/:accountId/:id/cost", spendHandler.GetVMSpend)
+
 			// 更新虚拟机dns标签
 			strictAuthRouter.POST("/vms/update/dns/:accountId/:ID", vmHandler.UpdateDNSLabel)
 
@@ -131,6 +182,34 @@ func NewHTTPServer(
 			strictAuthRouter.GET("/vm/images/:id", vmImageHandler.GetVmImage)
 			// 同步镜像
 			strictAuthRouter.POST("/vm/images/sync", vmImageHandler.SyncVmImages)
+
+			// 异步操作接口
+			// 查询操作进度与结果
+			strictAuthRouter.GET("/operations/:id", operationHandler.GetOperation)
+
+			// 定时同步接口，由 task 服务按设置执行
+			strictAuthRouter.POST("/sync-schedules", syncScheduleHandler.CreateSchedule)
+			strictAuthRouter.GET("/sync-schedules", syncScheduleHandler.ListSchedules)
+			strictAuthRouter.GET("/sync-schedules/:id", syncScheduleHandler.GetSchedule)
+			strictAuthRouter.PUT("/sync-schedules/:id", syncScheduleHandler.UpdateSchedule)
+			strictAuthRouter.DELETE("/sync-schedules/:id", syncScheduleHandler.DeleteSchedule)
+			// 查询定时同步执行记录
+			strictAuthRouter.GET("/sync-schedules/:id/runs", syncScheduleHandler.ListRuns)
+
+			// 脚本片段和脚本执行接口
+			strictAuthRouter.POST("/script-snippets", vmRunCommandHandler.CreateSnippet)
+			strictAuthRouter.GET("/script-snippets", vmRunCommandHandler.ListSnippets)
+			strictAuthRouter.PUT("/script-snippets/:id", vmRunCommandHandler.UpdateSnippet)
+			strictAuthRouter.DELETE("/script-snippets/:id", vmRunCommandHandler.DeleteSnippet)
+			strictAuthRouter.GET("/command-executions/:id", vmRunCommandHandler.GetExecution)
+			// 按虚拟机筛选条件批量执行脚本
+			strictAuthRouter.POST("/command-batches", vmRunCommandHandler.BatchRunCommand)
+			strictAuthRouter.GET("/command-batches/:id", vmRunCommandHandler.ListBatchExecutions)
+
+			// 实际费用接口，费用由 task 服务定时同步，也可手动同步
+			strictAuthRouter.POST("/costs/:accountId/sync", spendHandler.SyncCosts)
+			strictAuthRouter.GET("/costs/:accountId", spendHandler.GetAccountSpend)
+			strictAuthRouter.GET("/costs/:accountId/subscription/:subscriptionId", spendHandler.GetSubscriptionSpend)
 		}
 	}
 
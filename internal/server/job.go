@@ -1,23 +1,38 @@
 package server
 
 import (
+	"azure-vm-backend/internal/service"
 	"azure-vm-backend/pkg/log"
 	"context"
+	"go.uber.org/zap"
 )
 
 type Job struct {
-	log *log.Logger
+	log                 *log.Logger
+	operationService    service.OperationService
+	vmRunCommandService service.VmRunCommandService
 }
 
 func NewJob(
 	log *log.Logger,
+	operationService service.OperationService,
+	vmRunCommandService service.VmRunCommandService,
 ) *Job {
 	return &Job{
-		log: log,
+		log:                 log,
+		operationService:    operationService,
+		vmRunCommandService: vmRunCommandService,
 	}
 }
 func (j *Job) Start(ctx context.Context) error {
-	// eg: kafka consumer
+	// 恢复服务重启前未完成的异步操作
+	if err := j.operationService.ResumeOperations(ctx); err != nil {
+		j.log.Error("恢复未完成操作失败", zap.Error(err))
+	}
+	// 脚本执行无法恢复，结束服务重启前未完成的执行记录
+	if err := j.vmRunCommandService.FailInterruptedExecutions(ctx); err != nil {
+		j.log.Error("更新中断的脚本执行记录失败", zap.Error(err))
+	}
 	return nil
 }
 func (j *Job) Stop(ctx context.Context) error {
@@ -3,21 +3,26 @@ package server
 import (
 	"azure-vm-backend/internal/model"
 	"azure-vm-backend/pkg/log"
+	"azure-vm-backend/pkg/secret"
 	"context"
+	"fmt"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
 	"os"
+	"regexp"
 )
 
 type Migrate struct {
-	db  *gorm.DB
-	log *log.Logger
+	db     *gorm.DB
+	log    *log.Logger
+	cipher *secret.Cipher
 }
 
-func NewMigrate(db *gorm.DB, log *log.Logger) *Migrate {
+func NewMigrate(db *gorm.DB, log *log.Logger, cipher *secret.Cipher) *Migrate {
 	return &Migrate{
-		db:  db,
-		log: log,
+		db:     db,
+		log:    log,
+		cipher: cipher,
 	}
 }
 func (m *Migrate) Start(ctx context.Context) error {
@@ -25,6 +30,85 @@ func (m *Migrate) Start(ctx context.Context) error {
 		m.log.Error("user migrate error", zap.Error(err))
 		return err
 	}
+	if err := m.db.AutoMigrate(&model.Operation{}); err != nil {
+		m.log.Error("operation migrate error", zap.Error(err))
+		return err
+	}
+	if err := m.db.AutoMigrate(&model.Accounts{}); err != nil {
+		m.log.Error("accounts migrate error", zap.Error(err))
+		return err
+	}
+	// 镜像和规格按账户归属，新增 account_id 列
+	if err := m.db.AutoMigrate(&model.VmImage{}, &model.VmSize{}); err != nil {
+		m.log.Error("vm image/size migrate error", zap.Error(err))
+		return err
+	}
+	if err := m.db.AutoMigrate(&model.SyncSchedule{}, &model.SyncScheduleRun{}); err != nil {
+		m.log.Error("sync schedule migrate error", zap.Error(err))
+		return err
+	}
+	if err := m.db.AutoMigrate(&model.VmSnapshot{}); err != nil {
+		m.log.Error("vm snapshot migrate error", zap.Error(err))
+		return err
+	}
+	if err := m.db.AutoMigrate(&model.VmScriptSnippet{}, &model.VmCommandExecution{}); err != nil {
+		m.log.Error("vm run command migrate error", zap.Error(err))
+		return err
+	}
+	if err := m.db.AutoMigrate(&model.SubscriptionQuota{}); err != nil {
+		m.log.Error("subscription quota migrate error", zap.Error(err))
+		return err
+	}
+	// 规格限制按订阅保存
+	if err := m.db.AutoMigrate(&model.VmSizeRestriction{}); err != nil {
+		m.log.Error("vm size restriction migrate error", zap.Error(err))
+		return err
+	}
+	if err := m.db.AutoMigrate(&model.VmSizePrice{}); err != nil {
+		m.log.Error("vm size price migrate error", zap.Error(err))
+		return err
+	}
+	if err := m.db.AutoMigrate(&model.CostRecord{}); err != nil {
+		m.log.Error("cost record migrate error", zap.Error(err))
+		return err
+	}
+	if err := m.db.AutoMigrate(&model.SubscriptionStateChange{}); err != nil {
+		m.log.Error("subscription state change migrate error", zap.Error(err))
+		return err
+	}
+	// virtual_machines 由 storage/data.sql 建表，列定义与模型不完全一致，只补充新增的列而不做 AutoMigrate
+	if err := addMissingColumns(m.db, &model.VirtualMachine{}, "security_groups", "monthly_cost"); err != nil {
+		m.log.Error("virtual machine migrate error", zap.Error(err))
+		return err
+	}
+	// subscriptions 同样由 storage/data.sql 建表，补充优惠类型相关的列
+	if err := addMissingColumns(m.db, &model.Subscriptions{}, "offer_type", "credit_amount", "credit_currency", "credit_expires_at"); err != nil {
+		m.log.Error("subscriptions migrate error", zap.Error(err))
+		return err
+	}
+	if !m.db.Migrator().HasIndex(&model.Subscriptions{}, "OfferType") {
+		if err := m.db.Migrator().CreateIndex(&model.Subscriptions{}, "OfferType"); err != nil {
+			m.log.Error("subscriptions offer type index error", zap.Error(err))
+			return err
+		}
+	}
+	// storage/data.sql 旧版本的 accounts 表只允许 normal 和 error 两种订阅状态
+	if err := dropSubscriptionStatusCheck(m.db); err != nil {
+		m.log.Error("accounts subscription status constraint error", zap.Error(err))
+		return err
+	}
+	// 加密历史明文凭据，已加密的记录跳过，可重复执行
+	count, err := reencryptAccounts(ctx, m.db, func(value string) (string, error) {
+		if value == "" || secret.IsEncrypted(value) {
+			return value, nil
+		}
+		return m.cipher.Encrypt(value)
+	})
+	if err != nil {
+		m.log.Error("encrypt account credentials error", zap.Error(err))
+		return err
+	}
+	m.log.Info("encrypt account credentials success", zap.Int("accounts", count))
 	m.log.Info("AutoMigrate success")
 	os.Exit(0)
 	return nil
@@ -33,3 +117,53 @@ func (m *Migrate) Stop(ctx context.Context) error {
 	m.log.Info("AutoMigrate stop")
 	return nil
 }
+
+// addMissingColumns 按模型定义添加表中缺少的列，已存在的列跳过，可重复执行
+func addMissingColumns(db *gorm.DB, value interface{}, columns ...string) error {
+	migrator := db.Migrator()
+	for _, column := range columns {
+		if migrator.HasColumn(value, column) {
+			continue
+		}
+		if err := migrator.AddColumn(value, column); err != nil {
+			return fmt.Errorf("添加列 %s 失败: %w", column, err)
+		}
+	}
+	return nil
+}
+
+// subscriptionStatusCheck storage/data.sql 中 accounts 表的订阅状态检查约束
+var subscriptionStatusCheck = regexp.MustCompile(`(?is),\s*constraint\s+chk_subscription_status\s+check\s*\([^()]*\([^()]*\)\s*\)`)
+
+// dropSubscriptionStatusCheck 移除 SQLite 中 accounts 表的订阅状态检查约束，没有该约束时跳过
+// SQLite 不支持 DROP CONSTRAINT，按 https://www.sqlite.org/lang_altertable.html 第7节直接修改表定义，移除 CHECK 约束不影响已有数据
+func dropSubscriptionStatusCheck(db *gorm.DB) error {
+	if db.Dialector.Name() != "sqlite" {
+		return nil
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		var ddl string
+		if err := tx.Raw("SELECT sql FROM sqlite_master WHERE type = 'table' AND name = 'accounts'").Scan(&ddl).Error; err != nil {
+			return err
+		}
+		if !subscriptionStatusCheck.MatchString(ddl) {
+			return nil
+		}
+
+		var version int
+		if err := tx.Raw("PRAGMA schema_version").Scan(&version).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("PRAGMA writable_schema = ON").Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("UPDATE sqlite_master SET sql = ? WHERE type = 'table' AND name = 'accounts'",
+			subscriptionStatusCheck.ReplaceAllString(ddl, "")).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec(fmt.Sprintf("PRAGMA schema_version = %d", version+1)).Error; err != nil {
+			return err
+		}
+		return tx.Exec("PRAGMA writable_schema = OFF").Error
+	})
+}
@@ -1,25 +1,79 @@
 package server
 
 import (
+	"azure-vm-backend/internal/model"
 	"azure-vm-backend/internal/service"
 	"azure-vm-backend/pkg/log"
 	"context"
 	"time"
 
 	"github.com/go-co-op/gocron"
+	"github.com/spf13/viper"
 	"go.uber.org/zap"
 )
 
+// defaultScheduleReloadInterval 重新加载用户定时同步设置的默认间隔
+const defaultScheduleReloadInterval = time.Minute
+
+// defaultPriceRefreshInterval 刷新规格零售价格的默认间隔，零售价格通常按月调整
+const defaultPriceRefreshInterval = 24 * time.Hour
+
+// defaultCostSyncInterval 同步实际费用的默认间隔，Cost Management 的数据通常每天更新数次
+const defaultCostSyncInterval = 12 * time.Hour
+
+// defaultSubscriptionCheckInterval 检查订阅状态的默认间隔
+const defaultSubscriptionCheckInterval = time.Hour
+
+// registeredSchedule 已注册到调度器的定时同步，用于判断设置是否变更
+type registeredSchedule struct {
+	cronExpr  string
+	updatedAt time.Time
+}
+
 type Task struct {
-	log            *log.Logger
-	scheduler      *gocron.Scheduler
-	accountService service.AccountsService
+	log                 *log.Logger
+	scheduler           *gocron.Scheduler
+	syncScheduleService service.SyncScheduleService
+	vmPriceService      service.VmPriceService
+	spendService        service.SpendService
+	reloadInterval      time.Duration
+	priceInterval       time.Duration
+	costInterval        time.Duration
+	registered          map[string]registeredSchedule
+
+	subscriptionsService      service.SubscriptionsService
+	subscriptionCheckInterval time.Duration
 }
 
-func NewTask(log *log.Logger, accountService service.AccountsService) *Task {
+func NewTask(log *log.Logger, conf *viper.Viper, syncScheduleService service.SyncScheduleService, vmPriceService service.VmPriceService, spendService service.SpendService, subscriptionsService service.SubscriptionsService) *Task {
+	reloadInterval := conf.GetDuration("task.schedule_reload_interval")
+	if reloadInterval <= 0 {
+		reloadInterval = defaultScheduleReloadInterval
+	}
+	priceInterval := conf.GetDuration("task.price_refresh_interval")
+	if priceInterval <= 0 {
+		priceInterval = defaultPriceRefreshInterval
+	}
+	costInterval := conf.GetDuration("task.cost_sync_interval")
+	if costInterval <= 0 {
+		costInterval = defaultCostSyncInterval
+	}
+	subscriptionCheckInterval := conf.GetDuration("task.subscription_check_interval")
+	if subscriptionCheckInterval <= 0 {
+		subscriptionCheckInterval = defaultSubscriptionCheckInterval
+	}
 	return &Task{
-		log:            log,
-		accountService: accountService,
+		log:                 log,
+		syncScheduleService: syncScheduleService,
+		vmPriceService:      vmPriceService,
+		spendService:        spendService,
+		reloadInterval:      reloadInterval,
+		priceInterval:       priceInterval,
+		costInterval:        costInterval,
+		registered:          make(map[string]registeredSchedule),
+
+		subscriptionsService:      subscriptionsService,
+		subscriptionCheckInterval: subscriptionCheckInterval,
 	}
 }
 func (t *Task) Start(ctx context.Context) error {
@@ -27,24 +81,39 @@ func (t *Task) Start(ctx context.Context) error {
 		t.log.Error("Task Panic", zap.String("job", jobName), zap.Any("recover", recoverData))
 	})
 
-	// eg: crontab task
+	// 用户的cron表达式均按UTC解析
 	t.scheduler = gocron.NewScheduler(time.UTC)
-	// if you are in China, you will need to change the time zone as follows
-	// t.scheduler = gocron.NewScheduler(time.FixedZone("PRC", 8*60*60))
 
-	// 查询用户的同步设置 一次性加载 动态设置 corn
-	_, err := t.scheduler.CronWithSeconds("0/3 * * * * *").Do(func() {
-		t.log.Info("I'm a Task1.")
-	})
+	if err := t.syncScheduleService.FailInterruptedRuns(ctx); err != nil {
+		t.log.Error("处理中断的定时同步记录失败", zap.Error(err))
+	}
+
+	// 定时加载用户的同步设置，动态注册、更新和移除定时任务
+	_, err := t.scheduler.Every(t.reloadInterval).SingletonMode().Do(t.reloadSchedules, ctx)
 	if err != nil {
-		t.log.Error("Task1 error", zap.Error(err))
+		t.log.Error("注册定时同步加载任务失败", zap.Error(err))
+		return err
 	}
 
-	_, err = t.scheduler.Every("3s").Do(func() {
-		t.log.Info("I'm a Task2.")
-	})
+	// 定时刷新已同步规格的零售价格，启动时立即执行一次
+	_, err = t.scheduler.Every(t.priceInterval).SingletonMode().Do(t.refreshPrices, ctx)
+	if err != nil {
+		t.log.Error("注册规格价格刷新任务失败", zap.Error(err))
+		return err
+	}
+
+	// 定时同步全部账户的实际费用，启动时立即执行一次
+	_, err = t.scheduler.Every(t.costInterval).SingletonMode().Do(t.syncCosts, ctx)
+	if err != nil {
+		t.log.Error("注册费用同步任务失败", zap.Error(err))
+		return err
+	}
+
+	// 定时检查全部账户的订阅状态，启动时立即执行一次
+	_, err = t.scheduler.Every(t.subscriptionCheckInterval).SingletonMode().Do(t.checkSubscriptions, ctx)
 	if err != nil {
-		t.log.Error("Task2 error", zap.Error(err))
+		t.log.Error("注册订阅状态检查任务失败", zap.Error(err))
+		return err
 	}
 
 	t.scheduler.StartBlocking()
@@ -55,3 +124,85 @@ func (t *Task) Stop(ctx context.Context) error {
 	t.log.Info("Task stop...")
 	return nil
 }
+
+// reloadSchedules 对比数据库中启用的定时同步与已注册的任务，只在设置变化时重新注册
+func (t *Task) reloadSchedules(ctx context.Context) {
+	schedules, err := t.syncScheduleService.ListEnabledSchedules(ctx)
+	if err != nil {
+		t.log.Error("加载定时同步设置失败", zap.Error(err))
+		return
+	}
+
+	enabled := make(map[string]*model.SyncSchedule, len(schedules))
+	for _, schedule := range schedules {
+		enabled[schedule.ScheduleID] = schedule
+	}
+
+	// 移除已删除、已禁用或已修改的任务
+	for scheduleID, registered := range t.registered {
+		schedule, ok := enabled[scheduleID]
+		if ok && schedule.CronExpr == registered.cronExpr && schedule.UpdatedAt.Equal(registered.updatedAt) {
+			continue
+		}
+		if err := t.scheduler.RemoveByTag(scheduleID); err != nil {
+			t.log.Warn("移除定时同步任务失败", zap.String("scheduleId", scheduleID), zap.Error(err))
+		}
+		delete(t.registered, scheduleID)
+		t.log.Info("已移除定时同步任务", zap.String("scheduleId", scheduleID))
+	}
+
+	// 注册新增或修改后的任务
+	for scheduleID, schedule := range enabled {
+		if _, ok := t.registered[scheduleID]; ok {
+			continue
+		}
+		if _, err := service.ParseSyncCron(schedule.CronExpr); err != nil {
+			t.log.Error("定时同步的cron表达式无效",
+				zap.String("scheduleId", scheduleID),
+				zap.String("cronExpr", schedule.CronExpr),
+				zap.Error(err))
+			continue
+		}
+		_, err := t.scheduler.Cron(schedule.CronExpr).Tag(scheduleID).SingletonMode().Do(t.runSchedule, ctx, schedule)
+		if err != nil {
+			t.log.Error("注册定时同步任务失败", zap.String("scheduleId", scheduleID), zap.Error(err))
+			continue
+		}
+		t.registered[scheduleID] = registeredSchedule{
+			cronExpr:  schedule.CronExpr,
+			updatedAt: schedule.UpdatedAt,
+		}
+		t.log.Info("已注册定时同步任务",
+			zap.String("scheduleId", scheduleID),
+			zap.String("userId", schedule.UserID),
+			zap.String("cronExpr", schedule.CronExpr))
+	}
+}
+
+// runSchedule 执行一次用户的定时同步
+func (t *Task) runSchedule(ctx context.Context, schedule *model.SyncSchedule) {
+	if _, err := t.syncScheduleService.RunSchedule(ctx, schedule); err != nil {
+		t.log.Error("定时同步执行失败", zap.String("scheduleId", schedule.ScheduleID), zap.Error(err))
+	}
+}
+
+// refreshPrices 刷新规格零售价格
+func (t *Task) refreshPrices(ctx context.Context) {
+	if err := t.vmPriceService.RefreshPrices(ctx); err != nil {
+		t.log.Error("刷新规格价格失败", zap.Error(err))
+	}
+}
+
+// syncCosts 同步全部账户的实际费用
+func (t *Task) syncCosts(ctx context.Context) {
+	if err := t.spendService.SyncAllCosts(ctx); err != nil {
+		t.log.Error("同步实际费用失败", zap.Error(err))
+	}
+}
+
+// checkSubscriptions 检查全部账户的订阅状态
+func (t *Task) checkSubscriptions(ctx context.Context) {
+	if err := t.subscriptionsService.CheckSubscriptionStates(ctx); err != nil {
+		t.log.Error("检查订阅状态失败", zap.Error(err))
+	}
+}
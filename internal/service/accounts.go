@@ -13,7 +13,6 @@ import (
 	"go.uber.org/zap"
 	"golang.org/x/sync/errgroup"
 	"gorm.io/gorm"
-	"strings"
 	"time"
 )
 
@@ -32,6 +31,7 @@ type accountsService struct {
 	accountsRepo          repository.AccountsRepository
 	subscriptionsService  SubscriptionsService  // 添加订阅服务
 	virtualMachineService VirtualMachineService // 添加虚拟机服务
+	azureProvider         azure.Provider
 }
 
 func NewAccountsService(
@@ -39,12 +39,14 @@ func NewAccountsService(
 	accountsRepo repository.AccountsRepository,
 	subscriptionsService SubscriptionsService,
 	virtualMachineService VirtualMachineService,
+	azureProvider azure.Provider,
 ) AccountsService {
 	return &accountsService{
 		Service:               service,
 		accountsRepo:          accountsRepo,
 		subscriptionsService:  subscriptionsService,
 		virtualMachineService: virtualMachineService,
+		azureProvider:         azureProvider,
 	}
 }
 
@@ -56,7 +58,7 @@ func (s *accountsService) CreateAccount(ctx context.Context, userId string, req
 			zap.Error(err),
 			zap.String("email", req.LoginEmail),
 		)
-		return "", v1.ErrAccountError
+		return "", v1.ErrInternalServerError
 	}
 	if existingAccount != nil {
 		s.logger.Warn("已使用的电子邮件",
@@ -66,7 +68,7 @@ func (s *accountsService) CreateAccount(ctx context.Context, userId string, req
 		return "", v1.ErrAccountEmailDuplicate
 	}
 	// 2. 验证 Azure 凭据
-	validator := azure.NewValidator(60 * time.Second)
+	validator := s.azureProvider.Validator(60 * time.Second)
 	result := validator.ValidateWithContext(ctx, azure.Credentials{
 		TenantID:     req.Tenant,      // tenant
 		ClientID:     req.AppID,       // appId
@@ -81,21 +83,31 @@ func (s *accountsService) CreateAccount(ctx context.Context, userId string, req
 			zap.Time("validated_at", result.ValidatedAt),
 			zap.String("display_name", req.DisplayName),
 		)
-		return "", fmt.Errorf("azure验证失败: %s", result.Message)
+		return "", azureValidationError(result)
+	}
+	// 3. 加密凭据后创建账号记录
+	loginPassword, err := s.cipher.Encrypt(req.LoginPassword)
+	if err != nil {
+		s.logger.Error("加密登录密码失败", zap.Error(err))
+		return "", v1.ErrInternalServerError
+	}
+	clientSecret, err := s.cipher.Encrypt(req.PassWord)
+	if err != nil {
+		s.logger.Error("加密客户端密钥失败", zap.Error(err))
+		return "", v1.ErrInternalServerError
 	}
-	// 3. 创建账号记录
 	account := &model.Accounts{
 		AccountID:          uuid.New().String(),
 		UserID:             userId,
 		LoginEmail:         req.LoginEmail,
-		LoginPassword:      req.LoginPassword,
+		LoginPassword:      loginPassword,
 		Remark:             req.Remark,
 		AppID:              req.AppID,
-		PassWord:           req.PassWord,
+		PassWord:           clientSecret,
 		Tenant:             req.Tenant,
 		DisplayName:        req.DisplayName,
 		VmCount:            req.VmCount,
-		SubscriptionStatus: "normal",
+		SubscriptionStatus: model.AccountSubscriptionNormal,
 	}
 
 	if err := s.accountsRepo.Create(ctx, account); err != nil {
@@ -206,18 +218,37 @@ func (s *accountsService) UpdateAccount(ctx context.Context, userId string, acco
 		}
 	}
 
+	// 辅助函数：加密后添加到更新map中
+	addEncryptedIfNotEmpty := func(dbField string, value string) error {
+		if value == "" {
+			return nil
+		}
+		encrypted, err := s.cipher.Encrypt(value)
+		if err != nil {
+			return err
+		}
+		updates[dbField] = encrypted
+		return nil
+	}
+
 	// 统一处理所有字段更新
 	addIfNotEmpty("login_email", req.LoginEmail)
-	addIfNotEmpty("login_password", req.LoginPassword)
 	addIfNotEmpty("remark", req.Remark)
 	addIfNotEmpty("app_id", req.AppID)
-	addIfNotEmpty("password", req.PassWord)
 	addIfNotEmpty("tenant", req.Tenant)
 	addIfNotEmpty("display_name", req.DisplayName)
+	if err := addEncryptedIfNotEmpty("login_password", req.LoginPassword); err != nil {
+		s.logger.Error("加密登录密码失败", zap.Error(err))
+		return v1.ErrInternalServerError
+	}
+	if err := addEncryptedIfNotEmpty("password", req.PassWord); err != nil {
+		s.logger.Error("加密客户端密钥失败", zap.Error(err))
+		return v1.ErrInternalServerError
+	}
 
 	// 如果有Azure凭据相关的更新，需要验证新凭据
 	if req.AppID != "" || req.PassWord != "" || req.Tenant != "" {
-		validator := azure.NewValidator(60 * time.Second)
+		validator := s.azureProvider.Validator(60 * time.Second)
 		result := validator.ValidateWithContext(ctx, azure.Credentials{
 			TenantID:     req.Tenant,
 			ClientID:     req.AppID,
@@ -230,7 +261,7 @@ func (s *accountsService) UpdateAccount(ctx context.Context, userId string, acco
 				zap.Error(result.Error),
 				zap.String("message", result.Message),
 			)
-			return fmt.Errorf("azure验证失败: %s", result.Message)
+			return azureValidationError(result)
 		}
 	}
 
@@ -255,6 +286,22 @@ func (s *accountsService) UpdateAccount(ctx context.Context, userId string, acco
 	return nil
 }
 
+// azureValidationError 将凭据验证失败转换为业务错误，Azure返回的错误码优先
+func azureValidationError(result azure.ValidationResult) error {
+	if result.Error != nil {
+		if err := v1.FromAzureError(result.Error); !errors.Is(err, v1.ErrAzureRequestFailed) {
+			return err
+		}
+	}
+	return v1.ErrAzureAuthenticationFailed.Wrap(result.Error).WithDetail(result.Message)
+}
+
+// syncAccountOutcome 单个账户的同步结果及是否成功
+type syncAccountOutcome struct {
+	result  v1.SyncAccountResult
+	success bool
+}
+
 // SyncAccounts 同步多个Azure账户信息
 func (s *accountsService) SyncAccounts(ctx context.Context, userId string, accountIds []string) (*v1.SyncAccountResp, error) {
 	result := &v1.SyncAccountResp{
@@ -290,7 +337,7 @@ func (s *accountsService) SyncAccounts(ctx context.Context, userId string, accou
 
 	// 使用 errgroup 进行并发同步
 	g, ctx := errgroup.WithContext(ctx)
-	resultChan := make(chan v1.SyncAccountResult, len(accounts))
+	resultChan := make(chan syncAccountOutcome, len(accounts))
 
 	// 并发同步每个账户
 	for _, account := range accounts {
@@ -303,41 +350,52 @@ func (s *accountsService) SyncAccounts(ctx context.Context, userId string, accou
 			// 1. 同步订阅信息
 			subCount, err := s.subscriptionsService.SyncSubscriptions(ctx, userId, account.AccountID)
 			if err != nil {
-				syncResult.Message = fmt.Sprintf("同步订阅失败: %v", err)
-				resultChan <- syncResult
+				s.logger.Error("同步订阅失败",
+					zap.Error(err),
+					zap.String("accountId", account.AccountID))
+				syncResult.Message = "同步订阅失败: " + v1.FromAzureError(err).Error()
+				resultChan <- syncAccountOutcome{result: syncResult}
 				return nil // 不中断其他同步
 			}
 			syncResult.SubscriptionCount = subCount
 
-			// 2. 同步虚拟机信息
+			// 2. 同步虚拟机信息，所有订阅都失败时才视为账户同步失败
 			vmStats, err := s.virtualMachineService.SyncVMs(ctx, userId, account.AccountID)
+			if vmStats != nil {
+				syncResult.VMCount = vmStats.TotalVMs
+				syncResult.Subscriptions = vmStats.Subscriptions
+			}
 			if err != nil {
-				syncResult.Message = fmt.Sprintf("同步虚拟机失败: %v", err)
-				resultChan <- syncResult
+				s.logger.Error("同步虚拟机失败",
+					zap.Error(err),
+					zap.String("accountId", account.AccountID))
+				syncResult.Message = "同步虚拟机失败: " + v1.FromAzureError(err).Error()
+				resultChan <- syncAccountOutcome{result: syncResult}
 				return nil
 			}
-			syncResult.VMCount = vmStats.TotalVMs
 
-			// 同步成功
-			syncResult.Message = "同步成功"
-			resultChan <- syncResult
+			if vmStats.FailedSubscriptions > 0 {
+				syncResult.Message = fmt.Sprintf("同步成功，%d 个订阅的虚拟机同步失败", vmStats.FailedSubscriptions)
+			} else {
+				syncResult.Message = "同步成功"
+			}
+			resultChan <- syncAccountOutcome{result: syncResult, success: true}
 			return nil
 		})
 	}
 
-	// 等待所有同步完成
+	// 等待所有同步完成，goroutine 均返回 nil，结果通道总会被关闭
 	go func() {
-		err := g.Wait()
-		if err != nil {
+		if err := g.Wait(); err != nil {
 			s.logger.Error("同步账户失败", zap.Error(err))
-			return
 		}
 		close(resultChan)
 	}()
 
 	// 收集结果
-	for res := range resultChan {
-		if strings.Contains(res.Message, "失败") {
+	for outcome := range resultChan {
+		res := outcome.result
+		if !outcome.success {
 			result.FailedAccounts = append(result.FailedAccounts, res)
 			s.logger.Error("账户同步失败",
 				zap.String("accountId", res.AccountID),
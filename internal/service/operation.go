@@ -0,0 +1,676 @@
+package service
+
+import (
+	v1 "azure-vm-backend/api/v1"
+	"azure-vm-backend/internal/model"
+	"azure-vm-backend/internal/repository"
+	"azure-vm-backend/pkg/azure"
+	"azure-vm-backend/pkg/log"
+	"context"
+	"fmt"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"strings"
+	"time"
+)
+
+const (
+	// operationPollInterval 轮询Azure操作状态的间隔
+	operationPollInterval = 5 * time.Second
+	// operationTimeout 单个操作的最长等待时间
+	operationTimeout = time.Hour
+	// operationMaxPollErrors 连续轮询失败次数上限
+	operationMaxPollErrors = 5
+)
+
+type OperationService interface {
+	// GetOperation 查询操作进度与结果
+	GetOperation(ctx context.Context, userID, operationID string) (*model.Operation, error)
+	// StartVMOperation 提交虚拟机操作并在后台轮询，立即返回操作记录
+	StartVMOperation(ctx context.Context, userID string, account *model.Accounts, vm *model.VirtualMachine, req *v1.VMOperationRequest) (*model.Operation, error)
+	// StartAttachDataDisk 校验规格的数据磁盘上限后在后台创建并挂载数据磁盘
+	StartAttachDataDisk(ctx context.Context, userID string, account *model.Accounts, vm *model.VirtualMachine, req *v1.AttachDataDiskRequest) (*model.Operation, error)
+	// StartDetachDataDisk 在后台卸载数据磁盘，deleteDisk 为 true 时同时删除磁盘
+	StartDetachDataDisk(ctx context.Context, userID string, account *model.Accounts, vm *model.VirtualMachine, diskName string, deleteDisk bool) (*model.Operation, error)
+	// StartExpandDisk 在后台扩容磁盘，diskName 为空时扩容系统盘
+	StartExpandDisk(ctx context.Context, userID string, account *model.Accounts, vm *model.VirtualMachine, diskName string, sizeGB int32) (*model.Operation, error)
+	// StartTask 创建操作记录并在后台执行由多个Azure调用组成的任务，供其他服务复用操作跟踪
+	StartTask(ctx context.Context, userID string, account *model.Accounts, vm *model.VirtualMachine, opType v1.VMOperationType, initialStatus string, task OperationTask) (*model.Operation, error)
+	// ResumeOperations 服务启动时恢复未完成操作的轮询
+	ResumeOperations(ctx context.Context) error
+}
+
+// OperationTask 在后台执行的操作，返回写入操作记录的结果描述
+type OperationTask func(ctx context.Context, fetcher azure.VMClient) (string, error)
+
+func NewOperationService(
+	service *Service,
+	operationRepository repository.OperationRepository,
+	virtualMachineRepository repository.VirtualMachineRepository,
+	accountsRepository repository.AccountsRepository,
+	vmSizeRepository repository.VmSizeRepository,
+	subscriptionQuotaService SubscriptionQuotaService,
+	vmCostService VmCostService,
+	azureProvider azure.Provider,
+	logger *log.Logger,
+) OperationService {
+	return &operationService{
+		Service:                  service,
+		operationRepository:      operationRepository,
+		virtualMachineRepository: virtualMachineRepository,
+		accountsRepository:       accountsRepository,
+		vmSizeRepository:         vmSizeRepository,
+		subscriptionQuotaService: subscriptionQuotaService,
+		vmCostService:            vmCostService,
+		azureProvider:            azureProvider,
+		logger:                   logger,
+	}
+}
+
+type operationService struct {
+	*Service
+	operationRepository      repository.OperationRepository
+	virtualMachineRepository repository.VirtualMachineRepository
+	accountsRepository       repository.AccountsRepository
+	vmSizeRepository         repository.VmSizeRepository
+	subscriptionQuotaService SubscriptionQuotaService
+	vmCostService            VmCostService
+	azureProvider            azure.Provider
+	logger                   *log.Logger
+}
+
+// GetOperation 查询操作记录
+func (s *operationService) GetOperation(ctx context.Context, userID, operationID string) (*model.Operation, error) {
+	op, err := s.operationRepository.GetByOperationID(ctx, userID, operationID)
+	if err != nil {
+		s.logger.Error("获取操作记录失败",
+			zap.Error(err),
+			zap.String("operationId", operationID))
+		return nil, v1.ErrInternalServerError
+	}
+	if op == nil {
+		return nil, v1.ErrNotFound
+	}
+	return op, nil
+}
+
+// StartVMOperation 提交虚拟机操作
+func (s *operationService) StartVMOperation(ctx context.Context, userID string, account *model.Accounts, vm *model.VirtualMachine, req *v1.VMOperationRequest) (*model.Operation, error) {
+	opType, force := req.Operation, req.Force
+
+	// 更换公网IP和调整规格由多个Azure调用组成，不对应单个长时间操作
+	switch opType {
+	case v1.VMOperationChangeIP:
+		return s.startChangeIP(ctx, userID, account, vm, req)
+	case v1.VMOperationResize:
+		return s.startResize(ctx, userID, account, vm, req)
+	}
+
+	// 1. 映射操作类型和初始状态
+	var initialStatus string
+	switch opType {
+	case v1.VMOperationStart:
+		initialStatus = "Starting"
+	case v1.VMOperationStop:
+		initialStatus = "Stopping"
+	case v1.VMOperationRestart:
+		initialStatus = "Restarting"
+	case v1.VMOperationDelete:
+		initialStatus = "Deleting"
+	default:
+		return nil, v1.ErrBadRequest
+	}
+
+	// 2. 提交Azure操作，只等待请求被受理
+	creds, err := s.accountCredentials(account)
+	if err != nil {
+		s.logger.Error("构建账户凭据失败", zap.Error(err), zap.String("accountId", account.AccountID))
+		return nil, v1.ErrInternalServerError
+	}
+	fetcher := s.azureProvider.VMClient(creds, s.logger.With(), 30*time.Second)
+	poller, err := fetcher.BeginVMOperation(ctx, toAzureOperationType(opType), operationTarget(vm), &azure.OperationOptions{Force: force}, "")
+	if err != nil {
+		s.logger.Error("提交虚拟机操作失败",
+			zap.Error(err),
+			zap.String("vmId", vm.VMID),
+			zap.String("operation", string(opType)))
+		return nil, v1.FromAzureError(err)
+	}
+
+	// 3. 记录操作及恢复令牌
+	op := newOperation(userID, account, vm, opType, force)
+	if !poller.Done() {
+		if token, err := poller.ResumeToken(); err == nil {
+			op.ResumeToken = token
+		}
+	}
+	if err := s.operationRepository.Create(ctx, op); err != nil {
+		s.logger.Error("创建操作记录失败", zap.Error(err), zap.String("vmId", vm.VMID))
+		return nil, v1.ErrInternalServerError
+	}
+
+	// 4. 更新虚拟机过渡状态
+	if err := s.virtualMachineRepository.UpdateStatus(ctx, vm.VMID, initialStatus); err != nil {
+		s.logger.Error("更新虚拟机状态失败", zap.Error(err))
+	}
+
+	go s.track(op, fetcher, poller)
+
+	return op, nil
+}
+
+// startChangeIP 提交更换公网IP操作，在后台完成创建新IP、切换网卡和删除原IP
+func (s *operationService) startChangeIP(ctx context.Context, userID string, account *model.Accounts, vm *model.VirtualMachine, req *v1.VMOperationRequest) (*model.Operation, error) {
+	if req.PublicIPSku != "" &&
+		!strings.EqualFold(req.PublicIPSku, azure.PublicIPSkuStandard) &&
+		!strings.EqualFold(req.PublicIPSku, azure.PublicIPSkuBasic) {
+		return nil, v1.ErrInvalidParams.WithDetail("publicIpSku 只能为 Standard 或 Basic")
+	}
+
+	target := operationTarget(vm)
+	opts := azure.PublicIPChangeOptions{Sku: req.PublicIPSku}
+	op := newOperation(userID, account, vm, req.Operation, req.Force)
+	return s.startTask(ctx, op, account, "", func(ctx context.Context, fetcher azure.VMClient) (string, error) {
+		result, err := fetcher.ChangePublicIP(ctx, target, opts)
+		if err != nil {
+			return "", err
+		}
+
+		var publicIPs []string
+		if result.PublicIP != "" {
+			publicIPs = []string{result.PublicIP}
+		}
+		if err := s.virtualMachineRepository.UpdatePublicIP(ctx, op.VMID, publicIPs, result.PublicIPName, result.DnsAlias); err != nil {
+			s.logger.Error("更新虚拟机公网IP失败",
+				zap.Error(err),
+				zap.String("operationId", op.OperationID),
+				zap.String("vmId", op.VMID))
+		}
+		if result.OldPublicIPName != "" && !result.OldPublicIPDeleted {
+			s.logger.Warn("原公网IP删除失败，需要手动清理",
+				zap.String("operationId", op.OperationID),
+				zap.String("publicIpName", result.OldPublicIPName))
+			return fmt.Sprintf("公网IP已更换为: %s，原公网IP %s 删除失败", result.PublicIP, result.OldPublicIPName), nil
+		}
+		return fmt.Sprintf("公网IP已更换为: %s", result.PublicIP), nil
+	})
+}
+
+// startResize 校验目标规格后在后台调整虚拟机规格
+func (s *operationService) startResize(ctx context.Context, userID string, account *model.Accounts, vm *model.VirtualMachine, req *v1.VMOperationRequest) (*model.Operation, error) {
+	if req.Size == "" {
+		return nil, v1.ErrInvalidParams.WithDetail("size 不能为空")
+	}
+	if strings.EqualFold(req.Size, vm.Size) {
+		return nil, v1.ErrInvalidParams.WithDetail("虚拟机已是该规格")
+	}
+
+	// 目标规格必须是该账户在虚拟机所在区域已同步的规格
+	size, err := s.vmSizeRepository.GetVmSize(ctx, userID, account.AccountID, vm.SubscriptionID, vm.Location, req.Size)
+	if err != nil {
+		s.logger.Error("查询规格失败", zap.Error(err), zap.String("size", req.Size))
+		return nil, v1.ErrInternalServerError
+	}
+	if size == nil {
+		return nil, v1.ErrVMSizeUnavailable.WithDetail(fmt.Sprintf("规格 %s 在区域 %s 未同步或不可用", req.Size, vm.Location))
+	}
+	if size.Restricted {
+		return nil, restrictedSizeError(size)
+	}
+	// 已释放的虚拟机不占用 vCPU 配额，目标规格需要完整的配额
+	var current *model.VmSize
+	if !strings.EqualFold(vm.PowerState, "deallocated") {
+		current = &model.VmSize{Name: vm.Size, Cores: int(vm.Core)}
+		if stored, err := s.vmSizeRepository.GetVmSize(ctx, userID, account.AccountID, "", vm.Location, vm.Size); err == nil && stored != nil {
+			current.QuotaFamily = stored.QuotaFamily
+		}
+	}
+	if err := s.subscriptionQuotaService.CheckVCPUQuota(ctx, account, vm.SubscriptionID, vm.Location, size, current); err != nil {
+		return nil, err
+	}
+
+	target := operationTarget(vm)
+	op := newOperation(userID, account, vm, req.Operation, req.Force)
+	return s.startTask(ctx, op, account, "Resizing", func(ctx context.Context, fetcher azure.VMClient) (string, error) {
+		result, err := fetcher.ResizeVM(ctx, target, size.Name)
+		if err != nil {
+			s.refreshPowerState(ctx, op, fetcher)
+			return "", err
+		}
+
+		if err := s.virtualMachineRepository.UpdateSize(ctx, op.VMID, size.Name, int32(size.Cores), int32(size.MemoryGB), result.PowerState); err != nil {
+			s.logger.Error("更新虚拟机规格失败", zap.Error(err), zap.String("vmId", op.VMID))
+		}
+		s.vmCostService.RefreshMonthlyCost(ctx, op.UserID, op.AccountID, op.VMID)
+		return fmt.Sprintf("虚拟机规格已调整为: %s", size.Name), nil
+	})
+}
+
+// StartAttachDataDisk 创建并挂载数据磁盘
+func (s *operationService) StartAttachDataDisk(ctx context.Context, userID string, account *model.Accounts, vm *model.VirtualMachine, req *v1.AttachDataDiskRequest) (*model.Operation, error) {
+	if req.StorageAccountType != "" && !azure.IsValidStorageAccountType(req.StorageAccountType) {
+		return nil, v1.ErrInvalidParams.WithDetail(fmt.Sprintf("不支持的磁盘类型: %s", req.StorageAccountType))
+	}
+
+	disks := s.storedDisks(vm)
+	for _, disk := range disks.DataDisks {
+		if req.Name != "" && strings.EqualFold(disk.Name, req.Name) {
+			return nil, v1.ErrInvalidParams.WithDetail(fmt.Sprintf("磁盘 %s 已挂载", req.Name))
+		}
+		if req.Lun != nil && disk.Lun == *req.Lun {
+			return nil, v1.ErrInvalidParams.WithDetail(fmt.Sprintf("LUN %d 已被占用", *req.Lun))
+		}
+	}
+
+	// 数据磁盘数量和LUN受规格的 MaxDataDisks 限制，规格未同步时交由Azure校验
+	size, err := s.vmSizeRepository.GetVmSize(ctx, userID, account.AccountID, "", vm.Location, vm.Size)
+	if err != nil {
+		s.logger.Error("查询规格失败", zap.Error(err), zap.String("size", vm.Size))
+		return nil, v1.ErrInternalServerError
+	}
+	if size == nil {
+		s.logger.Warn("虚拟机规格未同步，跳过数据磁盘数量校验",
+			zap.String("vmId", vm.VMID),
+			zap.String("size", vm.Size))
+	} else if size.MaxDataDisks > 0 {
+		if len(disks.DataDisks) >= size.MaxDataDisks {
+			return nil, v1.ErrDataDiskLimitExceeded.WithDetail(fmt.Sprintf("规格 %s 最多挂载 %d 块数据磁盘", vm.Size, size.MaxDataDisks))
+		}
+		if req.Lun != nil && int(*req.Lun) >= size.MaxDataDisks {
+			return nil, v1.ErrInvalidParams.WithDetail(fmt.Sprintf("规格 %s 的LUN范围为 0-%d", vm.Size, size.MaxDataDisks-1))
+		}
+	}
+
+	target := operationTarget(vm)
+	opts := azure.DataDiskAttachOptions{
+		Name:               req.Name,
+		SizeGB:             req.SizeGB,
+		Lun:                req.Lun,
+		StorageAccountType: req.StorageAccountType,
+	}
+	op := newOperation(userID, account, vm, v1.VMOperationAttachDisk, false)
+	return s.startTask(ctx, op, account, "", func(ctx context.Context, fetcher azure.VMClient) (string, error) {
+		result, err := fetcher.AttachDataDisk(ctx, target, opts)
+		if err != nil {
+			return "", err
+		}
+		s.refreshDisks(ctx, op, result)
+		return fmt.Sprintf("已挂载 %dGB 数据磁盘，当前共 %d 块数据磁盘", opts.SizeGB, len(result.DataDisks)), nil
+	})
+}
+
+// StartDetachDataDisk 卸载数据磁盘
+func (s *operationService) StartDetachDataDisk(ctx context.Context, userID string, account *model.Accounts, vm *model.VirtualMachine, diskName string, deleteDisk bool) (*model.Operation, error) {
+	if findDataDisk(s.storedDisks(vm), diskName) == nil {
+		return nil, v1.ErrNotFound.WithDetail(fmt.Sprintf("虚拟机未挂载磁盘: %s", diskName))
+	}
+
+	target := operationTarget(vm)
+	op := newOperation(userID, account, vm, v1.VMOperationDetachDisk, false)
+	return s.startTask(ctx, op, account, "", func(ctx context.Context, fetcher azure.VMClient) (string, error) {
+		result, err := fetcher.DetachDataDisk(ctx, target, diskName, deleteDisk)
+		if err != nil {
+			return "", err
+		}
+		s.refreshDisks(ctx, op, result)
+		if deleteDisk {
+			return fmt.Sprintf("数据磁盘 %s 已卸载并删除", diskName), nil
+		}
+		return fmt.Sprintf("数据磁盘 %s 已卸载", diskName), nil
+	})
+}
+
+// StartExpandDisk 扩容系统盘或数据磁盘
+func (s *operationService) StartExpandDisk(ctx context.Context, userID string, account *model.Accounts, vm *model.VirtualMachine, diskName string, sizeGB int32) (*model.Operation, error) {
+	disks := s.storedDisks(vm)
+	label := diskName
+	current := disks.OSDiskSize
+	if diskName == "" {
+		// 系统盘只能在虚拟机释放后扩容
+		label = "系统盘"
+		if !strings.EqualFold(vm.PowerState, "deallocated") {
+			return nil, v1.ErrVMNotDeallocated.WithDetail("扩容系统盘前需要先释放虚拟机")
+		}
+	} else {
+		disk := findDataDisk(disks, diskName)
+		if disk == nil {
+			return nil, v1.ErrNotFound.WithDetail(fmt.Sprintf("虚拟机未挂载磁盘: %s", diskName))
+		}
+		current = disk.SizeGB
+	}
+	if sizeGB <= current {
+		return nil, v1.ErrInvalidParams.WithDetail(fmt.Sprintf("%s 当前为 %dGB，只能扩容不能缩小", label, current))
+	}
+
+	target := operationTarget(vm)
+	op := newOperation(userID, account, vm, v1.VMOperationExpandDisk, false)
+	return s.startTask(ctx, op, account, "", func(ctx context.Context, fetcher azure.VMClient) (string, error) {
+		result, err := fetcher.ExpandDisk(ctx, target, diskName, sizeGB)
+		if err != nil {
+			return "", err
+		}
+		s.refreshDisks(ctx, op, result)
+		return fmt.Sprintf("%s 已扩容至 %dGB", label, sizeGB), nil
+	})
+}
+
+// storedDisks 解析虚拟机记录中的磁盘信息，解析失败时按没有数据磁盘处理
+func (s *operationService) storedDisks(vm *model.VirtualMachine) *diskInfo {
+	disks, err := decodeDiskInfo(vm.DataDisks)
+	if err != nil {
+		s.logger.Warn("解析虚拟机磁盘信息失败", zap.Error(err), zap.String("vmId", vm.VMID))
+		disks = &diskInfo{}
+	}
+	if disks.OSDiskSize == 0 {
+		disks.OSDiskSize = int32(vm.OSDiskSize)
+	}
+	return disks
+}
+
+// refreshDisks 用Azure返回的磁盘配置更新虚拟机记录，并按新的磁盘重新估算月费用
+func (s *operationService) refreshDisks(ctx context.Context, op *model.Operation, disks *azure.VMDisks) {
+	data, err := encodeDiskInfo(disks.OSDiskSize, disks.DataDisks)
+	if err == nil {
+		err = s.virtualMachineRepository.UpdateDisks(ctx, op.VMID, int(disks.OSDiskSize), data)
+	}
+	if err != nil {
+		s.logger.Error("更新虚拟机磁盘信息失败",
+			zap.Error(err),
+			zap.String("operationId", op.OperationID),
+			zap.String("vmId", op.VMID))
+		return
+	}
+	s.vmCostService.RefreshMonthlyCost(ctx, op.UserID, op.AccountID, op.VMID)
+}
+
+// findDataDisk 按名称查找数据磁盘
+func findDataDisk(disks *diskInfo, name string) *azure.DiskInfo {
+	for i := range disks.DataDisks {
+		if strings.EqualFold(disks.DataDisks[i].Name, name) {
+			return &disks.DataDisks[i]
+		}
+	}
+	return nil
+}
+
+// StartTask 创建操作记录并在后台执行任务
+func (s *operationService) StartTask(ctx context.Context, userID string, account *model.Accounts, vm *model.VirtualMachine, opType v1.VMOperationType, initialStatus string, task OperationTask) (*model.Operation, error) {
+	return s.startTask(ctx, newOperation(userID, account, vm, opType, false), account, initialStatus, task)
+}
+
+// startTask 创建操作记录并在后台执行由多个Azure调用组成的操作，task 返回结果描述
+// 这类操作没有可持久化的恢复令牌，服务重启时按失败处理
+func (s *operationService) startTask(ctx context.Context, op *model.Operation, account *model.Accounts, initialStatus string, task OperationTask) (*model.Operation, error) {
+	creds, err := s.accountCredentials(account)
+	if err != nil {
+		s.logger.Error("构建账户凭据失败", zap.Error(err), zap.String("accountId", account.AccountID))
+		return nil, v1.ErrInternalServerError
+	}
+	fetcher := s.azureProvider.VMClient(creds, s.logger.With(), 30*time.Second)
+
+	if err := s.operationRepository.Create(ctx, op); err != nil {
+		s.logger.Error("创建操作记录失败", zap.Error(err), zap.String("vmId", op.VMID))
+		return nil, v1.ErrInternalServerError
+	}
+	if initialStatus != "" {
+		if err := s.virtualMachineRepository.UpdateStatus(ctx, op.VMID, initialStatus); err != nil {
+			s.logger.Error("更新虚拟机状态失败", zap.Error(err))
+		}
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), operationTimeout)
+		defer cancel()
+		result, err := task(ctx, fetcher)
+		s.complete(ctx, op, result, err)
+	}()
+
+	return op, nil
+}
+
+// ResumeOperations 恢复所有未完成操作的轮询
+func (s *operationService) ResumeOperations(ctx context.Context) error {
+	ops, err := s.operationRepository.ListUnfinished(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, op := range ops {
+		logger := s.logger.With(zap.String("operationId", op.OperationID))
+
+		account, err := s.accountsRepository.GetAccountByUserIdAndAccountId(ctx, op.UserID, op.AccountID)
+		if err != nil || account == nil {
+			s.finish(ctx, op, nil, fmt.Errorf("获取账户信息失败: %v", err))
+			continue
+		}
+
+		creds, err := s.accountCredentials(account)
+		if err != nil {
+			s.finish(ctx, op, nil, err)
+			continue
+		}
+		fetcher := s.azureProvider.VMClient(creds, logger, 30*time.Second)
+
+		// 多步操作没有恢复令牌，按失败处理并回写实际电源状态，避免虚拟机停留在 Resizing、Restoring 等中间状态
+		if op.ResumeToken == "" {
+			s.finish(ctx, op, nil, fmt.Errorf("缺少恢复令牌，无法继续跟踪操作"))
+			s.refreshPowerState(ctx, op, fetcher)
+			continue
+		}
+
+		poller, err := fetcher.BeginVMOperation(ctx, toAzureOperationType(v1.VMOperationType(op.Type)),
+			azure.VMDetails{SubscriptionID: op.SubscriptionID, ResourceGroup: op.ResourceGroup, Name: op.ResourceName},
+			&azure.OperationOptions{Force: op.Force}, op.ResumeToken)
+		if err != nil {
+			s.finish(ctx, op, fetcher, fmt.Errorf("恢复操作轮询失败: %w", err))
+			continue
+		}
+
+		logger.Info("恢复操作轮询", zap.String("type", op.Type), zap.String("vmId", op.VMID))
+		go s.track(op, fetcher, poller)
+	}
+
+	return nil
+}
+
+// refreshPowerState 操作失败后回写虚拟机的实际电源状态，查询失败时保留当前状态，由下次同步更正
+func (s *operationService) refreshPowerState(ctx context.Context, op *model.Operation, fetcher azure.VMClient) {
+	powerState, err := fetcher.GetVMStatus(ctx, op.SubscriptionID, op.ResourceGroup, op.ResourceName)
+	if err != nil {
+		s.logger.Warn("获取虚拟机状态失败", zap.Error(err), zap.String("vmId", op.VMID))
+		return
+	}
+	if err := s.virtualMachineRepository.UpdateStatus(ctx, op.VMID, powerState); err != nil {
+		s.logger.Error("更新虚拟机状态失败", zap.Error(err), zap.String("vmId", op.VMID))
+	}
+}
+
+// track 在后台轮询操作直至结束，每次轮询后刷新恢复令牌和进度
+func (s *operationService) track(op *model.Operation, fetcher azure.VMClient, poller azure.OperationPoller) {
+	ctx, cancel := context.WithTimeout(context.Background(), operationTimeout)
+	defer cancel()
+
+	logger := s.logger.With(zap.String("operationId", op.OperationID))
+	progress := op.Progress
+	pollErrors := 0
+
+	for !poller.Done() {
+		select {
+		case <-ctx.Done():
+			s.finish(context.Background(), op, fetcher, fmt.Errorf("等待操作完成超时"))
+			return
+		case <-time.After(operationPollInterval):
+		}
+
+		if err := poller.Poll(ctx); err != nil {
+			pollErrors++
+			logger.Warn("轮询操作状态失败", zap.Error(err), zap.Int("attempt", pollErrors))
+			if pollErrors >= operationMaxPollErrors {
+				s.finish(ctx, op, fetcher, fmt.Errorf("轮询操作状态失败: %w", err))
+				return
+			}
+			continue
+		}
+		pollErrors = 0
+
+		if poller.Done() {
+			break
+		}
+		if progress < 90 {
+			progress += 10
+		}
+		fields := map[string]interface{}{"progress": progress}
+		if token, err := poller.ResumeToken(); err == nil {
+			fields["resume_token"] = token
+		}
+		if err := s.operationRepository.Update(ctx, op.OperationID, fields); err != nil {
+			logger.Error("更新操作进度失败", zap.Error(err))
+		}
+	}
+
+	s.finish(ctx, op, fetcher, poller.Result(ctx))
+}
+
+// finish 轮询结束后更新虚拟机记录并记录操作结果
+func (s *operationService) finish(ctx context.Context, op *model.Operation, fetcher azure.VMClient, opErr error) {
+	var result string
+	if opErr == nil {
+		result = s.applyResult(ctx, op, fetcher)
+	}
+	s.complete(ctx, op, result, opErr)
+}
+
+// complete 记录操作最终结果，失败时按操作类型回写虚拟机状态
+func (s *operationService) complete(ctx context.Context, op *model.Operation, result string, opErr error) {
+	logger := s.logger.With(
+		zap.String("operationId", op.OperationID),
+		zap.String("vmId", op.VMID),
+		zap.String("type", op.Type))
+
+	now := time.Now()
+	fields := map[string]interface{}{
+		"resume_token": "",
+		"finished_at":  &now,
+	}
+
+	if opErr != nil {
+		logger.Error("虚拟机操作失败", zap.Error(opErr))
+		fields["status"] = model.OperationStatusFailed
+		fields["error"] = v1.FromAzureError(opErr).Error()
+		if marksVMErrorOnFailure(v1.VMOperationType(op.Type)) {
+			if err := s.virtualMachineRepository.UpdateStatus(ctx, op.VMID, "Error"); err != nil {
+				logger.Error("更新虚拟机状态失败", zap.Error(err))
+			}
+		}
+	} else {
+		fields["status"] = model.OperationStatusSucceeded
+		fields["progress"] = 100
+		fields["result"] = result
+		logger.Info("虚拟机操作完成")
+	}
+
+	if err := s.operationRepository.Update(ctx, op.OperationID, fields); err != nil {
+		logger.Error("更新操作结果失败", zap.Error(err))
+	}
+}
+
+// applyResult 根据操作类型更新虚拟机记录，返回结果描述
+func (s *operationService) applyResult(ctx context.Context, op *model.Operation, fetcher azure.VMClient) string {
+	logger := s.logger.With(zap.String("operationId", op.OperationID), zap.String("vmId", op.VMID))
+
+	var finalStatus string
+	switch v1.VMOperationType(op.Type) {
+	case v1.VMOperationDelete:
+		if fetcher != nil {
+			vm, err := s.virtualMachineRepository.GetByID(ctx, op.VMID)
+			target := azure.VMDetails{SubscriptionID: op.SubscriptionID, ResourceGroup: op.ResourceGroup, Name: op.ResourceName}
+			if err == nil {
+				target = operationTarget(vm)
+			}
+			if err := fetcher.CleanupVMResources(ctx, target); err != nil {
+				logger.Error("清理虚拟机相关资源失败", zap.Error(err))
+			}
+		}
+		// 删除操作成功后，直接删除数据库记录
+		if err := s.virtualMachineRepository.Delete(ctx, op.VMID); err != nil {
+			logger.Error("删除虚拟机数据库记录失败", zap.Error(err))
+		}
+		return "虚拟机已删除"
+	case v1.VMOperationStart, v1.VMOperationRestart:
+		finalStatus = "Running"
+	case v1.VMOperationStop:
+		if op.Force {
+			finalStatus = "Deallocated"
+		} else {
+			finalStatus = "Stopped"
+		}
+	}
+
+	if err := s.virtualMachineRepository.UpdateStatus(ctx, op.VMID, finalStatus); err != nil {
+		logger.Error("更新虚拟机状态失败", zap.Error(err))
+	}
+	return fmt.Sprintf("虚拟机状态: %s", finalStatus)
+}
+
+// newOperation 创建运行中的操作记录
+func newOperation(userID string, account *model.Accounts, vm *model.VirtualMachine, opType v1.VMOperationType, force bool) *model.Operation {
+	now := time.Now()
+	return &model.Operation{
+		OperationID:    uuid.New().String(),
+		UserID:         userID,
+		AccountID:      account.AccountID,
+		VMID:           vm.VMID,
+		SubscriptionID: vm.SubscriptionID,
+		ResourceGroup:  vm.ResourceGroup,
+		ResourceName:   vm.Name,
+		Type:           string(opType),
+		Force:          force,
+		Status:         model.OperationStatusRunning,
+		Progress:       10,
+		StartedAt:      &now,
+	}
+}
+
+// marksVMErrorOnFailure 操作失败时是否将虚拟机标记为 Error
+// 更换公网IP不影响电源状态，调整规格和从快照恢复失败时会回写实际电源状态，
+// 服务重启后无法继续的此类操作由 ResumeOperations 回写
+func marksVMErrorOnFailure(opType v1.VMOperationType) bool {
+	switch opType {
+	case v1.VMOperationChangeIP, v1.VMOperationResize,
+		v1.VMOperationAttachDisk, v1.VMOperationDetachDisk, v1.VMOperationExpandDisk,
+		v1.VMOperationSnapshot, v1.VMOperationRestoreSnapshot, v1.VMOperationRunCommand,
+		v1.VMOperationResetAccess:
+		return false
+	}
+	return true
+}
+
+// toAzureOperationType 将接口操作类型映射为Azure操作类型
+func toAzureOperationType(opType v1.VMOperationType) azure.VMOperationType {
+	switch opType {
+	case v1.VMOperationStart:
+		return azure.VMOperationStart
+	case v1.VMOperationStop:
+		return azure.VMOperationStop
+	case v1.VMOperationRestart:
+		return azure.VMOperationRestart
+	case v1.VMOperationDelete:
+		return azure.VMOperationDelete
+	}
+	return azure.VMOperationType(opType)
+}
+
+// operationTarget 将虚拟机记录转换为Azure操作目标
+func operationTarget(vm *model.VirtualMachine) azure.VMDetails {
+	details := azure.VMDetails{
+		ID:             vm.VMID,
+		SubscriptionID: vm.SubscriptionID,
+		ResourceGroup:  vm.ResourceGroup,
+		Name:           vm.Name,
+		PublicIPName:   vm.PublicIPName,
+	}
+	if vm.PrivateIPs != "" {
+		details.PrivateIPs = strings.Split(vm.PrivateIPs, ",")
+	}
+	return details
+}
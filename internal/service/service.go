@@ -1,10 +1,18 @@
 package service
 
 import (
+	v1 "azure-vm-backend/api/v1"
+	"azure-vm-backend/internal/model"
 	"azure-vm-backend/internal/repository"
+	"azure-vm-backend/pkg/azure"
 	"azure-vm-backend/pkg/jwt"
 	"azure-vm-backend/pkg/log"
+	"azure-vm-backend/pkg/secret"
 	"azure-vm-backend/pkg/sid"
+	"context"
+	"fmt"
+	"go.uber.org/zap"
+	"time"
 )
 
 type Service struct {
@@ -12,6 +20,7 @@ type Service struct {
 	sid    *sid.Sid
 	jwt    *jwt.JWT
 	tm     repository.Transaction
+	cipher *secret.Cipher
 }
 
 func NewService(
@@ -19,11 +28,68 @@ func NewService(
 	logger *log.Logger,
 	sid *sid.Sid,
 	jwt *jwt.JWT,
+	cipher *secret.Cipher,
 ) *Service {
 	return &Service{
 		logger: logger,
 		sid:    sid,
 		jwt:    jwt,
 		tm:     tm,
+		cipher: cipher,
 	}
 }
+
+// accountCredentials 由账户信息构建Azure凭据
+// 数据库中的客户端密钥为密文，只在此处解密，解密结果不得写入日志或响应
+func (s *Service) accountCredentials(account *model.Accounts) (*azure.Credentials, error) {
+	clientSecret, err := s.cipher.Decrypt(account.PassWord)
+	if err != nil {
+		return nil, fmt.Errorf("解密账户凭据失败: %w", err)
+	}
+	return &azure.Credentials{
+		TenantID:     account.Tenant,
+		ClientID:     account.AppID,
+		ClientSecret: clientSecret,
+		DisplayName:  account.DisplayName,
+	}, nil
+}
+
+// vmClient 使用账户凭据创建虚拟机客户端
+func (s *Service) vmClient(azureProvider azure.Provider, account *model.Accounts) (azure.VMClient, error) {
+	creds, err := s.accountCredentials(account)
+	if err != nil {
+		s.logger.Error("构建账户凭据失败", zap.Error(err), zap.String("accountId", account.AccountID))
+		return nil, v1.ErrInternalServerError
+	}
+	return azureProvider.VMClient(creds, s.logger.With(), 30*time.Second), nil
+}
+
+// getAccountVM 获取用户的账户及其下的虚拟机，虚拟机不属于该账户时返回无权限
+// lookup 按 key 查找虚拟机记录，如 VirtualMachineRepository.GetVM
+func (s *Service) getAccountVM(
+	ctx context.Context,
+	accountsRepository repository.AccountsRepository,
+	userID, accountID, key string,
+	lookup func(ctx context.Context, key string) (*model.VirtualMachine, error),
+) (*model.Accounts, *model.VirtualMachine, error) {
+	account, err := accountsRepository.GetAccountByUserIdAndAccountId(ctx, userID, accountID)
+	if err != nil {
+		s.logger.Error("获取账户信息失败",
+			zap.Error(err),
+			zap.String("userId", userID),
+			zap.String("accountId", accountID))
+		return nil, nil, v1.ErrInternalServerError
+	}
+	if account == nil {
+		return nil, nil, v1.ErrAccountError
+	}
+
+	vm, err := lookup(ctx, key)
+	if err != nil || vm == nil {
+		return nil, nil, v1.ErrorAzureNotFound
+	}
+	if vm.AccountID != accountID {
+		return nil, nil, v1.ErrUnauthorized
+	}
+	return account, vm, nil
+}
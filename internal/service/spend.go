@@ -0,0 +1,352 @@
+package service
+
+import (
+	v1 "azure-vm-backend/api/v1"
+	"azure-vm-backend/internal/model"
+	"azure-vm-backend/internal/repository"
+	"azure-vm-backend/pkg/azure"
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// spendWindowDays 最近 N 天实际费用的天数，包含今天
+const spendWindowDays = 30
+
+// vmResourceMarker 虚拟机资源ID中资源类型的部分，Cost Management 返回的资源ID均为小写
+const vmResourceMarker = "/providers/microsoft.compute/virtualmachines/"
+
+type SpendService interface {
+	// SyncAccountCosts 查询账户下各订阅的实际费用并替换本地记录，日期范围覆盖本月和最近30天
+	SyncAccountCosts(ctx context.Context, userID, accountID string) (*v1.CostSyncResult, error)
+	// SyncAllCosts 同步全部账户的实际费用，单个账户失败不影响其他账户，供后台任务使用
+	SyncAllCosts(ctx context.Context) error
+	// GetAccountSpend 账户本月至今和最近30天的实际费用，按订阅细分
+	GetAccountSpend(ctx context.Context, userID, accountID string) (*v1.AccountSpend, error)
+	// GetSubscriptionSpend 订阅本月至今和最近30天的实际费用，按虚拟机细分
+	GetSubscriptionSpend(ctx context.Context, userID, accountID, subscriptionID string) (*v1.SubscriptionSpend, error)
+	// GetVMSpend 虚拟机本月至今和最近30天的实际费用，id 为虚拟机记录ID
+	GetVMSpend(ctx context.Context, userID, accountID, id string) (*v1.VMSpend, error)
+}
+
+func NewSpendService(
+	service *Service,
+	costRecordRepository repository.CostRecordRepository,
+	accountsRepository repository.AccountsRepository,
+	subscriptionsRepository repository.SubscriptionsRepository,
+	virtualMachineRepository repository.VirtualMachineRepository,
+	azureProvider azure.Provider,
+) SpendService {
+	return &spendService{
+		Service:                  service,
+		costRecordRepository:     costRecordRepository,
+		accountsRepository:       accountsRepository,
+		subscriptionsRepository:  subscriptionsRepository,
+		virtualMachineRepository: virtualMachineRepository,
+		azureProvider:            azureProvider,
+	}
+}
+
+type spendService struct {
+	*Service
+	costRecordRepository     repository.CostRecordRepository
+	accountsRepository       repository.AccountsRepository
+	subscriptionsRepository  repository.SubscriptionsRepository
+	virtualMachineRepository repository.VirtualMachineRepository
+	azureProvider            azure.Provider
+}
+
+func (s *spendService) SyncAccountCosts(ctx context.Context, userID, accountID string) (*v1.CostSyncResult, error) {
+	account, err := s.getAccount(ctx, userID, accountID)
+	if err != nil {
+		return nil, err
+	}
+	return s.syncAccount(ctx, account)
+}
+
+func (s *spendService) SyncAllCosts(ctx context.Context) error {
+	accounts, err := s.accountsRepository.ListAllAccounts(ctx)
+	if err != nil {
+		return err
+	}
+	for _, account := range accounts {
+		result, err := s.syncAccount(ctx, account)
+		if err != nil {
+			s.logger.Error("同步账户费用失败", zap.Error(err), zap.String("accountId", account.AccountID))
+			continue
+		}
+		s.logger.Info("同步账户费用完成",
+			zap.String("accountId", account.AccountID),
+			zap.Int("subscriptions", result.Subscriptions),
+			zap.Int("records", result.Records),
+			zap.Strings("failedSubscriptions", result.FailedSubscriptions))
+	}
+	return nil
+}
+
+// syncAccount 逐个订阅查询费用，只有全部订阅都失败时返回错误
+func (s *spendService) syncAccount(ctx context.Context, account *model.Accounts) (*v1.CostSyncResult, error) {
+	subscriptions, err := s.subscriptionsRepository.GetSubscriptionsByAccountId(ctx, account.UserID, account.AccountID)
+	if err != nil {
+		s.logger.Error("获取订阅列表失败", zap.Error(err), zap.String("accountId", account.AccountID))
+		return nil, v1.ErrInternalServerError
+	}
+	creds, err := s.accountCredentials(account)
+	if err != nil {
+		s.logger.Error("构建账户凭据失败", zap.Error(err), zap.String("accountId", account.AccountID))
+		return nil, v1.ErrInternalServerError
+	}
+	credential := &azure.AzureCredential{
+		TenantID:     creds.TenantID,
+		ClientID:     creds.ClientID,
+		ClientSecret: creds.ClientSecret,
+	}
+
+	from, to := spendSyncRange(time.Now())
+	result := &v1.CostSyncResult{
+		From:                from.Format("2006-01-02"),
+		To:                  to.Format("2006-01-02"),
+		FailedSubscriptions: make([]string, 0),
+	}
+	var lastErr error
+	for _, subscription := range subscriptions {
+		fetcher := s.azureProvider.CostClient(subscription.SubscriptionID, credential, s.logger.With())
+		costs, err := fetcher.QueryDailyCosts(ctx, from, to)
+		if err != nil {
+			s.logger.Warn("查询订阅费用失败",
+				zap.Error(err),
+				zap.String("accountId", account.AccountID),
+				zap.String("subscriptionId", subscription.SubscriptionID))
+			result.FailedSubscriptions = append(result.FailedSubscriptions, subscription.SubscriptionID)
+			lastErr = err
+			continue
+		}
+
+		now := time.Now()
+		records := make([]*model.CostRecord, 0, len(costs))
+		for _, cost := range costs {
+			records = append(records, &model.CostRecord{
+				UsageDate:  cost.UsageDate,
+				ResourceID: cost.ResourceID,
+				Meter:      cost.Meter,
+				Cost:       cost.Cost,
+				Currency:   cost.Currency,
+				SyncedAt:   now,
+			})
+		}
+		if err := s.costRecordRepository.ReplaceCosts(ctx, account.AccountID, subscription.SubscriptionID, from, to, records); err != nil {
+			s.logger.Error("保存费用记录失败", zap.Error(err), zap.String("subscriptionId", subscription.SubscriptionID))
+			return nil, v1.ErrInternalServerError
+		}
+		result.Subscriptions++
+		result.Records += len(records)
+	}
+
+	if result.Subscriptions == 0 && lastErr != nil {
+		return nil, v1.FromAzureError(lastErr)
+	}
+	return result, nil
+}
+
+func (s *spendService) GetAccountSpend(ctx context.Context, userID, accountID string) (*v1.AccountSpend, error) {
+	if _, err := s.getAccount(ctx, userID, accountID); err != nil {
+		return nil, err
+	}
+	subscriptions, err := s.subscriptionsRepository.GetSubscriptionsByAccountId(ctx, userID, accountID)
+	if err != nil {
+		s.logger.Error("获取订阅列表失败", zap.Error(err), zap.String("accountId", accountID))
+		return nil, v1.ErrInternalServerError
+	}
+	bySubscription, err := s.sumSpend(ctx, repository.CostSumOptions{AccountID: accountID, GroupBy: "subscription_id"})
+	if err != nil {
+		return nil, err
+	}
+	syncedAt, err := s.costRecordRepository.LastSyncedAt(ctx, accountID)
+	if err != nil {
+		s.logger.Error("查询费用同步时间失败", zap.Error(err), zap.String("accountId", accountID))
+		return nil, v1.ErrInternalServerError
+	}
+
+	// 账户下的订阅都列出，没有费用时为 0，订阅删除后的历史费用仍计入账户
+	for _, subscription := range subscriptions {
+		if _, ok := bySubscription[subscription.SubscriptionID]; !ok {
+			bySubscription[subscription.SubscriptionID] = &v1.SpendSummary{Currency: costCurrency}
+		}
+	}
+	resp := &v1.AccountSpend{
+		AccountID:     accountID,
+		SyncedAt:      syncedAt,
+		Spend:         v1.SpendSummary{Currency: costCurrency},
+		Subscriptions: make([]*v1.SubscriptionSpend, 0, len(bySubscription)),
+	}
+	for _, subscriptionID := range sortedSpendKeys(bySubscription) {
+		spend := bySubscription[subscriptionID]
+		addSpend(&resp.Spend, spend)
+		resp.Subscriptions = append(resp.Subscriptions, &v1.SubscriptionSpend{
+			SubscriptionID: subscriptionID,
+			Spend:          *spend,
+		})
+	}
+	return resp, nil
+}
+
+func (s *spendService) GetSubscriptionSpend(ctx context.Context, userID, accountID, subscriptionID string) (*v1.SubscriptionSpend, error) {
+	if _, err := s.getAccount(ctx, userID, accountID); err != nil {
+		return nil, err
+	}
+	subscription, err := s.subscriptionsRepository.GetSubscription(ctx, userID, accountID, subscriptionID)
+	if err != nil {
+		s.logger.Error("获取订阅信息失败", zap.Error(err), zap.String("subscriptionId", subscriptionID))
+		return nil, v1.ErrInternalServerError
+	}
+	if subscription == nil {
+		return nil, v1.ErrSubscriptionNotFound
+	}
+
+	byResource, err := s.sumSpend(ctx, repository.CostSumOptions{
+		AccountID:      accountID,
+		SubscriptionID: subscriptionID,
+		GroupBy:        "resource_id",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &v1.SubscriptionSpend{
+		SubscriptionID: subscriptionID,
+		Spend:          v1.SpendSummary{Currency: costCurrency},
+		VMs:            make([]*v1.VMSpend, 0),
+	}
+	for _, resourceID := range sortedSpendKeys(byResource) {
+		spend := byResource[resourceID]
+		addSpend(&resp.Spend, spend)
+		if name, ok := vmNameFromResourceID(resourceID); ok {
+			resp.VMs = append(resp.VMs, &v1.VMSpend{VMID: resourceID, Name: name, Spend: *spend})
+		}
+	}
+	return resp, nil
+}
+
+func (s *spendService) GetVMSpend(ctx context.Context, userID, accountID, id string) (*v1.VMSpend, error) {
+	if _, err := s.getAccount(ctx, userID, accountID); err != nil {
+		return nil, err
+	}
+	vm, err := s.virtualMachineRepository.GetVM(ctx, id)
+	if err != nil || vm == nil {
+		return nil, v1.ErrorAzureNotFound
+	}
+	if vm.AccountID != accountID {
+		return nil, v1.ErrUnauthorized
+	}
+
+	totals, err := s.sumSpend(ctx, repository.CostSumOptions{
+		AccountID:      accountID,
+		SubscriptionID: vm.SubscriptionID,
+		ResourceID:     vm.VMID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	resp := &v1.VMSpend{VMID: vm.VMID, Name: vm.Name, Spend: v1.SpendSummary{Currency: costCurrency}}
+	if spend, ok := totals[""]; ok {
+		resp.Spend = *spend
+	}
+	return resp, nil
+}
+
+func (s *spendService) getAccount(ctx context.Context, userID, accountID string) (*model.Accounts, error) {
+	account, err := s.accountsRepository.GetAccountByUserIdAndAccountId(ctx, userID, accountID)
+	if err != nil {
+		s.logger.Error("获取账户信息失败", zap.Error(err), zap.String("accountId", accountID))
+		return nil, v1.ErrInternalServerError
+	}
+	if account == nil {
+		return nil, v1.ErrAccountError
+	}
+	return account, nil
+}
+
+// sumSpend 分别汇总本月至今和最近30天的费用，按分组键合并
+func (s *spendService) sumSpend(ctx context.Context, opts repository.CostSumOptions) (map[string]*v1.SpendSummary, error) {
+	monthStart, windowStart, today := spendPeriods(time.Now())
+	result := make(map[string]*v1.SpendSummary)
+	periods := []struct {
+		from  time.Time
+		apply func(summary *v1.SpendSummary, cost float64)
+	}{
+		{monthStart, func(summary *v1.SpendSummary, cost float64) { summary.MonthToDate = roundCost(cost) }},
+		{windowStart, func(summary *v1.SpendSummary, cost float64) { summary.Last30Days = roundCost(cost) }},
+	}
+	for _, period := range periods {
+		opts.From, opts.To = period.from, today
+		totals, err := s.costRecordRepository.SumCosts(ctx, opts)
+		if err != nil {
+			s.logger.Error("汇总费用失败", zap.Error(err), zap.String("accountId", opts.AccountID))
+			return nil, v1.ErrInternalServerError
+		}
+		for _, total := range totals {
+			summary, ok := result[total.Key]
+			if !ok {
+				summary = &v1.SpendSummary{Currency: costCurrency}
+				result[total.Key] = summary
+			}
+			if total.Currency != "" {
+				summary.Currency = total.Currency
+			}
+			period.apply(summary, total.Cost)
+		}
+	}
+	return result, nil
+}
+
+// spendPeriods 本月1日、最近30天的第一天和今天，均为UTC零点
+func spendPeriods(now time.Time) (monthStart, windowStart, today time.Time) {
+	now = now.UTC()
+	today = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	monthStart = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	windowStart = today.AddDate(0, 0, -(spendWindowDays - 1))
+	return monthStart, windowStart, today
+}
+
+// spendSyncRange 同步费用的日期范围，同时覆盖本月和最近30天
+func spendSyncRange(now time.Time) (from, to time.Time) {
+	monthStart, windowStart, today := spendPeriods(now)
+	if monthStart.Before(windowStart) {
+		return monthStart, today
+	}
+	return windowStart, today
+}
+
+// addSpend 将 spend 累加到 total
+func addSpend(total, spend *v1.SpendSummary) {
+	total.MonthToDate = roundCost(total.MonthToDate + spend.MonthToDate)
+	total.Last30Days = roundCost(total.Last30Days + spend.Last30Days)
+	if spend.Currency != "" {
+		total.Currency = spend.Currency
+	}
+}
+
+func sortedSpendKeys(spends map[string]*v1.SpendSummary) []string {
+	keys := make([]string, 0, len(spends))
+	for key := range spends {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// vmNameFromResourceID 从小写的资源ID中取出虚拟机名称，扩展等子资源不算作虚拟机
+func vmNameFromResourceID(resourceID string) (string, bool) {
+	i := strings.Index(resourceID, vmResourceMarker)
+	if i < 0 {
+		return "", false
+	}
+	name := resourceID[i+len(vmResourceMarker):]
+	if name == "" || strings.Contains(name, "/") {
+		return "", false
+	}
+	return name, true
+}
@@ -0,0 +1,224 @@
+package service
+
+import (
+	v1 "azure-vm-backend/api/v1"
+	"azure-vm-backend/internal/model"
+	"azure-vm-backend/internal/repository"
+	"azure-vm-backend/pkg/azure"
+	"context"
+	"fmt"
+	"go.uber.org/zap"
+	"strings"
+	"time"
+)
+
+type SubscriptionQuotaService interface {
+	// ListQuotas 获取订阅各区域的 vCPU 配额，refresh 为 true 或该区域没有记录时先从Azure获取
+	ListQuotas(ctx context.Context, userID, accountID, subscriptionID string, req *v1.ListQuotasRequest) ([]*v1.RegionQuota, error)
+	// CheckVCPUQuota 调用Azure前检查区域总 vCPU 和目标规格系列的剩余配额
+	// from 为调整规格前占用配额的规格，创建虚拟机或虚拟机已释放(不占用 vCPU 配额)时为 nil
+	CheckVCPUQuota(ctx context.Context, account *model.Accounts, subscriptionID, location string, to, from *model.VmSize) error
+}
+
+func NewSubscriptionQuotaService(
+	service *Service,
+	subscriptionQuotaRepository repository.SubscriptionQuotaRepository,
+	accountsRepository repository.AccountsRepository,
+	subscriptionsRepository repository.SubscriptionsRepository,
+	azureProvider azure.Provider,
+) SubscriptionQuotaService {
+	return &subscriptionQuotaService{
+		Service:                     service,
+		subscriptionQuotaRepository: subscriptionQuotaRepository,
+		accountsRepository:          accountsRepository,
+		subscriptionsRepository:     subscriptionsRepository,
+		azureProvider:               azureProvider,
+	}
+}
+
+type subscriptionQuotaService struct {
+	*Service
+	subscriptionQuotaRepository repository.SubscriptionQuotaRepository
+	accountsRepository          repository.AccountsRepository
+	subscriptionsRepository     repository.SubscriptionsRepository
+	azureProvider               azure.Provider
+}
+
+// ListQuotas 获取订阅的 vCPU 配额，按区域汇总
+func (s *subscriptionQuotaService) ListQuotas(ctx context.Context, userID, accountID, subscriptionID string, req *v1.ListQuotasRequest) ([]*v1.RegionQuota, error) {
+	if req.Refresh && req.Location == "" {
+		return nil, v1.ErrInvalidParams.WithDetail("刷新配额需要指定区域")
+	}
+
+	account, err := s.accountsRepository.GetAccountByUserIdAndAccountId(ctx, userID, accountID)
+	if err != nil {
+		s.logger.Error("获取账户信息失败", zap.Error(err), zap.String("accountId", accountID))
+		return nil, v1.ErrInternalServerError
+	}
+	if account == nil {
+		return nil, v1.ErrAccountError
+	}
+	subscription, err := s.subscriptionsRepository.GetSubscription(ctx, userID, accountID, subscriptionID)
+	if err != nil {
+		s.logger.Error("获取订阅信息失败", zap.Error(err), zap.String("subscriptionId", subscriptionID))
+		return nil, v1.ErrInternalServerError
+	}
+	if subscription == nil {
+		return nil, v1.ErrSubscriptionNotFound
+	}
+
+	quotas, err := s.subscriptionQuotaRepository.ListQuotas(ctx, userID, accountID, subscriptionID, req.Location)
+	if err != nil {
+		s.logger.Error("查询配额失败", zap.Error(err), zap.String("subscriptionId", subscriptionID))
+		return nil, v1.ErrInternalServerError
+	}
+	if req.Location != "" && (req.Refresh || len(quotas) == 0) {
+		quotas, err = s.refresh(ctx, account, subscriptionID, req.Location)
+		if err != nil {
+			s.logger.Error("获取配额失败", zap.Error(err), zap.String("subscriptionId", subscriptionID), zap.String("location", req.Location))
+			return nil, v1.FromAzureError(err)
+		}
+	}
+	return summarizeQuotas(quotas), nil
+}
+
+// CheckVCPUQuota 获取最新配额后检查剩余 vCPU，配额获取失败时不阻止操作，由Azure返回最终结果
+func (s *subscriptionQuotaService) CheckVCPUQuota(ctx context.Context, account *model.Accounts, subscriptionID, location string, to, from *model.VmSize) error {
+	if to == nil || to.Cores <= 0 {
+		return nil
+	}
+
+	quotas, err := s.refresh(ctx, account, subscriptionID, location)
+	if err != nil {
+		s.logger.Warn("获取配额失败，跳过配额检查",
+			zap.Error(err),
+			zap.String("subscriptionId", subscriptionID),
+			zap.String("location", location))
+		return nil
+	}
+
+	// 区域总 vCPU 只需要满足增加的部分
+	totalNeed := to.Cores
+	if from != nil {
+		totalNeed -= from.Cores
+	}
+	if total := findQuota(quotas, azure.QuotaTotalRegionalVCPUs); total != nil && totalNeed > 0 {
+		if available := total.Limit - total.CurrentValue; available < int64(totalNeed) {
+			return v1.ErrQuotaExceeded.WithDetail(fmt.Sprintf("区域 %s 的总 vCPU 配额不足: 需要 %d，剩余 %d (已用 %d/%d)",
+				location, totalNeed, available, total.CurrentValue, total.Limit))
+		}
+	}
+
+	// 同一系列内调整规格时只需要满足增加的部分
+	familyName := quotaFamilyOf(to)
+	family := findQuota(quotas, familyName)
+	if family == nil {
+		return nil
+	}
+	familyNeed := to.Cores
+	if from != nil && strings.EqualFold(quotaFamilyOf(from), familyName) {
+		familyNeed -= from.Cores
+	}
+	if available := family.Limit - family.CurrentValue; familyNeed > 0 && available < int64(familyNeed) {
+		return v1.ErrQuotaExceeded.WithDetail(fmt.Sprintf("区域 %s 的 %s 配额不足: 需要 %d，剩余 %d (已用 %d/%d)",
+			location, family.Name, familyNeed, available, family.CurrentValue, family.Limit))
+	}
+	return nil
+}
+
+// refresh 从Azure获取订阅在该区域的配额并替换数据库中的记录
+func (s *subscriptionQuotaService) refresh(ctx context.Context, account *model.Accounts, subscriptionID, location string) ([]*model.SubscriptionQuota, error) {
+	creds, err := s.accountCredentials(account)
+	if err != nil {
+		return nil, err
+	}
+	fetcher := s.azureProvider.QuotaClient(
+		subscriptionID,
+		&azure.AzureCredential{
+			TenantID:     creds.TenantID,
+			ClientID:     creds.ClientID,
+			ClientSecret: creds.ClientSecret,
+		},
+		s.logger.With(),
+	)
+	usages, err := fetcher.ListUsages(ctx, location)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	quotas := make([]*model.SubscriptionQuota, 0, len(usages))
+	for _, usage := range usages {
+		quotas = append(quotas, &model.SubscriptionQuota{
+			AccountID:      account.AccountID,
+			SubscriptionID: subscriptionID,
+			Location:       location,
+			Name:           usage.Name,
+			LocalizedName:  usage.LocalizedName,
+			CurrentValue:   usage.CurrentValue,
+			Limit:          usage.Limit,
+			Unit:           usage.Unit,
+			SyncedAt:       now,
+		})
+	}
+	if err := s.subscriptionQuotaRepository.ReplaceQuotas(ctx, account.AccountID, subscriptionID, location, quotas); err != nil {
+		// 保存失败不影响本次使用
+		s.logger.Error("保存配额失败", zap.Error(err), zap.String("subscriptionId", subscriptionID))
+	}
+	return quotas, nil
+}
+
+// quotaFamilyOf 规格的配额系列，优先使用 Resource SKU 同步的系列，没有时按名称推断
+func quotaFamilyOf(size *model.VmSize) string {
+	if size.QuotaFamily != "" {
+		return size.QuotaFamily
+	}
+	return azure.QuotaFamilyName(size.Name)
+}
+
+// findQuota 按名称查找配额，名称大小写不敏感
+func findQuota(quotas []*model.SubscriptionQuota, name string) *model.SubscriptionQuota {
+	if name == "" {
+		return nil
+	}
+	for _, quota := range quotas {
+		if strings.EqualFold(quota.Name, name) {
+			return quota
+		}
+	}
+	return nil
+}
+
+// summarizeQuotas 按区域汇总 vCPU 配额，忽略可用性集等其他配额
+func summarizeQuotas(quotas []*model.SubscriptionQuota) []*v1.RegionQuota {
+	result := make([]*v1.RegionQuota, 0)
+	byLocation := make(map[string]*v1.RegionQuota)
+	for _, quota := range quotas {
+		region, ok := byLocation[quota.Location]
+		if !ok {
+			region = &v1.RegionQuota{Location: quota.Location, Families: make([]*v1.VCPUQuota, 0)}
+			byLocation[quota.Location] = region
+			result = append(result, region)
+		}
+		if quota.SyncedAt.After(region.SyncedAt) {
+			region.SyncedAt = quota.SyncedAt
+		}
+
+		usage := &v1.VCPUQuota{
+			Name:          quota.Name,
+			LocalizedName: quota.LocalizedName,
+			Used:          quota.CurrentValue,
+			Limit:         quota.Limit,
+			Available:     quota.Limit - quota.CurrentValue,
+		}
+		switch {
+		case quota.Name == azure.QuotaTotalRegionalVCPUs:
+			region.Total = usage
+		case quota.Name == azure.QuotaSpotVCPUs:
+			region.Spot = usage
+		case azure.IsVCPUFamilyQuota(quota.Name) && (quota.Limit > 0 || quota.CurrentValue > 0):
+			region.Families = append(region.Families, usage)
+		}
+	}
+	return result
+}
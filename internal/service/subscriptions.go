@@ -27,17 +27,27 @@ type SubscriptionsService interface {
 
 	// ListAllSubscriptions 获取用户所有Azure账户下的订阅信息
 	ListAllSubscriptions(ctx context.Context, userId string, query *app.QueryOption) (*app.ListResult[*model.Subscriptions], error)
+
+	// CheckSubscriptionStates 重新获取全部账户的订阅状态，记录状态变化并更新账户的订阅状态，供后台任务使用
+	CheckSubscriptionStates(ctx context.Context) error
+
+	// ListStateChanges 分页获取账户下订阅状态的变化记录
+	ListStateChanges(ctx context.Context, userId, accountId, subscriptionId string, query *app.QueryOption) (*app.ListResult[*model.SubscriptionStateChange], error)
 }
 
 func NewSubscriptionsService(
 	service *Service,
 	subscriptionsRepository repository.SubscriptionsRepository,
 	accountsRepository repository.AccountsRepository,
+	stateChangeRepository repository.SubscriptionStateChangeRepository,
+	azureProvider azure.Provider,
 ) SubscriptionsService {
 	return &subscriptionsService{
 		Service:                service,
 		subscriptionRepository: subscriptionsRepository,
 		accountsRepository:     accountsRepository,
+		stateChangeRepository:  stateChangeRepository,
+		azureProvider:          azureProvider,
 	}
 }
 
@@ -45,6 +55,8 @@ type subscriptionsService struct {
 	*Service
 	subscriptionRepository repository.SubscriptionsRepository
 	accountsRepository     repository.AccountsRepository
+	stateChangeRepository  repository.SubscriptionStateChangeRepository
+	azureProvider          azure.Provider
 }
 
 // GetSubscriptions 获取指定账号的所有订阅信息
@@ -65,7 +77,7 @@ func (s *subscriptionsService) GetSubscriptions(ctx context.Context, userId, acc
 	}
 
 	// 2. 获取订阅信息
-	subs, err := s.subscriptionRepository.GetSubscriptionsByAccountId(ctx, accountId)
+	subs, err := s.subscriptionRepository.GetSubscriptionsByAccountId(ctx, userId, accountId)
 	if err != nil {
 		s.logger.Error("获取订阅信息失败",
 			zap.Error(err),
@@ -95,7 +107,7 @@ func (s *subscriptionsService) GetSubscription(ctx context.Context, userId, acco
 	}
 
 	// 2. 获取订阅信息
-	sub, err := s.subscriptionRepository.GetSubscription(ctx, accountId, subscriptionId)
+	sub, err := s.subscriptionRepository.GetSubscription(ctx, userId, accountId, subscriptionId)
 	if err != nil {
 		s.logger.Error("获取订阅信息失败",
 			zap.Error(err),
@@ -129,16 +141,35 @@ func (s *subscriptionsService) SyncSubscriptions(ctx context.Context, userId, ac
 		return 0, v1.ErrAccountError
 	}
 
-	// 2. 创建Azure凭据
-	creds := &azure.Credentials{
-		TenantID:     account.Tenant,
-		ClientID:     account.AppID,
-		ClientSecret: account.PassWord,
-		DisplayName:  account.DisplayName,
+	return s.refreshAccount(ctx, account)
+}
+
+// refreshAccount 从Azure获取账户的订阅并保存，记录订阅状态的变化并更新账户的订阅状态
+func (s *subscriptionsService) refreshAccount(ctx context.Context, account *model.Accounts) (int, error) {
+	accountId := account.AccountID
+
+	// 1. 创建Azure凭据
+	creds, err := s.accountCredentials(account)
+	if err != nil {
+		s.logger.Error("构建账户凭据失败",
+			zap.Error(err),
+			zap.String("accountId", accountId),
+		)
+		return 0, v1.ErrInternalServerError
+	}
+
+	// 2. 记录同步前的订阅状态
+	previous, err := s.subscriptionRepository.GetSubscriptionsByAccountId(ctx, account.UserID, accountId)
+	if err != nil {
+		s.logger.Error("获取订阅信息失败",
+			zap.Error(err),
+			zap.String("accountId", accountId),
+		)
+		return 0, v1.ErrInternalServerError
 	}
 
 	// 3. 从Azure获取订阅信息
-	fetcher := azure.NewFetcher(creds, s.logger.With(), 30*time.Second)
+	fetcher := s.azureProvider.SubscriptionClient(creds, s.logger.With(), 30*time.Second)
 	azureSubs, err := fetcher.FetchSubscriptionDetails(ctx)
 	if err != nil {
 		s.logger.Error("获取Azure订阅信息失败",
@@ -146,15 +177,8 @@ func (s *subscriptionsService) SyncSubscriptions(ctx context.Context, userId, ac
 			zap.String("accountId", accountId),
 		)
 		// 更新账户状态为错误
-		if updateErr := s.accountsRepository.UpdateAccount(ctx, userId, accountId, map[string]interface{}{
-			"subscription_status": "error",
-		}); updateErr != nil {
-			s.logger.Error("更新账户状态失败",
-				zap.Error(updateErr),
-				zap.String("accountId", accountId),
-			)
-		}
-		return 0, v1.ErrInternalServerError
+		s.updateAccountStatus(ctx, account, model.AccountSubscriptionError, time.Now())
+		return 0, v1.FromAzureError(err)
 	}
 
 	// 4. 转换并保存数据
@@ -173,7 +197,7 @@ func (s *subscriptionsService) SyncSubscriptions(ctx context.Context, userId, ac
 	}
 
 	// 5. 保存到数据库
-	if err := s.subscriptionRepository.UpsertSubscriptions(ctx, subscriptions); err != nil {
+	if err := s.subscriptionRepository.UpsertSubscriptions(ctx, accountId, subscriptions); err != nil {
 		s.logger.Error("保存订阅信息失败",
 			zap.Error(err),
 			zap.String("accountId", accountId),
@@ -181,19 +205,61 @@ func (s *subscriptionsService) SyncSubscriptions(ctx context.Context, userId, ac
 		return 0, v1.ErrInternalServerError
 	}
 
-	// 6. 更新账户状态为正常
-	if err := s.accountsRepository.UpdateAccount(ctx, userId, accountId, map[string]interface{}{
-		"subscription_status": "normal",
-	}); err != nil {
-		s.logger.Error("更新账户状态失败",
+	// 6. 记录订阅状态变化并更新账户状态
+	now := time.Now()
+	changes := subscriptionStateChanges(accountId, previous, subscriptions, now)
+	if err := s.stateChangeRepository.CreateChanges(ctx, changes); err != nil {
+		s.logger.Error("保存订阅状态变化失败",
 			zap.Error(err),
 			zap.String("accountId", accountId),
 		)
 	}
+	for _, change := range changes {
+		s.logger.Info("订阅状态变化",
+			zap.String("accountId", accountId),
+			zap.String("subscriptionId", change.SubscriptionID),
+			zap.String("from", change.FromState),
+			zap.String("to", change.ToState))
+	}
+	s.updateAccountStatus(ctx, account, accountSubscriptionStatus(subscriptions), now)
+
 	// 返回 同步成功多少个订阅
 	return int(int64(len(subscriptions))), nil
 }
 
+// updateAccountStatus 账户的订阅状态发生变化时更新并记录
+func (s *subscriptionsService) updateAccountStatus(ctx context.Context, account *model.Accounts, status string, changedAt time.Time) {
+	if account.SubscriptionStatus == status {
+		return
+	}
+	if err := s.accountsRepository.UpdateAccount(ctx, account.UserID, account.AccountID, map[string]interface{}{
+		"subscription_status": status,
+	}); err != nil {
+		s.logger.Error("更新账户状态失败",
+			zap.Error(err),
+			zap.String("accountId", account.AccountID),
+		)
+		return
+	}
+	change := &model.SubscriptionStateChange{
+		AccountID: account.AccountID,
+		FromState: account.SubscriptionStatus,
+		ToState:   status,
+		ChangedAt: changedAt,
+	}
+	if err := s.stateChangeRepository.CreateChanges(ctx, []*model.SubscriptionStateChange{change}); err != nil {
+		s.logger.Error("保存账户状态变化失败",
+			zap.Error(err),
+			zap.String("accountId", account.AccountID),
+		)
+	}
+	s.logger.Info("账户订阅状态变化",
+		zap.String("accountId", account.AccountID),
+		zap.String("from", account.SubscriptionStatus),
+		zap.String("to", status))
+	account.SubscriptionStatus = status
+}
+
 // DeleteSubscriptions 删除指定账号的所有订阅信息
 func (s *subscriptionsService) DeleteSubscriptions(ctx context.Context, userId, accountId string) error {
 	// 1. 验证账户是否存在且属于该用户
@@ -240,3 +306,115 @@ func (s *subscriptionsService) ListAllSubscriptions(ctx context.Context, userId
 
 	return result, nil
 }
+
+func (s *subscriptionsService) CheckSubscriptionStates(ctx context.Context) error {
+	accounts, err := s.accountsRepository.ListAllAccounts(ctx)
+	if err != nil {
+		return err
+	}
+	for _, account := range accounts {
+		// 单个账户失败时已记录为 error 状态，不影响其他账户
+		if _, err := s.refreshAccount(ctx, account); err != nil {
+			s.logger.Warn("检查订阅状态失败",
+				zap.Error(err),
+				zap.String("accountId", account.AccountID),
+			)
+		}
+	}
+	return nil
+}
+
+func (s *subscriptionsService) ListStateChanges(ctx context.Context, userId, accountId, subscriptionId string, query *app.QueryOption) (*app.ListResult[*model.SubscriptionStateChange], error) {
+	account, err := s.accountsRepository.GetAccountByUserIdAndAccountId(ctx, userId, accountId)
+	if err != nil {
+		s.logger.Error("获取账户信息失败",
+			zap.Error(err),
+			zap.String("userId", userId),
+			zap.String("accountId", accountId),
+		)
+		return nil, v1.ErrInternalServerError
+	}
+	if account == nil {
+		return nil, v1.ErrAccountError
+	}
+
+	query = app.ValidateAndFillQueryOption(query)
+	result, err := s.stateChangeRepository.ListChanges(ctx, userId, accountId, subscriptionId, query)
+	if err != nil {
+		s.logger.Error("获取订阅状态变化失败",
+			zap.Error(err),
+			zap.String("accountId", accountId),
+		)
+		return nil, v1.ErrInternalServerError
+	}
+	return result, nil
+}
+
+// subscriptionStateSeverity Azure订阅状态的严重程度，未知状态按 Enabled 处理
+var subscriptionStateSeverity = map[string]int{
+	"Enabled":  0,
+	"Warned":   1,
+	"PastDue":  2,
+	"Disabled": 3,
+	"Deleted":  3,
+}
+
+// accountSubscriptionStatus 取账户下最严重的订阅状态作为账户的订阅状态
+func accountSubscriptionStatus(subscriptions []*model.Subscriptions) string {
+	if len(subscriptions) == 0 {
+		return model.AccountSubscriptionNone
+	}
+	worst := 0
+	for _, sub := range subscriptions {
+		if severity := subscriptionStateSeverity[sub.State]; severity > worst {
+			worst = severity
+		}
+	}
+	switch worst {
+	case 1:
+		return model.AccountSubscriptionWarned
+	case 2:
+		return model.AccountSubscriptionPastDue
+	case 3:
+		return model.AccountSubscriptionDisabled
+	default:
+		return model.AccountSubscriptionNormal
+	}
+}
+
+// subscriptionStateChanges 对比同步前后的订阅状态，新出现的订阅 FromState 为空，消失的订阅 ToState 为 Missing
+func subscriptionStateChanges(accountID string, previous, current []*model.Subscriptions, changedAt time.Time) []*model.SubscriptionStateChange {
+	before := make(map[string]string, len(previous))
+	for _, sub := range previous {
+		before[sub.SubscriptionID] = sub.State
+	}
+
+	var changes []*model.SubscriptionStateChange
+	for _, sub := range current {
+		state, ok := before[sub.SubscriptionID]
+		delete(before, sub.SubscriptionID)
+		if ok && state == sub.State {
+			continue
+		}
+		changes = append(changes, &model.SubscriptionStateChange{
+			AccountID:      accountID,
+			SubscriptionID: sub.SubscriptionID,
+			FromState:      state,
+			ToState:        sub.State,
+			ChangedAt:      changedAt,
+		})
+	}
+	for _, sub := range previous {
+		if _, ok := before[sub.SubscriptionID]; !ok {
+			continue
+		}
+		changes = append(changes, &model.SubscriptionStateChange{
+			AccountID:      accountID,
+			SubscriptionID: sub.SubscriptionID,
+			FromState:      sub.State,
+			ToState:        model.SubscriptionStateMissing,
+			ChangedAt:      changedAt,
+		})
+	}
+	return changes
+}
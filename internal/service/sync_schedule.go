@@ -0,0 +1,302 @@
+package service
+
+import (
+	v1 "azure-vm-backend/api/v1"
+	"azure-vm-backend/internal/model"
+	"azure-vm-backend/internal/repository"
+	"azure-vm-backend/pkg/app"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/robfig/cron/v3"
+	"go.uber.org/zap"
+	"strings"
+	"time"
+)
+
+const (
+	// minSyncScheduleInterval 定时同步的最小执行间隔，避免频繁调用Azure接口
+	minSyncScheduleInterval = 5 * time.Minute
+	// syncScheduleRunTimeout 单次定时同步的最长执行时间
+	syncScheduleRunTimeout = 30 * time.Minute
+)
+
+type SyncScheduleService interface {
+	CreateSchedule(ctx context.Context, userID string, req *v1.CreateSyncScheduleReq) (*model.SyncSchedule, error)
+	UpdateSchedule(ctx context.Context, userID, scheduleID string, req *v1.UpdateSyncScheduleReq) (*model.SyncSchedule, error)
+	DeleteSchedule(ctx context.Context, userID, scheduleID string) error
+	GetSchedule(ctx context.Context, userID, scheduleID string) (*model.SyncSchedule, error)
+	ListSchedules(ctx context.Context, userID string) ([]*model.SyncSchedule, error)
+	ListRuns(ctx context.Context, userID, scheduleID string, query *app.QueryOption) (*app.ListResult[*model.SyncScheduleRun], error)
+
+	// ListEnabledSchedules 获取所有启用的定时同步，供 task 服务注册定时任务
+	ListEnabledSchedules(ctx context.Context) ([]*model.SyncSchedule, error)
+	// RunSchedule 执行一次定时同步并记录执行结果
+	RunSchedule(ctx context.Context, schedule *model.SyncSchedule) (*model.SyncScheduleRun, error)
+	// FailInterruptedRuns task 服务启动时将上次中断的执行记录标记为失败
+	FailInterruptedRuns(ctx context.Context) error
+}
+
+func NewSyncScheduleService(
+	service *Service,
+	syncScheduleRepository repository.SyncScheduleRepository,
+	accountsRepository repository.AccountsRepository,
+	accountsService AccountsService,
+) SyncScheduleService {
+	return &syncScheduleService{
+		Service:                service,
+		syncScheduleRepository: syncScheduleRepository,
+		accountsRepository:     accountsRepository,
+		accountsService:        accountsService,
+	}
+}
+
+type syncScheduleService struct {
+	*Service
+	syncScheduleRepository repository.SyncScheduleRepository
+	accountsRepository     repository.AccountsRepository
+	accountsService        AccountsService
+}
+
+// ParseSyncCron 解析定时同步的cron表达式，并检查执行间隔不小于最小间隔
+func ParseSyncCron(expr string) (cron.Schedule, error) {
+	schedule, err := cron.ParseStandard(strings.TrimSpace(expr))
+	if err != nil {
+		return nil, err
+	}
+	// 检查接下来若干次执行的间隔，覆盖 "0,1 * * * *" 这类不均匀的表达式
+	next := schedule.Next(time.Now().UTC())
+	for i := 0; i < 10; i++ {
+		following := schedule.Next(next)
+		if following.Sub(next) < minSyncScheduleInterval {
+			return nil, fmt.Errorf("执行间隔不能小于 %s", minSyncScheduleInterval)
+		}
+		next = following
+	}
+	return schedule, nil
+}
+
+// CreateSchedule 创建定时同步
+func (s *syncScheduleService) CreateSchedule(ctx context.Context, userID string, req *v1.CreateSyncScheduleReq) (*model.SyncSchedule, error) {
+	cronExpr, err := s.validateCron(req.CronExpr)
+	if err != nil {
+		return nil, err
+	}
+	accountIDs, err := s.encodeAccountIDs(ctx, userID, req.AccountIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	schedule := &model.SyncSchedule{
+		ScheduleID: uuid.New().String(),
+		UserID:     userID,
+		Name:       req.Name,
+		CronExpr:   cronExpr,
+		AccountIDs: accountIDs,
+		Enabled:    req.Enabled == nil || *req.Enabled,
+	}
+	if err := s.syncScheduleRepository.Create(ctx, schedule); err != nil {
+		s.logger.Error("创建定时同步失败", zap.Error(err), zap.String("userId", userID))
+		return nil, v1.ErrInternalServerError
+	}
+	return schedule, nil
+}
+
+// UpdateSchedule 更新定时同步，task 服务会在下次加载时重新注册
+func (s *syncScheduleService) UpdateSchedule(ctx context.Context, userID, scheduleID string, req *v1.UpdateSyncScheduleReq) (*model.SyncSchedule, error) {
+	if _, err := s.GetSchedule(ctx, userID, scheduleID); err != nil {
+		return nil, err
+	}
+
+	fields := map[string]interface{}{}
+	if req.Name != nil {
+		fields["name"] = *req.Name
+	}
+	if req.CronExpr != nil {
+		cronExpr, err := s.validateCron(*req.CronExpr)
+		if err != nil {
+			return nil, err
+		}
+		fields["cron_expr"] = cronExpr
+	}
+	if req.AccountIDs != nil {
+		accountIDs, err := s.encodeAccountIDs(ctx, userID, req.AccountIDs)
+		if err != nil {
+			return nil, err
+		}
+		fields["account_ids"] = accountIDs
+	}
+	if req.Enabled != nil {
+		fields["enabled"] = *req.Enabled
+	}
+	if len(fields) > 0 {
+		if err := s.syncScheduleRepository.Update(ctx, userID, scheduleID, fields); err != nil {
+			s.logger.Error("更新定时同步失败", zap.Error(err), zap.String("scheduleId", scheduleID))
+			return nil, v1.ErrInternalServerError
+		}
+	}
+	return s.GetSchedule(ctx, userID, scheduleID)
+}
+
+// DeleteSchedule 删除定时同步
+func (s *syncScheduleService) DeleteSchedule(ctx context.Context, userID, scheduleID string) error {
+	if _, err := s.GetSchedule(ctx, userID, scheduleID); err != nil {
+		return err
+	}
+	if err := s.syncScheduleRepository.Delete(ctx, userID, scheduleID); err != nil {
+		s.logger.Error("删除定时同步失败", zap.Error(err), zap.String("scheduleId", scheduleID))
+		return v1.ErrInternalServerError
+	}
+	return nil
+}
+
+// GetSchedule 获取定时同步
+func (s *syncScheduleService) GetSchedule(ctx context.Context, userID, scheduleID string) (*model.SyncSchedule, error) {
+	schedule, err := s.syncScheduleRepository.GetByScheduleID(ctx, userID, scheduleID)
+	if err != nil {
+		s.logger.Error("获取定时同步失败", zap.Error(err), zap.String("scheduleId", scheduleID))
+		return nil, v1.ErrInternalServerError
+	}
+	if schedule == nil {
+		return nil, v1.ErrNotFound
+	}
+	return schedule, nil
+}
+
+// ListSchedules 获取用户的所有定时同步
+func (s *syncScheduleService) ListSchedules(ctx context.Context, userID string) ([]*model.SyncSchedule, error) {
+	schedules, err := s.syncScheduleRepository.ListByUserID(ctx, userID)
+	if err != nil {
+		s.logger.Error("获取定时同步列表失败", zap.Error(err), zap.String("userId", userID))
+		return nil, v1.ErrInternalServerError
+	}
+	return schedules, nil
+}
+
+// ListRuns 分页获取定时同步的执行记录
+func (s *syncScheduleService) ListRuns(ctx context.Context, userID, scheduleID string, query *app.QueryOption) (*app.ListResult[*model.SyncScheduleRun], error) {
+	result, err := s.syncScheduleRepository.ListRuns(ctx, userID, scheduleID, query)
+	if err != nil {
+		s.logger.Error("获取执行记录失败", zap.Error(err), zap.String("scheduleId", scheduleID))
+		return nil, v1.ErrInternalServerError
+	}
+	return result, nil
+}
+
+// ListEnabledSchedules 获取所有启用的定时同步
+func (s *syncScheduleService) ListEnabledSchedules(ctx context.Context) ([]*model.SyncSchedule, error) {
+	return s.syncScheduleRepository.ListEnabled(ctx)
+}
+
+// RunSchedule 执行一次定时同步
+func (s *syncScheduleService) RunSchedule(ctx context.Context, schedule *model.SyncSchedule) (*model.SyncScheduleRun, error) {
+	run := &model.SyncScheduleRun{
+		ScheduleID: schedule.ScheduleID,
+		UserID:     schedule.UserID,
+		Status:     model.SyncRunStatusRunning,
+		StartedAt:  time.Now(),
+	}
+	if err := s.syncScheduleRepository.CreateRun(ctx, run); err != nil {
+		return nil, err
+	}
+
+	if resp, err := s.syncAccounts(ctx, schedule); err != nil {
+		run.Status = model.SyncRunStatusFailed
+		run.Error = err.Error()
+	} else {
+		run.SuccessCount = len(resp.SuccessAccounts)
+		run.FailedCount = len(resp.FailedAccounts)
+		switch {
+		case run.FailedCount == 0:
+			run.Status = model.SyncRunStatusSucceeded
+		case run.SuccessCount == 0:
+			run.Status = model.SyncRunStatusFailed
+		default:
+			run.Status = model.SyncRunStatusPartial
+		}
+		if data, err := json.Marshal(resp); err == nil {
+			run.Result = string(data)
+		}
+	}
+
+	finishedAt := time.Now()
+	run.FinishedAt = &finishedAt
+	// 同步可能因超时或服务停止被取消，执行结果仍需写入
+	if err := s.syncScheduleRepository.FinishRun(context.Background(), run); err != nil {
+		return run, err
+	}
+
+	s.logger.Info("定时同步执行完成",
+		zap.String("scheduleId", schedule.ScheduleID),
+		zap.String("userId", schedule.UserID),
+		zap.String("status", run.Status),
+		zap.Int("successCount", run.SuccessCount),
+		zap.Int("failedCount", run.FailedCount))
+	return run, nil
+}
+
+// syncAccounts 调用账户同步，单次执行限时
+func (s *syncScheduleService) syncAccounts(ctx context.Context, schedule *model.SyncSchedule) (*v1.SyncAccountResp, error) {
+	var accountIDs []string
+	if err := json.Unmarshal([]byte(schedule.AccountIDs), &accountIDs); err != nil {
+		return nil, fmt.Errorf("解析账户列表失败: %w", err)
+	}
+	ctx, cancel := context.WithTimeout(ctx, syncScheduleRunTimeout)
+	defer cancel()
+	return s.accountsService.SyncAccounts(ctx, schedule.UserID, accountIDs)
+}
+
+// FailInterruptedRuns 将上次中断的执行记录标记为失败
+func (s *syncScheduleService) FailInterruptedRuns(ctx context.Context) error {
+	count, err := s.syncScheduleRepository.FailInterruptedRuns(ctx, "task 服务重启，执行中断")
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		s.logger.Warn("已将中断的定时同步执行记录标记为失败", zap.Int64("count", count))
+	}
+	return nil
+}
+
+// validateCron 校验并规范化cron表达式
+func (s *syncScheduleService) validateCron(expr string) (string, error) {
+	expr = strings.TrimSpace(expr)
+	if _, err := ParseSyncCron(expr); err != nil {
+		s.logger.Warn("无效的cron表达式", zap.String("cronExpr", expr), zap.Error(err))
+		return "", v1.ErrInvalidCronExpr
+	}
+	return expr, nil
+}
+
+// encodeAccountIDs 去重并校验账户均属于该用户，返回JSON数组
+func (s *syncScheduleService) encodeAccountIDs(ctx context.Context, userID string, accountIDs []string) (string, error) {
+	seen := make(map[string]bool, len(accountIDs))
+	unique := make([]string, 0, len(accountIDs))
+	for _, id := range accountIDs {
+		id = strings.TrimSpace(id)
+		if id == "" || seen[id] {
+			continue
+		}
+		seen[id] = true
+		unique = append(unique, id)
+	}
+	if len(unique) == 0 {
+		return "", v1.ErrInvalidParams
+	}
+
+	notExist, err := s.accountsRepository.GetNotExistAccountIDs(ctx, userID, unique)
+	if err != nil {
+		s.logger.Error("检查账户存在性失败", zap.Error(err), zap.String("userId", userID))
+		return "", v1.ErrInternalServerError
+	}
+	if len(notExist) > 0 {
+		return "", v1.ErrorAzureNotFound
+	}
+
+	data, err := json.Marshal(unique)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
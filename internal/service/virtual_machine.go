@@ -9,8 +9,12 @@ import (
 	"azure-vm-backend/pkg/log"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"go.uber.org/zap"
+	"net"
+	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -26,11 +30,35 @@ type VirtualMachineService interface {
 	SyncVMs(ctx context.Context, userID, accountID string) (*v1.SyncStats, error)
 	SyncVMsBySubscription(ctx context.Context, userID, accountID, subscriptionID string) error
 
-	// CreateVM 虚拟机操作 - 这些接口暂时不实现
+	// CreateVM 虚拟机操作
 	CreateVM(ctx context.Context, userID, accountID string, params *v1.VMCreateParams) (*model.VirtualMachine, error)
-	OperateVM(ctx context.Context, userId, accountId, id string, opType v1.VMOperationType, force bool) error
+	OperateVM(ctx context.Context, userId, accountId, id string, req *v1.VMOperationRequest) (*model.Operation, error)
 	// UpdateDNSLabel 更新DNS标签
 	UpdateDNSLabel(ctx context.Context, userId string, accountId string, ID string, dnsLabel string) error
+	// ListResizeOptions 列出虚拟机可调整到的规格，并标明是否需要释放虚拟机
+	ListResizeOptions(ctx context.Context, userId, accountId, id string) ([]*v1.VMResizeOption, error)
+	// AttachDataDisk 创建并挂载数据磁盘，返回操作记录
+	AttachDataDisk(ctx context.Context, userId, accountId, id string, req *v1.AttachDataDiskRequest) (*model.Operation, error)
+	// DetachDataDisk 卸载数据磁盘，deleteDisk 为 true 时同时删除磁盘
+	DetachDataDisk(ctx context.Context, userId, accountId, id, diskName string, deleteDisk bool) (*model.Operation, error)
+	// ExpandDisk 扩容磁盘，diskName 为空时扩容系统盘
+	ExpandDisk(ctx context.Context, userId, accountId, id, diskName string, sizeGB int32) (*model.Operation, error)
+	// ListSecurityGroups 获取作用于虚拟机网卡和子网的网络安全组，refresh 为 true 时先从Azure同步
+	ListSecurityGroups(ctx context.Context, userId, accountId, id string, refresh bool) ([]azure.SecurityGroupInfo, error)
+	// AddSecurityRule 添加或更新入站规则，返回更新后的网络安全组
+	AddSecurityRule(ctx context.Context, userId, accountId, id string, req *v1.AddSecurityRuleRequest) ([]azure.SecurityGroupInfo, error)
+	// DeleteSecurityRule 删除入站规则，securityGroup 为空时在所有关联的网络安全组中查找
+	DeleteSecurityRule(ctx context.Context, userId, accountId, id, securityGroup, ruleName string) ([]azure.SecurityGroupInfo, error)
+	// CreateSecurityGroup 为没有网络安全组的网卡创建并关联网络安全组
+	CreateSecurityGroup(ctx context.Context, userId, accountId, id string, req *v1.CreateSecurityGroupRequest) ([]azure.SecurityGroupInfo, error)
+	// ResetVMAccess 重置管理员密码、SSH公钥或sshd配置，返回操作记录
+	ResetVMAccess(ctx context.Context, userId, accountId, id string, req *v1.ResetVMAccessRequest) (*model.Operation, error)
+	// GetVMAccessStatus 获取 VMAccess 扩展最近一次的执行结果
+	GetVMAccessStatus(ctx context.Context, userId, accountId, id string) (*azure.VMAccessResult, error)
+	// EnableBootDiagnostics 启用使用托管存储账户的启动诊断
+	EnableBootDiagnostics(ctx context.Context, userId, accountId, vmId string) error
+	// GetBootDiagnostics 获取串行日志或截图，调用方负责关闭返回的 Body
+	GetBootDiagnostics(ctx context.Context, userId, accountId, vmId string, kind azure.BootDiagnosticsKind) (*azure.BootDiagnosticsData, error)
 }
 
 func convertTags(tags map[string]string) string {
@@ -50,6 +78,11 @@ func NewVirtualMachineService(
 	virtualMachineRepository repository.VirtualMachineRepository,
 	accountsRepository repository.AccountsRepository, // 添加账号仓储
 	subscriptionsRepository repository.SubscriptionsRepository, // 添加订阅仓储
+	vmSizeRepository repository.VmSizeRepository,
+	operationService OperationService,
+	subscriptionQuotaService SubscriptionQuotaService,
+	vmCostService VmCostService,
+	azureProvider azure.Provider,
 	logger *log.Logger, // 添加日志器
 ) VirtualMachineService {
 	return &virtualMachineService{
@@ -57,6 +90,11 @@ func NewVirtualMachineService(
 		virtualMachineRepository: virtualMachineRepository,
 		accountsRepository:       accountsRepository,
 		subscriptionsRepository:  subscriptionsRepository,
+		vmSizeRepository:         vmSizeRepository,
+		operationService:         operationService,
+		subscriptionQuotaService: subscriptionQuotaService,
+		vmCostService:            vmCostService,
+		azureProvider:            azureProvider,
 		logger:                   logger,
 	}
 }
@@ -66,6 +104,11 @@ type virtualMachineService struct {
 	virtualMachineRepository repository.VirtualMachineRepository
 	accountsRepository       repository.AccountsRepository
 	subscriptionsRepository  repository.SubscriptionsRepository
+	vmSizeRepository         repository.VmSizeRepository
+	operationService         OperationService
+	subscriptionQuotaService SubscriptionQuotaService
+	vmCostService            VmCostService
+	azureProvider            azure.Provider
 	logger                   *log.Logger
 }
 
@@ -88,10 +131,9 @@ func newSyncVMsHelper(service *virtualMachineService, ctx context.Context, userI
 	}
 
 	// 创建Azure凭据
-	credentials := &azure.Credentials{
-		TenantID:     account.Tenant,
-		ClientID:     account.AppID,
-		ClientSecret: account.PassWord,
+	credentials, err := service.accountCredentials(account)
+	if err != nil {
+		return nil, err
 	}
 
 	// 创建日志记录器
@@ -110,6 +152,61 @@ func newSyncVMsHelper(service *virtualMachineService, ctx context.Context, userI
 	}, nil
 }
 
+// diskInfo 虚拟机记录中 data_disks 字段保存的磁盘信息
+type diskInfo struct {
+	OSDiskSize int32            `json:"osDiskSize"`
+	DataDisks  []azure.DiskInfo `json:"dataDisks"`
+}
+
+// encodeDiskInfo 将磁盘信息编码为 data_disks 字段的JSON
+func encodeDiskInfo(osDiskSize int32, dataDisks []azure.DiskInfo) (string, error) {
+	data, err := json.Marshal(diskInfo{OSDiskSize: osDiskSize, DataDisks: dataDisks})
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// decodeDiskInfo 解析 data_disks 字段，兼容早期被再次编码为JSON字符串的记录
+func decodeDiskInfo(raw string) (*diskInfo, error) {
+	info := &diskInfo{}
+	if raw == "" {
+		return info, nil
+	}
+	var nested string
+	if err := json.Unmarshal([]byte(raw), &nested); err == nil {
+		raw = nested
+	}
+	if err := json.Unmarshal([]byte(raw), info); err != nil {
+		return nil, fmt.Errorf("解析磁盘信息失败: %w", err)
+	}
+	return info, nil
+}
+
+// encodeSecurityGroups 将网络安全组编码为 security_groups 字段的JSON，没有网络安全组时为空数组
+func encodeSecurityGroups(groups []azure.SecurityGroupInfo) (string, error) {
+	if groups == nil {
+		groups = []azure.SecurityGroupInfo{}
+	}
+	data, err := json.Marshal(groups)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// decodeSecurityGroups 解析 security_groups 字段
+func decodeSecurityGroups(raw string) ([]azure.SecurityGroupInfo, error) {
+	groups := []azure.SecurityGroupInfo{}
+	if raw == "" {
+		return groups, nil
+	}
+	if err := json.Unmarshal([]byte(raw), &groups); err != nil {
+		return nil, fmt.Errorf("解析网络安全组失败: %w", err)
+	}
+	return groups, nil
+}
+
 // convertVMToModel 将Azure VM转换为数据库模型
 func (h *syncVMsHelper) convertVMToModel(vm azure.VMDetails) (*model.VirtualMachine, error) {
 	// 转换网络信息为JSON字符串
@@ -123,14 +220,18 @@ func (h *syncVMsHelper) convertVMToModel(vm azure.VMDetails) (*model.VirtualMach
 	}
 
 	// 转换磁盘信息为JSON字符串
-	diskInfo := map[string]interface{}{
-		"osDiskSize": vm.OSDiskSize,
-		"dataDisks":  vm.DataDisks,
-	}
-	diskJSON, err := json.Marshal(diskInfo)
+	diskJSON, err := encodeDiskInfo(vm.OSDiskSize, vm.DataDisks)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal disk info: %w", err)
 	}
+	// 未能获取网络安全组时留空，BatchUpsert 保留已保存的数据
+	var securityGroupsJSON string
+	if !vm.SecurityGroupsUnavailable {
+		securityGroupsJSON, err = encodeSecurityGroups(vm.SecurityGroups)
+		if err != nil {
+			return nil, fmt.Errorf("序列化网络安全组失败: %w", err)
+		}
+	}
 
 	// 创建数据库VM记录
 	return &model.VirtualMachine{
@@ -159,9 +260,12 @@ func (h *syncVMsHelper) convertVMToModel(vm azure.VMDetails) (*model.VirtualMach
 		PublicIPName: vm.PublicIPName,
 
 		// 磁盘配置
-		DataDisks:  string(diskJSON),
+		DataDisks:  diskJSON,
 		OSDiskSize: int(vm.OSDiskSize),
 
+		// 网络安全组
+		SecurityGroups: securityGroupsJSON,
+
 		// 元数据
 		Tags: convertTags(vm.Tags),
 
@@ -220,8 +324,20 @@ func (s *virtualMachineService) ListVMs(ctx context.Context, params *v1.VMQueryP
 		queryOpt.SortOrder = "desc"
 	}
 
-	// 构建VM查询选项
+	// 构建VM查询选项，只查询当前用户账户下的虚拟机
+	if params.UserID == "" {
+		return nil, v1.ErrUnauthorized
+	}
+	vmOpts := vmQueryOptions(params, queryOpt)
+
+	// 执行查询
+	return s.virtualMachineRepository.ListVMs(ctx, vmOpts)
+}
+
+// vmQueryOptions 将虚拟机查询参数转换为仓储查询选项
+func vmQueryOptions(params *v1.VMQueryParams, queryOpt *app.QueryOption) repository.QueryVMsOptions {
 	vmOpts := repository.QueryVMsOptions{
+		UserID:         params.UserID,
 		AccountID:      params.AccountID,
 		SubscriptionID: params.SubscriptionID,
 		Query:          queryOpt,
@@ -263,8 +379,7 @@ func (s *virtualMachineService) ListVMs(ctx context.Context, params *v1.VMQueryP
 		vmOpts.ExtraFilters["end_time"] = params.EndTime.Format(time.RFC3339)
 	}
 
-	// 执行查询
-	return s.virtualMachineRepository.ListVMs(ctx, vmOpts)
+	return vmOpts
 }
 
 // ListVMsBySubscription 获取指定订阅下的所有虚拟机
@@ -275,7 +390,7 @@ func (s *virtualMachineService) ListVMsBySubscription(ctx context.Context, userI
 	}
 
 	// 检查订阅是否属于该账号
-	if err := s.checkSubscriptionAccess(ctx, accountID, subscriptionID); err != nil {
+	if err := s.checkSubscriptionAccess(ctx, userID, accountID, subscriptionID); err != nil {
 		return nil, fmt.Errorf("subscription access denied: %w", err)
 	}
 
@@ -290,7 +405,7 @@ func (s *virtualMachineService) ListVMsBySubscription(ctx context.Context, userI
 	}
 
 	// 查询虚拟机列表
-	result, err := s.virtualMachineRepository.ListBySubscriptionID(ctx, subscriptionID, queryOpt)
+	result, err := s.virtualMachineRepository.ListByAccountAndSubscription(ctx, userID, accountID, subscriptionID, queryOpt)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list VMs by subscription: %w", err)
 	}
@@ -313,17 +428,40 @@ func (s *virtualMachineService) SyncVMs(ctx context.Context, userID, accountID s
 	}
 
 	// 创建VM获取器
-	vmFetcher := azure.NewVMFetcher(helper.credentials, helper.logger, 5*time.Minute)
+	vmFetcher := s.azureProvider.VMClient(helper.credentials, helper.logger, 5*time.Minute)
 
-	// 获取最新的VM信息
-	vms, err := vmFetcher.FetchVMDetails(ctx)
+	// 获取最新的VM信息，单个订阅失败不影响其他订阅
+	fetchResult, err := vmFetcher.FetchVMDetails(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("从 Azure 获取虚拟机失败: %w", err)
 	}
 
+	// 记录每个订阅的同步结果
+	stats.Subscriptions = make([]v1.SubscriptionSyncResult, 0, len(fetchResult.Subscriptions))
+	for _, sub := range fetchResult.Subscriptions {
+		subResult := v1.SubscriptionSyncResult{
+			SubscriptionID: sub.SubscriptionID,
+			DisplayName:    sub.DisplayName,
+			Success:        sub.Err == nil,
+			VMCount:        sub.VMCount,
+		}
+		if sub.Err != nil {
+			subResult.Message = v1.FromAzureError(sub.Err).Error()
+			stats.FailedSubscriptions++
+			helper.logger.Warn("订阅虚拟机同步失败",
+				zap.String("subscriptionId", sub.SubscriptionID),
+				zap.String("state", sub.State),
+				zap.Error(sub.Err))
+		}
+		stats.Subscriptions = append(stats.Subscriptions, subResult)
+	}
+	if len(fetchResult.Subscriptions) > 0 && stats.FailedSubscriptions == len(fetchResult.Subscriptions) {
+		return stats, fmt.Errorf("所有订阅的虚拟机同步均失败: %w", fetchResult.Subscriptions[0].Err)
+	}
+
 	// 转换所有VM为数据库模型
 	var dbVMs []*model.VirtualMachine
-	for _, vm := range vms {
+	for _, vm := range fetchResult.VMs {
 		dbVM, err := helper.convertVMToModel(vm)
 		if err != nil {
 			helper.logger.Error("转换虚拟机失败",
@@ -339,30 +477,51 @@ func (s *virtualMachineService) SyncVMs(ctx context.Context, userID, accountID s
 		}
 		dbVMs = append(dbVMs, dbVM)
 	}
-	stats.TotalVMs = len(vms)
+	stats.TotalVMs = len(fetchResult.VMs)
+	// 估算月费用
+	s.vmCostService.FillMonthlyCosts(ctx, userID, accountID, dbVMs)
 	// 批量更新数据库
 	if err := s.virtualMachineRepository.BatchUpsert(ctx, dbVMs); err != nil {
 		return nil, fmt.Errorf("更新数据库中的虚拟机失败: %w", err)
 	}
 
-	// 更新账户表中的虚拟机数量
-	if err := s.accountsRepository.UpdateVMCount(ctx, accountID, int64(len(vms))); err != nil {
+	// 对账：成功获取的订阅中 Azure 已不存在的虚拟机，失败的订阅保留原有记录
+	var syncedSubscriptionIDs []string
+	for _, sub := range fetchResult.Subscriptions {
+		if sub.Err == nil {
+			syncedSubscriptionIDs = append(syncedSubscriptionIDs, sub.SubscriptionID)
+		}
+	}
+	missing, err := s.virtualMachineRepository.ReconcileMissing(ctx, accountID, syncedSubscriptionIDs, fetchedVMIDs(fetchResult.VMs))
+	if err != nil {
+		return nil, fmt.Errorf("对账已删除的虚拟机失败: %w", err)
+	}
+	stats.MissingVMs = int(missing)
+
+	// 按数据库中的实际记录更新账户虚拟机数量，失败订阅下已有的虚拟机仍计入
+	vmCount, err := s.virtualMachineRepository.CountByAccountID(ctx, accountID)
+	if err != nil {
+		return nil, fmt.Errorf("统计账户虚拟机数量失败: %w", err)
+	}
+	if err := s.accountsRepository.UpdateVMCount(ctx, accountID, vmCount); err != nil {
 		s.logger.Error("更新账户中的虚拟机数量失败",
 			zap.String("accountID", accountID),
-			zap.String("vmCount", strconv.FormatInt(int64(len(vms)), 10)),
+			zap.String("vmCount", strconv.FormatInt(vmCount, 10)),
 			zap.Error(err))
 		return nil, err
 	}
 
 	s.logger.Info("成功更新账户虚拟机数量",
 		zap.String("accountID", accountID),
-		zap.Int64("vmCount", int64(len(vms))))
+		zap.Int64("vmCount", vmCount))
 	// 返回同步成功多少台虚拟机，运行中多少台，已停止多少台
 	s.logger.Info("成功同步虚拟机信息",
 		zap.String("accountID", accountID),
 		zap.Int("totalVMs", stats.TotalVMs),
 		zap.Int("runningVMs", stats.RunningVMs),
-		zap.Int("stoppedVMs", stats.StoppedVMs))
+		zap.Int("stoppedVMs", stats.StoppedVMs),
+		zap.Int("missingVMs", stats.MissingVMs),
+		zap.Int("failedSubscriptions", stats.FailedSubscriptions))
 	return stats, nil
 }
 
@@ -373,7 +532,7 @@ func (s *virtualMachineService) SyncVMsBySubscription(ctx context.Context, userI
 	}
 
 	// 检查订阅是否属于该账号
-	if err := s.checkSubscriptionAccess(ctx, accountID, subscriptionID); err != nil {
+	if err := s.checkSubscriptionAccess(ctx, userID, accountID, subscriptionID); err != nil {
 		return fmt.Errorf("拒绝订阅访问: %w", err)
 	}
 
@@ -384,19 +543,24 @@ func (s *virtualMachineService) SyncVMsBySubscription(ctx context.Context, userI
 	}
 
 	// 创建VM获取器
-	vmFetcher := azure.NewVMFetcher(helper.credentials, helper.logger.With(
+	vmFetcher := s.azureProvider.VMClient(helper.credentials, helper.logger.With(
 		zap.String("subscriptionId", subscriptionID),
 	), 5*time.Minute)
 
 	// 获取最新的VM信息
-	vms, err := vmFetcher.FetchVMDetails(ctx)
+	fetchResult, err := vmFetcher.FetchVMDetails(ctx)
 	if err != nil {
 		return fmt.Errorf("从 Azure 获取虚拟机失败: %w", err)
 	}
+	for _, sub := range fetchResult.Subscriptions {
+		if sub.SubscriptionID == subscriptionID && sub.Err != nil {
+			return fmt.Errorf("从 Azure 获取订阅虚拟机失败: %w", sub.Err)
+		}
+	}
 
 	// 过滤并转换指定订阅的VM
 	var subscriptionVMs []*model.VirtualMachine
-	for _, vm := range vms {
+	for _, vm := range fetchResult.VMs {
 		if vm.SubscriptionID == subscriptionID {
 			dbVM, err := helper.convertVMToModel(vm)
 			if err != nil {
@@ -409,20 +573,43 @@ func (s *virtualMachineService) SyncVMsBySubscription(ctx context.Context, userI
 		}
 	}
 
+	// 估算月费用
+	s.vmCostService.FillMonthlyCosts(ctx, userID, accountID, subscriptionVMs)
 	// 批量更新数据库
 	if err := s.virtualMachineRepository.BatchUpsert(ctx, subscriptionVMs); err != nil {
 		return fmt.Errorf("更新数据库中的虚拟机失败: %w", err)
 	}
 
+	// 对账该订阅下已删除的虚拟机
+	missing, err := s.virtualMachineRepository.ReconcileMissing(ctx, accountID, []string{subscriptionID}, fetchedVMIDs(fetchResult.VMs))
+	if err != nil {
+		return fmt.Errorf("对账已删除的虚拟机失败: %w", err)
+	}
+	if missing > 0 {
+		helper.logger.Info("已移除 Azure 中不存在的虚拟机", zap.Int64("missingVMs", missing))
+	}
+
 	return nil
 }
 
+// fetchedVMIDs 提取 Azure 返回的虚拟机资源ID
+func fetchedVMIDs(vms []azure.VMDetails) []string {
+	ids := make([]string, 0, len(vms))
+	for _, vm := range vms {
+		ids = append(ids, vm.ID)
+	}
+	return ids
+}
+
 // checkAccountAccess 检查用户是否有权限访问指定账号
 func (s *virtualMachineService) checkAccountAccess(ctx context.Context, userID, accountID string) error {
 	account, err := s.accountsRepository.GetAccountByUserIdAndAccountId(ctx, userID, accountID)
 	if err != nil {
 		return err
 	}
+	if account == nil {
+		return v1.ErrorAzureNotFound
+	}
 	// 检查账号是否属于该用户
 	if account.UserID != userID {
 		return fmt.Errorf("用户无权访问此帐户")
@@ -431,12 +618,15 @@ func (s *virtualMachineService) checkAccountAccess(ctx context.Context, userID,
 	return nil
 }
 
-// checkSubscriptionAccess 检查订阅是否属于指定账号
-func (s *virtualMachineService) checkSubscriptionAccess(ctx context.Context, accountID, subscriptionID string) error {
-	subscription, err := s.subscriptionsRepository.GetSubscription(ctx, accountID, subscriptionID)
+// checkSubscriptionAccess 检查订阅是否属于指定用户的账号
+func (s *virtualMachineService) checkSubscriptionAccess(ctx context.Context, userID, accountID, subscriptionID string) error {
+	subscription, err := s.subscriptionsRepository.GetSubscription(ctx, userID, accountID, subscriptionID)
 	if err != nil {
 		return err
 	}
+	if subscription == nil {
+		return v1.ErrSubscriptionNotFound
+	}
 
 	// 检查订阅是否属于该账号
 	if subscription.AccountID != accountID {
@@ -446,119 +636,234 @@ func (s *virtualMachineService) checkSubscriptionAccess(ctx context.Context, acc
 	return nil
 }
 
-// CreateVM 创建新的虚拟机 - 暂不实现
+// CreateVM 创建新的虚拟机
+// 先写入 sync_status=pending 的记录并立即返回，资源创建在后台完成后更新记录
 func (s *virtualMachineService) CreateVM(ctx context.Context, userID, accountID string, params *v1.VMCreateParams) (*model.VirtualMachine, error) {
-	// TODO: 预留创建虚拟机接口
-	return nil, v1.ErrNotImplemented
-}
-
-func (s *virtualMachineService) OperateVM(ctx context.Context, userId, accountId, id string, opType v1.VMOperationType, force bool) error {
-	// 1. 验证并获取上下文
-	account, err := s.accountsRepository.GetAccountByUserIdAndAccountId(ctx, userId, accountId)
+	// 1. 验证账户与订阅
+	account, err := s.accountsRepository.GetAccountByUserIdAndAccountId(ctx, userID, accountID)
 	if err != nil {
 		s.logger.Error("获取账户信息失败",
 			zap.Error(err),
-			zap.String("userId", userId),
-			zap.String("accountId", accountId))
-		return v1.ErrInternalServerError
+			zap.String("userId", userID),
+			zap.String("accountId", accountID))
+		return nil, v1.ErrInternalServerError
 	}
 	if account == nil {
-		return v1.ErrAccountError
+		return nil, v1.ErrAccountError
 	}
 
-	vm, err := s.virtualMachineRepository.GetVM(ctx, id)
+	if err := s.checkSubscriptionAccess(ctx, userID, accountID, params.SubscriptionID); err != nil {
+		return nil, v1.ErrSubscriptionNotFound
+	}
+
+	// 2. 校验登录方式
+	if params.AdminPassword == "" && params.SSHPublicKey == "" {
+		return nil, v1.ErrInvalidParams
+	}
+	if params.AdminPassword == "" && strings.EqualFold(params.OSType, "Windows") {
+		return nil, v1.ErrInvalidParams
+	}
+
+	// 3. 检查同名虚拟机，上次创建失败留下的记录允许重试时覆盖
+	vmID := azure.BuildVMResourceID(params.SubscriptionID, params.ResourceGroup, params.Name)
+	existing, err := s.virtualMachineRepository.GetByID(ctx, vmID)
+	if err == nil && existing != nil {
+		if existing.SyncStatus != "failed" {
+			return nil, v1.ErrVMAlreadyExist
+		}
+		if err := s.virtualMachineRepository.Delete(ctx, vmID); err != nil {
+			s.logger.Error("删除创建失败的虚拟机记录失败",
+				zap.Error(err),
+				zap.String("vmId", vmID))
+			return nil, v1.ErrInternalServerError
+		}
+	}
+
+	// 4. 检查规格限制和剩余vCPU配额，规格未同步时无法得知核数，交由Azure校验
+	size, err := s.vmSizeRepository.GetVmSize(ctx, userID, accountID, params.SubscriptionID, params.Location, params.Size)
 	if err != nil {
-		s.logger.Error("获取虚拟机信息失败",
-			zap.Error(err),
-			zap.String("vmId", vm.VMID))
-		return v1.ErrInternalServerError
+		s.logger.Warn("查询规格失败，跳过配额检查", zap.Error(err), zap.String("size", params.Size))
 	}
-	if vm == nil {
-		return v1.ErrorAzureNotFound
+	if size != nil && size.Restricted {
+		return nil, restrictedSizeError(size)
+	}
+	if err := s.subscriptionQuotaService.CheckVCPUQuota(ctx, account, params.SubscriptionID, params.Location, size, nil); err != nil {
+		return nil, err
 	}
 
-	if vm.AccountID != accountId {
-		return v1.ErrUnauthorized
+	osType := params.OSType
+	if osType == "" {
+		osType = "Linux"
+	}
+
+	creds, err := s.accountCredentials(account)
+	if err != nil {
+		s.logger.Error("构建账户凭据失败", zap.Error(err), zap.String("accountId", accountID))
+		return nil, v1.ErrInternalServerError
 	}
 
-	// 2. 准备Azure操作
-	creds := &azure.Credentials{
-		TenantID:     account.Tenant,
-		ClientID:     account.AppID,
-		ClientSecret: account.PassWord,
-		DisplayName:  account.DisplayName,
+	// 5. 写入待创建记录
+	vm := &model.VirtualMachine{
+		AccountID:      accountID,
+		VMID:           vmID,
+		SubscriptionID: params.SubscriptionID,
+		Name:           params.Name,
+		ResourceGroup:  params.ResourceGroup,
+		Location:       params.Location,
+		Size:           params.Size,
+		Status:         "Creating",
+		State:          "Creating",
+		PowerState:     "creating",
+		OSType:         osType,
+		OSImage:        fmt.Sprintf("%s:%s:%s", params.Image.Publisher, params.Image.Offer, params.Image.Sku),
+		OSDiskSize:     params.OSDiskSize,
+		Tags:           convertTags(params.Tags),
+		SyncStatus:     "pending",
+		CreatedTime:    time.Now(),
+	}
+	if err := s.virtualMachineRepository.Create(ctx, vm); err != nil {
+		s.logger.Error("创建虚拟机记录失败",
+			zap.Error(err),
+			zap.String("vmId", vmID))
+		return nil, v1.ErrInternalServerError
 	}
 
-	fetcher := azure.NewVMFetcher(creds, s.logger.With(), 30*time.Second)
+	// 6. 后台创建Azure资源
+	go s.provisionVM(creds, *vm, buildVMCreateOptions(params, osType))
 
-	// 3. 映射操作类型和初始状态
-	var azureOpType azure.VMOperationType
-	initialStatus := ""
-	switch opType {
-	case v1.VMOperationStart:
-		azureOpType = azure.VMOperationStart
-		initialStatus = "Running"
-	case v1.VMOperationStop:
-		azureOpType = azure.VMOperationStop
-		initialStatus = "Stopping"
-	case v1.VMOperationRestart:
-		azureOpType = azure.VMOperationRestart
-		initialStatus = "Restarting"
-	case v1.VMOperationDelete:
-		azureOpType = azure.VMOperationDelete
-		initialStatus = "Deleting"
-	default:
-		return v1.ErrBadRequest
+	return vm, nil
+}
+
+// provisionVM 在后台创建虚拟机并回写数据库记录
+func (s *virtualMachineService) provisionVM(creds *azure.Credentials, vm model.VirtualMachine, opts azure.VMCreateOptions) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+
+	logger := s.logger.With(
+		zap.String("accountId", vm.AccountID),
+		zap.String("vmId", vm.VMID),
+	)
+	fetcher := s.azureProvider.VMClient(creds, logger, 30*time.Minute)
+
+	// 任一步骤失败都将待创建记录标记为失败，避免记录一直停留在 pending
+	markFailed := func() {
+		vm.Status = "Failed"
+		vm.State = "Failed"
+		vm.PowerState = "failed"
+		vm.SyncStatus = "failed"
+		vm.LastSyncAt = time.Now()
+		if err := s.virtualMachineRepository.Update(ctx, &vm); err != nil {
+			logger.Error("更新虚拟机创建失败状态失败", zap.Error(err))
+		}
 	}
 
-	// 4. 更新初始状态（对于删除操作也要更新状态，这样如果删除失败可以看到）
-	if err := s.virtualMachineRepository.UpdateStatus(ctx, vm.VMID, initialStatus); err != nil {
-		s.logger.Error("更新虚拟机状态失败", zap.Error(err))
+	// Azure 创建失败时已清理本次新建的网卡、公网IP和网络安全组
+	details, err := fetcher.CreateVM(ctx, opts)
+	if err != nil {
+		logger.Error("创建虚拟机失败", zap.Error(err))
+		markFailed()
+		return
 	}
 
-	// 5. 执行操作
-	vmDetails := azure.VMDetails{
-		SubscriptionID: vm.SubscriptionID,
-		ResourceGroup:  vm.ResourceGroup,
-		Name:           vm.Name,
+	helper := &syncVMsHelper{
+		service:   s,
+		ctx:       ctx,
+		accountID: vm.AccountID,
+		logger:    logger,
+	}
+	synced, err := helper.convertVMToModel(*details)
+	if err != nil {
+		logger.Error("转换虚拟机失败", zap.Error(err))
+		markFailed()
+		return
+	}
+	synced.ID = vm.ID
+	if err := s.virtualMachineRepository.Update(ctx, synced); err != nil {
+		logger.Error("更新虚拟机记录失败", zap.Error(err))
+		markFailed()
+		return
+	}
+
+	// 按数据库中的实际记录刷新账户虚拟机数量
+	vmCount, err := s.virtualMachineRepository.CountByAccountID(ctx, vm.AccountID)
+	if err != nil {
+		logger.Error("统计账户虚拟机数量失败", zap.Error(err))
+		return
 	}
+	if err := s.accountsRepository.UpdateVMCount(ctx, vm.AccountID, vmCount); err != nil {
+		logger.Error("更新账户中的虚拟机数量失败", zap.Error(err))
+	}
+
+	logger.Info("虚拟机创建完成", zap.String("vmId", synced.VMID))
+}
+
+// buildVMCreateOptions 将接口参数转换为Azure创建参数
+func buildVMCreateOptions(params *v1.VMCreateParams, osType string) azure.VMCreateOptions {
+	opts := azure.VMCreateOptions{
+		SubscriptionID: params.SubscriptionID,
+		ResourceGroup:  params.ResourceGroup,
+		Name:           params.Name,
+		Location:       params.Location,
+		Size:           params.Size,
+		OSType:         osType,
+		OSDiskSizeGB:   int32(params.OSDiskSize),
+		OSDiskType:     params.OSDiskType,
+		ImagePublisher: params.Image.Publisher,
+		ImageOffer:     params.Image.Offer,
+		ImageSku:       params.Image.Sku,
+		ImageVersion:   params.Image.Version,
+		AdminUsername:  params.AdminUsername,
+		AdminPassword:  params.AdminPassword,
+		SSHPublicKey:   params.SSHPublicKey,
+		Network: azure.NetworkOptions{
+			VNetName:       params.NetworkConfig.VNetName,
+			SubnetName:     params.NetworkConfig.SubnetName,
+			AddressPrefix:  params.NetworkConfig.AddressPrefix,
+			SubnetPrefix:   params.NetworkConfig.SubnetPrefix,
+			PublicIP:       params.NetworkConfig.PublicIP,
+			SecurityGroups: params.NetworkConfig.SecurityGroups,
+		},
+		Tags: params.Tags,
+	}
+	for _, disk := range params.DataDisks {
+		opts.DataDisks = append(opts.DataDisks, azure.DataDiskOptions{
+			Name:     disk.Name,
+			SizeGB:   int32(disk.SizeGB),
+			DiskType: disk.DiskType,
+		})
+	}
+	return opts
+}
 
-	if err := fetcher.VMOperation(ctx, azureOpType, vmDetails, &azure.OperationOptions{Force: force}); err != nil {
-		s.logger.Error("执行虚拟机操作失败",
+// OperateVM 提交虚拟机操作，返回可用于查询进度的操作记录
+func (s *virtualMachineService) OperateVM(ctx context.Context, userId, accountId, id string, req *v1.VMOperationRequest) (*model.Operation, error) {
+	// 1. 验证并获取上下文
+	account, err := s.accountsRepository.GetAccountByUserIdAndAccountId(ctx, userId, accountId)
+	if err != nil {
+		s.logger.Error("获取账户信息失败",
 			zap.Error(err),
-			zap.String("operation", string(opType)))
-		_ = s.virtualMachineRepository.UpdateStatus(ctx, vm.VMID, "Error")
-		return v1.ErrInternalServerError
+			zap.String("userId", userId),
+			zap.String("accountId", accountId))
+		return nil, v1.ErrInternalServerError
+	}
+	if account == nil {
+		return nil, v1.ErrAccountError
 	}
 
-	// 6. 根据操作类型处理结果
-	if opType == v1.VMOperationDelete {
-		// 删除操作成功后，直接删除数据库记录
-		if err := s.virtualMachineRepository.Delete(ctx, vm.VMID); err != nil {
-			s.logger.Error("删除虚拟机数据库记录失败",
-				zap.Error(err),
-				zap.String("vmId", vm.VMID))
-			return v1.ErrInternalServerError
-		}
-		return nil
+	vm, err := s.virtualMachineRepository.GetVM(ctx, id)
+	if err != nil {
+		s.logger.Error("获取虚拟机信息失败",
+			zap.Error(err),
+			zap.String("id", id))
+		return nil, v1.ErrorAzureNotFound
 	}
 
-	// 7. 其他操作更新最终状态
-	finalStatus := ""
-	switch opType {
-	case v1.VMOperationStart:
-		finalStatus = "Running"
-	case v1.VMOperationStop:
-		if force {
-			finalStatus = "Deallocated"
-		} else {
-			finalStatus = "Stopped"
-		}
-	case v1.VMOperationRestart:
-		finalStatus = "Running"
+	if vm.AccountID != accountId {
+		return nil, v1.ErrUnauthorized
 	}
 
-	return s.virtualMachineRepository.UpdateStatus(ctx, vm.VMID, finalStatus)
+	// 2. 提交操作，由操作服务在后台跟踪直至完成
+	return s.operationService.StartVMOperation(ctx, userId, account, vm, req)
 }
 
 func (s *virtualMachineService) UpdateDNSLabel(ctx context.Context, userId string, accountId string, ID string, dnsLabel string) error {
@@ -597,15 +902,14 @@ func (s *virtualMachineService) UpdateDNSLabel(ctx context.Context, userId strin
 	}
 
 	// 4. 创建Azure凭据
-	creds := &azure.Credentials{
-		TenantID:     account.Tenant,
-		ClientID:     account.AppID,
-		ClientSecret: account.PassWord,
-		DisplayName:  account.DisplayName,
+	creds, err := s.accountCredentials(account)
+	if err != nil {
+		s.logger.Error("构建账户凭据失败", zap.Error(err), zap.String("accountId", accountId))
+		return v1.ErrInternalServerError
 	}
 
 	// 5. 更新Azure云上的DNS标签
-	fetcher := azure.NewVMFetcher(creds, s.logger.With(), 30*time.Second)
+	fetcher := s.azureProvider.VMClient(creds, s.logger.With(), 30*time.Second)
 	fqdn, err := fetcher.SetVMDNSLabel(
 		ctx,
 		vm.SubscriptionID,
@@ -619,7 +923,7 @@ func (s *virtualMachineService) UpdateDNSLabel(ctx context.Context, userId strin
 			zap.String("vmId", vm.VMID),
 			zap.String("dnsLabel", dnsLabel),
 		)
-		return v1.ErrInternalServerError
+		return v1.FromAzureError(err)
 	}
 
 	// 6. 更新本地数据库中的DNS记录
@@ -641,3 +945,537 @@ func (s *virtualMachineService) UpdateDNSLabel(ctx context.Context, userId strin
 
 	return nil
 }
+
+// ListResizeOptions 列出该账户在虚拟机所在区域已同步的规格，结合当前硬件集群判断是否需要释放
+func (s *virtualMachineService) ListResizeOptions(ctx context.Context, userId, accountId, id string) ([]*v1.VMResizeOption, error) {
+	account, err := s.accountsRepository.GetAccountByUserIdAndAccountId(ctx, userId, accountId)
+	if err != nil {
+		s.logger.Error("获取账户信息失败",
+			zap.Error(err),
+			zap.String("userId", userId),
+			zap.String("accountId", accountId))
+		return nil, v1.ErrInternalServerError
+	}
+	if account == nil {
+		return nil, v1.ErrAccountError
+	}
+
+	vm, err := s.virtualMachineRepository.GetVM(ctx, id)
+	if err != nil || vm == nil {
+		return nil, v1.ErrorAzureNotFound
+	}
+	if vm.AccountID != accountId {
+		return nil, v1.ErrUnauthorized
+	}
+
+	sizes, err := s.vmSizeRepository.ListVmSizes(ctx, userId, accountId, vm.SubscriptionID, vm.Location)
+	if err != nil {
+		s.logger.Error("查询规格列表失败", zap.Error(err), zap.String("location", vm.Location))
+		return nil, v1.ErrInternalServerError
+	}
+
+	creds, err := s.accountCredentials(account)
+	if err != nil {
+		s.logger.Error("构建账户凭据失败", zap.Error(err), zap.String("accountId", accountId))
+		return nil, v1.ErrInternalServerError
+	}
+	fetcher := s.azureProvider.VMClient(creds, s.logger.With(), 30*time.Second)
+	available, err := fetcher.ListAvailableSizes(ctx, vm.SubscriptionID, vm.ResourceGroup, vm.Name)
+	if err != nil {
+		s.logger.Error("获取虚拟机可用规格失败", zap.Error(err), zap.String("vmId", vm.VMID))
+		return nil, v1.FromAzureError(err)
+	}
+	inCluster := make(map[string]bool, len(available))
+	for _, name := range available {
+		inCluster[strings.ToLower(name)] = true
+	}
+
+	options := make([]*v1.VMResizeOption, 0, len(sizes))
+	for _, size := range sizes {
+		if strings.EqualFold(size.Name, vm.Size) {
+			continue
+		}
+		options = append(options, &v1.VMResizeOption{
+			Name:                 size.Name,
+			Cores:                size.Cores,
+			MemoryGB:             size.MemoryGB,
+			MaxDataDisks:         size.MaxDataDisks,
+			RequiresDeallocation: !inCluster[strings.ToLower(size.Name)],
+		})
+	}
+	sort.Slice(options, func(i, j int) bool {
+		if options[i].RequiresDeallocation != options[j].RequiresDeallocation {
+			return !options[i].RequiresDeallocation
+		}
+		return options[i].Name < options[j].Name
+	})
+	return options, nil
+}
+
+// AttachDataDisk 创建并挂载数据磁盘
+func (s *virtualMachineService) AttachDataDisk(ctx context.Context, userId, accountId, id string, req *v1.AttachDataDiskRequest) (*model.Operation, error) {
+	account, vm, err := s.getAccountVM(ctx, s.accountsRepository, userId, accountId, id, s.virtualMachineRepository.GetVM)
+	if err != nil {
+		return nil, err
+	}
+	return s.operationService.StartAttachDataDisk(ctx, userId, account, vm, req)
+}
+
+// DetachDataDisk 卸载数据磁盘
+func (s *virtualMachineService) DetachDataDisk(ctx context.Context, userId, accountId, id, diskName string, deleteDisk bool) (*model.Operation, error) {
+	account, vm, err := s.getAccountVM(ctx, s.accountsRepository, userId, accountId, id, s.virtualMachineRepository.GetVM)
+	if err != nil {
+		return nil, err
+	}
+	return s.operationService.StartDetachDataDisk(ctx, userId, account, vm, diskName, deleteDisk)
+}
+
+// ExpandDisk 扩容系统盘或数据磁盘
+func (s *virtualMachineService) ExpandDisk(ctx context.Context, userId, accountId, id, diskName string, sizeGB int32) (*model.Operation, error) {
+	account, vm, err := s.getAccountVM(ctx, s.accountsRepository, userId, accountId, id, s.virtualMachineRepository.GetVM)
+	if err != nil {
+		return nil, err
+	}
+	return s.operationService.StartExpandDisk(ctx, userId, account, vm, diskName, sizeGB)
+}
+
+// ListSecurityGroups 获取虚拟机的网络安全组
+func (s *virtualMachineService) ListSecurityGroups(ctx context.Context, userId, accountId, id string, refresh bool) ([]azure.SecurityGroupInfo, error) {
+	account, vm, err := s.getAccountVM(ctx, s.accountsRepository, userId, accountId, id, s.virtualMachineRepository.GetVM)
+	if err != nil {
+		return nil, err
+	}
+	if !refresh {
+		groups, err := decodeSecurityGroups(vm.SecurityGroups)
+		if err == nil {
+			return groups, nil
+		}
+		s.logger.Warn("解析网络安全组失败，从Azure重新获取", zap.Error(err), zap.String("vmId", vm.VMID))
+	}
+
+	fetcher, err := s.vmClient(s.azureProvider, account)
+	if err != nil {
+		return nil, err
+	}
+	groups, err := fetcher.ListSecurityGroups(ctx, operationTarget(vm))
+	if err != nil {
+		s.logger.Error("获取网络安全组失败", zap.Error(err), zap.String("vmId", vm.VMID))
+		return nil, v1.FromAzureError(err)
+	}
+	s.saveSecurityGroups(ctx, vm.VMID, groups)
+	return groups, nil
+}
+
+// AddSecurityRule 添加入站规则，同名的入站规则直接覆盖，同名的出站规则不能覆盖
+func (s *virtualMachineService) AddSecurityRule(ctx context.Context, userId, accountId, id string, req *v1.AddSecurityRuleRequest) ([]azure.SecurityGroupInfo, error) {
+	rule, err := buildInboundRule(req)
+	if err != nil {
+		return nil, err
+	}
+	account, vm, err := s.getAccountVM(ctx, s.accountsRepository, userId, accountId, id, s.virtualMachineRepository.GetVM)
+	if err != nil {
+		return nil, err
+	}
+	fetcher, err := s.vmClient(s.azureProvider, account)
+	if err != nil {
+		return nil, err
+	}
+
+	// 以Azure上的当前规则为准检查冲突
+	target := operationTarget(vm)
+	groups, err := fetcher.ListSecurityGroups(ctx, target)
+	if err != nil {
+		s.logger.Error("获取网络安全组失败", zap.Error(err), zap.String("vmId", vm.VMID))
+		return nil, v1.FromAzureError(err)
+	}
+	nsg := selectSecurityGroup(groups, req.SecurityGroup)
+	if nsg == nil {
+		return nil, v1.ErrSecurityGroupNotFound
+	}
+	for _, existing := range nsg.Rules {
+		if strings.EqualFold(existing.Name, rule.Name) {
+			if existing.Default {
+				return nil, v1.ErrSecurityRuleConflict.WithDetail(fmt.Sprintf("不能覆盖默认规则: %s", existing.Name))
+			}
+			if !strings.EqualFold(existing.Direction, rule.Direction) {
+				return nil, v1.ErrSecurityRuleConflict.WithDetail(fmt.Sprintf("同名规则 %s 为出站规则，不能覆盖", existing.Name))
+			}
+			continue
+		}
+		if !existing.Default && existing.Direction == rule.Direction && existing.Priority == rule.Priority {
+			return nil, v1.ErrSecurityRuleConflict.WithDetail(fmt.Sprintf("优先级 %d 已被规则 %s 使用", rule.Priority, existing.Name))
+		}
+	}
+
+	groups, err = fetcher.AddSecurityRule(ctx, target, nsg.ID, rule)
+	if err != nil {
+		s.logger.Error("添加安全规则失败",
+			zap.Error(err),
+			zap.String("vmId", vm.VMID),
+			zap.String("securityGroup", nsg.Name),
+			zap.String("rule", rule.Name))
+		return nil, v1.FromAzureError(err)
+	}
+	s.saveSecurityGroups(ctx, vm.VMID, groups)
+
+	s.logger.Info("安全规则已添加",
+		zap.String("vmId", vm.VMID),
+		zap.String("securityGroup", nsg.Name),
+		zap.String("rule", rule.Name),
+		zap.String("port", rule.DestinationPortRange))
+	return groups, nil
+}
+
+// DeleteSecurityRule 删除入站规则，默认规则和出站规则不能删除
+func (s *virtualMachineService) DeleteSecurityRule(ctx context.Context, userId, accountId, id, securityGroup, ruleName string) ([]azure.SecurityGroupInfo, error) {
+	account, vm, err := s.getAccountVM(ctx, s.accountsRepository, userId, accountId, id, s.virtualMachineRepository.GetVM)
+	if err != nil {
+		return nil, err
+	}
+	fetcher, err := s.vmClient(s.azureProvider, account)
+	if err != nil {
+		return nil, err
+	}
+
+	target := operationTarget(vm)
+	groups, err := fetcher.ListSecurityGroups(ctx, target)
+	if err != nil {
+		s.logger.Error("获取网络安全组失败", zap.Error(err), zap.String("vmId", vm.VMID))
+		return nil, v1.FromAzureError(err)
+	}
+	var nsg *azure.SecurityGroupInfo
+	var rule *azure.SecurityRule
+	for i := range groups {
+		if securityGroup != "" && !strings.EqualFold(groups[i].Name, securityGroup) {
+			continue
+		}
+		for j := range groups[i].Rules {
+			if strings.EqualFold(groups[i].Rules[j].Name, ruleName) && strings.EqualFold(groups[i].Rules[j].Direction, "Inbound") {
+				nsg, rule = &groups[i], &groups[i].Rules[j]
+				break
+			}
+		}
+		if rule != nil {
+			break
+		}
+	}
+	if rule == nil {
+		return nil, v1.ErrNotFound.WithDetail(fmt.Sprintf("未找到入站规则: %s", ruleName))
+	}
+	if rule.Default {
+		return nil, v1.ErrInvalidParams.WithDetail(fmt.Sprintf("默认规则不能删除: %s", rule.Name))
+	}
+
+	groups, err = fetcher.DeleteSecurityRule(ctx, target, nsg.ID, rule.Name)
+	if err != nil {
+		s.logger.Error("删除安全规则失败",
+			zap.Error(err),
+			zap.String("vmId", vm.VMID),
+			zap.String("securityGroup", nsg.Name),
+			zap.String("rule", ruleName))
+		return nil, v1.FromAzureError(err)
+	}
+	s.saveSecurityGroups(ctx, vm.VMID, groups)
+	return groups, nil
+}
+
+// CreateSecurityGroup 创建网络安全组并关联到虚拟机的主网卡
+func (s *virtualMachineService) CreateSecurityGroup(ctx context.Context, userId, accountId, id string, req *v1.CreateSecurityGroupRequest) ([]azure.SecurityGroupInfo, error) {
+	if req.Name != "" && !azureResourceNamePattern.MatchString(req.Name) {
+		return nil, v1.ErrInvalidParams.WithDetail(fmt.Sprintf("无效的网络安全组名称: %s", req.Name))
+	}
+	account, vm, err := s.getAccountVM(ctx, s.accountsRepository, userId, accountId, id, s.virtualMachineRepository.GetVM)
+	if err != nil {
+		return nil, err
+	}
+	fetcher, err := s.vmClient(s.azureProvider, account)
+	if err != nil {
+		return nil, err
+	}
+
+	target := operationTarget(vm)
+	groups, err := fetcher.ListSecurityGroups(ctx, target)
+	if err != nil {
+		s.logger.Error("获取网络安全组失败", zap.Error(err), zap.String("vmId", vm.VMID))
+		return nil, v1.FromAzureError(err)
+	}
+	for _, nsg := range groups {
+		if nsg.Scope == azure.SecurityGroupScopeNIC {
+			return nil, v1.ErrSecurityGroupAlreadyAttached.WithDetail(fmt.Sprintf("网卡 %s 已关联网络安全组 %s", nsg.AttachedTo, nsg.Name))
+		}
+	}
+
+	groups, err = fetcher.CreateSecurityGroup(ctx, target, req.Name)
+	if err != nil {
+		s.logger.Error("创建网络安全组失败", zap.Error(err), zap.String("vmId", vm.VMID))
+		return nil, v1.FromAzureError(err)
+	}
+	s.saveSecurityGroups(ctx, vm.VMID, groups)
+	return groups, nil
+}
+
+// saveSecurityGroups 保存网络安全组，Azure上的修改已完成，保存失败只记录日志，下次同步时修正
+func (s *virtualMachineService) saveSecurityGroups(ctx context.Context, vmID string, groups []azure.SecurityGroupInfo) {
+	data, err := encodeSecurityGroups(groups)
+	if err == nil {
+		err = s.virtualMachineRepository.UpdateSecurityGroups(ctx, vmID, data)
+	}
+	if err != nil {
+		s.logger.Error("更新虚拟机网络安全组失败", zap.Error(err), zap.String("vmId", vmID))
+	}
+}
+
+// selectSecurityGroup 按名称选择网络安全组，名称为空时优先使用网卡上的网络安全组
+func selectSecurityGroup(groups []azure.SecurityGroupInfo, name string) *azure.SecurityGroupInfo {
+	var subnetGroup *azure.SecurityGroupInfo
+	for i := range groups {
+		if name != "" {
+			if strings.EqualFold(groups[i].Name, name) {
+				return &groups[i]
+			}
+			continue
+		}
+		if groups[i].Scope == azure.SecurityGroupScopeNIC {
+			return &groups[i]
+		}
+		if subnetGroup == nil {
+			subnetGroup = &groups[i]
+		}
+	}
+	return subnetGroup
+}
+
+// azureResourceNamePattern 安全规则和网络安全组名称: 1-80个字符，以字母或数字开头，以字母、数字或下划线结尾
+var azureResourceNamePattern = regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9._-]{0,78}[A-Za-z0-9_])?$`)
+
+// securityRuleProtocols 支持的协议，key 为小写
+var securityRuleProtocols = map[string]string{
+	"tcp":  "Tcp",
+	"udp":  "Udp",
+	"icmp": "Icmp",
+	"*":    "*",
+	"any":  "*",
+}
+
+// buildInboundRule 校验请求并构建入站规则
+func buildInboundRule(req *v1.AddSecurityRuleRequest) (azure.SecurityRule, error) {
+	rule := azure.SecurityRule{
+		Name:                     req.Name,
+		Priority:                 req.Priority,
+		Direction:                "Inbound",
+		Access:                   req.Access,
+		Protocol:                 "Tcp",
+		SourceAddressPrefix:      strings.TrimSpace(req.SourceCIDR),
+		SourcePortRange:          "*",
+		DestinationAddressPrefix: "*",
+		DestinationPortRange:     strings.ReplaceAll(req.Port, " ", ""),
+		Description:              req.Description,
+	}
+	if !azureResourceNamePattern.MatchString(rule.Name) {
+		return rule, v1.ErrInvalidParams.WithDetail(fmt.Sprintf("无效的规则名称: %s", rule.Name))
+	}
+	if rule.Access == "" {
+		rule.Access = "Allow"
+	}
+	if req.Protocol != "" {
+		protocol, ok := securityRuleProtocols[strings.ToLower(req.Protocol)]
+		if !ok {
+			return rule, v1.ErrInvalidParams.WithDetail(fmt.Sprintf("不支持的协议: %s", req.Protocol))
+		}
+		rule.Protocol = protocol
+	}
+	if err := validatePortRanges(rule.DestinationPortRange); err != nil {
+		return rule, v1.ErrInvalidParams.WithDetail(err.Error())
+	}
+	if rule.SourceAddressPrefix == "" {
+		rule.SourceAddressPrefix = "*"
+	}
+	if err := validateAddressPrefixes(rule.SourceAddressPrefix); err != nil {
+		return rule, v1.ErrInvalidParams.WithDetail(err.Error())
+	}
+	return rule, nil
+}
+
+// validatePortRanges 校验逗号分隔的端口或端口范围
+func validatePortRanges(value string) error {
+	for _, item := range strings.Split(value, ",") {
+		if item == "*" {
+			continue
+		}
+		bounds := strings.SplitN(item, "-", 2)
+		var ports []int
+		for _, bound := range bounds {
+			port, err := strconv.Atoi(bound)
+			if err != nil || port < 1 || port > 65535 {
+				return fmt.Errorf("无效的端口: %s", item)
+			}
+			ports = append(ports, port)
+		}
+		if len(ports) == 2 && ports[0] > ports[1] {
+			return fmt.Errorf("无效的端口范围: %s", item)
+		}
+	}
+	return nil
+}
+
+// serviceTagPattern Azure服务标签，例如 Internet、VirtualNetwork、Storage.EastUS
+var serviceTagPattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9]*(\.[A-Za-z0-9]+)?$`)
+
+// validateAddressPrefixes 校验逗号分隔的IP、CIDR或服务标签
+func validateAddressPrefixes(value string) error {
+	items := strings.Split(value, ",")
+	for _, item := range items {
+		item = strings.TrimSpace(item)
+		switch {
+		case item == "*":
+			if len(items) > 1 {
+				return fmt.Errorf("* 不能与其他来源地址同时使用")
+			}
+		case strings.Contains(item, "/"):
+			if _, _, err := net.ParseCIDR(item); err != nil {
+				return fmt.Errorf("无效的CIDR: %s", item)
+			}
+		case net.ParseIP(item) != nil:
+		case serviceTagPattern.MatchString(item):
+			if len(items) > 1 {
+				return fmt.Errorf("服务标签不能与其他来源地址同时使用: %s", item)
+			}
+		default:
+			return fmt.Errorf("无效的来源地址: %s", item)
+		}
+	}
+	return nil
+}
+
+// ResetVMAccess 通过 VMAccess 扩展重置管理员密码、SSH公钥或sshd配置，虚拟机代理需要在运行中
+func (s *virtualMachineService) ResetVMAccess(ctx context.Context, userId, accountId, id string, req *v1.ResetVMAccessRequest) (*model.Operation, error) {
+	account, vm, err := s.getAccountVM(ctx, s.accountsRepository, userId, accountId, id, s.virtualMachineRepository.GetVM)
+	if err != nil {
+		return nil, err
+	}
+	opts, err := buildVMAccessOptions(vm.OSType, req)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.EqualFold(vm.PowerState, "running") {
+		return nil, v1.ErrVMNotRunning
+	}
+
+	target := operationTarget(vm)
+	target.OSType = vm.OSType
+	target.Location = vm.Location
+	return s.operationService.StartTask(ctx, userId, account, vm, v1.VMOperationResetAccess, "", func(ctx context.Context, fetcher azure.VMClient) (string, error) {
+		result, err := fetcher.ResetVMAccess(ctx, target, opts)
+		if err != nil {
+			return "", err
+		}
+		summary := fmt.Sprintf("扩展 %s 执行完成: %s", result.ExtensionName, result.ProvisioningState)
+		if result.Message != "" {
+			summary += ", " + result.Message
+		}
+		return summary, nil
+	})
+}
+
+// GetVMAccessStatus 获取 VMAccess 扩展最近一次的执行结果
+func (s *virtualMachineService) GetVMAccessStatus(ctx context.Context, userId, accountId, id string) (*azure.VMAccessResult, error) {
+	account, vm, err := s.getAccountVM(ctx, s.accountsRepository, userId, accountId, id, s.virtualMachineRepository.GetVM)
+	if err != nil {
+		return nil, err
+	}
+	fetcher, err := s.vmClient(s.azureProvider, account)
+	if err != nil {
+		return nil, err
+	}
+	result, err := fetcher.GetVMAccessStatus(ctx, operationTarget(vm))
+	if err != nil {
+		if errors.Is(err, azure.ErrVMAccessNotInstalled) {
+			return nil, v1.ErrNotFound.WithDetail(err.Error())
+		}
+		s.logger.Error("获取VMAccess扩展状态失败", zap.Error(err), zap.String("vmId", vm.VMID))
+		return nil, v1.FromAzureError(err)
+	}
+	return result, nil
+}
+
+// EnableBootDiagnostics 启用启动诊断，之后虚拟机重新启动时才会生成串行日志和截图
+func (s *virtualMachineService) EnableBootDiagnostics(ctx context.Context, userId, accountId, vmId string) error {
+	account, vm, err := s.getAccountVM(ctx, s.accountsRepository, userId, accountId, vmId, s.virtualMachineRepository.GetByID)
+	if err != nil {
+		return err
+	}
+	fetcher, err := s.vmClient(s.azureProvider, account)
+	if err != nil {
+		return err
+	}
+	if err := fetcher.EnableBootDiagnostics(ctx, operationTarget(vm)); err != nil {
+		s.logger.Error("启用启动诊断失败", zap.Error(err), zap.String("vmId", vm.VMID))
+		return v1.FromAzureError(err)
+	}
+	return nil
+}
+
+// GetBootDiagnostics 获取虚拟机的串行日志或截图
+func (s *virtualMachineService) GetBootDiagnostics(ctx context.Context, userId, accountId, vmId string, kind azure.BootDiagnosticsKind) (*azure.BootDiagnosticsData, error) {
+	account, vm, err := s.getAccountVM(ctx, s.accountsRepository, userId, accountId, vmId, s.virtualMachineRepository.GetByID)
+	if err != nil {
+		return nil, err
+	}
+	fetcher, err := s.vmClient(s.azureProvider, account)
+	if err != nil {
+		return nil, err
+	}
+	data, err := fetcher.GetBootDiagnostics(ctx, operationTarget(vm), kind)
+	switch {
+	case errors.Is(err, azure.ErrBootDiagnosticsDisabled):
+		return nil, v1.ErrBootDiagnosticsDisabled
+	case errors.Is(err, azure.ErrBootDiagnosticsUnavailable):
+		return nil, v1.ErrNotFound.WithDetail(err.Error())
+	case err != nil:
+		s.logger.Error("获取启动诊断数据失败", zap.Error(err), zap.String("vmId", vm.VMID), zap.String("kind", string(kind)))
+		return nil, v1.FromAzureError(err)
+	}
+	return data, nil
+}
+
+// sshPublicKeyPrefixes Azure 支持的SSH公钥类型
+var sshPublicKeyPrefixes = []string{"ssh-rsa ", "ssh-ed25519 ", "ecdsa-sha2-"}
+
+// buildVMAccessOptions 按操作系统类型校验重置请求
+func buildVMAccessOptions(osType string, req *v1.ResetVMAccessRequest) (azure.VMAccessResetOptions, error) {
+	opts := azure.VMAccessResetOptions{
+		Username:     strings.TrimSpace(req.Username),
+		Password:     req.Password,
+		SSHPublicKey: strings.TrimSpace(req.SSHPublicKey),
+		ResetSSH:     req.ResetSSH,
+	}
+	if strings.EqualFold(osType, "Windows") {
+		if opts.SSHPublicKey != "" || opts.ResetSSH {
+			return opts, v1.ErrInvalidParams.WithDetail("Windows 虚拟机只能重置密码")
+		}
+		if opts.Username == "" || opts.Password == "" {
+			return opts, v1.ErrInvalidParams.WithDetail("Windows 虚拟机需要提供用户名和密码")
+		}
+		return opts, nil
+	}
+
+	if opts.Password == "" && opts.SSHPublicKey == "" && !opts.ResetSSH {
+		return opts, v1.ErrInvalidParams.WithDetail("至少需要提供密码、SSH公钥或重置sshd配置中的一项")
+	}
+	if (opts.Password != "" || opts.SSHPublicKey != "") && opts.Username == "" {
+		return opts, v1.ErrInvalidParams.WithDetail("重置密码或SSH公钥需要提供用户名")
+	}
+	if opts.SSHPublicKey != "" {
+		valid := false
+		for _, prefix := range sshPublicKeyPrefixes {
+			if strings.HasPrefix(opts.SSHPublicKey, prefix) {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return opts, v1.ErrInvalidParams.WithDetail("无效的SSH公钥")
+		}
+	}
+	return opts, nil
+}
@@ -0,0 +1,279 @@
+package service
+
+import (
+	v1 "azure-vm-backend/api/v1"
+	"azure-vm-backend/internal/model"
+	"azure-vm-backend/internal/repository"
+	"context"
+	"fmt"
+	"math"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// hoursPerMonth 月费用按每月 730 小时估算
+const hoursPerMonth = 730
+
+// costCurrency 零售价格和磁盘参考价格的币种
+const costCurrency = "USD"
+
+// defaultDiskType 未指定磁盘类型时的默认类型，与创建虚拟机时的默认值一致
+const defaultDiskType = "StandardSSD_LRS"
+
+// diskPricePerGBMonth 托管磁盘参考价格(美元/GB/月)，由各类型 128GB 档位的零售价格折算
+var diskPricePerGBMonth = map[string]float64{
+	"Standard_LRS":    0.046,
+	"StandardSSD_LRS": 0.075,
+	"StandardSSD_ZRS": 0.094,
+	"Premium_LRS":     0.154,
+	"Premium_ZRS":     0.231,
+}
+
+// diskTiersGB 托管磁盘计费档位，磁盘按不小于其大小的最小档位计费
+var diskTiersGB = []int{4, 8, 16, 32, 64, 128, 256, 512, 1024, 2048, 4096, 8192, 16384, 32767}
+
+type VmCostService interface {
+	// FillMonthlyCosts 估算同步得到的虚拟机的月费用并写入 MonthlyCost，规格价格未知时只计算磁盘费用
+	FillMonthlyCosts(ctx context.Context, userID, accountID string, vms []*model.VirtualMachine)
+	// RefreshMonthlyCost 调整规格或磁盘后按虚拟机记录重新估算并保存月费用
+	RefreshMonthlyCost(ctx context.Context, userID, accountID, vmID string)
+	// EstimateCreate 在创建前估算按参数创建的虚拟机的月费用
+	EstimateCreate(ctx context.Context, userID, accountID string, params *v1.VMCreateParams) (*v1.CostEstimate, error)
+	// GetAccountCosts 按账户和订阅汇总虚拟机的预计月费用，key 为账户ID
+	GetAccountCosts(ctx context.Context, accountIDs []string) (map[string]*v1.AccountCost, error)
+}
+
+func NewVmCostService(
+	service *Service,
+	accountsRepository repository.AccountsRepository,
+	virtualMachineRepository repository.VirtualMachineRepository,
+	vmSizeRepository repository.VmSizeRepository,
+	vmPriceService VmPriceService,
+) VmCostService {
+	return &vmCostService{
+		Service:                  service,
+		accountsRepository:       accountsRepository,
+		virtualMachineRepository: virtualMachineRepository,
+		vmSizeRepository:         vmSizeRepository,
+		vmPriceService:           vmPriceService,
+	}
+}
+
+type vmCostService struct {
+	*Service
+	accountsRepository       repository.AccountsRepository
+	virtualMachineRepository repository.VirtualMachineRepository
+	vmSizeRepository         repository.VmSizeRepository
+	vmPriceService           VmPriceService
+}
+
+// costDisk 参与估算的磁盘
+type costDisk struct {
+	name     string
+	diskType string
+	sizeGB   int
+}
+
+func (s *vmCostService) FillMonthlyCosts(ctx context.Context, userID, accountID string, vms []*model.VirtualMachine) {
+	// 同一次同步中相同规格只查询一次价格
+	hourly := make(map[string]float64)
+	for _, vm := range vms {
+		key := strings.ToLower(vm.Location + "/" + vm.Size + "/" + vm.OSType)
+		price, ok := hourly[key]
+		if !ok {
+			var err error
+			price, _, err = s.hourlyPrice(ctx, userID, accountID, vm.Location, vm.Size, vm.OSType)
+			if err != nil {
+				s.logger.Warn("获取规格价格失败，只计算磁盘费用",
+					zap.Error(err),
+					zap.String("location", vm.Location),
+					zap.String("size", vm.Size))
+			}
+			hourly[key] = price
+		}
+
+		disks, err := decodeDiskInfo(vm.DataDisks)
+		if err != nil {
+			s.logger.Warn("解析虚拟机磁盘信息失败", zap.Error(err), zap.String("vmId", vm.VMID))
+			disks = &diskInfo{}
+		}
+		osDiskSize := int(disks.OSDiskSize)
+		if osDiskSize == 0 {
+			osDiskSize = vm.OSDiskSize
+		}
+		costDisks := []costDisk{{name: "osdisk", sizeGB: osDiskSize}}
+		for _, disk := range disks.DataDisks {
+			costDisks = append(costDisks, costDisk{name: disk.Name, diskType: disk.DiskType, sizeGB: int(disk.SizeGB)})
+		}
+
+		// 已释放的虚拟机不产生计算费用
+		estimate := buildCostEstimate(price, !strings.EqualFold(vm.PowerState, "deallocated"), costDisks)
+		vm.MonthlyCost = estimate.Total
+	}
+}
+
+func (s *vmCostService) RefreshMonthlyCost(ctx context.Context, userID, accountID, vmID string) {
+	vm, err := s.virtualMachineRepository.GetByID(ctx, vmID)
+	if err != nil {
+		s.logger.Warn("获取虚拟机记录失败，跳过月费用估算", zap.Error(err), zap.String("vmId", vmID))
+		return
+	}
+	s.FillMonthlyCosts(ctx, userID, accountID, []*model.VirtualMachine{vm})
+	if err := s.virtualMachineRepository.UpdateMonthlyCost(ctx, vmID, vm.MonthlyCost); err != nil {
+		s.logger.Error("更新虚拟机预计月费用失败", zap.Error(err), zap.String("vmId", vmID))
+	}
+}
+
+func (s *vmCostService) EstimateCreate(ctx context.Context, userID, accountID string, params *v1.VMCreateParams) (*v1.CostEstimate, error) {
+	account, err := s.accountsRepository.GetAccountByUserIdAndAccountId(ctx, userID, accountID)
+	if err != nil {
+		s.logger.Error("获取账户信息失败", zap.Error(err), zap.String("accountId", accountID))
+		return nil, v1.ErrInternalServerError
+	}
+	if account == nil {
+		return nil, v1.ErrAccountError
+	}
+
+	osType := params.OSType
+	if osType == "" {
+		osType = "Linux"
+	}
+	price, available, err := s.hourlyPrice(ctx, userID, accountID, params.Location, params.Size, osType)
+	if err != nil {
+		s.logger.Error("获取规格价格失败", zap.Error(err), zap.String("location", params.Location), zap.String("size", params.Size))
+		return nil, v1.ErrInternalServerError
+	}
+
+	// 未指定系统盘大小时使用镜像默认大小，Linux 通常为 30GB，Windows 为 127GB
+	osDiskSize := params.OSDiskSize
+	if osDiskSize <= 0 {
+		osDiskSize = 30
+		if strings.EqualFold(osType, "Windows") {
+			osDiskSize = 127
+		}
+	}
+	disks := []costDisk{{name: "osdisk", diskType: params.OSDiskType, sizeGB: osDiskSize}}
+	for i, disk := range params.DataDisks {
+		name := disk.Name
+		if name == "" {
+			name = fmt.Sprintf("datadisk%d", i)
+		}
+		disks = append(disks, costDisk{name: name, diskType: disk.DiskType, sizeGB: disk.SizeGB})
+	}
+
+	estimate := buildCostEstimate(price, true, disks)
+	estimate.Size = params.Size
+	estimate.Location = params.Location
+	estimate.OSType = osType
+	estimate.PriceAvailable = available
+	return estimate, nil
+}
+
+func (s *vmCostService) GetAccountCosts(ctx context.Context, accountIDs []string) (map[string]*v1.AccountCost, error) {
+	totals, err := s.virtualMachineRepository.SumMonthlyCosts(ctx, accountIDs)
+	if err != nil {
+		s.logger.Error("汇总虚拟机费用失败", zap.Error(err))
+		return nil, v1.ErrInternalServerError
+	}
+
+	costs := make(map[string]*v1.AccountCost, len(accountIDs))
+	for _, accountID := range accountIDs {
+		costs[accountID] = &v1.AccountCost{Currency: costCurrency, Subscriptions: make([]*v1.SubscriptionCost, 0)}
+	}
+	for _, total := range totals {
+		cost, ok := costs[total.AccountID]
+		if !ok {
+			continue
+		}
+		cost.MonthlyCost = roundCost(cost.MonthlyCost + total.MonthlyCost)
+		cost.Subscriptions = append(cost.Subscriptions, &v1.SubscriptionCost{
+			SubscriptionID: total.SubscriptionID,
+			VMCount:        total.VMCount,
+			MonthlyCost:    roundCost(total.MonthlyCost),
+		})
+	}
+	return costs, nil
+}
+
+// hourlyPrice 获取规格的按量价格，优先使用账户已同步规格中的价格，没有时从价格服务获取
+// 返回的 bool 表示是否找到了价格
+func (s *vmCostService) hourlyPrice(ctx context.Context, userID, accountID, location, size, osType string) (float64, bool, error) {
+	windows := strings.EqualFold(osType, "Windows")
+	vmSize, err := s.vmSizeRepository.GetVmSize(ctx, userID, accountID, "", location, size)
+	if err != nil {
+		return 0, false, err
+	}
+	if vmSize != nil {
+		price := vmSize.PricePerHour
+		if windows {
+			price = vmSize.WindowsPricePerHour
+		}
+		if price > 0 {
+			return price, true, nil
+		}
+	}
+
+	record, err := s.vmPriceService.GetPrice(ctx, location, size)
+	if err != nil || record == nil {
+		return 0, false, err
+	}
+	price := record.LinuxPerHour
+	if windows {
+		price = record.WindowsPerHour
+	}
+	return price, price > 0, nil
+}
+
+// buildCostEstimate 按小时价格和磁盘计算月费用
+func buildCostEstimate(hourly float64, running bool, disks []costDisk) *v1.CostEstimate {
+	estimate := &v1.CostEstimate{
+		Currency:       costCurrency,
+		PriceAvailable: hourly > 0,
+		ComputePerHour: hourly,
+		Disks:          make([]*v1.DiskEstimate, 0, len(disks)),
+	}
+	if running {
+		estimate.ComputeMonthly = roundCost(hourly * hoursPerMonth)
+	}
+	for _, disk := range disks {
+		if disk.sizeGB <= 0 {
+			continue
+		}
+		diskType := disk.diskType
+		if diskType == "" {
+			diskType = defaultDiskType
+		}
+		rate, ok := diskPricePerGBMonth[diskType]
+		if !ok {
+			rate = diskPricePerGBMonth[defaultDiskType]
+		}
+		billed := diskTierGB(disk.sizeGB)
+		cost := roundCost(float64(billed) * rate)
+		estimate.DiskMonthly = roundCost(estimate.DiskMonthly + cost)
+		estimate.Disks = append(estimate.Disks, &v1.DiskEstimate{
+			Name:        disk.name,
+			DiskType:    diskType,
+			SizeGB:      disk.sizeGB,
+			BilledGB:    billed,
+			MonthlyCost: cost,
+		})
+	}
+	estimate.Total = roundCost(estimate.ComputeMonthly + estimate.DiskMonthly)
+	return estimate
+}
+
+// diskTierGB 磁盘的计费档位，超过最大档位时按实际大小计费
+func diskTierGB(sizeGB int) int {
+	for _, tier := range diskTiersGB {
+		if sizeGB <= tier {
+			return tier
+		}
+	}
+	return sizeGB
+}
+
+// roundCost 费用保留两位小数
+func roundCost(value float64) float64 {
+	return math.Round(value*100) / 100
+}
@@ -10,10 +10,10 @@ import (
 )
 
 type VmImageService interface {
-	GetVmImage(ctx context.Context, id uint) (*model.VmImage, error)
-	ListVmImages(ctx context.Context) ([]*model.VmImage, error)
+	GetVmImage(ctx context.Context, userId string, id uint) (*model.VmImage, error)
+	ListVmImages(ctx context.Context, userId, accountId string) ([]*model.VmImage, error)
 	SyncVmImages(ctx context.Context, userId, accountId, subscriptionId, location string) error
-	GetVmImageBySpec(ctx context.Context, publisher, offer, sku string) (*model.VmImage, error)
+	GetVmImageBySpec(ctx context.Context, userId, accountId, publisher, offer, sku string) (*model.VmImage, error)
 }
 
 type vmImageService struct {
@@ -21,6 +21,7 @@ type vmImageService struct {
 	vmImageRepository       repository.VmImageRepository
 	accountsRepository      repository.AccountsRepository
 	subscriptionsRepository repository.SubscriptionsRepository
+	azureProvider           azure.Provider
 }
 
 func NewVmImageService(
@@ -28,25 +29,27 @@ func NewVmImageService(
 	vmImageRepository repository.VmImageRepository,
 	accountsRepository repository.AccountsRepository,
 	subscriptionsRepository repository.SubscriptionsRepository,
+	azureProvider azure.Provider,
 ) VmImageService {
 	return &vmImageService{
 		Service:                 service,
 		vmImageRepository:       vmImageRepository,
 		accountsRepository:      accountsRepository,
 		subscriptionsRepository: subscriptionsRepository,
+		azureProvider:           azureProvider,
 	}
 }
 
-func (s *vmImageService) GetVmImage(ctx context.Context, id uint) (*model.VmImage, error) {
-	return s.vmImageRepository.GetVmImage(ctx, id)
+func (s *vmImageService) GetVmImage(ctx context.Context, userId string, id uint) (*model.VmImage, error) {
+	return s.vmImageRepository.GetVmImage(ctx, userId, id)
 }
 
-func (s *vmImageService) ListVmImages(ctx context.Context) ([]*model.VmImage, error) {
-	return s.vmImageRepository.ListVmImages(ctx)
+func (s *vmImageService) ListVmImages(ctx context.Context, userId, accountId string) ([]*model.VmImage, error) {
+	return s.vmImageRepository.ListVmImages(ctx, userId, accountId)
 }
 
-func (s *vmImageService) GetVmImageBySpec(ctx context.Context, publisher, offer, sku string) (*model.VmImage, error) {
-	return s.vmImageRepository.GetVmImageBySpec(ctx, publisher, offer, sku)
+func (s *vmImageService) GetVmImageBySpec(ctx context.Context, userId, accountId, publisher, offer, sku string) (*model.VmImage, error) {
+	return s.vmImageRepository.GetVmImageBySpec(ctx, userId, accountId, publisher, offer, sku)
 }
 
 func (s *vmImageService) SyncVmImages(ctx context.Context, userId, accountId, subscriptionId, location string) error {
@@ -60,7 +63,7 @@ func (s *vmImageService) SyncVmImages(ctx context.Context, userId, accountId, su
 	}
 
 	// 2. 验证订阅
-	subscription, err := s.subscriptionsRepository.GetSubscription(ctx, accountId, subscriptionId)
+	subscription, err := s.subscriptionsRepository.GetSubscription(ctx, userId, accountId, subscriptionId)
 	if err != nil {
 		return fmt.Errorf("获取订阅信息失败: %w", err)
 	}
@@ -68,13 +71,18 @@ func (s *vmImageService) SyncVmImages(ctx context.Context, userId, accountId, su
 		return v1.ErrorAzureNotFound
 	}
 
+	creds, err := s.accountCredentials(account)
+	if err != nil {
+		return err
+	}
+
 	// 创建 VMImageFetcher 实例
-	fetcher := azure.NewVMImageFetcher(
+	fetcher := s.azureProvider.ImageClient(
 		subscriptionId,
 		&azure.AzureCredential{
-			TenantID:     account.Tenant,
-			ClientID:     account.AppID,
-			ClientSecret: account.PassWord,
+			TenantID:     creds.TenantID,
+			ClientID:     creds.ClientID,
+			ClientSecret: creds.ClientSecret,
 		},
 		s.logger.With(),
 	)
@@ -102,7 +110,7 @@ func (s *vmImageService) SyncVmImages(ctx context.Context, userId, accountId, su
 	}
 
 	// 批量更新数据库
-	if err := s.vmImageRepository.BatchUpsertVmImages(ctx, dbImages); err != nil {
+	if err := s.vmImageRepository.BatchUpsertVmImages(ctx, accountId, dbImages); err != nil {
 		return fmt.Errorf("更新数据库镜像信息失败: %w", err)
 	}
 
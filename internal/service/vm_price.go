@@ -0,0 +1,124 @@
+package service
+
+import (
+	"azure-vm-backend/internal/model"
+	"azure-vm-backend/internal/repository"
+	"azure-vm-backend/pkg/azure"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+)
+
+type VmPriceService interface {
+	// RefreshPrices 刷新所有已同步规格的区域的零售价格，单个区域失败不影响其他区域
+	RefreshPrices(ctx context.Context) error
+	// RefreshLocation 刷新单个区域的零售价格，返回获取到的规格数量
+	RefreshLocation(ctx context.Context, location string) (int, error)
+	// GetPrice 获取规格价格，数据库中没有时从零售价格 API 获取并保存，没有报价时返回 nil
+	GetPrice(ctx context.Context, location, size string) (*model.VmSizePrice, error)
+}
+
+func NewVmPriceService(
+	service *Service,
+	vmSizePriceRepository repository.VmSizePriceRepository,
+	azureProvider azure.Provider,
+	conf *viper.Viper,
+) VmPriceService {
+	return &vmPriceService{
+		Service:               service,
+		vmSizePriceRepository: vmSizePriceRepository,
+		azureProvider:         azureProvider,
+		retailPricesURL:       conf.GetString("azure.retail_prices_url"),
+	}
+}
+
+type vmPriceService struct {
+	*Service
+	vmSizePriceRepository repository.VmSizePriceRepository
+	azureProvider         azure.Provider
+	// retailPricesURL 为空时使用 azure.DefaultRetailPricesURL
+	retailPricesURL string
+}
+
+func (s *vmPriceService) RefreshPrices(ctx context.Context) error {
+	locations, err := s.vmSizePriceRepository.ListSizeLocations(ctx)
+	if err != nil {
+		return err
+	}
+
+	var failed int
+	for _, location := range locations {
+		count, err := s.RefreshLocation(ctx, location)
+		if err != nil {
+			failed++
+			s.logger.Error("刷新规格价格失败", zap.Error(err), zap.String("location", location))
+			continue
+		}
+		s.logger.Info("刷新规格价格完成", zap.String("location", location), zap.Int("count", count))
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d/%d 个区域的规格价格刷新失败", failed, len(locations))
+	}
+	return nil
+}
+
+func (s *vmPriceService) RefreshLocation(ctx context.Context, location string) (int, error) {
+	fetcher := s.azureProvider.PriceClient(s.retailPricesURL, s.logger.With())
+	prices, err := fetcher.ListVMPrices(ctx, location)
+	if err != nil {
+		return 0, fmt.Errorf("获取规格价格失败: %w", err)
+	}
+	// 接口临时返回空结果时保留原有价格
+	if len(prices) == 0 {
+		return 0, nil
+	}
+
+	now := time.Now()
+	records := make([]*model.VmSizePrice, 0, len(prices))
+	for _, price := range prices {
+		records = append(records, priceRecord(price, location, now))
+	}
+	if err := s.vmSizePriceRepository.ReplacePrices(ctx, location, records); err != nil {
+		return 0, fmt.Errorf("保存规格价格失败: %w", err)
+	}
+	return len(records), nil
+}
+
+func (s *vmPriceService) GetPrice(ctx context.Context, location, size string) (*model.VmSizePrice, error) {
+	price, err := s.vmSizePriceRepository.GetPrice(ctx, location, size)
+	if err != nil || price != nil {
+		return price, err
+	}
+
+	fetcher := s.azureProvider.PriceClient(s.retailPricesURL, s.logger.With())
+	fetched, err := fetcher.GetVMPrice(ctx, location, size)
+	if err != nil {
+		return nil, fmt.Errorf("获取规格价格失败: %w", err)
+	}
+	if fetched == nil {
+		return nil, nil
+	}
+	price = priceRecord(fetched, location, time.Now())
+	if err := s.vmSizePriceRepository.SavePrice(ctx, price); err != nil {
+		// 保存失败不影响本次使用
+		s.logger.Error("保存规格价格失败", zap.Error(err), zap.String("location", location), zap.String("size", size))
+	}
+	return price, nil
+}
+
+// priceRecord 将零售价格转换为数据库记录
+func priceRecord(price *azure.VMPrice, location string, syncedAt time.Time) *model.VmSizePrice {
+	return &model.VmSizePrice{
+		Name:               price.Size,
+		Location:           location,
+		Currency:           price.Currency,
+		LinuxPerHour:       price.LinuxPerHour,
+		WindowsPerHour:     price.WindowsPerHour,
+		LinuxSpotPerHour:   price.LinuxSpotPerHour,
+		WindowsSpotPerHour: price.WindowsSpotPerHour,
+		SyncedAt:           syncedAt,
+	}
+}
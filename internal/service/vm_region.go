@@ -18,15 +18,18 @@ type VmRegionService interface {
 type vmRegionService struct {
 	*Service
 	vmRegionRepository repository.VmRegionRepository
+	azureProvider      azure.Provider
 }
 
 func NewVmRegionService(
 	service *Service,
 	vmRegionRepository repository.VmRegionRepository,
+	azureProvider azure.Provider,
 ) VmRegionService {
 	return &vmRegionService{
 		Service:            service,
 		vmRegionRepository: vmRegionRepository,
+		azureProvider:      azureProvider,
 	}
 }
 
@@ -43,7 +46,7 @@ func (s *vmRegionService) ListVmRegions(ctx context.Context, enabled *bool) ([]*
 // SyncVmRegions 同步Azure区域信息
 func (s *vmRegionService) SyncVmRegions(ctx context.Context, cred *azure.AzureCredential, subscriptionID string) error {
 	// 创建Azure区域获取器
-	fetcher := azure.NewRegionFetcher(s.logger.With(), 3, 30*time.Second)
+	fetcher := s.azureProvider.RegionClient(s.logger.With(), 3, 30*time.Second)
 
 	// 从Azure获取区域信息
 	azureRegions, err := fetcher.GetRegions(ctx, cred, subscriptionID)
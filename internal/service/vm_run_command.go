@@ -0,0 +1,401 @@
+package service
+
+import (
+	v1 "azure-vm-backend/api/v1"
+	"azure-vm-backend/internal/model"
+	"azure-vm-backend/internal/repository"
+	"azure-vm-backend/pkg/app"
+	"azure-vm-backend/pkg/azure"
+	"context"
+	"fmt"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"strings"
+	"time"
+)
+
+const (
+	// maxBatchRunCommandVMs 单次批量执行最多匹配的虚拟机数量
+	maxBatchRunCommandVMs = 50
+	// maxRunCommandScriptSize Run Command 脚本的最大长度
+	maxRunCommandScriptSize = 256 * 1024
+)
+
+type VmRunCommandService interface {
+	CreateSnippet(ctx context.Context, userID string, req *v1.CreateScriptSnippetRequest) (*model.VmScriptSnippet, error)
+	UpdateSnippet(ctx context.Context, userID, snippetID string, req *v1.UpdateScriptSnippetRequest) (*model.VmScriptSnippet, error)
+	DeleteSnippet(ctx context.Context, userID, snippetID string) error
+	ListSnippets(ctx context.Context, userID string) ([]*model.VmScriptSnippet, error)
+
+	// RunCommand 在后台通过 Run Command 执行脚本，返回执行记录，结果写入执行记录
+	RunCommand(ctx context.Context, userID, accountID, id string, req *v1.RunCommandRequest) (*model.VmCommandExecution, error)
+	// BatchRunCommand 在所有匹配查询条件的运行中虚拟机上执行脚本，其余虚拟机跳过
+	BatchRunCommand(ctx context.Context, userID string, req *v1.BatchRunCommandRequest) (*v1.BatchRunCommandResponse, error)
+	// ListExecutions 分页查询虚拟机的执行记录
+	ListExecutions(ctx context.Context, userID, accountID, id string, req *v1.ListCommandExecutionsRequest) (*app.ListResult[*model.VmCommandExecution], error)
+	GetExecution(ctx context.Context, userID, executionID string) (*model.VmCommandExecution, error)
+	ListBatchExecutions(ctx context.Context, userID, batchID string) ([]*model.VmCommandExecution, error)
+	// FailInterruptedExecutions 服务启动时将重启前未结束的执行记录标记为失败
+	FailInterruptedExecutions(ctx context.Context) error
+}
+
+func NewVmRunCommandService(
+	service *Service,
+	vmRunCommandRepository repository.VmRunCommandRepository,
+	virtualMachineRepository repository.VirtualMachineRepository,
+	accountsRepository repository.AccountsRepository,
+	operationService OperationService,
+) VmRunCommandService {
+	return &vmRunCommandService{
+		Service:                  service,
+		vmRunCommandRepository:   vmRunCommandRepository,
+		virtualMachineRepository: virtualMachineRepository,
+		accountsRepository:       accountsRepository,
+		operationService:         operationService,
+	}
+}
+
+type vmRunCommandService struct {
+	*Service
+	vmRunCommandRepository   repository.VmRunCommandRepository
+	virtualMachineRepository repository.VirtualMachineRepository
+	accountsRepository       repository.AccountsRepository
+	operationService         OperationService
+}
+
+// CreateSnippet 保存脚本片段
+func (s *vmRunCommandService) CreateSnippet(ctx context.Context, userID string, req *v1.CreateScriptSnippetRequest) (*model.VmScriptSnippet, error) {
+	if err := validateScript(req.Script); err != nil {
+		return nil, err
+	}
+	snippet := &model.VmScriptSnippet{
+		SnippetID:   uuid.New().String(),
+		UserID:      userID,
+		Name:        req.Name,
+		OSType:      req.OSType,
+		Script:      req.Script,
+		Description: req.Description,
+	}
+	if err := s.vmRunCommandRepository.CreateSnippet(ctx, snippet); err != nil {
+		s.logger.Error("创建脚本片段失败", zap.Error(err), zap.String("userId", userID))
+		return nil, v1.ErrInternalServerError
+	}
+	return snippet, nil
+}
+
+// UpdateSnippet 更新脚本片段
+func (s *vmRunCommandService) UpdateSnippet(ctx context.Context, userID, snippetID string, req *v1.UpdateScriptSnippetRequest) (*model.VmScriptSnippet, error) {
+	if _, err := s.getSnippet(ctx, userID, snippetID); err != nil {
+		return nil, err
+	}
+
+	fields := make(map[string]interface{})
+	if req.Name != nil {
+		if *req.Name == "" {
+			return nil, v1.ErrInvalidParams.WithDetail("名称不能为空")
+		}
+		fields["name"] = *req.Name
+	}
+	if req.OSType != nil {
+		fields["os_type"] = *req.OSType
+	}
+	if req.Script != nil {
+		if err := validateScript(*req.Script); err != nil {
+			return nil, err
+		}
+		fields["script"] = *req.Script
+	}
+	if req.Description != nil {
+		fields["description"] = *req.Description
+	}
+	if len(fields) > 0 {
+		if err := s.vmRunCommandRepository.UpdateSnippet(ctx, userID, snippetID, fields); err != nil {
+			s.logger.Error("更新脚本片段失败", zap.Error(err), zap.String("snippetId", snippetID))
+			return nil, v1.ErrInternalServerError
+		}
+	}
+	return s.getSnippet(ctx, userID, snippetID)
+}
+
+// DeleteSnippet 删除脚本片段
+func (s *vmRunCommandService) DeleteSnippet(ctx context.Context, userID, snippetID string) error {
+	if _, err := s.getSnippet(ctx, userID, snippetID); err != nil {
+		return err
+	}
+	if err := s.vmRunCommandRepository.DeleteSnippet(ctx, userID, snippetID); err != nil {
+		s.logger.Error("删除脚本片段失败", zap.Error(err), zap.String("snippetId", snippetID))
+		return v1.ErrInternalServerError
+	}
+	return nil
+}
+
+// ListSnippets 获取用户的脚本片段
+func (s *vmRunCommandService) ListSnippets(ctx context.Context, userID string) ([]*model.VmScriptSnippet, error) {
+	snippets, err := s.vmRunCommandRepository.ListSnippets(ctx, userID)
+	if err != nil {
+		s.logger.Error("查询脚本片段失败", zap.Error(err), zap.String("userId", userID))
+		return nil, v1.ErrInternalServerError
+	}
+	return snippets, nil
+}
+
+// RunCommand 在单台虚拟机上执行脚本
+func (s *vmRunCommandService) RunCommand(ctx context.Context, userID, accountID, id string, req *v1.RunCommandRequest) (*model.VmCommandExecution, error) {
+	account, vm, err := s.getAccountVM(ctx, s.accountsRepository, userID, accountID, id, s.virtualMachineRepository.GetVM)
+	if err != nil {
+		return nil, err
+	}
+	script, snippet, err := s.resolveScript(ctx, userID, req)
+	if err != nil {
+		return nil, err
+	}
+	if snippet != nil && snippet.OSType != "" && !strings.EqualFold(snippet.OSType, vm.OSType) {
+		return nil, v1.ErrInvalidParams.WithDetail(fmt.Sprintf("脚本片段适用于 %s，虚拟机系统为 %s", snippet.OSType, vm.OSType))
+	}
+	if !strings.EqualFold(vm.PowerState, "running") {
+		return nil, v1.ErrVMNotRunning
+	}
+	return s.start(ctx, userID, account, vm, script, snippet, "")
+}
+
+// BatchRunCommand 按查询条件批量执行脚本
+func (s *vmRunCommandService) BatchRunCommand(ctx context.Context, userID string, req *v1.BatchRunCommandRequest) (*v1.BatchRunCommandResponse, error) {
+	script, snippet, err := s.resolveScript(ctx, userID, &req.RunCommandRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := req.Filter
+	filter.UserID = userID
+	result, err := s.virtualMachineRepository.ListVMs(ctx, vmQueryOptions(&filter, &app.QueryOption{
+		Pagination: app.Pagination{Page: 1, PageSize: maxBatchRunCommandVMs},
+		SortBy:     "id",
+		SortOrder:  "asc",
+	}))
+	if err != nil {
+		s.logger.Error("查询虚拟机列表失败", zap.Error(err), zap.String("userId", userID))
+		return nil, v1.ErrInternalServerError
+	}
+	if result.Total == 0 {
+		return nil, v1.ErrNotFound.WithDetail("没有匹配筛选条件的虚拟机")
+	}
+	if result.Total > maxBatchRunCommandVMs {
+		return nil, v1.ErrInvalidParams.WithDetail(fmt.Sprintf("匹配的虚拟机有 %d 台，单次最多 %d 台，请缩小筛选范围", result.Total, maxBatchRunCommandVMs))
+	}
+
+	resp := &v1.BatchRunCommandResponse{
+		BatchID:    uuid.New().String(),
+		Executions: []*model.VmCommandExecution{},
+		Skipped:    []*v1.BatchRunCommandSkipped{},
+	}
+	accounts := make(map[string]*model.Accounts)
+	for _, vm := range result.Items {
+		skip := func(reason string) {
+			resp.Skipped = append(resp.Skipped, &v1.BatchRunCommandSkipped{ID: vm.ID, VMID: vm.VMID, Name: vm.Name, Reason: reason})
+		}
+		if snippet != nil && snippet.OSType != "" && !strings.EqualFold(snippet.OSType, vm.OSType) {
+			skip(fmt.Sprintf("脚本片段适用于 %s，虚拟机系统为 %s", snippet.OSType, vm.OSType))
+			continue
+		}
+		if !strings.EqualFold(vm.PowerState, "running") {
+			skip("虚拟机未运行")
+			continue
+		}
+
+		account, ok := accounts[vm.AccountID]
+		if !ok {
+			account, err = s.accountsRepository.GetAccountByUserIdAndAccountId(ctx, userID, vm.AccountID)
+			if err != nil || account == nil {
+				s.logger.Error("获取账户信息失败", zap.Error(err), zap.String("accountId", vm.AccountID))
+				skip("获取账户信息失败")
+				continue
+			}
+			accounts[vm.AccountID] = account
+		}
+
+		execution, err := s.start(ctx, userID, account, vm, script, snippet, resp.BatchID)
+		if err != nil {
+			skip(err.Error())
+			continue
+		}
+		resp.Executions = append(resp.Executions, execution)
+	}
+	return resp, nil
+}
+
+// ListExecutions 查询虚拟机的执行记录
+func (s *vmRunCommandService) ListExecutions(ctx context.Context, userID, accountID, id string, req *v1.ListCommandExecutionsRequest) (*app.ListResult[*model.VmCommandExecution], error) {
+	_, vm, err := s.getAccountVM(ctx, s.accountsRepository, userID, accountID, id, s.virtualMachineRepository.GetVM)
+	if err != nil {
+		return nil, err
+	}
+	query := app.ValidateAndFillQueryOption(&app.QueryOption{
+		Pagination: app.Pagination{Page: req.Page, PageSize: req.PageSize},
+	})
+	result, err := s.vmRunCommandRepository.ListExecutions(ctx, userID, vm.VMID, query)
+	if err != nil {
+		s.logger.Error("查询执行记录失败", zap.Error(err), zap.String("vmId", vm.VMID))
+		return nil, v1.ErrInternalServerError
+	}
+	return result, nil
+}
+
+// GetExecution 获取执行记录
+func (s *vmRunCommandService) GetExecution(ctx context.Context, userID, executionID string) (*model.VmCommandExecution, error) {
+	execution, err := s.vmRunCommandRepository.GetExecution(ctx, userID, executionID)
+	if err != nil {
+		s.logger.Error("获取执行记录失败", zap.Error(err), zap.String("executionId", executionID))
+		return nil, v1.ErrInternalServerError
+	}
+	if execution == nil {
+		return nil, v1.ErrNotFound
+	}
+	return execution, nil
+}
+
+// ListBatchExecutions 获取批次内的执行记录
+func (s *vmRunCommandService) ListBatchExecutions(ctx context.Context, userID, batchID string) ([]*model.VmCommandExecution, error) {
+	executions, err := s.vmRunCommandRepository.ListBatchExecutions(ctx, userID, batchID)
+	if err != nil {
+		s.logger.Error("查询批量执行记录失败", zap.Error(err), zap.String("batchId", batchID))
+		return nil, v1.ErrInternalServerError
+	}
+	if len(executions) == 0 {
+		return nil, v1.ErrNotFound
+	}
+	return executions, nil
+}
+
+// FailInterruptedExecutions 标记中断的执行记录
+// Run Command 没有可持久化的恢复令牌，对应的后台操作在服务重启时按失败处理
+func (s *vmRunCommandService) FailInterruptedExecutions(ctx context.Context) error {
+	count, err := s.vmRunCommandRepository.FailInterruptedExecutions(ctx, "服务重启，执行结果未知")
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		s.logger.Warn("已将中断的脚本执行记录标记为失败", zap.Int64("count", count))
+	}
+	return nil
+}
+
+// start 创建执行记录并提交后台操作，提交失败时将执行记录标记为失败
+func (s *vmRunCommandService) start(ctx context.Context, userID string, account *model.Accounts, vm *model.VirtualMachine, script string, snippet *model.VmScriptSnippet, batchID string) (*model.VmCommandExecution, error) {
+	execution := &model.VmCommandExecution{
+		ExecutionID: uuid.New().String(),
+		UserID:      userID,
+		AccountID:   vm.AccountID,
+		VMID:        vm.VMID,
+		VMName:      vm.Name,
+		BatchID:     batchID,
+		CommandID:   azure.RunCommandID(vm.OSType),
+		Script:      script,
+		Status:      model.CommandStatusRunning,
+		StartedAt:   time.Now(),
+	}
+	if snippet != nil {
+		execution.SnippetID = snippet.SnippetID
+	}
+	if err := s.vmRunCommandRepository.CreateExecution(ctx, execution); err != nil {
+		s.logger.Error("创建执行记录失败", zap.Error(err), zap.String("vmId", vm.VMID))
+		return nil, v1.ErrInternalServerError
+	}
+
+	target := operationTarget(vm)
+	target.OSType = vm.OSType
+	finished := *execution
+	op, err := s.operationService.StartTask(ctx, userID, account, vm, v1.VMOperationRunCommand, "", func(ctx context.Context, fetcher azure.VMClient) (string, error) {
+		result, err := fetcher.RunCommand(ctx, target, script)
+		now := time.Now()
+		finished.FinishedAt = &now
+		if err != nil {
+			finished.Status = model.CommandStatusFailed
+			finished.Error = v1.FromAzureError(err).Error()
+		} else {
+			finished.Status = model.CommandStatusSucceeded
+			if !result.Succeeded {
+				finished.Status = model.CommandStatusFailed
+			}
+			finished.ExitCode = result.ExitCode
+			finished.Stdout = result.Stdout
+			finished.Stderr = result.Stderr
+		}
+		if saveErr := s.vmRunCommandRepository.FinishExecution(ctx, &finished); saveErr != nil {
+			s.logger.Error("更新执行记录失败", zap.Error(saveErr), zap.String("executionId", finished.ExecutionID))
+		}
+		if err != nil {
+			return "", err
+		}
+		return runCommandSummary(result), nil
+	})
+	if err != nil {
+		now := time.Now()
+		execution.Status = model.CommandStatusFailed
+		execution.Error = err.Error()
+		execution.FinishedAt = &now
+		if saveErr := s.vmRunCommandRepository.FinishExecution(ctx, execution); saveErr != nil {
+			s.logger.Error("更新执行记录失败", zap.Error(saveErr), zap.String("executionId", execution.ExecutionID))
+		}
+		return nil, err
+	}
+
+	execution.OperationID = op.OperationID
+	if err := s.vmRunCommandRepository.SetExecutionOperation(ctx, execution.ExecutionID, op.OperationID); err != nil {
+		s.logger.Error("关联执行记录与操作失败", zap.Error(err), zap.String("executionId", execution.ExecutionID))
+	}
+	return execution, nil
+}
+
+// resolveScript 返回要执行的脚本，引用脚本片段时同时返回片段
+func (s *vmRunCommandService) resolveScript(ctx context.Context, userID string, req *v1.RunCommandRequest) (string, *model.VmScriptSnippet, error) {
+	if (req.Script == "") == (req.SnippetID == "") {
+		return "", nil, v1.ErrInvalidParams.WithDetail("script 和 snippetId 必须且只能指定一个")
+	}
+	if req.SnippetID == "" {
+		if err := validateScript(req.Script); err != nil {
+			return "", nil, err
+		}
+		return req.Script, nil, nil
+	}
+	snippet, err := s.getSnippet(ctx, userID, req.SnippetID)
+	if err != nil {
+		return "", nil, err
+	}
+	return snippet.Script, snippet, nil
+}
+
+// getSnippet 获取用户的脚本片段
+func (s *vmRunCommandService) getSnippet(ctx context.Context, userID, snippetID string) (*model.VmScriptSnippet, error) {
+	snippet, err := s.vmRunCommandRepository.GetSnippet(ctx, userID, snippetID)
+	if err != nil {
+		s.logger.Error("获取脚本片段失败", zap.Error(err), zap.String("snippetId", snippetID))
+		return nil, v1.ErrInternalServerError
+	}
+	if snippet == nil {
+		return nil, v1.ErrNotFound.WithDetail("脚本片段不存在")
+	}
+	return snippet, nil
+}
+
+// validateScript 校验脚本内容
+func validateScript(script string) error {
+	if strings.TrimSpace(script) == "" {
+		return v1.ErrInvalidParams.WithDetail("脚本不能为空")
+	}
+	if len(script) > maxRunCommandScriptSize {
+		return v1.ErrInvalidParams.WithDetail(fmt.Sprintf("脚本长度不能超过 %d 字节", maxRunCommandScriptSize))
+	}
+	return nil
+}
+
+// runCommandSummary 生成操作的结果描述
+func runCommandSummary(result *azure.RunCommandResult) string {
+	status := "脚本执行完成"
+	if !result.Succeeded {
+		status = "脚本执行失败"
+	}
+	if result.ExitCode != nil {
+		return fmt.Sprintf("%s，退出码 %d", status, *result.ExitCode)
+	}
+	return status
+}
@@ -1,15 +1,17 @@
 package service
 
 import (
+	v1 "azure-vm-backend/api/v1"
 	"azure-vm-backend/internal/model"
 	"azure-vm-backend/internal/repository"
 	"azure-vm-backend/pkg/azure"
 	"context"
 	"fmt"
+	"strings"
 )
 
 type VmSizeService interface {
-	ListVmSizes(ctx context.Context, location string) ([]*model.VmSize, error)
+	ListVmSizes(ctx context.Context, userId, accountId, subscriptionId, location string) ([]*model.VmSize, error)
 	SyncVmSizes(ctx context.Context, userId, accountId, subscriptionId, location string) error
 }
 
@@ -18,6 +20,7 @@ type vmSizeService struct {
 	vmSizeRepository        repository.VmSizeRepository
 	accountsRepository      repository.AccountsRepository
 	subscriptionsRepository repository.SubscriptionsRepository
+	azureProvider           azure.Provider
 }
 
 func NewVmSizeService(
@@ -25,17 +28,19 @@ func NewVmSizeService(
 	vmSizeRepository repository.VmSizeRepository,
 	accountsRepository repository.AccountsRepository,
 	subscriptionsRepository repository.SubscriptionsRepository,
+	azureProvider azure.Provider,
 ) VmSizeService {
 	return &vmSizeService{
 		Service:                 service,
 		vmSizeRepository:        vmSizeRepository,
 		accountsRepository:      accountsRepository,
 		subscriptionsRepository: subscriptionsRepository,
+		azureProvider:           azureProvider,
 	}
 }
 
-func (s *vmSizeService) ListVmSizes(ctx context.Context, location string) ([]*model.VmSize, error) {
-	return s.vmSizeRepository.ListVmSizes(ctx, location)
+func (s *vmSizeService) ListVmSizes(ctx context.Context, userId, accountId, subscriptionId, location string) ([]*model.VmSize, error) {
+	return s.vmSizeRepository.ListVmSizes(ctx, userId, accountId, subscriptionId, location)
 }
 
 func (s *vmSizeService) SyncVmSizes(ctx context.Context, userId, accountId, subscriptionId, location string) error {
@@ -49,7 +54,7 @@ func (s *vmSizeService) SyncVmSizes(ctx context.Context, userId, accountId, subs
 	}
 
 	// 验证订阅
-	subscription, err := s.subscriptionsRepository.GetSubscription(ctx, accountId, subscriptionId)
+	subscription, err := s.subscriptionsRepository.GetSubscription(ctx, userId, accountId, subscriptionId)
 	if err != nil {
 		return fmt.Errorf("获取订阅信息失败: %w", err)
 	}
@@ -57,19 +62,24 @@ func (s *vmSizeService) SyncVmSizes(ctx context.Context, userId, accountId, subs
 		return fmt.Errorf("订阅不存在")
 	}
 
+	creds, err := s.accountCredentials(account)
+	if err != nil {
+		return err
+	}
+
 	// 创建 Azure 客户端
-	fetcher := azure.NewVMSizeFetcher(
+	fetcher := s.azureProvider.SizeClient(
 		subscriptionId,
 		&azure.AzureCredential{
-			TenantID:     account.Tenant,
-			ClientID:     account.AppID,
-			ClientSecret: account.PassWord,
+			TenantID:     creds.TenantID,
+			ClientID:     creds.ClientID,
+			ClientSecret: creds.ClientSecret,
 		},
 		s.logger.With(),
 	)
 
-	// 获取规格信息
-	sizes, err := fetcher.ListSizes(ctx, location)
+	// 通过 Resource SKU 获取规格信息，包含可用区和订阅级别的限制
+	sizes, err := fetcher.ListResourceSKUs(ctx, location)
 	if err != nil {
 		return fmt.Errorf("获取规格列表失败: %w", err)
 	}
@@ -87,14 +97,31 @@ func (s *vmSizeService) SyncVmSizes(ctx context.Context, userId, accountId, subs
 			Category:     size.Category,
 			Family:       size.Family,
 			Enabled:      true,
+
+			QuotaFamily:           size.QuotaFamily,
+			Zones:                 strings.Join(size.Zones, ","),
+			AcceleratedNetworking: size.AcceleratedNetworking,
+			PremiumIO:             size.PremiumIO,
+			HyperVGenerations:     strings.Join(size.HyperVGenerations, ","),
+			Restricted:            size.Restricted,
+			RestrictionReason:     size.RestrictionReason,
 		}
 		dbSizes = append(dbSizes, dbSize)
 	}
 
 	// 更新数据库
-	if err := s.vmSizeRepository.BatchUpsertVmSizes(ctx, dbSizes); err != nil {
+	if err := s.vmSizeRepository.BatchUpsertVmSizes(ctx, accountId, subscriptionId, dbSizes); err != nil {
 		return fmt.Errorf("更新数据库失败: %w", err)
 	}
 
 	return nil
 }
+
+// restrictedSizeError 规格在区域内对订阅受限时返回的错误
+func restrictedSizeError(size *model.VmSize) error {
+	detail := fmt.Sprintf("规格 %s 在区域 %s 对当前订阅不可用", size.Name, size.Location)
+	if size.RestrictionReason != "" {
+		detail += fmt.Sprintf(" (%s)", size.RestrictionReason)
+	}
+	return v1.ErrVMSizeUnavailable.WithDetail(detail)
+}
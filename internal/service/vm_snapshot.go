@@ -0,0 +1,298 @@
+package service
+
+import (
+	v1 "azure-vm-backend/api/v1"
+	"azure-vm-backend/internal/model"
+	"azure-vm-backend/internal/repository"
+	"azure-vm-backend/pkg/azure"
+	"context"
+	"fmt"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"strings"
+	"time"
+)
+
+// defaultSnapshotRetention 未指定时每台虚拟机保留的快照数量
+const defaultSnapshotRetention = 7
+
+type VmSnapshotService interface {
+	// CreateSnapshot 在后台为系统盘或数据磁盘创建增量快照，完成后按保留数量删除旧快照
+	CreateSnapshot(ctx context.Context, userID, accountID, id string, req *v1.CreateSnapshotRequest) (*model.Operation, error)
+	// ListSnapshots 获取虚拟机的快照，refresh 为 true 时先与Azure上的快照对账
+	ListSnapshots(ctx context.Context, userID, accountID, id string, refresh bool) ([]*model.VmSnapshot, error)
+	// DeleteSnapshot 删除快照
+	DeleteSnapshot(ctx context.Context, userID, accountID, id, snapshotID string) error
+	// RestoreSnapshot 在后台从系统盘快照创建新磁盘并替换虚拟机的系统盘
+	RestoreSnapshot(ctx context.Context, userID, accountID, id, snapshotID string) (*model.Operation, error)
+}
+
+func NewVmSnapshotService(
+	service *Service,
+	vmSnapshotRepository repository.VmSnapshotRepository,
+	virtualMachineRepository repository.VirtualMachineRepository,
+	accountsRepository repository.AccountsRepository,
+	operationService OperationService,
+	azureProvider azure.Provider,
+) VmSnapshotService {
+	return &vmSnapshotService{
+		Service:                  service,
+		vmSnapshotRepository:     vmSnapshotRepository,
+		virtualMachineRepository: virtualMachineRepository,
+		accountsRepository:       accountsRepository,
+		operationService:         operationService,
+		azureProvider:            azureProvider,
+	}
+}
+
+type vmSnapshotService struct {
+	*Service
+	vmSnapshotRepository     repository.VmSnapshotRepository
+	virtualMachineRepository repository.VirtualMachineRepository
+	accountsRepository       repository.AccountsRepository
+	operationService         OperationService
+	azureProvider            azure.Provider
+}
+
+// CreateSnapshot 创建快照
+func (s *vmSnapshotService) CreateSnapshot(ctx context.Context, userID, accountID, id string, req *v1.CreateSnapshotRequest) (*model.Operation, error) {
+	account, vm, err := s.getAccountVM(ctx, s.accountsRepository, userID, accountID, id, s.virtualMachineRepository.GetVM)
+	if err != nil {
+		return nil, err
+	}
+	if req.DiskName != "" {
+		disks, err := decodeDiskInfo(vm.DataDisks)
+		if err != nil {
+			s.logger.Warn("解析虚拟机磁盘信息失败", zap.Error(err), zap.String("vmId", vm.VMID))
+		} else if findDataDisk(disks, req.DiskName) == nil {
+			return nil, v1.ErrNotFound.WithDetail(fmt.Sprintf("虚拟机未挂载磁盘: %s", req.DiskName))
+		}
+	}
+	retention := req.Retention
+	if retention <= 0 {
+		retention = defaultSnapshotRetention
+	}
+
+	target := operationTarget(vm)
+	diskName, name := req.DiskName, req.Name
+	return s.operationService.StartTask(ctx, userID, account, vm, v1.VMOperationSnapshot, "", func(ctx context.Context, fetcher azure.VMClient) (string, error) {
+		info, err := fetcher.CreateSnapshot(ctx, target, diskName, name)
+		if err != nil {
+			return "", err
+		}
+		snapshot := toSnapshotModel(vm, info)
+		if err := s.vmSnapshotRepository.Create(ctx, snapshot); err != nil {
+			s.logger.Error("保存快照记录失败", zap.Error(err), zap.String("snapshot", info.ID))
+			return "", err
+		}
+
+		pruned := s.prune(ctx, fetcher, userID, vm.VMID, retention)
+		if pruned > 0 {
+			return fmt.Sprintf("快照 %s 已创建，已删除 %d 个超出保留数量的旧快照", info.Name, pruned), nil
+		}
+		return fmt.Sprintf("快照 %s 已创建", info.Name), nil
+	})
+}
+
+// ListSnapshots 获取虚拟机的快照
+func (s *vmSnapshotService) ListSnapshots(ctx context.Context, userID, accountID, id string, refresh bool) ([]*model.VmSnapshot, error) {
+	account, vm, err := s.getAccountVM(ctx, s.accountsRepository, userID, accountID, id, s.virtualMachineRepository.GetVM)
+	if err != nil {
+		return nil, err
+	}
+	if refresh {
+		if err := s.reconcile(ctx, userID, account, vm); err != nil {
+			return nil, err
+		}
+	}
+
+	snapshots, err := s.vmSnapshotRepository.ListByVMID(ctx, userID, vm.VMID)
+	if err != nil {
+		s.logger.Error("查询快照列表失败", zap.Error(err), zap.String("vmId", vm.VMID))
+		return nil, v1.ErrInternalServerError
+	}
+	return snapshots, nil
+}
+
+// DeleteSnapshot 删除Azure快照及其记录
+func (s *vmSnapshotService) DeleteSnapshot(ctx context.Context, userID, accountID, id, snapshotID string) error {
+	account, vm, err := s.getAccountVM(ctx, s.accountsRepository, userID, accountID, id, s.virtualMachineRepository.GetVM)
+	if err != nil {
+		return err
+	}
+	snapshot, err := s.getSnapshot(ctx, userID, vm, snapshotID)
+	if err != nil {
+		return err
+	}
+
+	fetcher, err := s.vmClient(s.azureProvider, account)
+	if err != nil {
+		return err
+	}
+	if err := fetcher.DeleteSnapshot(ctx, snapshot.SubscriptionID, snapshot.ResourceGroup, snapshot.Name); err != nil {
+		s.logger.Error("删除快照失败", zap.Error(err), zap.String("snapshotId", snapshotID))
+		return v1.FromAzureError(err)
+	}
+	if err := s.vmSnapshotRepository.Delete(ctx, snapshotID); err != nil {
+		s.logger.Error("删除快照记录失败", zap.Error(err), zap.String("snapshotId", snapshotID))
+		return v1.ErrInternalServerError
+	}
+	return nil
+}
+
+// RestoreSnapshot 从系统盘快照恢复
+func (s *vmSnapshotService) RestoreSnapshot(ctx context.Context, userID, accountID, id, snapshotID string) (*model.Operation, error) {
+	account, vm, err := s.getAccountVM(ctx, s.accountsRepository, userID, accountID, id, s.virtualMachineRepository.GetVM)
+	if err != nil {
+		return nil, err
+	}
+	snapshot, err := s.getSnapshot(ctx, userID, vm, snapshotID)
+	if err != nil {
+		return nil, err
+	}
+	if snapshot.DiskRole != azure.SnapshotDiskRoleOS {
+		return nil, v1.ErrInvalidParams.WithDetail("只能使用系统盘快照恢复系统盘")
+	}
+
+	target := operationTarget(vm)
+	return s.operationService.StartTask(ctx, userID, account, vm, v1.VMOperationRestoreSnapshot, "Restoring", func(ctx context.Context, fetcher azure.VMClient) (string, error) {
+		result, err := fetcher.RestoreOSDisk(ctx, target, snapshot.ResourceID)
+		if err != nil {
+			// 失败时回写虚拟机的实际电源状态
+			if powerState, statusErr := fetcher.GetVMStatus(ctx, target.SubscriptionID, target.ResourceGroup, target.Name); statusErr == nil {
+				if err := s.virtualMachineRepository.UpdateStatus(ctx, vm.VMID, powerState); err != nil {
+					s.logger.Error("更新虚拟机状态失败", zap.Error(err), zap.String("vmId", vm.VMID))
+				}
+			}
+			return "", err
+		}
+
+		data, err := encodeDiskInfo(result.Disks.OSDiskSize, result.Disks.DataDisks)
+		if err == nil {
+			err = s.virtualMachineRepository.UpdateDisks(ctx, vm.VMID, int(result.Disks.OSDiskSize), data)
+		}
+		if err != nil {
+			s.logger.Error("更新虚拟机磁盘信息失败", zap.Error(err), zap.String("vmId", vm.VMID))
+		}
+		if result.PowerState != "" {
+			if err := s.virtualMachineRepository.UpdateStatus(ctx, vm.VMID, result.PowerState); err != nil {
+				s.logger.Error("更新虚拟机状态失败", zap.Error(err), zap.String("vmId", vm.VMID))
+			}
+		}
+		return fmt.Sprintf("系统盘已从快照 %s 恢复，原系统盘 %s 已保留", snapshot.Name, result.OldOSDiskName), nil
+	})
+}
+
+// prune 删除超出保留数量的旧快照，返回删除数量；单个快照删除失败时保留记录以便下次重试
+func (s *vmSnapshotService) prune(ctx context.Context, fetcher azure.VMClient, userID, vmID string, retention int) int {
+	snapshots, err := s.vmSnapshotRepository.ListByVMID(ctx, userID, vmID)
+	if err != nil {
+		s.logger.Error("查询快照列表失败", zap.Error(err), zap.String("vmId", vmID))
+		return 0
+	}
+	if len(snapshots) <= retention {
+		return 0
+	}
+
+	pruned := 0
+	for _, snapshot := range snapshots[retention:] {
+		if err := fetcher.DeleteSnapshot(ctx, snapshot.SubscriptionID, snapshot.ResourceGroup, snapshot.Name); err != nil {
+			s.logger.Warn("删除旧快照失败",
+				zap.Error(err),
+				zap.String("vmId", vmID),
+				zap.String("snapshot", snapshot.Name))
+			continue
+		}
+		if err := s.vmSnapshotRepository.Delete(ctx, snapshot.SnapshotID); err != nil {
+			s.logger.Error("删除快照记录失败", zap.Error(err), zap.String("snapshotId", snapshot.SnapshotID))
+			continue
+		}
+		pruned++
+	}
+	return pruned
+}
+
+// reconcile 以Azure上的快照为准更新记录：补充未记录的快照，删除Azure上已不存在的记录
+func (s *vmSnapshotService) reconcile(ctx context.Context, userID string, account *model.Accounts, vm *model.VirtualMachine) error {
+	fetcher, err := s.vmClient(s.azureProvider, account)
+	if err != nil {
+		return err
+	}
+	remote, err := fetcher.ListSnapshots(ctx, operationTarget(vm))
+	if err != nil {
+		s.logger.Error("获取Azure快照列表失败", zap.Error(err), zap.String("vmId", vm.VMID))
+		return v1.FromAzureError(err)
+	}
+	local, err := s.vmSnapshotRepository.ListByVMID(ctx, userID, vm.VMID)
+	if err != nil {
+		s.logger.Error("查询快照列表失败", zap.Error(err), zap.String("vmId", vm.VMID))
+		return v1.ErrInternalServerError
+	}
+
+	return s.tm.Transaction(ctx, func(ctx context.Context) error {
+		present := make(map[string]bool, len(remote))
+		for _, info := range remote {
+			present[strings.ToLower(info.ID)] = true
+		}
+		known := make(map[string]bool, len(local))
+		for _, snapshot := range local {
+			known[strings.ToLower(snapshot.ResourceID)] = true
+			if present[strings.ToLower(snapshot.ResourceID)] {
+				continue
+			}
+			if err := s.vmSnapshotRepository.Delete(ctx, snapshot.SnapshotID); err != nil {
+				s.logger.Error("删除快照记录失败", zap.Error(err), zap.String("snapshotId", snapshot.SnapshotID))
+				return v1.ErrInternalServerError
+			}
+		}
+		for i := range remote {
+			if known[strings.ToLower(remote[i].ID)] {
+				continue
+			}
+			if err := s.vmSnapshotRepository.Create(ctx, toSnapshotModel(vm, &remote[i])); err != nil {
+				s.logger.Error("保存快照记录失败", zap.Error(err), zap.String("snapshot", remote[i].ID))
+				return v1.ErrInternalServerError
+			}
+		}
+		return nil
+	})
+}
+
+// getSnapshot 获取属于该虚拟机的快照
+func (s *vmSnapshotService) getSnapshot(ctx context.Context, userID string, vm *model.VirtualMachine, snapshotID string) (*model.VmSnapshot, error) {
+	snapshot, err := s.vmSnapshotRepository.GetBySnapshotID(ctx, userID, snapshotID)
+	if err != nil {
+		s.logger.Error("获取快照失败", zap.Error(err), zap.String("snapshotId", snapshotID))
+		return nil, v1.ErrInternalServerError
+	}
+	if snapshot == nil || snapshot.VMID != vm.VMID {
+		return nil, v1.ErrNotFound
+	}
+	return snapshot, nil
+}
+
+// toSnapshotModel 将Azure快照转换为快照记录，快照与虚拟机在同一订阅
+func toSnapshotModel(vm *model.VirtualMachine, info *azure.SnapshotInfo) *model.VmSnapshot {
+	snapshotTime := info.CreatedTime
+	if snapshotTime.IsZero() {
+		snapshotTime = time.Now()
+	}
+	resourceGroup := info.ResourceGroup
+	if resourceGroup == "" {
+		resourceGroup = vm.ResourceGroup
+	}
+	return &model.VmSnapshot{
+		SnapshotID:     uuid.New().String(),
+		AccountID:      vm.AccountID,
+		VMID:           vm.VMID,
+		SubscriptionID: vm.SubscriptionID,
+		ResourceGroup:  resourceGroup,
+		Name:           info.Name,
+		ResourceID:     info.ID,
+		Location:       info.Location,
+		SourceDiskName: info.SourceDiskName,
+		DiskRole:       info.DiskRole,
+		SizeGB:         int(info.SizeGB),
+		SnapshotTime:   snapshotTime,
+	}
+}
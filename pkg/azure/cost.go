@@ -0,0 +1,229 @@
+package azure
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
+	"go.uber.org/zap"
+)
+
+const (
+	costManagementAPIVersion = "2023-03-01"
+	// costQueryMaxPages 防止分页链接异常时无限请求
+	costQueryMaxPages = 200
+)
+
+// CostFetcher 通过 Cost Management query API 获取订阅的实际费用
+type CostFetcher struct {
+	subscriptionID string
+	credentials    *AzureCredential
+	logger         *zap.Logger
+}
+
+// NewCostFetcher 创建CostFetcher实例
+func NewCostFetcher(subscriptionID string, credentials *AzureCredential, logger *zap.Logger) *CostFetcher {
+	return &CostFetcher{
+		subscriptionID: subscriptionID,
+		credentials:    credentials,
+		logger:         logger,
+	}
+}
+
+// CostRecord 单个资源的单个计量在一天内的实际费用
+type CostRecord struct {
+	UsageDate  time.Time // UTC 零点
+	ResourceID string    // 小写的资源ID，订阅级别的费用(例如支持计划)为空
+	Meter      string
+	Cost       float64
+	Currency   string
+}
+
+// costQuery 按天、资源ID和计量分组的实际费用查询
+type costQuery struct {
+	Type       string         `json:"type"`
+	Timeframe  string         `json:"timeframe"`
+	TimePeriod costTimePeriod `json:"timePeriod"`
+	Dataset    costDataset    `json:"dataset"`
+}
+
+type costTimePeriod struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+type costDataset struct {
+	Granularity string                     `json:"granularity"`
+	Aggregation map[string]costAggregation `json:"aggregation"`
+	Grouping    []costGrouping             `json:"grouping"`
+}
+
+type costAggregation struct {
+	Name     string `json:"name"`
+	Function string `json:"function"`
+}
+
+type costGrouping struct {
+	Type string `json:"type"`
+	Name string `json:"name"`
+}
+
+// costQueryResult query API 的响应，rows 中各列的顺序由 columns 决定
+type costQueryResult struct {
+	Properties struct {
+		NextLink string          `json:"nextLink"`
+		Columns  []costColumn    `json:"columns"`
+		Rows     [][]interface{} `json:"rows"`
+	} `json:"properties"`
+}
+
+type costColumn struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// QueryDailyCosts 查询订阅在 [from, to] 内每天按资源和计量分组的实际费用，日期按UTC计算
+func (f *CostFetcher) QueryDailyCosts(ctx context.Context, from, to time.Time) ([]*CostRecord, error) {
+	credential, err := f.credentials.GetCredential()
+	if err != nil {
+		return nil, fmt.Errorf("获取认证对象失败: %w", err)
+	}
+
+	client, err := arm.NewClient("azure-vm-backend/costmanagement", "v1.0.0", credential, nil)
+	if err != nil {
+		return nil, fmt.Errorf("创建费用客户端失败: %w", err)
+	}
+
+	query := costQuery{
+		Type:      "ActualCost",
+		Timeframe: "Custom",
+		TimePeriod: costTimePeriod{
+			From: from.UTC().Format("2006-01-02") + "T00:00:00Z",
+			To:   to.UTC().Format("2006-01-02") + "T23:59:59Z",
+		},
+		Dataset: costDataset{
+			Granularity: "Daily",
+			Aggregation: map[string]costAggregation{
+				"totalCost": {Name: "Cost", Function: "Sum"},
+			},
+			Grouping: []costGrouping{
+				{Type: "Dimension", Name: "ResourceId"},
+				{Type: "Dimension", Name: "Meter"},
+			},
+		},
+	}
+
+	// 下一页同样使用 POST 并携带相同的查询条件
+	next := fmt.Sprintf("%s/subscriptions/%s/providers/Microsoft.CostManagement/query?api-version=%s",
+		strings.TrimSuffix(client.Endpoint(), "/"), f.subscriptionID, costManagementAPIVersion)
+	var records []*CostRecord
+	for page := 0; next != ""; page++ {
+		if page >= costQueryMaxPages {
+			return nil, fmt.Errorf("费用分页超过 %d 页", costQueryMaxPages)
+		}
+		req, err := runtime.NewRequest(ctx, http.MethodPost, next)
+		if err != nil {
+			return nil, fmt.Errorf("创建费用查询请求失败: %w", err)
+		}
+		if err := runtime.MarshalAsJSON(req, query); err != nil {
+			return nil, fmt.Errorf("序列化费用查询失败: %w", err)
+		}
+		resp, err := client.Pipeline().Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("查询费用失败: %w", err)
+		}
+		if !runtime.HasStatusCode(resp, http.StatusOK) {
+			return nil, fmt.Errorf("查询费用失败: %w", runtime.NewResponseError(resp))
+		}
+
+		var result costQueryResult
+		if err := runtime.UnmarshalAsJSON(resp, &result); err != nil {
+			return nil, fmt.Errorf("解析费用失败: %w", err)
+		}
+		pageRecords, err := parseCostRows(&result)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, pageRecords...)
+		next = result.Properties.NextLink
+	}
+
+	f.logger.Debug("查询费用完成",
+		zap.String("subscriptionId", f.subscriptionID),
+		zap.Time("from", from),
+		zap.Time("to", to),
+		zap.Int("count", len(records)))
+	return records, nil
+}
+
+// parseCostRows 按列名解析查询结果，费用列名为 Cost 或 PreTaxCost
+func parseCostRows(result *costQueryResult) ([]*CostRecord, error) {
+	index := make(map[string]int, len(result.Properties.Columns))
+	for i, column := range result.Properties.Columns {
+		index[strings.ToLower(column.Name)] = i
+	}
+	costIndex, ok := index["cost"]
+	if !ok {
+		costIndex, ok = index["pretaxcost"]
+	}
+	dateIndex, hasDate := index["usagedate"]
+	if !ok || !hasDate {
+		return nil, fmt.Errorf("费用查询结果缺少 Cost 或 UsageDate 列")
+	}
+
+	records := make([]*CostRecord, 0, len(result.Properties.Rows))
+	for _, row := range result.Properties.Rows {
+		usageDate, err := parseUsageDate(costCell(row, dateIndex))
+		if err != nil {
+			return nil, err
+		}
+		cost, _ := costCell(row, costIndex).(float64)
+		records = append(records, &CostRecord{
+			UsageDate:  usageDate,
+			ResourceID: strings.ToLower(costString(row, index, "resourceid")),
+			Meter:      costString(row, index, "meter"),
+			Cost:       cost,
+			Currency:   costString(row, index, "currency"),
+		})
+	}
+	return records, nil
+}
+
+// parseUsageDate 按天查询时 UsageDate 为 20240131 形式的数字，部分 API 版本返回日期字符串
+func parseUsageDate(value interface{}) (time.Time, error) {
+	switch v := value.(type) {
+	case float64:
+		return time.Parse("20060102", strconv.FormatInt(int64(v), 10))
+	case string:
+		if t, err := time.Parse("20060102", v); err == nil {
+			return t, nil
+		}
+		if t, err := time.Parse("2006-01-02T15:04:05", v); err == nil {
+			return t, nil
+		}
+		return time.Parse(time.RFC3339, v)
+	default:
+		return time.Time{}, fmt.Errorf("无法解析费用日期: %v", value)
+	}
+}
+
+func costCell(row []interface{}, i int) interface{} {
+	if i < 0 || i >= len(row) {
+		return nil
+	}
+	return row[i]
+}
+
+func costString(row []interface{}, index map[string]int, name string) string {
+	i, ok := index[name]
+	if !ok {
+		return ""
+	}
+	s, _ := costCell(row, i).(string)
+	return s
+}
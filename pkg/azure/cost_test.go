@@ -0,0 +1,52 @@
+package azure
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCostRows(t *testing.T) {
+	data, err := os.ReadFile("testdata/cost_query_response.json")
+	require.NoError(t, err)
+	var result costQueryResult
+	require.NoError(t, json.Unmarshal(data, &result))
+
+	records, err := parseCostRows(&result)
+	require.NoError(t, err)
+	require.Len(t, records, 5)
+
+	vm := records[0]
+	assert.Equal(t, time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), vm.UsageDate)
+	assert.Equal(t, "/subscriptions/00000000-0000-0000-0000-000000000001/resourcegroups/rg/providers/microsoft.compute/virtualmachines/vm1", vm.ResourceID)
+	assert.Equal(t, "D2s v3", vm.Meter)
+	assert.Equal(t, 1.2432, vm.Cost)
+	assert.Equal(t, "USD", vm.Currency)
+
+	// 订阅级别的费用没有资源ID
+	assert.Empty(t, records[4].ResourceID)
+	assert.Equal(t, time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC), records[4].UsageDate)
+}
+
+func TestParseCostRows_ColumnOrder(t *testing.T) {
+	// 按列名解析，与列的顺序无关，旧版本 API 的费用列名为 PreTaxCost
+	var result costQueryResult
+	require.NoError(t, json.Unmarshal([]byte(`{"properties":{
+		"columns":[{"name":"ResourceId","type":"String"},{"name":"UsageDate","type":"String"},{"name":"PreTaxCost","type":"Number"}],
+		"rows":[["/SUBSCRIPTIONS/S/RESOURCEGROUPS/RG/PROVIDERS/MICROSOFT.COMPUTE/VIRTUALMACHINES/VM1","2024-03-05T00:00:00",3.5]]}}`), &result))
+
+	records, err := parseCostRows(&result)
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, "/subscriptions/s/resourcegroups/rg/providers/microsoft.compute/virtualmachines/vm1", records[0].ResourceID)
+	assert.Equal(t, time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC), records[0].UsageDate)
+	assert.Equal(t, 3.5, records[0].Cost)
+
+	require.NoError(t, json.Unmarshal([]byte(`{"properties":{"columns":[{"name":"Cost","type":"Number"}],"rows":[]}}`), &result))
+	_, err = parseCostRows(&result)
+	assert.Error(t, err)
+}
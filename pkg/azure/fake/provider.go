@@ -0,0 +1,733 @@
+// Package fake 提供 azure.Provider 的内存实现，用于在没有Azure租户的情况下测试服务层
+package fake
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"azure-vm-backend/pkg/azure"
+	"go.uber.org/zap"
+)
+
+// NIC 内存中的网卡
+type NIC struct {
+	ID            string
+	Name          string
+	ResourceGroup string
+	VMID          string
+	PrivateIP     string
+	PublicIPName  string
+	// SecurityGroupID 网卡关联的网络安全组
+	SecurityGroupID string
+	// SubnetSecurityGroupID 网卡所在子网关联的网络安全组
+	SubnetSecurityGroupID string
+}
+
+// defaultSubnetName 内存网卡所在的子网名称
+const defaultSubnetName = "default"
+
+// PublicIP 内存中的公网IP
+type PublicIP struct {
+	Name          string
+	ResourceGroup string
+	Location      string
+	Address       string
+	DNSLabel      string
+	FQDN          string
+}
+
+// vmAccessState 虚拟机上 VMAccess 扩展最近一次的参数和结果
+type vmAccessState struct {
+	opts   azure.VMAccessResetOptions
+	result azure.VMAccessResult
+}
+
+// pendingOperation 尚未完成的长时间操作
+type pendingOperation struct {
+	token     string
+	remaining int
+	apply     func() error
+	done      bool
+	err       error
+}
+
+// Provider azure.Provider 的内存实现
+// 所有凭据共享同一份数据，ValidSecrets 为空时任何凭据都视为有效
+type Provider struct {
+	mu sync.Mutex
+
+	subscriptions map[string]azure.SubscriptionDetail
+	vms           map[string]*azure.VMDetails // key: 小写的VM资源ID
+	nics          map[string]*NIC             // key: 网卡名称
+	publicIPs     map[string]*PublicIP        // key: 公网IP名称
+	regions       []azure.RegionInfo
+	sizes         map[string][]*azure.VMSizeInfo
+	images        map[string][]*azure.VMImageInfo
+	quotas        map[string][]*azure.QuotaUsage // key: 小写的 订阅ID/区域
+	prices        map[string][]*azure.VMPrice    // key: 区域
+	costs         map[string][]*azure.CostRecord // key: 小写的订阅ID
+	operations    map[string]*pendingOperation
+	snapshots     map[string]*azure.SnapshotInfo      // key: 小写的快照资源ID
+	osDisks       map[string]string                   // key: 小写的VM资源ID，value: 当前系统盘名称
+	nsgs          map[string]*azure.SecurityGroupInfo // key: 小写的网络安全组资源ID
+	vmAccess      map[string]*vmAccessState           // key: 小写的VM资源ID
+	bootDiag      map[string]bool                     // key: 小写的VM资源ID，已启用启动诊断的虚拟机
+
+	// ValidSecrets 允许通过验证的 ClientSecret，为空表示全部通过
+	ValidSecrets map[string]bool
+	// PollSteps 长时间操作需要轮询的次数，0 表示提交后立即完成
+	PollSteps int
+	// Errors 按方法名注入的错误，例如 "FetchVMDetails"
+	Errors map[string]error
+	// SubscriptionErrors 按订阅ID注入的虚拟机获取错误，模拟单个订阅被禁用等情况
+	SubscriptionErrors map[string]error
+	// ClusterSizes 按虚拟机名称设置当前硬件集群可直接调整的规格，未设置时为所在区域的全部规格
+	ClusterSizes map[string][]string
+	// RunCommandResults 按虚拟机名称设置 Run Command 的执行结果，未设置时返回执行成功且没有输出
+	RunCommandResults map[string]azure.RunCommandResult
+	// SerialLogs、Screenshots 按虚拟机名称设置启动诊断数据，未设置时视为尚未生成
+	SerialLogs  map[string]string
+	Screenshots map[string][]byte
+
+	nextIP int
+	nextOp int
+}
+
+var _ azure.Provider = (*Provider)(nil)
+
+// NewProvider 创建空的内存Provider
+func NewProvider() *Provider {
+	return &Provider{
+		subscriptions: make(map[string]azure.SubscriptionDetail),
+		vms:           make(map[string]*azure.VMDetails),
+		nics:          make(map[string]*NIC),
+		publicIPs:     make(map[string]*PublicIP),
+		sizes:         make(map[string][]*azure.VMSizeInfo),
+		images:        make(map[string][]*azure.VMImageInfo),
+		quotas:        make(map[string][]*azure.QuotaUsage),
+		prices:        make(map[string][]*azure.VMPrice),
+		costs:         make(map[string][]*azure.CostRecord),
+		operations:    make(map[string]*pendingOperation),
+		snapshots:     make(map[string]*azure.SnapshotInfo),
+		osDisks:       make(map[string]string),
+		nsgs:          make(map[string]*azure.SecurityGroupInfo),
+		vmAccess:      make(map[string]*vmAccessState),
+		bootDiag:      make(map[string]bool),
+		ValidSecrets:  make(map[string]bool),
+		Errors:        make(map[string]error),
+
+		SubscriptionErrors: make(map[string]error),
+		ClusterSizes:       make(map[string][]string),
+		RunCommandResults:  make(map[string]azure.RunCommandResult),
+		SerialLogs:         make(map[string]string),
+		Screenshots:        make(map[string][]byte),
+	}
+}
+
+// AddSubscription 添加订阅
+func (p *Provider) AddSubscription(sub azure.SubscriptionDetail) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if sub.State == "" {
+		sub.State = "Enabled"
+	}
+	p.subscriptions[sub.SubscriptionID] = sub
+}
+
+// RemoveSubscription 移除订阅，模拟订阅被取消或账户失去访问权限
+func (p *Provider) RemoveSubscription(subscriptionID string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.subscriptions, subscriptionID)
+}
+
+// AddVM 添加虚拟机，按 PrivateIPs/PublicIPs/PublicIPName 同时生成网卡和公网IP
+func (p *Provider) AddVM(vm azure.VMDetails) azure.VMDetails {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return *p.addVMLocked(vm)
+}
+
+func (p *Provider) addVMLocked(vm azure.VMDetails) *azure.VMDetails {
+	if vm.ID == "" {
+		vm.ID = azure.BuildVMResourceID(vm.SubscriptionID, vm.ResourceGroup, vm.Name)
+	}
+	if vm.PowerState == "" {
+		vm.PowerState = "running"
+	}
+	if vm.State == "" {
+		vm.State = "Succeeded"
+	}
+	if vm.CreatedTime.IsZero() {
+		vm.CreatedTime = time.Now()
+	}
+
+	if len(vm.PrivateIPs) == 0 {
+		vm.PrivateIPs = []string{p.allocateIP("10.0.0.")}
+	}
+	nic := &NIC{
+		ID:            fmt.Sprintf("/subscriptions/%s/resourceGroups/%s/providers/Microsoft.Network/networkInterfaces/%s-nic", vm.SubscriptionID, vm.ResourceGroup, vm.Name),
+		Name:          vm.Name + "-nic",
+		ResourceGroup: vm.ResourceGroup,
+		VMID:          vm.ID,
+		PrivateIP:     vm.PrivateIPs[0],
+	}
+	if vm.PublicIPName != "" || len(vm.PublicIPs) > 0 {
+		if vm.PublicIPName == "" {
+			vm.PublicIPName = vm.Name + "-ip"
+		}
+		address := ""
+		if len(vm.PublicIPs) > 0 {
+			address = vm.PublicIPs[0]
+		} else {
+			address = p.allocateIP("20.0.0.")
+		}
+		p.publicIPs[vm.PublicIPName] = &PublicIP{
+			Name:          vm.PublicIPName,
+			ResourceGroup: vm.ResourceGroup,
+			Location:      vm.Location,
+			Address:       address,
+		}
+		nic.PublicIPName = vm.PublicIPName
+	}
+	p.nics[nic.Name] = nic
+
+	stored := vm
+	p.vms[strings.ToLower(vm.ID)] = &stored
+	return &stored
+}
+
+func (p *Provider) allocateIP(prefix string) string {
+	p.nextIP++
+	return fmt.Sprintf("%s%d", prefix, p.nextIP+3)
+}
+
+// RemoveVM 直接从内存中移除虚拟机，模拟在Azure门户中被删除
+func (p *Provider) RemoveVM(vmID string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.removeVMLocked(strings.ToLower(vmID))
+}
+
+func (p *Provider) removeVMLocked(key string) {
+	vm, ok := p.vms[key]
+	if !ok {
+		return
+	}
+	for name, nic := range p.nics {
+		if strings.EqualFold(nic.VMID, vm.ID) {
+			delete(p.publicIPs, nic.PublicIPName)
+			delete(p.nics, name)
+		}
+	}
+	delete(p.vmAccess, key)
+	delete(p.bootDiag, key)
+	delete(p.vms, key)
+}
+
+// GetVM 获取内存中的虚拟机快照
+func (p *Provider) GetVM(vmID string) (azure.VMDetails, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	vm, ok := p.vms[strings.ToLower(vmID)]
+	if !ok {
+		return azure.VMDetails{}, false
+	}
+	return p.composeLocked(vm), true
+}
+
+// GetPublicIP 获取内存中的公网IP
+func (p *Provider) GetPublicIP(name string) (PublicIP, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	ip, ok := p.publicIPs[name]
+	if !ok {
+		return PublicIP{}, false
+	}
+	return *ip, true
+}
+
+// AddSecurityGroup 添加网络安全组并关联到虚拟机的网卡(scope 为 nic)或其所在子网(scope 为 subnet)
+// 未设置ID时按虚拟机的订阅和资源组生成，规则中会补充Azure的默认规则
+func (p *Provider) AddSecurityGroup(vmID, scope string, nsg azure.SecurityGroupInfo) azure.SecurityGroupInfo {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	vm, ok := p.vms[strings.ToLower(vmID)]
+	if !ok {
+		return azure.SecurityGroupInfo{}
+	}
+	stored := p.addSecurityGroupLocked(vm, nsg)
+	for _, nic := range p.nics {
+		if !strings.EqualFold(nic.VMID, vm.ID) {
+			continue
+		}
+		if scope == azure.SecurityGroupScopeSubnet {
+			nic.SubnetSecurityGroupID = stored.ID
+		} else {
+			nic.SecurityGroupID = stored.ID
+		}
+	}
+	return *stored
+}
+
+func (p *Provider) addSecurityGroupLocked(vm *azure.VMDetails, nsg azure.SecurityGroupInfo) *azure.SecurityGroupInfo {
+	if nsg.ResourceGroup == "" {
+		nsg.ResourceGroup = vm.ResourceGroup
+	}
+	if nsg.Location == "" {
+		nsg.Location = vm.Location
+	}
+	if nsg.ID == "" {
+		nsg.ID = fmt.Sprintf("/subscriptions/%s/resourceGroups/%s/providers/Microsoft.Network/networkSecurityGroups/%s", vm.SubscriptionID, nsg.ResourceGroup, nsg.Name)
+	}
+	nsg.Scope, nsg.AttachedTo = "", ""
+	nsg.Rules = append(append([]azure.SecurityRule(nil), nsg.Rules...), defaultSecurityRules()...)
+	p.nsgs[strings.ToLower(nsg.ID)] = &nsg
+	return &nsg
+}
+
+// GetSecurityGroup 获取内存中的网络安全组
+func (p *Provider) GetSecurityGroup(id string) (azure.SecurityGroupInfo, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	nsg, ok := p.nsgs[strings.ToLower(id)]
+	if !ok {
+		return azure.SecurityGroupInfo{}, false
+	}
+	return composeSecurityGroup(nsg, "", ""), true
+}
+
+// composeSecurityGroup 复制网络安全组并设置关联范围，规则按方向和优先级排序
+func composeSecurityGroup(nsg *azure.SecurityGroupInfo, scope, attachedTo string) azure.SecurityGroupInfo {
+	info := *nsg
+	info.Scope, info.AttachedTo = scope, attachedTo
+	info.Rules = append([]azure.SecurityRule{}, nsg.Rules...)
+	sort.SliceStable(info.Rules, func(i, j int) bool {
+		if info.Rules[i].Direction != info.Rules[j].Direction {
+			return info.Rules[i].Direction == "Inbound"
+		}
+		return info.Rules[i].Priority < info.Rules[j].Priority
+	})
+	return info
+}
+
+// defaultSecurityRules Azure为每个网络安全组内置的默认规则
+func defaultSecurityRules() []azure.SecurityRule {
+	rule := func(name, direction, access, source, destination string, priority int32) azure.SecurityRule {
+		return azure.SecurityRule{
+			Name:                     name,
+			Priority:                 priority,
+			Direction:                direction,
+			Access:                   access,
+			Protocol:                 "*",
+			SourceAddressPrefix:      source,
+			SourcePortRange:          "*",
+			DestinationAddressPrefix: destination,
+			DestinationPortRange:     "*",
+			Default:                  true,
+		}
+	}
+	return []azure.SecurityRule{
+		rule("AllowVnetInBound", "Inbound", "Allow", "VirtualNetwork", "VirtualNetwork", 65000),
+		rule("AllowAzureLoadBalancerInBound", "Inbound", "Allow", "AzureLoadBalancer", "*", 65001),
+		rule("DenyAllInBound", "Inbound", "Deny", "*", "*", 65500),
+		rule("AllowVnetOutBound", "Outbound", "Allow", "VirtualNetwork", "VirtualNetwork", 65000),
+		rule("AllowInternetOutBound", "Outbound", "Allow", "*", "Internet", 65001),
+		rule("DenyAllOutBound", "Outbound", "Deny", "*", "*", 65500),
+	}
+}
+
+// GetVMAccessReset 获取虚拟机最近一次重置访问的参数
+func (p *Provider) GetVMAccessReset(vmID string) (azure.VMAccessResetOptions, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	state, ok := p.vmAccess[strings.ToLower(vmID)]
+	if !ok {
+		return azure.VMAccessResetOptions{}, false
+	}
+	return state.opts, true
+}
+
+// AddSnapshot 添加快照，模拟在Azure门户中创建的快照
+func (p *Provider) AddSnapshot(snapshot azure.SnapshotInfo) azure.SnapshotInfo {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if snapshot.CreatedTime.IsZero() {
+		snapshot.CreatedTime = time.Now()
+	}
+	p.snapshots[strings.ToLower(snapshot.ID)] = &snapshot
+	return snapshot
+}
+
+// GetSnapshot 获取内存中的快照
+func (p *Provider) GetSnapshot(id string) (azure.SnapshotInfo, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	snapshot, ok := p.snapshots[strings.ToLower(id)]
+	if !ok {
+		return azure.SnapshotInfo{}, false
+	}
+	return *snapshot, true
+}
+
+// SetRegions 设置区域列表
+func (p *Provider) SetRegions(regions []azure.RegionInfo) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.regions = regions
+}
+
+// SetSizes 设置指定区域的规格列表
+func (p *Provider) SetSizes(location string, sizes []*azure.VMSizeInfo) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.sizes[location] = sizes
+}
+
+// SetQuotas 设置订阅在指定区域的配额，未设置时返回空列表
+func (p *Provider) SetQuotas(subscriptionID, location string, usages []*azure.QuotaUsage) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.quotas[quotaKey(subscriptionID, location)] = usages
+}
+
+func quotaKey(subscriptionID, location string) string {
+	return strings.ToLower(subscriptionID + "/" + location)
+}
+
+// SetPrices 设置指定区域的规格价格，未设置时返回空列表
+func (p *Provider) SetPrices(location string, prices []*azure.VMPrice) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.prices[location] = prices
+}
+
+// SetCosts 设置订阅的每日费用记录，查询时按日期范围过滤
+func (p *Provider) SetCosts(subscriptionID string, records []*azure.CostRecord) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.costs[strings.ToLower(subscriptionID)] = records
+}
+
+// SetImages 设置指定区域的镜像列表
+func (p *Provider) SetImages(location string, images []*azure.VMImageInfo) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.images[location] = images
+}
+
+// composeLocked 合并网卡和公网IP信息得到完整的虚拟机详情
+func (p *Provider) composeLocked(vm *azure.VMDetails) azure.VMDetails {
+	details := *vm
+	details.PrivateIPs = nil
+	details.PublicIPs = nil
+	details.PublicIPName = ""
+	details.DnsAlias = ""
+	details.SecurityGroups = nil
+	names := make([]string, 0, len(p.nics))
+	for name := range p.nics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		nic := p.nics[name]
+		if !strings.EqualFold(nic.VMID, vm.ID) {
+			continue
+		}
+		details.PrivateIPs = append(details.PrivateIPs, nic.PrivateIP)
+		if ip, ok := p.publicIPs[nic.PublicIPName]; ok {
+			details.PublicIPs = append(details.PublicIPs, ip.Address)
+			details.PublicIPName = ip.Name
+			details.DnsAlias = ip.FQDN
+		}
+		if nsg, ok := p.nsgs[strings.ToLower(nic.SecurityGroupID)]; ok {
+			details.SecurityGroups = append(details.SecurityGroups, composeSecurityGroup(nsg, azure.SecurityGroupScopeNIC, nic.Name))
+		}
+		if nsg, ok := p.nsgs[strings.ToLower(nic.SubnetSecurityGroupID)]; ok {
+			details.SecurityGroups = append(details.SecurityGroups, composeSecurityGroup(nsg, azure.SecurityGroupScopeSubnet, defaultSubnetName))
+		}
+	}
+	details.FetchedAt = time.Now()
+	return details
+}
+
+func (p *Provider) injected(method string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.Errors[method]
+}
+
+func (p *Provider) VMClient(credentials *azure.Credentials, logger *zap.Logger, timeout time.Duration) azure.VMClient {
+	return &vmClient{provider: p}
+}
+
+func (p *Provider) SubscriptionClient(credentials *azure.Credentials, logger *zap.Logger, timeout time.Duration) azure.SubscriptionClient {
+	return &subscriptionClient{provider: p}
+}
+
+func (p *Provider) ImageClient(subscriptionID string, credentials *azure.AzureCredential, logger *zap.Logger) azure.ImageClient {
+	return &imageClient{provider: p}
+}
+
+func (p *Provider) SizeClient(subscriptionID string, credentials *azure.AzureCredential, logger *zap.Logger) azure.SizeClient {
+	return &sizeClient{provider: p}
+}
+
+func (p *Provider) QuotaClient(subscriptionID string, credentials *azure.AzureCredential, logger *zap.Logger) azure.QuotaClient {
+	return &quotaClient{provider: p, subscriptionID: subscriptionID}
+}
+
+func (p *Provider) PriceClient(baseURL string, logger *zap.Logger) azure.PriceClient {
+	return &priceClient{provider: p}
+}
+
+func (p *Provider) CostClient(subscriptionID string, credentials *azure.AzureCredential, logger *zap.Logger) azure.CostClient {
+	return &costClient{provider: p, subscriptionID: subscriptionID}
+}
+
+func (p *Provider) RegionClient(logger *zap.Logger, retries int, timeout time.Duration) azure.RegionClient {
+	return &regionClient{provider: p}
+}
+
+func (p *Provider) Validator(timeout time.Duration) azure.CredentialValidator {
+	return &validator{provider: p}
+}
+
+// subscriptionClient 订阅客户端
+type subscriptionClient struct {
+	provider *Provider
+}
+
+func (c *subscriptionClient) FetchSubscriptionDetails(ctx context.Context) ([]azure.SubscriptionDetail, error) {
+	if err := c.provider.injected("FetchSubscriptionDetails"); err != nil {
+		return nil, err
+	}
+	c.provider.mu.Lock()
+	defer c.provider.mu.Unlock()
+
+	subs := make([]azure.SubscriptionDetail, 0, len(c.provider.subscriptions))
+	for _, sub := range c.provider.subscriptions {
+		sub.FetchedAt = time.Now()
+		subs = append(subs, sub)
+	}
+	sort.Slice(subs, func(i, j int) bool { return subs[i].SubscriptionID < subs[j].SubscriptionID })
+	return subs, nil
+}
+
+// validator 凭据验证
+type validator struct {
+	provider *Provider
+}
+
+func (v *validator) ValidateWithContext(ctx context.Context, credentials azure.Credentials) azure.ValidationResult {
+	v.provider.mu.Lock()
+	defer v.provider.mu.Unlock()
+
+	result := azure.ValidationResult{ValidatedAt: time.Now()}
+	if len(v.provider.ValidSecrets) > 0 && !v.provider.ValidSecrets[credentials.ClientSecret] {
+		result.Message = "凭据验证失败"
+		result.Error = fmt.Errorf("invalid client secret")
+		return result
+	}
+	result.Valid = true
+	result.Message = "验证成功"
+	return result
+}
+
+// sizeClient 规格客户端
+type sizeClient struct {
+	provider *Provider
+}
+
+func (c *sizeClient) ListSizes(ctx context.Context, location string) ([]*azure.VMSizeInfo, error) {
+	if err := c.provider.injected("ListSizes"); err != nil {
+		return nil, err
+	}
+	c.provider.mu.Lock()
+	defer c.provider.mu.Unlock()
+	return c.provider.sizes[location], nil
+}
+
+func (c *sizeClient) ListResourceSKUs(ctx context.Context, location string) ([]*azure.VMSizeInfo, error) {
+	if err := c.provider.injected("ListResourceSKUs"); err != nil {
+		return nil, err
+	}
+	c.provider.mu.Lock()
+	defer c.provider.mu.Unlock()
+	return c.provider.sizes[location], nil
+}
+
+// quotaClient 配额客户端
+type quotaClient struct {
+	provider       *Provider
+	subscriptionID string
+}
+
+func (c *quotaClient) ListUsages(ctx context.Context, location string) ([]*azure.QuotaUsage, error) {
+	if err := c.provider.injected("ListUsages"); err != nil {
+		return nil, err
+	}
+	c.provider.mu.Lock()
+	defer c.provider.mu.Unlock()
+
+	stored := c.provider.quotas[quotaKey(c.subscriptionID, location)]
+	usages := make([]*azure.QuotaUsage, 0, len(stored))
+	for _, usage := range stored {
+		copied := *usage
+		copied.Location = location
+		usages = append(usages, &copied)
+	}
+	return usages, nil
+}
+
+// priceClient 价格客户端
+type priceClient struct {
+	provider *Provider
+}
+
+func (c *priceClient) ListVMPrices(ctx context.Context, location string) ([]*azure.VMPrice, error) {
+	if err := c.provider.injected("ListVMPrices"); err != nil {
+		return nil, err
+	}
+	c.provider.mu.Lock()
+	defer c.provider.mu.Unlock()
+	return c.provider.prices[location], nil
+}
+
+func (c *priceClient) GetVMPrice(ctx context.Context, location, size string) (*azure.VMPrice, error) {
+	if err := c.provider.injected("GetVMPrice"); err != nil {
+		return nil, err
+	}
+	c.provider.mu.Lock()
+	defer c.provider.mu.Unlock()
+	for _, price := range c.provider.prices[location] {
+		if strings.EqualFold(price.Size, size) {
+			return price, nil
+		}
+	}
+	return nil, nil
+}
+
+// costClient 费用客户端
+type costClient struct {
+	provider       *Provider
+	subscriptionID string
+}
+
+func (c *costClient) QueryDailyCosts(ctx context.Context, from, to time.Time) ([]*azure.CostRecord, error) {
+	if err := c.provider.injected("QueryDailyCosts"); err != nil {
+		return nil, err
+	}
+	c.provider.mu.Lock()
+	defer c.provider.mu.Unlock()
+
+	from = from.UTC().Truncate(24 * time.Hour)
+	to = to.UTC().Truncate(24 * time.Hour)
+	var records []*azure.CostRecord
+	for _, record := range c.provider.costs[strings.ToLower(c.subscriptionID)] {
+		if record.UsageDate.Before(from) || record.UsageDate.After(to) {
+			continue
+		}
+		copied := *record
+		records = append(records, &copied)
+	}
+	return records, nil
+}
+
+// regionClient 区域客户端
+type regionClient struct {
+	provider *Provider
+}
+
+func (c *regionClient) GetRegions(ctx context.Context, cred *azure.AzureCredential, subscriptionID string) ([]azure.RegionInfo, error) {
+	if err := c.provider.injected("GetRegions"); err != nil {
+		return nil, err
+	}
+	c.provider.mu.Lock()
+	defer c.provider.mu.Unlock()
+	return c.provider.regions, nil
+}
+
+func (c *regionClient) IsRegionAvailable(ctx context.Context, cred *azure.AzureCredential, subscriptionID string, regionName string) (bool, error) {
+	regions, err := c.GetRegions(ctx, cred, subscriptionID)
+	if err != nil {
+		return false, err
+	}
+	for _, region := range regions {
+		if strings.EqualFold(region.Name, regionName) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// imageClient 镜像客户端
+type imageClient struct {
+	provider *Provider
+}
+
+func (c *imageClient) list(location string, match func(*azure.VMImageInfo) bool, key func(*azure.VMImageInfo) string) []string {
+	c.provider.mu.Lock()
+	defer c.provider.mu.Unlock()
+
+	seen := make(map[string]bool)
+	var result []string
+	for _, img := range c.provider.images[location] {
+		if !match(img) || seen[key(img)] {
+			continue
+		}
+		seen[key(img)] = true
+		result = append(result, key(img))
+	}
+	return result
+}
+
+func (c *imageClient) ListPublishers(ctx context.Context, location string) ([]string, error) {
+	return c.list(location,
+		func(*azure.VMImageInfo) bool { return true },
+		func(img *azure.VMImageInfo) string { return img.Publisher }), nil
+}
+
+func (c *imageClient) ListOffers(ctx context.Context, location, publisher string) ([]string, error) {
+	return c.list(location,
+		func(img *azure.VMImageInfo) bool { return img.Publisher == publisher },
+		func(img *azure.VMImageInfo) string { return img.Offer }), nil
+}
+
+func (c *imageClient) ListSKUs(ctx context.Context, location, publisher, offer string) ([]string, error) {
+	return c.list(location,
+		func(img *azure.VMImageInfo) bool { return img.Publisher == publisher && img.Offer == offer },
+		func(img *azure.VMImageInfo) string { return img.SKU }), nil
+}
+
+func (c *imageClient) ListVersions(ctx context.Context, location, publisher, offer, sku string) ([]string, error) {
+	return c.list(location,
+		func(img *azure.VMImageInfo) bool {
+			return img.Publisher == publisher && img.Offer == offer && img.SKU == sku
+		},
+		func(img *azure.VMImageInfo) string { return img.Version }), nil
+}
+
+func (c *imageClient) GetImage(ctx context.Context, location, publisher, offer, sku, version string) (*azure.VMImageInfo, error) {
+	c.provider.mu.Lock()
+	defer c.provider.mu.Unlock()
+	for _, img := range c.provider.images[location] {
+		if img.Publisher == publisher && img.Offer == offer && img.SKU == sku && img.Version == version {
+			return img, nil
+		}
+	}
+	return nil, fmt.Errorf("镜像不存在: %s:%s:%s:%s", publisher, offer, sku, version)
+}
+
+func (c *imageClient) SyncImages(ctx context.Context, location string) ([]*azure.VMImageInfo, error) {
+	if err := c.provider.injected("SyncImages"); err != nil {
+		return nil, err
+	}
+	c.provider.mu.Lock()
+	defer c.provider.mu.Unlock()
+	return c.provider.images[location], nil
+}
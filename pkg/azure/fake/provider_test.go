@@ -0,0 +1,116 @@
+package fake
+
+import (
+	"context"
+	"testing"
+
+	"azure-vm-backend/pkg/azure"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestVM(p *Provider) azure.VMDetails {
+	p.AddSubscription(azure.SubscriptionDetail{SubscriptionID: "sub-1", DisplayName: "test"})
+	return p.AddVM(azure.VMDetails{
+		SubscriptionID: "sub-1",
+		ResourceGroup:  "rg",
+		Name:           "vm1",
+		Location:       "eastus",
+		PublicIPs:      []string{"20.1.1.1"},
+	})
+}
+
+func TestProvider_FetchVMDetails(t *testing.T) {
+	p := NewProvider()
+	vm := newTestVM(p)
+
+	result, err := p.VMClient(nil, nil, 0).FetchVMDetails(context.Background())
+	require.NoError(t, err)
+	vms := result.VMs
+	require.Len(t, vms, 1)
+	require.Len(t, result.Subscriptions, 1)
+	assert.Equal(t, 1, result.Subscriptions[0].VMCount)
+	assert.Equal(t, vm.ID, vms[0].ID)
+	assert.Equal(t, []string{"20.1.1.1"}, vms[0].PublicIPs)
+	assert.Equal(t, "vm1-ip", vms[0].PublicIPName)
+	assert.Len(t, vms[0].PrivateIPs, 1)
+	assert.Equal(t, "running", vms[0].PowerState)
+}
+
+func TestProvider_PowerTransitions(t *testing.T) {
+	ctx := context.Background()
+	p := NewProvider()
+	vm := newTestVM(p)
+	client := p.VMClient(nil, nil, 0)
+
+	require.NoError(t, client.VMOperation(ctx, azure.VMOperationStop, vm, nil))
+	state, _ := client.GetVMStatus(ctx, "sub-1", "rg", "vm1")
+	assert.Equal(t, "stopped", state)
+
+	require.NoError(t, client.VMOperation(ctx, azure.VMOperationStop, vm, &azure.OperationOptions{Force: true}))
+	state, _ = client.GetVMStatus(ctx, "sub-1", "rg", "vm1")
+	assert.Equal(t, "deallocated", state)
+
+	require.NoError(t, client.VMOperation(ctx, azure.VMOperationStart, vm, nil))
+	state, _ = client.GetVMStatus(ctx, "sub-1", "rg", "vm1")
+	assert.Equal(t, "running", state)
+
+	require.NoError(t, client.VMOperation(ctx, azure.VMOperationDelete, vm, nil))
+	_, ok := p.GetVM(vm.ID)
+	assert.False(t, ok)
+	_, ok = p.GetPublicIP("vm1-ip")
+	assert.False(t, ok)
+}
+
+func TestProvider_ResumeOperation(t *testing.T) {
+	ctx := context.Background()
+	p := NewProvider()
+	p.PollSteps = 2
+	vm := newTestVM(p)
+	client := p.VMClient(nil, nil, 0)
+
+	poller, err := client.BeginVMOperation(ctx, azure.VMOperationRestart, vm, nil, "")
+	require.NoError(t, err)
+	assert.False(t, poller.Done())
+	state, _ := client.GetVMStatus(ctx, "sub-1", "rg", "vm1")
+	assert.Equal(t, "restarting", state)
+
+	token, err := poller.ResumeToken()
+	require.NoError(t, err)
+
+	// 使用新客户端从令牌恢复，模拟服务重启
+	resumed, err := p.VMClient(nil, nil, 0).BeginVMOperation(ctx, azure.VMOperationRestart, vm, nil, token)
+	require.NoError(t, err)
+	for !resumed.Done() {
+		require.NoError(t, resumed.Poll(ctx))
+	}
+	require.NoError(t, resumed.Result(ctx))
+	state, _ = client.GetVMStatus(ctx, "sub-1", "rg", "vm1")
+	assert.Equal(t, "running", state)
+}
+
+func TestProvider_SetVMDNSLabel(t *testing.T) {
+	ctx := context.Background()
+	p := NewProvider()
+	vm := newTestVM(p)
+	client := p.VMClient(nil, nil, 0)
+
+	fqdn, err := client.SetVMDNSLabel(ctx, "sub-1", "rg", vm.PublicIPName, "myvm")
+	require.NoError(t, err)
+	assert.Equal(t, "myvm.eastus.cloudapp.azure.com", fqdn)
+
+	details, _ := p.GetVM(vm.ID)
+	assert.Equal(t, fqdn, details.DnsAlias)
+
+	_, err = client.SetVMDNSLabel(ctx, "sub-1", "rg", "missing-ip", "x")
+	assert.Error(t, err)
+}
+
+func TestProvider_Validator(t *testing.T) {
+	p := NewProvider()
+	p.ValidSecrets["good"] = true
+	v := p.Validator(0)
+
+	assert.True(t, v.ValidateWithContext(context.Background(), azure.Credentials{ClientSecret: "good"}).Valid)
+	assert.False(t, v.ValidateWithContext(context.Background(), azure.Credentials{ClientSecret: "bad"}).Valid)
+}
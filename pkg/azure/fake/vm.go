@@ -0,0 +1,826 @@
+package fake
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"azure-vm-backend/pkg/azure"
+)
+
+// vmClient 虚拟机客户端
+type vmClient struct {
+	provider *Provider
+}
+
+func (c *vmClient) FetchVMDetails(ctx context.Context) (*azure.VMFetchResult, error) {
+	if err := c.provider.injected("FetchVMDetails"); err != nil {
+		return nil, err
+	}
+	p := c.provider
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	subIDs := make([]string, 0, len(p.subscriptions))
+	for id := range p.subscriptions {
+		subIDs = append(subIDs, id)
+	}
+	sort.Strings(subIDs)
+
+	result := &azure.VMFetchResult{}
+	for _, id := range subIDs {
+		sub := p.subscriptions[id]
+		subResult := azure.SubscriptionVMResult{
+			SubscriptionID: id,
+			DisplayName:    sub.DisplayName,
+			State:          sub.State,
+		}
+		if err := p.SubscriptionErrors[id]; err != nil {
+			subResult.Err = err
+			result.Subscriptions = append(result.Subscriptions, subResult)
+			continue
+		}
+		for _, vm := range p.vms {
+			if vm.SubscriptionID == id {
+				result.VMs = append(result.VMs, p.composeLocked(vm))
+				subResult.VMCount++
+			}
+		}
+		result.Subscriptions = append(result.Subscriptions, subResult)
+	}
+	return result, nil
+}
+
+func (c *vmClient) CreateVM(ctx context.Context, opts azure.VMCreateOptions) (*azure.VMDetails, error) {
+	if err := c.provider.injected("CreateVM"); err != nil {
+		return nil, err
+	}
+	p := c.provider
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	id := azure.BuildVMResourceID(opts.SubscriptionID, opts.ResourceGroup, opts.Name)
+	if _, exists := p.vms[strings.ToLower(id)]; exists {
+		return nil, fmt.Errorf("虚拟机已存在: %s", opts.Name)
+	}
+
+	vm := azure.VMDetails{
+		ID:             id,
+		SubscriptionID: opts.SubscriptionID,
+		ResourceGroup:  opts.ResourceGroup,
+		Name:           opts.Name,
+		Location:       opts.Location,
+		Size:           opts.Size,
+		OSType:         opts.OSType,
+		OSImage:        fmt.Sprintf("%s:%s:%s", opts.ImagePublisher, opts.ImageOffer, opts.ImageSku),
+		OSDiskSize:     opts.OSDiskSizeGB,
+		Tags:           opts.Tags,
+	}
+	for i, disk := range opts.DataDisks {
+		vm.DataDisks = append(vm.DataDisks, azure.DiskInfo{
+			Name:     disk.Name,
+			SizeGB:   disk.SizeGB,
+			Lun:      int32(i),
+			DiskType: disk.DiskType,
+		})
+	}
+	if opts.Network.PublicIP {
+		vm.PublicIPName = opts.Name + "-ip"
+	}
+
+	details := p.composeLocked(p.addVMLocked(vm))
+	return &details, nil
+}
+
+func (c *vmClient) SetVMDNSLabel(ctx context.Context, subscriptionID, resourceGroup, publicIPName, dnsLabel string) (string, error) {
+	if err := c.provider.injected("SetVMDNSLabel"); err != nil {
+		return "", err
+	}
+	p := c.provider
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	ip, ok := p.publicIPs[publicIPName]
+	if !ok || !strings.EqualFold(ip.ResourceGroup, resourceGroup) {
+		return "", fmt.Errorf("获取公共IP失败: %s 不存在", publicIPName)
+	}
+	ip.DNSLabel = dnsLabel
+	ip.FQDN = fmt.Sprintf("%s.%s.cloudapp.azure.com", dnsLabel, ip.Location)
+	return ip.FQDN, nil
+}
+
+func (c *vmClient) ChangePublicIP(ctx context.Context, vm azure.VMDetails, opts azure.PublicIPChangeOptions) (*azure.PublicIPChangeResult, error) {
+	if err := c.provider.injected("ChangePublicIP"); err != nil {
+		return nil, err
+	}
+	if opts.Sku != "" && !strings.EqualFold(opts.Sku, azure.PublicIPSkuStandard) && !strings.EqualFold(opts.Sku, azure.PublicIPSkuBasic) {
+		return nil, fmt.Errorf("不支持的公网IP规格: %s", opts.Sku)
+	}
+	p := c.provider
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	key := strings.ToLower(azure.BuildVMResourceID(vm.SubscriptionID, vm.ResourceGroup, vm.Name))
+	target, ok := p.vms[key]
+	if !ok {
+		return nil, fmt.Errorf("虚拟机不存在: %s", vm.Name)
+	}
+	var nic *NIC
+	for _, n := range p.nics {
+		if strings.EqualFold(n.VMID, target.ID) && (nic == nil || n.PublicIPName != "") {
+			nic = n
+		}
+	}
+	if nic == nil {
+		return nil, fmt.Errorf("未找到可挂载公网IP的网卡配置")
+	}
+
+	p.nextIP++
+	newIP := &PublicIP{
+		Name:          fmt.Sprintf("%s-ip-%d", target.Name, p.nextIP),
+		ResourceGroup: target.ResourceGroup,
+		Location:      target.Location,
+		Address:       fmt.Sprintf("20.0.1.%d", p.nextIP),
+	}
+	result := &azure.PublicIPChangeResult{PublicIPName: newIP.Name, PublicIP: newIP.Address}
+	if old, ok := p.publicIPs[nic.PublicIPName]; ok {
+		result.OldPublicIPName = old.Name
+		result.OldPublicIP = old.Address
+		result.OldPublicIPDeleted = true
+		if old.DNSLabel != "" {
+			newIP.DNSLabel = old.DNSLabel
+			newIP.FQDN = fmt.Sprintf("%s.%s.cloudapp.azure.com", old.DNSLabel, newIP.Location)
+			result.DnsAlias = newIP.FQDN
+		}
+		delete(p.publicIPs, old.Name)
+	}
+	p.publicIPs[newIP.Name] = newIP
+	nic.PublicIPName = newIP.Name
+	return result, nil
+}
+
+func (c *vmClient) ListAvailableSizes(ctx context.Context, subscriptionID, resourceGroup, vmName string) ([]string, error) {
+	if err := c.provider.injected("ListAvailableSizes"); err != nil {
+		return nil, err
+	}
+	p := c.provider
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	vm, ok := p.vms[strings.ToLower(azure.BuildVMResourceID(subscriptionID, resourceGroup, vmName))]
+	if !ok {
+		return nil, fmt.Errorf("虚拟机不存在: %s", vmName)
+	}
+	return p.availableSizesLocked(vm), nil
+}
+
+func (c *vmClient) ResizeVM(ctx context.Context, vm azure.VMDetails, size string) (*azure.VMResizeResult, error) {
+	if err := c.provider.injected("ResizeVM"); err != nil {
+		return nil, err
+	}
+	p := c.provider
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	target, ok := p.vms[strings.ToLower(azure.BuildVMResourceID(vm.SubscriptionID, vm.ResourceGroup, vm.Name))]
+	if !ok {
+		return nil, fmt.Errorf("虚拟机不存在: %s", vm.Name)
+	}
+	var spec *azure.VMSizeInfo
+	for _, s := range p.sizes[target.Location] {
+		if strings.EqualFold(s.Name, size) {
+			spec = s
+		}
+	}
+	if spec == nil {
+		return nil, fmt.Errorf("规格 %s 在区域 %s 不可用", size, target.Location)
+	}
+
+	result := &azure.VMResizeResult{Size: spec.Name}
+	inCluster := false
+	for _, name := range p.availableSizesLocked(target) {
+		if strings.EqualFold(name, size) {
+			inCluster = true
+		}
+	}
+	if !inCluster && target.PowerState != "deallocated" {
+		result.Deallocated = true
+		if target.PowerState != "running" {
+			target.PowerState = "deallocated"
+		}
+	}
+	target.Size = spec.Name
+	target.NumberOfCores = int32(spec.Cores)
+	target.MemoryInGB = int32(spec.MemoryGB)
+	result.PowerState = target.PowerState
+	return result, nil
+}
+
+func (c *vmClient) AttachDataDisk(ctx context.Context, vm azure.VMDetails, opts azure.DataDiskAttachOptions) (*azure.VMDisks, error) {
+	if err := c.provider.injected("AttachDataDisk"); err != nil {
+		return nil, err
+	}
+	if opts.StorageAccountType != "" && !azure.IsValidStorageAccountType(opts.StorageAccountType) {
+		return nil, fmt.Errorf("不支持的磁盘类型: %s", opts.StorageAccountType)
+	}
+	p := c.provider
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	target, ok := p.vms[strings.ToLower(azure.BuildVMResourceID(vm.SubscriptionID, vm.ResourceGroup, vm.Name))]
+	if !ok {
+		return nil, fmt.Errorf("虚拟机不存在: %s", vm.Name)
+	}
+	lun := azure.NextFreeLun(target.DataDisks)
+	if opts.Lun != nil {
+		lun = *opts.Lun
+		for _, disk := range target.DataDisks {
+			if disk.Lun == lun {
+				return nil, fmt.Errorf("LUN %d 已被占用", lun)
+			}
+		}
+	}
+	if lun < 0 {
+		return nil, fmt.Errorf("没有空闲的LUN")
+	}
+
+	disk := azure.DiskInfo{
+		Name:     opts.Name,
+		SizeGB:   opts.SizeGB,
+		Lun:      lun,
+		DiskType: opts.StorageAccountType,
+	}
+	if disk.Name == "" {
+		disk.Name = fmt.Sprintf("%s_DataDisk_%d", target.Name, lun)
+	}
+	if disk.DiskType == "" {
+		disk.DiskType = "StandardSSD_LRS"
+	}
+	target.DataDisks = append(target.DataDisks, disk)
+	return p.vmDisksLocked(target), nil
+}
+
+func (c *vmClient) DetachDataDisk(ctx context.Context, vm azure.VMDetails, diskName string, deleteDisk bool) (*azure.VMDisks, error) {
+	if err := c.provider.injected("DetachDataDisk"); err != nil {
+		return nil, err
+	}
+	p := c.provider
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	target, ok := p.vms[strings.ToLower(azure.BuildVMResourceID(vm.SubscriptionID, vm.ResourceGroup, vm.Name))]
+	if !ok {
+		return nil, fmt.Errorf("虚拟机不存在: %s", vm.Name)
+	}
+	for i, disk := range target.DataDisks {
+		if strings.EqualFold(disk.Name, diskName) {
+			target.DataDisks = append(target.DataDisks[:i:i], target.DataDisks[i+1:]...)
+			return p.vmDisksLocked(target), nil
+		}
+	}
+	return nil, fmt.Errorf("虚拟机未挂载磁盘: %s", diskName)
+}
+
+func (c *vmClient) ExpandDisk(ctx context.Context, vm azure.VMDetails, diskName string, sizeGB int32) (*azure.VMDisks, error) {
+	if err := c.provider.injected("ExpandDisk"); err != nil {
+		return nil, err
+	}
+	p := c.provider
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	target, ok := p.vms[strings.ToLower(azure.BuildVMResourceID(vm.SubscriptionID, vm.ResourceGroup, vm.Name))]
+	if !ok {
+		return nil, fmt.Errorf("虚拟机不存在: %s", vm.Name)
+	}
+	if diskName == "" {
+		if target.PowerState != "deallocated" {
+			return nil, fmt.Errorf("系统盘只能在虚拟机释放后扩容")
+		}
+		if sizeGB <= target.OSDiskSize {
+			return nil, fmt.Errorf("磁盘容量只能增加")
+		}
+		target.OSDiskSize = sizeGB
+		return p.vmDisksLocked(target), nil
+	}
+	for i := range target.DataDisks {
+		if strings.EqualFold(target.DataDisks[i].Name, diskName) {
+			if sizeGB <= target.DataDisks[i].SizeGB {
+				return nil, fmt.Errorf("磁盘容量只能增加")
+			}
+			target.DataDisks[i].SizeGB = sizeGB
+			return p.vmDisksLocked(target), nil
+		}
+	}
+	return nil, fmt.Errorf("虚拟机未挂载托管磁盘: %s", diskName)
+}
+
+func (c *vmClient) CreateSnapshot(ctx context.Context, vm azure.VMDetails, diskName, name string) (*azure.SnapshotInfo, error) {
+	if err := c.provider.injected("CreateSnapshot"); err != nil {
+		return nil, err
+	}
+	if vm.ID == "" {
+		return nil, azure.ErrSnapshotVMIDRequired
+	}
+	p := c.provider
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	target, ok := p.vms[strings.ToLower(azure.BuildVMResourceID(vm.SubscriptionID, vm.ResourceGroup, vm.Name))]
+	if !ok {
+		return nil, fmt.Errorf("虚拟机不存在: %s", vm.Name)
+	}
+	role, sourceName, size := azure.SnapshotDiskRoleOS, p.osDiskNameLocked(target), target.OSDiskSize
+	if diskName != "" {
+		role, sourceName = azure.SnapshotDiskRoleData, ""
+		for _, disk := range target.DataDisks {
+			if strings.EqualFold(disk.Name, diskName) {
+				sourceName, size = disk.Name, disk.SizeGB
+			}
+		}
+		if sourceName == "" {
+			return nil, fmt.Errorf("未找到托管磁盘: %s", diskName)
+		}
+	}
+
+	p.nextOp++
+	if name == "" {
+		name = fmt.Sprintf("%s-%s-%d", target.Name, role, p.nextOp)
+	}
+	snapshot := &azure.SnapshotInfo{
+		ID:             fmt.Sprintf("/subscriptions/%s/resourceGroups/%s/providers/Microsoft.Compute/snapshots/%s", target.SubscriptionID, target.ResourceGroup, name),
+		Name:           name,
+		ResourceGroup:  target.ResourceGroup,
+		Location:       target.Location,
+		SourceVMID:     vm.ID,
+		SourceDiskID:   fmt.Sprintf("/subscriptions/%s/resourceGroups/%s/providers/Microsoft.Compute/disks/%s", target.SubscriptionID, target.ResourceGroup, sourceName),
+		SourceDiskName: sourceName,
+		DiskRole:       role,
+		SizeGB:         size,
+		CreatedTime:    time.Now(),
+	}
+	if _, exists := p.snapshots[strings.ToLower(snapshot.ID)]; exists {
+		return nil, fmt.Errorf("快照已存在: %s", name)
+	}
+	p.snapshots[strings.ToLower(snapshot.ID)] = snapshot
+	info := *snapshot
+	return &info, nil
+}
+
+func (c *vmClient) ListSnapshots(ctx context.Context, vm azure.VMDetails) ([]azure.SnapshotInfo, error) {
+	if err := c.provider.injected("ListSnapshots"); err != nil {
+		return nil, err
+	}
+	if vm.ID == "" {
+		return nil, azure.ErrSnapshotVMIDRequired
+	}
+	p := c.provider
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var snapshots []azure.SnapshotInfo
+	for _, snapshot := range p.snapshots {
+		if strings.EqualFold(snapshot.SourceVMID, vm.ID) {
+			snapshots = append(snapshots, *snapshot)
+		}
+	}
+	sort.Slice(snapshots, func(i, j int) bool { return snapshots[i].CreatedTime.Before(snapshots[j].CreatedTime) })
+	return snapshots, nil
+}
+
+func (c *vmClient) DeleteSnapshot(ctx context.Context, subscriptionID, resourceGroup, name string) error {
+	if err := c.provider.injected("DeleteSnapshot"); err != nil {
+		return err
+	}
+	p := c.provider
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	id := fmt.Sprintf("/subscriptions/%s/resourceGroups/%s/providers/Microsoft.Compute/snapshots/%s", subscriptionID, resourceGroup, name)
+	delete(p.snapshots, strings.ToLower(id))
+	return nil
+}
+
+func (c *vmClient) RestoreOSDisk(ctx context.Context, vm azure.VMDetails, snapshotID string) (*azure.OSDiskRestoreResult, error) {
+	if err := c.provider.injected("RestoreOSDisk"); err != nil {
+		return nil, err
+	}
+	p := c.provider
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	key := strings.ToLower(azure.BuildVMResourceID(vm.SubscriptionID, vm.ResourceGroup, vm.Name))
+	target, ok := p.vms[key]
+	if !ok {
+		return nil, fmt.Errorf("虚拟机不存在: %s", vm.Name)
+	}
+	snapshot, ok := p.snapshots[strings.ToLower(snapshotID)]
+	if !ok {
+		return nil, fmt.Errorf("快照不存在: %s", snapshotID)
+	}
+	if snapshot.DiskRole != azure.SnapshotDiskRoleOS {
+		return nil, fmt.Errorf("快照不包含操作系统: %s", snapshot.Name)
+	}
+
+	p.nextOp++
+	result := &azure.OSDiskRestoreResult{OldOSDiskName: p.osDiskNameLocked(target)}
+	p.osDisks[key] = fmt.Sprintf("%s_OsDisk_%d", target.Name, p.nextOp)
+	target.OSDiskSize = snapshot.SizeGB
+	if target.PowerState != "running" {
+		target.PowerState = "deallocated"
+	}
+	result.Disks = *p.vmDisksLocked(target)
+	result.PowerState = target.PowerState
+	return result, nil
+}
+
+func (c *vmClient) ListSecurityGroups(ctx context.Context, vm azure.VMDetails) ([]azure.SecurityGroupInfo, error) {
+	if err := c.provider.injected("ListSecurityGroups"); err != nil {
+		return nil, err
+	}
+	p := c.provider
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	target, ok := p.vms[strings.ToLower(azure.BuildVMResourceID(vm.SubscriptionID, vm.ResourceGroup, vm.Name))]
+	if !ok {
+		return nil, fmt.Errorf("虚拟机不存在: %s", vm.Name)
+	}
+	return p.composeLocked(target).SecurityGroups, nil
+}
+
+func (c *vmClient) AddSecurityRule(ctx context.Context, vm azure.VMDetails, securityGroupID string, rule azure.SecurityRule) ([]azure.SecurityGroupInfo, error) {
+	if err := c.provider.injected("AddSecurityRule"); err != nil {
+		return nil, err
+	}
+	p := c.provider
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	target, ok := p.vms[strings.ToLower(azure.BuildVMResourceID(vm.SubscriptionID, vm.ResourceGroup, vm.Name))]
+	if !ok {
+		return nil, fmt.Errorf("虚拟机不存在: %s", vm.Name)
+	}
+	nsg, ok := p.nsgs[strings.ToLower(securityGroupID)]
+	if !ok {
+		return nil, fmt.Errorf("网络安全组不存在: %s", securityGroupID)
+	}
+	for i, existing := range nsg.Rules {
+		if existing.Default {
+			continue
+		}
+		if strings.EqualFold(existing.Name, rule.Name) {
+			nsg.Rules[i] = rule
+			return p.composeLocked(target).SecurityGroups, nil
+		}
+		if existing.Direction == rule.Direction && existing.Priority == rule.Priority {
+			return nil, fmt.Errorf("规则 %s 与 %s 的优先级冲突", rule.Name, existing.Name)
+		}
+	}
+	nsg.Rules = append(nsg.Rules, rule)
+	return p.composeLocked(target).SecurityGroups, nil
+}
+
+func (c *vmClient) DeleteSecurityRule(ctx context.Context, vm azure.VMDetails, securityGroupID, ruleName string) ([]azure.SecurityGroupInfo, error) {
+	if err := c.provider.injected("DeleteSecurityRule"); err != nil {
+		return nil, err
+	}
+	p := c.provider
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	target, ok := p.vms[strings.ToLower(azure.BuildVMResourceID(vm.SubscriptionID, vm.ResourceGroup, vm.Name))]
+	if !ok {
+		return nil, fmt.Errorf("虚拟机不存在: %s", vm.Name)
+	}
+	nsg, ok := p.nsgs[strings.ToLower(securityGroupID)]
+	if !ok {
+		return nil, fmt.Errorf("网络安全组不存在: %s", securityGroupID)
+	}
+	for i, existing := range nsg.Rules {
+		if !existing.Default && strings.EqualFold(existing.Name, ruleName) {
+			nsg.Rules = append(nsg.Rules[:i:i], nsg.Rules[i+1:]...)
+			break
+		}
+	}
+	return p.composeLocked(target).SecurityGroups, nil
+}
+
+func (c *vmClient) CreateSecurityGroup(ctx context.Context, vm azure.VMDetails, name string) ([]azure.SecurityGroupInfo, error) {
+	if err := c.provider.injected("CreateSecurityGroup"); err != nil {
+		return nil, err
+	}
+	p := c.provider
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	target, ok := p.vms[strings.ToLower(azure.BuildVMResourceID(vm.SubscriptionID, vm.ResourceGroup, vm.Name))]
+	if !ok {
+		return nil, fmt.Errorf("虚拟机不存在: %s", vm.Name)
+	}
+	nic, ok := p.nics[target.Name+"-nic"]
+	if !ok {
+		return nil, fmt.Errorf("未找到可关联网络安全组的网卡")
+	}
+	if nic.SecurityGroupID != "" {
+		return nil, fmt.Errorf("网卡 %s 已关联网络安全组", nic.Name)
+	}
+	if name == "" {
+		name = target.Name + "-nsg"
+	}
+	nic.SecurityGroupID = p.addSecurityGroupLocked(target, azure.SecurityGroupInfo{Name: name}).ID
+	return p.composeLocked(target).SecurityGroups, nil
+}
+
+func (c *vmClient) RunCommand(ctx context.Context, vm azure.VMDetails, script string) (*azure.RunCommandResult, error) {
+	if err := c.provider.injected("RunCommand"); err != nil {
+		return nil, err
+	}
+	p := c.provider
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	target, ok := p.vms[strings.ToLower(azure.BuildVMResourceID(vm.SubscriptionID, vm.ResourceGroup, vm.Name))]
+	if !ok {
+		return nil, fmt.Errorf("虚拟机不存在: %s", vm.Name)
+	}
+	if target.PowerState != "running" {
+		return nil, fmt.Errorf("虚拟机 %s 未运行，无法执行脚本", vm.Name)
+	}
+	if result, ok := p.RunCommandResults[target.Name]; ok {
+		return &result, nil
+	}
+	exitCode := 0
+	return &azure.RunCommandResult{Succeeded: true, ExitCode: &exitCode}, nil
+}
+
+func (c *vmClient) VMOperation(ctx context.Context, opType azure.VMOperationType, vm azure.VMDetails, opts *azure.OperationOptions) error {
+	poller, err := c.BeginVMOperation(ctx, opType, vm, opts, "")
+	if err != nil {
+		return err
+	}
+	for !poller.Done() {
+		if err := poller.Poll(ctx); err != nil {
+			return err
+		}
+	}
+	return poller.Result(ctx)
+}
+
+func (c *vmClient) BeginVMOperation(ctx context.Context, opType azure.VMOperationType, vm azure.VMDetails, opts *azure.OperationOptions, resumeToken string) (azure.OperationPoller, error) {
+	if err := c.provider.injected("BeginVMOperation"); err != nil {
+		return nil, err
+	}
+	p := c.provider
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	// 从恢复令牌继续
+	if resumeToken != "" {
+		op, ok := p.operations[resumeToken]
+		if !ok {
+			return nil, fmt.Errorf("无效的恢复令牌: %s", resumeToken)
+		}
+		return &poller{provider: p, op: op}, nil
+	}
+
+	key := strings.ToLower(azure.BuildVMResourceID(vm.SubscriptionID, vm.ResourceGroup, vm.Name))
+	target, ok := p.vms[key]
+	if !ok {
+		return nil, fmt.Errorf("虚拟机不存在: %s", vm.Name)
+	}
+
+	var transition, final string
+	switch azure.ResolveOperationType(opType, opts) {
+	case azure.VMOperationStart:
+		transition, final = "starting", "running"
+	case azure.VMOperationStop:
+		transition, final = "stopping", "stopped"
+	case azure.VMOperationDeallocate:
+		transition, final = "deallocating", "deallocated"
+	case azure.VMOperationRestart:
+		transition, final = "restarting", "running"
+	case azure.VMOperationDelete:
+		transition = "deleting"
+	default:
+		return nil, fmt.Errorf("不支持的操作类型: %s", opType)
+	}
+
+	target.PowerState = transition
+	apply := func() error {
+		if final == "" {
+			p.removeVMLocked(key)
+			return nil
+		}
+		if vm, ok := p.vms[key]; ok {
+			vm.PowerState = final
+		}
+		return nil
+	}
+
+	p.nextOp++
+	op := &pendingOperation{
+		token:     fmt.Sprintf("fake-operation-%d", p.nextOp),
+		remaining: p.PollSteps,
+		apply:     apply,
+	}
+	p.operations[op.token] = op
+	if op.remaining == 0 {
+		op.err = op.apply()
+		op.done = true
+	}
+	return &poller{provider: p, op: op}, nil
+}
+
+func (c *vmClient) CleanupVMResources(ctx context.Context, vm azure.VMDetails) error {
+	return c.provider.injected("CleanupVMResources")
+}
+
+func (c *vmClient) GetVMStatus(ctx context.Context, subscriptionID, resourceGroup, vmName string) (string, error) {
+	p := c.provider
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	vm, ok := p.vms[strings.ToLower(azure.BuildVMResourceID(subscriptionID, resourceGroup, vmName))]
+	if !ok {
+		return "", fmt.Errorf("虚拟机不存在: %s", vmName)
+	}
+	return vm.PowerState, nil
+}
+
+// poller 内存中的长时间操作轮询器，每次 Poll 推进一步
+type poller struct {
+	provider *Provider
+	op       *pendingOperation
+}
+
+func (p *poller) Poll(ctx context.Context) error {
+	p.provider.mu.Lock()
+	defer p.provider.mu.Unlock()
+	if p.op.done {
+		return nil
+	}
+	p.op.remaining--
+	if p.op.remaining <= 0 {
+		p.op.err = p.op.apply()
+		p.op.done = true
+	}
+	return nil
+}
+
+func (p *poller) Done() bool {
+	p.provider.mu.Lock()
+	defer p.provider.mu.Unlock()
+	return p.op.done
+}
+
+func (p *poller) Result(ctx context.Context) error {
+	p.provider.mu.Lock()
+	defer p.provider.mu.Unlock()
+	if !p.op.done {
+		return fmt.Errorf("操作尚未完成")
+	}
+	return p.op.err
+}
+
+func (p *poller) ResumeToken() (string, error) {
+	p.provider.mu.Lock()
+	defer p.provider.mu.Unlock()
+	if p.op.done {
+		return "", fmt.Errorf("操作已完成，无法获取恢复令牌")
+	}
+	return p.op.token, nil
+}
+
+// availableSizesLocked 虚拟机当前硬件集群可直接调整的规格
+func (p *Provider) availableSizesLocked(vm *azure.VMDetails) []string {
+	if sizes, ok := p.ClusterSizes[vm.Name]; ok {
+		return sizes
+	}
+	names := make([]string, 0, len(p.sizes[vm.Location]))
+	for _, size := range p.sizes[vm.Location] {
+		names = append(names, size.Name)
+	}
+	return names
+}
+
+// osDiskNameLocked 虚拟机当前的系统盘名称
+func (p *Provider) osDiskNameLocked(vm *azure.VMDetails) string {
+	if name, ok := p.osDisks[strings.ToLower(vm.ID)]; ok {
+		return name
+	}
+	return vm.Name + "_OsDisk"
+}
+
+// vmDisksLocked 虚拟机当前的磁盘配置
+func (p *Provider) vmDisksLocked(vm *azure.VMDetails) *azure.VMDisks {
+	return &azure.VMDisks{
+		OSDiskName: p.osDiskNameLocked(vm),
+		OSDiskSize: vm.OSDiskSize,
+		DataDisks:  append([]azure.DiskInfo(nil), vm.DataDisks...),
+	}
+}
+
+func (c *vmClient) ResetVMAccess(ctx context.Context, vm azure.VMDetails, opts azure.VMAccessResetOptions) (*azure.VMAccessResult, error) {
+	if err := c.provider.injected("ResetVMAccess"); err != nil {
+		return nil, err
+	}
+	p := c.provider
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	key := strings.ToLower(azure.BuildVMResourceID(vm.SubscriptionID, vm.ResourceGroup, vm.Name))
+	target, ok := p.vms[key]
+	if !ok {
+		return nil, fmt.Errorf("虚拟机不存在: %s", vm.Name)
+	}
+	if target.PowerState != "running" {
+		return nil, fmt.Errorf("虚拟机 %s 未运行，无法执行VMAccess扩展", vm.Name)
+	}
+	result := azure.VMAccessResult{
+		ExtensionName:     "enablevmaccess",
+		ExtensionType:     "VMAccessForLinux",
+		ProvisioningState: "Succeeded",
+		Status:            "Provisioning succeeded",
+		Message:           "Enable succeeded",
+	}
+	if strings.EqualFold(target.OSType, "Windows") {
+		result.ExtensionType = "VMAccessAgent"
+	}
+	p.vmAccess[key] = &vmAccessState{opts: opts, result: result}
+	return &result, nil
+}
+
+func (c *vmClient) GetVMAccessStatus(ctx context.Context, vm azure.VMDetails) (*azure.VMAccessResult, error) {
+	if err := c.provider.injected("GetVMAccessStatus"); err != nil {
+		return nil, err
+	}
+	p := c.provider
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	state, ok := p.vmAccess[strings.ToLower(azure.BuildVMResourceID(vm.SubscriptionID, vm.ResourceGroup, vm.Name))]
+	if !ok {
+		return nil, azure.ErrVMAccessNotInstalled
+	}
+	result := state.result
+	return &result, nil
+}
+
+func (c *vmClient) EnableBootDiagnostics(ctx context.Context, vm azure.VMDetails) error {
+	if err := c.provider.injected("EnableBootDiagnostics"); err != nil {
+		return err
+	}
+	p := c.provider
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	key := strings.ToLower(azure.BuildVMResourceID(vm.SubscriptionID, vm.ResourceGroup, vm.Name))
+	if _, ok := p.vms[key]; !ok {
+		return fmt.Errorf("虚拟机不存在: %s", vm.Name)
+	}
+	p.bootDiag[key] = true
+	return nil
+}
+
+func (c *vmClient) GetBootDiagnostics(ctx context.Context, vm azure.VMDetails, kind azure.BootDiagnosticsKind) (*azure.BootDiagnosticsData, error) {
+	if err := c.provider.injected("GetBootDiagnostics"); err != nil {
+		return nil, err
+	}
+	p := c.provider
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	key := strings.ToLower(azure.BuildVMResourceID(vm.SubscriptionID, vm.ResourceGroup, vm.Name))
+	if _, ok := p.vms[key]; !ok {
+		return nil, fmt.Errorf("虚拟机不存在: %s", vm.Name)
+	}
+	if !p.bootDiag[key] {
+		return nil, azure.ErrBootDiagnosticsDisabled
+	}
+
+	var data []byte
+	contentType := "text/plain; charset=utf-8"
+	switch kind {
+	case azure.BootDiagnosticsSerialLog:
+		if log, ok := p.SerialLogs[vm.Name]; ok {
+			data = []byte(log)
+		}
+	case azure.BootDiagnosticsScreenshot:
+		data, contentType = p.Screenshots[vm.Name], "image/bmp"
+	default:
+		return nil, fmt.Errorf("不支持的启动诊断数据类型: %s", kind)
+	}
+	if data == nil {
+		return nil, azure.ErrBootDiagnosticsUnavailable
+	}
+	return &azure.BootDiagnosticsData{
+		Body:          io.NopCloser(bytes.NewReader(data)),
+		ContentType:   contentType,
+		ContentLength: int64(len(data)),
+	}, nil
+}
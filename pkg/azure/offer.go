@@ -0,0 +1,78 @@
+package azure
+
+import "strings"
+
+// OfferType 订阅的优惠类型
+type OfferType string
+
+const (
+	OfferFreeTrial  OfferType = "FreeTrial"
+	OfferStudents   OfferType = "Students"
+	OfferPayAsYouGo OfferType = "PayAsYouGo"
+	OfferMSDN       OfferType = "MSDN"
+	OfferSponsored  OfferType = "Sponsored"
+	OfferEnterprise OfferType = "EA/CSP"
+	OfferOther      OfferType = "Other"
+)
+
+// OfferInfo 优惠类型及其赠送额度，额度金额因地区和等级不同时 CreditAmount 为 0
+type OfferInfo struct {
+	Type           OfferType
+	CreditAmount   float64
+	CreditCurrency string
+	// CreditExpires 赠送额度在订阅结束时失效，到期时间取订阅的 EndDate
+	CreditExpires bool
+}
+
+// offerQuotaPrefixes quotaId 的前缀与优惠类型，quotaId 形如 FreeTrial_2014-09-01
+var offerQuotaPrefixes = []struct {
+	prefix string
+	offer  OfferType
+}{
+	{"FreeTrial", OfferFreeTrial},
+	{"AzureForStudents", OfferStudents},
+	{"PayAsYouGo", OfferPayAsYouGo},
+	{"MSDNDevTest", OfferPayAsYouGo}, // 开发测试即用即付，没有赠送额度
+	{"MSDN", OfferMSDN},
+	{"Sponsored", OfferSponsored},
+	{"AzurePass", OfferSponsored},
+	{"EnterpriseAgreement", OfferEnterprise},
+	{"CSP", OfferEnterprise},
+}
+
+// ClassifyOffer 根据 quotaId 和消费限制判断订阅的优惠类型
+// 免费试用和学生订阅升级后 quotaId 可能尚未更新，但消费限制已被移除，按即用即付处理
+func ClassifyOffer(quotaID, spendingLimit string) OfferInfo {
+	offer := OfferOther
+	for _, item := range offerQuotaPrefixes {
+		if strings.HasPrefix(strings.ToLower(quotaID), strings.ToLower(item.prefix)) {
+			offer = item.offer
+			break
+		}
+	}
+
+	if (offer == OfferFreeTrial || offer == OfferStudents) && spendingLimit == string(SpendingLimitOff) {
+		offer = OfferPayAsYouGo
+	}
+
+	switch offer {
+	case OfferFreeTrial:
+		return OfferInfo{Type: offer, CreditAmount: 200, CreditCurrency: "USD", CreditExpires: true}
+	case OfferStudents:
+		return OfferInfo{Type: offer, CreditAmount: 100, CreditCurrency: "USD", CreditExpires: true}
+	case OfferSponsored:
+		return OfferInfo{Type: offer, CreditExpires: true}
+	default:
+		return OfferInfo{Type: offer}
+	}
+}
+
+// IsOfferType 判断是否为已知的优惠类型
+func IsOfferType(value string) bool {
+	switch OfferType(value) {
+	case OfferFreeTrial, OfferStudents, OfferPayAsYouGo, OfferMSDN, OfferSponsored, OfferEnterprise, OfferOther:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,46 @@
+package azure
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClassifyOffer(t *testing.T) {
+	cases := []struct {
+		quotaID       string
+		spendingLimit string
+		want          OfferType
+	}{
+		{"FreeTrial_2014-09-01", "On", OfferFreeTrial},
+		{"FreeTrial_2014-09-01", "Off", OfferPayAsYouGo},
+		{"AzureForStudents_2018-01-01", "On", OfferStudents},
+		{"AzureForStudents_2018-01-01", "Off", OfferPayAsYouGo},
+		{"PayAsYouGo_2014-09-01", "Off", OfferPayAsYouGo},
+		{"MSDNDevTest_2014-09-01", "Off", OfferPayAsYouGo},
+		{"MSDN_2014-09-01", "On", OfferMSDN},
+		{"MSDN_2014-09-01", "CurrentPeriodOff", OfferMSDN},
+		{"Sponsored_2016-01-01", "On", OfferSponsored},
+		{"AzurePass_2014-09-01", "On", OfferSponsored},
+		{"EnterpriseAgreement_2014-09-01", "Off", OfferEnterprise},
+		{"CSP_2015-05-01", "", OfferEnterprise},
+		{"Internal_2014-09-01", "Off", OfferOther},
+		{"", "", OfferOther},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.want, ClassifyOffer(c.quotaID, c.spendingLimit).Type, c.quotaID+"/"+c.spendingLimit)
+	}
+
+	trial := ClassifyOffer("FreeTrial_2014-09-01", "On")
+	assert.Equal(t, 200.0, trial.CreditAmount)
+	assert.Equal(t, "USD", trial.CreditCurrency)
+	assert.True(t, trial.CreditExpires)
+
+	// 升级后不再有赠送额度
+	upgraded := ClassifyOffer("FreeTrial_2014-09-01", "Off")
+	assert.Zero(t, upgraded.CreditAmount)
+	assert.False(t, upgraded.CreditExpires)
+
+	assert.True(t, IsOfferType("EA/CSP"))
+	assert.False(t, IsOfferType("freetrial"))
+}
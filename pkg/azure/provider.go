@@ -0,0 +1,156 @@
+package azure
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// VMClient 虚拟机相关操作，由 VMFetcher 实现
+type VMClient interface {
+	FetchVMDetails(ctx context.Context) (*VMFetchResult, error)
+	CreateVM(ctx context.Context, opts VMCreateOptions) (*VMDetails, error)
+	SetVMDNSLabel(ctx context.Context, subscriptionID, resourceGroup, publicIPName, dnsLabel string) (string, error)
+	ChangePublicIP(ctx context.Context, vm VMDetails, opts PublicIPChangeOptions) (*PublicIPChangeResult, error)
+	ListAvailableSizes(ctx context.Context, subscriptionID, resourceGroup, vmName string) ([]string, error)
+	ResizeVM(ctx context.Context, vm VMDetails, size string) (*VMResizeResult, error)
+	AttachDataDisk(ctx context.Context, vm VMDetails, opts DataDiskAttachOptions) (*VMDisks, error)
+	DetachDataDisk(ctx context.Context, vm VMDetails, diskName string, deleteDisk bool) (*VMDisks, error)
+	ExpandDisk(ctx context.Context, vm VMDetails, diskName string, sizeGB int32) (*VMDisks, error)
+	CreateSnapshot(ctx context.Context, vm VMDetails, diskName, name string) (*SnapshotInfo, error)
+	ListSnapshots(ctx context.Context, vm VMDetails) ([]SnapshotInfo, error)
+	DeleteSnapshot(ctx context.Context, subscriptionID, resourceGroup, name string) error
+	RestoreOSDisk(ctx context.Context, vm VMDetails, snapshotID string) (*OSDiskRestoreResult, error)
+	ListSecurityGroups(ctx context.Context, vm VMDetails) ([]SecurityGroupInfo, error)
+	AddSecurityRule(ctx context.Context, vm VMDetails, securityGroupID string, rule SecurityRule) ([]SecurityGroupInfo, error)
+	DeleteSecurityRule(ctx context.Context, vm VMDetails, securityGroupID, ruleName string) ([]SecurityGroupInfo, error)
+	CreateSecurityGroup(ctx context.Context, vm VMDetails, name string) ([]SecurityGroupInfo, error)
+	RunCommand(ctx context.Context, vm VMDetails, script string) (*RunCommandResult, error)
+	ResetVMAccess(ctx context.Context, vm VMDetails, opts VMAccessResetOptions) (*VMAccessResult, error)
+	GetVMAccessStatus(ctx context.Context, vm VMDetails) (*VMAccessResult, error)
+	EnableBootDiagnostics(ctx context.Context, vm VMDetails) error
+	GetBootDiagnostics(ctx context.Context, vm VMDetails, kind BootDiagnosticsKind) (*BootDiagnosticsData, error)
+	VMOperation(ctx context.Context, opType VMOperationType, vm VMDetails, opts *OperationOptions) error
+	BeginVMOperation(ctx context.Context, opType VMOperationType, vm VMDetails, opts *OperationOptions, resumeToken string) (OperationPoller, error)
+	CleanupVMResources(ctx context.Context, vm VMDetails) error
+	GetVMStatus(ctx context.Context, subscriptionID, resourceGroup, vmName string) (string, error)
+}
+
+// SubscriptionClient 订阅信息获取，由 Fetcher 实现
+type SubscriptionClient interface {
+	FetchSubscriptionDetails(ctx context.Context) ([]SubscriptionDetail, error)
+}
+
+// ImageClient 镜像信息获取，由 VMImageFetcher 实现
+type ImageClient interface {
+	ListPublishers(ctx context.Context, location string) ([]string, error)
+	ListOffers(ctx context.Context, location, publisher string) ([]string, error)
+	ListSKUs(ctx context.Context, location, publisher, offer string) ([]string, error)
+	ListVersions(ctx context.Context, location, publisher, offer, sku string) ([]string, error)
+	GetImage(ctx context.Context, location, publisher, offer, sku, version string) (*VMImageInfo, error)
+	SyncImages(ctx context.Context, location string) ([]*VMImageInfo, error)
+}
+
+// SizeClient 规格信息获取，由 VMSizeFetcher 实现
+type SizeClient interface {
+	ListSizes(ctx context.Context, location string) ([]*VMSizeInfo, error)
+	ListResourceSKUs(ctx context.Context, location string) ([]*VMSizeInfo, error)
+}
+
+// QuotaClient 计算资源配额获取，由 QuotaFetcher 实现
+type QuotaClient interface {
+	ListUsages(ctx context.Context, location string) ([]*QuotaUsage, error)
+}
+
+// PriceClient 规格零售价格获取，由 PriceFetcher 实现
+type PriceClient interface {
+	ListVMPrices(ctx context.Context, location string) ([]*VMPrice, error)
+	GetVMPrice(ctx context.Context, location, size string) (*VMPrice, error)
+}
+
+// CostClient 订阅实际费用获取，由 CostFetcher 实现
+type CostClient interface {
+	QueryDailyCosts(ctx context.Context, from, to time.Time) ([]*CostRecord, error)
+}
+
+// RegionClient 区域信息获取，由 RegionFetcher 实现
+type RegionClient interface {
+	GetRegions(ctx context.Context, cred *AzureCredential, subscriptionID string) ([]RegionInfo, error)
+	IsRegionAvailable(ctx context.Context, cred *AzureCredential, subscriptionID string, regionName string) (bool, error)
+}
+
+// CredentialValidator 凭据验证，由 Validator 实现
+type CredentialValidator interface {
+	ValidateWithContext(ctx context.Context, credentials Credentials) ValidationResult
+}
+
+// Provider Azure客户端工厂
+// 服务层只通过 Provider 获取客户端，测试时可替换为内存实现(见 pkg/azure/fake)
+type Provider interface {
+	VMClient(credentials *Credentials, logger *zap.Logger, timeout time.Duration) VMClient
+	SubscriptionClient(credentials *Credentials, logger *zap.Logger, timeout time.Duration) SubscriptionClient
+	ImageClient(subscriptionID string, credentials *AzureCredential, logger *zap.Logger) ImageClient
+	SizeClient(subscriptionID string, credentials *AzureCredential, logger *zap.Logger) SizeClient
+	QuotaClient(subscriptionID string, credentials *AzureCredential, logger *zap.Logger) QuotaClient
+	PriceClient(baseURL string, logger *zap.Logger) PriceClient
+	CostClient(subscriptionID string, credentials *AzureCredential, logger *zap.Logger) CostClient
+	RegionClient(logger *zap.Logger, retries int, timeout time.Duration) RegionClient
+	Validator(timeout time.Duration) CredentialValidator
+}
+
+var (
+	_ VMClient            = (*VMFetcher)(nil)
+	_ SubscriptionClient  = (*Fetcher)(nil)
+	_ ImageClient         = (*VMImageFetcher)(nil)
+	_ SizeClient          = (*VMSizeFetcher)(nil)
+	_ QuotaClient         = (*QuotaFetcher)(nil)
+	_ PriceClient         = (*PriceFetcher)(nil)
+	_ CostClient          = (*CostFetcher)(nil)
+	_ RegionClient        = (*RegionFetcher)(nil)
+	_ CredentialValidator = (*Validator)(nil)
+)
+
+// armProvider 基于Azure SDK的默认实现
+type armProvider struct{}
+
+// NewProvider 创建基于Azure SDK的客户端工厂
+func NewProvider() Provider {
+	return &armProvider{}
+}
+
+func (p *armProvider) VMClient(credentials *Credentials, logger *zap.Logger, timeout time.Duration) VMClient {
+	return NewVMFetcher(credentials, logger, timeout)
+}
+
+func (p *armProvider) SubscriptionClient(credentials *Credentials, logger *zap.Logger, timeout time.Duration) SubscriptionClient {
+	return NewFetcher(credentials, logger, timeout)
+}
+
+func (p *armProvider) ImageClient(subscriptionID string, credentials *AzureCredential, logger *zap.Logger) ImageClient {
+	return NewVMImageFetcher(subscriptionID, credentials, logger)
+}
+
+func (p *armProvider) SizeClient(subscriptionID string, credentials *AzureCredential, logger *zap.Logger) SizeClient {
+	return NewVMSizeFetcher(subscriptionID, credentials, logger)
+}
+
+func (p *armProvider) QuotaClient(subscriptionID string, credentials *AzureCredential, logger *zap.Logger) QuotaClient {
+	return NewQuotaFetcher(subscriptionID, credentials, logger)
+}
+
+func (p *armProvider) PriceClient(baseURL string, logger *zap.Logger) PriceClient {
+	return NewPriceFetcher(baseURL, logger)
+}
+
+func (p *armProvider) CostClient(subscriptionID string, credentials *AzureCredential, logger *zap.Logger) CostClient {
+	return NewCostFetcher(subscriptionID, credentials, logger)
+}
+
+func (p *armProvider) RegionClient(logger *zap.Logger, retries int, timeout time.Duration) RegionClient {
+	return NewRegionFetcher(logger, retries, timeout)
+}
+
+func (p *armProvider) Validator(timeout time.Duration) CredentialValidator {
+	return NewValidator(timeout)
+}
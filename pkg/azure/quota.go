@@ -0,0 +1,136 @@
+package azure
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/compute/armcompute/v5"
+	"go.uber.org/zap"
+)
+
+// 计算资源配额名称
+const (
+	// QuotaTotalRegionalVCPUs 区域总 vCPU
+	QuotaTotalRegionalVCPUs = "cores"
+	// QuotaSpotVCPUs 区域 Spot/低优先级 vCPU
+	QuotaSpotVCPUs = "lowPriorityCores"
+)
+
+// QuotaFetcher 用于获取订阅在指定区域的计算资源配额和用量
+type QuotaFetcher struct {
+	subscriptionID string
+	credentials    *AzureCredential
+	logger         *zap.Logger
+}
+
+// NewQuotaFetcher 创建QuotaFetcher实例
+func NewQuotaFetcher(subscriptionID string, credentials *AzureCredential, logger *zap.Logger) *QuotaFetcher {
+	return &QuotaFetcher{
+		subscriptionID: subscriptionID,
+		credentials:    credentials,
+		logger:         logger,
+	}
+}
+
+// QuotaUsage 单项配额的用量
+type QuotaUsage struct {
+	Name          string // 配额名称，例如 cores、standardDSv3Family
+	LocalizedName string // 显示名称，例如 Total Regional vCPUs
+	Location      string
+	CurrentValue  int64
+	Limit         int64
+	Unit          string
+}
+
+// ListUsages 通过 Usage API 获取指定区域的计算资源配额
+func (f *QuotaFetcher) ListUsages(ctx context.Context, location string) ([]*QuotaUsage, error) {
+	credential, err := f.credentials.GetCredential()
+	if err != nil {
+		return nil, fmt.Errorf("获取认证对象失败: %w", err)
+	}
+
+	client, err := armcompute.NewUsageClient(f.subscriptionID, credential, nil)
+	if err != nil {
+		return nil, fmt.Errorf("创建配额客户端失败: %w", err)
+	}
+
+	pager := client.NewListPager(location, nil)
+	var usages []*QuotaUsage
+
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("获取配额列表失败: %w", err)
+		}
+
+		for _, usage := range page.Value {
+			if usage == nil || usage.Name == nil || usage.Name.Value == nil {
+				continue
+			}
+
+			info := &QuotaUsage{
+				Name:     *usage.Name.Value,
+				Location: location,
+			}
+			if usage.Name.LocalizedValue != nil {
+				info.LocalizedName = *usage.Name.LocalizedValue
+			}
+			if usage.CurrentValue != nil {
+				info.CurrentValue = int64(*usage.CurrentValue)
+			}
+			if usage.Limit != nil {
+				info.Limit = *usage.Limit
+			}
+			if usage.Unit != nil {
+				info.Unit = string(*usage.Unit)
+			}
+			usages = append(usages, info)
+		}
+	}
+
+	f.logger.Debug("获取配额完成",
+		zap.String("subscriptionId", f.subscriptionID),
+		zap.String("location", location),
+		zap.Int("count", len(usages)))
+	return usages, nil
+}
+
+// IsVCPUFamilyQuota 判断配额是否为某个规格系列的 vCPU 配额
+func IsVCPUFamilyQuota(name string) bool {
+	return strings.HasSuffix(name, "Family")
+}
+
+// sizeNamePattern 规格名称，例如 Standard_D2s_v3、Standard_E4-2ads_v5、Standard_DS2_v2
+var sizeNamePattern = regexp.MustCompile(`(?i)^(?:standard|basic)_([a-z]+)\d+(?:-\d+)?([a-z]*)(?:_v(\d+))?`)
+
+// burstableFamilies B 系列各规格的配额系列不按后缀区分
+var burstableFamilies = map[string]string{
+	"":  "standardBSFamily",
+	"2": "standardBsv2Family",
+}
+
+// QuotaFamilyName 根据规格名称推断 vCPU 配额系列名称，例如 Standard_D2s_v3 -> standardDSv3Family
+// 规格名称与配额系列没有固定的对应关系，无法推断时返回空字符串，调用方只检查区域总配额
+func QuotaFamilyName(size string) string {
+	m := sizeNamePattern.FindStringSubmatch(size)
+	if m == nil {
+		return ""
+	}
+	series, features, version := strings.ToUpper(m[1]), strings.ToUpper(m[2]), m[3]
+	if series == "B" {
+		if version == "2" && strings.Contains(features, "A") {
+			return "standardBasv2Family"
+		}
+		if version == "2" && strings.Contains(features, "P") {
+			return "standardBpsv2Family"
+		}
+		return burstableFamilies[version]
+	}
+	name := "standard" + series + features
+	if version != "" {
+		name += "v" + version
+	}
+	return name + "Family"
+}
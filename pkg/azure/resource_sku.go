@@ -0,0 +1,169 @@
+package azure
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/compute/armcompute/v5"
+	"go.uber.org/zap"
+)
+
+// Resource SKU 中虚拟机规格的能力名称
+const (
+	skuCapabilityVCPUs                 = "vCPUs"
+	skuCapabilityMemoryGB              = "MemoryGB"
+	skuCapabilityMaxDataDiskCount      = "MaxDataDiskCount"
+	skuCapabilityOSVhdSizeMB           = "OSVhdSizeMB"
+	skuCapabilityAcceleratedNetworking = "AcceleratedNetworkingEnabled"
+	skuCapabilityPremiumIO             = "PremiumIO"
+	skuCapabilityHyperVGenerations     = "HyperVGenerations"
+)
+
+// ListResourceSKUs 通过 Resource SKUs API 获取指定区域的虚拟机规格
+// 与 ListSizes 不同，结果包含可用区、能力标记以及订阅级别的限制
+func (f *VMSizeFetcher) ListResourceSKUs(ctx context.Context, location string) ([]*VMSizeInfo, error) {
+	credential, err := f.credentials.GetCredential()
+	if err != nil {
+		return nil, fmt.Errorf("获取认证对象失败: %w", err)
+	}
+
+	client, err := armcompute.NewResourceSKUsClient(f.subscriptionID, credential, nil)
+	if err != nil {
+		return nil, fmt.Errorf("创建SKU客户端失败: %w", err)
+	}
+
+	// 过滤条件只支持区域
+	pager := client.NewListPager(&armcompute.ResourceSKUsClientListOptions{
+		Filter: to.Ptr(fmt.Sprintf("location eq '%s'", location)),
+	})
+	var sizes []*VMSizeInfo
+
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("获取SKU列表失败: %w", err)
+		}
+
+		for _, sku := range page.Value {
+			if info := sizeInfoFromSKU(sku, location); info != nil {
+				sizes = append(sizes, info)
+			}
+		}
+	}
+
+	f.logger.Debug("获取SKU完成",
+		zap.String("subscriptionId", f.subscriptionID),
+		zap.String("location", location),
+		zap.Int("count", len(sizes)))
+	return sizes, nil
+}
+
+// sizeInfoFromSKU 将虚拟机类型的 SKU 转换为规格信息，其他资源类型返回 nil
+func sizeInfoFromSKU(sku *armcompute.ResourceSKU, location string) *VMSizeInfo {
+	if sku == nil || sku.Name == nil || sku.ResourceType == nil || !strings.EqualFold(*sku.ResourceType, "virtualMachines") {
+		return nil
+	}
+
+	info := &VMSizeInfo{
+		Name:     *sku.Name,
+		Location: location,
+		Family:   extractVMFamily(*sku.Name),
+		Category: categorizeVMSize(*sku.Name),
+	}
+	if sku.Family != nil {
+		info.QuotaFamily = *sku.Family
+	}
+
+	for _, capability := range sku.Capabilities {
+		if capability == nil || capability.Name == nil || capability.Value == nil {
+			continue
+		}
+		value := *capability.Value
+		switch *capability.Name {
+		case skuCapabilityVCPUs:
+			info.Cores, _ = strconv.Atoi(value)
+		case skuCapabilityMemoryGB:
+			info.MemoryGB, _ = strconv.ParseFloat(value, 64)
+		case skuCapabilityMaxDataDiskCount:
+			info.MaxDataDisks, _ = strconv.Atoi(value)
+		case skuCapabilityOSVhdSizeMB:
+			mb, _ := strconv.Atoi(value)
+			info.OSDiskSizeGB = mb / 1024
+		case skuCapabilityAcceleratedNetworking:
+			info.AcceleratedNetworking = strings.EqualFold(value, "True")
+		case skuCapabilityPremiumIO:
+			info.PremiumIO = strings.EqualFold(value, "True")
+		case skuCapabilityHyperVGenerations:
+			info.HyperVGenerations = splitSKUList(value)
+		}
+	}
+
+	zones := make(map[string]bool)
+	for _, locationInfo := range sku.LocationInfo {
+		if locationInfo == nil || locationInfo.Location == nil || !strings.EqualFold(*locationInfo.Location, location) {
+			continue
+		}
+		for _, zone := range locationInfo.Zones {
+			if zone != nil {
+				zones[*zone] = true
+			}
+		}
+	}
+
+	// Location 类型的限制使规格在整个区域不可用，Zone 类型只排除对应的可用区
+	for _, restriction := range sku.Restrictions {
+		if restriction == nil || restriction.Type == nil {
+			continue
+		}
+		switch *restriction.Type {
+		case armcompute.ResourceSKURestrictionsTypeLocation:
+			if !skuValuesContain(restriction.Values, location) {
+				continue
+			}
+			info.Restricted = true
+			if restriction.ReasonCode != nil {
+				info.RestrictionReason = string(*restriction.ReasonCode)
+			}
+		case armcompute.ResourceSKURestrictionsTypeZone:
+			if restriction.RestrictionInfo == nil || !skuValuesContain(restriction.RestrictionInfo.Locations, location) {
+				continue
+			}
+			for _, zone := range restriction.RestrictionInfo.Zones {
+				if zone != nil {
+					delete(zones, *zone)
+				}
+			}
+		}
+	}
+
+	for zone := range zones {
+		info.Zones = append(info.Zones, zone)
+	}
+	sort.Strings(info.Zones)
+	return info
+}
+
+// splitSKUList 拆分逗号分隔的能力值，例如 V1,V2
+func splitSKUList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// skuValuesContain 判断 SKU 返回的列表中是否包含指定值，大小写不敏感
+func skuValuesContain(values []*string, value string) bool {
+	for _, v := range values {
+		if v != nil && strings.EqualFold(*v, value) {
+			return true
+		}
+	}
+	return false
+}
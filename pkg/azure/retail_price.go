@@ -0,0 +1,192 @@
+package azure
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// DefaultRetailPricesURL Azure 零售价格 API 地址，该接口无需认证
+const DefaultRetailPricesURL = "https://prices.azure.com/api/retail/prices"
+
+const (
+	retailPricesAPIVersion = "2023-01-01-preview"
+	retailPricesCurrency   = "USD"
+	// retailPricesMaxPages 防止分页链接异常时无限请求，单个区域的虚拟机价格通常不超过 100 页
+	retailPricesMaxPages = 500
+)
+
+// PriceFetcher 通过零售价格 API 获取虚拟机规格的按小时价格
+type PriceFetcher struct {
+	baseURL string
+	client  *http.Client
+	logger  *zap.Logger
+}
+
+// NewPriceFetcher 创建PriceFetcher实例，baseURL 为空时使用 DefaultRetailPricesURL
+func NewPriceFetcher(baseURL string, logger *zap.Logger) *PriceFetcher {
+	if baseURL == "" {
+		baseURL = DefaultRetailPricesURL
+	}
+	return &PriceFetcher{
+		baseURL: baseURL,
+		client:  &http.Client{Timeout: 30 * time.Second},
+		logger:  logger,
+	}
+}
+
+// VMPrice 规格在区域内的按小时价格，没有对应报价时为 0
+type VMPrice struct {
+	Size               string
+	Location           string
+	Currency           string
+	LinuxPerHour       float64
+	WindowsPerHour     float64
+	LinuxSpotPerHour   float64
+	WindowsSpotPerHour float64
+}
+
+// retailPricePage 零售价格 API 的分页响应
+type retailPricePage struct {
+	Items        []retailPriceItem `json:"Items"`
+	NextPageLink string            `json:"NextPageLink"`
+}
+
+type retailPriceItem struct {
+	CurrencyCode         string  `json:"currencyCode"`
+	RetailPrice          float64 `json:"retailPrice"`
+	ArmRegionName        string  `json:"armRegionName"`
+	ArmSkuName           string  `json:"armSkuName"`
+	ProductName          string  `json:"productName"`
+	SkuName              string  `json:"skuName"`
+	UnitOfMeasure        string  `json:"unitOfMeasure"`
+	Type                 string  `json:"type"`
+	IsPrimaryMeterRegion bool    `json:"isPrimaryMeterRegion"`
+}
+
+// ListVMPrices 获取区域内所有虚拟机规格的按量和 Spot 价格，按 NextPageLink 读取全部分页
+func (f *PriceFetcher) ListVMPrices(ctx context.Context, location string) ([]*VMPrice, error) {
+	return f.listPrices(ctx, location, "")
+}
+
+// GetVMPrice 获取单个规格在区域内的价格，没有报价时返回 nil
+func (f *PriceFetcher) GetVMPrice(ctx context.Context, location, size string) (*VMPrice, error) {
+	prices, err := f.listPrices(ctx, location, fmt.Sprintf(" and armSkuName eq '%s'", size))
+	if err != nil {
+		return nil, err
+	}
+	for _, price := range prices {
+		if strings.EqualFold(price.Size, size) {
+			return price, nil
+		}
+	}
+	return nil, nil
+}
+
+// listPrices 按区域和附加过滤条件获取价格
+func (f *PriceFetcher) listPrices(ctx context.Context, location, extraFilter string) ([]*VMPrice, error) {
+	query := url.Values{}
+	query.Set("api-version", retailPricesAPIVersion)
+	query.Set("currencyCode", retailPricesCurrency)
+	query.Set("$filter", fmt.Sprintf("serviceName eq 'Virtual Machines' and armRegionName eq '%s' and priceType eq 'Consumption'", location)+extraFilter)
+	next := f.baseURL + "?" + query.Encode()
+
+	bySize := make(map[string]*VMPrice)
+	var prices []*VMPrice
+	for page := 0; next != ""; page++ {
+		if page >= retailPricesMaxPages {
+			return nil, fmt.Errorf("价格分页超过 %d 页", retailPricesMaxPages)
+		}
+		resp, err := f.getPage(ctx, next)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range resp.Items {
+			kind, ok := classifyRetailPrice(item, location)
+			if !ok {
+				continue
+			}
+			price, ok := bySize[strings.ToLower(item.ArmSkuName)]
+			if !ok {
+				price = &VMPrice{Size: item.ArmSkuName, Location: location, Currency: item.CurrencyCode}
+				bySize[strings.ToLower(item.ArmSkuName)] = price
+				prices = append(prices, price)
+			}
+			// 同一规格存在多个计量时以主计量区域的报价为准
+			if target := kind.field(price); *target == 0 || item.IsPrimaryMeterRegion {
+				*target = item.RetailPrice
+			}
+		}
+		next = resp.NextPageLink
+	}
+
+	f.logger.Debug("获取规格价格完成",
+		zap.String("location", location),
+		zap.Int("count", len(prices)))
+	return prices, nil
+}
+
+// getPage 读取一页价格
+func (f *PriceFetcher) getPage(ctx context.Context, pageURL string) (*retailPricePage, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, pageURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("创建价格请求失败: %w", err)
+	}
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("获取价格失败: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("获取价格失败: HTTP %d", resp.StatusCode)
+	}
+
+	var page retailPricePage
+	if err := json.NewDecoder(resp.Body).Decode(&page); err != nil {
+		return nil, fmt.Errorf("解析价格失败: %w", err)
+	}
+	return &page, nil
+}
+
+// retailPriceKind 报价对应的操作系统和计费方式
+type retailPriceKind struct {
+	windows bool
+	spot    bool
+}
+
+func (k retailPriceKind) field(price *VMPrice) *float64 {
+	switch {
+	case k.windows && k.spot:
+		return &price.WindowsSpotPerHour
+	case k.windows:
+		return &price.WindowsPerHour
+	case k.spot:
+		return &price.LinuxSpotPerHour
+	default:
+		return &price.LinuxPerHour
+	}
+}
+
+// classifyRetailPrice 判断报价类型，低优先级、非按小时计费和其他区域的报价被忽略
+// Windows 报价的产品名以 Windows 结尾，Spot 报价的 SKU 名以 Spot 结尾
+func classifyRetailPrice(item retailPriceItem, location string) (retailPriceKind, bool) {
+	if item.ArmSkuName == "" || item.RetailPrice <= 0 || !strings.EqualFold(item.ArmRegionName, location) {
+		return retailPriceKind{}, false
+	}
+	if item.Type != "" && item.Type != "Consumption" {
+		return retailPriceKind{}, false
+	}
+	if item.UnitOfMeasure != "1 Hour" || strings.HasSuffix(item.SkuName, "Low Priority") {
+		return retailPriceKind{}, false
+	}
+	return retailPriceKind{
+		windows: strings.HasSuffix(item.ProductName, "Windows"),
+		spot:    strings.HasSuffix(item.SkuName, "Spot"),
+	}, true
+}
@@ -0,0 +1,80 @@
+package azure
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestPriceFetcher_ListVMPrices(t *testing.T) {
+	item := func(sku, product, skuName string, price float64) map[string]interface{} {
+		return map[string]interface{}{
+			"currencyCode":         "USD",
+			"retailPrice":          price,
+			"armRegionName":        "eastus",
+			"armSkuName":           sku,
+			"productName":          product,
+			"skuName":              skuName,
+			"unitOfMeasure":        "1 Hour",
+			"type":                 "Consumption",
+			"isPrimaryMeterRegion": true,
+		}
+	}
+
+	var filters []string
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		filters = append(filters, r.URL.Query().Get("$filter"))
+		page := map[string]interface{}{}
+		if r.URL.Query().Get("$skip") == "" {
+			page["Items"] = []interface{}{
+				item("Standard_D2s_v3", "Virtual Machines DSv3 Series", "D2s v3", 0.096),
+				item("Standard_D2s_v3", "Virtual Machines DSv3 Series Windows", "D2s v3", 0.188),
+				item("Standard_D2s_v3", "Virtual Machines DSv3 Series", "D2s v3 Spot", 0.0192),
+				item("Standard_D2s_v3", "Virtual Machines DSv3 Series", "D2s v3 Low Priority", 0.0192),
+			}
+			page["NextPageLink"] = server.URL + "/api/retail/prices?" + r.URL.RawQuery + "&$skip=100"
+		} else {
+			page["Items"] = []interface{}{
+				item("Standard_D2s_v3", "Virtual Machines DSv3 Series Windows", "D2s v3 Spot", 0.0376),
+				item("Standard_B1s", "Virtual Machines BS Series", "B1s", 0.0104),
+			}
+		}
+		require.NoError(t, json.NewEncoder(w).Encode(page))
+	}))
+	defer server.Close()
+
+	fetcher := NewPriceFetcher(server.URL+"/api/retail/prices", zap.NewNop())
+	prices, err := fetcher.ListVMPrices(context.Background(), "eastus")
+	require.NoError(t, err)
+	require.Len(t, filters, 2)
+	assert.True(t, strings.Contains(filters[0], "armRegionName eq 'eastus'"), filters[0])
+
+	require.Len(t, prices, 2)
+	assert.Equal(t, &VMPrice{
+		Size:               "Standard_D2s_v3",
+		Location:           "eastus",
+		Currency:           "USD",
+		LinuxPerHour:       0.096,
+		WindowsPerHour:     0.188,
+		LinuxSpotPerHour:   0.0192,
+		WindowsSpotPerHour: 0.0376,
+	}, prices[0])
+	assert.Equal(t, "Standard_B1s", prices[1].Size)
+	assert.Equal(t, 0.0104, prices[1].LinuxPerHour)
+	assert.Zero(t, prices[1].WindowsPerHour)
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer failing.Close()
+	_, err = NewPriceFetcher(failing.URL, zap.NewNop()).ListVMPrices(context.Background(), "eastus")
+	assert.Error(t, err)
+}
@@ -3,6 +3,7 @@ package azure
 import (
 	"context"
 	"fmt"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
 	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/compute/armcompute/v5"
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/network/armnetwork/v5"
@@ -60,6 +61,10 @@ type VMDetails struct {
 	MemoryInGB    int32  `json:"memoryInGB"`
 	DnsAlias      string `json:"dnsAlias"`
 	PublicIPName  string `json:"publicIpName"`
+	// 网卡及子网关联的网络安全组
+	SecurityGroups []SecurityGroupInfo `json:"securityGroups"`
+	// SecurityGroupsUnavailable 获取网络安全组失败，SecurityGroups 不完整，不应覆盖已保存的数据
+	SecurityGroupsUnavailable bool `json:"-"`
 	// 获取时间
 	FetchedAt time.Time `json:"fetchedAt"`
 
@@ -129,8 +134,37 @@ func extractSubscriptionID(subscriptionPath string) string {
 	return subscriptionPath
 }
 
+// SubscriptionVMResult 单个订阅的虚拟机获取结果
+type SubscriptionVMResult struct {
+	SubscriptionID string
+	DisplayName    string
+	State          string
+	VMCount        int
+	Err            error // 为空表示获取成功
+}
+
+// VMFetchResult 虚拟机获取结果，包含每个订阅各自的成功/失败情况
+type VMFetchResult struct {
+	VMs           []VMDetails
+	Subscriptions []SubscriptionVMResult
+}
+
+// FailedSubscriptions 获取失败的订阅数量
+func (r *VMFetchResult) FailedSubscriptions() int {
+	failed := 0
+	for _, sub := range r.Subscriptions {
+		if sub.Err != nil {
+			failed++
+		}
+	}
+	return failed
+}
+
 // FetchVMDetails 获取所有订阅下的虚拟机详细信息
-func (f *VMFetcher) FetchVMDetails(ctx context.Context) ([]VMDetails, error) {
+// 每个订阅只发起一次虚拟机列表(statusOnly)以及网卡、公网IP、虚拟网络和网络安全组各一次列表请求，
+// 规格信息按区域缓存，ARM调用次数只与订阅数和区域数相关。
+// 单个订阅失败不会影响其他订阅，失败原因记录在 Subscriptions 中；没有虚拟机不视为错误
+func (f *VMFetcher) FetchVMDetails(ctx context.Context) (*VMFetchResult, error) {
 	ctx, cancel := context.WithTimeout(ctx, f.timeout)
 	defer cancel()
 
@@ -150,92 +184,381 @@ func (f *VMFetcher) FetchVMDetails(ctx context.Context) ([]VMDetails, error) {
 		return nil, fmt.Errorf("创建Azure凭据失败: %w", err)
 	}
 
-	// 使用通道来管理并发
-	vmChan := make(chan VMDetails)
-	errChan := make(chan error)
+	// 2. 每个订阅并发获取，结果写入各自的槽位，无需通道
+	results := make([]SubscriptionVMResult, len(subscriptions))
+	vmsBySub := make([][]VMDetails, len(subscriptions))
+	sizes := newSizeCache()
 	var wg sync.WaitGroup
 
-	// 收集结果的切片
-	var allVMs []VMDetails
-
-	// 启动结果收集goroutine
-	done := make(chan bool)
-	go func() {
-		for vm := range vmChan {
-			allVMs = append(allVMs, vm)
-		}
-		done <- true
-	}()
-
-	// 为每个订阅创建一个goroutine
-	for _, sub := range subscriptions {
+	for i, sub := range subscriptions {
 		subscriptionID := extractSubscriptionID(sub.SubscriptionID)
+		results[i] = SubscriptionVMResult{
+			SubscriptionID: subscriptionID,
+			DisplayName:    sub.DisplayName,
+			State:          sub.State,
+		}
 		if subscriptionID == "" {
 			f.logger.Error("无效的订阅ID",
 				zap.String("rawSubscriptionId", sub.SubscriptionID))
+			results[i].Err = fmt.Errorf("无效的订阅ID: %s", sub.SubscriptionID)
 			continue
 		}
 
 		wg.Add(1)
-		go func(subscription SubscriptionDetail) {
+		go func(i int, subscriptionID string) {
 			defer wg.Done()
 
-			vmClient, err := armcompute.NewVirtualMachinesClient(subscription.SubscriptionID, cred, nil)
+			vms, err := f.fetchSubscriptionVMs(ctx, subscriptionID, cred, sizes)
 			if err != nil {
-				errChan <- fmt.Errorf("创建虚拟机客户端失败: %w", err)
+				f.logger.Error("获取订阅虚拟机失败",
+					zap.String("subscriptionId", subscriptionID),
+					zap.Error(err))
+				results[i].Err = err
 				return
 			}
+			vmsBySub[i] = vms
+			results[i].VMCount = len(vms)
+		}(i, subscriptionID)
+	}
+	wg.Wait()
+
+	result := &VMFetchResult{Subscriptions: results}
+	for _, vms := range vmsBySub {
+		result.VMs = append(result.VMs, vms...)
+	}
+
+	f.logger.Info("完成虚拟机详细信息获取",
+		zap.Int("totalVMs", len(result.VMs)),
+		zap.Int("subscriptions", len(results)),
+		zap.Int("failedSubscriptions", result.FailedSubscriptions()),
+		zap.Duration("duration", time.Since(startTime)))
+
+	return result, nil
+}
+
+// fetchSubscriptionVMs 批量获取单个订阅下的虚拟机详情
+func (f *VMFetcher) fetchSubscriptionVMs(ctx context.Context, subscriptionID string, cred *azidentity.ClientSecretCredential, sizes *sizeCache) ([]VMDetails, error) {
+	vmClient, err := armcompute.NewVirtualMachinesClient(subscriptionID, cred, nil)
+	if err != nil {
+		return nil, fmt.Errorf("创建虚拟机客户端失败: %w", err)
+	}
+
+	// 1. 列出虚拟机，statusOnly 同时返回实例视图中的电源状态
+	var vms []*armcompute.VirtualMachine
+	pager := vmClient.NewListAllPager(&armcompute.VirtualMachinesClientListAllOptions{
+		StatusOnly: to.Ptr("true"),
+	})
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("获取虚拟机列表失败: %w", err)
+		}
+		vms = append(vms, page.Value...)
+	}
+	if len(vms) == 0 {
+		return nil, nil
+	}
+
+	// 2. 一次性列出订阅内的网卡、公网IP、子网和网络安全组
+	network, err := listNetworkIndex(ctx, subscriptionID, cred, f.logger)
+	if err != nil {
+		return nil, err
+	}
+
+	sizeClient, err := armcompute.NewVirtualMachineSizesClient(subscriptionID, cred, nil)
+	if err != nil {
+		return nil, fmt.Errorf("创建VM规格客户端失败: %w", err)
+	}
+
+	// 3. 在内存中组装详情
+	result := make([]VMDetails, 0, len(vms))
+	for _, vm := range vms {
+		var specs map[string]sizeSpec
+		if vm.Location != nil {
+			specs = sizes.get(ctx, sizeClient, *vm.Location, f.logger)
+		}
+		vmDetail, err := f.extractVMDetails(subscriptionID, vm, network, specs)
+		if err != nil {
+			f.logger.Error("解析虚拟机详情失败",
+				zap.String("subscriptionId", subscriptionID),
+				zap.Error(err))
+			continue
+		}
+		f.logger.Debug("成功解析虚拟机详情",
+			zap.String("vmName", vmDetail.Name),
+			zap.String("vmId", vmDetail.ID),
+			zap.String("state", vmDetail.State))
+		result = append(result, vmDetail)
+	}
 
-			pager := vmClient.NewListAllPager(nil)
-			for pager.More() {
-				page, err := pager.NextPage(ctx)
+	return result, nil
+}
+
+// fetchVMDetail 获取单台虚拟机详情，用于创建等操作完成后刷新记录
+func (f *VMFetcher) fetchVMDetail(ctx context.Context, subscriptionID, resourceGroup, name string, cred *azidentity.ClientSecretCredential) (VMDetails, error) {
+	vmClient, err := armcompute.NewVirtualMachinesClient(subscriptionID, cred, nil)
+	if err != nil {
+		return VMDetails{}, fmt.Errorf("创建虚拟机客户端失败: %w", err)
+	}
+
+	resp, err := vmClient.Get(ctx, resourceGroup, name, &armcompute.VirtualMachinesClientGetOptions{
+		Expand: to.Ptr(armcompute.InstanceViewTypesInstanceView),
+	})
+	if err != nil {
+		return VMDetails{}, fmt.Errorf("获取虚拟机失败: %w", err)
+	}
+	vm := &resp.VirtualMachine
+
+	// 单台虚拟机只按需获取其网卡、公网IP和网络安全组
+	network := newNetworkIndex()
+	if vm.Properties != nil && vm.Properties.NetworkProfile != nil {
+		nicClient, err := armnetwork.NewInterfacesClient(subscriptionID, cred, nil)
+		if err != nil {
+			return VMDetails{}, fmt.Errorf("创建网络客户端失败: %w", err)
+		}
+		pipClient, err := armnetwork.NewPublicIPAddressesClient(subscriptionID, cred, nil)
+		if err != nil {
+			return VMDetails{}, fmt.Errorf("创建公共IP客户端失败: %w", err)
+		}
+		for _, ref := range vm.Properties.NetworkProfile.NetworkInterfaces {
+			if ref.ID == nil {
+				continue
+			}
+			nic, err := nicClient.Get(ctx, extractResourceGroupFromID(*ref.ID), extractResourceNameFromID(*ref.ID), nil)
+			if err != nil {
+				f.logger.Error("获取网络接口失败", zap.String("nicId", *ref.ID), zap.Error(err))
+				continue
+			}
+			network.nics[strings.ToLower(*ref.ID)] = &nic.Interface
+			if nic.Properties == nil {
+				continue
+			}
+			if err := f.loadSecurityGroups(ctx, subscriptionID, cred, &nic.Interface, network); err != nil {
+				f.logger.Error("获取网络安全组失败", zap.String("nicId", *ref.ID), zap.Error(err))
+			}
+			for _, ipConfig := range nic.Properties.IPConfigurations {
+				if ipConfig.Properties == nil || ipConfig.Properties.PublicIPAddress == nil || ipConfig.Properties.PublicIPAddress.ID == nil {
+					continue
+				}
+				pipID := *ipConfig.Properties.PublicIPAddress.ID
+				pip, err := pipClient.Get(ctx, extractResourceGroupFromID(pipID), extractResourceNameFromID(pipID), nil)
 				if err != nil {
-					errChan <- fmt.Errorf("获取虚拟机列表失败: %w", err)
-					return
+					f.logger.Error("获取公网IP失败", zap.String("publicIpId", pipID), zap.Error(err))
+					continue
 				}
+				network.publicIPs[strings.ToLower(pipID)] = &pip.PublicIPAddress
+			}
+		}
+	}
 
-				for _, vm := range page.Value {
-					vmDetail, err := f.extractVMDetails(ctx, subscription.SubscriptionID, vm, cred)
-					if err != nil {
-						f.logger.Error("解析虚拟机详情失败",
-							zap.String("subscriptionId", subscription.SubscriptionID),
-							zap.Error(err))
-						continue
-					}
-					f.logger.Debug("成功解析虚拟机详情",
-						zap.String("vmName", vmDetail.Name),
-						zap.String("vmId", vmDetail.ID),
-						zap.String("state", vmDetail.State))
-					vmChan <- vmDetail
+	var specs map[string]sizeSpec
+	if vm.Location != nil {
+		sizeClient, err := armcompute.NewVirtualMachineSizesClient(subscriptionID, cred, nil)
+		if err != nil {
+			return VMDetails{}, fmt.Errorf("创建VM规格客户端失败: %w", err)
+		}
+		specs = newSizeCache().get(ctx, sizeClient, *vm.Location, f.logger)
+	}
+
+	return f.extractVMDetails(subscriptionID, vm, network, specs)
+}
+
+// networkIndex 订阅内网卡、公网IP、子网与网络安全组的索引，key 为小写的资源ID
+type networkIndex struct {
+	nics           map[string]*armnetwork.Interface
+	publicIPs      map[string]*armnetwork.PublicIPAddress
+	subnets        map[string]*armnetwork.Subnet
+	securityGroups map[string]*armnetwork.SecurityGroup
+	// securityGroupsUnavailable 列出虚拟网络或网络安全组失败，subnets 和 securityGroups 不可用
+	securityGroupsUnavailable bool
+}
+
+func newNetworkIndex() *networkIndex {
+	return &networkIndex{
+		nics:           make(map[string]*armnetwork.Interface),
+		publicIPs:      make(map[string]*armnetwork.PublicIPAddress),
+		subnets:        make(map[string]*armnetwork.Subnet),
+		securityGroups: make(map[string]*armnetwork.SecurityGroup),
+	}
+}
+
+// listNetworkIndex 一次性列出订阅内的所有网卡、公网IP、子网和网络安全组
+// 网络安全组只用于展示，列出虚拟网络或网络安全组失败时记录日志并继续，不影响订阅同步
+func listNetworkIndex(ctx context.Context, subscriptionID string, cred *azidentity.ClientSecretCredential, logger *zap.Logger) (*networkIndex, error) {
+	index := newNetworkIndex()
+
+	nicClient, err := armnetwork.NewInterfacesClient(subscriptionID, cred, nil)
+	if err != nil {
+		return nil, fmt.Errorf("创建网络客户端失败: %w", err)
+	}
+	nicPager := nicClient.NewListAllPager(nil)
+	for nicPager.More() {
+		page, err := nicPager.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("获取网络接口列表失败: %w", err)
+		}
+		for _, nic := range page.Value {
+			if nic.ID != nil {
+				index.nics[strings.ToLower(*nic.ID)] = nic
+			}
+		}
+	}
+
+	pipClient, err := armnetwork.NewPublicIPAddressesClient(subscriptionID, cred, nil)
+	if err != nil {
+		return nil, fmt.Errorf("创建公共IP客户端失败: %w", err)
+	}
+	pipPager := pipClient.NewListAllPager(nil)
+	for pipPager.More() {
+		page, err := pipPager.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("获取公网IP列表失败: %w", err)
+		}
+		for _, pip := range page.Value {
+			if pip.ID != nil {
+				index.publicIPs[strings.ToLower(*pip.ID)] = pip
+			}
+		}
+	}
+
+	if err := listSecurityGroupIndex(ctx, subscriptionID, cred, index); err != nil {
+		logger.Warn("获取网络安全组信息失败，本次同步不更新网络安全组",
+			zap.String("subscriptionId", subscriptionID),
+			zap.Error(err))
+		index.subnets = make(map[string]*armnetwork.Subnet)
+		index.securityGroups = make(map[string]*armnetwork.SecurityGroup)
+		index.securityGroupsUnavailable = true
+	}
+
+	return index, nil
+}
+
+// listSecurityGroupIndex 列出订阅内的子网和网络安全组，子网关联的网络安全组只能从虚拟网络中获取
+func listSecurityGroupIndex(ctx context.Context, subscriptionID string, cred *azidentity.ClientSecretCredential, index *networkIndex) error {
+	vnetClient, err := armnetwork.NewVirtualNetworksClient(subscriptionID, cred, nil)
+	if err != nil {
+		return fmt.Errorf("创建虚拟网络客户端失败: %w", err)
+	}
+	vnetPager := vnetClient.NewListAllPager(nil)
+	for vnetPager.More() {
+		page, err := vnetPager.NextPage(ctx)
+		if err != nil {
+			return fmt.Errorf("获取虚拟网络列表失败: %w", err)
+		}
+		for _, vnet := range page.Value {
+			if vnet.Properties == nil {
+				continue
+			}
+			for _, subnet := range vnet.Properties.Subnets {
+				if subnet.ID != nil {
+					index.subnets[strings.ToLower(*subnet.ID)] = subnet
 				}
 			}
-		}(sub)
+		}
+	}
+
+	nsgClient, err := armnetwork.NewSecurityGroupsClient(subscriptionID, cred, nil)
+	if err != nil {
+		return fmt.Errorf("创建网络安全组客户端失败: %w", err)
 	}
+	nsgPager := nsgClient.NewListAllPager(nil)
+	for nsgPager.More() {
+		page, err := nsgPager.NextPage(ctx)
+		if err != nil {
+			return fmt.Errorf("获取网络安全组列表失败: %w", err)
+		}
+		for _, nsg := range page.Value {
+			if nsg.ID != nil {
+				index.securityGroups[strings.ToLower(*nsg.ID)] = nsg
+			}
+		}
+	}
+
+	return nil
+}
+
+// sizeSpec 规格的核心数与内存
+type sizeSpec struct {
+	cores    int32
+	memoryGB int32
+}
+
+// sizeCache 按区域缓存一次同步中的规格信息，不同区域可以并发请求，同一区域只请求一次
+type sizeCache struct {
+	mu      sync.Mutex
+	entries map[string]*sizeCacheEntry
+}
 
-	// 等待所有goroutine完成
-	go func() {
-		wg.Wait()
-		close(vmChan)
-		close(errChan)
-	}()
+// sizeCacheEntry 单个区域的规格表，once 保证并发访问时只请求一次
+type sizeCacheEntry struct {
+	once  sync.Once
+	specs map[string]sizeSpec
+}
 
-	// 等待结果收集完成
-	<-done
+func newSizeCache() *sizeCache {
+	return &sizeCache{entries: make(map[string]*sizeCacheEntry)}
+}
+
+// get 获取区域规格表，请求失败时缓存已获取的部分，本次同步内不再重试
+func (c *sizeCache) get(ctx context.Context, client *armcompute.VirtualMachineSizesClient, location string, logger *zap.Logger) map[string]sizeSpec {
+	return c.load(location, func() (map[string]sizeSpec, error) {
+		return listSizeSpecs(ctx, client, location)
+	}, logger)
+}
 
-	// 验证结果
-	if len(allVMs) == 0 {
-		return nil, fmt.Errorf("未找到有效的虚拟机记录")
+// load 返回区域的规格表，只在区域首次访问时调用 fetch，其他访问同一区域的调用等待其完成
+func (c *sizeCache) load(location string, fetch func() (map[string]sizeSpec, error), logger *zap.Logger) map[string]sizeSpec {
+	key := strings.ToLower(location)
+
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	if !ok {
+		entry = &sizeCacheEntry{}
+		c.entries[key] = entry
 	}
+	c.mu.Unlock()
 
-	f.logger.Info("完成虚拟机详细信息获取",
-		zap.Int("totalVMs", len(allVMs)),
-		zap.Duration("duration", time.Since(startTime)))
+	entry.once.Do(func() {
+		specs, err := fetch()
+		if err != nil {
+			logger.Error("获取VM规格列表失败", zap.String("location", location), zap.Error(err))
+		}
+		if specs == nil {
+			specs = make(map[string]sizeSpec)
+		}
+		entry.specs = specs
+	})
+	return entry.specs
+}
 
-	return allVMs, nil
+// listSizeSpecs 分页获取区域的规格列表，失败时同时返回已获取的部分
+func listSizeSpecs(ctx context.Context, client *armcompute.VirtualMachineSizesClient, location string) (map[string]sizeSpec, error) {
+	specs := make(map[string]sizeSpec)
+	pager := client.NewListPager(location, nil)
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return specs, err
+		}
+		for _, size := range page.Value {
+			if size.Name == nil {
+				continue
+			}
+			spec := sizeSpec{}
+			if size.NumberOfCores != nil {
+				spec.cores = *size.NumberOfCores
+			}
+			if size.MemoryInMB != nil {
+				spec.memoryGB = *size.MemoryInMB / 1024
+			}
+			specs[strings.ToLower(*size.Name)] = spec
+		}
+	}
+	return specs, nil
 }
 
-// extractVMDetails 从Azure VM响应中提取详细信息
-func (f *VMFetcher) extractVMDetails(ctx context.Context, subscriptionID string, vm *armcompute.VirtualMachine, cred *azidentity.ClientSecretCredential) (VMDetails, error) {
+// extractVMDetails 从Azure VM响应及预先获取的网络、规格索引中提取详细信息，不发起任何请求
+func (f *VMFetcher) extractVMDetails(subscriptionID string, vm *armcompute.VirtualMachine, network *networkIndex, sizes map[string]sizeSpec) (VMDetails, error) {
 	details := VMDetails{
 		SubscriptionID: subscriptionID,
 		FetchedAt:      time.Now(),
@@ -272,6 +595,10 @@ func (f *VMFetcher) extractVMDetails(ctx context.Context, subscriptionID string,
 		// 获取VM大小
 		if vm.Properties.HardwareProfile != nil && vm.Properties.HardwareProfile.VMSize != nil {
 			details.Size = string(*vm.Properties.HardwareProfile.VMSize)
+			if spec, ok := sizes[strings.ToLower(details.Size)]; ok {
+				details.NumberOfCores = spec.cores
+				details.MemoryInGB = spec.memoryGB
+			}
 		}
 
 		// 获取操作系统类型和镜像信息
@@ -307,58 +634,9 @@ func (f *VMFetcher) extractVMDetails(ctx context.Context, subscriptionID string,
 			details.State = *vm.Properties.ProvisioningState
 		}
 
-		// 如果有实例视图，获取更详细的状态
-		// 首先获取实例视图以获取最新状态
-		vmClient, err := armcompute.NewVirtualMachinesClient(subscriptionID, cred, nil)
-		if err != nil {
-			f.logger.Error("创建VM客户端失败", zap.Error(err))
-		} else {
-			instanceView, err := vmClient.InstanceView(ctx, details.ResourceGroup, details.Name, nil)
-			if err != nil {
-				f.logger.Error("获取VM实例视图失败",
-					zap.String("vmName", details.Name),
-					zap.Error(err))
-			} else if instanceView.Statuses != nil {
-				// 解析状态信息
-				var provisioningState, powerState string
-				for _, status := range instanceView.Statuses {
-					if status.Code == nil {
-						continue
-					}
-
-					code := *status.Code
-					// 处理配置状态
-					if strings.HasPrefix(code, "ProvisioningState/") {
-						provisioningState = strings.TrimPrefix(code, "ProvisioningState/")
-					}
-					// 处理电源状态
-					if strings.HasPrefix(code, "PowerState/") {
-						powerState = strings.TrimPrefix(code, "PowerState/")
-					}
-				}
-
-				// 设置状态信息
-				details.State = provisioningState // 部署状态
-				details.PowerState = powerState   // 电源状态
-
-				// 根据电源状态设置运行状态
-				switch powerState {
-				case "running":
-					details.Status = "Running"
-				case "stopped":
-					details.Status = "Stopped"
-				case "deallocated":
-					details.Status = "Deallocated"
-				case "starting":
-					details.Status = "Starting"
-				case "stopping":
-					details.Status = "Stopping"
-				case "deallocating":
-					details.Status = "Deallocating"
-				default:
-					details.Status = "Unknown"
-				}
-			}
+		// 列表请求已携带实例视图，直接解析电源状态
+		if vm.Properties.InstanceView != nil && vm.Properties.InstanceView.Statuses != nil {
+			applyInstanceStatuses(&details, vm.Properties.InstanceView.Statuses)
 		}
 
 		// 处理存储配置
@@ -400,107 +678,8 @@ func (f *VMFetcher) extractVMDetails(ctx context.Context, subscriptionID string,
 		}
 
 		// 处理网络配置
-		if vm.Properties.NetworkProfile != nil && vm.Properties.NetworkProfile.NetworkInterfaces != nil {
-			// 创建网络客户端
-			networkClient, err := armnetwork.NewInterfacesClient(subscriptionID, cred, nil)
-			if err != nil {
-				f.logger.Error("创建网络客户端失败",
-					zap.String("vmName", details.Name),
-					zap.Error(err))
-			} else {
-				for _, nic := range vm.Properties.NetworkProfile.NetworkInterfaces {
-					if nic.ID == nil {
-						continue
-					}
-
-					// 从网络接口ID中提取资源组和名称
-					nicResourceGroup := extractResourceGroupFromID(*nic.ID)
-					nicName := extractResourceNameFromID(*nic.ID)
-
-					// 获取网络接口详情
-					nicResponse, err := networkClient.Get(ctx, nicResourceGroup, nicName, nil)
-					if err != nil {
-						f.logger.Error("获取网络接口失败",
-							zap.String("vmName", details.Name),
-							zap.String("nicId", *nic.ID),
-							zap.Error(err))
-						continue
-					}
-
-					// 处理 IP 配置
-					if nicResponse.Properties != nil && nicResponse.Properties.IPConfigurations != nil {
-						for _, ipConfig := range nicResponse.Properties.IPConfigurations {
-							if ipConfig.Properties == nil {
-								continue
-							}
-
-							// 获取私有 IP
-							if ipConfig.Properties.PrivateIPAddress != nil {
-								details.PrivateIPs = append(details.PrivateIPs, *ipConfig.Properties.PrivateIPAddress)
-								f.logger.Debug("找到子网 IP",
-									zap.String("vmName", details.Name),
-									zap.String("privateIP", *ipConfig.Properties.PrivateIPAddress))
-							}
-
-							// 获取公网 IP（如果有）
-							if ipConfig.Properties.PublicIPAddress != nil && ipConfig.Properties.PublicIPAddress.ID != nil {
-								pubIPResourceGroup := extractResourceGroupFromID(*ipConfig.Properties.PublicIPAddress.ID)
-								pubIPName := extractResourceNameFromID(*ipConfig.Properties.PublicIPAddress.ID)
-
-								details.PublicIPName = pubIPName
-
-								f.logger.Debug("找到公共 IP 资源",
-									zap.String("vmName", details.Name),
-									zap.String("publicIpName", pubIPName),
-									zap.String("resourceGroup", pubIPResourceGroup))
-								// 创建公网 IP 客户端
-								pubIPClient, err := armnetwork.NewPublicIPAddressesClient(subscriptionID, cred, nil)
-								if err != nil {
-									f.logger.Error("创建公网IP客户端失败",
-										zap.String("vmName", details.Name),
-										zap.Error(err))
-									continue
-								}
-
-								// 获取公网 IP 详情
-								pubIP, err := pubIPClient.Get(ctx, pubIPResourceGroup, pubIPName, nil)
-								if err != nil {
-									f.logger.Error("获取公网IP失败",
-										zap.String("vmName", details.Name),
-										zap.String("publicIpId", *ipConfig.Properties.PublicIPAddress.ID),
-										zap.Error(err))
-									continue
-								}
-
-								if pubIP.Properties != nil && pubIP.Properties.IPAddress != nil {
-									details.PublicIPs = append(details.PublicIPs, *pubIP.Properties.IPAddress)
-									f.logger.Debug("找到公共 IP",
-										zap.String("vmName", details.Name),
-										zap.String("publicIP", *pubIP.Properties.IPAddress))
-
-								}
-								if pubIP.Properties.DNSSettings != nil && pubIP.Properties.DNSSettings.Fqdn != nil {
-									details.DnsAlias = *pubIP.Properties.DNSSettings.Fqdn
-									f.logger.Debug("找到 DNS 别名",
-										zap.String("vmName", details.Name),
-										zap.String("dnsAlias", details.DnsAlias))
-								} else if pubIP.Properties.DNSSettings != nil && pubIP.Properties.DNSSettings.DomainNameLabel != nil {
-									// 如果没有完整的 FQDN，但有 domain name label，我们也可以记录它
-									domainLabel := *pubIP.Properties.DNSSettings.DomainNameLabel
-									if pubIP.Properties.DNSSettings.DomainNameLabel != nil {
-										// 构造完整的 FQDN
-										location := details.Location
-										details.DnsAlias = fmt.Sprintf("%s.%s.cloudapp.azure.com", domainLabel, location)
-										f.logger.Debug("根据域名标签构建 DNS 别名",
-											zap.String("vmName", details.Name),
-											zap.String("dnsAlias", details.DnsAlias))
-									}
-								}
-							}
-						}
-					}
-				}
-			}
+		if vm.Properties.NetworkProfile != nil && network != nil {
+			f.applyNetwork(&details, vm.Properties.NetworkProfile.NetworkInterfaces, network)
 		}
 	}
 
@@ -513,36 +692,7 @@ func (f *VMFetcher) extractVMDetails(ctx context.Context, subscriptionID string,
 			}
 		}
 	}
-	// 获取虚拟机大小详情
-	if vm.Properties != nil && vm.Properties.HardwareProfile != nil && vm.Properties.HardwareProfile.VMSize != nil {
-		sizeClient, err := armcompute.NewVirtualMachineSizesClient(subscriptionID, cred, nil)
-		if err != nil {
-			f.logger.Error("创建VM规格客户端失败", zap.Error(err))
-		} else {
-			pager := sizeClient.NewListPager(details.Location, nil)
-			for pager.More() {
-				page, err := pager.NextPage(ctx)
-				if err != nil {
-					f.logger.Error("获取VM规格列表失败", zap.Error(err))
-					break
-				}
 
-				// 查找匹配的 VM 大小
-				vmSize := string(*vm.Properties.HardwareProfile.VMSize)
-				for _, size := range page.Value {
-					if size.Name != nil && *size.Name == vmSize {
-						if size.NumberOfCores != nil {
-							details.NumberOfCores = *size.NumberOfCores
-						}
-						if size.MemoryInMB != nil {
-							details.MemoryInGB = *size.MemoryInMB / 1024
-						}
-						break
-					}
-				}
-			}
-		}
-	}
 	// 记录获取到的VM详情
 	f.logger.Debug("提取的虚拟机详细信息",
 		zap.String("vmName", details.Name),
@@ -555,10 +705,113 @@ func (f *VMFetcher) extractVMDetails(ctx context.Context, subscriptionID string,
 		zap.String("provisioningState", details.State),
 		zap.Int32("osDiskSize", details.OSDiskSize),
 		zap.Int("dataDisksCount", len(details.DataDisks)))
-	// json 格式化输出details 对象
 	return details, nil
 }
 
+// applyInstanceStatuses 解析实例视图中的部署状态与电源状态
+func applyInstanceStatuses(details *VMDetails, statuses []*armcompute.InstanceViewStatus) {
+	var provisioningState, powerState string
+	for _, status := range statuses {
+		if status.Code == nil {
+			continue
+		}
+
+		code := *status.Code
+		// 处理配置状态
+		if strings.HasPrefix(code, "ProvisioningState/") {
+			provisioningState = strings.TrimPrefix(code, "ProvisioningState/")
+		}
+		// 处理电源状态
+		if strings.HasPrefix(code, "PowerState/") {
+			powerState = strings.TrimPrefix(code, "PowerState/")
+		}
+	}
+
+	// 设置状态信息
+	if provisioningState != "" {
+		details.State = provisioningState // 部署状态
+	}
+	details.PowerState = powerState // 电源状态
+
+	// 根据电源状态设置运行状态
+	switch powerState {
+	case "running":
+		details.Status = "Running"
+	case "stopped":
+		details.Status = "Stopped"
+	case "deallocated":
+		details.Status = "Deallocated"
+	case "starting":
+		details.Status = "Starting"
+	case "stopping":
+		details.Status = "Stopping"
+	case "deallocating":
+		details.Status = "Deallocating"
+	default:
+		details.Status = "Unknown"
+	}
+}
+
+// applyNetwork 根据网络索引填充私有IP、公网IP和DNS信息
+func (f *VMFetcher) applyNetwork(details *VMDetails, refs []*armcompute.NetworkInterfaceReference, network *networkIndex) {
+	for _, ref := range refs {
+		if ref.ID == nil {
+			continue
+		}
+
+		nic, ok := network.nics[strings.ToLower(*ref.ID)]
+		if !ok {
+			f.logger.Warn("未找到网络接口",
+				zap.String("vmName", details.Name),
+				zap.String("nicId", *ref.ID))
+			continue
+		}
+		if nic.Properties == nil {
+			continue
+		}
+		if network.securityGroupsUnavailable {
+			details.SecurityGroupsUnavailable = true
+		} else {
+			applySecurityGroups(details, nic, network)
+		}
+
+		// 处理 IP 配置
+		for _, ipConfig := range nic.Properties.IPConfigurations {
+			if ipConfig.Properties == nil {
+				continue
+			}
+
+			// 获取私有 IP
+			if ipConfig.Properties.PrivateIPAddress != nil {
+				details.PrivateIPs = append(details.PrivateIPs, *ipConfig.Properties.PrivateIPAddress)
+			}
+
+			// 获取公网 IP（如果有）
+			if ipConfig.Properties.PublicIPAddress == nil || ipConfig.Properties.PublicIPAddress.ID == nil {
+				continue
+			}
+			pubIPID := *ipConfig.Properties.PublicIPAddress.ID
+			details.PublicIPName = extractResourceNameFromID(pubIPID)
+
+			pubIP, ok := network.publicIPs[strings.ToLower(pubIPID)]
+			if !ok || pubIP.Properties == nil {
+				continue
+			}
+			if pubIP.Properties.IPAddress != nil {
+				details.PublicIPs = append(details.PublicIPs, *pubIP.Properties.IPAddress)
+			}
+			if dns := pubIP.Properties.DNSSettings; dns != nil {
+				if dns.Fqdn != nil {
+					details.DnsAlias = *dns.Fqdn
+				} else if dns.DomainNameLabel != nil {
+					// 如果没有完整的 FQDN，但有 domain name label，则构造完整的 FQDN
+					details.DnsAlias = fmt.Sprintf("%s.%s.cloudapp.azure.com", *dns.DomainNameLabel, details.Location)
+				}
+			}
+		}
+	}
+}
+
 // SetVMDNSLabel 为虚拟机设置 DNS 名称标签，返回设置后的FQDN
 func (f *VMFetcher) SetVMDNSLabel(ctx context.Context, subscriptionID, resourceGroup, publicIPName, dnsLabel string) (string, error) {
 	// 创建凭据
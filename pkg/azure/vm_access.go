@@ -0,0 +1,210 @@
+package azure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/compute/armcompute/v5"
+	"go.uber.org/zap"
+)
+
+// VMAccess 扩展，Linux 和 Windows 分别使用不同的发布者和版本
+const (
+	vmAccessExtensionName = "enablevmaccess"
+
+	vmAccessLinuxPublisher   = "Microsoft.OSTCExtensions"
+	vmAccessLinuxType        = "VMAccessForLinux"
+	vmAccessLinuxVersion     = "1.5"
+	vmAccessWindowsPublisher = "Microsoft.Compute"
+	vmAccessWindowsType      = "VMAccessAgent"
+	vmAccessWindowsVersion   = "2.4"
+)
+
+// ErrVMAccessNotInstalled 虚拟机没有安装 VMAccess 扩展
+var ErrVMAccessNotInstalled = errors.New("虚拟机未安装VMAccess扩展")
+
+// VMAccessResetOptions 重置虚拟机访问的参数
+// Linux 可以重置密码、替换 SSH 公钥或修复 sshd 配置；Windows 只能重置密码
+type VMAccessResetOptions struct {
+	Username     string
+	Password     string
+	SSHPublicKey string
+	// ResetSSH 将 sshd 配置恢复为默认值，仅 Linux 生效
+	ResetSSH bool
+}
+
+// VMAccessResult VMAccess 扩展的执行结果
+type VMAccessResult struct {
+	ExtensionName     string `json:"extensionName"`
+	ExtensionType     string `json:"extensionType"`
+	ProvisioningState string `json:"provisioningState"` // Succeeded/Failed/Updating
+	// Status 扩展在虚拟机内的执行状态，例如 "Provisioning succeeded"
+	Status  string `json:"status"`
+	Message string `json:"message"`
+}
+
+// ResetVMAccess 安装或更新 VMAccess 扩展重置管理员密码、SSH 公钥或 sshd 配置，等待扩展执行完成
+// 虚拟机已安装同类型扩展时沿用原扩展名，Azure 不允许同一虚拟机安装两个同类型扩展
+func (f *VMFetcher) ResetVMAccess(ctx context.Context, vm VMDetails, opts VMAccessResetOptions) (*VMAccessResult, error) {
+	cred, err := createAzureCredential(f.credentials)
+	if err != nil {
+		return nil, fmt.Errorf("创建Azure凭据失败: %w", err)
+	}
+
+	client, err := armcompute.NewVirtualMachineExtensionsClient(vm.SubscriptionID, cred, nil)
+	if err != nil {
+		return nil, fmt.Errorf("创建扩展客户端失败: %w", err)
+	}
+
+	extension := buildVMAccessExtension(vm, opts)
+	name, err := f.findVMAccessExtension(ctx, client, vm)
+	if err != nil {
+		return nil, err
+	}
+	if name == "" {
+		name = vmAccessExtensionName
+	}
+
+	f.logger.Info("开始重置虚拟机访问",
+		zap.String("vmName", vm.Name),
+		zap.String("extension", name),
+		zap.Bool("password", opts.Password != ""),
+		zap.Bool("sshKey", opts.SSHPublicKey != ""),
+		zap.Bool("resetSSH", opts.ResetSSH))
+
+	poller, err := client.BeginCreateOrUpdate(ctx, vm.ResourceGroup, vm.Name, name, extension, nil)
+	if err != nil {
+		return nil, fmt.Errorf("提交VMAccess扩展失败: %w", err)
+	}
+	if _, err := poller.PollUntilDone(ctx, nil); err != nil {
+		// 扩展执行失败时返回扩展报告的原因
+		if result, getErr := f.getVMAccessResult(ctx, client, vm, name); getErr == nil && result.Message != "" {
+			return nil, fmt.Errorf("VMAccess扩展执行失败: %s: %w", result.Message, err)
+		}
+		return nil, fmt.Errorf("VMAccess扩展执行失败: %w", err)
+	}
+	return f.getVMAccessResult(ctx, client, vm, name)
+}
+
+// GetVMAccessStatus 获取 VMAccess 扩展最近一次的执行结果，未安装时返回 ErrVMAccessNotInstalled
+func (f *VMFetcher) GetVMAccessStatus(ctx context.Context, vm VMDetails) (*VMAccessResult, error) {
+	cred, err := createAzureCredential(f.credentials)
+	if err != nil {
+		return nil, fmt.Errorf("创建Azure凭据失败: %w", err)
+	}
+
+	client, err := armcompute.NewVirtualMachineExtensionsClient(vm.SubscriptionID, cred, nil)
+	if err != nil {
+		return nil, fmt.Errorf("创建扩展客户端失败: %w", err)
+	}
+
+	name, err := f.findVMAccessExtension(ctx, client, vm)
+	if err != nil {
+		return nil, err
+	}
+	if name == "" {
+		return nil, ErrVMAccessNotInstalled
+	}
+	return f.getVMAccessResult(ctx, client, vm, name)
+}
+
+// findVMAccessExtension 查找虚拟机上已安装的 VMAccess 扩展名称，未安装时返回空字符串
+func (f *VMFetcher) findVMAccessExtension(ctx context.Context, client *armcompute.VirtualMachineExtensionsClient, vm VMDetails) (string, error) {
+	resp, err := client.List(ctx, vm.ResourceGroup, vm.Name, nil)
+	if err != nil {
+		return "", fmt.Errorf("获取虚拟机扩展失败: %w", err)
+	}
+	for _, ext := range resp.Value {
+		if ext == nil || ext.Name == nil || ext.Properties == nil || ext.Properties.Type == nil {
+			continue
+		}
+		if strings.EqualFold(*ext.Properties.Type, vmAccessLinuxType) || strings.EqualFold(*ext.Properties.Type, vmAccessWindowsType) {
+			return *ext.Name, nil
+		}
+	}
+	return "", nil
+}
+
+// getVMAccessResult 读取扩展的实例视图
+func (f *VMFetcher) getVMAccessResult(ctx context.Context, client *armcompute.VirtualMachineExtensionsClient, vm VMDetails, name string) (*VMAccessResult, error) {
+	resp, err := client.Get(ctx, vm.ResourceGroup, vm.Name, name, &armcompute.VirtualMachineExtensionsClientGetOptions{
+		Expand: to.Ptr("instanceView"),
+	})
+	if err != nil {
+		if isNotFound(err) {
+			return nil, ErrVMAccessNotInstalled
+		}
+		return nil, fmt.Errorf("获取VMAccess扩展状态失败: %w", err)
+	}
+
+	result := &VMAccessResult{ExtensionName: name}
+	if props := resp.Properties; props != nil {
+		if props.Type != nil {
+			result.ExtensionType = *props.Type
+		}
+		if props.ProvisioningState != nil {
+			result.ProvisioningState = *props.ProvisioningState
+		}
+		if view := props.InstanceView; view != nil {
+			var messages []string
+			for _, status := range view.Statuses {
+				if status == nil {
+					continue
+				}
+				if status.DisplayStatus != nil && result.Status == "" {
+					result.Status = *status.DisplayStatus
+				}
+				if status.Message != nil && *status.Message != "" {
+					messages = append(messages, *status.Message)
+				}
+			}
+			result.Message = strings.Join(messages, "; ")
+		}
+	}
+	return result, nil
+}
+
+// buildVMAccessExtension 按操作系统类型构建扩展参数，密码和公钥放在受保护的设置中
+func buildVMAccessExtension(vm VMDetails, opts VMAccessResetOptions) armcompute.VirtualMachineExtension {
+	props := &armcompute.VirtualMachineExtensionProperties{
+		AutoUpgradeMinorVersion: to.Ptr(true),
+		// 每次提交使用不同的标记，使相同设置也会重新执行
+		ForceUpdateTag: to.Ptr(strconv.FormatInt(time.Now().UnixNano(), 10)),
+	}
+
+	if isWindows(vm.OSType) {
+		props.Publisher = to.Ptr(vmAccessWindowsPublisher)
+		props.Type = to.Ptr(vmAccessWindowsType)
+		props.TypeHandlerVersion = to.Ptr(vmAccessWindowsVersion)
+		props.Settings = map[string]interface{}{"UserName": opts.Username}
+		props.ProtectedSettings = map[string]interface{}{"Password": opts.Password}
+	} else {
+		protected := map[string]interface{}{}
+		if opts.Username != "" {
+			protected["username"] = opts.Username
+		}
+		if opts.Password != "" {
+			protected["password"] = opts.Password
+		}
+		if opts.SSHPublicKey != "" {
+			protected["ssh_key"] = opts.SSHPublicKey
+		}
+		if opts.ResetSSH {
+			protected["reset_ssh"] = true
+		}
+		props.Publisher = to.Ptr(vmAccessLinuxPublisher)
+		props.Type = to.Ptr(vmAccessLinuxType)
+		props.TypeHandlerVersion = to.Ptr(vmAccessLinuxVersion)
+		props.ProtectedSettings = protected
+	}
+
+	return armcompute.VirtualMachineExtension{
+		Location:   to.Ptr(vm.Location),
+		Properties: props,
+	}
+}
@@ -0,0 +1,163 @@
+package azure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/compute/armcompute/v5"
+	"go.uber.org/zap"
+)
+
+// BootDiagnosticsKind 启动诊断数据类型
+type BootDiagnosticsKind string
+
+const (
+	// BootDiagnosticsSerialLog 串行控制台日志
+	BootDiagnosticsSerialLog BootDiagnosticsKind = "serial-log"
+	// BootDiagnosticsScreenshot 控制台截图
+	BootDiagnosticsScreenshot BootDiagnosticsKind = "screenshot"
+)
+
+// bootDiagnosticsSASMinutes 启动诊断数据下载链接的有效期，只用于本次读取
+const bootDiagnosticsSASMinutes int32 = 5
+
+// bootDiagnosticsHTTPClient 下载启动诊断数据的客户端，超时包含读取响应体，避免存储端无响应时请求一直挂起
+var bootDiagnosticsHTTPClient = &http.Client{Timeout: 2 * time.Minute}
+
+var (
+	// ErrBootDiagnosticsDisabled 虚拟机未启用启动诊断
+	ErrBootDiagnosticsDisabled = errors.New("虚拟机未启用启动诊断")
+	// ErrBootDiagnosticsUnavailable 启动诊断已启用但还没有生成数据，通常是虚拟机启用后尚未重新启动
+	ErrBootDiagnosticsUnavailable = errors.New("启动诊断数据尚未生成")
+)
+
+// BootDiagnosticsData 启动诊断数据，调用方负责关闭 Body
+type BootDiagnosticsData struct {
+	Body          io.ReadCloser
+	ContentType   string
+	ContentLength int64 // 未知时为 -1
+}
+
+// EnableBootDiagnostics 启用使用托管存储账户的启动诊断，已启用托管存储时不做修改
+func (f *VMFetcher) EnableBootDiagnostics(ctx context.Context, vm VMDetails) error {
+	cred, err := createAzureCredential(f.credentials)
+	if err != nil {
+		return fmt.Errorf("创建Azure凭据失败: %w", err)
+	}
+
+	client, err := armcompute.NewVirtualMachinesClient(vm.SubscriptionID, cred, nil)
+	if err != nil {
+		return fmt.Errorf("创建虚拟机客户端失败: %w", err)
+	}
+
+	resp, err := client.Get(ctx, vm.ResourceGroup, vm.Name, nil)
+	if err != nil {
+		return fmt.Errorf("获取虚拟机信息失败: %w", err)
+	}
+	if diag := bootDiagnosticsOf(&resp.VirtualMachine); diag != nil && diag.Enabled != nil && *diag.Enabled && diag.StorageURI == nil {
+		return nil
+	}
+
+	f.logger.Info("启用启动诊断", zap.String("vmName", vm.Name))
+	// StorageURI 显式置空，原先使用自定义存储账户的虚拟机也改为托管存储
+	poller, err := client.BeginUpdate(ctx, vm.ResourceGroup, vm.Name, armcompute.VirtualMachineUpdate{
+		Properties: &armcompute.VirtualMachineProperties{
+			DiagnosticsProfile: &armcompute.DiagnosticsProfile{
+				BootDiagnostics: &armcompute.BootDiagnostics{
+					Enabled:    to.Ptr(true),
+					StorageURI: azcore.NullValue[*string](),
+				},
+			},
+		},
+	}, nil)
+	if err == nil {
+		_, err = poller.PollUntilDone(ctx, nil)
+	}
+	if err != nil {
+		return fmt.Errorf("启用启动诊断失败: %w", err)
+	}
+	return nil
+}
+
+// GetBootDiagnostics 通过 RetrieveBootDiagnosticsData 获取短期下载链接并读取串行日志或截图
+func (f *VMFetcher) GetBootDiagnostics(ctx context.Context, vm VMDetails, kind BootDiagnosticsKind) (*BootDiagnosticsData, error) {
+	if kind != BootDiagnosticsSerialLog && kind != BootDiagnosticsScreenshot {
+		return nil, fmt.Errorf("不支持的启动诊断数据类型: %s", kind)
+	}
+
+	cred, err := createAzureCredential(f.credentials)
+	if err != nil {
+		return nil, fmt.Errorf("创建Azure凭据失败: %w", err)
+	}
+
+	client, err := armcompute.NewVirtualMachinesClient(vm.SubscriptionID, cred, nil)
+	if err != nil {
+		return nil, fmt.Errorf("创建虚拟机客户端失败: %w", err)
+	}
+
+	// 未启用时 Azure 返回的错误信息不固定，先检查虚拟机配置
+	vmResp, err := client.Get(ctx, vm.ResourceGroup, vm.Name, nil)
+	if err != nil {
+		return nil, fmt.Errorf("获取虚拟机信息失败: %w", err)
+	}
+	if diag := bootDiagnosticsOf(&vmResp.VirtualMachine); diag == nil || diag.Enabled == nil || !*diag.Enabled {
+		return nil, ErrBootDiagnosticsDisabled
+	}
+
+	resp, err := client.RetrieveBootDiagnosticsData(ctx, vm.ResourceGroup, vm.Name, &armcompute.VirtualMachinesClientRetrieveBootDiagnosticsDataOptions{
+		SasURIExpirationTimeInMinutes: to.Ptr(bootDiagnosticsSASMinutes),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("获取启动诊断数据失败: %w", err)
+	}
+
+	blobURI, contentType := resp.SerialConsoleLogBlobURI, "text/plain; charset=utf-8"
+	if kind == BootDiagnosticsScreenshot {
+		blobURI, contentType = resp.ConsoleScreenshotBlobURI, "image/bmp"
+	}
+	if blobURI == nil || *blobURI == "" {
+		return nil, ErrBootDiagnosticsUnavailable
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, *blobURI, nil)
+	if err != nil {
+		return nil, fmt.Errorf("创建下载请求失败: %w", err)
+	}
+	blobResp, err := bootDiagnosticsHTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("下载启动诊断数据失败: %w", err)
+	}
+	switch {
+	case blobResp.StatusCode == http.StatusNotFound:
+		blobResp.Body.Close()
+		return nil, ErrBootDiagnosticsUnavailable
+	case blobResp.StatusCode != http.StatusOK:
+		blobResp.Body.Close()
+		return nil, fmt.Errorf("下载启动诊断数据失败: HTTP %d", blobResp.StatusCode)
+	}
+
+	if kind == BootDiagnosticsScreenshot {
+		if value := blobResp.Header.Get("Content-Type"); value != "" && value != "application/octet-stream" {
+			contentType = value
+		}
+	}
+	return &BootDiagnosticsData{
+		Body:          blobResp.Body,
+		ContentType:   contentType,
+		ContentLength: blobResp.ContentLength,
+	}, nil
+}
+
+// bootDiagnosticsOf 获取虚拟机的启动诊断配置
+func bootDiagnosticsOf(vm *armcompute.VirtualMachine) *armcompute.BootDiagnostics {
+	if vm.Properties == nil || vm.Properties.DiagnosticsProfile == nil {
+		return nil
+	}
+	return vm.Properties.DiagnosticsProfile.BootDiagnostics
+}
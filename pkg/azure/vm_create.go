@@ -0,0 +1,584 @@
+package azure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/compute/armcompute/v5"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/network/armnetwork/v5"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/resources/armresources"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultAddressPrefix = "10.0.0.0/16"
+	defaultSubnetPrefix  = "10.0.0.0/24"
+	defaultSubnetName    = "default"
+	defaultOSDiskType    = "StandardSSD_LRS"
+)
+
+// VMCreateOptions 创建虚拟机所需的全部参数
+type VMCreateOptions struct {
+	SubscriptionID string
+	ResourceGroup  string
+	Name           string
+	Location       string
+	Size           string
+	OSType         string // Windows/Linux
+	OSDiskSizeGB   int32
+	OSDiskType     string
+
+	// 镜像信息
+	ImagePublisher string
+	ImageOffer     string
+	ImageSku       string
+	ImageVersion   string
+
+	// 登录信息
+	AdminUsername string
+	AdminPassword string
+	SSHPublicKey  string
+
+	DataDisks []DataDiskOptions
+	Network   NetworkOptions
+	Tags      map[string]string
+}
+
+// DataDiskOptions 数据磁盘参数
+type DataDiskOptions struct {
+	Name     string
+	SizeGB   int32
+	DiskType string
+}
+
+// NetworkOptions 网络参数
+type NetworkOptions struct {
+	VNetName       string
+	SubnetName     string
+	AddressPrefix  string
+	SubnetPrefix   string
+	PublicIP       bool
+	SecurityGroups []string
+}
+
+// BuildVMResourceID 根据订阅、资源组和名称拼接虚拟机资源ID
+func BuildVMResourceID(subscriptionID, resourceGroup, name string) string {
+	return fmt.Sprintf("/subscriptions/%s/resourceGroups/%s/providers/Microsoft.Compute/virtualMachines/%s",
+		subscriptionID, resourceGroup, name)
+}
+
+// isWindows 判断操作系统类型是否为 Windows
+func isWindows(osType string) bool {
+	return strings.EqualFold(osType, string(armcompute.OperatingSystemTypesWindows))
+}
+
+// CreateVM 按参数依次创建资源组、虚拟网络/子网、网络安全组、公网IP、网卡和虚拟机
+func (f *VMFetcher) CreateVM(ctx context.Context, opts VMCreateOptions) (*VMDetails, error) {
+	cred, err := createAzureCredential(f.credentials)
+	if err != nil {
+		return nil, fmt.Errorf("创建Azure凭据失败: %w", err)
+	}
+
+	logger := f.logger.With(
+		zap.String("subscriptionId", opts.SubscriptionID),
+		zap.String("resourceGroup", opts.ResourceGroup),
+		zap.String("vmName", opts.Name),
+	)
+	logger.Info("开始创建虚拟机")
+
+	// 1. 资源组
+	if err := f.ensureResourceGroup(ctx, cred, opts); err != nil {
+		return nil, err
+	}
+
+	// 2. 虚拟网络和子网
+	subnetID, err := f.ensureSubnet(ctx, cred, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// 3. 网络安全组，之后的步骤失败时删除本次新建的网络资源
+	var created createdResources
+	nsgID, nsgCreated, err := f.ensureSecurityGroup(ctx, cred, opts)
+	if err != nil {
+		return nil, err
+	}
+	if nsgCreated {
+		created.nsgName = securityGroupName(opts)
+	}
+
+	// 4. 公网IP
+	var publicIPID string
+	if opts.Network.PublicIP {
+		publicIPID, err = f.createPublicIP(ctx, cred, opts)
+		if err != nil {
+			f.rollbackCreate(cred, opts, created, logger)
+			return nil, err
+		}
+		created.publicIPName = opts.Name + "-ip"
+	}
+
+	// 5. 网卡
+	nicID, err := f.createNIC(ctx, cred, opts, subnetID, nsgID, publicIPID)
+	if err != nil {
+		f.rollbackCreate(cred, opts, created, logger)
+		return nil, err
+	}
+	created.nicName = opts.Name + "-nic"
+
+	// 6. 虚拟机，提交后失败时虚拟机和磁盘可能已经存在，同样需要清理
+	if _, submitted, err := f.createVirtualMachine(ctx, cred, opts, nicID); err != nil {
+		created.vmSubmitted = submitted
+		f.rollbackCreate(cred, opts, created, logger)
+		return nil, err
+	}
+
+	details, err := f.fetchVMDetail(ctx, opts.SubscriptionID, opts.ResourceGroup, opts.Name, cred)
+	if err != nil {
+		return nil, fmt.Errorf("解析新建虚拟机详情失败: %w", err)
+	}
+
+	logger.Info("虚拟机创建成功", zap.String("vmId", details.ID))
+	return &details, nil
+}
+
+// createdResources CreateVM 过程中新建的资源，为空表示未创建或使用了已有资源
+type createdResources struct {
+	nsgName      string
+	publicIPName string
+	nicName      string
+	// vmSubmitted Azure 已接受虚拟机创建请求，虚拟机和磁盘可能已存在
+	vmSubmitted bool
+}
+
+// rollbackCreate 删除创建失败前新建的资源，虚拟机引用网卡、网卡引用公网IP和网络安全组，按引用顺序先删除虚拟机和磁盘
+// 使用独立的超时，创建超时后仍能清理；清理失败只记录日志，不覆盖创建失败的原因
+func (f *VMFetcher) rollbackCreate(cred *azidentity.ClientSecretCredential, opts VMCreateOptions, created createdResources, logger *zap.Logger) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	if created.vmSubmitted {
+		f.rollbackVirtualMachine(ctx, cred, opts, logger)
+	}
+
+	if created.nicName != "" {
+		nicClient, err := armnetwork.NewInterfacesClient(opts.SubscriptionID, cred, nil)
+		if err == nil {
+			var poller *runtime.Poller[armnetwork.InterfacesClientDeleteResponse]
+			poller, err = nicClient.BeginDelete(ctx, opts.ResourceGroup, created.nicName, nil)
+			if err == nil {
+				_, err = poller.PollUntilDone(ctx, nil)
+			}
+		}
+		if err != nil {
+			logger.Warn("清理网卡失败", zap.String("nicName", created.nicName), zap.Error(err))
+		}
+	}
+
+	if created.publicIPName != "" {
+		pipClient, err := armnetwork.NewPublicIPAddressesClient(opts.SubscriptionID, cred, nil)
+		if err != nil {
+			logger.Warn("清理公网IP失败", zap.String("publicIPName", created.publicIPName), zap.Error(err))
+		} else {
+			f.deletePublicIP(ctx, pipClient, opts.ResourceGroup, created.publicIPName)
+		}
+	}
+
+	if created.nsgName != "" {
+		nsgClient, err := armnetwork.NewSecurityGroupsClient(opts.SubscriptionID, cred, nil)
+		if err == nil {
+			var poller *runtime.Poller[armnetwork.SecurityGroupsClientDeleteResponse]
+			poller, err = nsgClient.BeginDelete(ctx, opts.ResourceGroup, created.nsgName, nil)
+			if err == nil {
+				_, err = poller.PollUntilDone(ctx, nil)
+			}
+		}
+		if err != nil {
+			logger.Warn("清理网络安全组失败", zap.String("securityGroup", created.nsgName), zap.Error(err))
+		}
+	}
+}
+
+// rollbackVirtualMachine 删除创建失败的虚拟机及其系统盘和数据磁盘
+// 磁盘设置了随虚拟机删除，但预配失败时可能未挂载，仍按名称删除，不存在时忽略
+func (f *VMFetcher) rollbackVirtualMachine(ctx context.Context, cred *azidentity.ClientSecretCredential, opts VMCreateOptions, logger *zap.Logger) {
+	vmClient, err := armcompute.NewVirtualMachinesClient(opts.SubscriptionID, cred, nil)
+	if err == nil {
+		var poller *runtime.Poller[armcompute.VirtualMachinesClientDeleteResponse]
+		poller, err = vmClient.BeginDelete(ctx, opts.ResourceGroup, opts.Name, nil)
+		if err == nil {
+			_, err = poller.PollUntilDone(ctx, nil)
+		}
+	}
+	if err != nil && !isNotFound(err) {
+		logger.Warn("清理虚拟机失败", zap.Error(err))
+	}
+
+	diskClient, err := armcompute.NewDisksClient(opts.SubscriptionID, cred, nil)
+	if err != nil {
+		logger.Warn("清理虚拟机磁盘失败", zap.Error(err))
+		return
+	}
+	diskNames := []string{opts.Name + "_OsDisk"}
+	for _, disk := range opts.DataDisks {
+		if disk.Name != "" {
+			diskNames = append(diskNames, disk.Name)
+		}
+	}
+	for _, name := range diskNames {
+		poller, err := diskClient.BeginDelete(ctx, opts.ResourceGroup, name, nil)
+		if err == nil {
+			_, err = poller.PollUntilDone(ctx, nil)
+		}
+		if err != nil && !isNotFound(err) {
+			logger.Warn("清理虚拟机磁盘失败", zap.String("diskName", name), zap.Error(err))
+		}
+	}
+}
+
+// securityGroupName 创建虚拟机时使用的网络安全组名称，未指定时为 {name}-nsg
+func securityGroupName(opts VMCreateOptions) string {
+	if len(opts.Network.SecurityGroups) > 0 && opts.Network.SecurityGroups[0] != "" {
+		return opts.Network.SecurityGroups[0]
+	}
+	return opts.Name + "-nsg"
+}
+
+// ensureResourceGroup 资源组不存在时创建
+func (f *VMFetcher) ensureResourceGroup(ctx context.Context, cred *azidentity.ClientSecretCredential, opts VMCreateOptions) error {
+	client, err := armresources.NewResourceGroupsClient(opts.SubscriptionID, cred, nil)
+	if err != nil {
+		return fmt.Errorf("创建资源组客户端失败: %w", err)
+	}
+
+	if _, err := client.Get(ctx, opts.ResourceGroup, nil); err == nil {
+		return nil
+	} else if !isNotFound(err) {
+		return fmt.Errorf("获取资源组失败: %w", err)
+	}
+
+	_, err = client.CreateOrUpdate(ctx, opts.ResourceGroup, armresources.ResourceGroup{
+		Location: to.Ptr(opts.Location),
+	}, nil)
+	if err != nil {
+		return fmt.Errorf("创建资源组失败: %w", err)
+	}
+	return nil
+}
+
+// ensureSubnet 获取或创建虚拟网络与子网，返回子网ID
+func (f *VMFetcher) ensureSubnet(ctx context.Context, cred *azidentity.ClientSecretCredential, opts VMCreateOptions) (string, error) {
+	vnetName := opts.Network.VNetName
+	if vnetName == "" {
+		vnetName = opts.Name + "-vnet"
+	}
+	subnetName := opts.Network.SubnetName
+	if subnetName == "" {
+		subnetName = defaultSubnetName
+	}
+	addressPrefix := opts.Network.AddressPrefix
+	if addressPrefix == "" {
+		addressPrefix = defaultAddressPrefix
+	}
+	subnetPrefix := opts.Network.SubnetPrefix
+	if subnetPrefix == "" {
+		subnetPrefix = defaultSubnetPrefix
+	}
+
+	vnetClient, err := armnetwork.NewVirtualNetworksClient(opts.SubscriptionID, cred, nil)
+	if err != nil {
+		return "", fmt.Errorf("创建虚拟网络客户端失败: %w", err)
+	}
+
+	if _, err := vnetClient.Get(ctx, opts.ResourceGroup, vnetName, nil); err != nil {
+		if !isNotFound(err) {
+			return "", fmt.Errorf("获取虚拟网络失败: %w", err)
+		}
+		poller, err := vnetClient.BeginCreateOrUpdate(ctx, opts.ResourceGroup, vnetName, armnetwork.VirtualNetwork{
+			Location: to.Ptr(opts.Location),
+			Properties: &armnetwork.VirtualNetworkPropertiesFormat{
+				AddressSpace: &armnetwork.AddressSpace{
+					AddressPrefixes: []*string{to.Ptr(addressPrefix)},
+				},
+			},
+		}, nil)
+		if err != nil {
+			return "", fmt.Errorf("创建虚拟网络失败: %w", err)
+		}
+		if _, err := poller.PollUntilDone(ctx, nil); err != nil {
+			return "", fmt.Errorf("等待虚拟网络创建完成失败: %w", err)
+		}
+	}
+
+	subnetClient, err := armnetwork.NewSubnetsClient(opts.SubscriptionID, cred, nil)
+	if err != nil {
+		return "", fmt.Errorf("创建子网客户端失败: %w", err)
+	}
+
+	subnet, err := subnetClient.Get(ctx, opts.ResourceGroup, vnetName, subnetName, nil)
+	if err == nil {
+		return *subnet.ID, nil
+	}
+	if !isNotFound(err) {
+		return "", fmt.Errorf("获取子网失败: %w", err)
+	}
+
+	poller, err := subnetClient.BeginCreateOrUpdate(ctx, opts.ResourceGroup, vnetName, subnetName, armnetwork.Subnet{
+		Properties: &armnetwork.SubnetPropertiesFormat{
+			AddressPrefix: to.Ptr(subnetPrefix),
+		},
+	}, nil)
+	if err != nil {
+		return "", fmt.Errorf("创建子网失败: %w", err)
+	}
+	resp, err := poller.PollUntilDone(ctx, nil)
+	if err != nil {
+		return "", fmt.Errorf("等待子网创建完成失败: %w", err)
+	}
+	return *resp.ID, nil
+}
+
+// ensureSecurityGroup 获取或创建网络安全组，返回NSG ID 以及是否为本次新建
+// 未指定安全组时创建 {name}-nsg，并按系统类型放行 SSH 或 RDP
+func (f *VMFetcher) ensureSecurityGroup(ctx context.Context, cred *azidentity.ClientSecretCredential, opts VMCreateOptions) (string, bool, error) {
+	nsgName := securityGroupName(opts)
+
+	client, err := armnetwork.NewSecurityGroupsClient(opts.SubscriptionID, cred, nil)
+	if err != nil {
+		return "", false, fmt.Errorf("创建网络安全组客户端失败: %w", err)
+	}
+
+	nsg, err := client.Get(ctx, opts.ResourceGroup, nsgName, nil)
+	if err == nil {
+		return *nsg.ID, false, nil
+	}
+	if !isNotFound(err) {
+		return "", false, fmt.Errorf("获取网络安全组失败: %w", err)
+	}
+
+	ruleName, port := "AllowSSH", "22"
+	if isWindows(opts.OSType) {
+		ruleName, port = "AllowRDP", "3389"
+	}
+
+	poller, err := client.BeginCreateOrUpdate(ctx, opts.ResourceGroup, nsgName, armnetwork.SecurityGroup{
+		Location: to.Ptr(opts.Location),
+		Properties: &armnetwork.SecurityGroupPropertiesFormat{
+			SecurityRules: []*armnetwork.SecurityRule{
+				{
+					Name: to.Ptr(ruleName),
+					Properties: &armnetwork.SecurityRulePropertiesFormat{
+						Access:                   to.Ptr(armnetwork.SecurityRuleAccessAllow),
+						Direction:                to.Ptr(armnetwork.SecurityRuleDirectionInbound),
+						Priority:                 to.Ptr[int32](1000),
+						Protocol:                 to.Ptr(armnetwork.SecurityRuleProtocolTCP),
+						SourceAddressPrefix:      to.Ptr("*"),
+						SourcePortRange:          to.Ptr("*"),
+						DestinationAddressPrefix: to.Ptr("*"),
+						DestinationPortRange:     to.Ptr(port),
+					},
+				},
+			},
+		},
+	}, nil)
+	if err != nil {
+		return "", false, fmt.Errorf("创建网络安全组失败: %w", err)
+	}
+	resp, err := poller.PollUntilDone(ctx, nil)
+	if err != nil {
+		return "", false, fmt.Errorf("等待网络安全组创建完成失败: %w", err)
+	}
+	return *resp.ID, true, nil
+}
+
+// createPublicIP 创建标准静态公网IP，返回公网IP ID
+func (f *VMFetcher) createPublicIP(ctx context.Context, cred *azidentity.ClientSecretCredential, opts VMCreateOptions) (string, error) {
+	client, err := armnetwork.NewPublicIPAddressesClient(opts.SubscriptionID, cred, nil)
+	if err != nil {
+		return "", fmt.Errorf("创建公共IP客户端失败: %w", err)
+	}
+
+	poller, err := client.BeginCreateOrUpdate(ctx, opts.ResourceGroup, opts.Name+"-ip", armnetwork.PublicIPAddress{
+		Location: to.Ptr(opts.Location),
+		SKU: &armnetwork.PublicIPAddressSKU{
+			Name: to.Ptr(armnetwork.PublicIPAddressSKUNameStandard),
+		},
+		Properties: &armnetwork.PublicIPAddressPropertiesFormat{
+			PublicIPAllocationMethod: to.Ptr(armnetwork.IPAllocationMethodStatic),
+		},
+	}, nil)
+	if err != nil {
+		return "", fmt.Errorf("创建公网IP失败: %w", err)
+	}
+	resp, err := poller.PollUntilDone(ctx, nil)
+	if err != nil {
+		return "", fmt.Errorf("等待公网IP创建完成失败: %w", err)
+	}
+	return *resp.ID, nil
+}
+
+// createNIC 创建网卡，返回网卡ID
+func (f *VMFetcher) createNIC(ctx context.Context, cred *azidentity.ClientSecretCredential, opts VMCreateOptions, subnetID, nsgID, publicIPID string) (string, error) {
+	client, err := armnetwork.NewInterfacesClient(opts.SubscriptionID, cred, nil)
+	if err != nil {
+		return "", fmt.Errorf("创建网络客户端失败: %w", err)
+	}
+
+	ipConfig := &armnetwork.InterfaceIPConfigurationPropertiesFormat{
+		PrivateIPAllocationMethod: to.Ptr(armnetwork.IPAllocationMethodDynamic),
+		Subnet:                    &armnetwork.Subnet{ID: to.Ptr(subnetID)},
+	}
+	if publicIPID != "" {
+		ipConfig.PublicIPAddress = &armnetwork.PublicIPAddress{ID: to.Ptr(publicIPID)}
+	}
+
+	poller, err := client.BeginCreateOrUpdate(ctx, opts.ResourceGroup, opts.Name+"-nic", armnetwork.Interface{
+		Location: to.Ptr(opts.Location),
+		Properties: &armnetwork.InterfacePropertiesFormat{
+			NetworkSecurityGroup: &armnetwork.SecurityGroup{ID: to.Ptr(nsgID)},
+			IPConfigurations: []*armnetwork.InterfaceIPConfiguration{
+				{
+					Name:       to.Ptr("ipconfig1"),
+					Properties: ipConfig,
+				},
+			},
+		},
+	}, nil)
+	if err != nil {
+		return "", fmt.Errorf("创建网卡失败: %w", err)
+	}
+	resp, err := poller.PollUntilDone(ctx, nil)
+	if err != nil {
+		return "", fmt.Errorf("等待网卡创建完成失败: %w", err)
+	}
+	return *resp.ID, nil
+}
+
+// createVirtualMachine 创建虚拟机本体，submitted 表示 Azure 已接受创建请求
+func (f *VMFetcher) createVirtualMachine(ctx context.Context, cred *azidentity.ClientSecretCredential, opts VMCreateOptions, nicID string) (vm *armcompute.VirtualMachine, submitted bool, err error) {
+	client, err := armcompute.NewVirtualMachinesClient(opts.SubscriptionID, cred, nil)
+	if err != nil {
+		return nil, false, fmt.Errorf("创建虚拟机客户端失败: %w", err)
+	}
+
+	version := opts.ImageVersion
+	if version == "" {
+		version = "latest"
+	}
+	osDiskType := opts.OSDiskType
+	if osDiskType == "" {
+		osDiskType = defaultOSDiskType
+	}
+
+	osDisk := &armcompute.OSDisk{
+		Name:         to.Ptr(opts.Name + "_OsDisk"),
+		CreateOption: to.Ptr(armcompute.DiskCreateOptionTypesFromImage),
+		DeleteOption: to.Ptr(armcompute.DiskDeleteOptionTypesDelete),
+		ManagedDisk: &armcompute.ManagedDiskParameters{
+			StorageAccountType: to.Ptr(armcompute.StorageAccountTypes(osDiskType)),
+		},
+	}
+	if opts.OSDiskSizeGB > 0 {
+		osDisk.DiskSizeGB = to.Ptr(opts.OSDiskSizeGB)
+	}
+
+	var dataDisks []*armcompute.DataDisk
+	for i, disk := range opts.DataDisks {
+		diskType := disk.DiskType
+		if diskType == "" {
+			diskType = defaultOSDiskType
+		}
+		dataDisk := &armcompute.DataDisk{
+			Lun:          to.Ptr(int32(i)),
+			CreateOption: to.Ptr(armcompute.DiskCreateOptionTypesEmpty),
+			DiskSizeGB:   to.Ptr(disk.SizeGB),
+			DeleteOption: to.Ptr(armcompute.DiskDeleteOptionTypesDelete),
+			ManagedDisk: &armcompute.ManagedDiskParameters{
+				StorageAccountType: to.Ptr(armcompute.StorageAccountTypes(diskType)),
+			},
+		}
+		if disk.Name != "" {
+			dataDisk.Name = to.Ptr(disk.Name)
+		}
+		dataDisks = append(dataDisks, dataDisk)
+	}
+
+	osProfile := &armcompute.OSProfile{
+		ComputerName:  to.Ptr(opts.Name),
+		AdminUsername: to.Ptr(opts.AdminUsername),
+	}
+	if opts.AdminPassword != "" {
+		osProfile.AdminPassword = to.Ptr(opts.AdminPassword)
+	}
+	if !isWindows(opts.OSType) && opts.SSHPublicKey != "" {
+		osProfile.LinuxConfiguration = &armcompute.LinuxConfiguration{
+			DisablePasswordAuthentication: to.Ptr(opts.AdminPassword == ""),
+			SSH: &armcompute.SSHConfiguration{
+				PublicKeys: []*armcompute.SSHPublicKey{
+					{
+						Path:    to.Ptr(fmt.Sprintf("/home/%s/.ssh/authorized_keys", opts.AdminUsername)),
+						KeyData: to.Ptr(opts.SSHPublicKey),
+					},
+				},
+			},
+		}
+	}
+
+	tags := make(map[string]*string, len(opts.Tags))
+	for k, v := range opts.Tags {
+		tags[k] = to.Ptr(v)
+	}
+
+	poller, err := client.BeginCreateOrUpdate(ctx, opts.ResourceGroup, opts.Name, armcompute.VirtualMachine{
+		Location: to.Ptr(opts.Location),
+		Tags:     tags,
+		Properties: &armcompute.VirtualMachineProperties{
+			HardwareProfile: &armcompute.HardwareProfile{
+				VMSize: to.Ptr(armcompute.VirtualMachineSizeTypes(opts.Size)),
+			},
+			StorageProfile: &armcompute.StorageProfile{
+				ImageReference: &armcompute.ImageReference{
+					Publisher: to.Ptr(opts.ImagePublisher),
+					Offer:     to.Ptr(opts.ImageOffer),
+					SKU:       to.Ptr(opts.ImageSku),
+					Version:   to.Ptr(version),
+				},
+				OSDisk:    osDisk,
+				DataDisks: dataDisks,
+			},
+			OSProfile: osProfile,
+			NetworkProfile: &armcompute.NetworkProfile{
+				NetworkInterfaces: []*armcompute.NetworkInterfaceReference{
+					{
+						ID: to.Ptr(nicID),
+						Properties: &armcompute.NetworkInterfaceReferenceProperties{
+							Primary: to.Ptr(true),
+						},
+					},
+				},
+			},
+		},
+	}, nil)
+	if err != nil {
+		return nil, false, fmt.Errorf("创建虚拟机失败: %w", err)
+	}
+	resp, err := poller.PollUntilDone(ctx, nil)
+	if err != nil {
+		return nil, true, fmt.Errorf("等待虚拟机创建完成失败: %w", err)
+	}
+	return &resp.VirtualMachine, true, nil
+}
+
+// isNotFound 判断Azure返回的错误是否为资源不存在
+func isNotFound(err error) bool {
+	var respErr *azcore.ResponseError
+	return errors.As(err, &respErr) && respErr.StatusCode == http.StatusNotFound
+}
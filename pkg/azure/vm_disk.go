@@ -0,0 +1,354 @@
+package azure
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/compute/armcompute/v5"
+	"go.uber.org/zap"
+)
+
+// MaxDataDiskLun 数据磁盘LUN的最大值，实际可挂载数量受规格的 MaxDataDisks 限制
+const MaxDataDiskLun = 63
+
+// DataDiskAttachOptions 创建并挂载数据磁盘的选项
+type DataDiskAttachOptions struct {
+	// Name 磁盘名称，为空时按虚拟机名称和LUN生成
+	Name   string
+	SizeGB int32
+	// Lun 为空时使用最小的空闲LUN
+	Lun *int32
+	// StorageAccountType 磁盘类型，为空时使用 StandardSSD_LRS
+	StorageAccountType string
+}
+
+// VMDisks 虚拟机当前的磁盘配置
+type VMDisks struct {
+	OSDiskName string     `json:"osDiskName"`
+	OSDiskSize int32      `json:"osDiskSize"`
+	DataDisks  []DiskInfo `json:"dataDisks"`
+}
+
+// IsValidStorageAccountType 判断是否为托管磁盘支持的存储类型
+func IsValidStorageAccountType(storageType string) bool {
+	for _, t := range armcompute.PossibleDiskStorageAccountTypesValues() {
+		if string(t) == storageType {
+			return true
+		}
+	}
+	return false
+}
+
+// NextFreeLun 返回未被占用的最小LUN，没有空闲LUN时返回 -1
+func NextFreeLun(disks []DiskInfo) int32 {
+	used := make(map[int32]bool, len(disks))
+	for _, disk := range disks {
+		used[disk.Lun] = true
+	}
+	for lun := int32(0); lun <= MaxDataDiskLun; lun++ {
+		if !used[lun] {
+			return lun
+		}
+	}
+	return -1
+}
+
+// AttachDataDisk 创建空的托管磁盘并挂载到虚拟机
+// 磁盘创建在虚拟机所在的资源组和可用区，挂载失败时删除新建的磁盘
+func (f *VMFetcher) AttachDataDisk(ctx context.Context, vm VMDetails, opts DataDiskAttachOptions) (*VMDisks, error) {
+	cred, err := createAzureCredential(f.credentials)
+	if err != nil {
+		return nil, fmt.Errorf("创建Azure凭据失败: %w", err)
+	}
+	vmClient, err := armcompute.NewVirtualMachinesClient(vm.SubscriptionID, cred, nil)
+	if err != nil {
+		return nil, fmt.Errorf("创建虚拟机客户端失败: %w", err)
+	}
+	diskClient, err := armcompute.NewDisksClient(vm.SubscriptionID, cred, nil)
+	if err != nil {
+		return nil, fmt.Errorf("创建磁盘客户端失败: %w", err)
+	}
+
+	// 1. 读取当前的数据磁盘配置
+	vmResp, err := vmClient.Get(ctx, vm.ResourceGroup, vm.Name, nil)
+	if err != nil {
+		return nil, fmt.Errorf("获取虚拟机失败: %w", err)
+	}
+	if vmResp.Properties == nil || vmResp.Properties.StorageProfile == nil {
+		return nil, fmt.Errorf("虚拟机没有存储配置")
+	}
+	dataDisks := vmResp.Properties.StorageProfile.DataDisks
+
+	var lun int32
+	if opts.Lun != nil {
+		lun = *opts.Lun
+		for _, disk := range dataDisks {
+			if disk.Lun != nil && *disk.Lun == lun {
+				return nil, fmt.Errorf("LUN %d 已被占用", lun)
+			}
+		}
+	} else {
+		lun = NextFreeLun(toDiskInfos(dataDisks))
+		if lun < 0 {
+			return nil, fmt.Errorf("没有空闲的LUN")
+		}
+	}
+
+	storageType := opts.StorageAccountType
+	if storageType == "" {
+		storageType = defaultOSDiskType
+	}
+	name := opts.Name
+	if name == "" {
+		name = fmt.Sprintf("%s_DataDisk_%d_%d", vm.Name, lun, time.Now().Unix())
+	}
+	location := vm.Location
+	if vmResp.Location != nil {
+		location = *vmResp.Location
+	}
+
+	// 2. 创建空磁盘
+	createPoller, err := diskClient.BeginCreateOrUpdate(ctx, vm.ResourceGroup, name, armcompute.Disk{
+		Location: to.Ptr(location),
+		Zones:    vmResp.Zones,
+		SKU:      &armcompute.DiskSKU{Name: to.Ptr(armcompute.DiskStorageAccountTypes(storageType))},
+		Properties: &armcompute.DiskProperties{
+			CreationData: &armcompute.CreationData{CreateOption: to.Ptr(armcompute.DiskCreateOptionEmpty)},
+			DiskSizeGB:   to.Ptr(opts.SizeGB),
+		},
+	}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("创建磁盘失败: %w", err)
+	}
+	created, err := createPoller.PollUntilDone(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("等待磁盘创建完成失败: %w", err)
+	}
+
+	// 3. 挂载到虚拟机
+	dataDisks = append(dataDisks, &armcompute.DataDisk{
+		Lun:          to.Ptr(lun),
+		Name:         to.Ptr(name),
+		CreateOption: to.Ptr(armcompute.DiskCreateOptionTypesAttach),
+		ManagedDisk:  &armcompute.ManagedDiskParameters{ID: created.ID},
+	})
+	disks, err := f.updateDataDisks(ctx, vmClient, vm, dataDisks)
+	if err != nil {
+		f.deleteDisk(ctx, diskClient, vm.ResourceGroup, name)
+		return nil, fmt.Errorf("挂载磁盘失败: %w", err)
+	}
+
+	f.logger.Info("数据磁盘挂载成功",
+		zap.String("vmName", vm.Name),
+		zap.String("diskName", name),
+		zap.Int32("lun", lun),
+		zap.Int32("sizeGB", opts.SizeGB))
+
+	return disks, nil
+}
+
+// DetachDataDisk 从虚拟机卸载数据磁盘，deleteDisk 为 true 时同时删除磁盘资源
+// 磁盘删除失败不影响卸载结果，只记录日志
+func (f *VMFetcher) DetachDataDisk(ctx context.Context, vm VMDetails, diskName string, deleteDisk bool) (*VMDisks, error) {
+	cred, err := createAzureCredential(f.credentials)
+	if err != nil {
+		return nil, fmt.Errorf("创建Azure凭据失败: %w", err)
+	}
+	vmClient, err := armcompute.NewVirtualMachinesClient(vm.SubscriptionID, cred, nil)
+	if err != nil {
+		return nil, fmt.Errorf("创建虚拟机客户端失败: %w", err)
+	}
+
+	vmResp, err := vmClient.Get(ctx, vm.ResourceGroup, vm.Name, nil)
+	if err != nil {
+		return nil, fmt.Errorf("获取虚拟机失败: %w", err)
+	}
+	if vmResp.Properties == nil || vmResp.Properties.StorageProfile == nil {
+		return nil, fmt.Errorf("虚拟机没有存储配置")
+	}
+
+	var remaining []*armcompute.DataDisk
+	var detached *armcompute.DataDisk
+	for _, disk := range vmResp.Properties.StorageProfile.DataDisks {
+		if disk.Name != nil && strings.EqualFold(*disk.Name, diskName) {
+			detached = disk
+			continue
+		}
+		remaining = append(remaining, disk)
+	}
+	if detached == nil {
+		return nil, fmt.Errorf("虚拟机未挂载磁盘: %s", diskName)
+	}
+
+	disks, err := f.updateDataDisks(ctx, vmClient, vm, remaining)
+	if err != nil {
+		return nil, fmt.Errorf("卸载磁盘失败: %w", err)
+	}
+
+	if deleteDisk && detached.ManagedDisk != nil && detached.ManagedDisk.ID != nil {
+		diskClient, err := armcompute.NewDisksClient(vm.SubscriptionID, cred, nil)
+		if err != nil {
+			f.logger.Warn("创建磁盘客户端失败，磁盘未删除", zap.String("diskName", diskName), zap.Error(err))
+		} else {
+			id := *detached.ManagedDisk.ID
+			f.deleteDisk(ctx, diskClient, extractResourceGroupFromID(id), extractResourceNameFromID(id))
+		}
+	}
+
+	f.logger.Info("数据磁盘卸载成功",
+		zap.String("vmName", vm.Name),
+		zap.String("diskName", diskName),
+		zap.Bool("deleteDisk", deleteDisk))
+
+	return disks, nil
+}
+
+// ExpandDisk 扩容磁盘，diskName 为空时扩容系统盘
+// 系统盘只能在虚拟机释放后扩容，磁盘容量只能增加不能缩小
+func (f *VMFetcher) ExpandDisk(ctx context.Context, vm VMDetails, diskName string, sizeGB int32) (*VMDisks, error) {
+	cred, err := createAzureCredential(f.credentials)
+	if err != nil {
+		return nil, fmt.Errorf("创建Azure凭据失败: %w", err)
+	}
+	vmClient, err := armcompute.NewVirtualMachinesClient(vm.SubscriptionID, cred, nil)
+	if err != nil {
+		return nil, fmt.Errorf("创建虚拟机客户端失败: %w", err)
+	}
+	diskClient, err := armcompute.NewDisksClient(vm.SubscriptionID, cred, nil)
+	if err != nil {
+		return nil, fmt.Errorf("创建磁盘客户端失败: %w", err)
+	}
+
+	// 1. 找到磁盘资源
+	vmResp, err := vmClient.Get(ctx, vm.ResourceGroup, vm.Name, nil)
+	if err != nil {
+		return nil, fmt.Errorf("获取虚拟机失败: %w", err)
+	}
+	if vmResp.Properties == nil || vmResp.Properties.StorageProfile == nil {
+		return nil, fmt.Errorf("虚拟机没有存储配置")
+	}
+	storage := vmResp.Properties.StorageProfile
+
+	var managed *armcompute.ManagedDiskParameters
+	if diskName == "" {
+		if storage.OSDisk == nil || storage.OSDisk.ManagedDisk == nil {
+			return nil, fmt.Errorf("系统盘不是托管磁盘")
+		}
+		managed = storage.OSDisk.ManagedDisk
+	} else {
+		for _, disk := range storage.DataDisks {
+			if disk.Name != nil && strings.EqualFold(*disk.Name, diskName) {
+				managed = disk.ManagedDisk
+			}
+		}
+		if managed == nil {
+			return nil, fmt.Errorf("虚拟机未挂载托管磁盘: %s", diskName)
+		}
+	}
+	if managed.ID == nil {
+		return nil, fmt.Errorf("磁盘缺少资源ID")
+	}
+
+	// 2. 更新磁盘容量
+	poller, err := diskClient.BeginUpdate(ctx, extractResourceGroupFromID(*managed.ID), extractResourceNameFromID(*managed.ID), armcompute.DiskUpdate{
+		Properties: &armcompute.DiskUpdateProperties{DiskSizeGB: to.Ptr(sizeGB)},
+	}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("扩容磁盘失败: %w", err)
+	}
+	if _, err := poller.PollUntilDone(ctx, nil); err != nil {
+		return nil, fmt.Errorf("等待磁盘扩容完成失败: %w", err)
+	}
+
+	// 3. 重新读取虚拟机的磁盘配置
+	vmResp, err = vmClient.Get(ctx, vm.ResourceGroup, vm.Name, nil)
+	if err != nil {
+		return nil, fmt.Errorf("获取虚拟机失败: %w", err)
+	}
+
+	f.logger.Info("磁盘扩容成功",
+		zap.String("vmName", vm.Name),
+		zap.String("diskId", *managed.ID),
+		zap.Int32("sizeGB", sizeGB))
+
+	return toVMDisks(&vmResp.VirtualMachine), nil
+}
+
+// updateDataDisks 用给定的数据磁盘列表更新虚拟机，返回更新后的磁盘配置
+func (f *VMFetcher) updateDataDisks(ctx context.Context, client *armcompute.VirtualMachinesClient, vm VMDetails, dataDisks []*armcompute.DataDisk) (*VMDisks, error) {
+	if dataDisks == nil {
+		// 空列表才会卸载全部磁盘，nil 会被忽略
+		dataDisks = []*armcompute.DataDisk{}
+	}
+	poller, err := client.BeginUpdate(ctx, vm.ResourceGroup, vm.Name, armcompute.VirtualMachineUpdate{
+		Properties: &armcompute.VirtualMachineProperties{
+			StorageProfile: &armcompute.StorageProfile{DataDisks: dataDisks},
+		},
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := poller.PollUntilDone(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	return toVMDisks(&resp.VirtualMachine), nil
+}
+
+// deleteDisk 删除托管磁盘，返回是否删除成功
+func (f *VMFetcher) deleteDisk(ctx context.Context, client *armcompute.DisksClient, resourceGroup, name string) bool {
+	poller, err := client.BeginDelete(ctx, resourceGroup, name, nil)
+	if err == nil {
+		_, err = poller.PollUntilDone(ctx, nil)
+	}
+	if err != nil {
+		f.logger.Warn("删除磁盘失败",
+			zap.String("diskName", name),
+			zap.Error(err))
+		return false
+	}
+	return true
+}
+
+// toVMDisks 从虚拟机资源中提取磁盘配置
+func toVMDisks(vm *armcompute.VirtualMachine) *VMDisks {
+	disks := &VMDisks{}
+	if vm.Properties == nil || vm.Properties.StorageProfile == nil {
+		return disks
+	}
+	if osDisk := vm.Properties.StorageProfile.OSDisk; osDisk != nil {
+		if osDisk.Name != nil {
+			disks.OSDiskName = *osDisk.Name
+		}
+		if osDisk.DiskSizeGB != nil {
+			disks.OSDiskSize = *osDisk.DiskSizeGB
+		}
+	}
+	disks.DataDisks = toDiskInfos(vm.Properties.StorageProfile.DataDisks)
+	return disks
+}
+
+// toDiskInfos 转换数据磁盘列表
+func toDiskInfos(dataDisks []*armcompute.DataDisk) []DiskInfo {
+	var infos []DiskInfo
+	for _, disk := range dataDisks {
+		info := DiskInfo{}
+		if disk.Name != nil {
+			info.Name = *disk.Name
+		}
+		if disk.DiskSizeGB != nil {
+			info.SizeGB = *disk.DiskSizeGB
+		}
+		if disk.Lun != nil {
+			info.Lun = *disk.Lun
+		}
+		if disk.ManagedDisk != nil && disk.ManagedDisk.StorageAccountType != nil {
+			info.DiskType = string(*disk.ManagedDisk.StorageAccountType)
+		}
+		infos = append(infos, info)
+	}
+	return infos
+}
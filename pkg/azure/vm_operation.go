@@ -0,0 +1,107 @@
+package azure
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/compute/armcompute/v5"
+)
+
+// OperationPoller 可持久化的长时间运行操作轮询器
+// 通过 ResumeToken 可以在服务重启后恢复轮询
+type OperationPoller interface {
+	// Poll 向Azure查询一次操作状态
+	Poll(ctx context.Context) error
+	// Done 操作是否已结束(成功或失败)
+	Done() bool
+	// Result 获取最终结果，操作失败时返回错误
+	Result(ctx context.Context) error
+	// ResumeToken 获取用于恢复轮询的令牌，操作结束后不可用
+	ResumeToken() (string, error)
+}
+
+// sdkPoller 将SDK泛型轮询器适配为 OperationPoller
+type sdkPoller[T any] struct {
+	poller *runtime.Poller[T]
+}
+
+func (p *sdkPoller[T]) Poll(ctx context.Context) error {
+	_, err := p.poller.Poll(ctx)
+	return err
+}
+
+func (p *sdkPoller[T]) Done() bool {
+	return p.poller.Done()
+}
+
+func (p *sdkPoller[T]) Result(ctx context.Context) error {
+	_, err := p.poller.Result(ctx)
+	return err
+}
+
+func (p *sdkPoller[T]) ResumeToken() (string, error) {
+	return p.poller.ResumeToken()
+}
+
+func wrapPoller[T any](poller *runtime.Poller[T], err error) (OperationPoller, error) {
+	if err != nil {
+		return nil, err
+	}
+	return &sdkPoller[T]{poller: poller}, nil
+}
+
+// ResolveOperationType 结合强制选项得到实际执行的操作类型
+// 强制停止实际执行的是释放(deallocate)
+func ResolveOperationType(opType VMOperationType, opts *OperationOptions) VMOperationType {
+	if opType == VMOperationStop && opts != nil && opts.Force {
+		return VMOperationDeallocate
+	}
+	return opType
+}
+
+// BeginVMOperation 提交虚拟机操作但不等待完成，返回可持久化的轮询器
+// resumeToken 不为空时从令牌恢复已提交的操作，而不会重新提交
+func (f *VMFetcher) BeginVMOperation(ctx context.Context, opType VMOperationType, vm VMDetails, opts *OperationOptions, resumeToken string) (OperationPoller, error) {
+	cred, err := createAzureCredential(f.credentials)
+	if err != nil {
+		return nil, fmt.Errorf("创建Azure凭据失败: %w", err)
+	}
+
+	client, err := armcompute.NewVirtualMachinesClient(vm.SubscriptionID, cred, nil)
+	if err != nil {
+		return nil, fmt.Errorf("创建虚拟机客户端失败: %w", err)
+	}
+
+	var poller OperationPoller
+	switch ResolveOperationType(opType, opts) {
+	case VMOperationStart:
+		poller, err = wrapPoller(client.BeginStart(ctx, vm.ResourceGroup, vm.Name,
+			&armcompute.VirtualMachinesClientBeginStartOptions{ResumeToken: resumeToken}))
+	case VMOperationStop:
+		poller, err = wrapPoller(client.BeginPowerOff(ctx, vm.ResourceGroup, vm.Name,
+			&armcompute.VirtualMachinesClientBeginPowerOffOptions{ResumeToken: resumeToken}))
+	case VMOperationDeallocate:
+		poller, err = wrapPoller(client.BeginDeallocate(ctx, vm.ResourceGroup, vm.Name,
+			&armcompute.VirtualMachinesClientBeginDeallocateOptions{ResumeToken: resumeToken}))
+	case VMOperationRestart:
+		poller, err = wrapPoller(client.BeginRestart(ctx, vm.ResourceGroup, vm.Name,
+			&armcompute.VirtualMachinesClientBeginRestartOptions{ResumeToken: resumeToken}))
+	case VMOperationDelete:
+		forceDeletion := opts != nil && opts.Force
+		poller, err = wrapPoller(client.BeginDelete(ctx, vm.ResourceGroup, vm.Name,
+			&armcompute.VirtualMachinesClientBeginDeleteOptions{ForceDeletion: &forceDeletion, ResumeToken: resumeToken}))
+	default:
+		return nil, fmt.Errorf("不支持的操作类型: %s", opType)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("提交虚拟机操作失败: %w", err)
+	}
+
+	return poller, nil
+}
+
+// CleanupVMResources 虚拟机删除完成后清理网卡、公网IP和磁盘
+func (f *VMFetcher) CleanupVMResources(ctx context.Context, vm VMDetails) error {
+	return f.cleanupVMResources(ctx, vm)
+}
@@ -0,0 +1,256 @@
+package azure
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/compute/armcompute/v5"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/network/armnetwork/v5"
+	"go.uber.org/zap"
+)
+
+// 公网IP规格
+const (
+	PublicIPSkuStandard = "Standard"
+	PublicIPSkuBasic    = "Basic"
+)
+
+// PublicIPChangeOptions 更换公网IP的选项
+type PublicIPChangeOptions struct {
+	// Sku 新公网IP的规格(Standard/Basic)，为空时沿用原公网IP的规格，没有原公网IP时使用 Standard
+	Sku string
+}
+
+// PublicIPChangeResult 更换公网IP的结果
+type PublicIPChangeResult struct {
+	OldPublicIPName string `json:"oldPublicIpName"`
+	OldPublicIP     string `json:"oldPublicIp"`
+	PublicIPName    string `json:"publicIpName"`
+	PublicIP        string `json:"publicIp"`
+	DnsAlias        string `json:"dnsAlias"`
+	// OldPublicIPDeleted 原公网IP是否已删除，删除失败不影响更换结果
+	OldPublicIPDeleted bool `json:"oldPublicIpDeleted"`
+}
+
+// ChangePublicIP 为虚拟机分配新的公网IP并替换网卡上原有的公网IP
+// 流程: 创建新公网IP -> 网卡IP配置指向新IP -> 删除原公网IP -> 将原DNS标签迁移到新IP
+// DNS标签在区域内唯一，因此只能在原公网IP删除后再设置到新IP上
+func (f *VMFetcher) ChangePublicIP(ctx context.Context, vm VMDetails, opts PublicIPChangeOptions) (*PublicIPChangeResult, error) {
+	cred, err := createAzureCredential(f.credentials)
+	if err != nil {
+		return nil, fmt.Errorf("创建Azure凭据失败: %w", err)
+	}
+
+	nicClient, err := armnetwork.NewInterfacesClient(vm.SubscriptionID, cred, nil)
+	if err != nil {
+		return nil, fmt.Errorf("创建网络客户端失败: %w", err)
+	}
+	pipClient, err := armnetwork.NewPublicIPAddressesClient(vm.SubscriptionID, cred, nil)
+	if err != nil {
+		return nil, fmt.Errorf("创建公共IP客户端失败: %w", err)
+	}
+
+	// 1. 找到要替换的网卡IP配置
+	nic, ipConfig, err := f.findPublicIPConfig(ctx, cred, nicClient, vm)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &PublicIPChangeResult{}
+	var oldIP *armnetwork.PublicIPAddress
+	if ipConfig.Properties.PublicIPAddress != nil && ipConfig.Properties.PublicIPAddress.ID != nil {
+		oldID := *ipConfig.Properties.PublicIPAddress.ID
+		resp, err := pipClient.Get(ctx, extractResourceGroupFromID(oldID), extractResourceNameFromID(oldID), nil)
+		if err != nil {
+			return nil, fmt.Errorf("获取原公网IP失败: %w", err)
+		}
+		oldIP = &resp.PublicIPAddress
+		result.OldPublicIPName = *oldIP.Name
+		if oldIP.Properties != nil && oldIP.Properties.IPAddress != nil {
+			result.OldPublicIP = *oldIP.Properties.IPAddress
+		}
+	}
+
+	// 2. 创建新公网IP
+	location := vm.Location
+	if nic.Location != nil {
+		location = *nic.Location
+	}
+	newIP, err := f.createReplacementPublicIP(ctx, pipClient, vm, location, oldIP, opts)
+	if err != nil {
+		return nil, err
+	}
+	result.PublicIPName = *newIP.Name
+
+	// 3. 网卡指向新公网IP
+	ipConfig.Properties.PublicIPAddress = &armnetwork.PublicIPAddress{ID: newIP.ID}
+	nicPoller, err := nicClient.BeginCreateOrUpdate(ctx, extractResourceGroupFromID(*nic.ID), *nic.Name, *nic, nil)
+	if err == nil {
+		_, err = nicPoller.PollUntilDone(ctx, nil)
+	}
+	if err != nil {
+		// 网卡更新失败时清理新建的公网IP，原公网IP保持不变
+		f.deletePublicIP(ctx, pipClient, extractResourceGroupFromID(*newIP.ID), *newIP.Name)
+		return nil, fmt.Errorf("更新网卡公网IP失败: %w", err)
+	}
+
+	// 4. 删除原公网IP
+	var dnsLabel string
+	if oldIP != nil {
+		if oldIP.Properties != nil && oldIP.Properties.DNSSettings != nil && oldIP.Properties.DNSSettings.DomainNameLabel != nil {
+			dnsLabel = *oldIP.Properties.DNSSettings.DomainNameLabel
+		}
+		result.OldPublicIPDeleted = f.deletePublicIP(ctx, pipClient, extractResourceGroupFromID(*oldIP.ID), *oldIP.Name)
+	}
+
+	// 5. 迁移DNS标签，原公网IP未删除时标签仍被占用，无法迁移
+	if dnsLabel != "" {
+		if result.OldPublicIPDeleted {
+			fqdn, err := f.SetVMDNSLabel(ctx, vm.SubscriptionID, extractResourceGroupFromID(*newIP.ID), *newIP.Name, dnsLabel)
+			if err != nil {
+				f.logger.Warn("迁移DNS标签失败",
+					zap.String("publicIPName", *newIP.Name),
+					zap.String("dnsLabel", dnsLabel),
+					zap.Error(err))
+			}
+			result.DnsAlias = fqdn
+		} else {
+			f.logger.Warn("原公网IP未删除，DNS标签未迁移",
+				zap.String("oldPublicIPName", result.OldPublicIPName),
+				zap.String("dnsLabel", dnsLabel))
+		}
+	}
+
+	// 6. 读取新分配的地址，此时网卡已切换且原公网IP可能已删除，读取失败时仍返回结果，
+	// 使用创建时返回的地址，地址由下次同步补全
+	if newIP.Properties != nil && newIP.Properties.IPAddress != nil {
+		result.PublicIP = *newIP.Properties.IPAddress
+	}
+	resp, err := pipClient.Get(ctx, extractResourceGroupFromID(*newIP.ID), *newIP.Name, nil)
+	if err != nil {
+		f.logger.Warn("获取新公网IP失败",
+			zap.String("publicIPName", *newIP.Name),
+			zap.Error(err))
+	} else if resp.Properties != nil && resp.Properties.IPAddress != nil {
+		result.PublicIP = *resp.Properties.IPAddress
+	}
+
+	f.logger.Info("公网IP更换成功",
+		zap.String("vmName", vm.Name),
+		zap.String("oldPublicIP", result.OldPublicIP),
+		zap.String("publicIP", result.PublicIP))
+
+	return result, nil
+}
+
+// findPublicIPConfig 查找虚拟机挂载公网IP的网卡IP配置
+// 优先匹配当前公网IP名称，没有公网IP时使用主网卡的主IP配置
+func (f *VMFetcher) findPublicIPConfig(ctx context.Context, cred *azidentity.ClientSecretCredential, nicClient *armnetwork.InterfacesClient, vm VMDetails) (*armnetwork.Interface, *armnetwork.InterfaceIPConfiguration, error) {
+	vmClient, err := armcompute.NewVirtualMachinesClient(vm.SubscriptionID, cred, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("创建虚拟机客户端失败: %w", err)
+	}
+	vmResp, err := vmClient.Get(ctx, vm.ResourceGroup, vm.Name, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("获取虚拟机失败: %w", err)
+	}
+	if vmResp.Properties == nil || vmResp.Properties.NetworkProfile == nil {
+		return nil, nil, fmt.Errorf("虚拟机没有网络配置")
+	}
+
+	var primaryNIC *armnetwork.Interface
+	var primaryConfig *armnetwork.InterfaceIPConfiguration
+	for _, ref := range vmResp.Properties.NetworkProfile.NetworkInterfaces {
+		if ref.ID == nil {
+			continue
+		}
+		resp, err := nicClient.Get(ctx, extractResourceGroupFromID(*ref.ID), extractResourceNameFromID(*ref.ID), nil)
+		if err != nil {
+			return nil, nil, fmt.Errorf("获取网络接口失败: %w", err)
+		}
+		nic := resp.Interface
+		if nic.Properties == nil {
+			continue
+		}
+		isPrimaryNIC := len(vmResp.Properties.NetworkProfile.NetworkInterfaces) == 1 ||
+			(ref.Properties != nil && ref.Properties.Primary != nil && *ref.Properties.Primary)
+		for _, ipConfig := range nic.Properties.IPConfigurations {
+			if ipConfig.Properties == nil {
+				continue
+			}
+			if pip := ipConfig.Properties.PublicIPAddress; pip != nil && pip.ID != nil && vm.PublicIPName != "" &&
+				strings.EqualFold(extractResourceNameFromID(*pip.ID), vm.PublicIPName) {
+				return &nic, ipConfig, nil
+			}
+			isPrimaryConfig := len(nic.Properties.IPConfigurations) == 1 ||
+				(ipConfig.Properties.Primary != nil && *ipConfig.Properties.Primary)
+			if isPrimaryNIC && isPrimaryConfig && primaryConfig == nil {
+				primaryNIC, primaryConfig = &nic, ipConfig
+			}
+		}
+	}
+	if primaryConfig == nil {
+		return nil, nil, fmt.Errorf("未找到可挂载公网IP的网卡配置")
+	}
+	return primaryNIC, primaryConfig, nil
+}
+
+// createReplacementPublicIP 创建用于替换的静态公网IP，沿用原公网IP的规格和可用区
+func (f *VMFetcher) createReplacementPublicIP(ctx context.Context, client *armnetwork.PublicIPAddressesClient, vm VMDetails, location string, oldIP *armnetwork.PublicIPAddress, opts PublicIPChangeOptions) (*armnetwork.PublicIPAddress, error) {
+	sku := armnetwork.PublicIPAddressSKUNameStandard
+	if oldIP != nil && oldIP.SKU != nil && oldIP.SKU.Name != nil {
+		sku = *oldIP.SKU.Name
+	}
+	switch {
+	case strings.EqualFold(opts.Sku, PublicIPSkuStandard):
+		sku = armnetwork.PublicIPAddressSKUNameStandard
+	case strings.EqualFold(opts.Sku, PublicIPSkuBasic):
+		sku = armnetwork.PublicIPAddressSKUNameBasic
+	case opts.Sku != "":
+		return nil, fmt.Errorf("不支持的公网IP规格: %s", opts.Sku)
+	}
+
+	pip := armnetwork.PublicIPAddress{
+		Location: to.Ptr(location),
+		SKU:      &armnetwork.PublicIPAddressSKU{Name: to.Ptr(sku)},
+		Properties: &armnetwork.PublicIPAddressPropertiesFormat{
+			PublicIPAllocationMethod: to.Ptr(armnetwork.IPAllocationMethodStatic),
+			PublicIPAddressVersion:   to.Ptr(armnetwork.IPVersionIPv4),
+		},
+	}
+	// 标准公网IP需要与原IP保持相同的可用区，否则无法挂载到可用区内的虚拟机
+	if oldIP != nil && sku == armnetwork.PublicIPAddressSKUNameStandard &&
+		oldIP.SKU != nil && oldIP.SKU.Name != nil && *oldIP.SKU.Name == sku {
+		pip.Zones = oldIP.Zones
+	}
+
+	name := fmt.Sprintf("%s-ip-%d", vm.Name, time.Now().Unix())
+	poller, err := client.BeginCreateOrUpdate(ctx, vm.ResourceGroup, name, pip, nil)
+	if err != nil {
+		return nil, fmt.Errorf("创建公网IP失败: %w", err)
+	}
+	resp, err := poller.PollUntilDone(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("等待公网IP创建完成失败: %w", err)
+	}
+	return &resp.PublicIPAddress, nil
+}
+
+// deletePublicIP 删除公网IP，返回是否删除成功
+func (f *VMFetcher) deletePublicIP(ctx context.Context, client *armnetwork.PublicIPAddressesClient, resourceGroup, name string) bool {
+	poller, err := client.BeginDelete(ctx, resourceGroup, name, nil)
+	if err == nil {
+		_, err = poller.PollUntilDone(ctx, nil)
+	}
+	if err != nil {
+		f.logger.Warn("删除公网IP失败",
+			zap.String("publicIPName", name),
+			zap.Error(err))
+		return false
+	}
+	return true
+}
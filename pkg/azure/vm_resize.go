@@ -0,0 +1,135 @@
+package azure
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/compute/armcompute/v5"
+	"go.uber.org/zap"
+)
+
+// VMResizeResult 调整规格的结果
+type VMResizeResult struct {
+	Size string `json:"size"`
+	// Deallocated 目标规格不在当前硬件集群上，调整前释放了虚拟机
+	Deallocated bool `json:"deallocated"`
+	// PowerState 调整完成后的电源状态
+	PowerState string `json:"powerState"`
+}
+
+// ListAvailableSizes 获取虚拟机当前所在硬件集群可直接调整到的规格
+// 不在列表中的规格需要先释放虚拟机才能调整
+func (f *VMFetcher) ListAvailableSizes(ctx context.Context, subscriptionID, resourceGroup, vmName string) ([]string, error) {
+	cred, err := createAzureCredential(f.credentials)
+	if err != nil {
+		return nil, fmt.Errorf("创建Azure凭据失败: %w", err)
+	}
+
+	client, err := armcompute.NewVirtualMachinesClient(subscriptionID, cred, nil)
+	if err != nil {
+		return nil, fmt.Errorf("创建虚拟机客户端失败: %w", err)
+	}
+
+	var sizes []string
+	pager := client.NewListAvailableSizesPager(resourceGroup, vmName, nil)
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("获取可用规格失败: %w", err)
+		}
+		for _, size := range page.Value {
+			if size.Name != nil {
+				sizes = append(sizes, *size.Name)
+			}
+		}
+	}
+	return sizes, nil
+}
+
+// ResizeVM 调整虚拟机规格
+// 目标规格在当前硬件集群上时直接更新(运行中的虚拟机由Azure自动重启)；
+// 否则先释放虚拟机，更新规格后再按原状态启动。更新失败时尽量恢复原来的运行状态
+func (f *VMFetcher) ResizeVM(ctx context.Context, vm VMDetails, size string) (*VMResizeResult, error) {
+	cred, err := createAzureCredential(f.credentials)
+	if err != nil {
+		return nil, fmt.Errorf("创建Azure凭据失败: %w", err)
+	}
+
+	client, err := armcompute.NewVirtualMachinesClient(vm.SubscriptionID, cred, nil)
+	if err != nil {
+		return nil, fmt.Errorf("创建虚拟机客户端失败: %w", err)
+	}
+
+	// 1. 判断是否需要释放
+	available, err := f.ListAvailableSizes(ctx, vm.SubscriptionID, vm.ResourceGroup, vm.Name)
+	if err != nil {
+		return nil, err
+	}
+	powerState, err := f.GetVMStatus(ctx, vm.SubscriptionID, vm.ResourceGroup, vm.Name)
+	if err != nil {
+		return nil, err
+	}
+	wasRunning := powerState == "running" || powerState == "starting"
+
+	result := &VMResizeResult{Size: size}
+	if !containsFold(available, size) && powerState != "deallocated" {
+		f.logger.Info("目标规格不在当前硬件集群，先释放虚拟机",
+			zap.String("vmName", vm.Name),
+			zap.String("size", size))
+		if err := f.performDeallocate(ctx, client, vm); err != nil {
+			return nil, fmt.Errorf("释放虚拟机失败: %w", err)
+		}
+		result.Deallocated = true
+	}
+
+	// 2. 更新硬件配置
+	poller, err := client.BeginUpdate(ctx, vm.ResourceGroup, vm.Name, armcompute.VirtualMachineUpdate{
+		Properties: &armcompute.VirtualMachineProperties{
+			HardwareProfile: &armcompute.HardwareProfile{
+				VMSize: to.Ptr(armcompute.VirtualMachineSizeTypes(size)),
+			},
+		},
+	}, nil)
+	if err == nil {
+		_, err = poller.PollUntilDone(ctx, nil)
+	}
+	if err != nil {
+		if result.Deallocated && wasRunning {
+			if startErr := f.performStart(ctx, client, vm); startErr != nil {
+				f.logger.Error("调整规格失败后恢复启动失败", zap.String("vmName", vm.Name), zap.Error(startErr))
+			}
+		}
+		return nil, fmt.Errorf("更新虚拟机规格失败: %w", err)
+	}
+
+	// 3. 按原状态重新启动
+	if result.Deallocated && wasRunning {
+		if err := f.performStart(ctx, client, vm); err != nil {
+			return nil, fmt.Errorf("调整规格后启动虚拟机失败: %w", err)
+		}
+	}
+
+	result.PowerState, err = f.GetVMStatus(ctx, vm.SubscriptionID, vm.ResourceGroup, vm.Name)
+	if err != nil {
+		f.logger.Warn("获取调整规格后的虚拟机状态失败", zap.String("vmName", vm.Name), zap.Error(err))
+	}
+
+	f.logger.Info("虚拟机规格调整成功",
+		zap.String("vmName", vm.Name),
+		zap.String("size", size),
+		zap.Bool("deallocated", result.Deallocated))
+
+	return result, nil
+}
+
+// containsFold 忽略大小写判断列表中是否包含指定值
+func containsFold(values []string, target string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, target) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,141 @@
+package azure
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/compute/armcompute/v5"
+	"go.uber.org/zap"
+)
+
+// Run Command 内置的脚本命令
+const (
+	RunCommandShellScript      = "RunShellScript"
+	RunCommandPowerShellScript = "RunPowerShellScript"
+)
+
+// RunCommandResult Run Command 的执行结果
+// Azure 只返回输出的最后 4096 字节
+type RunCommandResult struct {
+	// Succeeded 脚本执行成功
+	Succeeded bool `json:"succeeded"`
+	// ExitCode 脚本退出码，Azure 未返回时为 nil
+	ExitCode *int   `json:"exitCode,omitempty"`
+	Stdout   string `json:"stdout"`
+	Stderr   string `json:"stderr"`
+}
+
+// RunCommandID 按操作系统类型返回内置的脚本命令
+func RunCommandID(osType string) string {
+	if strings.EqualFold(osType, "Windows") {
+		return RunCommandPowerShellScript
+	}
+	return RunCommandShellScript
+}
+
+// RunCommand 通过 Run Command 在虚拟机内执行脚本并等待结果，虚拟机必须处于运行状态
+// Linux 执行 shell 脚本，Windows 执行 PowerShell 脚本
+func (f *VMFetcher) RunCommand(ctx context.Context, vm VMDetails, script string) (*RunCommandResult, error) {
+	cred, err := createAzureCredential(f.credentials)
+	if err != nil {
+		return nil, fmt.Errorf("创建Azure凭据失败: %w", err)
+	}
+
+	client, err := armcompute.NewVirtualMachinesClient(vm.SubscriptionID, cred, nil)
+	if err != nil {
+		return nil, fmt.Errorf("创建虚拟机客户端失败: %w", err)
+	}
+
+	commandID := RunCommandID(vm.OSType)
+	f.logger.Info("开始执行Run Command",
+		zap.String("vmName", vm.Name),
+		zap.String("commandId", commandID))
+
+	poller, err := client.BeginRunCommand(ctx, vm.ResourceGroup, vm.Name, armcompute.RunCommandInput{
+		CommandID: to.Ptr(commandID),
+		Script:    to.SliceOfPtrs(strings.Split(script, "\n")...),
+	}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("提交Run Command失败: %w", err)
+	}
+	resp, err := poller.PollUntilDone(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("执行Run Command失败: %w", err)
+	}
+	return parseRunCommandResult(resp.Value), nil
+}
+
+// runCommandExitPattern Linux 脚本以非零状态退出时的消息，例如 "command terminated with exit status=1"
+var runCommandExitPattern = regexp.MustCompile(`exit status=(-?\d+)`)
+
+// parseRunCommandResult 解析 Run Command 返回的状态
+// Linux 返回一条状态，消息中以 [stdout]/[stderr] 分隔输出；
+// Windows 分别返回 ComponentStatus/StdOut 和 ComponentStatus/StdErr 两条状态
+func parseRunCommandResult(statuses []*armcompute.InstanceViewStatus) *RunCommandResult {
+	result := &RunCommandResult{Succeeded: true}
+	for _, status := range statuses {
+		if status == nil {
+			continue
+		}
+		var code, message string
+		if status.Code != nil {
+			code = *status.Code
+		}
+		if status.Message != nil {
+			message = *status.Message
+		}
+		if strings.HasSuffix(strings.ToLower(code), "/failed") {
+			result.Succeeded = false
+		}
+
+		switch {
+		case strings.Contains(code, "StdOut"):
+			result.Stdout = message
+		case strings.Contains(code, "StdErr"):
+			result.Stderr = message
+		default:
+			parseShellRunCommandMessage(result, message)
+		}
+	}
+	if result.ExitCode != nil && *result.ExitCode != 0 {
+		result.Succeeded = false
+	}
+	return result
+}
+
+// parseShellRunCommandMessage 解析 Linux 的执行消息，例如 "Enable succeeded: \n[stdout]\n...\n[stderr]\n..."
+// 退出状态只从输出之前的状态头中解析，避免脚本输出中的 "exit status=" 被误认为退出码
+func parseShellRunCommandMessage(result *RunCommandResult, message string) {
+	stdoutIdx := strings.Index(message, "[stdout]\n")
+	stderrIdx := strings.Index(message, "[stderr]\n")
+	header := message
+	if stdoutIdx >= 0 {
+		header = message[:stdoutIdx]
+	} else if stderrIdx >= 0 {
+		header = message[:stderrIdx]
+	}
+
+	if m := runCommandExitPattern.FindStringSubmatch(header); m != nil {
+		if code, err := strconv.Atoi(m[1]); err == nil {
+			result.ExitCode = &code
+		}
+	} else if strings.HasPrefix(message, "Enable succeeded") {
+		code := 0
+		result.ExitCode = &code
+	}
+
+	if stdoutIdx >= 0 {
+		end := len(message)
+		if stderrIdx > stdoutIdx {
+			end = stderrIdx
+		}
+		result.Stdout = strings.TrimSuffix(message[stdoutIdx+len("[stdout]\n"):end], "\n")
+	}
+	if stderrIdx >= 0 {
+		result.Stderr = strings.TrimSuffix(message[stderrIdx+len("[stderr]\n"):], "\n")
+	}
+}
@@ -0,0 +1,39 @@
+package azure
+
+import (
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/compute/armcompute/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRunCommandResult_Shell(t *testing.T) {
+	parse := func(message string) *RunCommandResult {
+		return parseRunCommandResult([]*armcompute.InstanceViewStatus{{
+			Code:    to.Ptr("ProvisioningState/succeeded"),
+			Message: to.Ptr(message),
+		}})
+	}
+
+	result := parse("Enable succeeded: \n[stdout]\nhello\n\n[stderr]\nwarn\n")
+	assert.True(t, result.Succeeded)
+	require.NotNil(t, result.ExitCode)
+	assert.Equal(t, 0, *result.ExitCode)
+	assert.Equal(t, "hello\n", result.Stdout)
+	assert.Equal(t, "warn", result.Stderr)
+
+	result = parse("Enable failed: failed to execute command: command terminated with exit status=2\n[stdout]\n\n[stderr]\nboom\n")
+	assert.False(t, result.Succeeded)
+	require.NotNil(t, result.ExitCode)
+	assert.Equal(t, 2, *result.ExitCode)
+	assert.Equal(t, "boom", result.Stderr)
+
+	// 脚本输出中的 "exit status=" 不影响退出码
+	result = parse("Enable succeeded: \n[stdout]\nprevious run: exit status=1\n\n[stderr]\nexit status=3\n")
+	assert.True(t, result.Succeeded)
+	require.NotNil(t, result.ExitCode)
+	assert.Equal(t, 0, *result.ExitCode)
+	assert.Equal(t, "previous run: exit status=1\n", result.Stdout)
+}
@@ -0,0 +1,390 @@
+package azure
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/network/armnetwork/v5"
+	"go.uber.org/zap"
+)
+
+// 网络安全组的关联范围
+const (
+	SecurityGroupScopeNIC    = "nic"
+	SecurityGroupScopeSubnet = "subnet"
+)
+
+// SecurityRule 网络安全组规则，多个地址前缀或端口范围以逗号分隔
+type SecurityRule struct {
+	Name                     string `json:"name"`
+	Priority                 int32  `json:"priority"`
+	Direction                string `json:"direction"` // Inbound/Outbound
+	Access                   string `json:"access"`    // Allow/Deny
+	Protocol                 string `json:"protocol"`  // Tcp/Udp/Icmp/*
+	SourceAddressPrefix      string `json:"sourceAddressPrefix"`
+	SourcePortRange          string `json:"sourcePortRange"`
+	DestinationAddressPrefix string `json:"destinationAddressPrefix"`
+	DestinationPortRange     string `json:"destinationPortRange"`
+	Description              string `json:"description,omitempty"`
+	// Default Azure内置的默认规则，不能修改或删除
+	Default bool `json:"default"`
+}
+
+// SecurityGroupInfo 作用于虚拟机的网络安全组
+type SecurityGroupInfo struct {
+	ID            string `json:"id"`
+	Name          string `json:"name"`
+	ResourceGroup string `json:"resourceGroup"`
+	Location      string `json:"location"`
+	// Scope 关联在网卡(nic)还是子网(subnet)上
+	Scope string `json:"scope"`
+	// AttachedTo 关联的网卡或子网名称
+	AttachedTo string         `json:"attachedTo"`
+	Rules      []SecurityRule `json:"rules"`
+}
+
+// ListSecurityGroups 获取作用于虚拟机网卡及其子网的网络安全组和规则
+func (f *VMFetcher) ListSecurityGroups(ctx context.Context, vm VMDetails) ([]SecurityGroupInfo, error) {
+	cred, err := createAzureCredential(f.credentials)
+	if err != nil {
+		return nil, fmt.Errorf("创建Azure凭据失败: %w", err)
+	}
+	details, err := f.fetchVMDetail(ctx, vm.SubscriptionID, vm.ResourceGroup, vm.Name, cred)
+	if err != nil {
+		return nil, err
+	}
+	return details.SecurityGroups, nil
+}
+
+// AddSecurityRule 在网络安全组中添加或更新规则，返回更新后的网络安全组
+func (f *VMFetcher) AddSecurityRule(ctx context.Context, vm VMDetails, securityGroupID string, rule SecurityRule) ([]SecurityGroupInfo, error) {
+	cred, err := createAzureCredential(f.credentials)
+	if err != nil {
+		return nil, fmt.Errorf("创建Azure凭据失败: %w", err)
+	}
+	client, err := armnetwork.NewSecurityRulesClient(extractSubscriptionID(securityGroupID), cred, nil)
+	if err != nil {
+		return nil, fmt.Errorf("创建安全规则客户端失败: %w", err)
+	}
+
+	poller, err := client.BeginCreateOrUpdate(ctx, extractResourceGroupFromID(securityGroupID),
+		extractResourceNameFromID(securityGroupID), rule.Name, toARMSecurityRule(rule), nil)
+	if err == nil {
+		_, err = poller.PollUntilDone(ctx, nil)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("创建安全规则失败: %w", err)
+	}
+
+	f.logger.Info("安全规则已创建",
+		zap.String("vmName", vm.Name),
+		zap.String("securityGroup", extractResourceNameFromID(securityGroupID)),
+		zap.String("rule", rule.Name))
+	return f.ListSecurityGroups(ctx, vm)
+}
+
+// DeleteSecurityRule 删除网络安全组中的规则，返回更新后的网络安全组
+func (f *VMFetcher) DeleteSecurityRule(ctx context.Context, vm VMDetails, securityGroupID, ruleName string) ([]SecurityGroupInfo, error) {
+	cred, err := createAzureCredential(f.credentials)
+	if err != nil {
+		return nil, fmt.Errorf("创建Azure凭据失败: %w", err)
+	}
+	client, err := armnetwork.NewSecurityRulesClient(extractSubscriptionID(securityGroupID), cred, nil)
+	if err != nil {
+		return nil, fmt.Errorf("创建安全规则客户端失败: %w", err)
+	}
+
+	poller, err := client.BeginDelete(ctx, extractResourceGroupFromID(securityGroupID),
+		extractResourceNameFromID(securityGroupID), ruleName, nil)
+	if err == nil {
+		_, err = poller.PollUntilDone(ctx, nil)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("删除安全规则失败: %w", err)
+	}
+
+	f.logger.Info("安全规则已删除",
+		zap.String("vmName", vm.Name),
+		zap.String("securityGroup", extractResourceNameFromID(securityGroupID)),
+		zap.String("rule", ruleName))
+	return f.ListSecurityGroups(ctx, vm)
+}
+
+// CreateSecurityGroup 创建网络安全组并关联到虚拟机的主网卡，主网卡已关联网络安全组时返回错误
+// 新建的网络安全组只有默认规则，入站流量需另外添加规则放行
+func (f *VMFetcher) CreateSecurityGroup(ctx context.Context, vm VMDetails, name string) ([]SecurityGroupInfo, error) {
+	cred, err := createAzureCredential(f.credentials)
+	if err != nil {
+		return nil, fmt.Errorf("创建Azure凭据失败: %w", err)
+	}
+	nicClient, err := armnetwork.NewInterfacesClient(vm.SubscriptionID, cred, nil)
+	if err != nil {
+		return nil, fmt.Errorf("创建网络客户端失败: %w", err)
+	}
+	nsgClient, err := armnetwork.NewSecurityGroupsClient(vm.SubscriptionID, cred, nil)
+	if err != nil {
+		return nil, fmt.Errorf("创建网络安全组客户端失败: %w", err)
+	}
+
+	// 1. 找到主网卡
+	nic, _, err := f.findPublicIPConfig(ctx, cred, nicClient, vm)
+	if err != nil {
+		return nil, err
+	}
+	if nic.Properties.NetworkSecurityGroup != nil && nic.Properties.NetworkSecurityGroup.ID != nil {
+		return nil, fmt.Errorf("网卡 %s 已关联网络安全组 %s", *nic.Name, extractResourceNameFromID(*nic.Properties.NetworkSecurityGroup.ID))
+	}
+
+	// 2. 在网卡所在区域创建网络安全组
+	if name == "" {
+		name = vm.Name + "-nsg"
+	}
+	location := vm.Location
+	if nic.Location != nil {
+		location = *nic.Location
+	}
+	poller, err := nsgClient.BeginCreateOrUpdate(ctx, vm.ResourceGroup, name, armnetwork.SecurityGroup{
+		Location: to.Ptr(location),
+	}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("创建网络安全组失败: %w", err)
+	}
+	resp, err := poller.PollUntilDone(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("等待网络安全组创建完成失败: %w", err)
+	}
+
+	// 3. 关联到网卡
+	nic.Properties.NetworkSecurityGroup = &armnetwork.SecurityGroup{ID: resp.ID}
+	nicPoller, err := nicClient.BeginCreateOrUpdate(ctx, extractResourceGroupFromID(*nic.ID), *nic.Name, *nic, nil)
+	if err == nil {
+		_, err = nicPoller.PollUntilDone(ctx, nil)
+	}
+	if err != nil {
+		// 关联失败时清理新建的网络安全组
+		if deletePoller, deleteErr := nsgClient.BeginDelete(ctx, vm.ResourceGroup, name, nil); deleteErr == nil {
+			_, deleteErr = deletePoller.PollUntilDone(ctx, nil)
+			if deleteErr != nil {
+				f.logger.Warn("清理网络安全组失败", zap.String("securityGroup", name), zap.Error(deleteErr))
+			}
+		}
+		return nil, fmt.Errorf("关联网络安全组到网卡失败: %w", err)
+	}
+
+	f.logger.Info("网络安全组已创建并关联",
+		zap.String("vmName", vm.Name),
+		zap.String("securityGroup", name),
+		zap.String("nic", *nic.Name))
+	return f.ListSecurityGroups(ctx, vm)
+}
+
+// loadSecurityGroups 按需获取网卡及其子网关联的网络安全组，写入网络索引
+func (f *VMFetcher) loadSecurityGroups(ctx context.Context, subscriptionID string, cred *azidentity.ClientSecretCredential, nic *armnetwork.Interface, network *networkIndex) error {
+	nsgClient, err := armnetwork.NewSecurityGroupsClient(subscriptionID, cred, nil)
+	if err != nil {
+		return fmt.Errorf("创建网络安全组客户端失败: %w", err)
+	}
+	subnetClient, err := armnetwork.NewSubnetsClient(subscriptionID, cred, nil)
+	if err != nil {
+		return fmt.Errorf("创建子网客户端失败: %w", err)
+	}
+
+	var nsgIDs []string
+	if nic.Properties.NetworkSecurityGroup != nil && nic.Properties.NetworkSecurityGroup.ID != nil {
+		nsgIDs = append(nsgIDs, *nic.Properties.NetworkSecurityGroup.ID)
+	}
+	for _, ipConfig := range nic.Properties.IPConfigurations {
+		if ipConfig.Properties == nil || ipConfig.Properties.Subnet == nil || ipConfig.Properties.Subnet.ID == nil {
+			continue
+		}
+		subnetID := *ipConfig.Properties.Subnet.ID
+		if _, ok := network.subnets[strings.ToLower(subnetID)]; ok {
+			continue
+		}
+		resp, err := subnetClient.Get(ctx, extractResourceGroupFromID(subnetID), extractVNetNameFromSubnetID(subnetID), extractResourceNameFromID(subnetID), nil)
+		if err != nil {
+			return fmt.Errorf("获取子网失败: %w", err)
+		}
+		network.subnets[strings.ToLower(subnetID)] = &resp.Subnet
+		if resp.Properties != nil && resp.Properties.NetworkSecurityGroup != nil && resp.Properties.NetworkSecurityGroup.ID != nil {
+			nsgIDs = append(nsgIDs, *resp.Properties.NetworkSecurityGroup.ID)
+		}
+	}
+
+	for _, id := range nsgIDs {
+		if _, ok := network.securityGroups[strings.ToLower(id)]; ok {
+			continue
+		}
+		resp, err := nsgClient.Get(ctx, extractResourceGroupFromID(id), extractResourceNameFromID(id), nil)
+		if err != nil {
+			return fmt.Errorf("获取网络安全组失败: %w", err)
+		}
+		network.securityGroups[strings.ToLower(id)] = &resp.SecurityGroup
+	}
+	return nil
+}
+
+// applySecurityGroups 根据网络索引填充网卡及其子网关联的网络安全组，多块网卡位于同一子网时只记录一次
+func applySecurityGroups(details *VMDetails, nic *armnetwork.Interface, network *networkIndex) {
+	add := func(ref *armnetwork.SecurityGroup, scope, attachedTo string) {
+		if ref == nil || ref.ID == nil {
+			return
+		}
+		for _, existing := range details.SecurityGroups {
+			if existing.Scope == scope && existing.AttachedTo == attachedTo && strings.EqualFold(existing.ID, *ref.ID) {
+				return
+			}
+		}
+		if nsg, ok := network.securityGroups[strings.ToLower(*ref.ID)]; ok {
+			details.SecurityGroups = append(details.SecurityGroups, toSecurityGroupInfo(nsg, scope, attachedTo))
+		}
+	}
+
+	var nicName string
+	if nic.Name != nil {
+		nicName = *nic.Name
+	}
+	add(nic.Properties.NetworkSecurityGroup, SecurityGroupScopeNIC, nicName)
+	for _, ipConfig := range nic.Properties.IPConfigurations {
+		if ipConfig.Properties == nil || ipConfig.Properties.Subnet == nil || ipConfig.Properties.Subnet.ID == nil {
+			continue
+		}
+		subnet, ok := network.subnets[strings.ToLower(*ipConfig.Properties.Subnet.ID)]
+		if !ok || subnet.Properties == nil {
+			continue
+		}
+		add(subnet.Properties.NetworkSecurityGroup, SecurityGroupScopeSubnet, extractResourceNameFromID(*ipConfig.Properties.Subnet.ID))
+	}
+}
+
+// toSecurityGroupInfo 转换网络安全组，规则按方向和优先级排序
+func toSecurityGroupInfo(nsg *armnetwork.SecurityGroup, scope, attachedTo string) SecurityGroupInfo {
+	info := SecurityGroupInfo{
+		Scope:      scope,
+		AttachedTo: attachedTo,
+		Rules:      []SecurityRule{},
+	}
+	if nsg.ID != nil {
+		info.ID = *nsg.ID
+		info.ResourceGroup = extractResourceGroupFromID(*nsg.ID)
+	}
+	if nsg.Name != nil {
+		info.Name = *nsg.Name
+	}
+	if nsg.Location != nil {
+		info.Location = *nsg.Location
+	}
+	if nsg.Properties != nil {
+		for _, rule := range nsg.Properties.SecurityRules {
+			info.Rules = append(info.Rules, fromARMSecurityRule(rule, false))
+		}
+		for _, rule := range nsg.Properties.DefaultSecurityRules {
+			info.Rules = append(info.Rules, fromARMSecurityRule(rule, true))
+		}
+	}
+	sort.SliceStable(info.Rules, func(i, j int) bool {
+		if info.Rules[i].Direction != info.Rules[j].Direction {
+			return info.Rules[i].Direction == string(armnetwork.SecurityRuleDirectionInbound)
+		}
+		return info.Rules[i].Priority < info.Rules[j].Priority
+	})
+	return info
+}
+
+// fromARMSecurityRule 转换安全规则，多个前缀或端口合并为逗号分隔的字符串
+func fromARMSecurityRule(rule *armnetwork.SecurityRule, isDefault bool) SecurityRule {
+	result := SecurityRule{Default: isDefault}
+	if rule.Name != nil {
+		result.Name = *rule.Name
+	}
+	props := rule.Properties
+	if props == nil {
+		return result
+	}
+	if props.Priority != nil {
+		result.Priority = *props.Priority
+	}
+	if props.Direction != nil {
+		result.Direction = string(*props.Direction)
+	}
+	if props.Access != nil {
+		result.Access = string(*props.Access)
+	}
+	if props.Protocol != nil {
+		result.Protocol = string(*props.Protocol)
+	}
+	result.SourceAddressPrefix = joinRuleValues(props.SourceAddressPrefix, props.SourceAddressPrefixes)
+	result.SourcePortRange = joinRuleValues(props.SourcePortRange, props.SourcePortRanges)
+	result.DestinationAddressPrefix = joinRuleValues(props.DestinationAddressPrefix, props.DestinationAddressPrefixes)
+	result.DestinationPortRange = joinRuleValues(props.DestinationPortRange, props.DestinationPortRanges)
+	if props.Description != nil {
+		result.Description = *props.Description
+	}
+	return result
+}
+
+// toARMSecurityRule 构建安全规则，逗号分隔的多个值使用复数字段
+func toARMSecurityRule(rule SecurityRule) armnetwork.SecurityRule {
+	props := &armnetwork.SecurityRulePropertiesFormat{
+		Priority:  to.Ptr(rule.Priority),
+		Direction: to.Ptr(armnetwork.SecurityRuleDirection(rule.Direction)),
+		Access:    to.Ptr(armnetwork.SecurityRuleAccess(rule.Access)),
+		Protocol:  to.Ptr(armnetwork.SecurityRuleProtocol(rule.Protocol)),
+	}
+	if rule.Description != "" {
+		props.Description = to.Ptr(rule.Description)
+	}
+	props.SourceAddressPrefix, props.SourceAddressPrefixes = splitRuleValues(rule.SourceAddressPrefix)
+	props.SourcePortRange, props.SourcePortRanges = splitRuleValues(rule.SourcePortRange)
+	props.DestinationAddressPrefix, props.DestinationAddressPrefixes = splitRuleValues(rule.DestinationAddressPrefix)
+	props.DestinationPortRange, props.DestinationPortRanges = splitRuleValues(rule.DestinationPortRange)
+	return armnetwork.SecurityRule{Name: to.Ptr(rule.Name), Properties: props}
+}
+
+// joinRuleValues 合并单值和多值字段
+func joinRuleValues(single *string, multi []*string) string {
+	if single != nil && *single != "" {
+		return *single
+	}
+	values := make([]string, 0, len(multi))
+	for _, v := range multi {
+		if v != nil {
+			values = append(values, *v)
+		}
+	}
+	return strings.Join(values, ",")
+}
+
+// splitRuleValues 拆分逗号分隔的值，只有一个值时使用单值字段，为空时表示任意(*)
+func splitRuleValues(value string) (*string, []*string) {
+	var values []*string
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, to.Ptr(v))
+		}
+	}
+	switch len(values) {
+	case 0:
+		return to.Ptr("*"), nil
+	case 1:
+		return values[0], nil
+	default:
+		return nil, values
+	}
+}
+
+// extractVNetNameFromSubnetID 从子网ID中提取虚拟网络名称
+// ID格式: /subscriptions/{subID}/resourceGroups/{rg}/providers/Microsoft.Network/virtualNetworks/{vnet}/subnets/{subnet}
+func extractVNetNameFromSubnetID(id string) string {
+	parts := strings.Split(id, "/")
+	for i, part := range parts {
+		if strings.EqualFold(part, "virtualNetworks") && i+1 < len(parts) {
+			return parts[i+1]
+		}
+	}
+	return ""
+}
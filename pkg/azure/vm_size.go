@@ -37,6 +37,15 @@ type VMSizeInfo struct {
 	Family       string
 	PricePerHour float64
 	Currency     string
+
+	// 以下字段仅由 ListResourceSKUs 填充
+	QuotaFamily           string   // 配额系列，例如 standardDSv3Family
+	Zones                 []string // 订阅在该区域可用的可用区
+	AcceleratedNetworking bool
+	PremiumIO             bool
+	HyperVGenerations     []string // 支持的 Hyper-V 代数，例如 V1、V2
+	Restricted            bool     // 规格在该区域对订阅不可用
+	RestrictionReason     string   // NotAvailableForSubscription 或 QuotaId
 }
 
 // ListSizes 获取指定位置的虚拟机规格列表
@@ -0,0 +1,342 @@
+package azure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/compute/armcompute/v5"
+	"go.uber.org/zap"
+)
+
+// SnapshotVMTag 快照上记录来源虚拟机资源ID的标签，磁盘更换后仍可按虚拟机查找快照
+const SnapshotVMTag = "sourceVmId"
+
+// ErrSnapshotVMIDRequired 未提供虚拟机资源ID，无法标记或筛选快照，避免误匹配其他虚拟机的快照
+var ErrSnapshotVMIDRequired = errors.New("缺少虚拟机资源ID，无法按虚拟机管理快照")
+
+// 快照来源磁盘的类型
+const (
+	SnapshotDiskRoleOS   = "os"
+	SnapshotDiskRoleData = "data"
+)
+
+// SnapshotInfo 磁盘快照信息
+type SnapshotInfo struct {
+	ID             string    `json:"id"`
+	Name           string    `json:"name"`
+	ResourceGroup  string    `json:"resourceGroup"`
+	Location       string    `json:"location"`
+	SourceVMID     string    `json:"sourceVmId"` // 来源虚拟机，取自 SnapshotVMTag 标签
+	SourceDiskID   string    `json:"sourceDiskId"`
+	SourceDiskName string    `json:"sourceDiskName"`
+	DiskRole       string    `json:"diskRole"` // os/data
+	SizeGB         int32     `json:"sizeGb"`
+	CreatedTime    time.Time `json:"createdTime"`
+}
+
+// OSDiskRestoreResult 从快照恢复系统盘的结果
+type OSDiskRestoreResult struct {
+	// OldOSDiskName 被替换的原系统盘，恢复后保留以便回退
+	OldOSDiskName string  `json:"oldOsDiskName"`
+	Disks         VMDisks `json:"disks"`
+	// PowerState 恢复完成后的电源状态
+	PowerState string `json:"powerState"`
+}
+
+// CreateSnapshot 为虚拟机的磁盘创建增量快照，diskName 为空时为系统盘创建快照
+// 快照创建在虚拟机所在的资源组，name 为空时按虚拟机名称和时间生成
+func (f *VMFetcher) CreateSnapshot(ctx context.Context, vm VMDetails, diskName, name string) (*SnapshotInfo, error) {
+	if vm.ID == "" {
+		return nil, ErrSnapshotVMIDRequired
+	}
+	cred, err := createAzureCredential(f.credentials)
+	if err != nil {
+		return nil, fmt.Errorf("创建Azure凭据失败: %w", err)
+	}
+	vmClient, err := armcompute.NewVirtualMachinesClient(vm.SubscriptionID, cred, nil)
+	if err != nil {
+		return nil, fmt.Errorf("创建虚拟机客户端失败: %w", err)
+	}
+	snapshotClient, err := armcompute.NewSnapshotsClient(vm.SubscriptionID, cred, nil)
+	if err != nil {
+		return nil, fmt.Errorf("创建快照客户端失败: %w", err)
+	}
+
+	// 1. 找到来源磁盘
+	vmResp, err := vmClient.Get(ctx, vm.ResourceGroup, vm.Name, nil)
+	if err != nil {
+		return nil, fmt.Errorf("获取虚拟机失败: %w", err)
+	}
+	if vmResp.Properties == nil || vmResp.Properties.StorageProfile == nil {
+		return nil, fmt.Errorf("虚拟机没有存储配置")
+	}
+	storage := vmResp.Properties.StorageProfile
+
+	role := SnapshotDiskRoleOS
+	var managed *armcompute.ManagedDiskParameters
+	if diskName == "" {
+		if storage.OSDisk != nil {
+			managed = storage.OSDisk.ManagedDisk
+		}
+	} else {
+		role = SnapshotDiskRoleData
+		for _, disk := range storage.DataDisks {
+			if disk.Name != nil && strings.EqualFold(*disk.Name, diskName) {
+				managed = disk.ManagedDisk
+			}
+		}
+	}
+	if managed == nil || managed.ID == nil {
+		return nil, fmt.Errorf("未找到托管磁盘: %s", diskName)
+	}
+
+	location := vm.Location
+	if vmResp.Location != nil {
+		location = *vmResp.Location
+	}
+	if name == "" {
+		name = fmt.Sprintf("%s-%s-%s", vm.Name, role, time.Now().UTC().Format("20060102-150405"))
+	}
+
+	// 2. 创建增量快照
+	poller, err := snapshotClient.BeginCreateOrUpdate(ctx, vm.ResourceGroup, name, armcompute.Snapshot{
+		Location: to.Ptr(location),
+		Tags:     map[string]*string{SnapshotVMTag: to.Ptr(vm.ID)},
+		Properties: &armcompute.SnapshotProperties{
+			CreationData: &armcompute.CreationData{
+				CreateOption:     to.Ptr(armcompute.DiskCreateOptionCopy),
+				SourceResourceID: managed.ID,
+			},
+			Incremental: to.Ptr(true),
+		},
+	}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("创建快照失败: %w", err)
+	}
+	resp, err := poller.PollUntilDone(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("等待快照创建完成失败: %w", err)
+	}
+
+	info := toSnapshotInfo(&resp.Snapshot)
+	info.DiskRole = role
+
+	f.logger.Info("快照创建成功",
+		zap.String("vmName", vm.Name),
+		zap.String("snapshotName", info.Name),
+		zap.String("sourceDisk", info.SourceDiskName))
+
+	return info, nil
+}
+
+// ListSnapshots 列出虚拟机所在资源组中由该虚拟机创建的快照
+func (f *VMFetcher) ListSnapshots(ctx context.Context, vm VMDetails) ([]SnapshotInfo, error) {
+	if vm.ID == "" {
+		return nil, ErrSnapshotVMIDRequired
+	}
+	cred, err := createAzureCredential(f.credentials)
+	if err != nil {
+		return nil, fmt.Errorf("创建Azure凭据失败: %w", err)
+	}
+	client, err := armcompute.NewSnapshotsClient(vm.SubscriptionID, cred, nil)
+	if err != nil {
+		return nil, fmt.Errorf("创建快照客户端失败: %w", err)
+	}
+
+	var snapshots []SnapshotInfo
+	pager := client.NewListByResourceGroupPager(vm.ResourceGroup, nil)
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("获取快照列表失败: %w", err)
+		}
+		for _, snapshot := range page.Value {
+			info := toSnapshotInfo(snapshot)
+			if strings.EqualFold(info.SourceVMID, vm.ID) {
+				snapshots = append(snapshots, *info)
+			}
+		}
+	}
+	return snapshots, nil
+}
+
+// DeleteSnapshot 删除快照
+func (f *VMFetcher) DeleteSnapshot(ctx context.Context, subscriptionID, resourceGroup, name string) error {
+	cred, err := createAzureCredential(f.credentials)
+	if err != nil {
+		return fmt.Errorf("创建Azure凭据失败: %w", err)
+	}
+	client, err := armcompute.NewSnapshotsClient(subscriptionID, cred, nil)
+	if err != nil {
+		return fmt.Errorf("创建快照客户端失败: %w", err)
+	}
+
+	poller, err := client.BeginDelete(ctx, resourceGroup, name, nil)
+	if err != nil {
+		return fmt.Errorf("删除快照失败: %w", err)
+	}
+	if _, err := poller.PollUntilDone(ctx, nil); err != nil {
+		return fmt.Errorf("等待快照删除完成失败: %w", err)
+	}
+	return nil
+}
+
+// RestoreOSDisk 从系统盘快照创建新的托管磁盘并替换为虚拟机的系统盘
+// 更换系统盘需要释放虚拟机，完成后按原状态启动；原系统盘保留不删除
+func (f *VMFetcher) RestoreOSDisk(ctx context.Context, vm VMDetails, snapshotID string) (*OSDiskRestoreResult, error) {
+	cred, err := createAzureCredential(f.credentials)
+	if err != nil {
+		return nil, fmt.Errorf("创建Azure凭据失败: %w", err)
+	}
+	vmClient, err := armcompute.NewVirtualMachinesClient(vm.SubscriptionID, cred, nil)
+	if err != nil {
+		return nil, fmt.Errorf("创建虚拟机客户端失败: %w", err)
+	}
+	diskClient, err := armcompute.NewDisksClient(vm.SubscriptionID, cred, nil)
+	if err != nil {
+		return nil, fmt.Errorf("创建磁盘客户端失败: %w", err)
+	}
+
+	// 1. 读取当前系统盘配置
+	vmResp, err := vmClient.Get(ctx, vm.ResourceGroup, vm.Name, nil)
+	if err != nil {
+		return nil, fmt.Errorf("获取虚拟机失败: %w", err)
+	}
+	if vmResp.Properties == nil || vmResp.Properties.StorageProfile == nil ||
+		vmResp.Properties.StorageProfile.OSDisk == nil || vmResp.Properties.StorageProfile.OSDisk.ManagedDisk == nil {
+		return nil, fmt.Errorf("虚拟机系统盘不是托管磁盘")
+	}
+	osDisk := vmResp.Properties.StorageProfile.OSDisk
+	result := &OSDiskRestoreResult{}
+	if osDisk.Name != nil {
+		result.OldOSDiskName = *osDisk.Name
+	}
+
+	location := vm.Location
+	if vmResp.Location != nil {
+		location = *vmResp.Location
+	}
+	disk := armcompute.Disk{
+		Location: to.Ptr(location),
+		Zones:    vmResp.Zones,
+		Properties: &armcompute.DiskProperties{
+			CreationData: &armcompute.CreationData{
+				CreateOption:     to.Ptr(armcompute.DiskCreateOptionCopy),
+				SourceResourceID: to.Ptr(snapshotID),
+			},
+		},
+	}
+	// 新系统盘沿用原系统盘的存储类型
+	if osDisk.ManagedDisk.StorageAccountType != nil {
+		disk.SKU = &armcompute.DiskSKU{Name: to.Ptr(armcompute.DiskStorageAccountTypes(*osDisk.ManagedDisk.StorageAccountType))}
+	}
+
+	// 2. 从快照创建新磁盘
+	name := fmt.Sprintf("%s_OsDisk_%d", vm.Name, time.Now().Unix())
+	createPoller, err := diskClient.BeginCreateOrUpdate(ctx, vm.ResourceGroup, name, disk, nil)
+	if err != nil {
+		return nil, fmt.Errorf("从快照创建磁盘失败: %w", err)
+	}
+	created, err := createPoller.PollUntilDone(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("等待磁盘创建完成失败: %w", err)
+	}
+
+	// 3. 释放虚拟机
+	powerState, err := f.GetVMStatus(ctx, vm.SubscriptionID, vm.ResourceGroup, vm.Name)
+	if err != nil {
+		f.deleteDisk(ctx, diskClient, vm.ResourceGroup, name)
+		return nil, err
+	}
+	wasRunning := powerState == "running" || powerState == "starting"
+	if powerState != "deallocated" {
+		if err := f.performDeallocate(ctx, vmClient, vm); err != nil {
+			f.deleteDisk(ctx, diskClient, vm.ResourceGroup, name)
+			return nil, fmt.Errorf("释放虚拟机失败: %w", err)
+		}
+	}
+
+	// 4. 替换系统盘
+	poller, err := vmClient.BeginUpdate(ctx, vm.ResourceGroup, vm.Name, armcompute.VirtualMachineUpdate{
+		Properties: &armcompute.VirtualMachineProperties{
+			StorageProfile: &armcompute.StorageProfile{
+				OSDisk: &armcompute.OSDisk{
+					Name:        to.Ptr(name),
+					ManagedDisk: &armcompute.ManagedDiskParameters{ID: created.ID},
+				},
+			},
+		},
+	}, nil)
+	var updated armcompute.VirtualMachinesClientUpdateResponse
+	if err == nil {
+		updated, err = poller.PollUntilDone(ctx, nil)
+	}
+	if err != nil {
+		f.deleteDisk(ctx, diskClient, vm.ResourceGroup, name)
+		if wasRunning {
+			if startErr := f.performStart(ctx, vmClient, vm); startErr != nil {
+				f.logger.Error("替换系统盘失败后恢复启动失败", zap.String("vmName", vm.Name), zap.Error(startErr))
+			}
+		}
+		return nil, fmt.Errorf("替换系统盘失败: %w", err)
+	}
+	result.Disks = *toVMDisks(&updated.VirtualMachine)
+
+	// 5. 按原状态重新启动
+	if wasRunning {
+		if err := f.performStart(ctx, vmClient, vm); err != nil {
+			return nil, fmt.Errorf("替换系统盘后启动虚拟机失败: %w", err)
+		}
+	}
+
+	result.PowerState, err = f.GetVMStatus(ctx, vm.SubscriptionID, vm.ResourceGroup, vm.Name)
+	if err != nil {
+		f.logger.Warn("获取恢复后的虚拟机状态失败", zap.String("vmName", vm.Name), zap.Error(err))
+	}
+
+	f.logger.Info("系统盘恢复成功",
+		zap.String("vmName", vm.Name),
+		zap.String("snapshotId", snapshotID),
+		zap.String("osDiskName", name),
+		zap.String("oldOsDiskName", result.OldOSDiskName))
+
+	return result, nil
+}
+
+// toSnapshotInfo 转换快照信息，系统盘快照带有操作系统类型
+func toSnapshotInfo(snapshot *armcompute.Snapshot) *SnapshotInfo {
+	info := &SnapshotInfo{DiskRole: SnapshotDiskRoleData}
+	if snapshot.ID != nil {
+		info.ID = *snapshot.ID
+		info.ResourceGroup = extractResourceGroupFromID(*snapshot.ID)
+	}
+	if snapshot.Name != nil {
+		info.Name = *snapshot.Name
+	}
+	if snapshot.Location != nil {
+		info.Location = *snapshot.Location
+	}
+	if tag := snapshot.Tags[SnapshotVMTag]; tag != nil {
+		info.SourceVMID = *tag
+	}
+	if props := snapshot.Properties; props != nil {
+		if props.CreationData != nil && props.CreationData.SourceResourceID != nil {
+			info.SourceDiskID = *props.CreationData.SourceResourceID
+			info.SourceDiskName = extractResourceNameFromID(info.SourceDiskID)
+		}
+		if props.DiskSizeGB != nil {
+			info.SizeGB = *props.DiskSizeGB
+		}
+		if props.TimeCreated != nil {
+			info.CreatedTime = *props.TimeCreated
+		}
+		if props.OSType != nil {
+			info.DiskRole = SnapshotDiskRoleOS
+		}
+	}
+	return info
+}
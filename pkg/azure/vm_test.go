@@ -0,0 +1,271 @@
+package azure
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/compute/armcompute/v5"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/network/armnetwork/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestVMFetcher_ExtractVMDetails(t *testing.T) {
+	const (
+		vmID  = "/subscriptions/sub-1/resourceGroups/RG/providers/Microsoft.Compute/virtualMachines/vm1"
+		nicID = "/subscriptions/sub-1/resourceGroups/rg/providers/Microsoft.Network/networkInterfaces/vm1-nic"
+		pipID = "/subscriptions/sub-1/resourceGroups/rg/providers/Microsoft.Network/publicIPAddresses/vm1-ip"
+	)
+
+	vm := &armcompute.VirtualMachine{
+		ID:       to.Ptr(vmID),
+		Name:     to.Ptr("vm1"),
+		Location: to.Ptr("eastus"),
+		Properties: &armcompute.VirtualMachineProperties{
+			HardwareProfile: &armcompute.HardwareProfile{
+				VMSize: to.Ptr(armcompute.VirtualMachineSizeTypesStandardB2S),
+			},
+			InstanceView: &armcompute.VirtualMachineInstanceView{
+				Statuses: []*armcompute.InstanceViewStatus{
+					{Code: to.Ptr("ProvisioningState/succeeded")},
+					{Code: to.Ptr("PowerState/deallocated")},
+				},
+			},
+			NetworkProfile: &armcompute.NetworkProfile{
+				NetworkInterfaces: []*armcompute.NetworkInterfaceReference{{ID: to.Ptr(nicID)}},
+			},
+		},
+	}
+
+	network := &networkIndex{
+		nics: map[string]*armnetwork.Interface{
+			strings.ToLower(nicID): {
+				ID: to.Ptr(nicID),
+				Properties: &armnetwork.InterfacePropertiesFormat{
+					IPConfigurations: []*armnetwork.InterfaceIPConfiguration{{
+						Properties: &armnetwork.InterfaceIPConfigurationPropertiesFormat{
+							PrivateIPAddress: to.Ptr("10.0.0.4"),
+							PublicIPAddress:  &armnetwork.PublicIPAddress{ID: to.Ptr(strings.ToUpper(pipID))},
+						},
+					}},
+				},
+			},
+		},
+		publicIPs: map[string]*armnetwork.PublicIPAddress{
+			strings.ToLower(pipID): {
+				ID: to.Ptr(pipID),
+				Properties: &armnetwork.PublicIPAddressPropertiesFormat{
+					IPAddress:   to.Ptr("20.1.1.1"),
+					DNSSettings: &armnetwork.PublicIPAddressDNSSettings{DomainNameLabel: to.Ptr("myvm")},
+				},
+			},
+		},
+	}
+	sizes := map[string]sizeSpec{"standard_b2s": {cores: 2, memoryGB: 4}}
+
+	f := NewVMFetcher(&Credentials{}, zap.NewNop(), 0)
+	details, err := f.extractVMDetails("sub-1", vm, network, sizes)
+	require.NoError(t, err)
+
+	assert.Equal(t, "RG", details.ResourceGroup)
+	assert.Equal(t, "succeeded", details.State)
+	assert.Equal(t, "deallocated", details.PowerState)
+	assert.Equal(t, "Deallocated", details.Status)
+	assert.Equal(t, []string{"10.0.0.4"}, details.PrivateIPs)
+	assert.Equal(t, []string{"20.1.1.1"}, details.PublicIPs)
+	assert.Equal(t, "VM1-IP", details.PublicIPName)
+	assert.Equal(t, "myvm.eastus.cloudapp.azure.com", details.DnsAlias)
+	assert.Equal(t, int32(2), details.NumberOfCores)
+	assert.Equal(t, int32(4), details.MemoryInGB)
+	assert.False(t, details.SecurityGroupsUnavailable)
+
+	// 网络安全组不可用时其他网络信息照常解析
+	network.securityGroupsUnavailable = true
+	details, err = f.extractVMDetails("sub-1", vm, network, sizes)
+	require.NoError(t, err)
+	assert.True(t, details.SecurityGroupsUnavailable)
+	assert.Empty(t, details.SecurityGroups)
+	assert.Equal(t, []string{"20.1.1.1"}, details.PublicIPs)
+}
+
+func TestVMFetcher_ExtractVMDetails_MissingNIC(t *testing.T) {
+	vm := &armcompute.VirtualMachine{
+		ID:       to.Ptr("/subscriptions/sub-1/resourceGroups/rg/providers/Microsoft.Compute/virtualMachines/vm1"),
+		Name:     to.Ptr("vm1"),
+		Location: to.Ptr("eastus"),
+		Properties: &armcompute.VirtualMachineProperties{
+			NetworkProfile: &armcompute.NetworkProfile{
+				NetworkInterfaces: []*armcompute.NetworkInterfaceReference{{ID: to.Ptr("/missing")}},
+			},
+		},
+	}
+
+	f := NewVMFetcher(&Credentials{}, zap.NewNop(), 0)
+	details, err := f.extractVMDetails("sub-1", vm, &networkIndex{}, nil)
+	require.NoError(t, err)
+	assert.Empty(t, details.PrivateIPs)
+	assert.Empty(t, details.PowerState)
+}
+
+func TestParseRunCommandResult(t *testing.T) {
+	// Linux 执行成功
+	result := parseRunCommandResult([]*armcompute.InstanceViewStatus{{
+		Code:    to.Ptr("ProvisioningState/succeeded"),
+		Message: to.Ptr("Enable succeeded: \n[stdout]\nhello\nworld\n\n[stderr]\n"),
+	}})
+	assert.True(t, result.Succeeded)
+	require.NotNil(t, result.ExitCode)
+	assert.Equal(t, 0, *result.ExitCode)
+	assert.Equal(t, "hello\nworld\n", result.Stdout)
+	assert.Equal(t, "", result.Stderr)
+
+	// Linux 脚本以非零状态退出
+	result = parseRunCommandResult([]*armcompute.InstanceViewStatus{{
+		Code:    to.Ptr("ProvisioningState/succeeded"),
+		Message: to.Ptr("Enable failed: failed to execute command: command terminated with exit status=2\n[stdout]\n\n[stderr]\nno such file\n"),
+	}})
+	assert.False(t, result.Succeeded)
+	require.NotNil(t, result.ExitCode)
+	assert.Equal(t, 2, *result.ExitCode)
+	assert.Equal(t, "no such file", result.Stderr)
+
+	// Windows 分别返回标准输出和标准错误
+	result = parseRunCommandResult([]*armcompute.InstanceViewStatus{
+		{Code: to.Ptr("ComponentStatus/StdOut/succeeded"), Message: to.Ptr("hello")},
+		{Code: to.Ptr("ComponentStatus/StdErr/succeeded"), Message: to.Ptr("")},
+	})
+	assert.True(t, result.Succeeded)
+	assert.Nil(t, result.ExitCode)
+	assert.Equal(t, "hello", result.Stdout)
+}
+
+func TestQuotaFamilyName(t *testing.T) {
+	for size, family := range map[string]string{
+		"Standard_D2s_v3":     "standardDSv3Family",
+		"Standard_D2_v3":      "standardDv3Family",
+		"Standard_DS2_v2":     "standardDSv2Family",
+		"Standard_E4-2ads_v5": "standardEADSv5Family",
+		"Standard_NC6s_v3":    "standardNCSv3Family",
+		"Standard_B1ls":       "standardBSFamily",
+		"Standard_B2ms":       "standardBSFamily",
+		"Standard_B2ats_v2":   "standardBasv2Family",
+		"Standard_A1_v2":      "standardAv2Family",
+		"custom-size":         "",
+	} {
+		assert.Equal(t, family, QuotaFamilyName(size), size)
+	}
+}
+
+func TestSizeInfoFromSKU(t *testing.T) {
+	capability := func(name, value string) *armcompute.ResourceSKUCapabilities {
+		return &armcompute.ResourceSKUCapabilities{Name: to.Ptr(name), Value: to.Ptr(value)}
+	}
+	sku := &armcompute.ResourceSKU{
+		Name:         to.Ptr("Standard_D2s_v3"),
+		ResourceType: to.Ptr("virtualMachines"),
+		Family:       to.Ptr("standardDSv3Family"),
+		Capabilities: []*armcompute.ResourceSKUCapabilities{
+			capability("vCPUs", "2"),
+			capability("MemoryGB", "8"),
+			capability("MaxDataDiskCount", "4"),
+			capability("OSVhdSizeMB", "1047552"),
+			capability("AcceleratedNetworkingEnabled", "True"),
+			capability("PremiumIO", "True"),
+			capability("HyperVGenerations", "V1,V2"),
+		},
+		LocationInfo: []*armcompute.ResourceSKULocationInfo{
+			{Location: to.Ptr("EastUS"), Zones: to.SliceOfPtrs("3", "1", "2")},
+		},
+		Restrictions: []*armcompute.ResourceSKURestrictions{
+			{
+				Type:            to.Ptr(armcompute.ResourceSKURestrictionsTypeZone),
+				ReasonCode:      to.Ptr(armcompute.ResourceSKURestrictionsReasonCodeNotAvailableForSubscription),
+				RestrictionInfo: &armcompute.ResourceSKURestrictionInfo{Locations: to.SliceOfPtrs("eastus"), Zones: to.SliceOfPtrs("2")},
+			},
+		},
+	}
+
+	info := sizeInfoFromSKU(sku, "eastus")
+	require.NotNil(t, info)
+	assert.Equal(t, 2, info.Cores)
+	assert.Equal(t, 8.0, info.MemoryGB)
+	assert.Equal(t, 4, info.MaxDataDisks)
+	assert.Equal(t, 1023, info.OSDiskSizeGB)
+	assert.Equal(t, "standardDSv3Family", info.QuotaFamily)
+	assert.True(t, info.AcceleratedNetworking)
+	assert.True(t, info.PremiumIO)
+	assert.Equal(t, []string{"V1", "V2"}, info.HyperVGenerations)
+	assert.Equal(t, []string{"1", "3"}, info.Zones)
+	assert.False(t, info.Restricted)
+
+	// 区域级别的限制
+	sku.Restrictions = append(sku.Restrictions, &armcompute.ResourceSKURestrictions{
+		Type:       to.Ptr(armcompute.ResourceSKURestrictionsTypeLocation),
+		ReasonCode: to.Ptr(armcompute.ResourceSKURestrictionsReasonCodeNotAvailableForSubscription),
+		Values:     to.SliceOfPtrs("eastus"),
+	})
+	info = sizeInfoFromSKU(sku, "eastus")
+	require.NotNil(t, info)
+	assert.True(t, info.Restricted)
+	assert.Equal(t, "NotAvailableForSubscription", info.RestrictionReason)
+	info = sizeInfoFromSKU(sku, "westus")
+	require.NotNil(t, info)
+	assert.False(t, info.Restricted)
+	assert.Empty(t, info.Zones)
+
+	// 非虚拟机类型的 SKU
+	assert.Nil(t, sizeInfoFromSKU(&armcompute.ResourceSKU{Name: to.Ptr("Premium_LRS"), ResourceType: to.Ptr("disks")}, "eastus"))
+}
+
+func TestSizeCache_Load(t *testing.T) {
+	cache := newSizeCache()
+	logger := zap.NewNop()
+
+	// 同一区域并发访问只请求一次
+	var calls int32
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			specs := cache.load("eastus", func() (map[string]sizeSpec, error) {
+				atomic.AddInt32(&calls, 1)
+				return map[string]sizeSpec{"standard_b1s": {cores: 1, memoryGB: 1}}, nil
+			}, logger)
+			assert.Equal(t, int32(1), specs["standard_b1s"].cores)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+	// 一个区域的请求未完成时不阻塞其他区域
+	release := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		cache.load("westus", func() (map[string]sizeSpec, error) {
+			<-release
+			return nil, errors.New("timeout")
+		}, logger)
+	}()
+	specs := cache.load("EastUS", func() (map[string]sizeSpec, error) {
+		t.Fatal("已缓存的区域不应再次请求")
+		return nil, nil
+	}, logger)
+	assert.Len(t, specs, 1)
+	close(release)
+	<-done
+
+	// 失败的结果同样缓存，本次同步内不再重试
+	specs = cache.load("westus", func() (map[string]sizeSpec, error) {
+		t.Fatal("失败的区域不应再次请求")
+		return nil, nil
+	}, logger)
+	assert.NotNil(t, specs)
+	assert.Empty(t, specs)
+}
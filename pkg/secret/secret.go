@@ -0,0 +1,259 @@
+// Package secret 提供数据库中敏感凭据的信封加密
+//
+// 每个值使用随机生成的数据密钥(DEK)以 AES-256-GCM 加密，数据密钥再由主密钥(KEK)加密后与密文一起存储。
+// 轮换主密钥时只需用新主密钥重新加密数据密钥，无需重新加密数据本身。
+//
+// 密文格式: enc:v1:<主密钥ID>:<base64(加密后的数据密钥)>:<base64(加密后的数据)>
+package secret
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+const (
+	prefix    = "enc:v1:"
+	dekLength = 32
+
+	// EnvMasterKey 主密钥环境变量，优先于配置文件
+	EnvMasterKey = "AZURE_VM_MASTER_KEY"
+	// EnvPreviousMasterKeys 旧主密钥环境变量，多个以逗号分隔，仅用于解密和轮换
+	EnvPreviousMasterKeys = "AZURE_VM_PREVIOUS_MASTER_KEYS"
+)
+
+var (
+	// ErrNotEncrypted 值不是本包生成的密文
+	ErrNotEncrypted = errors.New("值未加密")
+	// ErrUnknownKey 密文使用的主密钥未配置
+	ErrUnknownKey = errors.New("未找到对应的主密钥")
+)
+
+// masterKey 主密钥
+type masterKey struct {
+	id   string
+	aead cipher.AEAD
+}
+
+// Cipher 信封加密器
+type Cipher struct {
+	current *masterKey
+	keys    map[string]*masterKey
+}
+
+// NewCipher 从配置创建加密器
+// 主密钥读取顺序: 环境变量 AZURE_VM_MASTER_KEY，配置项 security.credential.master_key
+// 旧主密钥读取顺序: 环境变量 AZURE_VM_PREVIOUS_MASTER_KEYS，配置项 security.credential.previous_keys
+func NewCipher(conf *viper.Viper) *Cipher {
+	master := os.Getenv(EnvMasterKey)
+	if master == "" {
+		master = conf.GetString("security.credential.master_key")
+	}
+
+	var previous []string
+	if env := os.Getenv(EnvPreviousMasterKeys); env != "" {
+		previous = strings.Split(env, ",")
+	} else {
+		previous = conf.GetStringSlice("security.credential.previous_keys")
+	}
+
+	c, err := New(master, previous...)
+	if err != nil {
+		panic(fmt.Sprintf("初始化凭据加密失败: %v", err))
+	}
+	return c
+}
+
+// New 使用 base64 编码的 32 字节主密钥创建加密器，previous 为轮换前的旧主密钥
+func New(master string, previous ...string) (*Cipher, error) {
+	if master == "" {
+		return nil, errors.New("未配置主密钥")
+	}
+	current, err := parseMasterKey(master)
+	if err != nil {
+		return nil, fmt.Errorf("主密钥无效: %w", err)
+	}
+
+	c := &Cipher{
+		current: current,
+		keys:    map[string]*masterKey{current.id: current},
+	}
+	for _, p := range previous {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		key, err := parseMasterKey(p)
+		if err != nil {
+			return nil, fmt.Errorf("旧主密钥无效: %w", err)
+		}
+		if _, exists := c.keys[key.id]; !exists {
+			c.keys[key.id] = key
+		}
+	}
+	return c, nil
+}
+
+func parseMasterKey(encoded string) (*masterKey, error) {
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("需要 base64 编码: %w", err)
+	}
+	if len(raw) != 32 {
+		return nil, fmt.Errorf("需要 32 字节，实际 %d 字节", len(raw))
+	}
+	aead, err := newAEAD(raw)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(raw)
+	return &masterKey{id: hex.EncodeToString(sum[:4]), aead: aead}, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// IsEncrypted 判断值是否为本包生成的密文
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, prefix)
+}
+
+// Encrypt 加密明文，空字符串原样返回
+func (c *Cipher) Encrypt(plaintext string) (string, error) {
+	if plaintext == "" {
+		return "", nil
+	}
+
+	dek := make([]byte, dekLength)
+	if _, err := rand.Read(dek); err != nil {
+		return "", fmt.Errorf("生成数据密钥失败: %w", err)
+	}
+	dataAEAD, err := newAEAD(dek)
+	if err != nil {
+		return "", err
+	}
+
+	data, err := seal(dataAEAD, []byte(plaintext))
+	if err != nil {
+		return "", err
+	}
+	wrapped, err := seal(c.current.aead, dek)
+	if err != nil {
+		return "", err
+	}
+	return format(c.current.id, wrapped, data), nil
+}
+
+// Decrypt 解密密文，空字符串原样返回
+func (c *Cipher) Decrypt(value string) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+	key, wrapped, data, err := c.parse(value)
+	if err != nil {
+		return "", err
+	}
+
+	dek, err := open(key.aead, wrapped)
+	if err != nil {
+		return "", fmt.Errorf("解密数据密钥失败: %w", err)
+	}
+	dataAEAD, err := newAEAD(dek)
+	if err != nil {
+		return "", err
+	}
+	plaintext, err := open(dataAEAD, data)
+	if err != nil {
+		return "", fmt.Errorf("解密数据失败: %w", err)
+	}
+	return string(plaintext), nil
+}
+
+// NeedsRotation 判断密文是否使用旧主密钥加密
+func (c *Cipher) NeedsRotation(value string) bool {
+	if !IsEncrypted(value) {
+		return false
+	}
+	id, _, _ := strings.Cut(strings.TrimPrefix(value, prefix), ":")
+	return id != c.current.id
+}
+
+// Rotate 使用当前主密钥重新加密数据密钥，数据密文保持不变
+func (c *Cipher) Rotate(value string) (string, error) {
+	if !c.NeedsRotation(value) {
+		return value, nil
+	}
+	key, wrapped, data, err := c.parse(value)
+	if err != nil {
+		return "", err
+	}
+	dek, err := open(key.aead, wrapped)
+	if err != nil {
+		return "", fmt.Errorf("解密数据密钥失败: %w", err)
+	}
+	rewrapped, err := seal(c.current.aead, dek)
+	if err != nil {
+		return "", err
+	}
+	return format(c.current.id, rewrapped, data), nil
+}
+
+func (c *Cipher) parse(value string) (*masterKey, []byte, []byte, error) {
+	if !IsEncrypted(value) {
+		return nil, nil, nil, ErrNotEncrypted
+	}
+	parts := strings.Split(strings.TrimPrefix(value, prefix), ":")
+	if len(parts) != 3 {
+		return nil, nil, nil, errors.New("密文格式无效")
+	}
+	key, ok := c.keys[parts[0]]
+	if !ok {
+		return nil, nil, nil, fmt.Errorf("%w: %s", ErrUnknownKey, parts[0])
+	}
+	wrapped, err := base64.StdEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("数据密钥格式无效: %w", err)
+	}
+	data, err := base64.StdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("数据格式无效: %w", err)
+	}
+	return key, wrapped, data, nil
+}
+
+func format(keyID string, wrapped, data []byte) string {
+	return prefix + keyID + ":" +
+		base64.StdEncoding.EncodeToString(wrapped) + ":" +
+		base64.StdEncoding.EncodeToString(data)
+}
+
+// seal 加密并在密文前附加随机 nonce
+func seal(aead cipher.AEAD, plaintext []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("生成随机数失败: %w", err)
+	}
+	return aead.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+func open(aead cipher.AEAD, sealed []byte) ([]byte, error) {
+	if len(sealed) < aead.NonceSize() {
+		return nil, errors.New("密文长度无效")
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	return aead.Open(nil, nonce, ciphertext, nil)
+}
@@ -0,0 +1,95 @@
+package secret
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newKey(t *testing.T) string {
+	raw := make([]byte, 32)
+	_, err := rand.Read(raw)
+	require.NoError(t, err)
+	return base64.StdEncoding.EncodeToString(raw)
+}
+
+func TestCipher_EncryptDecrypt(t *testing.T) {
+	c, err := New(newKey(t))
+	require.NoError(t, err)
+
+	encrypted, err := c.Encrypt("client-secret")
+	require.NoError(t, err)
+	assert.True(t, IsEncrypted(encrypted))
+	assert.NotContains(t, encrypted, "client-secret")
+
+	// 相同明文每次加密结果不同
+	again, err := c.Encrypt("client-secret")
+	require.NoError(t, err)
+	assert.NotEqual(t, encrypted, again)
+
+	plaintext, err := c.Decrypt(encrypted)
+	require.NoError(t, err)
+	assert.Equal(t, "client-secret", plaintext)
+
+	empty, err := c.Encrypt("")
+	require.NoError(t, err)
+	assert.Empty(t, empty)
+}
+
+func TestCipher_DecryptInvalid(t *testing.T) {
+	c, err := New(newKey(t))
+	require.NoError(t, err)
+
+	_, err = c.Decrypt("plaintext")
+	assert.ErrorIs(t, err, ErrNotEncrypted)
+
+	other, err := New(newKey(t))
+	require.NoError(t, err)
+	encrypted, err := other.Encrypt("client-secret")
+	require.NoError(t, err)
+	_, err = c.Decrypt(encrypted)
+	assert.ErrorIs(t, err, ErrUnknownKey)
+
+	// 篡改数据密文
+	tampered := encrypted[:len(encrypted)-4] + "AAA="
+	_, err = other.Decrypt(tampered)
+	assert.Error(t, err)
+}
+
+func TestCipher_Rotate(t *testing.T) {
+	oldKey, newKeyValue := newKey(t), newKey(t)
+	oldCipher, err := New(oldKey)
+	require.NoError(t, err)
+	encrypted, err := oldCipher.Encrypt("client-secret")
+	require.NoError(t, err)
+
+	rotating, err := New(newKeyValue, oldKey)
+	require.NoError(t, err)
+	assert.True(t, rotating.NeedsRotation(encrypted))
+
+	rotated, err := rotating.Rotate(encrypted)
+	require.NoError(t, err)
+	assert.False(t, rotating.NeedsRotation(rotated))
+	// 只重新加密数据密钥，数据密文不变
+	assert.Equal(t, encrypted[strings.LastIndex(encrypted, ":"):], rotated[strings.LastIndex(rotated, ":"):])
+
+	// 轮换完成后移除旧主密钥仍可解密
+	newCipher, err := New(newKeyValue)
+	require.NoError(t, err)
+	plaintext, err := newCipher.Decrypt(rotated)
+	require.NoError(t, err)
+	assert.Equal(t, "client-secret", plaintext)
+}
+
+func TestNew_InvalidKey(t *testing.T) {
+	_, err := New("")
+	assert.Error(t, err)
+	_, err = New(base64.StdEncoding.EncodeToString([]byte("short")))
+	assert.Error(t, err)
+	_, err = New(newKey(t), "not-base64!")
+	assert.Error(t, err)
+}
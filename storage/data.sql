@@ -31,6 +31,8 @@ create table virtual_machines
     core            int,
     memory          int,
     os_image        VARCHAR(32),
+    security_groups TEXT,
+    monthly_cost    REAL,
     constraint idx_vm_account_subscription
         unique (account_id, subscription_id, name)
 );
@@ -86,6 +88,10 @@ create table subscriptions
     spending_limit        VARCHAR(32),
     start_date            DATETIME,
     end_date              DATETIME,
+    offer_type            VARCHAR(32),
+    credit_amount         REAL,
+    credit_currency       VARCHAR(8),
+    credit_expires_at     DATETIME,
     created_at            DATETIME default CURRENT_TIMESTAMP not null,
     updated_at            DATETIME default CURRENT_TIMESTAMP not null,
     deleted_at            DATETIME default NULL,
@@ -98,6 +104,9 @@ create index idx_subs_account_id
 create index idx_subs_subscription_id
     on subscriptions (subscription_id);
 
+create index idx_subscriptions_offer_type
+    on subscriptions (offer_type);
+
 -- auto-generated definition
 create table accounts
 (
@@ -119,7 +128,7 @@ create table accounts
     deleted_at          DATETIME    default NULL,
     vm_count            integer     default 0,
     constraint chk_subscription_status
-        check (subscription_status IN ('normal', 'error'))
+        check (subscription_status IN ('normal', 'warned', 'past_due', 'disabled', 'none', 'error'))
 );
 
 create index idx_azure_accounts_deleted_at
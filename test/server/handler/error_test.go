@@ -0,0 +1,119 @@
+package handler
+
+import (
+	v1 "azure-vm-backend/api/v1"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// handleError 使用 HandleError 处理错误并返回响应
+func handleError(t *testing.T, httpCode int, err error) (int, v1.Response) {
+	w := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(w)
+	v1.HandleError(ctx, httpCode, err, nil)
+
+	var resp v1.Response
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	return w.Code, resp
+}
+
+// azureResponseError 构造 Azure SDK 返回的错误
+func azureResponseError(status int, code, message string) error {
+	body := fmt.Sprintf(`{"error":{"code":%q,"message":%q}}`, code, message)
+	return &azcore.ResponseError{
+		ErrorCode:  code,
+		StatusCode: status,
+		RawResponse: &http.Response{
+			StatusCode: status,
+			Body:       io.NopCloser(strings.NewReader(body)),
+		},
+	}
+}
+
+func TestHandleError_WrappedError(t *testing.T) {
+	err := fmt.Errorf("获取操作失败: %w", v1.ErrNotFound)
+	assert.ErrorIs(t, err, v1.ErrNotFound)
+
+	status, resp := handleError(t, http.StatusInternalServerError, err)
+	assert.Equal(t, http.StatusNotFound, status)
+	assert.Equal(t, 404, resp.Code)
+	assert.Equal(t, "Not Found", resp.Message)
+
+	status, resp = handleError(t, http.StatusInternalServerError, errors.New("boom"))
+	assert.Equal(t, http.StatusInternalServerError, status)
+	assert.Equal(t, "unknown error", resp.Message)
+}
+
+func TestHandleError_Detail(t *testing.T) {
+	err := v1.ErrInvalidParams.WithDetail("name 不能为空")
+	assert.ErrorIs(t, err, v1.ErrInvalidParams)
+	// 预定义错误本身不被修改
+	assert.Empty(t, v1.ErrInvalidParams.Detail)
+
+	status, resp := handleError(t, http.StatusInternalServerError, err)
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Equal(t, "Invalid Params", resp.Message)
+	assert.Equal(t, "name 不能为空", resp.Detail)
+}
+
+func TestFromAzureError(t *testing.T) {
+	cases := []struct {
+		status  int
+		code    string
+		message string
+		want    *v1.Error
+	}{
+		{http.StatusConflict, "QuotaExceeded", "Operation results in exceeding quota limits of Core.", v1.ErrAzureQuotaExceeded},
+		{http.StatusConflict, "OperationNotAllowed", "Operation could not be completed as it results in exceeding approved standardBSFamily Cores quota.", v1.ErrAzureQuotaExceeded},
+		{http.StatusConflict, "SkuNotAvailable", "The requested size for resource is currently not available in location 'eastus'.", v1.ErrAzureSkuNotAvailable},
+		{http.StatusForbidden, "AuthorizationFailed", "The client does not have authorization to perform action.", v1.ErrAzureAuthorizationFailed},
+		{http.StatusNotFound, "ResourceGroupNotFound", "Resource group 'rg' could not be found.", v1.ErrAzureResourceNotFound},
+		{http.StatusTooManyRequests, "", "", v1.ErrAzureThrottled},
+		{http.StatusServiceUnavailable, "ServiceUnavailable", "", v1.ErrAzureRequestFailed},
+	}
+	for _, c := range cases {
+		err := v1.FromAzureError(fmt.Errorf("创建虚拟机失败: %w", azureResponseError(c.status, c.code, c.message)))
+		assert.ErrorIs(t, err, c.want, c.code)
+
+		var respErr *azcore.ResponseError
+		assert.True(t, errors.As(err, &respErr), "原始Azure错误应保留在错误链中")
+
+		status, resp := handleError(t, http.StatusInternalServerError, err)
+		assert.Equal(t, c.want.HTTPStatus, status, c.code)
+		assert.Equal(t, c.want.Code, resp.Code, c.code)
+		if c.message != "" {
+			assert.Equal(t, c.code+": "+c.message, resp.Detail)
+		}
+	}
+
+	// 未经转换的Azure错误在响应时同样被识别
+	status, resp := handleError(t, http.StatusInternalServerError,
+		fmt.Errorf("同步失败: %w", azureResponseError(http.StatusConflict, "SkuNotAvailable", "not available")))
+	assert.Equal(t, http.StatusConflict, status)
+	assert.Equal(t, v1.ErrAzureSkuNotAvailable.Code, resp.Code)
+
+	// 无法归类的错误不向调用方返回原始错误文本，原始错误保留在错误链和请求上下文中
+	cause := errors.New("Post \"https://login.microsoftonline.com/tenant-id/oauth2/v2.0/token\": dial tcp: i/o timeout")
+	err := v1.FromAzureError(cause)
+	assert.ErrorIs(t, err, v1.ErrAzureRequestFailed)
+	assert.ErrorIs(t, err, cause)
+	w := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(w)
+	v1.HandleError(ctx, http.StatusInternalServerError, err, nil)
+	assert.NotContains(t, w.Body.String(), "login.microsoftonline.com")
+	assert.Contains(t, ctx.Errors.String(), "login.microsoftonline.com")
+
+	assert.Nil(t, v1.FromAzureError(nil))
+	assert.ErrorIs(t, v1.FromAzureError(v1.ErrNotFound), v1.ErrNotFound)
+}
@@ -0,0 +1,95 @@
+package service_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	v1 "azure-vm-backend/api/v1"
+	"azure-vm-backend/internal/model"
+	"azure-vm-backend/internal/repository"
+	"azure-vm-backend/internal/service"
+	"azure-vm-backend/pkg/secret"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newSubscriptionsService(env *vmTestEnv) service.SubscriptionsService {
+	stateChangeRepo := repository.NewSubscriptionStateChangeRepository(repository.NewRepository(logger, env.db))
+	return service.NewSubscriptionsService(env.srv, env.subsRepo, env.accountsRepo, stateChangeRepo, env.provider)
+}
+
+func newAccountsService(env *vmTestEnv) service.AccountsService {
+	return service.NewAccountsService(env.srv, env.accountsRepo, newSubscriptionsService(env), env.vmService, env.provider)
+}
+
+func TestAccountsService_CreateAccount_EncryptsCredentials(t *testing.T) {
+	env := newVMTestEnv(t)
+	ctx := context.Background()
+	// 只有明文密钥能通过验证，确保验证时使用的是请求中的明文
+	env.provider.ValidSecrets["client-secret"] = true
+	accountsService := newAccountsService(env)
+
+	accountID, err := accountsService.CreateAccount(ctx, testUserID, &v1.CreateAccountReq{
+		LoginEmail:    "new@example.com",
+		LoginPassword: "portal-password",
+		AppID:         "app",
+		PassWord:      "client-secret",
+		Tenant:        "tenant",
+		DisplayName:   "new",
+	})
+	require.NoError(t, err)
+
+	var account model.Accounts
+	require.NoError(t, env.db.Where("account_id = ?", accountID).First(&account).Error)
+	assert.True(t, secret.IsEncrypted(account.PassWord))
+	assert.True(t, secret.IsEncrypted(account.LoginPassword))
+	assert.NotContains(t, account.PassWord, "client-secret")
+
+	plaintext, err := c.Decrypt(account.PassWord)
+	require.NoError(t, err)
+	assert.Equal(t, "client-secret", plaintext)
+
+	// 模型序列化时不包含任何凭据
+	data, err := json.Marshal(account)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), account.PassWord)
+	assert.NotContains(t, string(data), account.LoginPassword)
+
+	// 更新后的凭据同样加密存储
+	require.NoError(t, accountsService.UpdateAccount(ctx, testUserID, accountID, &v1.UpdateAccountReq{
+		LoginPassword: "new-portal-password",
+	}))
+	require.NoError(t, env.db.Where("account_id = ?", accountID).First(&account).Error)
+	plaintext, err = c.Decrypt(account.LoginPassword)
+	require.NoError(t, err)
+	assert.Equal(t, "new-portal-password", plaintext)
+}
+
+func TestVirtualMachineService_SyncVMs_PlaintextCredentials(t *testing.T) {
+	env := newVMTestEnv(t)
+	// 未执行迁移的明文凭据不会被直接使用
+	require.NoError(t, env.db.Model(&model.Accounts{}).Where("account_id = ?", testAccountID).
+		Update("password", "plaintext").Error)
+
+	_, err := env.vmService.SyncVMs(context.Background(), testUserID, testAccountID)
+	assert.Error(t, err)
+}
+
+func TestAccountsService_SyncAccounts_HidesRawErrors(t *testing.T) {
+	env := newVMTestEnv(t)
+	ctx := context.Background()
+	accountsService := newAccountsService(env)
+
+	// 原始错误中的租户ID和请求地址不返回给调用方
+	raw := errors.New("AADSTS7000215: tenant tenant-secret-id https://login.microsoftonline.com/tenant-secret-id")
+	for _, method := range []string{"FetchSubscriptionDetails", "FetchVMDetails"} {
+		env.provider.Errors = map[string]error{method: raw}
+		resp, err := accountsService.SyncAccounts(ctx, testUserID, []string{testAccountID})
+		require.NoError(t, err, method)
+		require.Len(t, resp.FailedAccounts, 1, method)
+		assert.NotEmpty(t, resp.FailedAccounts[0].Message, method)
+		assert.NotContains(t, resp.FailedAccounts[0].Message, "tenant-secret-id", method)
+	}
+}
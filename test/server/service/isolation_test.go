@@ -0,0 +1,158 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+
+	v1 "azure-vm-backend/api/v1"
+	"azure-vm-backend/internal/model"
+	"azure-vm-backend/internal/repository"
+	"azure-vm-backend/internal/service"
+	"azure-vm-backend/pkg/app"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	otherUserID    = "user-2"
+	otherAccountID = "account-2"
+	otherSubID     = "sub-2"
+)
+
+// seedTenants 为两个用户各写入一个账户下的订阅、虚拟机、镜像和规格
+func seedTenants(t *testing.T, env *vmTestEnv) {
+	require.NoError(t, env.db.Create(&model.Accounts{
+		AccountID:   otherAccountID,
+		UserID:      otherUserID,
+		LoginEmail:  "b@example.com",
+		AppID:       "app",
+		Tenant:      "tenant",
+		DisplayName: "other",
+	}).Error)
+	require.NoError(t, env.db.Create(&model.Subscriptions{
+		AccountID:      otherAccountID,
+		SubscriptionID: otherSubID,
+		DisplayName:    "other",
+		State:          "Enabled",
+	}).Error)
+
+	for _, vm := range []*model.VirtualMachine{
+		{VMID: "vm-1", AccountID: testAccountID, SubscriptionID: testSubID, Name: "mine", ResourceGroup: "rg", Location: "eastus"},
+		{VMID: "vm-2", AccountID: otherAccountID, SubscriptionID: otherSubID, Name: "theirs", ResourceGroup: "rg", Location: "eastus"},
+	} {
+		require.NoError(t, env.db.Create(vm).Error)
+	}
+	for _, image := range []*model.VmImage{
+		{AccountID: testAccountID, Publisher: "Canonical", Offer: "ubuntu", Sku: "22_04", Version: "latest", OSType: "Linux", Enabled: true},
+		{AccountID: otherAccountID, Publisher: "Canonical", Offer: "ubuntu", Sku: "22_04", Version: "latest", OSType: "Linux", Enabled: true},
+	} {
+		require.NoError(t, env.db.Create(image).Error)
+	}
+	for _, size := range []*model.VmSize{
+		{AccountID: testAccountID, Name: "Standard_B1s", Location: "eastus", Cores: 1, Enabled: true},
+		{AccountID: otherAccountID, Name: "Standard_B1s", Location: "eastus", Cores: 1, Enabled: true},
+	} {
+		require.NoError(t, env.db.Create(size).Error)
+	}
+}
+
+func TestTenantIsolation_ListVMs(t *testing.T) {
+	env := newVMTestEnv(t)
+	seedTenants(t, env)
+	ctx := context.Background()
+
+	result, err := env.vmService.ListVMs(ctx, &v1.VMQueryParams{UserID: testUserID})
+	require.NoError(t, err)
+	require.Len(t, result.Items, 1)
+	assert.Equal(t, "vm-1", result.Items[0].VMID)
+	assert.Equal(t, int64(1), result.Total)
+
+	// 指定他人账户ID也不能查到对方的虚拟机
+	result, err = env.vmService.ListVMs(ctx, &v1.VMQueryParams{UserID: testUserID, AccountID: otherAccountID})
+	require.NoError(t, err)
+	assert.Empty(t, result.Items)
+
+	result, err = env.vmService.ListVMs(ctx, &v1.VMQueryParams{UserID: testUserID, SubscriptionID: otherSubID})
+	require.NoError(t, err)
+	assert.Empty(t, result.Items)
+
+	_, err = env.vmService.ListVMs(ctx, &v1.VMQueryParams{AccountID: otherAccountID})
+	assert.ErrorIs(t, err, v1.ErrUnauthorized)
+
+	_, err = env.vmRepo.ListVMs(ctx, repository.QueryVMsOptions{Query: &app.QueryOption{}})
+	assert.ErrorIs(t, err, repository.ErrUserScopeRequired)
+
+	byAccount, err := env.vmRepo.ListByAccountID(ctx, testUserID, otherAccountID, &app.QueryOption{})
+	require.NoError(t, err)
+	assert.Empty(t, byAccount.Items)
+
+	bySub, err := env.vmRepo.ListByAccountAndSubscription(ctx, testUserID, otherAccountID, otherSubID, &app.QueryOption{})
+	require.NoError(t, err)
+	assert.Empty(t, bySub.Items)
+
+	_, err = env.vmService.ListVMsBySubscription(ctx, testUserID, otherAccountID, otherSubID)
+	assert.Error(t, err)
+}
+
+func TestTenantIsolation_Subscriptions(t *testing.T) {
+	env := newVMTestEnv(t)
+	seedTenants(t, env)
+	ctx := context.Background()
+
+	subs, err := env.subsRepo.GetSubscriptionsByAccountId(ctx, testUserID, otherAccountID)
+	require.NoError(t, err)
+	assert.Empty(t, subs)
+
+	sub, err := env.subsRepo.GetSubscription(ctx, testUserID, otherAccountID, otherSubID)
+	require.NoError(t, err)
+	assert.Nil(t, sub)
+
+	all, err := env.subsRepo.ListAllUserSubscriptions(ctx, testUserID, &app.QueryOption{})
+	require.NoError(t, err)
+	require.Len(t, all.Items, 1)
+	assert.Equal(t, testSubID, all.Items[0].SubscriptionID)
+
+	subsService := newSubscriptionsService(env)
+	_, err = subsService.GetSubscriptions(ctx, testUserID, otherAccountID)
+	assert.Error(t, err)
+	_, err = subsService.GetSubscription(ctx, testUserID, otherAccountID, otherSubID)
+	assert.Error(t, err)
+}
+
+func TestTenantIsolation_ImagesAndSizes(t *testing.T) {
+	env := newVMTestEnv(t)
+	seedTenants(t, env)
+	ctx := context.Background()
+
+	repo := repository.NewRepository(logger, env.db)
+	imageService := service.NewVmImageService(env.srv, repository.NewVmImageRepository(repo), env.accountsRepo, env.subsRepo, env.provider)
+	sizeService := service.NewVmSizeService(env.srv, repository.NewVmSizeRepository(repo), env.accountsRepo, env.subsRepo, env.provider)
+
+	images, err := imageService.ListVmImages(ctx, testUserID, "")
+	require.NoError(t, err)
+	require.Len(t, images, 1)
+	assert.Equal(t, testAccountID, images[0].AccountID)
+
+	images, err = imageService.ListVmImages(ctx, testUserID, otherAccountID)
+	require.NoError(t, err)
+	assert.Empty(t, images)
+
+	var otherImage model.VmImage
+	require.NoError(t, env.db.Where("account_id = ?", otherAccountID).First(&otherImage).Error)
+	image, err := imageService.GetVmImage(ctx, testUserID, otherImage.ID)
+	require.NoError(t, err)
+	assert.Nil(t, image)
+
+	image, err = imageService.GetVmImageBySpec(ctx, testUserID, otherAccountID, "Canonical", "ubuntu", "22_04")
+	require.NoError(t, err)
+	assert.Nil(t, image)
+
+	sizes, err := sizeService.ListVmSizes(ctx, testUserID, "", "", "eastus")
+	require.NoError(t, err)
+	require.Len(t, sizes, 1)
+	assert.Equal(t, testAccountID, sizes[0].AccountID)
+
+	sizes, err = sizeService.ListVmSizes(ctx, testUserID, otherAccountID, "", "eastus")
+	require.NoError(t, err)
+	assert.Empty(t, sizes)
+}
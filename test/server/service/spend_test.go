@@ -0,0 +1,167 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	v1 "azure-vm-backend/api/v1"
+	"azure-vm-backend/internal/model"
+	"azure-vm-backend/internal/repository"
+	"azure-vm-backend/internal/service"
+	"azure-vm-backend/pkg/azure"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSpendService_SyncAndQuery(t *testing.T) {
+	env := newVMTestEnv(t)
+	ctx := context.Background()
+	repo := repository.NewRepository(logger, env.db)
+	spendService := service.NewSpendService(env.srv, repository.NewCostRecordRepository(repo), env.accountsRepo, env.subsRepo, env.vmRepo, env.provider)
+
+	vm := env.addVM("vm1", "running")
+	_, err := env.vmService.SyncVMs(ctx, testUserID, testAccountID)
+	require.NoError(t, err)
+	stored, err := env.vmRepo.GetByID(ctx, vm.ID)
+	require.NoError(t, err)
+
+	now := time.Now().UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	vmResource := strings.ToLower(vm.ID)
+	diskResource := "/subscriptions/" + testSubID + "/resourcegroups/rg/providers/microsoft.compute/disks/vm1_osdisk"
+	records := []*azure.CostRecord{
+		{UsageDate: today, ResourceID: vmResource, Meter: "B1s", Cost: 1, Currency: "USD"},
+		{UsageDate: today, ResourceID: diskResource, Meter: "E4 LRS Disk", Cost: 0.5, Currency: "USD"},
+		{UsageDate: today, ResourceID: vmResource + "/extensions/vmaccess", Meter: "Extension", Cost: 0.25, Currency: "USD"},
+		{UsageDate: today.AddDate(0, 0, -10), ResourceID: vmResource, Meter: "B1s", Cost: 2, Currency: "USD"},
+		// 超出同步范围的记录不会被查询到
+		{UsageDate: today.AddDate(0, 0, -40), ResourceID: vmResource, Meter: "B1s", Cost: 100, Currency: "USD"},
+	}
+	env.provider.SetCosts(testSubID, records)
+
+	result, err := spendService.SyncAccountCosts(ctx, testUserID, testAccountID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Subscriptions)
+	assert.Equal(t, 4, result.Records)
+	assert.Empty(t, result.FailedSubscriptions)
+
+	// 本月至今按日期计算，10 天前可能在上个月
+	vmMonthToDate, subMonthToDate := 1.0, 1.75
+	if !today.AddDate(0, 0, -10).Before(monthStart) {
+		vmMonthToDate, subMonthToDate = 3.0, 3.75
+	}
+
+	account, err := spendService.GetAccountSpend(ctx, testUserID, testAccountID)
+	require.NoError(t, err)
+	require.NotNil(t, account.SyncedAt)
+	assert.Equal(t, "USD", account.Spend.Currency)
+	assert.Equal(t, 3.75, account.Spend.Last30Days)
+	assert.Equal(t, subMonthToDate, account.Spend.MonthToDate)
+	require.Len(t, account.Subscriptions, 1)
+	assert.Equal(t, testSubID, account.Subscriptions[0].SubscriptionID)
+
+	subscription, err := spendService.GetSubscriptionSpend(ctx, testUserID, testAccountID, testSubID)
+	require.NoError(t, err)
+	assert.Equal(t, 3.75, subscription.Spend.Last30Days)
+	require.Len(t, subscription.VMs, 1)
+	assert.Equal(t, "vm1", subscription.VMs[0].Name)
+	assert.Equal(t, 3.0, subscription.VMs[0].Spend.Last30Days)
+
+	vmSpend, err := spendService.GetVMSpend(ctx, testUserID, testAccountID, strconv.Itoa(int(stored.ID)))
+	require.NoError(t, err)
+	assert.Equal(t, vm.ID, vmSpend.VMID)
+	assert.Equal(t, 3.0, vmSpend.Spend.Last30Days)
+	assert.Equal(t, vmMonthToDate, vmSpend.Spend.MonthToDate)
+
+	// 重新同步时替换日期范围内的旧记录
+	env.provider.SetCosts(testSubID, []*azure.CostRecord{
+		{UsageDate: today, ResourceID: vmResource, Meter: "B1s", Cost: 5, Currency: "USD"},
+	})
+	_, err = spendService.SyncAccountCosts(ctx, testUserID, testAccountID)
+	require.NoError(t, err)
+	vmSpend, err = spendService.GetVMSpend(ctx, testUserID, testAccountID, strconv.Itoa(int(stored.ID)))
+	require.NoError(t, err)
+	assert.Equal(t, 5.0, vmSpend.Spend.Last30Days)
+
+	// 全部订阅查询失败时返回错误并保留已有记录
+	env.provider.Errors["QueryDailyCosts"] = errors.New("RBACAccessDenied")
+	_, err = spendService.SyncAccountCosts(ctx, testUserID, testAccountID)
+	assert.Error(t, err)
+	account, err = spendService.GetAccountSpend(ctx, testUserID, testAccountID)
+	require.NoError(t, err)
+	assert.Equal(t, 5.0, account.Spend.Last30Days)
+}
+
+func TestSpendService_Isolation(t *testing.T) {
+	env := newVMTestEnv(t)
+	ctx := context.Background()
+	repo := repository.NewRepository(logger, env.db)
+	spendService := service.NewSpendService(env.srv, repository.NewCostRecordRepository(repo), env.accountsRepo, env.subsRepo, env.vmRepo, env.provider)
+
+	_, err := spendService.GetAccountSpend(ctx, "other-user", testAccountID)
+	assert.ErrorIs(t, err, v1.ErrAccountError)
+	_, err = spendService.SyncAccountCosts(ctx, "other-user", testAccountID)
+	assert.ErrorIs(t, err, v1.ErrAccountError)
+	_, err = spendService.GetSubscriptionSpend(ctx, testUserID, testAccountID, "sub-unknown")
+	assert.ErrorIs(t, err, v1.ErrSubscriptionNotFound)
+
+	// 没有同步过费用时返回 0
+	account, err := spendService.GetAccountSpend(ctx, testUserID, testAccountID)
+	require.NoError(t, err)
+	assert.Nil(t, account.SyncedAt)
+	assert.Zero(t, account.Spend.Last30Days)
+	require.Len(t, account.Subscriptions, 1)
+}
+
+func TestSpendService_SharedSubscription(t *testing.T) {
+	env := newVMTestEnv(t)
+	ctx := context.Background()
+	repo := repository.NewRepository(logger, env.db)
+	spendService := service.NewSpendService(env.srv, repository.NewCostRecordRepository(repo), env.accountsRepo, env.subsRepo, env.vmRepo, env.provider)
+
+	// 同一个订阅授权给同一用户的两个账户
+	var account model.Accounts
+	require.NoError(t, env.db.Where("account_id = ?", testAccountID).First(&account).Error)
+	require.NoError(t, env.db.Create(&model.Accounts{
+		AccountID:   "account-shared",
+		UserID:      testUserID,
+		LoginEmail:  "shared@example.com",
+		AppID:       "app",
+		PassWord:    account.PassWord,
+		Tenant:      "tenant",
+		DisplayName: "shared",
+	}).Error)
+	require.NoError(t, env.db.Create(&model.Subscriptions{
+		AccountID:      "account-shared",
+		SubscriptionID: testSubID,
+		DisplayName:    "test",
+		State:          "Enabled",
+	}).Error)
+
+	now := time.Now().UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	env.provider.SetCosts(testSubID, []*azure.CostRecord{
+		{UsageDate: today, ResourceID: "/subscriptions/" + testSubID + "/resourcegroups/rg", Meter: "Disk", Cost: 2, Currency: "USD"},
+	})
+	_, err := spendService.SyncAccountCosts(ctx, testUserID, testAccountID)
+	require.NoError(t, err)
+
+	// 另一个账户同步时不能删除本账户的记录
+	env.provider.SetCosts(testSubID, []*azure.CostRecord{
+		{UsageDate: today, ResourceID: "/subscriptions/" + testSubID + "/resourcegroups/rg", Meter: "Disk", Cost: 3, Currency: "USD"},
+	})
+	_, err = spendService.SyncAccountCosts(ctx, testUserID, "account-shared")
+	require.NoError(t, err)
+
+	spend, err := spendService.GetAccountSpend(ctx, testUserID, testAccountID)
+	require.NoError(t, err)
+	assert.Equal(t, 2.0, spend.Spend.Last30Days)
+	spend, err = spendService.GetAccountSpend(ctx, testUserID, "account-shared")
+	require.NoError(t, err)
+	assert.Equal(t, 3.0, spend.Spend.Last30Days)
+}
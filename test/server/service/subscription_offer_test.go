@@ -0,0 +1,72 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"azure-vm-backend/pkg/app"
+	"azure-vm-backend/pkg/azure"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubscriptionsService_OfferClassification(t *testing.T) {
+	env := newVMTestEnv(t)
+	ctx := context.Background()
+	subsService := newSubscriptionsService(env)
+
+	endDate := time.Date(2026, 11, 16, 0, 0, 0, 0, time.UTC)
+	env.provider.AddSubscription(azure.SubscriptionDetail{
+		SubscriptionID:       testSubID,
+		DisplayName:          "trial",
+		SpendingLimit:        "On",
+		EndDate:              &endDate,
+		SubscriptionPolicies: map[string]interface{}{"quotaId": "FreeTrial_2014-09-01", "spendingLimit": "On"},
+	})
+	env.provider.AddSubscription(azure.SubscriptionDetail{
+		SubscriptionID:       "sub-2",
+		DisplayName:          "payg",
+		SpendingLimit:        "Off",
+		SubscriptionPolicies: map[string]interface{}{"quotaId": "PayAsYouGo_2014-09-01", "spendingLimit": "Off"},
+	})
+	_, err := subsService.SyncSubscriptions(ctx, testUserID, testAccountID)
+	require.NoError(t, err)
+
+	sub, err := env.subsRepo.GetSubscription(ctx, testUserID, testAccountID, testSubID)
+	require.NoError(t, err)
+	assert.Equal(t, "FreeTrial", sub.OfferType)
+	assert.Equal(t, 200.0, sub.CreditAmount)
+	assert.Equal(t, "USD", sub.CreditCurrency)
+	require.NotNil(t, sub.CreditExpiresAt)
+	assert.True(t, endDate.Equal(*sub.CreditExpiresAt))
+
+	result, err := subsService.ListAllSubscriptions(ctx, testUserID, &app.QueryOption{
+		Filters: map[string]string{"offerType": "PayAsYouGo"},
+	})
+	require.NoError(t, err)
+	require.Len(t, result.Items, 1)
+	assert.Equal(t, "sub-2", result.Items[0].SubscriptionID)
+	assert.Nil(t, result.Items[0].CreditExpiresAt)
+
+	// 升级后移除消费限制，重新同步时更新为即用即付
+	env.provider.AddSubscription(azure.SubscriptionDetail{
+		SubscriptionID:       testSubID,
+		DisplayName:          "trial",
+		SpendingLimit:        "Off",
+		EndDate:              &endDate,
+		SubscriptionPolicies: map[string]interface{}{"quotaId": "FreeTrial_2014-09-01", "spendingLimit": "Off"},
+	})
+	_, err = subsService.SyncSubscriptions(ctx, testUserID, testAccountID)
+	require.NoError(t, err)
+
+	result, err = subsService.ListAllSubscriptions(ctx, testUserID, &app.QueryOption{
+		Filters: map[string]string{"offerType": "PayAsYouGo"},
+	})
+	require.NoError(t, err)
+	assert.Len(t, result.Items, 2)
+	sub, err = env.subsRepo.GetSubscription(ctx, testUserID, testAccountID, testSubID)
+	require.NoError(t, err)
+	assert.Zero(t, sub.CreditAmount)
+	assert.Nil(t, sub.CreditExpiresAt)
+}
@@ -0,0 +1,106 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+
+	v1 "azure-vm-backend/api/v1"
+	"azure-vm-backend/internal/model"
+	"azure-vm-backend/internal/repository"
+	"azure-vm-backend/pkg/azure"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubscriptionQuotaService_ListQuotas(t *testing.T) {
+	env := newVMTestEnv(t)
+	ctx := context.Background()
+	env.provider.SetQuotas(testSubID, "eastus", []*azure.QuotaUsage{
+		{Name: "cores", LocalizedName: "Total Regional vCPUs", CurrentValue: 2, Limit: 4},
+		{Name: "lowPriorityCores", LocalizedName: "Total Regional Low-priority vCPUs", CurrentValue: 0, Limit: 3},
+		{Name: "standardBSFamily", LocalizedName: "Standard BS Family vCPUs", CurrentValue: 2, Limit: 4},
+		{Name: "standardNCFamily", LocalizedName: "Standard NC Family vCPUs", CurrentValue: 0, Limit: 0},
+		{Name: "availabilitySets", LocalizedName: "Availability Sets", CurrentValue: 1, Limit: 2500},
+	})
+
+	_, err := env.quotaService.ListQuotas(ctx, testUserID, testAccountID, testSubID, &v1.ListQuotasRequest{Refresh: true})
+	assert.ErrorIs(t, err, v1.ErrInvalidParams)
+	_, err = env.quotaService.ListQuotas(ctx, testUserID, testAccountID, "sub-missing", &v1.ListQuotasRequest{Location: "eastus"})
+	assert.ErrorIs(t, err, v1.ErrSubscriptionNotFound)
+
+	// 没有记录时从Azure获取
+	quotas, err := env.quotaService.ListQuotas(ctx, testUserID, testAccountID, testSubID, &v1.ListQuotasRequest{Location: "eastus"})
+	require.NoError(t, err)
+	require.Len(t, quotas, 1)
+	assert.Equal(t, "eastus", quotas[0].Location)
+	require.NotNil(t, quotas[0].Total)
+	assert.Equal(t, int64(2), quotas[0].Total.Available)
+	require.NotNil(t, quotas[0].Spot)
+	require.Len(t, quotas[0].Families, 1)
+	assert.Equal(t, "standardBSFamily", quotas[0].Families[0].Name)
+
+	// 未指定区域时只读取数据库
+	env.provider.Errors["ListUsages"] = errors.New("AuthorizationFailed")
+	quotas, err = env.quotaService.ListQuotas(ctx, testUserID, testAccountID, testSubID, &v1.ListQuotasRequest{})
+	require.NoError(t, err)
+	require.Len(t, quotas, 1)
+	_, err = env.quotaService.ListQuotas(ctx, testUserID, testAccountID, testSubID, &v1.ListQuotasRequest{Location: "eastus", Refresh: true})
+	assert.Error(t, err)
+
+	// 其他用户不可见
+	_, err = env.quotaService.ListQuotas(ctx, "user-2", testAccountID, testSubID, &v1.ListQuotasRequest{})
+	assert.ErrorIs(t, err, v1.ErrAccountError)
+}
+
+func TestSubscriptionQuotaService_CheckBeforeResize(t *testing.T) {
+	env := newVMTestEnv(t)
+	ctx := context.Background()
+	vm := env.addVM("vm1", "running")
+	sizeRepo := repository.NewVmSizeRepository(repository.NewRepository(logger, env.db))
+	require.NoError(t, sizeRepo.BatchUpsertVmSizes(ctx, testAccountID, testSubID, []*model.VmSize{
+		{Name: "Standard_B1s", Location: "eastus", Cores: 1, MemoryGB: 1, MaxDataDisks: 2},
+		{Name: "Standard_B2s", Location: "eastus", Cores: 2, MemoryGB: 4, MaxDataDisks: 4},
+		{Name: "Standard_D4s_v3", Location: "eastus", Cores: 4, MemoryGB: 16, MaxDataDisks: 8},
+	}))
+	env.provider.SetQuotas(testSubID, "eastus", []*azure.QuotaUsage{
+		{Name: "cores", CurrentValue: 1, Limit: 4},
+		{Name: "standardBSFamily", CurrentValue: 1, Limit: 4},
+		{Name: "standardDSv3Family", CurrentValue: 0, Limit: 2},
+	})
+
+	_, err := env.vmService.SyncVMs(ctx, testUserID, testAccountID)
+	require.NoError(t, err)
+	dbVM, err := env.vmRepo.GetByID(ctx, vm.ID)
+	require.NoError(t, err)
+	id := strconv.Itoa(int(dbVM.ID))
+
+	// 系列配额不足
+	_, err = env.vmService.OperateVM(ctx, testUserID, testAccountID, id, &v1.VMOperationRequest{Operation: v1.VMOperationResize, Size: "Standard_D4s_v3"})
+	assert.ErrorIs(t, err, v1.ErrQuotaExceeded)
+
+	// 区域总配额不足
+	env.provider.SetQuotas(testSubID, "eastus", []*azure.QuotaUsage{
+		{Name: "cores", CurrentValue: 3, Limit: 4},
+		{Name: "standardBSFamily", CurrentValue: 1, Limit: 10},
+	})
+	account, err := env.accountsRepo.GetAccountByUserIdAndAccountId(ctx, testUserID, testAccountID)
+	require.NoError(t, err)
+	require.NoError(t, env.quotaService.CheckVCPUQuota(ctx, account, testSubID, "eastus",
+		&model.VmSize{Name: "Standard_B2s", Cores: 2}, &model.VmSize{Name: "Standard_B1s", Cores: 1}))
+	_, err = env.vmService.OperateVM(ctx, testUserID, testAccountID, id, &v1.VMOperationRequest{Operation: v1.VMOperationResize, Size: "Standard_D4s_v3"})
+	assert.ErrorIs(t, err, v1.ErrQuotaExceeded)
+
+	// 已释放的虚拟机不占用配额，调整规格需要目标规格的全部 vCPU
+	require.NoError(t, env.db.Model(&model.VirtualMachine{}).Where("id = ?", dbVM.ID).
+		Updates(map[string]interface{}{"power_state": "deallocated", "core": 1}).Error)
+	_, err = env.vmService.OperateVM(ctx, testUserID, testAccountID, id, &v1.VMOperationRequest{Operation: v1.VMOperationResize, Size: "Standard_B2s"})
+	assert.ErrorIs(t, err, v1.ErrQuotaExceeded)
+	require.NoError(t, env.db.Model(&model.VirtualMachine{}).Where("id = ?", dbVM.ID).Update("power_state", "running").Error)
+
+	// 获取配额失败时不阻止操作
+	env.provider.Errors["ListUsages"] = errors.New("AuthorizationFailed")
+	_, err = env.vmService.OperateVM(ctx, testUserID, testAccountID, id, &v1.VMOperationRequest{Operation: v1.VMOperationResize, Size: "Standard_B2s"})
+	assert.NoError(t, err)
+}
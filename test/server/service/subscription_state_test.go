@@ -0,0 +1,122 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	v1 "azure-vm-backend/api/v1"
+	"azure-vm-backend/internal/model"
+	"azure-vm-backend/internal/service"
+	"azure-vm-backend/pkg/app"
+	"azure-vm-backend/pkg/azure"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubscriptionsService_CheckSubscriptionStates(t *testing.T) {
+	env := newVMTestEnv(t)
+	ctx := context.Background()
+	subsService := newSubscriptionsService(env)
+
+	// 状态未变化时不记录
+	require.NoError(t, subsService.CheckSubscriptionStates(ctx))
+	result, err := subsService.ListStateChanges(ctx, testUserID, testAccountID, "", &app.QueryOption{})
+	require.NoError(t, err)
+	assert.Empty(t, result.Items)
+
+	// 订阅进入 PastDue，同时新增一个订阅
+	env.provider.AddSubscription(azure.SubscriptionDetail{SubscriptionID: testSubID, DisplayName: "test", State: "PastDue"})
+	env.provider.AddSubscription(azure.SubscriptionDetail{SubscriptionID: "sub-2", DisplayName: "second", State: "Warned"})
+	require.NoError(t, subsService.CheckSubscriptionStates(ctx))
+
+	account, err := env.accountsRepo.GetAccountByUserIdAndAccountId(ctx, testUserID, testAccountID)
+	require.NoError(t, err)
+	assert.Equal(t, model.AccountSubscriptionPastDue, account.SubscriptionStatus)
+
+	sub, err := env.subsRepo.GetSubscription(ctx, testUserID, testAccountID, testSubID)
+	require.NoError(t, err)
+	assert.Equal(t, "PastDue", sub.State)
+
+	changes := stateChanges(t, subsService, "")
+	assert.ElementsMatch(t, []string{
+		"|normal->past_due",
+		testSubID + "|Enabled->PastDue",
+		"sub-2|->Warned",
+	}, changes)
+
+	// 订阅消失后记录为 Missing，账户状态取剩余订阅中最严重的
+	env.provider.RemoveSubscription(testSubID)
+	require.NoError(t, subsService.CheckSubscriptionStates(ctx))
+	account, err = env.accountsRepo.GetAccountByUserIdAndAccountId(ctx, testUserID, testAccountID)
+	require.NoError(t, err)
+	assert.Equal(t, model.AccountSubscriptionWarned, account.SubscriptionStatus)
+	assert.Equal(t, []string{
+		testSubID + "|PastDue->" + model.SubscriptionStateMissing,
+		testSubID + "|Enabled->PastDue",
+	}, stateChanges(t, subsService, testSubID))
+
+	// 获取订阅失败时账户状态为 error
+	env.provider.Errors["FetchSubscriptionDetails"] = errors.New("AuthorizationFailed")
+	require.NoError(t, subsService.CheckSubscriptionStates(ctx))
+	account, err = env.accountsRepo.GetAccountByUserIdAndAccountId(ctx, testUserID, testAccountID)
+	require.NoError(t, err)
+	assert.Equal(t, model.AccountSubscriptionError, account.SubscriptionStatus)
+
+	// 恢复后手动同步同样更新状态
+	delete(env.provider.Errors, "FetchSubscriptionDetails")
+	env.provider.AddSubscription(azure.SubscriptionDetail{SubscriptionID: "sub-2", DisplayName: "second", State: "Enabled"})
+	count, err := subsService.SyncSubscriptions(ctx, testUserID, testAccountID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+	account, err = env.accountsRepo.GetAccountByUserIdAndAccountId(ctx, testUserID, testAccountID)
+	require.NoError(t, err)
+	assert.Equal(t, model.AccountSubscriptionNormal, account.SubscriptionStatus)
+	assert.Equal(t, "|error->normal", stateChanges(t, subsService, "")[0])
+
+	// 所有订阅都消失时同样记录为 Missing，之后的检查不再重复记录
+	env.provider.RemoveSubscription("sub-2")
+	require.NoError(t, subsService.CheckSubscriptionStates(ctx))
+	require.NoError(t, subsService.CheckSubscriptionStates(ctx))
+	assert.Equal(t, []string{
+		"sub-2|Enabled->" + model.SubscriptionStateMissing,
+		"sub-2|Warned->Enabled",
+		"sub-2|->Warned",
+	}, stateChanges(t, subsService, "sub-2"))
+	sub, err = env.subsRepo.GetSubscription(ctx, testUserID, testAccountID, "sub-2")
+	require.NoError(t, err)
+	assert.Nil(t, sub)
+	var missing model.Subscriptions
+	require.NoError(t, env.db.Unscoped().Where("account_id = ? AND subscription_id = ?", testAccountID, "sub-2").First(&missing).Error)
+	assert.Equal(t, model.SubscriptionStateMissing, missing.State)
+}
+
+func TestSubscriptionsService_ListStateChanges_Isolation(t *testing.T) {
+	env := newVMTestEnv(t)
+	ctx := context.Background()
+	subsService := newSubscriptionsService(env)
+
+	env.provider.AddSubscription(azure.SubscriptionDetail{SubscriptionID: testSubID, DisplayName: "test", State: "Disabled"})
+	require.NoError(t, subsService.CheckSubscriptionStates(ctx))
+
+	_, err := subsService.ListStateChanges(ctx, "other-user", testAccountID, "", &app.QueryOption{})
+	assert.ErrorIs(t, err, v1.ErrAccountError)
+
+	result, err := subsService.ListStateChanges(ctx, testUserID, testAccountID, "", &app.QueryOption{
+		Pagination: app.Pagination{Page: 1, PageSize: 1},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), result.Total)
+	require.Len(t, result.Items, 1)
+}
+
+// stateChanges 以 "订阅|原状态->新状态" 的形式返回状态变化，按时间倒序
+func stateChanges(t *testing.T, subsService service.SubscriptionsService, subscriptionID string) []string {
+	result, err := subsService.ListStateChanges(context.Background(), testUserID, testAccountID, subscriptionID, &app.QueryOption{})
+	require.NoError(t, err)
+	changes := make([]string, 0, len(result.Items))
+	for _, change := range result.Items {
+		changes = append(changes, change.SubscriptionID+"|"+change.FromState+"->"+change.ToState)
+	}
+	return changes
+}
@@ -0,0 +1,143 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	v1 "azure-vm-backend/api/v1"
+	"azure-vm-backend/internal/model"
+	"azure-vm-backend/internal/repository"
+	"azure-vm-backend/internal/service"
+	"azure-vm-backend/pkg/app"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newSyncScheduleService(t *testing.T, env *vmTestEnv) service.SyncScheduleService {
+	require.NoError(t, env.db.AutoMigrate(&model.SyncSchedule{}, &model.SyncScheduleRun{}))
+	repo := repository.NewSyncScheduleRepository(repository.NewRepository(logger, env.db))
+	return service.NewSyncScheduleService(env.srv, repo, env.accountsRepo, newAccountsService(env))
+}
+
+func TestSyncScheduleService_CreateSchedule(t *testing.T) {
+	env := newVMTestEnv(t)
+	seedTenants(t, env)
+	ctx := context.Background()
+	scheduleService := newSyncScheduleService(t, env)
+
+	schedule, err := scheduleService.CreateSchedule(ctx, testUserID, &v1.CreateSyncScheduleReq{
+		Name:       "nightly",
+		CronExpr:   " 0 2 * * * ",
+		AccountIDs: []string{testAccountID, testAccountID},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "0 2 * * *", schedule.CronExpr)
+	assert.True(t, schedule.Enabled)
+	assert.Equal(t, []string{testAccountID}, v1.ToSyncScheduleInfo(schedule).AccountIDs)
+
+	// 无效表达式或执行过于频繁
+	for _, expr := range []string{"not a cron", "* * * * *", "0,1 * * * *"} {
+		_, err = scheduleService.CreateSchedule(ctx, testUserID, &v1.CreateSyncScheduleReq{
+			CronExpr:   expr,
+			AccountIDs: []string{testAccountID},
+		})
+		assert.ErrorIs(t, err, v1.ErrInvalidCronExpr, expr)
+	}
+
+	// 不能同步其他用户的账户
+	_, err = scheduleService.CreateSchedule(ctx, testUserID, &v1.CreateSyncScheduleReq{
+		CronExpr:   "0 2 * * *",
+		AccountIDs: []string{otherAccountID},
+	})
+	assert.ErrorIs(t, err, v1.ErrorAzureNotFound)
+
+	// 其他用户看不到也改不了该设置
+	_, err = scheduleService.GetSchedule(ctx, otherUserID, schedule.ScheduleID)
+	assert.ErrorIs(t, err, v1.ErrNotFound)
+	disabled := false
+	_, err = scheduleService.UpdateSchedule(ctx, otherUserID, schedule.ScheduleID, &v1.UpdateSyncScheduleReq{Enabled: &disabled})
+	assert.ErrorIs(t, err, v1.ErrNotFound)
+
+	updated, err := scheduleService.UpdateSchedule(ctx, testUserID, schedule.ScheduleID, &v1.UpdateSyncScheduleReq{Enabled: &disabled})
+	require.NoError(t, err)
+	assert.False(t, updated.Enabled)
+
+	enabled, err := scheduleService.ListEnabledSchedules(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, enabled)
+
+	require.NoError(t, scheduleService.DeleteSchedule(ctx, testUserID, schedule.ScheduleID))
+	schedules, err := scheduleService.ListSchedules(ctx, testUserID)
+	require.NoError(t, err)
+	assert.Empty(t, schedules)
+}
+
+func TestSyncScheduleService_RunSchedule(t *testing.T) {
+	env := newVMTestEnv(t)
+	ctx := context.Background()
+	env.addVM("vm1", "running")
+	scheduleService := newSyncScheduleService(t, env)
+
+	schedule, err := scheduleService.CreateSchedule(ctx, testUserID, &v1.CreateSyncScheduleReq{
+		CronExpr:   "*/30 * * * *",
+		AccountIDs: []string{testAccountID},
+	})
+	require.NoError(t, err)
+
+	run, err := scheduleService.RunSchedule(ctx, schedule)
+	require.NoError(t, err)
+	assert.Equal(t, model.SyncRunStatusSucceeded, run.Status)
+	assert.Equal(t, 1, run.SuccessCount)
+	assert.NotNil(t, run.FinishedAt)
+
+	var count int64
+	require.NoError(t, env.db.Model(&model.VirtualMachine{}).Where("account_id = ?", testAccountID).Count(&count).Error)
+	assert.Equal(t, int64(1), count)
+
+	// Azure 调用失败时记录失败结果
+	env.provider.Errors["FetchVMDetails"] = errors.New("azure unavailable")
+	run, err = scheduleService.RunSchedule(ctx, schedule)
+	require.NoError(t, err)
+	assert.Equal(t, model.SyncRunStatusFailed, run.Status)
+	assert.Equal(t, 1, run.FailedCount)
+
+	runs, err := scheduleService.ListRuns(ctx, testUserID, schedule.ScheduleID, &app.QueryOption{
+		Pagination: app.Pagination{Page: 1, PageSize: 10},
+	})
+	require.NoError(t, err)
+	require.Len(t, runs.Items, 2)
+	assert.Equal(t, int64(2), runs.Total)
+	info := v1.ToSyncScheduleRunInfo(runs.Items[0])
+	require.NotNil(t, info.Result)
+
+	current, err := scheduleService.GetSchedule(ctx, testUserID, schedule.ScheduleID)
+	require.NoError(t, err)
+	assert.Equal(t, model.SyncRunStatusFailed, current.LastStatus)
+	assert.NotNil(t, current.LastRunAt)
+
+	// 其他用户无法查看执行记录
+	runs, err = scheduleService.ListRuns(ctx, otherUserID, schedule.ScheduleID, &app.QueryOption{
+		Pagination: app.Pagination{Page: 1, PageSize: 10},
+	})
+	require.NoError(t, err)
+	assert.Empty(t, runs.Items)
+}
+
+func TestSyncScheduleService_FailInterruptedRuns(t *testing.T) {
+	env := newVMTestEnv(t)
+	ctx := context.Background()
+	scheduleService := newSyncScheduleService(t, env)
+
+	require.NoError(t, env.db.Create(&model.SyncScheduleRun{
+		ScheduleID: "schedule-1",
+		UserID:     testUserID,
+		Status:     model.SyncRunStatusRunning,
+	}).Error)
+	require.NoError(t, scheduleService.FailInterruptedRuns(ctx))
+
+	var run model.SyncScheduleRun
+	require.NoError(t, env.db.First(&run).Error)
+	assert.Equal(t, model.SyncRunStatusFailed, run.Status)
+	assert.NotNil(t, run.FinishedAt)
+}
@@ -15,6 +15,7 @@ import (
 	"azure-vm-backend/internal/service"
 	"azure-vm-backend/pkg/config"
 	"azure-vm-backend/pkg/log"
+	"azure-vm-backend/pkg/secret"
 	"azure-vm-backend/pkg/sid"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
@@ -25,6 +26,7 @@ var (
 	logger *log.Logger
 	j      *jwt.JWT
 	sf     *sid.Sid
+	c      *secret.Cipher
 )
 
 func TestMain(m *testing.M) {
@@ -41,6 +43,7 @@ func TestMain(m *testing.M) {
 
 	logger = log.NewLog(conf)
 	j = jwt.NewJwt(conf)
+	c = secret.NewCipher(conf)
 	sf = sid.NewSid()
 
 	code := m.Run()
@@ -55,7 +58,7 @@ func TestUserService_Register(t *testing.T) {
 
 	mockUserRepo := mock_repository.NewMockUserRepository(ctrl)
 	mockTm := mock_repository.NewMockTransaction(ctrl)
-	srv := service.NewService(mockTm, logger, sf, j)
+	srv := service.NewService(mockTm, logger, sf, j, c)
 
 	userService := service.NewUserService(srv, mockUserRepo)
 
@@ -79,7 +82,7 @@ func TestUserService_Register_UsernameExists(t *testing.T) {
 
 	mockUserRepo := mock_repository.NewMockUserRepository(ctrl)
 	mockTm := mock_repository.NewMockTransaction(ctrl)
-	srv := service.NewService(mockTm, logger, sf, j)
+	srv := service.NewService(mockTm, logger, sf, j, c)
 	userService := service.NewUserService(srv, mockUserRepo)
 
 	ctx := context.Background()
@@ -101,7 +104,7 @@ func TestUserService_Login(t *testing.T) {
 
 	mockUserRepo := mock_repository.NewMockUserRepository(ctrl)
 	mockTm := mock_repository.NewMockTransaction(ctrl)
-	srv := service.NewService(mockTm, logger, sf, j)
+	srv := service.NewService(mockTm, logger, sf, j, c)
 	userService := service.NewUserService(srv, mockUserRepo)
 
 	ctx := context.Background()
@@ -130,7 +133,7 @@ func TestUserService_Login_UserNotFound(t *testing.T) {
 
 	mockUserRepo := mock_repository.NewMockUserRepository(ctrl)
 	mockTm := mock_repository.NewMockTransaction(ctrl)
-	srv := service.NewService(mockTm, logger, sf, j)
+	srv := service.NewService(mockTm, logger, sf, j, c)
 	userService := service.NewUserService(srv, mockUserRepo)
 
 	ctx := context.Background()
@@ -152,7 +155,7 @@ func TestUserService_GetProfile(t *testing.T) {
 
 	mockUserRepo := mock_repository.NewMockUserRepository(ctrl)
 	mockTm := mock_repository.NewMockTransaction(ctrl)
-	srv := service.NewService(mockTm, logger, sf, j)
+	srv := service.NewService(mockTm, logger, sf, j, c)
 	userService := service.NewUserService(srv, mockUserRepo)
 
 	ctx := context.Background()
@@ -175,7 +178,7 @@ func TestUserService_UpdateProfile(t *testing.T) {
 
 	mockUserRepo := mock_repository.NewMockUserRepository(ctrl)
 	mockTm := mock_repository.NewMockTransaction(ctrl)
-	srv := service.NewService(mockTm, logger, sf, j)
+	srv := service.NewService(mockTm, logger, sf, j, c)
 	userService := service.NewUserService(srv, mockUserRepo)
 
 	ctx := context.Background()
@@ -202,7 +205,7 @@ func TestUserService_UpdateProfile_UserNotFound(t *testing.T) {
 
 	mockUserRepo := mock_repository.NewMockUserRepository(ctrl)
 	mockTm := mock_repository.NewMockTransaction(ctrl)
-	srv := service.NewService(mockTm, logger, sf, j)
+	srv := service.NewService(mockTm, logger, sf, j, c)
 	userService := service.NewUserService(srv, mockUserRepo)
 
 	ctx := context.Background()
@@ -0,0 +1,872 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	v1 "azure-vm-backend/api/v1"
+	"azure-vm-backend/internal/model"
+	"azure-vm-backend/internal/repository"
+	"azure-vm-backend/internal/service"
+	"azure-vm-backend/pkg/azure"
+	"azure-vm-backend/pkg/azure/fake"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/glebarez/sqlite"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+const (
+	testUserID    = "user-1"
+	testAccountID = "account-1"
+	testSubID     = "sub-1"
+)
+
+type vmTestEnv struct {
+	db           *gorm.DB
+	provider     *fake.Provider
+	srv          *service.Service
+	vmRepo       repository.VirtualMachineRepository
+	opRepo       repository.OperationRepository
+	accountsRepo repository.AccountsRepository
+	subsRepo     repository.SubscriptionsRepository
+	opService    service.OperationService
+	quotaService service.SubscriptionQuotaService
+	costService  service.VmCostService
+	vmService    service.VirtualMachineService
+}
+
+// newVMTestEnv 使用内存SQLite和内存Azure Provider构建虚拟机服务
+func newVMTestEnv(t *testing.T) *vmTestEnv {
+	db, err := gorm.Open(sqlite.Open(fmt.Sprintf("file:%s?mode=memory&cache=shared", t.Name())), &gorm.Config{})
+	require.NoError(t, err)
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { _ = sqlDB.Close() })
+
+	require.NoError(t, db.AutoMigrate(&model.Accounts{}, &model.Subscriptions{}, &model.VirtualMachine{}, &model.Operation{}, &model.VmImage{}, &model.VmSize{}, &model.VmSnapshot{}, &model.VmScriptSnippet{}, &model.VmCommandExecution{}, &model.SubscriptionQuota{}, &model.VmSizeRestriction{}, &model.VmSizePrice{}, &model.CostRecord{}, &model.SubscriptionStateChange{}))
+	clientSecret, err := c.Encrypt("secret")
+	require.NoError(t, err)
+	require.NoError(t, db.Create(&model.Accounts{
+		AccountID:   testAccountID,
+		UserID:      testUserID,
+		LoginEmail:  "a@example.com",
+		AppID:       "app",
+		PassWord:    clientSecret,
+		Tenant:      "tenant",
+		DisplayName: "test",
+	}).Error)
+	require.NoError(t, db.Create(&model.Subscriptions{
+		AccountID:      testAccountID,
+		SubscriptionID: testSubID,
+		DisplayName:    "test",
+		State:          "Enabled",
+	}).Error)
+
+	provider := fake.NewProvider()
+	provider.AddSubscription(azure.SubscriptionDetail{SubscriptionID: testSubID, DisplayName: "test"})
+
+	repo := repository.NewRepository(logger, db)
+	srv := service.NewService(repository.NewTransaction(repo), logger, sf, j, c)
+	vmRepo := repository.NewVirtualMachineRepository(repo)
+	opRepo := repository.NewOperationRepository(repo)
+	accountsRepo := repository.NewAccountsRepository(repo)
+	subsRepo := repository.NewSubscriptionsRepository(repo)
+	sizeRepo := repository.NewVmSizeRepository(repo)
+	quotaService := service.NewSubscriptionQuotaService(srv, repository.NewSubscriptionQuotaRepository(repo), accountsRepo, subsRepo, provider)
+	priceService := service.NewVmPriceService(srv, repository.NewVmSizePriceRepository(repo), provider, viper.New())
+	costService := service.NewVmCostService(srv, accountsRepo, vmRepo, sizeRepo, priceService)
+	opService := service.NewOperationService(srv, opRepo, vmRepo, accountsRepo, sizeRepo, quotaService, costService, provider, logger)
+
+	return &vmTestEnv{
+		db:           db,
+		provider:     provider,
+		srv:          srv,
+		vmRepo:       vmRepo,
+		opRepo:       opRepo,
+		accountsRepo: accountsRepo,
+		subsRepo:     subsRepo,
+		opService:    opService,
+		quotaService: quotaService,
+		costService:  costService,
+		vmService:    service.NewVirtualMachineService(srv, vmRepo, accountsRepo, subsRepo, sizeRepo, opService, quotaService, costService, provider, logger),
+	}
+}
+
+func (e *vmTestEnv) addVM(name, powerState string) azure.VMDetails {
+	return e.provider.AddVM(azure.VMDetails{
+		SubscriptionID: testSubID,
+		ResourceGroup:  "rg",
+		Name:           name,
+		Location:       "eastus",
+		Size:           "Standard_B1s",
+		PowerState:     powerState,
+		PublicIPs:      []string{"20.0.0." + strconv.Itoa(len(name))},
+	})
+}
+
+func TestVirtualMachineService_SyncVMs(t *testing.T) {
+	env := newVMTestEnv(t)
+	ctx := context.Background()
+	env.addVM("vm1", "running")
+	env.addVM("vm22", "deallocated")
+
+	stats, err := env.vmService.SyncVMs(ctx, testUserID, testAccountID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, stats.TotalVMs)
+	assert.Equal(t, 1, stats.RunningVMs)
+	assert.Equal(t, 1, stats.StoppedVMs)
+
+	var count int64
+	require.NoError(t, env.db.Model(&model.VirtualMachine{}).Where("account_id = ?", testAccountID).Count(&count).Error)
+	assert.Equal(t, int64(2), count)
+
+	var account model.Accounts
+	require.NoError(t, env.db.Where("account_id = ?", testAccountID).First(&account).Error)
+	assert.Equal(t, 2, account.VmCount)
+}
+
+func TestVirtualMachineService_SyncVMs_VMIDCase(t *testing.T) {
+	env := newVMTestEnv(t)
+	ctx := context.Background()
+	vm := env.addVM("vm1", "running")
+
+	// 创建虚拟机时记录的资源组大小写与列表接口返回的不同
+	storedID := strings.Replace(vm.ID, "/resourceGroups/rg/", "/resourceGroups/RG/", 1)
+	require.NotEqual(t, vm.ID, storedID)
+	_, err := env.vmService.SyncVMs(ctx, testUserID, testAccountID)
+	require.NoError(t, err)
+	require.NoError(t, env.db.Model(&model.VirtualMachine{}).Where("vm_id = ?", vm.ID).
+		Updates(map[string]interface{}{"vm_id": storedID, "size": "Standard_A0"}).Error)
+
+	stats, err := env.vmService.SyncVMs(ctx, testUserID, testAccountID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, stats.TotalVMs)
+
+	var vms []model.VirtualMachine
+	require.NoError(t, env.db.Unscoped().Where("account_id = ?", testAccountID).Find(&vms).Error)
+	require.Len(t, vms, 1)
+	assert.Equal(t, storedID, vms[0].VMID)
+	assert.Equal(t, vm.Size, vms[0].Size)
+	assert.False(t, vms[0].DeletedAt.Valid)
+}
+
+func TestVirtualMachineService_SyncVMs_PartialFailure(t *testing.T) {
+	env := newVMTestEnv(t)
+	ctx := context.Background()
+	env.addVM("vm1", "running")
+	env.provider.AddSubscription(azure.SubscriptionDetail{SubscriptionID: "sub-disabled", DisplayName: "disabled", State: "Disabled"})
+	env.provider.SubscriptionErrors["sub-disabled"] = &azcore.ResponseError{ErrorCode: "ReadOnlyDisabledSubscription", StatusCode: http.StatusConflict}
+
+	stats, err := env.vmService.SyncVMs(ctx, testUserID, testAccountID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, stats.TotalVMs)
+	assert.Equal(t, 1, stats.FailedSubscriptions)
+	require.Len(t, stats.Subscriptions, 2)
+
+	results := make(map[string]v1.SubscriptionSyncResult)
+	for _, sub := range stats.Subscriptions {
+		results[sub.SubscriptionID] = sub
+	}
+	assert.True(t, results[testSubID].Success)
+	assert.Equal(t, 1, results[testSubID].VMCount)
+	assert.False(t, results["sub-disabled"].Success)
+	assert.Contains(t, results["sub-disabled"].Message, "ReadOnlyDisabledSubscription")
+
+	// 所有订阅都失败时返回错误
+	env.provider.SubscriptionErrors[testSubID] = errors.New("AuthorizationFailed")
+	_, err = env.vmService.SyncVMs(ctx, testUserID, testAccountID)
+	assert.Error(t, err)
+}
+
+func TestVirtualMachineService_SyncVMs_ReconcileDeleted(t *testing.T) {
+	env := newVMTestEnv(t)
+	ctx := context.Background()
+	vm1 := env.addVM("vm1", "running")
+	env.addVM("vm2", "running")
+
+	_, err := env.vmService.SyncVMs(ctx, testUserID, testAccountID)
+	require.NoError(t, err)
+
+	// 在 Azure 门户中删除 vm1
+	env.provider.RemoveVM(vm1.ID)
+	stats, err := env.vmService.SyncVMs(ctx, testUserID, testAccountID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, stats.MissingVMs)
+
+	_, err = env.vmRepo.GetByID(ctx, vm1.ID)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	var deleted model.VirtualMachine
+	require.NoError(t, env.db.Unscoped().Where("vm_id = ?", vm1.ID).First(&deleted).Error)
+	assert.Equal(t, "missing", deleted.SyncStatus)
+
+	var account model.Accounts
+	require.NoError(t, env.db.Where("account_id = ?", testAccountID).First(&account).Error)
+	assert.Equal(t, 1, account.VmCount)
+
+	// 订阅获取失败时不移除该订阅下的记录
+	env.provider.AddSubscription(azure.SubscriptionDetail{SubscriptionID: "sub-2", DisplayName: "other"})
+	env.provider.SubscriptionErrors[testSubID] = errors.New("AuthorizationFailed")
+	env.provider.RemoveVM(azure.BuildVMResourceID(testSubID, "rg", "vm2"))
+	stats, err = env.vmService.SyncVMs(ctx, testUserID, testAccountID)
+	require.NoError(t, err)
+	assert.Equal(t, 0, stats.MissingVMs)
+
+	// 同名虚拟机重新出现时恢复原记录
+	delete(env.provider.SubscriptionErrors, testSubID)
+	env.addVM("vm1", "running")
+	_, err = env.vmService.SyncVMs(ctx, testUserID, testAccountID)
+	require.NoError(t, err)
+	restored, err := env.vmRepo.GetByID(ctx, vm1.ID)
+	require.NoError(t, err)
+	assert.Equal(t, deleted.ID, restored.ID)
+	assert.Equal(t, "synced", restored.SyncStatus)
+}
+
+func TestVirtualMachineRepository_CreateRestoresDeleted(t *testing.T) {
+	env := newVMTestEnv(t)
+	ctx := context.Background()
+	vm := env.addVM("vm1", "running")
+	_, err := env.vmService.SyncVMs(ctx, testUserID, testAccountID)
+	require.NoError(t, err)
+	env.provider.RemoveVM(vm.ID)
+	_, err = env.vmService.SyncVMs(ctx, testUserID, testAccountID)
+	require.NoError(t, err)
+
+	var deleted model.VirtualMachine
+	require.NoError(t, env.db.Unscoped().Where("vm_id = ?", vm.ID).First(&deleted).Error)
+	require.True(t, deleted.DeletedAt.Valid)
+
+	// 以相同名称重新创建时恢复已软删除的记录，而不是违反 vm_id 唯一索引
+	recreated := &model.VirtualMachine{
+		VMID:           vm.ID,
+		AccountID:      testAccountID,
+		SubscriptionID: testSubID,
+		Name:           "vm1",
+		ResourceGroup:  "rg",
+		Location:       "eastus",
+		Size:           "Standard_B2s",
+		Status:         "Creating",
+		State:          "Creating",
+		PowerState:     "creating",
+		SyncStatus:     "pending",
+	}
+	require.NoError(t, env.vmRepo.Create(ctx, recreated))
+	assert.Equal(t, deleted.ID, recreated.ID)
+
+	stored, err := env.vmRepo.GetByID(ctx, vm.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "pending", stored.SyncStatus)
+	assert.Equal(t, "Standard_B2s", stored.Size)
+	assert.Empty(t, stored.PrivateIPs)
+
+	// 未删除的记录仍然不能重复创建
+	assert.Error(t, env.vmRepo.Create(ctx, recreated))
+}
+
+func TestVirtualMachineService_SyncVMs_NoVMs(t *testing.T) {
+	env := newVMTestEnv(t)
+
+	stats, err := env.vmService.SyncVMs(context.Background(), testUserID, testAccountID)
+	require.NoError(t, err)
+	assert.Equal(t, 0, stats.TotalVMs)
+	require.Len(t, stats.Subscriptions, 1)
+	assert.True(t, stats.Subscriptions[0].Success)
+}
+
+func TestVirtualMachineService_CreateVM(t *testing.T) {
+	env := newVMTestEnv(t)
+	ctx := context.Background()
+	params := &v1.VMCreateParams{
+		SubscriptionID: testSubID,
+		ResourceGroup:  "rg",
+		Name:           "vm-new",
+		Location:       "eastus",
+		Size:           "Standard_B1s",
+		Image:          v1.VMImageParams{Publisher: "Canonical", Offer: "ubuntu-24_04-lts", Sku: "server"},
+		AdminUsername:  "azureuser",
+		SSHPublicKey:   "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAI test",
+		NetworkConfig:  v1.VMNetworkParams{PublicIP: true},
+	}
+	waitSyncStatus := func(vmID, status string) *model.VirtualMachine {
+		var vm *model.VirtualMachine
+		assert.Eventually(t, func() bool {
+			got, err := env.vmRepo.GetByID(ctx, vmID)
+			if err != nil {
+				return false
+			}
+			vm = got
+			return got.SyncStatus == status
+		}, 5*time.Second, 20*time.Millisecond)
+		return vm
+	}
+
+	// 参数校验和权限
+	_, err := env.vmService.CreateVM(ctx, "other-user", testAccountID, params)
+	assert.ErrorIs(t, err, v1.ErrAccountError)
+	noLogin := *params
+	noLogin.SSHPublicKey = ""
+	_, err = env.vmService.CreateVM(ctx, testUserID, testAccountID, &noLogin)
+	assert.ErrorIs(t, err, v1.ErrInvalidParams)
+
+	// 先返回 pending 记录，后台创建完成后回写Azure中的信息
+	vm, err := env.vmService.CreateVM(ctx, testUserID, testAccountID, params)
+	require.NoError(t, err)
+	assert.Equal(t, "pending", vm.SyncStatus)
+	created := waitSyncStatus(vm.VMID, "synced")
+	require.NotNil(t, created)
+	assert.Equal(t, vm.ID, created.ID)
+	assert.Equal(t, "vm-new-ip", created.PublicIPName)
+	_, ok := env.provider.GetVM(vm.VMID)
+	assert.True(t, ok)
+
+	_, err = env.vmService.CreateVM(ctx, testUserID, testAccountID, params)
+	assert.ErrorIs(t, err, v1.ErrVMAlreadyExist)
+
+	// Azure 创建失败时记录标记为失败，不会停留在 pending
+	env.provider.Errors["CreateVM"] = errors.New("SkuNotAvailable")
+	failed := *params
+	failed.Name = "vm-failed"
+	vm, err = env.vmService.CreateVM(ctx, testUserID, testAccountID, &failed)
+	require.NoError(t, err)
+	dbVM := waitSyncStatus(vm.VMID, "failed")
+	require.NotNil(t, dbVM)
+	assert.Equal(t, "failed", dbVM.PowerState)
+
+	// 创建失败后可以使用相同名称重试，覆盖失败的记录
+	delete(env.provider.Errors, "CreateVM")
+	retried, err := env.vmService.CreateVM(ctx, testUserID, testAccountID, &failed)
+	require.NoError(t, err)
+	assert.Equal(t, dbVM.ID, retried.ID)
+	assert.NotNil(t, waitSyncStatus(vm.VMID, "synced"))
+}
+
+func TestVirtualMachineService_OperateVM(t *testing.T) {
+	env := newVMTestEnv(t)
+	ctx := context.Background()
+	vm := env.addVM("vm1", "running")
+
+	_, err := env.vmService.SyncVMs(ctx, testUserID, testAccountID)
+	require.NoError(t, err)
+	dbVM, err := env.vmRepo.GetByID(ctx, vm.ID)
+	require.NoError(t, err)
+
+	op, err := env.vmService.OperateVM(ctx, testUserID, testAccountID, strconv.Itoa(int(dbVM.ID)), &v1.VMOperationRequest{Operation: v1.VMOperationStop, Force: true})
+	require.NoError(t, err)
+	require.NotEmpty(t, op.OperationID)
+
+	assert.Eventually(t, func() bool {
+		got, err := env.opRepo.GetByOperationID(ctx, testUserID, op.OperationID)
+		return err == nil && got != nil && got.Status == model.OperationStatusSucceeded
+	}, 5*time.Second, 20*time.Millisecond)
+
+	dbVM, err = env.vmRepo.GetByID(ctx, vm.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Deallocated", dbVM.PowerState)
+
+	state, ok := env.provider.GetVM(vm.ID)
+	require.True(t, ok)
+	assert.Equal(t, "deallocated", state.PowerState)
+}
+
+func TestVirtualMachineService_OperateVM_Delete(t *testing.T) {
+	env := newVMTestEnv(t)
+	ctx := context.Background()
+	vm := env.addVM("vm1", "running")
+
+	_, err := env.vmService.SyncVMs(ctx, testUserID, testAccountID)
+	require.NoError(t, err)
+	dbVM, err := env.vmRepo.GetByID(ctx, vm.ID)
+	require.NoError(t, err)
+
+	op, err := env.vmService.OperateVM(ctx, testUserID, testAccountID, strconv.Itoa(int(dbVM.ID)), &v1.VMOperationRequest{Operation: v1.VMOperationDelete})
+	require.NoError(t, err)
+
+	assert.Eventually(t, func() bool {
+		got, err := env.opRepo.GetByOperationID(ctx, testUserID, op.OperationID)
+		return err == nil && got != nil && got.Status == model.OperationStatusSucceeded
+	}, 5*time.Second, 20*time.Millisecond)
+
+	_, err = env.vmRepo.GetByID(ctx, vm.ID)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
+
+func TestVirtualMachineService_OperateVM_WrongAccount(t *testing.T) {
+	env := newVMTestEnv(t)
+	ctx := context.Background()
+	env.addVM("vm1", "running")
+
+	_, err := env.vmService.SyncVMs(ctx, testUserID, testAccountID)
+	require.NoError(t, err)
+
+	_, err = env.vmService.OperateVM(ctx, "other-user", testAccountID, "1", &v1.VMOperationRequest{Operation: v1.VMOperationStart})
+	assert.ErrorIs(t, err, v1.ErrAccountError)
+}
+
+func TestVirtualMachineService_OperateVM_ChangeIP(t *testing.T) {
+	env := newVMTestEnv(t)
+	ctx := context.Background()
+	vm := env.addVM("vm1", "running")
+
+	_, err := env.vmService.SyncVMs(ctx, testUserID, testAccountID)
+	require.NoError(t, err)
+	dbVM, err := env.vmRepo.GetByID(ctx, vm.ID)
+	require.NoError(t, err)
+	id := strconv.Itoa(int(dbVM.ID))
+	require.NoError(t, env.vmService.UpdateDNSLabel(ctx, testUserID, testAccountID, id, "myvm"))
+
+	_, err = env.vmService.OperateVM(ctx, testUserID, testAccountID, id, &v1.VMOperationRequest{Operation: v1.VMOperationChangeIP, PublicIPSku: "Premium"})
+	assert.ErrorIs(t, err, v1.ErrInvalidParams)
+
+	op, err := env.vmService.OperateVM(ctx, testUserID, testAccountID, id, &v1.VMOperationRequest{Operation: v1.VMOperationChangeIP})
+	require.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		got, err := env.opRepo.GetByOperationID(ctx, testUserID, op.OperationID)
+		return err == nil && got != nil && got.Status == model.OperationStatusSucceeded
+	}, 5*time.Second, 20*time.Millisecond)
+
+	state, ok := env.provider.GetVM(vm.ID)
+	require.True(t, ok)
+	require.Len(t, state.PublicIPs, 1)
+	assert.NotEqual(t, vm.PublicIPs[0], state.PublicIPs[0])
+	assert.NotEqual(t, vm.PublicIPName, state.PublicIPName)
+	_, ok = env.provider.GetPublicIP(vm.PublicIPName)
+	assert.False(t, ok, "原公网IP应被删除")
+
+	// DNS标签迁移到新公网IP，数据库记录同步更新
+	dbVM, err = env.vmRepo.GetByID(ctx, vm.ID)
+	require.NoError(t, err)
+	assert.Equal(t, state.PublicIPs[0], dbVM.PublicIPs)
+	assert.Equal(t, state.PublicIPName, dbVM.PublicIPName)
+	assert.Equal(t, "myvm.eastus.cloudapp.azure.com", dbVM.DnsAlias)
+	assert.Equal(t, "running", dbVM.PowerState)
+
+	// Azure 调用失败时操作失败，但不影响虚拟机状态
+	env.provider.Errors["ChangePublicIP"] = errors.New("PublicIPCountLimitReached")
+	op, err = env.vmService.OperateVM(ctx, testUserID, testAccountID, id, &v1.VMOperationRequest{Operation: v1.VMOperationChangeIP})
+	require.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		got, err := env.opRepo.GetByOperationID(ctx, testUserID, op.OperationID)
+		return err == nil && got != nil && got.Status == model.OperationStatusFailed
+	}, 5*time.Second, 20*time.Millisecond)
+	dbVM, err = env.vmRepo.GetByID(ctx, vm.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "running", dbVM.PowerState)
+}
+
+func TestVirtualMachineService_OperateVM_Resize(t *testing.T) {
+	env := newVMTestEnv(t)
+	ctx := context.Background()
+	vm := env.addVM("vm1", "running")
+	sizes := []*azure.VMSizeInfo{
+		{Name: "Standard_B1s", Location: "eastus", Cores: 1, MemoryGB: 1, MaxDataDisks: 2},
+		{Name: "Standard_B2s", Location: "eastus", Cores: 2, MemoryGB: 4, MaxDataDisks: 4},
+		{Name: "Standard_D4s_v3", Location: "eastus", Cores: 4, MemoryGB: 16, MaxDataDisks: 8},
+	}
+	env.provider.SetSizes("eastus", sizes)
+	env.provider.ClusterSizes["vm1"] = []string{"Standard_B1s", "Standard_B2s"}
+	sizeRepo := repository.NewVmSizeRepository(repository.NewRepository(logger, env.db))
+	var records []*model.VmSize
+	for _, size := range sizes {
+		records = append(records, &model.VmSize{Name: size.Name, Location: size.Location, Cores: size.Cores, MemoryGB: size.MemoryGB, MaxDataDisks: size.MaxDataDisks})
+	}
+	require.NoError(t, sizeRepo.BatchUpsertVmSizes(ctx, testAccountID, testSubID, records))
+
+	_, err := env.vmService.SyncVMs(ctx, testUserID, testAccountID)
+	require.NoError(t, err)
+	dbVM, err := env.vmRepo.GetByID(ctx, vm.ID)
+	require.NoError(t, err)
+	id := strconv.Itoa(int(dbVM.ID))
+
+	// 当前硬件集群上的规格无需释放
+	options, err := env.vmService.ListResizeOptions(ctx, testUserID, testAccountID, id)
+	require.NoError(t, err)
+	require.Len(t, options, 2)
+	assert.Equal(t, "Standard_B2s", options[0].Name)
+	assert.False(t, options[0].RequiresDeallocation)
+	assert.Equal(t, "Standard_D4s_v3", options[1].Name)
+	assert.True(t, options[1].RequiresDeallocation)
+
+	_, err = env.vmService.OperateVM(ctx, testUserID, testAccountID, id, &v1.VMOperationRequest{Operation: v1.VMOperationResize})
+	assert.ErrorIs(t, err, v1.ErrInvalidParams)
+	_, err = env.vmService.OperateVM(ctx, testUserID, testAccountID, id, &v1.VMOperationRequest{Operation: v1.VMOperationResize, Size: "Standard_M128"})
+	assert.ErrorIs(t, err, v1.ErrVMSizeUnavailable)
+
+	op, err := env.vmService.OperateVM(ctx, testUserID, testAccountID, id, &v1.VMOperationRequest{Operation: v1.VMOperationResize, Size: "Standard_D4s_v3"})
+	require.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		got, err := env.opRepo.GetByOperationID(ctx, testUserID, op.OperationID)
+		return err == nil && got != nil && got.Status == model.OperationStatusSucceeded
+	}, 5*time.Second, 20*time.Millisecond)
+
+	dbVM, err = env.vmRepo.GetByID(ctx, vm.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Standard_D4s_v3", dbVM.Size)
+	assert.Equal(t, int32(4), dbVM.Core)
+	assert.Equal(t, int32(16), dbVM.Memory)
+	assert.Equal(t, "running", dbVM.PowerState)
+
+	state, ok := env.provider.GetVM(vm.ID)
+	require.True(t, ok)
+	assert.Equal(t, "Standard_D4s_v3", state.Size)
+
+	// Azure 调整失败时回写实际电源状态
+	env.provider.Errors["ResizeVM"] = errors.New("AllocationFailed")
+	op, err = env.vmService.OperateVM(ctx, testUserID, testAccountID, id, &v1.VMOperationRequest{Operation: v1.VMOperationResize, Size: "Standard_B2s"})
+	require.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		got, err := env.opRepo.GetByOperationID(ctx, testUserID, op.OperationID)
+		return err == nil && got != nil && got.Status == model.OperationStatusFailed
+	}, 5*time.Second, 20*time.Millisecond)
+	dbVM, err = env.vmRepo.GetByID(ctx, vm.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "running", dbVM.PowerState)
+	assert.Equal(t, "Standard_D4s_v3", dbVM.Size)
+}
+
+func TestOperationService_ResumeTaskWithoutToken(t *testing.T) {
+	env := newVMTestEnv(t)
+	ctx := context.Background()
+	vm := env.addVM("vm1", "running")
+	_, err := env.vmService.SyncVMs(ctx, testUserID, testAccountID)
+	require.NoError(t, err)
+
+	// 调整规格过程中服务重启，操作没有恢复令牌
+	require.NoError(t, env.opRepo.Create(ctx, &model.Operation{
+		OperationID:    "resize-interrupted",
+		UserID:         testUserID,
+		AccountID:      testAccountID,
+		VMID:           vm.ID,
+		SubscriptionID: testSubID,
+		ResourceGroup:  vm.ResourceGroup,
+		ResourceName:   vm.Name,
+		Type:           string(v1.VMOperationResize),
+		Status:         model.OperationStatusRunning,
+	}))
+	require.NoError(t, env.vmRepo.UpdateStatus(ctx, vm.ID, "Resizing"))
+
+	require.NoError(t, env.opService.ResumeOperations(ctx))
+	op, err := env.opRepo.GetByOperationID(ctx, testUserID, "resize-interrupted")
+	require.NoError(t, err)
+	assert.Equal(t, model.OperationStatusFailed, op.Status)
+	dbVM, err := env.vmRepo.GetByID(ctx, vm.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "running", dbVM.PowerState)
+}
+
+func TestVirtualMachineService_DataDisks(t *testing.T) {
+	env := newVMTestEnv(t)
+	ctx := context.Background()
+	vm := env.provider.AddVM(azure.VMDetails{
+		SubscriptionID: testSubID,
+		ResourceGroup:  "rg",
+		Name:           "vm1",
+		Location:       "eastus",
+		Size:           "Standard_B1s",
+		OSDiskSize:     30,
+		DataDisks:      []azure.DiskInfo{{Name: "vm1-data-0", SizeGB: 64, Lun: 0, DiskType: "StandardSSD_LRS"}},
+	})
+	sizeRepo := repository.NewVmSizeRepository(repository.NewRepository(logger, env.db))
+	require.NoError(t, sizeRepo.BatchUpsertVmSizes(ctx, testAccountID, testSubID, []*model.VmSize{
+		{Name: "Standard_B1s", Location: "eastus", Cores: 1, MemoryGB: 1, MaxDataDisks: 2},
+	}))
+
+	_, err := env.vmService.SyncVMs(ctx, testUserID, testAccountID)
+	require.NoError(t, err)
+	dbVM, err := env.vmRepo.GetByID(ctx, vm.ID)
+	require.NoError(t, err)
+	id := strconv.Itoa(int(dbVM.ID))
+
+	waitOperation := func(op *model.Operation, status string) {
+		assert.Eventually(t, func() bool {
+			got, err := env.opRepo.GetByOperationID(ctx, testUserID, op.OperationID)
+			return err == nil && got != nil && got.Status == status
+		}, 5*time.Second, 20*time.Millisecond)
+	}
+
+	// 参数校验
+	lun := int32(0)
+	_, err = env.vmService.AttachDataDisk(ctx, testUserID, testAccountID, id, &v1.AttachDataDiskRequest{SizeGB: 32, Lun: &lun})
+	assert.ErrorIs(t, err, v1.ErrInvalidParams)
+	_, err = env.vmService.AttachDataDisk(ctx, testUserID, testAccountID, id, &v1.AttachDataDiskRequest{SizeGB: 32, StorageAccountType: "Fast_LRS"})
+	assert.ErrorIs(t, err, v1.ErrInvalidParams)
+
+	// 挂载后刷新数据库中的磁盘信息
+	op, err := env.vmService.AttachDataDisk(ctx, testUserID, testAccountID, id, &v1.AttachDataDiskRequest{Name: "vm1-data-1", SizeGB: 128, StorageAccountType: "Premium_LRS"})
+	require.NoError(t, err)
+	assert.Equal(t, string(v1.VMOperationAttachDisk), op.Type)
+	waitOperation(op, model.OperationStatusSucceeded)
+	state, ok := env.provider.GetVM(vm.ID)
+	require.True(t, ok)
+	require.Len(t, state.DataDisks, 2)
+	assert.Equal(t, int32(1), state.DataDisks[1].Lun)
+	dbVM, err = env.vmRepo.GetByID(ctx, vm.ID)
+	require.NoError(t, err)
+	assert.Contains(t, dbVM.DataDisks, `"name":"vm1-data-1"`)
+	assert.Contains(t, dbVM.DataDisks, `"diskType":"Premium_LRS"`)
+
+	// 达到规格的数据磁盘上限
+	_, err = env.vmService.AttachDataDisk(ctx, testUserID, testAccountID, id, &v1.AttachDataDiskRequest{SizeGB: 32})
+	assert.ErrorIs(t, err, v1.ErrDataDiskLimitExceeded)
+
+	// 扩容数据磁盘只能增加容量
+	_, err = env.vmService.ExpandDisk(ctx, testUserID, testAccountID, id, "vm1-data-1", 64)
+	assert.ErrorIs(t, err, v1.ErrInvalidParams)
+	_, err = env.vmService.ExpandDisk(ctx, testUserID, testAccountID, id, "missing", 256)
+	assert.ErrorIs(t, err, v1.ErrNotFound)
+	op, err = env.vmService.ExpandDisk(ctx, testUserID, testAccountID, id, "vm1-data-1", 256)
+	require.NoError(t, err)
+	waitOperation(op, model.OperationStatusSucceeded)
+	dbVM, err = env.vmRepo.GetByID(ctx, vm.ID)
+	require.NoError(t, err)
+	assert.Contains(t, dbVM.DataDisks, `"sizeGb":256`)
+
+	// 系统盘需要先释放虚拟机
+	_, err = env.vmService.ExpandDisk(ctx, testUserID, testAccountID, id, "", 64)
+	assert.ErrorIs(t, err, v1.ErrVMNotDeallocated)
+	op, err = env.vmService.OperateVM(ctx, testUserID, testAccountID, id, &v1.VMOperationRequest{Operation: v1.VMOperationStop, Force: true})
+	require.NoError(t, err)
+	waitOperation(op, model.OperationStatusSucceeded)
+	op, err = env.vmService.ExpandDisk(ctx, testUserID, testAccountID, id, "", 64)
+	require.NoError(t, err)
+	waitOperation(op, model.OperationStatusSucceeded)
+	dbVM, err = env.vmRepo.GetByID(ctx, vm.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 64, dbVM.OSDiskSize)
+
+	// 卸载数据磁盘
+	_, err = env.vmService.DetachDataDisk(ctx, testUserID, testAccountID, id, "missing", false)
+	assert.ErrorIs(t, err, v1.ErrNotFound)
+	op, err = env.vmService.DetachDataDisk(ctx, testUserID, testAccountID, id, "vm1-data-0", true)
+	require.NoError(t, err)
+	waitOperation(op, model.OperationStatusSucceeded)
+	state, ok = env.provider.GetVM(vm.ID)
+	require.True(t, ok)
+	require.Len(t, state.DataDisks, 1)
+	assert.Equal(t, "vm1-data-1", state.DataDisks[0].Name)
+	dbVM, err = env.vmRepo.GetByID(ctx, vm.ID)
+	require.NoError(t, err)
+	assert.NotContains(t, dbVM.DataDisks, "vm1-data-0")
+
+	// Azure 调用失败时操作失败，虚拟机状态不变
+	env.provider.Errors["AttachDataDisk"] = errors.New("OperationNotAllowed")
+	op, err = env.vmService.AttachDataDisk(ctx, testUserID, testAccountID, id, &v1.AttachDataDiskRequest{SizeGB: 32})
+	require.NoError(t, err)
+	waitOperation(op, model.OperationStatusFailed)
+	dbVM, err = env.vmRepo.GetByID(ctx, vm.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Deallocated", dbVM.PowerState)
+}
+
+func TestVirtualMachineService_UpdateDNSLabel(t *testing.T) {
+	env := newVMTestEnv(t)
+	ctx := context.Background()
+	vm := env.addVM("vm1", "running")
+
+	_, err := env.vmService.SyncVMs(ctx, testUserID, testAccountID)
+	require.NoError(t, err)
+	dbVM, err := env.vmRepo.GetByID(ctx, vm.ID)
+	require.NoError(t, err)
+
+	err = env.vmService.UpdateDNSLabel(ctx, testUserID, testAccountID, strconv.Itoa(int(dbVM.ID)), "myvm")
+	require.NoError(t, err)
+
+	dbVM, err = env.vmRepo.GetByID(ctx, vm.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "myvm.eastus.cloudapp.azure.com", dbVM.DnsAlias)
+
+	ip, ok := env.provider.GetPublicIP(vm.PublicIPName)
+	require.True(t, ok)
+	assert.Equal(t, "myvm", ip.DNSLabel)
+}
+
+func TestVirtualMachineService_SecurityGroups(t *testing.T) {
+	env := newVMTestEnv(t)
+	ctx := context.Background()
+	vm := env.addVM("vm1", "running")
+	subnetGroup := env.provider.AddSecurityGroup(vm.ID, azure.SecurityGroupScopeSubnet, azure.SecurityGroupInfo{
+		Name: "vnet-default-nsg",
+		Rules: []azure.SecurityRule{{
+			Name: "block-smtp", Priority: 100, Direction: "Outbound", Access: "Deny", Protocol: "Tcp",
+			SourceAddressPrefix: "*", SourcePortRange: "*", DestinationAddressPrefix: "*", DestinationPortRange: "25",
+		}},
+	})
+
+	// 同步时保存网络安全组
+	_, err := env.vmService.SyncVMs(ctx, testUserID, testAccountID)
+	require.NoError(t, err)
+	dbVM, err := env.vmRepo.GetByID(ctx, vm.ID)
+	require.NoError(t, err)
+	id := strconv.Itoa(int(dbVM.ID))
+	groups, err := env.vmService.ListSecurityGroups(ctx, testUserID, testAccountID, id, false)
+	require.NoError(t, err)
+	require.Len(t, groups, 1)
+	assert.Equal(t, azure.SecurityGroupScopeSubnet, groups[0].Scope)
+	assert.Equal(t, "DenyAllInBound", groups[0].Rules[2].Name)
+
+	// 参数校验
+	for _, req := range []*v1.AddSecurityRuleRequest{
+		{Name: "allow-ssh", Port: "70000", Priority: 1000},
+		{Name: "allow-ssh", Port: "22", Priority: 1000, Protocol: "gre"},
+		{Name: "allow-ssh", Port: "22", Priority: 1000, SourceCIDR: "10.0.0.0/33"},
+		{Name: "allow ssh", Port: "22", Priority: 1000},
+	} {
+		_, err = env.vmService.AddSecurityRule(ctx, testUserID, testAccountID, id, req)
+		assert.ErrorIs(t, err, v1.ErrInvalidParams, req)
+	}
+
+	// 网卡没有网络安全组时使用子网上的网络安全组
+	groups, err = env.vmService.AddSecurityRule(ctx, testUserID, testAccountID, id, &v1.AddSecurityRuleRequest{
+		Name: "allow-ssh", Port: "22", Protocol: "tcp", SourceCIDR: "203.0.113.0/24", Priority: 1000,
+	})
+	require.NoError(t, err)
+	stored, ok := env.provider.GetSecurityGroup(subnetGroup.ID)
+	require.True(t, ok)
+	assert.Equal(t, "allow-ssh", stored.Rules[0].Name)
+	assert.Equal(t, "Tcp", stored.Rules[0].Protocol)
+	assert.Equal(t, "203.0.113.0/24", stored.Rules[0].SourceAddressPrefix)
+	_, err = env.vmService.AddSecurityRule(ctx, testUserID, testAccountID, id, &v1.AddSecurityRuleRequest{
+		Name: "allow-rdp", Port: "3389", Priority: 1000,
+	})
+	assert.ErrorIs(t, err, v1.ErrSecurityRuleConflict)
+
+	// 同名的出站规则不能被入站规则覆盖，也不能删除
+	_, err = env.vmService.AddSecurityRule(ctx, testUserID, testAccountID, id, &v1.AddSecurityRuleRequest{
+		Name: "block-smtp", Port: "25", Priority: 1100,
+	})
+	assert.ErrorIs(t, err, v1.ErrSecurityRuleConflict)
+	_, err = env.vmService.DeleteSecurityRule(ctx, testUserID, testAccountID, id, "", "block-smtp")
+	assert.ErrorIs(t, err, v1.ErrNotFound)
+	stored, ok = env.provider.GetSecurityGroup(subnetGroup.ID)
+	require.True(t, ok)
+	var outbound []string
+	for _, rule := range stored.Rules {
+		if rule.Direction == "Outbound" && !rule.Default {
+			outbound = append(outbound, rule.Name)
+		}
+	}
+	assert.Equal(t, []string{"block-smtp"}, outbound)
+
+	// 为网卡创建网络安全组，之后的规则默认添加到网卡上的网络安全组
+	groups, err = env.vmService.CreateSecurityGroup(ctx, testUserID, testAccountID, id, &v1.CreateSecurityGroupRequest{})
+	require.NoError(t, err)
+	require.Len(t, groups, 2)
+	assert.Equal(t, "vm1-nsg", groups[0].Name)
+	assert.Equal(t, azure.SecurityGroupScopeNIC, groups[0].Scope)
+	_, err = env.vmService.CreateSecurityGroup(ctx, testUserID, testAccountID, id, &v1.CreateSecurityGroupRequest{})
+	assert.ErrorIs(t, err, v1.ErrSecurityGroupAlreadyAttached)
+	groups, err = env.vmService.AddSecurityRule(ctx, testUserID, testAccountID, id, &v1.AddSecurityRuleRequest{
+		Name: "allow-web", Port: "80,443", Priority: 1000,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "allow-web", groups[0].Rules[0].Name)
+	assert.Equal(t, "80,443", groups[0].Rules[0].DestinationPortRange)
+
+	dbVM, err = env.vmRepo.GetByID(ctx, vm.ID)
+	require.NoError(t, err)
+	assert.Contains(t, dbVM.SecurityGroups, `"name":"allow-web"`)
+
+	// 删除规则
+	_, err = env.vmService.DeleteSecurityRule(ctx, testUserID, testAccountID, id, "", "DenyAllInBound")
+	assert.ErrorIs(t, err, v1.ErrInvalidParams)
+	_, err = env.vmService.DeleteSecurityRule(ctx, testUserID, testAccountID, id, "vm1-nsg", "allow-ssh")
+	assert.ErrorIs(t, err, v1.ErrNotFound)
+	_, err = env.vmService.DeleteSecurityRule(ctx, testUserID, testAccountID, id, "", "allow-ssh")
+	require.NoError(t, err)
+	stored, ok = env.provider.GetSecurityGroup(subnetGroup.ID)
+	require.True(t, ok)
+	assert.True(t, stored.Rules[0].Default)
+	dbVM, err = env.vmRepo.GetByID(ctx, vm.ID)
+	require.NoError(t, err)
+	assert.NotContains(t, dbVM.SecurityGroups, "allow-ssh")
+}
+
+func TestVirtualMachineService_ResetVMAccess(t *testing.T) {
+	env := newVMTestEnv(t)
+	ctx := context.Background()
+	vm := env.addVM("vm1", "running")
+	stopped := env.addVM("vm2", "deallocated")
+
+	_, err := env.vmService.SyncVMs(ctx, testUserID, testAccountID)
+	require.NoError(t, err)
+	dbVM, err := env.vmRepo.GetByID(ctx, vm.ID)
+	require.NoError(t, err)
+	id := strconv.Itoa(int(dbVM.ID))
+	dbStopped, err := env.vmRepo.GetByID(ctx, stopped.ID)
+	require.NoError(t, err)
+
+	// 参数校验
+	for _, req := range []*v1.ResetVMAccessRequest{
+		{},
+		{Password: "P@ssw0rd1234"},
+		{Username: "azureuser", SSHPublicKey: "not-a-key"},
+	} {
+		_, err = env.vmService.ResetVMAccess(ctx, testUserID, testAccountID, id, req)
+		assert.ErrorIs(t, err, v1.ErrInvalidParams, req)
+	}
+	_, err = env.vmService.ResetVMAccess(ctx, testUserID, testAccountID, strconv.Itoa(int(dbStopped.ID)), &v1.ResetVMAccessRequest{ResetSSH: true})
+	assert.ErrorIs(t, err, v1.ErrVMNotRunning)
+
+	// 未安装扩展
+	_, err = env.vmService.GetVMAccessStatus(ctx, testUserID, testAccountID, id)
+	assert.ErrorIs(t, err, v1.ErrNotFound)
+
+	// 操作完成后电源状态记录为 Running，同样视为运行中
+	require.NoError(t, env.db.Model(&model.VirtualMachine{}).Where("id = ?", dbVM.ID).Update("power_state", "Running").Error)
+	op, err := env.vmService.ResetVMAccess(ctx, testUserID, testAccountID, id, &v1.ResetVMAccessRequest{
+		Username: "azureuser", SSHPublicKey: "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAI test", ResetSSH: true,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, string(v1.VMOperationResetAccess), op.Type)
+	assert.Eventually(t, func() bool {
+		got, err := env.opRepo.GetByOperationID(ctx, testUserID, op.OperationID)
+		return err == nil && got != nil && got.Status == model.OperationStatusSucceeded
+	}, 5*time.Second, 20*time.Millisecond)
+
+	opts, ok := env.provider.GetVMAccessReset(vm.ID)
+	require.True(t, ok)
+	assert.Equal(t, "azureuser", opts.Username)
+	assert.True(t, opts.ResetSSH)
+	result, err := env.vmService.GetVMAccessStatus(ctx, testUserID, testAccountID, id)
+	require.NoError(t, err)
+	assert.Equal(t, "Succeeded", result.ProvisioningState)
+}
+
+func TestVirtualMachineService_BootDiagnostics(t *testing.T) {
+	env := newVMTestEnv(t)
+	ctx := context.Background()
+	vm := env.addVM("vm1", "running")
+	env.provider.SerialLogs["vm1"] = "[    0.000000] Linux version 5.15.0\nlogin: "
+
+	_, err := env.vmService.SyncVMs(ctx, testUserID, testAccountID)
+	require.NoError(t, err)
+
+	// 未启用启动诊断
+	_, err = env.vmService.GetBootDiagnostics(ctx, testUserID, testAccountID, vm.ID, azure.BootDiagnosticsSerialLog)
+	assert.ErrorIs(t, err, v1.ErrBootDiagnosticsDisabled)
+	err = env.vmService.EnableBootDiagnostics(ctx, "user-2", testAccountID, vm.ID)
+	assert.ErrorIs(t, err, v1.ErrAccountError)
+
+	require.NoError(t, env.vmService.EnableBootDiagnostics(ctx, testUserID, testAccountID, vm.ID))
+	data, err := env.vmService.GetBootDiagnostics(ctx, testUserID, testAccountID, vm.ID, azure.BootDiagnosticsSerialLog)
+	require.NoError(t, err)
+	defer data.Body.Close()
+	content, err := io.ReadAll(data.Body)
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "login:")
+	assert.Equal(t, int64(len(content)), data.ContentLength)
+
+	// 截图尚未生成
+	_, err = env.vmService.GetBootDiagnostics(ctx, testUserID, testAccountID, vm.ID, azure.BootDiagnosticsScreenshot)
+	assert.ErrorIs(t, err, v1.ErrNotFound)
+}
@@ -0,0 +1,190 @@
+package service_test
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	v1 "azure-vm-backend/api/v1"
+	"azure-vm-backend/internal/model"
+	"azure-vm-backend/internal/repository"
+	"azure-vm-backend/pkg/azure"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVmCostService_EstimateCreate(t *testing.T) {
+	env := newVMTestEnv(t)
+	ctx := context.Background()
+	env.provider.SetPrices("eastus", []*azure.VMPrice{
+		{Size: "Standard_D2s_v3", Location: "eastus", Currency: "USD", LinuxPerHour: 0.096, WindowsPerHour: 0.188},
+	})
+
+	// 系统盘默认 30GB 按 32GB 档位计费，数据盘 100GB 按 128GB 档位计费
+	estimate, err := env.costService.EstimateCreate(ctx, testUserID, testAccountID, &v1.VMCreateParams{
+		Location:  "eastus",
+		Size:      "Standard_D2s_v3",
+		DataDisks: []v1.VMDiskParams{{SizeGB: 100, DiskType: "Premium_LRS"}},
+	})
+	require.NoError(t, err)
+	assert.True(t, estimate.PriceAvailable)
+	assert.Equal(t, "Linux", estimate.OSType)
+	assert.Equal(t, 70.08, estimate.ComputeMonthly)
+	require.Len(t, estimate.Disks, 2)
+	assert.Equal(t, 32, estimate.Disks[0].BilledGB)
+	assert.Equal(t, 2.4, estimate.Disks[0].MonthlyCost)
+	assert.Equal(t, "datadisk0", estimate.Disks[1].Name)
+	assert.Equal(t, 128, estimate.Disks[1].BilledGB)
+	assert.Equal(t, 19.71, estimate.Disks[1].MonthlyCost)
+	assert.Equal(t, 22.11, estimate.DiskMonthly)
+	assert.Equal(t, 92.19, estimate.Total)
+
+	// Windows 使用 Windows 价格，系统盘默认 127GB
+	estimate, err = env.costService.EstimateCreate(ctx, testUserID, testAccountID, &v1.VMCreateParams{
+		Location: "eastus",
+		Size:     "Standard_D2s_v3",
+		OSType:   "Windows",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 137.24, estimate.ComputeMonthly)
+	assert.Equal(t, 146.84, estimate.Total)
+
+	// 查询过的价格保存到价格表
+	var count int64
+	require.NoError(t, env.db.Model(&model.VmSizePrice{}).Count(&count).Error)
+	assert.Equal(t, int64(1), count)
+
+	// 没有报价的规格只计算磁盘费用
+	estimate, err = env.costService.EstimateCreate(ctx, testUserID, testAccountID, &v1.VMCreateParams{
+		Location: "eastus",
+		Size:     "Standard_Unknown",
+	})
+	require.NoError(t, err)
+	assert.False(t, estimate.PriceAvailable)
+	assert.Zero(t, estimate.ComputeMonthly)
+	assert.Equal(t, 2.4, estimate.Total)
+
+	_, err = env.costService.EstimateCreate(ctx, "other-user", testAccountID, &v1.VMCreateParams{
+		Location: "eastus",
+		Size:     "Standard_D2s_v3",
+	})
+	assert.ErrorIs(t, err, v1.ErrAccountError)
+}
+
+func TestVmCostService_SyncFillsMonthlyCost(t *testing.T) {
+	env := newVMTestEnv(t)
+	ctx := context.Background()
+	env.provider.SetPrices("eastus", []*azure.VMPrice{
+		{Size: "Standard_B1s", Location: "eastus", Currency: "USD", LinuxPerHour: 0.0104},
+	})
+	running := env.provider.AddVM(azure.VMDetails{
+		SubscriptionID: testSubID,
+		ResourceGroup:  "rg",
+		Name:           "vm1",
+		Location:       "eastus",
+		Size:           "Standard_B1s",
+		PowerState:     "running",
+		OSDiskSize:     30,
+	})
+	stopped := env.provider.AddVM(azure.VMDetails{
+		SubscriptionID: testSubID,
+		ResourceGroup:  "rg",
+		Name:           "vm2",
+		Location:       "eastus",
+		Size:           "Standard_B1s",
+		PowerState:     "deallocated",
+		OSDiskSize:     30,
+	})
+
+	_, err := env.vmService.SyncVMs(ctx, testUserID, testAccountID)
+	require.NoError(t, err)
+
+	// 运行中: 0.0104*730 + 32GB*0.075，已释放的只计算磁盘
+	vm, err := env.vmRepo.GetByID(ctx, running.ID)
+	require.NoError(t, err)
+	require.NotNil(t, vm)
+	assert.Equal(t, 9.99, vm.MonthlyCost)
+	vm, err = env.vmRepo.GetByID(ctx, stopped.ID)
+	require.NoError(t, err)
+	require.NotNil(t, vm)
+	assert.Equal(t, 2.4, vm.MonthlyCost)
+
+	costs, err := env.costService.GetAccountCosts(ctx, []string{testAccountID, "account-empty"})
+	require.NoError(t, err)
+	require.Contains(t, costs, testAccountID)
+	assert.Equal(t, 12.39, costs[testAccountID].MonthlyCost)
+	require.Len(t, costs[testAccountID].Subscriptions, 1)
+	assert.Equal(t, testSubID, costs[testAccountID].Subscriptions[0].SubscriptionID)
+	assert.Equal(t, int64(2), costs[testAccountID].Subscriptions[0].VMCount)
+	require.Contains(t, costs, "account-empty")
+	assert.Zero(t, costs["account-empty"].MonthlyCost)
+	assert.Empty(t, costs["account-empty"].Subscriptions)
+}
+
+func TestVmCostService_RefreshAfterOperations(t *testing.T) {
+	env := newVMTestEnv(t)
+	ctx := context.Background()
+	env.provider.SetPrices("eastus", []*azure.VMPrice{
+		{Size: "Standard_B1s", Location: "eastus", Currency: "USD", LinuxPerHour: 0.0104},
+		{Size: "Standard_B2s", Location: "eastus", Currency: "USD", LinuxPerHour: 0.0416},
+	})
+	env.provider.SetSizes("eastus", []*azure.VMSizeInfo{
+		{Name: "Standard_B1s", Location: "eastus", Cores: 1, MemoryGB: 1, MaxDataDisks: 2},
+		{Name: "Standard_B2s", Location: "eastus", Cores: 2, MemoryGB: 4, MaxDataDisks: 4},
+	})
+	sizeRepo := repository.NewVmSizeRepository(repository.NewRepository(logger, env.db))
+	require.NoError(t, sizeRepo.BatchUpsertVmSizes(ctx, testAccountID, testSubID, []*model.VmSize{
+		{Name: "Standard_B1s", Location: "eastus", Cores: 1, MemoryGB: 1, MaxDataDisks: 2},
+		{Name: "Standard_B2s", Location: "eastus", Cores: 2, MemoryGB: 4, MaxDataDisks: 4},
+	}))
+	vm := env.provider.AddVM(azure.VMDetails{
+		SubscriptionID: testSubID,
+		ResourceGroup:  "rg",
+		Name:           "vm1",
+		Location:       "eastus",
+		Size:           "Standard_B1s",
+		PowerState:     "running",
+		OSDiskSize:     30,
+	})
+
+	_, err := env.vmService.SyncVMs(ctx, testUserID, testAccountID)
+	require.NoError(t, err)
+	dbVM, err := env.vmRepo.GetByID(ctx, vm.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 9.99, dbVM.MonthlyCost)
+	id := strconv.Itoa(int(dbVM.ID))
+
+	waitOperation := func(op *model.Operation) {
+		assert.Eventually(t, func() bool {
+			got, err := env.opRepo.GetByOperationID(ctx, testUserID, op.OperationID)
+			return err == nil && got != nil && got.Status == model.OperationStatusSucceeded
+		}, 5*time.Second, 20*time.Millisecond)
+	}
+	monthlyCost := func() float64 {
+		dbVM, err := env.vmRepo.GetByID(ctx, vm.ID)
+		require.NoError(t, err)
+		return dbVM.MonthlyCost
+	}
+
+	// 调整规格后按新规格计算: 0.0416*730 + 32GB*0.075
+	op, err := env.vmService.OperateVM(ctx, testUserID, testAccountID, id, &v1.VMOperationRequest{Operation: v1.VMOperationResize, Size: "Standard_B2s"})
+	require.NoError(t, err)
+	waitOperation(op)
+	assert.Equal(t, 32.77, monthlyCost())
+
+	// 挂载数据磁盘后加上 128GB*0.154
+	op, err = env.vmService.AttachDataDisk(ctx, testUserID, testAccountID, id, &v1.AttachDataDiskRequest{SizeGB: 128, StorageAccountType: "Premium_LRS"})
+	require.NoError(t, err)
+	waitOperation(op)
+	assert.Equal(t, 52.48, monthlyCost())
+
+	// 释放后电源状态记录为 Deallocated，扩容系统盘后只计算磁盘: 64GB*0.075 + 128GB*0.154
+	op, err = env.vmService.OperateVM(ctx, testUserID, testAccountID, id, &v1.VMOperationRequest{Operation: v1.VMOperationStop, Force: true})
+	require.NoError(t, err)
+	waitOperation(op)
+	op, err = env.vmService.ExpandDisk(ctx, testUserID, testAccountID, id, "", 64)
+	require.NoError(t, err)
+	waitOperation(op)
+	assert.Equal(t, 24.51, monthlyCost())
+}
@@ -0,0 +1,77 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"azure-vm-backend/internal/repository"
+	"azure-vm-backend/internal/service"
+	"azure-vm-backend/pkg/azure"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVmPriceService_RefreshPrices(t *testing.T) {
+	env := newVMTestEnv(t)
+	ctx := context.Background()
+	repo := repository.NewRepository(logger, env.db)
+	sizeRepo := repository.NewVmSizeRepository(repo)
+	priceRepo := repository.NewVmSizePriceRepository(repo)
+	sizeService := service.NewVmSizeService(env.srv, sizeRepo, env.accountsRepo, env.subsRepo, env.provider)
+	priceService := service.NewVmPriceService(env.srv, priceRepo, env.provider, viper.New())
+
+	env.provider.SetSizes("eastus", []*azure.VMSizeInfo{
+		{Name: "Standard_B1s", Location: "eastus", Cores: 1, MemoryGB: 1},
+		{Name: "Standard_D2s_v3", Location: "eastus", Cores: 2, MemoryGB: 8},
+	})
+	require.NoError(t, sizeService.SyncVmSizes(ctx, testUserID, testAccountID, testSubID, "eastus"))
+	env.provider.SetPrices("eastus", []*azure.VMPrice{
+		{Size: "Standard_B1s", Location: "eastus", Currency: "USD", LinuxPerHour: 0.0104, WindowsPerHour: 0.0144, LinuxSpotPerHour: 0.0021},
+		{Size: "Standard_D2s_v3", Location: "eastus", Currency: "USD", LinuxPerHour: 0.096, WindowsPerHour: 0.188, LinuxSpotPerHour: 0.0192, WindowsSpotPerHour: 0.0376},
+		{Size: "Standard_M8ms", Location: "eastus", Currency: "USD", LinuxPerHour: 2.2},
+	})
+
+	// 按已同步规格的区域刷新，写入价格表并同步到规格记录
+	require.NoError(t, priceService.RefreshPrices(ctx))
+	size, err := sizeRepo.GetVmSize(ctx, testUserID, testAccountID, testSubID, "eastus", "Standard_D2s_v3")
+	require.NoError(t, err)
+	require.NotNil(t, size)
+	assert.Equal(t, 0.096, size.PricePerHour)
+	assert.Equal(t, 0.188, size.WindowsPricePerHour)
+	assert.Equal(t, 0.0192, size.SpotPricePerHour)
+	assert.Equal(t, 0.0376, size.WindowsSpotPricePerHour)
+	assert.Equal(t, "USD", size.Currency)
+
+	// 价格同步后新增的规格直接使用已有价格
+	env.provider.SetSizes("eastus", []*azure.VMSizeInfo{
+		{Name: "Standard_M8ms", Location: "eastus", Cores: 8, MemoryGB: 218},
+	})
+	require.NoError(t, sizeService.SyncVmSizes(ctx, testUserID, testAccountID, testSubID, "eastus"))
+	size, err = sizeRepo.GetVmSize(ctx, testUserID, testAccountID, testSubID, "eastus", "Standard_M8ms")
+	require.NoError(t, err)
+	require.NotNil(t, size)
+	assert.Equal(t, 2.2, size.PricePerHour)
+
+	// 不再有报价的规格价格清零
+	env.provider.SetPrices("eastus", []*azure.VMPrice{
+		{Size: "Standard_D2s_v3", Location: "eastus", Currency: "USD", LinuxPerHour: 0.1},
+	})
+	count, err := priceService.RefreshLocation(ctx, "eastus")
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+	size, err = sizeRepo.GetVmSize(ctx, testUserID, testAccountID, testSubID, "eastus", "Standard_B1s")
+	require.NoError(t, err)
+	assert.Zero(t, size.PricePerHour)
+	price, err := priceRepo.GetPrice(ctx, "eastus", "Standard_D2s_v3")
+	require.NoError(t, err)
+	require.NotNil(t, price)
+	assert.Equal(t, 0.1, price.LinuxPerHour)
+
+	env.provider.Errors["ListVMPrices"] = errors.New("TooManyRequests")
+	assert.Error(t, priceService.RefreshPrices(ctx))
+	price, err = priceRepo.GetPrice(ctx, "eastus", "Standard_D2s_v3")
+	require.NoError(t, err)
+	require.NotNil(t, price)
+}
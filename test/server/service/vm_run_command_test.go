@@ -0,0 +1,141 @@
+package service_test
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	v1 "azure-vm-backend/api/v1"
+	"azure-vm-backend/internal/model"
+	"azure-vm-backend/internal/repository"
+	"azure-vm-backend/internal/service"
+	"azure-vm-backend/pkg/azure"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVmRunCommandService(t *testing.T) {
+	env := newVMTestEnv(t)
+	ctx := context.Background()
+	env.provider.AddVM(azure.VMDetails{SubscriptionID: testSubID, ResourceGroup: "rg", Name: "linux1", Location: "eastus", PowerState: "running", OSType: "Linux"})
+	env.provider.AddVM(azure.VMDetails{SubscriptionID: testSubID, ResourceGroup: "rg", Name: "linux2", Location: "eastus", PowerState: "deallocated", OSType: "Linux"})
+	env.provider.AddVM(azure.VMDetails{SubscriptionID: testSubID, ResourceGroup: "rg", Name: "win1", Location: "eastus", PowerState: "running", OSType: "Windows"})
+	env.provider.AddVM(azure.VMDetails{SubscriptionID: testSubID, ResourceGroup: "other", Name: "linux3", Location: "eastus", PowerState: "running", OSType: "Linux"})
+	runCommandRepo := repository.NewVmRunCommandRepository(repository.NewRepository(logger, env.db))
+	runCommandService := service.NewVmRunCommandService(env.srv, runCommandRepo, env.vmRepo, env.accountsRepo, env.opService)
+
+	_, err := env.vmService.SyncVMs(ctx, testUserID, testAccountID)
+	require.NoError(t, err)
+	vmIDs := make(map[string]string)
+	var vms []*model.VirtualMachine
+	require.NoError(t, env.db.Find(&vms).Error)
+	for _, vm := range vms {
+		vmIDs[vm.Name] = strconv.Itoa(int(vm.ID))
+	}
+
+	waitExecution := func(executionID, status string) *model.VmCommandExecution {
+		var execution *model.VmCommandExecution
+		assert.Eventually(t, func() bool {
+			execution, err = runCommandService.GetExecution(ctx, testUserID, executionID)
+			return err == nil && execution.Status == status
+		}, 5*time.Second, 20*time.Millisecond)
+		return execution
+	}
+
+	// 脚本片段
+	snippet, err := runCommandService.CreateSnippet(ctx, testUserID, &v1.CreateScriptSnippetRequest{Name: "uptime", OSType: "Linux", Script: "uptime"})
+	require.NoError(t, err)
+	_, err = runCommandService.CreateSnippet(ctx, testUserID, &v1.CreateScriptSnippetRequest{Name: "blank", Script: "  "})
+	assert.ErrorIs(t, err, v1.ErrInvalidParams)
+	script := "df -h"
+	snippet, err = runCommandService.UpdateSnippet(ctx, testUserID, snippet.SnippetID, &v1.UpdateScriptSnippetRequest{Script: &script})
+	require.NoError(t, err)
+	assert.Equal(t, "df -h", snippet.Script)
+	_, err = runCommandService.UpdateSnippet(ctx, "other-user", snippet.SnippetID, &v1.UpdateScriptSnippetRequest{Script: &script})
+	assert.ErrorIs(t, err, v1.ErrNotFound)
+
+	// script 和 snippetId 必须且只能指定一个
+	_, err = runCommandService.RunCommand(ctx, testUserID, testAccountID, vmIDs["linux1"], &v1.RunCommandRequest{})
+	assert.ErrorIs(t, err, v1.ErrInvalidParams)
+	_, err = runCommandService.RunCommand(ctx, testUserID, testAccountID, vmIDs["linux1"], &v1.RunCommandRequest{Script: "ls", SnippetID: snippet.SnippetID})
+	assert.ErrorIs(t, err, v1.ErrInvalidParams)
+
+	// 未运行的虚拟机和系统不匹配的脚本片段
+	_, err = runCommandService.RunCommand(ctx, testUserID, testAccountID, vmIDs["linux2"], &v1.RunCommandRequest{Script: "ls"})
+	assert.ErrorIs(t, err, v1.ErrVMNotRunning)
+	_, err = runCommandService.RunCommand(ctx, testUserID, testAccountID, vmIDs["win1"], &v1.RunCommandRequest{SnippetID: snippet.SnippetID})
+	assert.ErrorIs(t, err, v1.ErrInvalidParams)
+
+	// 脚本以非零状态退出时记录退出码和输出
+	exitCode := 2
+	env.provider.RunCommandResults["linux1"] = azure.RunCommandResult{ExitCode: &exitCode, Stdout: "partial", Stderr: "boom"}
+	execution, err := runCommandService.RunCommand(ctx, testUserID, testAccountID, vmIDs["linux1"], &v1.RunCommandRequest{Script: "exit 2"})
+	require.NoError(t, err)
+	assert.Equal(t, azure.RunCommandShellScript, execution.CommandID)
+	assert.NotEmpty(t, execution.OperationID)
+	execution = waitExecution(execution.ExecutionID, model.CommandStatusFailed)
+	require.NotNil(t, execution.ExitCode)
+	assert.Equal(t, 2, *execution.ExitCode)
+	assert.Equal(t, "partial", execution.Stdout)
+	assert.Equal(t, "boom", execution.Stderr)
+	assert.NotNil(t, execution.FinishedAt)
+	delete(env.provider.RunCommandResults, "linux1")
+
+	// 操作完成后电源状态记录为 Running，同样视为运行中
+	require.NoError(t, env.db.Model(&model.VirtualMachine{}).Where("name = ?", "win1").Update("power_state", "Running").Error)
+
+	// Windows 执行 PowerShell 脚本
+	execution, err = runCommandService.RunCommand(ctx, testUserID, testAccountID, vmIDs["win1"], &v1.RunCommandRequest{Script: "Get-Date"})
+	require.NoError(t, err)
+	assert.Equal(t, azure.RunCommandPowerShellScript, execution.CommandID)
+	waitExecution(execution.ExecutionID, model.CommandStatusSucceeded)
+
+	// 批量执行，按筛选条件选择虚拟机并跳过未运行和系统不匹配的虚拟机
+	_, err = runCommandService.BatchRunCommand(ctx, testUserID, &v1.BatchRunCommandRequest{
+		RunCommandRequest: v1.RunCommandRequest{Script: "ls"},
+		Filter:            v1.VMQueryParams{ResourceGroup: "missing"},
+	})
+	assert.ErrorIs(t, err, v1.ErrNotFound)
+	batch, err := runCommandService.BatchRunCommand(ctx, testUserID, &v1.BatchRunCommandRequest{
+		RunCommandRequest: v1.RunCommandRequest{SnippetID: snippet.SnippetID},
+		Filter:            v1.VMQueryParams{UserID: "other-user", ResourceGroup: "rg"},
+	})
+	require.NoError(t, err)
+	require.Len(t, batch.Executions, 1)
+	assert.Equal(t, "linux1", batch.Executions[0].VMName)
+	assert.Equal(t, snippet.SnippetID, batch.Executions[0].SnippetID)
+	assert.Len(t, batch.Skipped, 2)
+	waitExecution(batch.Executions[0].ExecutionID, model.CommandStatusSucceeded)
+	executions, err := runCommandService.ListBatchExecutions(ctx, testUserID, batch.BatchID)
+	require.NoError(t, err)
+	assert.Len(t, executions, 1)
+	_, err = runCommandService.ListBatchExecutions(ctx, "other-user", batch.BatchID)
+	assert.ErrorIs(t, err, v1.ErrNotFound)
+
+	// 执行记录按开始时间倒序
+	history, err := runCommandService.ListExecutions(ctx, testUserID, testAccountID, vmIDs["linux1"], &v1.ListCommandExecutionsRequest{})
+	require.NoError(t, err)
+	require.Equal(t, int64(2), history.Total)
+	assert.Equal(t, batch.BatchID, history.Items[0].BatchID)
+
+	// 服务重启时未完成的执行记录标记为失败
+	require.NoError(t, runCommandRepo.CreateExecution(ctx, &model.VmCommandExecution{
+		ExecutionID: "interrupted",
+		UserID:      testUserID,
+		AccountID:   testAccountID,
+		VMID:        batch.Executions[0].VMID,
+		Script:      "sleep 600",
+		Status:      model.CommandStatusRunning,
+		StartedAt:   time.Now(),
+	}))
+	require.NoError(t, runCommandService.FailInterruptedExecutions(ctx))
+	execution, err = runCommandService.GetExecution(ctx, testUserID, "interrupted")
+	require.NoError(t, err)
+	assert.Equal(t, model.CommandStatusFailed, execution.Status)
+
+	require.NoError(t, runCommandService.DeleteSnippet(ctx, testUserID, snippet.SnippetID))
+	snippets, err := runCommandService.ListSnippets(ctx, testUserID)
+	require.NoError(t, err)
+	assert.Empty(t, snippets)
+}
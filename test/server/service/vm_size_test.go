@@ -0,0 +1,98 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+
+	v1 "azure-vm-backend/api/v1"
+	"azure-vm-backend/internal/repository"
+	"azure-vm-backend/internal/service"
+	"azure-vm-backend/pkg/azure"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVmSizeService_SyncResourceSKUs(t *testing.T) {
+	env := newVMTestEnv(t)
+	ctx := context.Background()
+	vm := env.addVM("vm1", "running")
+	sizeRepo := repository.NewVmSizeRepository(repository.NewRepository(logger, env.db))
+	sizeService := service.NewVmSizeService(env.srv, sizeRepo, env.accountsRepo, env.subsRepo, env.provider)
+	env.provider.SetSizes("eastus", []*azure.VMSizeInfo{
+		{Name: "Standard_B1s", Location: "eastus", Cores: 1, MemoryGB: 1, MaxDataDisks: 2, QuotaFamily: "standardBSFamily", HyperVGenerations: []string{"V1", "V2"}},
+		{Name: "Standard_D2s_v3", Location: "eastus", Cores: 2, MemoryGB: 8, MaxDataDisks: 4, QuotaFamily: "standardDSv3Family",
+			Zones: []string{"1", "2", "3"}, AcceleratedNetworking: true, PremiumIO: true, HyperVGenerations: []string{"V1", "V2"}},
+		{Name: "Standard_M8ms", Location: "eastus", Cores: 8, MemoryGB: 218, MaxDataDisks: 8, QuotaFamily: "standardMSFamily",
+			Restricted: true, RestrictionReason: "NotAvailableForSubscription"},
+	})
+
+	require.NoError(t, sizeService.SyncVmSizes(ctx, testUserID, testAccountID, testSubID, "eastus"))
+
+	// 受限的规格不出现在列表中
+	sizes, err := sizeService.ListVmSizes(ctx, testUserID, testAccountID, "", "eastus")
+	require.NoError(t, err)
+	require.Len(t, sizes, 2)
+	resp := v1.ToListVmSizesResponse(sizes)
+	for _, size := range resp.Sizes {
+		if size.Name == "Standard_D2s_v3" {
+			assert.Equal(t, []string{"1", "2", "3"}, size.Zones)
+			assert.True(t, size.AcceleratedNetworking)
+			assert.True(t, size.PremiumIO)
+			assert.Equal(t, "standardDSv3Family", size.QuotaFamily)
+		}
+	}
+	restricted, err := sizeRepo.GetVmSize(ctx, testUserID, testAccountID, testSubID, "eastus", "Standard_M8ms")
+	require.NoError(t, err)
+	require.NotNil(t, restricted)
+	assert.True(t, restricted.Restricted)
+	assert.Equal(t, "NotAvailableForSubscription", restricted.RestrictionReason)
+
+	// 调整为受限的规格在调用Azure前被拒绝
+	_, err = env.vmService.SyncVMs(ctx, testUserID, testAccountID)
+	require.NoError(t, err)
+	dbVM, err := env.vmRepo.GetByID(ctx, vm.ID)
+	require.NoError(t, err)
+	_, err = env.vmService.OperateVM(ctx, testUserID, testAccountID, strconv.Itoa(int(dbVM.ID)), &v1.VMOperationRequest{Operation: v1.VMOperationResize, Size: "Standard_M8ms"})
+	assert.ErrorIs(t, err, v1.ErrVMSizeUnavailable)
+
+	// 限制按订阅保存，同步另一个订阅不会覆盖本订阅的限制
+	env.provider.AddSubscription(azure.SubscriptionDetail{SubscriptionID: "sub-2", DisplayName: "second", State: "Enabled"})
+	_, err = newSubscriptionsService(env).SyncSubscriptions(ctx, testUserID, testAccountID)
+	require.NoError(t, err)
+	env.provider.SetSizes("eastus", []*azure.VMSizeInfo{
+		{Name: "Standard_M8ms", Location: "eastus", Cores: 8, MemoryGB: 218, MaxDataDisks: 8, QuotaFamily: "standardMSFamily"},
+	})
+	require.NoError(t, sizeService.SyncVmSizes(ctx, testUserID, testAccountID, "sub-2", "eastus"))
+	other, err := sizeRepo.GetVmSize(ctx, testUserID, testAccountID, "sub-2", "eastus", "Standard_M8ms")
+	require.NoError(t, err)
+	require.NotNil(t, other)
+	assert.False(t, other.Restricted)
+	restricted, err = sizeRepo.GetVmSize(ctx, testUserID, testAccountID, testSubID, "eastus", "Standard_M8ms")
+	require.NoError(t, err)
+	require.NotNil(t, restricted)
+	assert.True(t, restricted.Restricted)
+	_, err = env.vmService.OperateVM(ctx, testUserID, testAccountID, strconv.Itoa(int(dbVM.ID)), &v1.VMOperationRequest{Operation: v1.VMOperationResize, Size: "Standard_M8ms"})
+	assert.ErrorIs(t, err, v1.ErrVMSizeUnavailable)
+
+	// 未指定订阅时只要有一个订阅可用就列出，指定订阅时按该订阅过滤
+	sizes, err = sizeService.ListVmSizes(ctx, testUserID, testAccountID, "", "eastus")
+	require.NoError(t, err)
+	assert.Len(t, sizes, 3)
+	sizes, err = sizeService.ListVmSizes(ctx, testUserID, testAccountID, testSubID, "eastus")
+	require.NoError(t, err)
+	assert.Len(t, sizes, 2)
+
+	// 再次同步时限制解除
+	env.provider.SetSizes("eastus", []*azure.VMSizeInfo{
+		{Name: "Standard_M8ms", Location: "eastus", Cores: 8, MemoryGB: 218, MaxDataDisks: 8, QuotaFamily: "standardMSFamily"},
+	})
+	require.NoError(t, sizeService.SyncVmSizes(ctx, testUserID, testAccountID, testSubID, "eastus"))
+	sizes, err = sizeService.ListVmSizes(ctx, testUserID, testAccountID, "", "eastus")
+	require.NoError(t, err)
+	assert.Len(t, sizes, 3)
+
+	env.provider.Errors["ListResourceSKUs"] = errors.New("AuthorizationFailed")
+	assert.Error(t, sizeService.SyncVmSizes(ctx, testUserID, testAccountID, testSubID, "eastus"))
+}
@@ -0,0 +1,188 @@
+package service_test
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	v1 "azure-vm-backend/api/v1"
+	"azure-vm-backend/internal/model"
+	"azure-vm-backend/internal/repository"
+	"azure-vm-backend/internal/service"
+	"azure-vm-backend/pkg/azure"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVmSnapshotService(t *testing.T) {
+	env := newVMTestEnv(t)
+	ctx := context.Background()
+	vm := env.provider.AddVM(azure.VMDetails{
+		SubscriptionID: testSubID,
+		ResourceGroup:  "rg",
+		Name:           "vm1",
+		Location:       "eastus",
+		Size:           "Standard_B1s",
+		PowerState:     "running",
+		OSDiskSize:     30,
+		DataDisks:      []azure.DiskInfo{{Name: "vm1-data-0", SizeGB: 64, Lun: 0, DiskType: "StandardSSD_LRS"}},
+	})
+	snapshotRepo := repository.NewVmSnapshotRepository(repository.NewRepository(logger, env.db))
+	snapshotService := service.NewVmSnapshotService(env.srv, snapshotRepo, env.vmRepo, env.accountsRepo, env.opService, env.provider)
+
+	_, err := env.vmService.SyncVMs(ctx, testUserID, testAccountID)
+	require.NoError(t, err)
+	dbVM, err := env.vmRepo.GetByID(ctx, vm.ID)
+	require.NoError(t, err)
+	id := strconv.Itoa(int(dbVM.ID))
+
+	waitOperation := func(op *model.Operation, status string) {
+		assert.Eventually(t, func() bool {
+			got, err := env.opRepo.GetByOperationID(ctx, testUserID, op.OperationID)
+			return err == nil && got != nil && got.Status == status
+		}, 5*time.Second, 20*time.Millisecond)
+	}
+	listSnapshots := func(refresh bool) []*model.VmSnapshot {
+		snapshots, err := snapshotService.ListSnapshots(ctx, testUserID, testAccountID, id, refresh)
+		require.NoError(t, err)
+		return snapshots
+	}
+
+	// 同一资源组中其他虚拟机的快照及没有来源标签的快照，不应被导入或清理
+	other := env.provider.AddVM(azure.VMDetails{
+		SubscriptionID: testSubID,
+		ResourceGroup:  "rg",
+		Name:           "vm2",
+		Location:       "eastus",
+		Size:           "Standard_B1s",
+		PowerState:     "running",
+		OSDiskSize:     30,
+	})
+	foreign := []azure.SnapshotInfo{
+		{Name: "vm2-os", SourceVMID: other.ID},
+		{Name: "untagged"},
+	}
+	for i := range foreign {
+		foreign[i].ID = "/subscriptions/sub-1/resourceGroups/rg/providers/Microsoft.Compute/snapshots/" + foreign[i].Name
+		foreign[i].ResourceGroup = "rg"
+		foreign[i].Location = "eastus"
+		foreign[i].DiskRole = azure.SnapshotDiskRoleOS
+		foreign[i].SizeGB = 30
+		env.provider.AddSnapshot(foreign[i])
+	}
+	assert.Empty(t, listSnapshots(true))
+
+	// 未挂载的磁盘
+	_, err = snapshotService.CreateSnapshot(ctx, testUserID, testAccountID, id, &v1.CreateSnapshotRequest{DiskName: "missing"})
+	assert.ErrorIs(t, err, v1.ErrNotFound)
+
+	// 系统盘快照
+	op, err := snapshotService.CreateSnapshot(ctx, testUserID, testAccountID, id, &v1.CreateSnapshotRequest{Name: "vm1-os-1"})
+	require.NoError(t, err)
+	assert.Equal(t, string(v1.VMOperationSnapshot), op.Type)
+	waitOperation(op, model.OperationStatusSucceeded)
+	snapshots := listSnapshots(false)
+	require.Len(t, snapshots, 1)
+	osSnapshot := snapshots[0]
+	assert.Equal(t, azure.SnapshotDiskRoleOS, osSnapshot.DiskRole)
+	assert.Equal(t, dbVM.VMID, osSnapshot.VMID)
+	assert.Equal(t, 30, osSnapshot.SizeGB)
+
+	// 数据磁盘快照，超出保留数量时删除最旧的快照
+	op, err = snapshotService.CreateSnapshot(ctx, testUserID, testAccountID, id, &v1.CreateSnapshotRequest{DiskName: "vm1-data-0", Name: "vm1-data-1"})
+	require.NoError(t, err)
+	waitOperation(op, model.OperationStatusSucceeded)
+	op, err = snapshotService.CreateSnapshot(ctx, testUserID, testAccountID, id, &v1.CreateSnapshotRequest{Name: "vm1-os-2", Retention: 2})
+	require.NoError(t, err)
+	waitOperation(op, model.OperationStatusSucceeded)
+	snapshots = listSnapshots(false)
+	require.Len(t, snapshots, 2)
+	assert.Equal(t, "vm1-os-2", snapshots[0].Name)
+	assert.Equal(t, "vm1-data-1", snapshots[1].Name)
+	_, ok := env.provider.GetSnapshot(osSnapshot.ResourceID)
+	assert.False(t, ok)
+	dataSnapshot := snapshots[1]
+
+	// 对账补充Azure上未记录的快照
+	env.provider.AddSnapshot(azure.SnapshotInfo{
+		ID:             "/subscriptions/sub-1/resourceGroups/rg/providers/Microsoft.Compute/snapshots/manual",
+		Name:           "manual",
+		ResourceGroup:  "rg",
+		Location:       "eastus",
+		SourceVMID:     vm.ID,
+		SourceDiskName: "vm1_OsDisk",
+		DiskRole:       azure.SnapshotDiskRoleOS,
+		SizeGB:         30,
+	})
+	assert.Len(t, listSnapshots(false), 2)
+	snapshots = listSnapshots(true)
+	require.Len(t, snapshots, 3)
+	assert.Equal(t, "manual", snapshots[0].Name)
+
+	for _, snapshot := range foreign {
+		_, ok := env.provider.GetSnapshot(snapshot.ID)
+		assert.True(t, ok, snapshot.Name)
+	}
+
+	// 删除快照
+	require.NoError(t, snapshotService.DeleteSnapshot(ctx, testUserID, testAccountID, id, snapshots[0].SnapshotID))
+	assert.Len(t, listSnapshots(true), 2)
+	err = snapshotService.DeleteSnapshot(ctx, testUserID, testAccountID, id, "missing")
+	assert.ErrorIs(t, err, v1.ErrNotFound)
+
+	// 数据磁盘快照不能恢复系统盘
+	_, err = snapshotService.RestoreSnapshot(ctx, testUserID, testAccountID, id, dataSnapshot.SnapshotID)
+	assert.ErrorIs(t, err, v1.ErrInvalidParams)
+
+	// 扩容系统盘后从快照恢复，磁盘大小回到快照时的容量
+	op, err = env.vmService.OperateVM(ctx, testUserID, testAccountID, id, &v1.VMOperationRequest{Operation: v1.VMOperationStop, Force: true})
+	require.NoError(t, err)
+	waitOperation(op, model.OperationStatusSucceeded)
+	op, err = env.vmService.ExpandDisk(ctx, testUserID, testAccountID, id, "", 64)
+	require.NoError(t, err)
+	waitOperation(op, model.OperationStatusSucceeded)
+
+	restoreSnapshot := listSnapshots(false)[0]
+	op, err = snapshotService.RestoreSnapshot(ctx, testUserID, testAccountID, id, restoreSnapshot.SnapshotID)
+	require.NoError(t, err)
+	assert.Equal(t, string(v1.VMOperationRestoreSnapshot), op.Type)
+	waitOperation(op, model.OperationStatusSucceeded)
+	dbVM, err = env.vmRepo.GetByID(ctx, vm.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 30, dbVM.OSDiskSize)
+	assert.Contains(t, dbVM.DataDisks, `"name":"vm1-data-0"`)
+	state, ok := env.provider.GetVM(vm.ID)
+	require.True(t, ok)
+	assert.Equal(t, int32(30), state.OSDiskSize)
+}
+
+func TestVmSnapshotService_RestoreInterrupted(t *testing.T) {
+	env := newVMTestEnv(t)
+	ctx := context.Background()
+	vm := env.addVM("vm1", "deallocated")
+	_, err := env.vmService.SyncVMs(ctx, testUserID, testAccountID)
+	require.NoError(t, err)
+
+	// 从快照恢复过程中服务重启，操作没有恢复令牌，虚拟机不应停留在 Restoring
+	require.NoError(t, env.opRepo.Create(ctx, &model.Operation{
+		OperationID:    "restore-interrupted",
+		UserID:         testUserID,
+		AccountID:      testAccountID,
+		VMID:           vm.ID,
+		SubscriptionID: testSubID,
+		ResourceGroup:  vm.ResourceGroup,
+		ResourceName:   vm.Name,
+		Type:           string(v1.VMOperationRestoreSnapshot),
+		Status:         model.OperationStatusRunning,
+	}))
+	require.NoError(t, env.vmRepo.UpdateStatus(ctx, vm.ID, "Restoring"))
+
+	require.NoError(t, env.opService.ResumeOperations(ctx))
+	op, err := env.opRepo.GetByOperationID(ctx, testUserID, "restore-interrupted")
+	require.NoError(t, err)
+	assert.Equal(t, model.OperationStatusFailed, op.Status)
+	dbVM, err := env.vmRepo.GetByID(ctx, vm.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "deallocated", dbVM.PowerState)
+}